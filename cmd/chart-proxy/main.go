@@ -0,0 +1,107 @@
+// chart-proxy is a caching proxy of chart repositories and OCI registries
+// that the controller, with --chart-proxy-url, sends all its chart
+// requests through, deduplicating downloads across controller replicas and
+// shards and giving network policies a single egress point.
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+)
+
+var (
+	listenAddr    string
+	cacheDir      string
+	indexTTL      time.Duration
+	maxObjectSize int64
+	maxCacheSize  int64
+	httpTimeout   time.Duration
+	httpOptions   chartUtils.TransportOptions
+	allowedRepos  []string
+	deniedRepos   []string
+	deniedCIDRs   []string
+	logLevel      string
+	logFormat     string
+
+	logger = logging.New(os.Stderr, logging.Info, logging.TextFormat)
+)
+
+func init() {
+	pflag.StringVar(&listenAddr, "listen", ":8080", "address to serve the proxy on, requested as /<http|https>/<host>/<path>, and /healthz")
+	pflag.StringVar(&cacheDir, "cache-dir", "/var/cache/chart-proxy", "directory of the cached responses, emptied on start")
+	pflag.DurationVar(&indexTTL, "index-ttl", time.Minute, "how long repository indexes and OCI manifests requested by tag are served from the cache. Chart archives and content requested by digest are cached until evicted.")
+	pflag.Int64Var(&maxObjectSize, "max-object-size", 20<<20, "size in bytes of the largest response cached, larger ones failing. Unlimited if zero.")
+	pflag.Int64Var(&maxCacheSize, "max-cache-size", 1<<30, "size in bytes of the cache, the least recently used responses being evicted beyond it. Unlimited if zero.")
+	pflag.DurationVar(&httpTimeout, "http-timeout", 5*time.Minute, "maximum duration of an upstream request, including downloading the response. Unlimited if zero.")
+	pflag.DurationVar(&httpOptions.DialTimeout, "http-dial-timeout", 10*time.Second, "maximum duration of establishing a connection upstream")
+	pflag.DurationVar(&httpOptions.TLSHandshakeTimeout, "http-tls-handshake-timeout", 10*time.Second, "maximum duration of the TLS handshake with an upstream")
+	pflag.DurationVar(&httpOptions.ResponseHeaderTimeout, "http-response-header-timeout", 30*time.Second, "maximum time waiting for the response headers of an upstream request")
+	pflag.IntVar(&httpOptions.MaxIdleConnsPerHost, "http-max-idle-conns-per-host", 16, "number of idle connections kept alive to each upstream host")
+	pflag.StringSliceVar(&allowedRepos, "allowed-repos", nil, "comma separated patterns of the URLs that may be proxied, * matching any characters, e.g. https://charts.bitnami.com/*, including the token endpoints of OCI registries. Required.")
+	pflag.StringSliceVar(&deniedRepos, "denied-repos", nil, "comma separated patterns of the URLs that may not be proxied, even if allowed")
+	pflag.StringSliceVar(&deniedCIDRs, "denied-cidrs", []string{"127.0.0.0/8", "169.254.0.0/16", "0.0.0.0/8", "::1/128", "fe80::/10"}, "comma separated CIDRs the proxy never connects to, whatever the allowed URLs resolve to. Add the pod and service CIDRs of the cluster.")
+	pflag.StringVar(&logLevel, "log-level", "info", "minimum level of logged messages: debug, info, warn or error")
+	pflag.StringVar(&logFormat, "log-format", "text", "log format: text or json")
+}
+
+func main() {
+	pflag.Parse()
+
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		panic(err.Error())
+	}
+	format, err := logging.ParseFormat(logFormat)
+	if err != nil {
+		panic(err.Error())
+	}
+	logger = logging.New(os.Stderr, level, format)
+
+	// The proxy must not relay requests to arbitrary hosts
+	if len(allowedRepos) == 0 {
+		panic("--allowed-repos is required")
+	}
+	for _, cidr := range deniedCIDRs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err.Error())
+		}
+		httpOptions.DeniedNetworks = append(httpOptions.DeniedNetworks, n)
+	}
+
+	client := &http.Client{
+		Transport: chartUtils.NewTransport(httpOptions),
+		Timeout:   httpTimeout,
+	}
+	p, err := newProxy(client, cacheDir)
+	if err != nil {
+		panic(err.Error())
+	}
+	p.indexTTL = indexTTL
+	p.maxObjectSize = maxObjectSize
+	p.maxCacheSize = maxCacheSize
+	p.policy = policy.RepoPolicy{Allowed: allowedRepos, Denied: deniedRepos}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.Handle("/", p)
+
+	logger.With("address", listenAddr, "cacheDir", cacheDir).Infof("Serving chart proxy")
+	server := &http.Server{
+		Addr:    listenAddr,
+		Handler: mux,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		panic(err.Error())
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+)
+
+// maxErrorBody is the size in bytes of the largest error response body
+// passed on to the waiters of a deduplicated download
+const maxErrorBody = 64 << 10
+
+// Paths of immutable content, cached until evicted: chart archives and OCI
+// blobs and manifests requested by digest
+var immutablePath = regexp.MustCompile(`(\.tgz|\.tar\.gz|/blobs/sha256:[0-9a-f]+|/manifests/sha256:[0-9a-f]+)$`)
+
+// Paths of mutable content, cached for the index TTL: repository indexes
+// and OCI manifests requested by tag
+var mutablePath = regexp.MustCompile(`(/index\.yaml|/manifests/[^/]+)$`)
+
+// Paths of values files, only proxied
+var valuesPath = regexp.MustCompile(`\.(yaml|yml|json)$`)
+
+// entry is a cached response
+type entry struct {
+	// file holds the body of 200 responses
+	file        string
+	size        int64
+	contentType string
+	fetched     time.Time
+	expires     time.Time
+	used        time.Time
+}
+
+// call is a download in progress, awaited by the concurrent requests of
+// the same content
+type call struct {
+	done chan struct{}
+	// res is the upstream response other than 200, with its body read
+	// into body, which isn't cached
+	res  *http.Response
+	body []byte
+	err  error
+}
+
+// proxy is a caching proxy of chart repositories and OCI registries,
+// deduplicating the downloads of the same content, with the same
+// Authorization, across its clients
+type proxy struct {
+	client chartUtils.HTTPClient
+	policy policy.RepoPolicy
+	// dir holds the cached bodies
+	dir string
+	// indexTTL is how long mutable content is served from the cache
+	indexTTL time.Duration
+	// maxObjectSize is the size in bytes of the largest response cached
+	maxObjectSize int64
+	// maxCacheSize is the size in bytes of the cache, least recently used
+	// entries being evicted beyond it. Unlimited if zero.
+	maxCacheSize int64
+	now          func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	calls   map[string]*call
+	size    int64
+}
+
+func newProxy(client chartUtils.HTTPClient, dir string) (*proxy, error) {
+	// Entries only live in memory, drop the ones of a previous run
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &proxy{
+		client:  client,
+		dir:     dir,
+		now:     time.Now,
+		entries: map[string]*entry{},
+		calls:   map[string]*call{},
+	}, nil
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "only GET and HEAD are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	upstream, err := chartUtils.UpstreamURL(r.URL.EscapedPath(), r.URL.RawQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := p.policy.Check(upstream.Scheme + "://" + upstream.Host + upstream.Path); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	rlog := logger.With("url", upstream.Scheme+"://"+upstream.Host+upstream.Path)
+
+	var ttl time.Duration
+	switch {
+	case immutablePath.MatchString(upstream.Path):
+	case mutablePath.MatchString(upstream.Path):
+		ttl = p.indexTTL
+	case valuesPath.MatchString(upstream.Path) || isTokenRequest(upstream.Query()):
+		// Values files and registry tokens are only proxied
+		rlog.Debugf("Proxying")
+		p.forward(w, r, upstream.String())
+		return
+	default:
+		http.Error(w, "only chart archives, repository indexes, OCI content, values files and registry tokens are proxied", http.StatusNotFound)
+		return
+	}
+	key := cacheKey(upstream.String(), r.Header.Get("Authorization"), r.Header.Get("Accept"))
+	f, e, hit := p.lookup(key)
+	if !hit {
+		rlog.Debugf("Downloading")
+		c := p.fetch(key, upstream.String(), r.Header, ttl)
+		if c.err != nil {
+			rlog.With("error", c.err).Warnf("Download failed")
+			http.Error(w, c.err.Error(), http.StatusBadGateway)
+			return
+		}
+		if c.res != nil {
+			copyHeader(w.Header(), c.res.Header)
+			w.WriteHeader(c.res.StatusCode)
+			w.Write(c.body)
+			return
+		}
+		if f, e, hit = p.lookup(key); !hit {
+			http.Error(w, "evicted while downloading", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Cache", "MISS")
+	} else {
+		w.Header().Set("X-Cache", "HIT")
+	}
+	defer f.Close()
+	if e.contentType != "" {
+		w.Header().Set("Content-Type", e.contentType)
+	}
+	http.ServeContent(w, r, "", e.fetched, f)
+}
+
+// isTokenRequest returns whether query is that of a request of a bearer
+// token answering the challenge of an OCI registry
+func isTokenRequest(query url.Values) bool {
+	return query.Get("scope") != "" || query.Get("service") != ""
+}
+
+// cacheKey returns the key of the cache entry of u requested with the
+// given headers, so that credentials never get another's content
+func cacheKey(u, authorization, accept string) string {
+	sum := sha256.Sum256([]byte(u + "\n" + authorization + "\n" + accept))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup opens the body of the unexpired entry of key
+func (p *proxy) lookup(key string) (*os.File, *entry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[key]
+	if !ok || (!e.expires.IsZero() && !p.now().Before(e.expires)) {
+		return nil, nil, false
+	}
+	f, err := os.Open(e.file)
+	if err != nil {
+		return nil, nil, false
+	}
+	e.used = p.now()
+	return f, e, true
+}
+
+// fetch downloads u into the entry of key, unless a download of it is
+// already in progress, waiting for it to complete. Entries expire after
+// ttl unless zero.
+func (p *proxy) fetch(key, u string, header http.Header, ttl time.Duration) *call {
+	p.mu.Lock()
+	if c, ok := p.calls[key]; ok {
+		p.mu.Unlock()
+		<-c.done
+		return c
+	}
+	c := &call{done: make(chan struct{})}
+	p.calls[key] = c
+	p.mu.Unlock()
+
+	c.res, c.body, c.err = p.download(key, u, header, ttl)
+	close(c.done)
+
+	p.mu.Lock()
+	delete(p.calls, key)
+	p.mu.Unlock()
+	return c
+}
+
+// download gets u, caching 200 responses. Other responses are returned
+// with their body.
+func (p *proxy) download(key, u string, header http.Header, ttl time.Duration) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range []string{"Authorization", "Accept"} {
+		if v := header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		return res, body, err
+	}
+
+	tmp, err := ioutil.TempFile(p.dir, "download-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	body := io.Reader(res.Body)
+	if p.maxObjectSize > 0 {
+		body = io.LimitReader(res.Body, p.maxObjectSize+1)
+	}
+	size, err := io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.maxObjectSize > 0 && size > p.maxObjectSize {
+		return nil, nil, fmt.Errorf("%s exceeds the maximum size of %d bytes", req.URL.Host+req.URL.Path, p.maxObjectSize)
+	}
+	file := filepath.Join(p.dir, key)
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return nil, nil, err
+	}
+
+	now := p.now()
+	e := &entry{file: file, size: size, contentType: res.Header.Get("Content-Type"), fetched: now, used: now}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if old, ok := p.entries[key]; ok {
+		p.size -= old.size
+	}
+	p.entries[key] = e
+	p.size += size
+	p.evict(key)
+	return nil, nil, nil
+}
+
+// evict removes the least recently used entries other than keep while the
+// cache exceeds its maximum size
+func (p *proxy) evict(keep string) {
+	if p.maxCacheSize <= 0 || p.size <= p.maxCacheSize {
+		return
+	}
+	keys := make([]string, 0, len(p.entries))
+	for key := range p.entries {
+		if key != keep {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return p.entries[keys[i]].used.Before(p.entries[keys[j]].used)
+	})
+	for _, key := range keys {
+		if p.size <= p.maxCacheSize {
+			return
+		}
+		e := p.entries[key]
+		// Readers of the file keep reading it once removed
+		os.Remove(e.file)
+		delete(p.entries, key)
+		p.size -= e.size
+	}
+}
+
+// forward proxies r to u without caching the response
+func (p *proxy) forward(w http.ResponseWriter, r *http.Request, u string) {
+	req, err := http.NewRequest(r.Method, u, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, name := range []string{"Authorization", "Accept"} {
+		if v := r.Header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+	copyHeader(w.Header(), res.Header)
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+// copyHeader copies the end-to-end headers of a response
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		switch strings.ToLower(name) {
+		case "connection", "keep-alive", "transfer-encoding", "upgrade":
+			continue
+		}
+		dst[name] = values
+	}
+}
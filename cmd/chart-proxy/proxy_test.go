@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+)
+
+// testProxy is a proxy of an upstream server counting its requests
+type testProxy struct {
+	*proxy
+	client   chartUtils.HTTPClient
+	upstream string
+	requests int32
+	close    func()
+}
+
+func newTestProxy(t *testing.T, handler http.HandlerFunc) *testProxy {
+	tp := &testProxy{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tp.requests, 1)
+		handler(w, r)
+	}))
+	dir, err := ioutil.TempDir("", "chart-proxy")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	tp.proxy, err = newProxy(http.DefaultClient, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	tp.indexTTL = time.Minute
+	server := httptest.NewServer(tp.proxy)
+	tp.client = &chartUtils.ProxyClient{Client: http.DefaultClient, URL: server.URL}
+	tp.upstream = upstream.URL
+	tp.close = func() {
+		server.Close()
+		upstream.Close()
+		os.RemoveAll(dir)
+	}
+	return tp
+}
+
+func (tp *testProxy) get(t *testing.T, path, auth string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", tp.upstream+path, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	res, err := tp.client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	return res, string(body)
+}
+
+func chartRepository(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/charts/index.yaml":
+		w.Write([]byte("apiVersion: v1\n"))
+	case "/charts/foo-1.0.0.tgz":
+		w.Write([]byte("chart" + r.Header.Get("Authorization")))
+	case "/token":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"x"}`))
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="/token"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+}
+
+func TestProxyCaching(t *testing.T) {
+	tp := newTestProxy(t, chartRepository)
+	defer tp.close()
+	now := time.Now()
+	tp.now = func() time.Time { return now }
+
+	for i, cache := range []string{"MISS", "HIT"} {
+		res, body := tp.get(t, "/charts/foo-1.0.0.tgz", "")
+		if res.StatusCode != 200 || body != "chart" || res.Header.Get("X-Cache") != cache {
+			t.Errorf("Unexpected response %d %q, cache %s", res.StatusCode, body, res.Header.Get("X-Cache"))
+		}
+		if tp.requests != 1 {
+			t.Errorf("Expecting a single upstream request after %d requests, received %d", i+1, tp.requests)
+		}
+	}
+	// Requests with other credentials don't share the cache
+	if _, body := tp.get(t, "/charts/foo-1.0.0.tgz", "Basic dXNlcjpwYXNz"); body != "chartBasic dXNlcjpwYXNz" || tp.requests != 2 {
+		t.Errorf("Expecting the credentials to be forwarded, received %q", body)
+	}
+
+	// Indexes are cached for the index TTL, chart archives until evicted
+	tp.get(t, "/charts/index.yaml", "")
+	tp.get(t, "/charts/index.yaml", "")
+	if tp.requests != 3 {
+		t.Errorf("Expecting the index to be cached, sent %d requests", tp.requests)
+	}
+	now = now.Add(time.Hour)
+	tp.get(t, "/charts/index.yaml", "")
+	tp.get(t, "/charts/foo-1.0.0.tgz", "")
+	if tp.requests != 4 {
+		t.Errorf("Expecting the index to expire, sent %d requests", tp.requests)
+	}
+
+	// Errors and other content are passed through, not cached
+	for i := 0; i < 2; i++ {
+		res, _ := tp.get(t, "/v2/charts/foo/manifests/1.0.0", "")
+		if res.StatusCode != http.StatusUnauthorized || res.Header.Get("WWW-Authenticate") != `Bearer realm="/token"` {
+			t.Errorf("Expecting the challenge to be passed through, received %d %v", res.StatusCode, res.Header)
+		}
+		if _, body := tp.get(t, "/token?scope=repository:charts/foo:pull", ""); body != `{"token":"x"}` {
+			t.Errorf("Unexpected token response %q", body)
+		}
+	}
+	if tp.requests != 8 {
+		t.Errorf("Expecting errors and tokens not to be cached, sent %d requests", tp.requests)
+	}
+}
+
+func TestProxyDeduplication(t *testing.T) {
+	release := make(chan struct{})
+	tp := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		chartRepository(w, r)
+	})
+	defer tp.close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, body := tp.get(t, "/charts/foo-1.0.0.tgz", ""); body != "chart" {
+				t.Errorf("Unexpected body %q", body)
+			}
+		}()
+	}
+	// Let the requests reach the proxy before the download completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if tp.requests != 1 {
+		t.Errorf("Expecting concurrent downloads to be deduplicated, sent %d requests", tp.requests)
+	}
+}
+
+func TestProxyEviction(t *testing.T) {
+	tp := newTestProxy(t, chartRepository)
+	defer tp.close()
+	now := time.Now()
+	tp.now = func() time.Time { return now }
+	tp.maxCacheSize = 30
+
+	tp.get(t, "/charts/foo-1.0.0.tgz", "")
+	now = now.Add(time.Second)
+	tp.get(t, "/charts/index.yaml", "")
+	now = now.Add(time.Second)
+	// The least recently used archive is evicted
+	tp.get(t, "/charts/foo-1.0.0.tgz", "Bearer x")
+	if len(tp.entries) != 2 || tp.size != int64(len("apiVersion: v1\n")+len("chartBearer x")) {
+		t.Errorf("Expecting an entry to be evicted, cached %d entries of %d bytes", len(tp.entries), tp.size)
+	}
+	tp.get(t, "/charts/index.yaml", "")
+	tp.get(t, "/charts/foo-1.0.0.tgz", "")
+	if tp.requests != 4 {
+		t.Errorf("Expecting the index to be cached and the archive evicted, sent %d requests", tp.requests)
+	}
+}
+
+func TestProxyErrors(t *testing.T) {
+	tp := newTestProxy(t, chartRepository)
+	defer tp.close()
+	tp.policy = policy.RepoPolicy{Denied: []string{tp.upstream + "/private/*"}}
+
+	if res, _ := tp.get(t, "/private/index.yaml", ""); res.StatusCode != http.StatusForbidden {
+		t.Errorf("Expecting denied URLs to be forbidden, received %d", res.StatusCode)
+	}
+	tp.maxObjectSize = 2
+	if res, _ := tp.get(t, "/charts/foo-1.0.0.tgz", ""); res.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expecting large responses to fail, received %d", res.StatusCode)
+	}
+	if len(tp.entries) != 0 {
+		t.Errorf("Unexpected cache entries %v", tp.entries)
+	}
+
+	// Only the requests of charts, values and tokens are proxied
+	for _, path := range []string{"/", "/api/v1/namespaces", "/token"} {
+		if res, _ := tp.get(t, path, ""); res.StatusCode != http.StatusNotFound {
+			t.Errorf("Expecting %s not to be proxied, received %d", path, res.StatusCode)
+		}
+	}
+
+	for _, path := range []string{"/ftp/example.com/index.yaml", "/https"} {
+		res, err := http.Get(tp.client.(*chartUtils.ProxyClient).URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expecting %s to be rejected, received %d", path, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+const (
+	// apiReleasesPath is the path of the releases API
+	apiReleasesPath = "/api/v1/releases"
+	// apiHistoryMax is the number of revisions returned by the releases API
+	apiHistoryMax = 10
+)
+
+// apiRelease is a HelmRelease as returned by the releases API, with the
+// status of its release in Tiller. Inline values are left out as they
+// may hold secrets.
+type apiRelease struct {
+	Namespace   string                      `json:"namespace"`
+	Name        string                      `json:"name"`
+	ReleaseName string                      `json:"releaseName"`
+	Spec        helmCrdV2.HelmReleaseSpec   `json:"spec"`
+	Status      helmCrdV2.HelmReleaseStatus `json:"status"`
+	// TillerStatus is the status code of the latest revision of the release
+	TillerStatus string `json:"tillerStatus,omitempty"`
+	// History are the latest revisions of the release, only returned for
+	// a single release
+	History []apiRevision `json:"history,omitempty"`
+	// Error is why the release couldn't be queried from Tiller
+	Error string `json:"error,omitempty"`
+}
+
+// apiRevision is a revision of a release
+type apiRevision struct {
+	Revision     int32      `json:"revision"`
+	Status       string     `json:"status"`
+	Chart        string     `json:"chart"`
+	ChartVersion string     `json:"chartVersion"`
+	Description  string     `json:"description,omitempty"`
+	Updated      *time.Time `json:"updated,omitempty"`
+}
+
+// apiHandler serves the read-only releases API: the HelmReleases of every
+// namespace, or of ?namespace=, at /api/v1/releases and a single one with
+// its history at /api/v1/releases/<namespace>/<name>. Requests must
+// carry token as a bearer token.
+func (c *Controller) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiReleasesPath, func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		releases := []apiRelease{}
+		for _, obj := range c.informer.GetStore().List() {
+			h := obj.(*helmCrdV2.HelmRelease)
+			if namespace == "" || h.Namespace == namespace {
+				releases = append(releases, c.apiRelease(h, false))
+			}
+		}
+		sort.Slice(releases, func(i, j int) bool {
+			if releases[i].Namespace != releases[j].Namespace {
+				return releases[i].Namespace < releases[j].Namespace
+			}
+			return releases[i].Name < releases[j].Name
+		})
+		writeJSON(w, releases)
+	})
+	mux.HandleFunc(apiReleasesPath+"/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, apiReleasesPath+"/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		obj, exists, err := c.informer.GetStore().GetByKey(parts[0] + "/" + parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, c.apiRelease(obj.(*helmCrdV2.HelmRelease), true))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// apiRelease returns h with the Tiller status of its release and, if
+// history is set, its latest revisions
+func (c *Controller) apiRelease(h *helmCrdV2.HelmRelease, history bool) apiRelease {
+	key, _ := cache.MetaNamespaceKeyFunc(h)
+	res := apiRelease{
+		Namespace:   h.Namespace,
+		Name:        h.Name,
+		ReleaseName: getReleaseName(h),
+		Spec:        *h.Spec.DeepCopy(),
+		Status:      h.Status,
+	}
+	res.Spec.Values = ""
+	if res.Spec.Chart.Inline != nil {
+		res.Spec.Chart.Inline.Values = ""
+	}
+	helmClient, err := c.helmClientFor(h)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if !history {
+		rlsStatus, err := helmClient.Status(res.ReleaseName)
+		if err != nil {
+			logger.With("helmrelease", key, "error", err).Debugf("Unable to get release status")
+			res.Error = err.Error()
+			return res
+		}
+		res.TillerStatus = rlsStatus.GetCode().String()
+		return res
+	}
+
+	revisions, err := helmClient.History(res.ReleaseName, apiHistoryMax)
+	if err != nil {
+		logger.With("helmrelease", key, "error", err).Debugf("Unable to get release history")
+		res.Error = err.Error()
+		return res
+	}
+	for i, rel := range revisions {
+		rev := apiRevision{
+			Revision:     rel.GetVersion(),
+			Status:       rel.GetInfo().GetStatus().GetCode().String(),
+			Chart:        rel.GetChart().GetMetadata().GetName(),
+			ChartVersion: rel.GetChart().GetMetadata().GetVersion(),
+			Description:  rel.GetInfo().GetDescription(),
+		}
+		if ts := rel.GetInfo().GetLastDeployed(); ts != nil {
+			if t, err := ptypes.Timestamp(ts); err == nil {
+				rev.Updated = &t
+			}
+		}
+		if i == 0 {
+			res.TillerStatus = rev.Status
+		}
+		res.History = append(res.History, rev)
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.With("error", err).Warnf("Unable to write API response")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestAPIHandler(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Values: "password: sekret\n",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+	other := h.DeepCopy()
+	other.Namespace = "other"
+	other.Spec.ReleaseName = "missing"
+	controller.informer.GetIndexer().Add(other)
+	handler := controller.apiHandler("s3cret")
+	get := func(method, path string, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := get("GET", "/api/v1/releases", ""); w.Code != 401 {
+		t.Errorf("Expecting requests without the token to be rejected, received %d", w.Code)
+	}
+	if w := get("GET", "/api/v1/releases", "wrong"); w.Code != 401 {
+		t.Errorf("Expecting requests with another token to be rejected, received %d", w.Code)
+	}
+	r := httptest.NewRequest("GET", "/api/v1/releases", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	controller.apiHandler("").ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("Expecting requests to be rejected without a token, received %d", w.Code)
+	}
+	if w := get("POST", "/api/v1/releases", "s3cret"); w.Code != 405 {
+		t.Errorf("Expecting the API to be read-only, received %d", w.Code)
+	}
+
+	w = get("GET", "/api/v1/releases", "s3cret")
+	var releases []apiRelease
+	if err := json.Unmarshal(w.Body.Bytes(), &releases); err != nil {
+		t.Fatalf("Unexpected error %v: %s", err, w.Body.String())
+	}
+	if len(releases) != 2 || releases[0].Namespace != "myns" || releases[0].TillerStatus != "DEPLOYED" || releases[0].Spec.Values != "" {
+		t.Errorf("Unexpected releases %+v", releases)
+	}
+	if releases[1].Namespace != "other" || releases[1].Error == "" {
+		t.Errorf("Expecting an error for the missing release, received %+v", releases[1])
+	}
+
+	w = get("GET", "/api/v1/releases?namespace=other", "s3cret")
+	if err := json.Unmarshal(w.Body.Bytes(), &releases); err != nil || len(releases) != 1 || releases[0].Namespace != "other" {
+		t.Errorf("Expecting the releases of the namespace, received %s", w.Body.String())
+	}
+
+	w = get("GET", "/api/v1/releases/myns/foo", "s3cret")
+	var release apiRelease
+	if err := json.Unmarshal(w.Body.Bytes(), &release); err != nil {
+		t.Fatalf("Unexpected error %v: %s", err, w.Body.String())
+	}
+	if release.ReleaseName != "bar" || len(release.History) != 1 || release.History[0].Revision != 1 || release.History[0].Status != "DEPLOYED" {
+		t.Errorf("Unexpected release %+v", release)
+	}
+
+	for _, path := range []string{"/api/v1/releases/myns/missing", "/api/v1/releases/myns", "/api/v1/releases/myns/foo/bar"} {
+		if w := get("GET", path, "s3cret"); w.Code != 404 {
+			t.Errorf("Expecting %s not to be found, received %d", path, w.Code)
+		}
+	}
+
+	// The values of inline charts are left out too
+	other.Spec.Chart = helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{
+		Metadata: helmCrdV2.InlineChartMetadata{Name: "foo", Version: "1.0.0"},
+		Values:   "password: sekret\n",
+	}}
+	if res := controller.apiRelease(other, false); res.Spec.Chart.Inline.Values != "" || other.Spec.Chart.Inline.Values == "" {
+		t.Errorf("Expecting the inline chart values to be left out, received %+v", res.Spec.Chart.Inline)
+	}
+}
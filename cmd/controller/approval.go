@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// reasonAwaitingApproval is the reason of the PendingApproval condition
+// of HelmReleases whose upgrade waits for an approval
+const reasonAwaitingApproval = "AwaitingApproval"
+
+// approvalRequired returns true if upgrades of h to the release of
+// checksum wait for the approved annotation to match it. Approvals are
+// tied to what is deployed rather than to the HelmRelease generation, so
+// that a newer chart version of a range or changed valuesFrom sources
+// aren't deployed with an approval given for another upgrade.
+func approvalRequired(h *helmCrdV2.HelmRelease, checksum string) bool {
+	return h.Spec.UpgradeApproval == helmCrdV2.UpgradeApprovalManual &&
+		h.Annotations[helmCrdV2.ApprovedAnnotation] != checksum
+}
+
+// awaitApproval records in the PendingApproval condition of h that its
+// upgrade to the release of checksum, changing what diff summarizes,
+// waits for an approval, along with the diff in the status as with
+// spec.upgradeDiff. The HelmRelease is reconciled again once annotated.
+func (c *Controller) awaitApproval(h *helmCrdV2.HelmRelease, diff *helmCrdV2.UpgradeDiffStatus, full, checksum string) error {
+	msg := fmt.Sprintf("Upgrade from chart version %s to %s adding %d, changing %d and removing %d objects awaits approval with the %s: %q annotation",
+		diff.FromVersion, diff.ToVersion, diff.Added, diff.Changed, diff.Removed, helmCrdV2.ApprovedAnnotation, checksum)
+	if diff.Summary != "" {
+		msg += ":\n" + diff.Summary
+	}
+	status := h.Status
+	if cond := getCondition(&status, helmCrdV2.HelmReleasePendingApproval); cond != nil && cond.Message == msg {
+		return nil
+	}
+	if upgradeDiffEnabled(h) && h.Spec.UpgradeDiff.Full {
+		var err error
+		if diff.FullDiff, err = c.storeReleaseData(h, h.Name+"-upgrade-diff", upgradeDiffKey, full); err != nil {
+			return err
+		}
+	}
+	c.recordEvent(h, corev1.EventTypeNormal, reasonAwaitingApproval, msg)
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleasePendingApproval,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonAwaitingApproval,
+		Message: msg,
+	})
+	diff.Time = metav1.NewTime(c.now())
+	diff.Checksum = checksum
+	status.UpgradeDiff = diff
+	_, err := c.updateStatus(h, status)
+	return err
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUpgradeApproval(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "myns",
+			Name:       "foo",
+			Generation: 3,
+			// Approves a previous upgrade
+			Annotations: map[string]string{helmCrdV2.ApprovedAnnotation: "2"},
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			UpgradeApproval: helmCrdV2.UpgradeApprovalManual,
+		},
+		Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expecting the upgrade to wait for an approval")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	diff := res.Status.UpgradeDiff
+	if diff == nil || diff.ToVersion != "1.0.0" || diff.Checksum == "" {
+		t.Fatalf("Expecting the pending upgrade diff, received %+v", diff)
+	}
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleasePendingApproval)
+	if cond == nil || cond.Status != corev1.ConditionTrue || !strings.Contains(cond.Message, `helm.bitnami.com/approved: "`+diff.Checksum+`"`) {
+		t.Errorf("Unexpected PendingApproval condition %+v", cond)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != reasonAwaitingApproval {
+		t.Errorf("Unexpected events %+v", events.Items)
+	}
+
+	// Resyncs don't record the pending upgrade again
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{}); len(events.Items) != 1 {
+		t.Errorf("Unexpected events %+v", events.Items)
+	}
+
+	// Approving the generation doesn't approve the upgrade
+	res.Annotations[helmCrdV2.ApprovedAnnotation] = "3"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expecting the upgrade to wait for an approval of its checksum")
+	}
+
+	res.Annotations[helmCrdV2.ApprovedAnnotation] = diff.Checksum
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 2 {
+		t.Errorf("Expecting the approved upgrade to run")
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleasePendingApproval); cond != nil {
+		t.Errorf("Expecting the PendingApproval condition to be removed, received %+v", cond)
+	}
+
+	// Values changing without a new generation, e.g. from valuesFrom
+	// sources, are not covered by the approval
+	res.Spec.Values = "replicas: 2\n"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 2 {
+		t.Errorf("Expecting the changed upgrade to wait for an approval")
+	}
+}
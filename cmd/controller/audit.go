@@ -0,0 +1,87 @@
+package main
+
+import (
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/audit"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// auditedClient records in the audit log the installs, upgrades,
+// rollbacks and deletions of the release of the HelmRelease
+// namespace/name. Dry-runs aren't recorded.
+type auditedClient struct {
+	helmclient.Interface
+	c         *Controller
+	namespace string
+	name      string
+}
+
+// audited returns client, whose operations are recorded in the audit log
+// of --audit-log as run for the HelmRelease namespace/name
+func (c *Controller) audited(namespace, name string, client helmclient.Interface) helmclient.Interface {
+	if c.auditSink == nil {
+		return client
+	}
+	return &auditedClient{Interface: client, c: c, namespace: namespace, name: name}
+}
+
+func (a *auditedClient) Install(ch *chart.Chart, namespace string, opts helmclient.InstallOptions) (*release.Release, error) {
+	rel, err := a.Interface.Install(ch, namespace, opts)
+	if !opts.DryRun {
+		a.record(audit.Install, opts.ReleaseName, ch, valuesChecksum(opts.Values), rel, err)
+	}
+	return rel, err
+}
+
+func (a *auditedClient) Upgrade(rlsName string, ch *chart.Chart, opts helmclient.UpgradeOptions) (*release.Release, error) {
+	rel, err := a.Interface.Upgrade(rlsName, ch, opts)
+	if !opts.DryRun {
+		a.record(audit.Upgrade, rlsName, ch, valuesChecksum(opts.Values), rel, err)
+	}
+	return rel, err
+}
+
+func (a *auditedClient) Rollback(rlsName string, opts helmclient.RollbackOptions) (*release.Release, error) {
+	rel, err := a.Interface.Rollback(rlsName, opts)
+	a.record(audit.Rollback, rlsName, rel.GetChart(), "", rel, err)
+	return rel, err
+}
+
+func (a *auditedClient) Delete(rlsName string, opts helmclient.DeleteOptions) error {
+	err := a.Interface.Delete(rlsName, opts)
+	a.record(audit.Delete, rlsName, nil, "", nil, err)
+	return err
+}
+
+func (a *auditedClient) record(op audit.Operation, rlsName string, ch *chart.Chart, checksum string, rel *release.Release, err error) {
+	a.c.recordAudit(a.namespace, a.name, op, rlsName, ch, checksum, rel, err)
+}
+
+// recordAudit writes the audit record of the operation op, run for the
+// HelmRelease namespace/name on its release rlsName, if --audit-log is set
+func (c *Controller) recordAudit(namespace, name string, op audit.Operation, rlsName string, ch *chart.Chart, checksum string, rel *release.Release, err error) {
+	if c.auditSink == nil {
+		return
+	}
+	r := audit.Record{
+		Time:           c.now(),
+		Operation:      op,
+		Namespace:      namespace,
+		Name:           name,
+		Release:        rlsName,
+		Chart:          ch.GetMetadata().GetName(),
+		Version:        ch.GetMetadata().GetVersion(),
+		ValuesChecksum: checksum,
+		Revision:       rel.GetVersion(),
+		Result:         audit.Succeeded,
+	}
+	if err != nil {
+		r.Result = audit.Failed
+		r.Error = err.Error()
+	}
+	if err := c.auditSink.Write(r); err != nil {
+		logger.With("namespace", namespace, "name", name, "operation", op, "error", err).Warnf("Unable to write audit record")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/audit"
+)
+
+type fakeAuditSink struct {
+	records []audit.Record
+}
+
+func (s *fakeAuditSink) Write(r audit.Record) error {
+	s.records = append(s.records, r)
+	return nil
+}
+
+func TestAuditLog(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Values: "replicas: 1\n",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	sink := &fakeAuditSink{}
+	controller.auditSink = sink
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	res.Spec.Values = "replicas: 2\n"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res.DeletionTimestamp = &metav1.Time{}
+	res.Finalizers = []string{releaseFinalizer}
+	if err := controller.deleteRelease(res); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	ops := []audit.Operation{audit.Install, audit.Upgrade, audit.Delete}
+	if len(sink.records) != len(ops) {
+		t.Fatalf("Expecting %v to be recorded, received %+v", ops, sink.records)
+	}
+	for i, r := range sink.records {
+		if r.Operation != ops[i] || r.Namespace != "myns" || r.Name != "foo" || r.Release != getReleaseName(&h) || r.Result != audit.Succeeded {
+			t.Errorf("Unexpected record %+v", r)
+		}
+	}
+	install, upgrade := sink.records[0], sink.records[1]
+	if install.Chart != "foo" || install.Version != "1.0.0" || install.Revision != 1 {
+		t.Errorf("Unexpected install record %+v", install)
+	}
+	if upgrade.Revision != 2 || upgrade.ValuesChecksum == "" || upgrade.ValuesChecksum == install.ValuesChecksum {
+		t.Errorf("Expecting the upgrade to record the checksum of the new values, received %+v", upgrade)
+	}
+}
+
+func TestAuditLogDeleteHistoryOnly(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			DeletionPolicy: helmCrdV2.DeletionPolicyDeleteHistoryOnly,
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.tillerless = true
+	controller.storage = secretStorage{kubeClient: controller.kubeClient}
+	sink := &fakeAuditSink{}
+	controller.auditSink = sink
+	cm := tillerConfigMap("myns-foo", "1", nil)
+	controller.kubeClient.Core().Secrets("kube-system").Create(&corev1.Secret{ObjectMeta: cm.ObjectMeta})
+
+	if err := controller.deleteReleaseStorage(&h); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(sink.records) != 1 {
+		t.Fatalf("Expecting the history deletion to be recorded, received %+v", sink.records)
+	}
+	if r := sink.records[0]; r.Operation != audit.Delete || r.Namespace != "myns" || r.Name != "foo" || r.Release != getReleaseName(&h) || r.Result != audit.Succeeded {
+		t.Errorf("Unexpected record %+v", r)
+	}
+}
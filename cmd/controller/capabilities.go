@@ -0,0 +1,22 @@
+package main
+
+import (
+	"k8s.io/helm/pkg/chartutil"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/render"
+)
+
+// reasonInvalidCapabilities is the reason of the Ready condition of
+// HelmReleases whose spec.kubeVersion can not be parsed
+const reasonInvalidCapabilities = "InvalidCapabilities"
+
+// renderCapabilities returns the capabilities the chart of h is rendered
+// and linted with, nil to use those of the cluster when neither
+// spec.kubeVersion nor spec.apiVersions is set
+func renderCapabilities(h *helmCrdV2.HelmRelease) (*chartutil.Capabilities, error) {
+	if h.Spec.KubeVersion == "" && len(h.Spec.APIVersions) == 0 {
+		return nil, nil
+	}
+	return render.Capabilities(h.Spec.KubeVersion, h.Spec.APIVersions)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cloudauth"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cosign"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/oci"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/tracing"
+)
+
+// controllerNamespace returns the namespace the controller runs in
+func controllerNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	return defaultNamespace
+}
+
+// authSecretNamespace returns the namespace of the auth secret set on a
+// chart source of h, following its scope or the controller
+// --default-auth-scope. With --deny-cross-namespace-auth, HelmReleases
+// may only read secrets of their own namespace. Chart sources without
+// auth get the controller namespace, holding --default-repo-auth-secret.
+func (c *Controller) authSecretNamespace(h *helmCrdV2.HelmRelease, auth helmCrdV2.HelmReleaseAuth) (string, error) {
+	refs, scope := authSecretRefs(auth)
+	if len(refs) == 0 {
+		return controllerNamespace(), nil
+	}
+	if scope == "" {
+		scope = c.defaultAuthScope
+	}
+	if scope == helmCrdV2.AuthScopeNamespace {
+		return h.Namespace, nil
+	}
+	namespace := controllerNamespace()
+	if c.denyCrossNamespaceAuth && namespace != h.Namespace {
+		return "", fmt.Errorf("auth secret %s of the controller namespace may not be used by HelmReleases of namespace %s, set scope to %s", refs[0].Name, h.Namespace, helmCrdV2.AuthScopeNamespace)
+	}
+	return namespace, nil
+}
+
+// authSecretRefs returns the secret keys read by the header, headers or
+// bearer auth and their scope, validated to be the same for all of them
+func authSecretRefs(auth helmCrdV2.HelmReleaseAuth) ([]*corev1.SecretKeySelector, helmCrdV2.AuthScope) {
+	var refs []*corev1.SecretKeySelector
+	var scope helmCrdV2.AuthScope
+	if auth.Header != nil {
+		refs, scope = append(refs, &auth.Header.SecretKeyRef), auth.Header.Scope
+	}
+	if auth.Bearer != nil {
+		refs, scope = append(refs, &auth.Bearer.SecretKeyRef), auth.Bearer.Scope
+	}
+	for _, name := range authHeaderNames(auth) {
+		header := auth.Headers[name]
+		refs, scope = append(refs, &header.SecretKeyRef), header.Scope
+	}
+	return refs, scope
+}
+
+// authHeaderNames returns the sorted names of the headers of auth
+func authHeaderNames(auth helmCrdV2.HelmReleaseAuth) []string {
+	names := make([]string, 0, len(auth.Headers))
+	for name := range auth.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// authEmpty returns whether auth sets no authentication
+func authEmpty(auth helmCrdV2.HelmReleaseAuth) bool {
+	refs, _ := authSecretRefs(auth)
+	return len(refs) == 0 && auth.Provider == ""
+}
+
+// chartAuthHeaders returns the headers sent to a chart source at url: its
+// Authorization, read from a Secret in namespace, exchanged for an OAuth2
+// access token or issued by its cloud provider, and the headers of auth
+func (c *Controller) chartAuthHeaders(namespace, url string, auth helmCrdV2.HelmReleaseAuth) (http.Header, error) {
+	header := http.Header{}
+	if auth.Provider != "" {
+		value, err := c.cloudAuth.AuthHeader(cloudauth.Provider(auth.Provider), url)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Authorization", value)
+	}
+	secrets := map[string]*corev1.Secret{}
+	secretValue := func(ref corev1.SecretKeySelector) (string, error) {
+		secret, ok := secrets[ref.Name]
+		if !ok {
+			var err error
+			if secret, err = c.kubeClient.Core().Secrets(namespace).Get(ref.Name, metav1.GetOptions{}); err != nil {
+				return "", err
+			}
+			secrets[ref.Name] = secret
+		}
+		return string(secret.Data[ref.Key]), nil
+	}
+	for _, name := range authHeaderNames(auth) {
+		value, err := secretValue(auth.Headers[name].SecretKeyRef)
+		if err != nil {
+			return nil, err
+		}
+		header.Set(name, strings.TrimSpace(value))
+	}
+	switch {
+	case auth.Header != nil:
+		value, err := secretValue(auth.Header.SecretKeyRef)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Authorization", value)
+	case auth.Bearer != nil:
+		value, err := secretValue(auth.Bearer.SecretKeyRef)
+		if err != nil {
+			return nil, err
+		}
+		if bearer := auth.Bearer; bearer.TokenURL != "" {
+			if value, err = c.cloudAuth.ClientCredentialsAuthHeader(bearer.TokenURL, bearer.ClientID, strings.TrimSpace(value), bearer.Scopes); err != nil {
+				return nil, err
+			}
+			header.Set("Authorization", value)
+		} else {
+			header.Set("Authorization", "Bearer "+strings.TrimSpace(value))
+		}
+	}
+	return header, nil
+}
+
+// repoURLAndAuth returns the repository URL and auth of repo, defaulting
+// to the controller --default-repo-url. The --default-repo-auth-secret
+// is used for the default repository unless repo sets its own auth, also
+// when the URL was set to the default one by the admission webhook.
+func (c *Controller) repoURLAndAuth(repo *helmCrdV2.RepositoryChartSource) (string, helmCrdV2.HelmReleaseAuth) {
+	c.settingsLock.RLock()
+	defaultRepoURL := c.defaultRepoURL
+	c.settingsLock.RUnlock()
+	repoURL := repo.URL
+	if repoURL == "" {
+		repoURL = defaultRepoURL
+	}
+	auth := repo.Auth
+	if authEmpty(auth) && strings.TrimSuffix(repoURL, "/") == strings.TrimSuffix(defaultRepoURL, "/") {
+		auth = c.defaultRepoAuth
+	}
+	return repoURL, auth
+}
+
+// fetchRepositoryChart resolves the chart version of repo in the index of
+// its repository, or of a mirror, and downloads the chart along with the
+// dependencies missing from it. The resolved version is returned.
+func (c *Controller) fetchRepositoryChart(h *helmCrdV2.HelmRelease, repo *helmCrdV2.RepositoryChartSource, span *tracing.Span, rlog *logging.Logger) (*chart.Chart, string, error) {
+	repoURL, auth := c.repoURLAndAuth(repo)
+	repoURLs := []string{indexURL(repoURL)}
+	for _, mirror := range repo.Mirrors {
+		repoURLs = append(repoURLs, indexURL(mirror))
+	}
+
+	// Only the auth set on the HelmRelease is scoped, not the default one
+	authNamespace, err := c.authSecretNamespace(h, repo.Auth)
+	if err != nil {
+		return nil, "", err
+	}
+	authNamespace, auth = c.credentialAuth(repoURL, authNamespace, auth)
+	header, err := c.chartAuthHeaders(authNamespace, repoURL, auth)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rlog.With("url", repoURLs[0]).Debugf("Downloading repo index")
+	s := c.tracer.Start(span, "fetchRepoIndex", "url", repoURLs[0])
+	repoIndex, repoURL, err := c.indexGroup.FetchFailover(c.netClient, repoURLs, header)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	if repoURL != repoURLs[0] {
+		rlog.With("url", repoURL).Warnf("Repository unavailable, using mirror")
+	}
+
+	version := repo.Version
+	if updatePolicyEnabled(h) {
+		if version, err = chartUtils.UpdateConstraint(repo.Version, string(h.Spec.UpdatePolicy)); err != nil {
+			return nil, "", err
+		}
+	}
+	chartURL, chartVersion, err := chartUtils.FindChartInRepoIndex(repoIndex, repoURL, repo.Name, version, h.Spec.Devel)
+	if err != nil {
+		return nil, "", err
+	}
+	// Indexes may list archives hosted outside of the repository
+	if err := c.repoPolicy.Check(chartURL); err != nil {
+		return nil, "", err
+	}
+	if chartVersion != h.Status.ResolvedVersion && h.Status.ResolvedVersion != "" {
+		rlog.With("chart", repo.Name).Infof("Chart version resolved to %s (was %s)", chartVersion, h.Status.ResolvedVersion)
+	}
+
+	rlog.With("url", chartURL).Debugf("Downloading chart")
+	s = c.tracer.Start(span, "fetchChart", "url", chartURL)
+	chartRequested, err := chartUtils.FetchChartDigest(c.netClient, chartURL, header, repo.Digest, c.maxChartSize, c.loadChart)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	s = c.tracer.Start(span, "resolveDependencies")
+	err = chartUtils.ResolveDependencies(c.netClient, chartRequested, repoURL, header, c.maxChartSize, c.loadChart, c.repoPolicy.Check)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	return chartRequested, chartVersion, nil
+}
+
+// fetchTarballChart downloads the chart archive of tarball, bypassing
+// repository indexes, along with the dependencies missing from it.
+// Credentials are not sent to the repositories of dependencies.
+func (c *Controller) fetchTarballChart(h *helmCrdV2.HelmRelease, tarball *helmCrdV2.TarballChartSource, span *tracing.Span, rlog *logging.Logger) (*chart.Chart, error) {
+	authNamespace, err := c.authSecretNamespace(h, tarball.Auth)
+	if err != nil {
+		return nil, err
+	}
+	authNamespace, auth := c.credentialAuth(tarball.URL, authNamespace, tarball.Auth)
+	header, err := c.chartAuthHeaders(authNamespace, tarball.URL, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	rlog.With("url", tarball.URL).Debugf("Downloading chart")
+	s := c.tracer.Start(span, "fetchChart", "url", tarball.URL)
+	chartRequested, err := chartUtils.FetchChartDigest(c.netClient, tarball.URL, header, tarball.Digest, c.maxChartSize, c.loadChart)
+	s.End(err)
+	if err != nil {
+		return nil, err
+	}
+	s = c.tracer.Start(span, "resolveDependencies")
+	err = chartUtils.ResolveDependencies(c.netClient, chartRequested, "", nil, c.maxChartSize, c.loadChart, c.repoPolicy.Check)
+	s.End(err)
+	if err != nil {
+		return nil, err
+	}
+	return chartRequested, nil
+}
+
+// fetchOCIChart pulls the chart of src from its registry, verifying its
+// signatures with spec.verification, along with the dependencies missing
+// from it. Credentials are not sent to the repositories of dependencies.
+// Who signed the chart is returned.
+func (c *Controller) fetchOCIChart(h *helmCrdV2.HelmRelease, src *helmCrdV2.OCIChartSource, span *tracing.Span, rlog *logging.Logger) (*chart.Chart, string, error) {
+	ref, err := oci.ParseReference(src.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	registryURL := "https://" + ref.Registry
+	if src.PlainHTTP {
+		registryURL = "http://" + ref.Registry
+	}
+	authNamespace, err := c.authSecretNamespace(h, src.Auth)
+	if err != nil {
+		return nil, "", err
+	}
+	authNamespace, auth := c.credentialAuth(src.URL, authNamespace, src.Auth)
+	// Registries of cloud providers exchange their credentials
+	// differently than Helm repositories
+	provider := auth.Provider
+	auth.Provider = ""
+	header, err := c.chartAuthHeaders(authNamespace, registryURL, auth)
+	if err != nil {
+		return nil, "", err
+	}
+	if provider != "" {
+		value, err := c.cloudAuth.RegistryAuthHeader(cloudauth.Provider(provider), registryURL)
+		if err != nil {
+			return nil, "", err
+		}
+		header.Set("Authorization", value)
+	}
+	client := &oci.Client{HTTP: *c.netClient, Header: header, PlainHTTP: src.PlainHTTP}
+
+	rlog.With("url", src.URL, "tag", src.Version).Debugf("Pulling chart")
+	s := c.tracer.Start(span, "fetchManifest", "url", src.URL)
+	manifest, digest, err := client.Manifest(ref, src.Version)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	layer, err := oci.ChartLayer(manifest)
+	if err != nil {
+		return nil, "", err
+	}
+	if src.Digest != "" && layer.Digest != "sha256:"+strings.TrimPrefix(src.Digest, "sha256:") {
+		return nil, "", fmt.Errorf("chart digest %s does not match %s", layer.Digest, src.Digest)
+	}
+
+	var signedBy string
+	if v := h.Spec.Verification; v != nil {
+		s = c.tracer.Start(span, "verifyChart")
+		signedBy, err = c.verifyChart(h, client, ref, digest)
+		s.End(err)
+		if _, ok := err.(*cosign.VerificationError); ok && v.Mode == helmCrdV2.VerificationWarn {
+			rlog.With("error", err).Warnf("Chart signature not verified")
+			c.recordEvent(h, corev1.EventTypeWarning, reasonVerificationFailed, err.Error())
+			err = nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	s = c.tracer.Start(span, "fetchChart", "url", src.URL)
+	data, err := client.Blob(ref, layer, c.maxChartSize)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	chartRequested, err := c.loadChart(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	s = c.tracer.Start(span, "resolveDependencies")
+	err = chartUtils.ResolveDependencies(c.netClient, chartRequested, "", nil, c.maxChartSize, c.loadChart, c.repoPolicy.Check)
+	s.End(err)
+	if err != nil {
+		return nil, "", err
+	}
+	return chartRequested, signedBy, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cloudauth"
+)
+
+func TestRepoURLAndAuth(t *testing.T) {
+	defaultAuth := helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+		SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "mirror"}, Key: "token"},
+	}}
+	ownAuth := helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+		SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "own"}, Key: "token"},
+	}}
+	c := &Controller{defaultRepoURL: "https://charts.internal/stable", defaultRepoAuth: defaultAuth}
+	tests := []struct {
+		name         string
+		repo         helmCrdV2.RepositoryChartSource
+		expectedURL  string
+		expectedAuth helmCrdV2.HelmReleaseAuth
+	}{
+		{"default", helmCrdV2.RepositoryChartSource{}, "https://charts.internal/stable", defaultAuth},
+		{"defaulted by the webhook", helmCrdV2.RepositoryChartSource{URL: "https://charts.internal/stable/"}, "https://charts.internal/stable/", defaultAuth},
+		{"own auth", helmCrdV2.RepositoryChartSource{Auth: ownAuth}, "https://charts.internal/stable", ownAuth},
+		{"cloud provider", helmCrdV2.RepositoryChartSource{Auth: helmCrdV2.HelmReleaseAuth{Provider: helmCrdV2.AuthProviderGCP}}, "https://charts.internal/stable", helmCrdV2.HelmReleaseAuth{}},
+		{"other repository", helmCrdV2.RepositoryChartSource{URL: "https://charts.example.com"}, "https://charts.example.com", helmCrdV2.HelmReleaseAuth{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoURL, auth := c.repoURLAndAuth(&tt.repo)
+			if repoURL != tt.expectedURL || auth.Header != tt.expectedAuth.Header {
+				t.Errorf("Expecting %s with %+v, received %s with %+v", tt.expectedURL, tt.expectedAuth.Header, repoURL, auth.Header)
+			}
+		})
+	}
+}
+
+func TestAuthSecretNamespace(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}}
+	auth := func(scope helmCrdV2.AuthScope) helmCrdV2.HelmReleaseAuth {
+		return helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+			SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: "token"},
+			Scope:        scope,
+		}}
+	}
+	tests := []struct {
+		name              string
+		defaultScope      helmCrdV2.AuthScope
+		deny              bool
+		auth              helmCrdV2.HelmReleaseAuth
+		expectedNamespace string
+		expectedErr       bool
+	}{
+		{"no auth", helmCrdV2.AuthScopeNamespace, true, helmCrdV2.HelmReleaseAuth{}, defaultNamespace, false},
+		{"default controller scope", helmCrdV2.AuthScopeController, false, auth(""), defaultNamespace, false},
+		{"default namespace scope", helmCrdV2.AuthScopeNamespace, false, auth(""), "myns", false},
+		{"namespace scope", helmCrdV2.AuthScopeController, false, auth(helmCrdV2.AuthScopeNamespace), "myns", false},
+		{"controller scope", helmCrdV2.AuthScopeNamespace, false, auth(helmCrdV2.AuthScopeController), defaultNamespace, false},
+		{"cross-namespace denied", helmCrdV2.AuthScopeController, true, auth(""), "", true},
+		{"namespace scope with denial", helmCrdV2.AuthScopeController, true, auth(helmCrdV2.AuthScopeNamespace), "myns", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{defaultAuthScope: tt.defaultScope, denyCrossNamespaceAuth: tt.deny}
+			namespace, err := c.authSecretNamespace(h, tt.auth)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expecting error %v, received %v", tt.expectedErr, err)
+			}
+			if namespace != tt.expectedNamespace {
+				t.Errorf("Expecting namespace %q, received %q", tt.expectedNamespace, namespace)
+			}
+		})
+	}
+}
+
+func TestChartAuthHeadersProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"gcptoken","expires_in":3599}`)
+	}))
+	defer server.Close()
+	c := &Controller{cloudAuth: cloudauth.NewHelper(http.DefaultClient)}
+	c.cloudAuth.GCPMetadataURL = server.URL
+
+	header, err := c.chartAuthHeaders("myns", "https://storage.googleapis.com/mycharts", helmCrdV2.HelmReleaseAuth{Provider: helmCrdV2.AuthProviderGCP})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if auth := header.Get("Authorization"); auth != "Bearer gcptoken" {
+		t.Errorf("Expecting the access token of the pod, received %q", auth)
+	}
+}
+
+func TestChartAuthHeadersBearer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, _ := r.BasicAuth(); password != "client-secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"issued","expires_in":3600}`)
+	}))
+	defer server.Close()
+	c := prepareTestController(nil, []string{})
+	c.cloudAuth = cloudauth.NewHelper(http.DefaultClient)
+	c.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "creds"},
+		Data:       map[string][]byte{"token": []byte("static\n"), "clientSecret": []byte("client-secret")},
+	})
+	secretRef := func(key string) corev1.SecretKeySelector {
+		return corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: key}
+	}
+
+	tests := []struct {
+		name     string
+		bearer   helmCrdV2.HelmReleaseAuthBearer
+		expected string
+	}{
+		{"static token", helmCrdV2.HelmReleaseAuthBearer{SecretKeyRef: secretRef("token")}, "Bearer static"},
+		{"client credentials", helmCrdV2.HelmReleaseAuthBearer{SecretKeyRef: secretRef("clientSecret"), TokenURL: server.URL, ClientID: "controller"}, "Bearer issued"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, err := c.chartAuthHeaders("myns", "https://charts.example.com", helmCrdV2.HelmReleaseAuth{Bearer: &tt.bearer})
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if auth := header.Get("Authorization"); auth != tt.expected {
+				t.Errorf("Expecting %q, received %q", tt.expected, auth)
+			}
+		})
+	}
+}
+
+func TestChartAuthHeaders(t *testing.T) {
+	c := prepareTestController(nil, []string{})
+	c.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "creds"},
+		Data:       map[string][]byte{"token": []byte("static"), "apiKey": []byte("key\n"), "tenant": []byte("acme")},
+	})
+	secretRef := func(key string) corev1.SecretKeySelector {
+		return corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: key}
+	}
+
+	header, err := c.chartAuthHeaders("myns", "https://charts.example.com", helmCrdV2.HelmReleaseAuth{
+		Bearer: &helmCrdV2.HelmReleaseAuthBearer{SecretKeyRef: secretRef("token")},
+		Headers: map[string]helmCrdV2.HelmReleaseAuthHeader{
+			"x-api-key": {SecretKeyRef: secretRef("apiKey")},
+			"X-Tenant":  {SecretKeyRef: secretRef("tenant")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := http.Header{"Authorization": {"Bearer static"}, "X-Api-Key": {"key"}, "X-Tenant": {"acme"}}
+	if !reflect.DeepEqual(header, expected) {
+		t.Errorf("Expecting %v, received %v", expected, header)
+	}
+
+	if _, err := c.chartAuthHeaders("myns", "https://charts.example.com", helmCrdV2.HelmReleaseAuth{
+		Headers: map[string]helmCrdV2.HelmReleaseAuthHeader{"X-Api-Key": {SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}}}},
+	}); err == nil {
+		t.Errorf("Expecting headers of missing secrets to fail")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// releaseChecksum returns the checksum of the release of h with ch, as
+// post-rendered, and the merged values, rendered for the capabilities
+// set in the spec of h
+func releaseChecksum(h *helmCrdV2.HelmRelease, ch *chart.Chart, values []byte) (string, error) {
+	// Maps such as the chart annotations are encoded in key order, so that
+	// the same chart always has the same checksum
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(ch); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal([]interface{}{buf.Bytes(), values, h.Spec.KubeVersion, h.Spec.APIVersions})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// releaseUnchanged returns true if the deployed release of h is the
+// revision recorded in its status, deployed with checksum, so upgrading
+// it would change nothing. Forced syncs and drifted releases are always
+// upgraded.
+func releaseUnchanged(h *helmCrdV2.HelmRelease, deployed *release.Release, checksum string, drifted bool) bool {
+	if h.Status.ReleaseChecksum == "" || h.Status.ReleaseChecksum != checksum || drifted {
+		return false
+	}
+	if h.Annotations[helmCrdV2.ForceSyncAnnotation] != h.Status.LastForceSync {
+		return false
+	}
+	return deployed.GetVersion() == h.Status.Revision &&
+		deployed.GetInfo().GetStatus().GetCode() == release.Status_DEPLOYED
+}
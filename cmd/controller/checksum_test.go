@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUnchangedReleaseNotUpgraded(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+		// Deployed by a previous reconcile
+		Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+	reconcile := func() *helmCrdV2.HelmRelease {
+		if err := controller.updateRelease("myns/foo"); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+		controller.informer.GetIndexer().Update(res)
+		return res
+	}
+
+	// Releases deployed without a checksum are upgraded once
+	res := reconcile()
+	if len(fakeHelmClient(controller).Releases) != 2 {
+		t.Fatalf("Expecting the release to be upgraded, received releases %v", fakeHelmClient(controller).Releases)
+	}
+	if res.Status.ReleaseChecksum == "" || res.Status.Revision != 2 {
+		t.Errorf("Expecting the checksum of revision 2, received %+v", res.Status)
+	}
+
+	res = reconcile()
+	if len(fakeHelmClient(controller).Releases) != 2 {
+		t.Errorf("Expecting the resync to skip the upgrade, received releases %v", fakeHelmClient(controller).Releases)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonDeployed {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+
+	res.Annotations = map[string]string{helmCrdV2.ForceSyncAnnotation: "2026-10-15T12:00:00Z"}
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	reconcile()
+	if len(fakeHelmClient(controller).Releases) != 3 {
+		t.Errorf("Expecting force-synced releases to be upgraded, received releases %v", fakeHelmClient(controller).Releases)
+	}
+
+	res.Spec.Values = "replicas: 2\n"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	checksum := res.Status.ReleaseChecksum
+	res = reconcile()
+	if len(fakeHelmClient(controller).Releases) != 4 {
+		t.Errorf("Expecting changed values to be upgraded, received releases %v", fakeHelmClient(controller).Releases)
+	}
+	if res.Status.ReleaseChecksum == checksum {
+		t.Errorf("Expecting the checksum to change with the values")
+	}
+}
+
+func TestReleaseChecksumDeterministic(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{}
+	newChart := func() *chart.Chart {
+		annotations := map[string]string{}
+		for i := 0; i < 32; i++ {
+			annotations[fmt.Sprintf("example.com/annotation-%d", i)] = fmt.Sprintf("%d", i)
+		}
+		return &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0", Annotations: annotations}}
+	}
+	expected, err := releaseChecksum(h, newChart(), []byte("replicas: 1\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if checksum, err := releaseChecksum(h, newChart(), []byte("replicas: 1\n")); err != nil || checksum != expected {
+			t.Fatalf("Expecting the checksum %s of the same chart, received %s %v", expected, checksum, err)
+		}
+	}
+
+	ch := newChart()
+	ch.Metadata.Annotations["example.com/annotation-0"] = "changed"
+	if checksum, _ := releaseChecksum(h, ch, []byte("replicas: 1\n")); checksum == expected {
+		t.Errorf("Expecting changed annotations to change the checksum")
+	}
+}
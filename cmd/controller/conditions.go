@@ -0,0 +1,51 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// setCondition adds or replaces the condition of the same type, keeping
+// its transition time when the status is unchanged. The conditions slice
+// is copied since status may share it with an object from the cache.
+func setCondition(status *helmCrdV2.HelmReleaseStatus, cond helmCrdV2.HelmReleaseCondition) {
+	cond.LastTransitionTime = metav1.Now()
+	conditions := make([]helmCrdV2.HelmReleaseCondition, 0, len(status.Conditions)+1)
+	found := false
+	for _, c := range status.Conditions {
+		if c.Type == cond.Type {
+			if c.Status == cond.Status {
+				cond.LastTransitionTime = c.LastTransitionTime
+			}
+			c = cond
+			found = true
+		}
+		conditions = append(conditions, c)
+	}
+	if !found {
+		conditions = append(conditions, cond)
+	}
+	status.Conditions = conditions
+}
+
+// removeCondition removes the condition of the given type
+func removeCondition(status *helmCrdV2.HelmReleaseStatus, condType helmCrdV2.HelmReleaseConditionType) {
+	var conditions []helmCrdV2.HelmReleaseCondition
+	for _, c := range status.Conditions {
+		if c.Type != condType {
+			conditions = append(conditions, c)
+		}
+	}
+	status.Conditions = conditions
+}
+
+// getCondition returns the condition of the given type, or nil
+func getCondition(status *helmCrdV2.HelmReleaseStatus, condType helmCrdV2.HelmReleaseConditionType) *helmCrdV2.HelmReleaseCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
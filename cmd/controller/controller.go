@@ -1,28 +1,47 @@
 package main
 
 import (
+	"crypto"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
-	"google.golang.org/grpc"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/juju/ratelimit"
+	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/proto/hapi/release"
 
-	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
 	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/audit"
 	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cloudauth"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cosign"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/notify"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/opa"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/releasename"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/sops"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/tracing"
 )
 
 const (
@@ -30,7 +49,17 @@ const (
 	defaultRepoURL        = "https://kubernetes-charts.storage.googleapis.com"
 	releaseFinalizer      = "helm.bitnami.com/helmrelease"
 	defaultTimeoutSeconds = 180
-	maxRetries            = 5
+	// defaultMaxRetries is the number of retries of failed reconciles,
+	// unless overridden by --max-retries or spec.retries
+	defaultMaxRetries = 5
+	// maxNotesLen caps the release notes stored in the HelmRelease status
+	maxNotesLen = 4096
+	// defaultMaxChartSize is the size in bytes of the largest chart
+	// archive downloaded, unless overridden by --max-chart-size
+	defaultMaxChartSize = 20 << 20
+	// notificationQueueSize is the number of notifications waiting to be
+	// sent before further ones are dropped
+	notificationQueueSize = 100
 )
 
 // Controller is a cache.Controller for acting on Helm CRD objects
@@ -39,23 +68,173 @@ type Controller struct {
 	informer          cache.SharedIndexInformer
 	kubeClient        kubernetes.Interface
 	helmReleaseClient helmClientset.Interface
-	helmClient        helm.Interface
+	helmClient        helmclient.Interface
 	netClient         *chartUtils.HTTPClient
 	loadChart         chartUtils.LoadChart
+	objects           objectClient
+	// maxRetries is the number of retries of failed reconciles of
+	// HelmReleases not setting spec.retries
+	maxRetries int
+	// releaseTimeout is the Tiller timeout of the installs and upgrades of
+	// HelmReleases not setting spec.timeout
+	releaseTimeout time.Duration
+	// settingsLock guards maxRetries, releaseTimeout and defaultRepoURL,
+	// changed by the settings ConfigMap while reconciles run
+	settingsLock sync.RWMutex
+	// settingsConfigMap is the name of the ConfigMap of the controller
+	// namespace overriding flagSettings, if set
+	settingsConfigMap string
+	flagSettings      runtimeSettings
+	// globalValuesConfigMap is the name of the ConfigMap of the controller
+	// namespace whose values are merged into those of every release
+	globalValuesConfigMap string
+	// dryRun renders releases without changing anything in Tiller or the cluster
+	dryRun bool
+	// sopsKeys holds the private keys decrypting SOPS values
+	sopsKeys sops.Keys
+	// clusterDomain is substituted for ${CLUSTER_DOMAIN} in values
+	clusterDomain string
+	// notifier publishes release lifecycle events, if configured
+	notifier notify.Notifier
+	// auditSink records the Helm operations changing releases, if configured
+	auditSink audit.Sink
+	// tracer records reconcile spans, if configured
+	tracer *tracing.Tracer
+	// stalled are the specs of the HelmReleases given up on
+	stalled stalledSpecs
+	// tillerNamespace is where Tiller stores releases
+	tillerNamespace string
+	// storage holds the revisions of releases in tillerNamespace
+	storage revisionStorage
+	// tillerless is set when releases are applied by the controller
+	// rather than Tiller, in which case spec.tillerHost and
+	// spec.tillerNamespace are ignored
+	tillerless bool
+	// allowedTillerNamespaces and allowedTillerHosts are the
+	// spec.tillerNamespace and spec.tillerHost values HelmReleases may set
+	allowedTillerNamespaces []string
+	allowedTillerHosts      []string
+	// newTillerClient returns a client of the Tiller at a host:port, for
+	// HelmReleases not managed by the default Tiller
+	newTillerClient func(host string) helmclient.Interface
+	// tillerOptions configure the connections to the Tillers of
+	// HelmReleases
+	tillerOptions helmclient.TillerOptions
+	// tillerClients caches the clients of the Tillers of HelmReleases
+	tillerClients     map[string]helmclient.Interface
+	tillerClientsLock sync.Mutex
+	// tillerLimit bounds the concurrent operations of all Tillers, and
+	// tillerHostLimits those of each Tiller to maxTillerOpsPerHost.
+	// Unlimited if nil or zero.
+	tillerLimit         helmclient.Semaphore
+	maxTillerOpsPerHost int
+	tillerHostLimits    map[string]helmclient.Semaphore
+	// gcInterval is the period of deleting releases of deleted
+	// HelmReleases, disabled if zero
+	gcInterval time.Duration
+	// crdTimeout and crdPollInterval bound waiting for the CRDs of
+	// HelmReleases setting spec.installCRDsFirst to be established
+	crdTimeout      time.Duration
+	crdPollInterval time.Duration
+	// defaultRepoURL is the repository of charts not setting one
+	defaultRepoURL string
+	// defaultRepoAuth authenticates requests to defaultRepoURL for charts
+	// not setting their own auth
+	defaultRepoAuth helmCrdV2.HelmReleaseAuth
+	// labelResources labels the objects of releases with their release
+	// name, post-rendering every chart
+	labelResources bool
+	// defaultAuthScope is where the auth secrets of chart sources not
+	// setting a scope are read from
+	defaultAuthScope helmCrdV2.AuthScope
+	// denyCrossNamespaceAuth restricts HelmReleases to the auth secrets of
+	// their own namespace
+	denyCrossNamespaceAuth bool
+	// cloudAuth issues the credentials of chart sources setting a cloud
+	// provider
+	cloudAuth *cloudauth.Helper
+	// shard is the partition of the HelmReleases reconciled by this
+	// instance of the controller
+	shard shard
+	// fulcioRoots and rekorKey verify keyless chart signatures
+	fulcioRoots *x509.CertPool
+	rekorKey    crypto.PublicKey
+	// repoPolicy restricts the repositories charts are downloaded from
+	repoPolicy policy.RepoPolicy
+	// indexGroup shares the repository indexes fetched by concurrent
+	// reconciles
+	indexGroup chartUtils.IndexGroup
+	// maxChartSize is the size in bytes of the largest chart archive
+	// downloaded, unlimited if zero
+	maxChartSize int64
+	// regoConfigMap is the name of the ConfigMap of the controller
+	// namespace holding the Rego policies rendered releases are checked
+	// against before they are installed or upgraded, if set
+	regoConfigMap string
+	// regoLock guards policyEvaluator, compiled from the regoConfigMap at
+	// resource version regoVersion
+	regoLock        sync.Mutex
+	policyEvaluator opa.Evaluator
+	regoVersion     string
+	// restrictNamespace confines every HelmRelease to its own namespace,
+	// rejecting other target namespaces and cluster scoped objects
+	restrictNamespace bool
+	// policyInformer watches the HelmReleasePolicies constraining the
+	// HelmReleases of their namespace
+	policyInformer cache.SharedIndexInformer
+	// credentialInformer watches the RepositoryCredentials of the
+	// controller namespace authenticating chart downloads
+	credentialInformer cache.SharedIndexInformer
+	// setInformer and setQueue track the HelmReleaseSets stamping out
+	// HelmReleases, namespaceInformer the namespaces they select
+	setInformer       cache.SharedIndexInformer
+	setQueue          workqueue.RateLimitingInterface
+	namespaceInformer cache.SharedIndexInformer
+	// secretInformer and configMapInformer watch the objects referenced
+	// by HelmReleases, to reconcile them on change
+	secretInformer    cache.SharedIndexInformer
+	configMapInformer cache.SharedIndexInformer
+	// healthQueue holds the deployed HelmReleases whose workloads are
+	// checked until they are ready, with spec.healthCheck
+	healthQueue workqueue.RateLimitingInterface
+	// valuesCache holds the values files fetched from URLs, by URL and
+	// credentials
+	valuesCache     map[string]cachedValues
+	valuesCacheLock sync.Mutex
+	// workers reconcile the HelmReleases of the queue
+	workers *workerPool
+	// inFlight are the operations of the HelmReleases being reconciled
+	inFlight inFlightOperations
+	// healthClient sends the requests of URL health checks
+	healthClient chartUtils.HTTPClient
+	// defaultRecoveryPolicy recovers the releases left pending by
+	// interrupted operations, of HelmReleases not setting spec.recovery
+	defaultRecoveryPolicy helmCrdV2.RecoveryPolicy
+	// newRemoteCluster connects to the clusters of HelmReleases setting
+	// spec.kubeConfigSecretRef
+	newRemoteCluster func(config *rest.Config, tillerless bool, storageNamespace string) (*remoteCluster, error)
+	// remoteClusters caches the clients of remote clusters by kubeconfig
+	// Secret key
+	remoteClusters     map[string]*remoteCluster
+	remoteClustersLock sync.Mutex
+	// now returns the current time, checked against upgrade windows
+	now func() time.Time
+	// podLogs returns the last lines of the logs of a container, of
+	// failed hooks
+	podLogs func(namespace, pod, container string, tailLines int64) (string, error)
 }
 
 // NewController creates a Controller
-func NewController(clientset helmClientset.Interface, kubeClient kubernetes.Interface, helmClient helm.Interface, netClient chartUtils.HTTPClient, loadChart chartUtils.LoadChart) *Controller {
-	lw := cache.NewListWatchFromClient(clientset.HelmV1().RESTClient(), "helmreleases", metav1.NamespaceAll, fields.Everything())
-
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+func NewController(clientset helmClientset.Interface, kubeClient kubernetes.Interface, helmClient helmclient.Interface, netClient chartUtils.HTTPClient, loadChart chartUtils.LoadChart, resyncPeriod time.Duration, rateLimiter workqueue.RateLimiter) *Controller {
+	lw := cache.NewListWatchFromClient(clientset.HelmV2().RESTClient(), "helmreleases", metav1.NamespaceAll, fields.Everything())
 
 	informer := cache.NewSharedIndexInformer(
 		lw,
-		&helmCrdV1.HelmRelease{},
-		0, // No periodic resync
+		&helmCrdV2.HelmRelease{},
+		resyncPeriod,
 		cache.Indexers{},
 	)
+	queue := newPriorityQueue(rateLimiter, releasePriority(informer.GetIndexer()))
 
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -67,12 +246,15 @@ func NewController(clientset helmClientset.Interface, kubeClient kubernetes.Inte
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			key, err := cache.MetaNamespaceKeyFunc(newObj)
 			if err == nil {
-				newReleaseObj := newObj.(*helmCrdV1.HelmRelease)
-				oldReleaseObj := oldObj.(*helmCrdV1.HelmRelease)
+				newReleaseObj := newObj.(*helmCrdV2.HelmRelease)
+				oldReleaseObj := oldObj.(*helmCrdV2.HelmRelease)
 				if releaseObjChanged(oldReleaseObj, newReleaseObj) {
 					queue.Add(key)
+				} else if isResync(oldReleaseObj, newReleaseObj) && releaseNeedsResync(newReleaseObj) {
+					logger.With("helmrelease", key).Debugf("Resyncing")
+					queue.Add(key)
 				} else {
-					log.Printf("Ignoring update event on unchanged object %v", newReleaseObj)
+					logger.With("helmrelease", key).Debugf("Ignoring update event on unchanged object")
 				}
 			}
 		},
@@ -84,21 +266,119 @@ func NewController(clientset helmClientset.Interface, kubeClient kubernetes.Inte
 		},
 	})
 
-	return &Controller{
-		helmReleaseClient: clientset,
-		informer:          informer,
-		queue:             queue,
-		kubeClient:        kubeClient,
-		helmClient:        helmClient,
-		netClient:         &netClient,
-		loadChart:         loadChart,
+	policyLW := cache.NewListWatchFromClient(clientset.HelmV2().RESTClient(), "helmreleasepolicies", metav1.NamespaceAll, fields.Everything())
+	policyInformer := cache.NewSharedIndexInformer(
+		policyLW,
+		&helmCrdV2.HelmReleasePolicy{},
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	credentialLW := cache.NewListWatchFromClient(clientset.HelmV2().RESTClient(), "repositorycredentials", controllerNamespace(), fields.Everything())
+	credentialInformer := cache.NewSharedIndexInformer(credentialLW, &helmCrdV2.RepositoryCredential{}, resyncPeriod, cache.Indexers{})
+
+	setLW := cache.NewListWatchFromClient(clientset.HelmV2().RESTClient(), "helmreleasesets", metav1.NamespaceAll, fields.Everything())
+	setInformer := cache.NewSharedIndexInformer(setLW, &helmCrdV2.HelmReleaseSet{}, resyncPeriod, cache.Indexers{})
+	setQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	setInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+				setQueue.Add(key)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if key, err := cache.MetaNamespaceKeyFunc(newObj); err == nil {
+				setQueue.Add(key)
+			}
+		},
+	})
+	namespaceLW := cache.NewListWatchFromClient(kubeClient.Core().RESTClient(), "namespaces", metav1.NamespaceAll, fields.Everything())
+	namespaceInformer := cache.NewSharedIndexInformer(namespaceLW, &corev1.Namespace{}, resyncPeriod, cache.Indexers{})
+	secretLW := cache.NewListWatchFromClient(kubeClient.Core().RESTClient(), "secrets", metav1.NamespaceAll, fields.Everything())
+	secretInformer := cache.NewSharedIndexInformer(secretLW, &corev1.Secret{}, resyncPeriod, cache.Indexers{})
+	configMapLW := cache.NewListWatchFromClient(kubeClient.Core().RESTClient(), "configmaps", metav1.NamespaceAll, fields.Everything())
+	configMapInformer := cache.NewSharedIndexInformer(configMapLW, &corev1.ConfigMap{}, resyncPeriod, cache.Indexers{})
+
+	c := &Controller{
+		helmReleaseClient:  clientset,
+		informer:           informer,
+		policyInformer:     policyInformer,
+		credentialInformer: credentialInformer,
+		setInformer:        setInformer,
+		setQueue:           setQueue,
+		healthQueue:        workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		namespaceInformer:  namespaceInformer,
+		secretInformer:     secretInformer,
+		configMapInformer:  configMapInformer,
+		queue:              queue,
+		kubeClient:         kubeClient,
+		helmClient:         helmClient,
+		netClient:          &netClient,
+		loadChart:          loadChart,
+		objects:            &restObjectClient{discovery: kubeClient.Discovery()},
+		maxRetries:         defaultMaxRetries,
+		tillerNamespace:    defaultNamespace,
+		storage:            configMapStorage{kubeClient: kubeClient},
+		tillerOptions:      defaultTillerOptions,
+		tillerClients:      map[string]helmclient.Interface{},
+		tillerHostLimits:   map[string]helmclient.Semaphore{},
+		newRemoteCluster:   newRemoteCluster,
+		remoteClusters:     map[string]*remoteCluster{},
+		defaultRepoURL:     defaultRepoURL,
+		crdTimeout:         defaultCRDTimeout,
+		crdPollInterval:    defaultCRDPollInterval,
+		valuesCache:        map[string]cachedValues{},
+		now:                time.Now,
+		maxChartSize:       defaultMaxChartSize,
+		defaultAuthScope:   helmCrdV2.AuthScopeController,
+		cloudAuth:          cloudauth.NewHelper(&http.Client{Timeout: 30 * time.Second}),
+		healthClient:       &http.Client{Timeout: healthCheckTimeout},
 	}
+	c.newTillerClient = c.dialTiller
+	c.podLogs = c.fetchPodLogs
+	c.workers = &workerPool{size: defaultWorkers, work: c.processNextItem}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.enqueueConflicting,
+	})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueSet,
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueSet(newObj) },
+		DeleteFunc: c.enqueueSet,
+	})
+	namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueSelectingSets,
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueSelectingSets(newObj) },
+		DeleteFunc: c.enqueueSelectingSets,
+	})
+	policyInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueNamespace,
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueueNamespace(newObj) },
+		DeleteFunc: c.enqueueNamespace,
+	})
+	credentialInformer.AddEventHandler(c.credentialHandler())
+	secretInformer.AddEventHandler(c.referenceHandler("Secret"))
+	configMapInformer.AddEventHandler(c.referenceHandler("ConfigMap"))
+	configMapInformer.AddEventHandler(c.settingsHandler())
+	configMapInformer.AddEventHandler(c.globalValuesHandler())
+	configMapInformer.AddEventHandler(c.regoHandler())
+	return c
+}
+
+// newRateLimiter returns a workqueue.DefaultControllerRateLimiter with
+// the given per-item exponential backoff bounds
+func newRateLimiter(baseDelay, maxDelay time.Duration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		// 10 qps, 100 bucket size, overall rather than per item
+		&workqueue.BucketRateLimiter{Bucket: ratelimit.NewBucketWithRate(float64(10), int64(100))},
+	)
 }
 
 // HasSynced returns true once this controller has completed an
 // initial resource listing
 func (c *Controller) HasSynced() bool {
-	return c.informer.HasSynced()
+	return c.informer.HasSynced() && c.policyInformer.HasSynced() && c.credentialInformer.HasSynced() && c.setInformer.HasSynced() &&
+		c.namespaceInformer.HasSynced() && c.secretInformer.HasSynced() && c.configMapInformer.HasSynced()
 }
 
 // LastSyncResourceVersion is the resource version observed when last
@@ -111,15 +391,32 @@ func (c *Controller) LastSyncResourceVersion() string {
 
 // Run begins processing items, and will continue until a value is
 // sent down stopCh.  It's an error to call Run more than once.  Run
-// blocks; call via go.
+// blocks; call via go.  Once stopCh is closed, Run returns when the
+// reconciles in flight, if any, finish.
 func (c *Controller) Run(stopCh <-chan struct{}) {
-	log.Print("Starting HelmReleases controller")
+	logger.Infof("Starting HelmReleases controller")
 
 	defer utilruntime.HandleCrash()
 
 	defer c.queue.ShutDown()
+	defer c.setQueue.ShutDown()
+	defer c.healthQueue.ShutDown()
 
 	go c.informer.Run(stopCh)
+	go c.policyInformer.Run(stopCh)
+	go c.credentialInformer.Run(stopCh)
+	go c.setInformer.Run(stopCh)
+	go c.namespaceInformer.Run(stopCh)
+	go c.secretInformer.Run(stopCh)
+	go c.configMapInformer.Run(stopCh)
+	// Stop taking items from the queue on shutdown, letting the reconcile
+	// in flight finish
+	go func() {
+		<-stopCh
+		c.queue.ShutDown()
+		c.setQueue.ShutDown()
+		c.healthQueue.ShutDown()
+	}()
 
 	// Set up a helm home dir sufficient to fool the rest of helm
 	// client code
@@ -132,17 +429,20 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		utilruntime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 		return
 	}
-	log.Print("Cache synchronised, starting main loop")
+	logger.Infof("Cache synchronised, starting main loop")
+	c.reloadSettings()
 
-	wait.Until(c.runWorker, time.Second, stopCh)
+	if c.gcInterval > 0 && c.shard.first() {
+		go wait.Until(c.collectGarbage, c.gcInterval, stopCh)
+	}
 
-	log.Print("Shutting down controller")
-}
+	go wait.Until(c.runSetWorker, time.Second, stopCh)
+	go wait.Until(c.runHealthWorker, time.Second, stopCh)
+	c.workers.start()
+	<-stopCh
+	c.workers.wait()
 
-func (c *Controller) runWorker() {
-	for c.processNextItem() {
-		// continue looping
-	}
+	logger.Infof("Shutting down controller")
 }
 
 func (c *Controller) processNextItem() bool {
@@ -152,37 +452,72 @@ func (c *Controller) processNextItem() bool {
 	}
 
 	defer c.queue.Done(key)
+	if c.queue.ShuttingDown() {
+		// Queued items are added again when the controller restarts
+		return false
+	}
+	if !c.shard.owns(key.(string)) {
+		// Reconciled by another controller instance
+		c.queue.Forget(key)
+		return true
+	}
 	err := c.updateRelease(key.(string))
+	reconcileCount.Add(1)
+	if err != nil {
+		reconcileErrorCount.Add(1)
+	}
 	if err == nil {
 		// No error, reset the ratelimit counters
 		c.queue.Forget(key)
-	} else if c.queue.NumRequeues(key) < maxRetries {
-		log.Printf("Error updating %s, will retry: %v", key, err)
+	} else if c.queue.NumRequeues(key) < c.retries(key.(string)) || errorReason(err) == reasonTillerUnavailable {
+		// Reconciles failing to reach Tiller are retried until it is back
+		logger.With("helmrelease", key, "error", err).Warnf("Error updating, will retry")
+		c.markNotReady(key.(string), err)
 		c.queue.AddRateLimited(key)
 	} else {
 		// err != nil and too many retries
-		log.Printf("Error updating %s, giving up: %v", key, err)
+		logger.With("helmrelease", key, "error", err).Errorf("Error updating, giving up")
 		c.queue.Forget(key)
+		c.markStalled(key.(string), err)
 		utilruntime.HandleError(err)
 	}
 
 	return true
 }
 
-func isNotFound(err error) bool {
-	// Ideally this would be `grpc.Code(err) == codes.NotFound`,
-	// but it seems helm doesn't return grpc codes
-	return strings.Contains(grpc.ErrorDesc(err), "not found")
+// retries returns the number of retries of failed reconciles of the
+// HelmRelease with key
+func (c *Controller) retries(key string) int {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
+	if err == nil && exists {
+		if r := obj.(*helmCrdV2.HelmRelease).Spec.Retries; r != nil {
+			return int(*r)
+		}
+	}
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	return c.maxRetries
 }
 
-func getReleaseName(r *helmCrdV1.HelmRelease) string {
-	rname := r.Spec.ReleaseName
-	if rname == "" {
-		rname = fmt.Sprintf("%s-%s", r.Namespace, r.Name)
-	}
+func getReleaseName(r *helmCrdV2.HelmRelease) string {
+	rname, _ := renderReleaseName(r)
 	return rname
 }
 
+// renderReleaseName returns the release name of r, rendered from its
+// template unless set
+func renderReleaseName(r *helmCrdV2.HelmRelease) (string, error) {
+	if r.Spec.ReleaseName != "" {
+		return r.Spec.ReleaseName, nil
+	}
+	return releasename.Render(r.Spec.ReleaseNameTemplate, r.Namespace, r.Name)
+}
+
+// indexURL returns the URL of the index of a chart repository
+func indexURL(repoURL string) string {
+	return strings.TrimSuffix(strings.TrimSpace(repoURL), "/") + "/index.yaml"
+}
+
 func findIndex(target string, s []string) int {
 	for i := range s {
 		if s[i] == target {
@@ -201,14 +536,56 @@ func removeIndex(i int, s []string) []string {
 	return s[:lastIdx]
 }
 
-func releaseObjChanged(old, new *helmCrdV1.HelmRelease) bool {
+func releaseObjChanged(old, new *helmCrdV2.HelmRelease) bool {
 	// If the object deletion timestamp is set, then process
 	if old.DeletionTimestamp != new.DeletionTimestamp {
 		return true
 	}
+	for _, a := range []string{helmCrdV2.ForceSyncAnnotation, helmCrdV2.RollbackRevisionAnnotation, helmCrdV2.MigrateToHelm3Annotation} {
+		if old.Annotations[a] != new.Annotations[a] {
+			return true
+		}
+	}
+	// Propagated labels and annotations are deployed with the release
+	oldLabels, oldAnnotations := propagatedMetadata(old)
+	newLabels, newAnnotations := propagatedMetadata(new)
+	if !apiequality.Semantic.DeepEqual(oldLabels, newLabels) || !apiequality.Semantic.DeepEqual(oldAnnotations, newAnnotations) {
+		return true
+	}
 	return !apiequality.Semantic.DeepEqual(old.Spec, new.Spec)
 }
 
+// isResync returns true if the update event was triggered by the
+// informer periodic resync rather than by a change in the object
+func isResync(old, new *helmCrdV2.HelmRelease) bool {
+	return old.ResourceVersion == new.ResourceVersion
+}
+
+// driftDetectionEnabled returns whether the live objects of the release
+// of h are compared with its manifest
+func driftDetectionEnabled(h *helmCrdV2.HelmRelease) bool {
+	return h.Spec.DriftDetection != nil && h.Spec.DriftDetection.Mode != ""
+}
+
+// releaseNeedsResync returns true if an unchanged object should still be
+// reconciled on periodic resyncs
+func releaseNeedsResync(h *helmCrdV2.HelmRelease) bool {
+	// Deployed objects may have drifted from the release manifest
+	if driftDetectionEnabled(h) {
+		return true
+	}
+	// Ignored releases may be patched by hand
+	if ignored(h) {
+		return true
+	}
+	// Failed migrations to Helm 3 storage are retried
+	if helm3MigrationRequested(h) && h.Status.Helm3MigratedRevision != h.Status.Revision {
+		return true
+	}
+	// Version ranges and update policies may resolve to a newer chart version
+	return h.Spec.Chart.Repository != nil && (chartUtils.IsVersionRange(h.Spec.Chart.Repository.Version) || updatePolicyEnabled(h))
+}
+
 // remove item from slice without keeping order
 func remove(item string, s []string) ([]string, error) {
 	index := findIndex(item, s)
@@ -217,7 +594,7 @@ func remove(item string, s []string) ([]string, error) {
 	}
 	return removeIndex(index, s), nil
 }
-func hasFinalizer(h *helmCrdV1.HelmRelease) bool {
+func hasFinalizer(h *helmCrdV2.HelmRelease) bool {
 	currentFinalizers := h.ObjectMeta.Finalizers
 	for _, f := range currentFinalizers {
 		if f == releaseFinalizer {
@@ -227,7 +604,7 @@ func hasFinalizer(h *helmCrdV1.HelmRelease) bool {
 	return false
 }
 
-func removeFinalizer(helmObj *helmCrdV1.HelmRelease) *helmCrdV1.HelmRelease {
+func removeFinalizer(helmObj *helmCrdV2.HelmRelease) *helmCrdV2.HelmRelease {
 	helmObjClone := helmObj.DeepCopy()
 	newSlice, _ := remove(releaseFinalizer, helmObj.ObjectMeta.Finalizers)
 	if len(newSlice) == 0 {
@@ -237,18 +614,173 @@ func removeFinalizer(helmObj *helmCrdV1.HelmRelease) *helmCrdV1.HelmRelease {
 	return helmObjClone
 }
 
-func addFinalizer(helmObj *helmCrdV1.HelmRelease) *helmCrdV1.HelmRelease {
+// ensureFinalizer adds the finalizer deleting the release of h with it.
+// It is only added once h installs its release, or is known to own the
+// existing one, so that deleting a HelmRelease refused a release it
+// doesn't own leaves the release alone.
+func (c *Controller) ensureFinalizer(h *helmCrdV2.HelmRelease) (*helmCrdV2.HelmRelease, error) {
+	if hasFinalizer(h) {
+		return h, nil
+	}
+	updated, err := updateHelmRelease(c.helmReleaseClient, addFinalizer(h))
+	if err != nil {
+		logger.With("namespace", h.Namespace, "name", h.Name, "error", err).Errorf("Error adding finalizer")
+		return nil, err
+	}
+	return updated, nil
+}
+
+func addFinalizer(helmObj *helmCrdV2.HelmRelease) *helmCrdV2.HelmRelease {
 	helmObjClone := helmObj.DeepCopy()
 	helmObjClone.ObjectMeta.Finalizers = append(helmObjClone.ObjectMeta.Finalizers, releaseFinalizer)
 	return helmObjClone
 }
 
-func updateHelmRelease(helmReleaseClient helmClientset.Interface, helmObj *helmCrdV1.HelmRelease) error {
-	_, err := helmReleaseClient.HelmV1().HelmReleases(helmObj.Namespace).Update(helmObj)
-	return err
+func updateHelmRelease(helmReleaseClient helmClientset.Interface, helmObj *helmCrdV2.HelmRelease) (*helmCrdV2.HelmRelease, error) {
+	return helmReleaseClient.HelmV2().HelmReleases(helmObj.Namespace).Update(helmObj)
+}
+
+func (c *Controller) updateStatus(helmObj *helmCrdV2.HelmRelease, status helmCrdV2.HelmReleaseStatus) (*helmCrdV2.HelmRelease, error) {
+	if apiequality.Semantic.DeepEqual(helmObj.Status, status) {
+		return helmObj, nil
+	}
+	helmObjCopy := helmObj.DeepCopy()
+	helmObjCopy.Status = status
+	return updateHelmRelease(c.helmReleaseClient, helmObjCopy)
+}
+
+// setDeployedStatus records what Tiller reports as deployed for rel
+func setDeployedStatus(status *helmCrdV2.HelmReleaseStatus, rel *release.Release) {
+	status.Revision = rel.GetVersion()
+	status.ReleaseName = rel.GetName()
+	if meta := rel.GetChart().GetMetadata(); meta != nil {
+		status.Chart = meta.Name
+		status.ChartVersion = meta.Version
+		status.AppVersion = meta.AppVersion
+	}
+	if ts := rel.GetInfo().GetLastDeployed(); ts != nil {
+		if t, err := ptypes.Timestamp(ts); err == nil {
+			lastDeployed := metav1.NewTime(t)
+			status.LastDeployed = &lastDeployed
+		}
+	}
+	status.Notes = truncateNotes(rel.GetInfo().GetStatus().GetNotes())
+
+	objs, err := manifest.Objects(rel.GetManifest())
+	if err != nil {
+		logger.With("release", rel.GetName(), "error", err).Warnf("Unable to parse release manifest")
+		return
+	}
+	status.Resources = resourceReferences(objs)
+}
+
+// setDryRunStatus records the changes a dry-run release would make
+// compared to the deployed manifest
+func setDryRunStatus(status *helmCrdV2.HelmReleaseStatus, action, deployedManifest string, rel *release.Release) {
+	dryRun := &helmCrdV2.DryRunStatus{Action: action}
+	if meta := rel.GetChart().GetMetadata(); meta != nil {
+		dryRun.ChartVersion = meta.Version
+	}
+	added, changed, removed, err := manifest.Changes(deployedManifest, rel.GetManifest())
+	if err != nil {
+		logger.With("release", rel.GetName(), "error", err).Warnf("Unable to compare release manifests")
+	} else {
+		dryRun.Added = resourceReferences(added)
+		dryRun.Changed = resourceReferences(changed)
+		dryRun.Removed = resourceReferences(removed)
+	}
+	status.DryRun = dryRun
+}
+
+func resourceReferences(objs []manifest.Object) []helmCrdV2.ResourceReference {
+	var refs []helmCrdV2.ResourceReference
+	for _, obj := range objs {
+		refs = append(refs, helmCrdV2.ResourceReference{
+			APIVersion: obj.APIVersion,
+			Kind:       obj.Kind,
+			Namespace:  obj.Namespace,
+			Name:       obj.Name,
+		})
+	}
+	return refs
+}
+
+// deleteRelease uninstalls the release of h as configured by its
+// spec.uninstall, and checks Tiller no longer reports it as deployed
+func (c *Controller) deleteRelease(h *helmCrdV2.HelmRelease) error {
+	rlsName := getReleaseName(h)
+	opts := helmclient.DeleteOptions{Purge: true}
+	if u := h.Spec.Uninstall; u != nil {
+		if u.Purge != nil {
+			opts.Purge = *u.Purge
+		}
+		opts.Timeout = u.Timeout
+		opts.DisableHooks = u.DisableHooks
+	}
+	opts.DisableHooks = opts.DisableHooks || h.Spec.DisableHooks
+	helmClient, err := c.helmClientFor(h)
+	if err != nil {
+		return err
+	}
+	if err := helmClient.Delete(rlsName, opts); err != nil && !helmclient.IsNotFound(err) {
+		return err
+	}
+
+	status, err := helmClient.Status(rlsName)
+	if helmclient.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to check deletion of release %s: %v", rlsName, err)
+	}
+	if code := status.GetCode(); code != release.Status_DELETED {
+		return fmt.Errorf("release %s is still %s after deletion", rlsName, code)
+	}
+	return nil
+}
+
+// notify publishes a lifecycle event of the release of h, if
+// notifications are configured
+func (c *Controller) notify(h *helmCrdV2.HelmRelease, eventType notify.EventType, chartName, version, message string) {
+	if c.notifier == nil {
+		return
+	}
+	e := notify.Event{
+		Type:      eventType,
+		Namespace: h.Namespace,
+		Name:      h.Name,
+		Release:   getReleaseName(h),
+		Chart:     chartName,
+		Version:   version,
+		Message:   message,
+	}
+	if err := c.notifier.Notify(e); err != nil {
+		logger.With("namespace", h.Namespace, "name", h.Name, "event", eventType, "error", err).Warnf("Unable to send notification")
+	}
+}
+
+func truncateNotes(notes string) string {
+	const marker = "\n[truncated]"
+	if len(notes) <= maxNotesLen {
+		return notes
+	}
+	cut := maxNotesLen - len(marker)
+	// Don't split a multi-byte character
+	for cut > 0 && !utf8.RuneStart(notes[cut]) {
+		cut--
+	}
+	return notes[:cut] + marker
 }
 
 func (c *Controller) updateRelease(key string) error {
+	defer c.inFlight.clear(key)
+	span := c.tracer.Start(nil, "reconcile", "helmrelease", key)
+	err := c.reconcile(key, span)
+	span.End(err)
+	return err
+}
+
+func (c *Controller) reconcile(key string, span *tracing.Span) error {
 	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
 	if err != nil {
 		return fmt.Errorf("error fetching object with key %s from store: %v", key, err)
@@ -256,123 +788,586 @@ func (c *Controller) updateRelease(key string) error {
 
 	// this is an update when Function API object is actually deleted, we dont need to process anything here
 	if !exists {
-		log.Printf("HelmRelease object %s not found in the cache, ignoring the deletion update", key)
+		logger.With("helmrelease", key).Debugf("HelmRelease not found in the cache, ignoring the deletion update")
 		return nil
 	}
 
-	helmObj := obj.(*helmCrdV1.HelmRelease)
+	helmObj := obj.(*helmCrdV2.HelmRelease)
+	rlog := logger.With("namespace", helmObj.Namespace, "name", helmObj.Name, "release", getReleaseName(helmObj))
 
 	if helmObj.ObjectMeta.DeletionTimestamp != nil {
-		log.Printf("HelmRelease %s marked to be deleted, uninstalling chart", key)
+		rlog.Infof("HelmRelease marked to be deleted, uninstalling chart")
 		// If finalizer is removed, then we already processed the delete update, so just return
 		if !hasFinalizer(helmObj) {
 			return nil
 		}
-		_, err = c.helmClient.DeleteRelease(getReleaseName(helmObj), helm.DeletePurge(true))
-		if err != nil {
-			return err
+		if c.dryRun {
+			// Keep the finalizer so the release is uninstalled once out of dry-run
+			rlog.Infof("Dry-run: would delete release")
+			return nil
+		}
+		// Releases patched by hand are left as they are
+		if ignored(helmObj) {
+			rlog.Infof("Release is ignored, removing the finalizer without deleting it")
+		} else if helmObj.Status.Phase != helmCrdV2.PhaseDeleted {
+			helmObj, err = c.deleteHelmRelease(helmObj, span, rlog)
+			if err != nil {
+				return err
+			}
 		}
 
 		// remove finalizer from the function object, so that we dont have to process any further and object can be deleted
 		helmObjCopy := removeFinalizer(helmObj)
-		err = updateHelmRelease(c.helmReleaseClient, helmObjCopy)
+		_, err = updateHelmRelease(c.helmReleaseClient, helmObjCopy)
 		if err != nil {
-			log.Printf("Failed to remove finalizer for obj: %s object due to: %v: ", key, err)
+			rlog.With("error", err).Errorf("Failed to remove finalizer")
 			return err
 		}
-		log.Printf("Release %s has been successfully processed and marked for deletion", key)
+		if !ignored(helmObj) {
+			rlog.Infof("Release has been successfully deleted")
+		}
 		return nil
 	}
 
-	if !hasFinalizer(helmObj) {
-		helmObjCopy := addFinalizer(helmObj)
-		err = updateHelmRelease(c.helmReleaseClient, helmObjCopy)
+	helmObj, err = c.clearStalled(key, helmObj)
+	if err != nil {
+		return err
+	}
+
+	if err := checkReleaseName(helmObj); err != nil {
+		rlog.With("error", err).Warnf("Invalid release name")
+		return c.rejectRelease(helmObj, reasonInvalidReleaseName, err)
+	}
+	if other := c.conflictingRelease(helmObj); other != nil {
+		rlog.With("helmrelease", other.Namespace+"/"+other.Name).Warnf("Release managed by another HelmRelease")
+		return c.rejectConflict(helmObj, other)
+	}
+
+	if helmObj.Status.Phase == "" {
+		helmObj, err = c.setPhase(helmObj, helmCrdV2.PhasePending)
 		if err != nil {
-			log.Printf("Error adding finalizer to %s due to: %v: ", key, err)
 			return err
 		}
 	}
 
-	repoURL := helmObj.Spec.RepoURL
-	if repoURL == "" {
-		// FIXME: Make configurable
-		repoURL = defaultRepoURL
+	if cond := getCondition(&helmObj.Status, helmCrdV2.HelmReleaseInterrupted); cond != nil {
+		rlog.With("reason", cond.Message).Infof("Resuming interrupted release")
 	}
-	repoURL = strings.TrimSuffix(strings.TrimSpace(repoURL), "/") + "/index.yaml"
 
-	authHeader := ""
-	if helmObj.Spec.Auth.Header != nil {
-		namespace := os.Getenv("POD_NAMESPACE")
-		if namespace == "" {
-			namespace = defaultNamespace
-		}
+	if ignored(helmObj) {
+		return c.reconcileIgnored(helmObj, rlog)
+	}
+	if helmObj, err = c.clearIgnored(helmObj); err != nil {
+		return err
+	}
+
+	if helmObj.Spec.Suspend {
+		return c.reconcileSuspended(helmObj, rlog)
+	}
 
-		secret, err := c.kubeClient.Core().Secrets(namespace).Get(helmObj.Spec.Auth.Header.SecretKeyRef.Name, metav1.GetOptions{})
+	if err := c.checkTargetNamespace(helmObj); err != nil {
+		rlog.With("error", err).Warnf("Target namespace not allowed")
+		return c.rejectRelease(helmObj, reasonNamespaceRestricted, err)
+	}
+
+	if err := c.checkTiller(helmObj); err != nil {
+		rlog.With("error", err).Warnf("Tiller not allowed")
+		return c.rejectRelease(helmObj, reasonTillerNotAllowed, err)
+	}
+
+	if err := c.checkRepoPolicy(helmObj); err != nil {
+		rlog.With("error", err).Warnf("Chart source not allowed")
+		return c.rejectRelease(helmObj, reasonRepositoryNotAllowed, err)
+	}
+
+	var chartRequested *chart.Chart
+	var chartName, chartVersion, signedBy string
+	switch src := helmObj.Spec.ChartLocation(); {
+	case src.Tarball != nil:
+		chartRequested, err = c.fetchTarballChart(helmObj, src.Tarball, span, rlog)
+		if e, ok := err.(*chartUtils.RepoUnavailableError); ok {
+			return c.markRepoUnavailable(helmObj, key, e)
+		}
 		if err != nil {
-			return err
+			return fetchFailed(err)
 		}
-		authHeader = string(secret.Data[helmObj.Spec.Auth.Header.SecretKeyRef.Key])
+		chartName, chartVersion = chartRequested.GetMetadata().GetName(), chartRequested.GetMetadata().GetVersion()
+	case src.OCI != nil:
+		chartRequested, signedBy, err = c.fetchOCIChart(helmObj, src.OCI, span, rlog)
+		if e, ok := err.(*chartUtils.RepoUnavailableError); ok {
+			return c.markRepoUnavailable(helmObj, key, e)
+		}
+		if _, ok := err.(*cosign.VerificationError); ok {
+			rlog.With("error", err).Warnf("Chart signature not verified")
+			return c.rejectUnverified(helmObj, err)
+		}
+		if err != nil {
+			return fetchFailed(err)
+		}
+		chartName, chartVersion = chartRequested.GetMetadata().GetName(), chartRequested.GetMetadata().GetVersion()
+	case src.Repository != nil:
+		chartRequested, chartVersion, err = c.fetchRepositoryChart(helmObj, src.Repository, span, rlog)
+		if e, ok := err.(*chartUtils.RepoUnavailableError); ok {
+			return c.markRepoUnavailable(helmObj, key, e)
+		}
+		if err != nil {
+			return fetchFailed(err)
+		}
+		chartName = src.Repository.Name
+	case src.Inline != nil:
+		chartRequested = inlineChart(src.Inline)
+		chartName, chartVersion = src.Inline.Metadata.Name, src.Inline.Metadata.Version
+	default:
+		return fmt.Errorf("HelmRelease %s has no chart source", key)
 	}
+	rlog = rlog.With("chart", chartName, "version", chartVersion)
+	if err := c.checkReleasePolicies(helmObj, chartName, chartVersion); err != nil {
+		rlog.With("error", err).Warnf("Release not allowed")
+		return c.rejectRelease(helmObj, reasonPolicyViolation, err)
+	}
+	span.SetAttributes("release", getReleaseName(helmObj), "chart", chartName, "version", chartVersion)
 
-	log.Printf("Downloading repo %s index...", repoURL)
-	repoIndex, err := chartUtils.FetchRepoIndex(c.netClient, repoURL, authHeader)
+	values, fetchedValues, err := c.releaseValues(helmObj)
 	if err != nil {
 		return err
 	}
+	if err := validateValuesSchema(chartRequested, values); err != nil {
+		rlog.With("error", err).Warnf("Invalid values")
+		return c.rejectInvalidValues(helmObj, err)
+	}
 
-	chartURL, err := chartUtils.FindChartInRepoIndex(repoIndex, repoURL, helmObj.Spec.ChartName, helmObj.Spec.Version)
+	rlsName := getReleaseName(helmObj)
+	helmClient, err := c.helmClientFor(helmObj)
 	if err != nil {
 		return err
 	}
+	var rel *release.Release
+	var driftCondition *helmCrdV2.HelmReleaseCondition
+	dryRun := c.dryRun || helmObj.Spec.RenderOnly
+	action := "install"
+	deployedManifest := ""
 
-	log.Printf("Downloading %s ...", chartURL)
-	chartRequested, err := chartUtils.FetchChart(c.netClient, chartURL, authHeader, c.loadChart)
-	if err != nil {
+	s := c.tracer.Start(span, "tiller.history")
+	history, err := helmClient.History(rlsName, 1)
+	s.End(err)
+	if err != nil && !helmclient.IsNotFound(err) {
 		return err
 	}
+	if err == nil && len(history) > 0 && pendingOperation(history[0]) && !dryRun {
+		s = c.tracer.Start(span, "recoverPending")
+		proceed, err := c.recoverPending(helmObj, key, helmClient, history[0], rlog)
+		s.End(err)
+		if !proceed {
+			return err
+		}
+		history, err = helmClient.History(rlsName, 1)
+		if err != nil && !helmclient.IsNotFound(err) {
+			return err
+		}
+	}
+	if err == nil && len(history) > 0 && failedRelease(history[0]) && helmObj.Spec.Remediation != nil && !dryRun {
+		var proceed bool
+		s = c.tracer.Start(span, "remediate")
+		helmObj, proceed, err = c.remediate(helmObj, key, helmClient, history[0], rlog)
+		s.End(err)
+		if !proceed {
+			return err
+		}
+		history, err = helmClient.History(rlsName, 1)
+		if err != nil && !helmclient.IsNotFound(err) {
+			return err
+		}
+	}
+	deployed := err == nil && len(history) > 0
+	namespace := helmObj.Spec.TargetNamespace
+	if namespace == "" {
+		namespace = helmObj.Namespace
+	}
+	rlog = rlog.With("targetNamespace", namespace)
 
-	rlsName := getReleaseName(helmObj)
-	var rel *release.Release
+	caps, err := renderCapabilities(helmObj)
+	if err != nil {
+		return c.rejectRelease(helmObj, reasonInvalidCapabilities, err)
+	}
+
+	var lintWarnings []string
+	if helmObj.Spec.Lint != nil {
+		lintWarnings, err = lintChart(helmObj, chartRequested, rlsName, namespace, values, !deployed, caps)
+		if err != nil {
+			rlog.With("error", err, "warnings", lintWarnings).Warnf("Chart lint failed")
+			return c.rejectLintErrors(helmObj, lintWarnings, err)
+		}
+		if len(lintWarnings) > 0 {
+			rlog.With("warnings", lintWarnings).Infof("Chart lint warnings")
+		}
+	}
+
+	var crds []manifest.Object
+	if c.postRendered(helmObj) {
+		s = c.tracer.Start(span, "postRender")
+		chartRequested, crds, err = c.postRenderChart(helmObj, chartRequested, rlsName, namespace, values, deployed, caps)
+		s.End(err)
+		if err != nil {
+			return err
+		}
+	}
+
+	if helmObj.Spec.ServiceAccountName != "" {
+		var deployedRel *release.Release
+		if deployed {
+			deployedRel = history[0]
+		}
+		s = c.tracer.Start(span, "authorize")
+		err = c.authorizeRelease(helmObj, helmClient, chartRequested, namespace, values, deployedRel, caps)
+		s.End(err)
+		if err != nil {
+			if !c.dryRun {
+				c.recordEvent(helmObj, corev1.EventTypeWarning, "Forbidden", err.Error())
+			}
+			return err
+		}
+	}
 
-	h, err := c.helmClient.ReleaseHistory(rlsName, helm.WithMaxHistory(1))
-	if err != nil || len(h.GetReleases()) == 0 {
-		if err != nil && !isNotFound(err) {
+	if c.restrictNamespace {
+		s = c.tracer.Start(span, "restrictNamespace")
+		violations, err := c.namespaceViolations(helmObj, helmClient, chartRequested, namespace, values, deployed, crds, caps)
+		s.End(err)
+		if err != nil {
 			return err
 		}
-		log.Printf("Installing release %s into namespace %s", rlsName, helmObj.Namespace)
-		res, err := c.helmClient.InstallReleaseFromChart(
-			chartRequested,
-			helmObj.Namespace,
-			helm.ValueOverrides([]byte(helmObj.Spec.Values)),
-			helm.ReleaseName(rlsName),
-		)
+		if len(violations) > 0 {
+			rlog.With("objects", violations).Warnf("Release objects outside of its namespace")
+			return c.rejectNamespaceViolations(helmObj, violations)
+		}
+	}
+
+	evaluator, err := c.regoPolicies()
+	if err != nil {
+		return err
+	}
+	if evaluator != nil {
+		s = c.tracer.Start(span, "evaluatePolicies")
+		denials, err := c.evaluatePolicies(helmObj, evaluator, helmClient, chartRequested, namespace, values, deployed, crds, caps)
+		s.End(err)
 		if err != nil {
 			return err
 		}
-		rel = res.GetRelease()
+		if len(denials) > 0 {
+			rlog.With("denials", denials).Warnf("Release denied by policy")
+			return c.rejectDenied(helmObj, denials)
+		}
+	}
+
+	if helmObj.Spec.CreateNamespace && !dryRun {
+		s = c.tracer.Start(span, "ensureNamespace")
+		err = c.ensureNamespace(helmObj, namespace)
+		s.End(err)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(crds) > 0 && !dryRun {
+		rlog.Infof("Installing %d CustomResourceDefinitions", len(crds))
+		s = c.tracer.Start(span, "installCRDs")
+		err = c.installCRDs(helmObj, crds)
+		s.End(err)
+		if err != nil {
+			return err
+		}
+	}
+
+	checksum, err := releaseChecksum(helmObj, chartRequested, values)
+	if err != nil {
+		return err
+	}
+
+	if !deployed {
+		rlog.Infof("Installing release")
+		if !dryRun {
+			if helmObj, err = c.ensureFinalizer(helmObj); err != nil {
+				return err
+			}
+			if helmObj, err = c.startOperation(helmObj, helmCrdV2.PhaseInstalling, 1); err != nil {
+				return err
+			}
+		}
+		s = c.tracer.Start(span, "tiller.install", "dryRun", dryRun)
+		rel, err = helmClient.Install(chartRequested, namespace, helmclient.InstallOptions{
+			ReleaseName:  rlsName,
+			Values:       values,
+			Timeout:      c.timeout(helmObj),
+			DryRun:       dryRun,
+			DisableHooks: helmObj.Spec.DisableHooks,
+			Capabilities: caps,
+		})
+		s.End(err)
+		if helmclient.IsHooksNotSupported(err) {
+			return c.rejectRelease(helmObj, reasonHooksNotSupported, fmt.Errorf("%v, set spec.disableHooks to release it without them", err))
+		}
+		if err != nil {
+			if !dryRun {
+				c.notify(helmObj, notify.Failed, chartName, chartVersion, fmt.Sprintf("install failed: %v", err))
+				err = c.withHookDiagnostics(helmObj, helmClient, rlsName, err)
+			}
+			return failed(reasonInstallFailed, err)
+		}
 	} else {
-		log.Printf("Updating release %s", rlsName)
-		res, err := c.helmClient.UpdateReleaseFromChart(
-			rlsName,
-			chartRequested,
-			helm.UpdateValueOverrides([]byte(helmObj.Spec.Values)),
-			//helm.UpgradeForce(true), ?
-		)
+		action = "upgrade"
+		if err := c.checkOwnership(helmObj, history[0]); err != nil {
+			return err
+		}
+		if !dryRun {
+			if helmObj, err = c.ensureFinalizer(helmObj); err != nil {
+				return err
+			}
+		}
+		deployedManifest = history[0].GetManifest()
+		if driftDetectionEnabled(helmObj) {
+			cond, err := c.detectDrift(helmObj, history[0])
+			if err != nil {
+				rlog.With("error", err).Warnf("Unable to detect drift")
+			} else {
+				driftCondition = &cond
+			}
+		}
+
+		drifted := driftCondition != nil && driftCondition.Status == corev1.ConditionTrue
+		if !dryRun && releaseUnchanged(helmObj, history[0], checksum, drifted) {
+			// Resyncs skip Tiller when the chart and values are unchanged
+			rlog.Debugf("Release unchanged, skipping upgrade")
+			rel = history[0]
+		} else {
+			if helmObj.Spec.UpgradeWindow != nil && !dryRun {
+				opens, closed, err := c.upgradeDeferred(helmObj)
+				if err != nil {
+					return c.rejectRelease(helmObj, reasonInvalidUpgradeWindow, err)
+				}
+				if closed {
+					pending, err := upgradePending(helmClient, rlsName, chartRequested, values, history[0], caps)
+					if err != nil {
+						return err
+					}
+					if pending {
+						rlog.With("opens", opens).Infof("Upgrade deferred until the upgrade window opens")
+						return c.deferUpgrade(helmObj, key, chartVersion, opens)
+					}
+				}
+			}
+
+			if approvalRequired(helmObj, checksum) && !dryRun {
+				diff, full, err := diffUpgrade(helmClient, rlsName, chartRequested, values, history[0], caps)
+				if err != nil {
+					return err
+				}
+				if diff != nil {
+					rlog.With("checksum", checksum).Infof("Upgrade awaiting approval")
+					return c.awaitApproval(helmObj, diff, full, checksum)
+				}
+			}
+
+			if upgradeDiffEnabled(helmObj) && !dryRun {
+				s = c.tracer.Start(span, "diffUpgrade")
+				helmObj, err = c.recordUpgradeDiff(helmObj, helmClient, rlsName, chartRequested, values, history[0], caps)
+				s.End(err)
+				if err != nil {
+					return err
+				}
+			}
+
+			rlog.Infof("Updating release")
+			if !dryRun {
+				if helmObj, err = c.startOperation(helmObj, helmCrdV2.PhaseUpgrading, history[0].Version+1); err != nil {
+					return err
+				}
+			}
+			s = c.tracer.Start(span, "tiller.upgrade", "dryRun", dryRun)
+			rel, err = helmClient.Upgrade(rlsName, chartRequested, helmclient.UpgradeOptions{
+				Values:       values,
+				Timeout:      c.timeout(helmObj),
+				DryRun:       dryRun,
+				DisableHooks: helmObj.Spec.DisableHooks,
+				Capabilities: caps,
+			})
+			s.End(err)
+			if helmclient.IsHooksNotSupported(err) {
+				return c.rejectRelease(helmObj, reasonHooksNotSupported, fmt.Errorf("%v, set spec.disableHooks to release it without them", err))
+			}
+			if err != nil {
+				if !dryRun {
+					c.notify(helmObj, notify.Failed, chartName, chartVersion, fmt.Sprintf("upgrade failed: %v", err))
+					err = c.withHookDiagnostics(helmObj, helmClient, rlsName, err)
+				}
+				if rb := helmObj.Spec.Rollback; rb != nil && rb.Enable && !dryRun {
+					rlog.With("error", err).Warnf("Upgrade failed, rolling back")
+					s = c.tracer.Start(span, "tiller.rollback")
+					_, rbErr := helmClient.Rollback(rlsName, helmclient.RollbackOptions{
+						Recreate:     rb.Recreate,
+						Force:        rb.Force,
+						DisableHooks: helmObj.Spec.DisableHooks,
+					})
+					s.End(rbErr)
+					if rbErr != nil {
+						rlog.With("error", rbErr).Errorf("Unable to roll back release")
+					}
+				}
+				return failed(reasonUpgradeFailed, err)
+			}
+			changed := rel.GetManifest() != deployedManifest || chartVersion != helmObj.Status.ChartVersion
+			if t := helmObj.Spec.Test; t != nil && t.Enable && !dryRun && changed {
+				if err := c.testUpgrade(helmObj, helmClient, rlsName, span, rlog); err != nil {
+					c.notify(helmObj, notify.Failed, chartName, chartVersion, err.Error())
+					return c.rejectRelease(helmObj, reasonTestFailed, err)
+				}
+			}
+		}
+	}
+
+	status := helmObj.Status
+	status.ResolvedVersion = chartVersion
+	logFetchedValuesChanges(rlog, status.FetchedValues, fetchedValues)
+	status.FetchedValues = fetchedValues
+	status.LintWarnings = lintWarnings
+	status.SignedBy = signedBy
+	removeCondition(&status, helmCrdV2.HelmReleaseStalled)
+	removeCondition(&status, helmCrdV2.HelmReleaseInterrupted)
+	removeCondition(&status, helmCrdV2.HelmReleaseConflict)
+	removeCondition(&status, helmCrdV2.HelmReleaseValuesInvalid)
+	removeCondition(&status, helmCrdV2.HelmReleasePolicyDenied)
+	removeCondition(&status, helmCrdV2.HelmReleaseUpgradeDeferred)
+	removeCondition(&status, helmCrdV2.HelmReleaseRepoUnavailable)
+	removeCondition(&status, helmCrdV2.HelmReleaseLintFailed)
+	removeCondition(&status, helmCrdV2.HelmReleasePendingApproval)
+	removeCondition(&status, helmCrdV2.HelmReleaseVerificationFailed)
+	c.stalled.remove(key)
+	if driftCondition != nil {
+		setCondition(&status, *driftCondition)
+	} else if !driftDetectionEnabled(helmObj) {
+		removeCondition(&status, helmCrdV2.HelmReleaseDrifted)
+	}
+
+	if c.dryRun {
+		rlog.Infof("Dry-run: would %s release", action)
+		setDryRunStatus(&status, action, deployedManifest, rel)
+		_, err = c.updateStatus(helmObj, status)
+		return err
+	}
+	status.DryRun = nil
+
+	if helmObj.Spec.RenderOnly {
+		rlog.Infof("Storing rendered manifests")
+		ref, err := c.storeRenderedManifests(helmObj, rel)
 		if err != nil {
 			return err
 		}
-		rel = res.GetRelease()
+		status.RenderedManifests = ref
+		setDeployed(&status, helmObj)
+		setReady(&status, reasonRendered, fmt.Sprintf("Manifests of chart version %s rendered", chartVersion))
+		_, err = c.updateStatus(helmObj, status)
+		return err
 	}
+	status.RenderedManifests = nil
 
-	status, err := c.helmClient.ReleaseStatus(rel.Name)
+	rlsStatus, err := helmClient.Status(rel.Name)
 	if err == nil {
-		log.Printf("Installed/updated release %s", rel.Name)
-		if status.Info != nil && status.Info.Status != nil {
-			log.Printf("Release status: %s", status.Info.Status.Code)
-		}
+		rlog.With("status", rlsStatus.GetCode().String()).Infof("Installed/updated release")
 	} else {
-		log.Printf("Unable to fetch release status for %s: %v", rel.Name, err)
+		rlog.With("error", err).Warnf("Unable to fetch release status")
+	}
+
+	if action == "install" {
+		c.notify(helmObj, notify.Installed, chartName, chartVersion, "")
+	} else if rel.GetManifest() != deployedManifest || chartVersion != helmObj.Status.ChartVersion {
+		// Resyncs upgrade releases without changing them
+		c.notify(helmObj, notify.Upgraded, chartName, chartVersion, "")
+	}
+
+	if err := c.labelReleaseStorage(helmObj, rel); err != nil {
+		rlog.With("error", err).Warnf("Unable to label Tiller release storage")
+	}
+	if helm3MigrationRequested(helmObj) {
+		if err := c.migrateToHelm3(helmObj, helmClient, rel); err != nil {
+			rlog.With("error", err).Warnf("Unable to migrate release to Helm 3 storage")
+			c.recordEvent(helmObj, corev1.EventTypeWarning, reasonHelm3MigrationFailed, err.Error())
+		} else {
+			if status.Helm3MigratedRevision == 0 {
+				c.recordEvent(helmObj, corev1.EventTypeNormal, reasonMigratedToHelm3, fmt.Sprintf("Release %s copied to Helm 3 storage", rel.GetName()))
+			}
+			status.Helm3MigratedRevision = rel.GetVersion()
+		}
 	}
 
+	if updatePolicyEnabled(helmObj) && action == "upgrade" && helmObj.Status.ChartVersion != "" && chartVersion != helmObj.Status.ChartVersion {
+		c.recordChartUpdate(helmObj, &status, helmObj.Status.ChartVersion, chartVersion)
+	}
+	outputs, outputsErr := c.exportOutputs(helmObj, values)
+	if outputsErr == nil {
+		status.Outputs = outputs
+	}
+
+	setDeployedStatus(&status, rel)
+	status.ReleaseChecksum = checksum
+	setDeployed(&status, helmObj)
+	var healthPoll time.Duration
+	if healthCheckEnabled(helmObj) {
+		healthPoll = c.assessHealth(helmObj, &status)
+	} else {
+		removeCondition(&status, helmCrdV2.HelmReleaseHealthy)
+		setReady(&status, reasonDeployed, fmt.Sprintf("Release %s revision %d deployed", rel.GetName(), rel.GetVersion()))
+	}
+	if _, err = c.updateStatus(helmObj, status); err != nil {
+		return err
+	}
+	if healthPoll > 0 {
+		c.healthQueue.AddAfter(key, healthPoll)
+	}
+	if outputsErr != nil {
+		// Retried, e.g. until a Secret generated by the chart exists
+		return failed(reasonOutputsFailed, outputsErr)
+	}
 	return nil
 }
+
+// deleteHelmRelease deletes, or retains, the release of h according to its
+// deletion policy, returning the updated HelmRelease
+func (c *Controller) deleteHelmRelease(h *helmCrdV2.HelmRelease, span *tracing.Span, rlog *logging.Logger) (*helmCrdV2.HelmRelease, error) {
+	h, err := c.setPhase(h, helmCrdV2.PhaseDeleting)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := c.ownsRelease(h)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasConflict(h):
+		rlog.Infof("Release managed by another HelmRelease, not deleting it")
+	case !owned:
+		rlog.Infof("Release not owned by the HelmRelease, not deleting it")
+	case h.Spec.DeletionPolicy == helmCrdV2.DeletionPolicyRetain:
+		rlog.Infof("Retaining release")
+		if err := c.unlabelReleaseStorage(h); err != nil {
+			return nil, err
+		}
+	case h.Spec.DeletionPolicy == helmCrdV2.DeletionPolicyDeleteHistoryOnly:
+		rlog.Infof("Deleting release history, retaining its objects")
+		if err := c.deleteReleaseStorage(h); err != nil {
+			return nil, err
+		}
+	default:
+		s := c.tracer.Start(span, "tiller.delete")
+		err = c.deleteRelease(h)
+		s.End(err)
+		if err != nil {
+			return nil, failed(reasonDeleteFailed, err)
+		}
+		// Record the deletion before notifying it, so that retries
+		// removing the finalizer don't notify it again
+		h, err = c.setPhase(h, helmCrdV2.PhaseDeleted)
+		if err != nil {
+			return nil, err
+		}
+		c.notify(h, notify.Deleted, h.Status.Chart, h.Status.ChartVersion, "")
+	}
+	return h, nil
+}
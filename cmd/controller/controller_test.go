@@ -7,17 +7,26 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
-	helmCRDApi "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
-	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCRDApi "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
 	helmCRDFake "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/fake"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/notify"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/tracing"
+	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
-	"k8s.io/helm/pkg/helm"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/proto/hapi/release"
 	"k8s.io/helm/pkg/repo"
@@ -25,11 +34,11 @@ import (
 
 func TestAddFinalizer(t *testing.T) {
 	tests := []struct {
-		src               *helmCrdV1.HelmRelease
+		src               *helmCrdV2.HelmRelease
 		expectedFinalizer []string
 	}{
-		{&helmCrdV1.HelmRelease{}, []string{"helm.bitnami.com/helmrelease"}},
-		{&helmCrdV1.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"foo"}}}, []string{"foo", "helm.bitnami.com/helmrelease"}},
+		{&helmCrdV2.HelmRelease{}, []string{"helm.bitnami.com/helmrelease"}},
+		{&helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"foo"}}}, []string{"foo", "helm.bitnami.com/helmrelease"}},
 	}
 	for _, tt := range tests {
 		res := addFinalizer(tt.src)
@@ -41,11 +50,11 @@ func TestAddFinalizer(t *testing.T) {
 
 func TestRemoveFinalizer(t *testing.T) {
 	tests := []struct {
-		src               *helmCrdV1.HelmRelease
+		src               *helmCrdV2.HelmRelease
 		expectedFinalizer []string
 	}{
-		{&helmCrdV1.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"helm.bitnami.com/helmrelease"}}}, []string{}},
-		{&helmCrdV1.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"foo", "helm.bitnami.com/helmrelease", "bar"}}}, []string{"foo", "bar"}},
+		{&helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"helm.bitnami.com/helmrelease"}}}, []string{}},
+		{&helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"foo", "helm.bitnami.com/helmrelease", "bar"}}}, []string{"foo", "bar"}},
 	}
 	for _, tt := range tests {
 		res := removeFinalizer(tt.src)
@@ -84,7 +93,7 @@ func (f *fakeHTTPClient) Do(h *http.Request) (*http.Response, error) {
 }
 
 func fakeLoadChart(in io.Reader) (*chart.Chart, error) {
-	return &chart.Chart{}, nil
+	return &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"}}, nil
 }
 
 func prepareTestController(hrs []helmCRDApi.HelmRelease, existingTillerReleases []string) *Controller {
@@ -93,30 +102,43 @@ func prepareTestController(hrs []helmCRDApi.HelmRelease, existingTillerReleases
 	entries := map[string]repo.ChartVersions{}
 	var hrObjects []runtime.Object
 	for _, hr := range hrs {
-		repoURLs = append(repoURLs, hr.Spec.RepoURL)
-		chartMeta := chart.Metadata{Name: hr.Spec.ChartName, Version: hr.Spec.Version}
-		chartURL := fmt.Sprintf("%s%s-%s.tgz", hr.Spec.RepoURL, hr.Spec.ChartName, hr.Spec.Version)
+		if tarball := hr.Spec.ChartLocation().Tarball; tarball != nil {
+			chartURLs = append(chartURLs, tarball.URL)
+			hrObjects = append(hrObjects, &hr)
+			continue
+		}
+		// OCI charts are pulled from registries served by the tests, inline
+		// charts aren't downloaded
+		if hr.Spec.Chart.OCI != nil || hr.Spec.Chart.Inline != nil {
+			hrObjects = append(hrObjects, &hr)
+			continue
+		}
+		chartSrc := hr.Spec.Chart.Repository
+		repoURLs = append(repoURLs, chartSrc.URL)
+		chartMeta := chart.Metadata{Name: chartSrc.Name, Version: chartSrc.Version}
+		chartURL := fmt.Sprintf("%s%s-%s.tgz", chartSrc.URL, chartSrc.Name, chartSrc.Version)
 		chartURLs = append(chartURLs, chartURL)
 		chartVersion := repo.ChartVersion{Metadata: &chartMeta, URLs: []string{chartURL}}
 		chartVersions := []*repo.ChartVersion{&chartVersion}
-		entries[hr.Spec.ChartName] = chartVersions
+		entries[chartSrc.Name] = chartVersions
 		hrObjects = append(hrObjects, &hr)
 	}
 	index := &repo.IndexFile{APIVersion: "v1", Generated: time.Now(), Entries: entries}
 	netClient := fakeHTTPClient{repoURLs, chartURLs, index}
-	helmClient := helm.FakeClient{}
-	for _, r := range existingTillerReleases {
-		helmClient.Rels = append(helmClient.Rels, &release.Release{Name: r})
-	}
+	helmClient := helmclient.NewFakeClient(existingTillerReleases...)
 	clientset := helmCRDFake.NewSimpleClientset(hrObjects...)
 	kubeClient := fake.NewSimpleClientset()
-	controller := NewController(clientset, kubeClient, &helmClient, &netClient, fakeLoadChart)
+	controller := NewController(clientset, kubeClient, helmClient, &netClient, fakeLoadChart, 0, workqueue.DefaultControllerRateLimiter())
 	for _, hr := range hrs {
 		controller.informer.GetIndexer().Add(&hr)
 	}
 	return controller
 }
 
+func fakeHelmClient(c *Controller) *helmclient.FakeClient {
+	return c.helmClient.(*helmclient.FakeClient)
+}
+
 func TestHelmReleaseAdded(t *testing.T) {
 	myNsFoo := metav1.ObjectMeta{
 		Namespace: "myns",
@@ -125,9 +147,11 @@ func TestHelmReleaseAdded(t *testing.T) {
 	h := helmCRDApi.HelmRelease{
 		ObjectMeta: myNsFoo,
 		Spec: helmCRDApi.HelmReleaseSpec{
-			RepoURL:   "http://charts.example.com/repo/",
-			ChartName: "foo",
-			Version:   "v1.0.0",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "v1.0.0",
+			}},
 		},
 	}
 	expectedRelease := fmt.Sprintf("%s-%s", myNsFoo.Namespace, myNsFoo.Name)
@@ -137,18 +161,13 @@ func TestHelmReleaseAdded(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
-	rels, err := controller.helmClient.ListReleases()
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
-	}
-	if rels.Releases[0].Name != expectedRelease {
-		t.Errorf("Expected release named %s received %s", expectedRelease, rels.Releases[0].Name)
+	rels := fakeHelmClient(controller).Releases
+	if rels[0].Name != expectedRelease {
+		t.Errorf("Expected release named %s received %s", expectedRelease, rels[0].Name)
 	}
-	if rels.Releases[0].Namespace != myNsFoo.Namespace {
-		t.Errorf("Expected release in namespace %s received %s", myNsFoo.Namespace, rels.Releases[0].Namespace)
+	if rels[0].Namespace != myNsFoo.Namespace {
+		t.Errorf("Expected release in namespace %s received %s", myNsFoo.Namespace, rels[0].Namespace)
 	}
-	// We cannot check that the rest of the chart properties as properly set
-	// because the fake InstallReleaseFromChart ignores the given chart
 }
 
 func TestHelmReleaseAddedWithReleaseName(t *testing.T) {
@@ -160,23 +179,320 @@ func TestHelmReleaseAddedWithReleaseName(t *testing.T) {
 		ObjectMeta: myNsFoo,
 		Spec: helmCRDApi.HelmReleaseSpec{
 			ReleaseName: "not-foo",
-			RepoURL:     "http://charts.example.com/repo/",
-			ChartName:   "foo",
-			Version:     "v1.0.0",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "v1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+
+	err := controller.updateRelease("myns/foo")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	rels := fakeHelmClient(controller).Releases
+	if rels[0].Name != h.Spec.ReleaseName {
+		t.Errorf("Expected release named %s received %s", h.Spec.ReleaseName, rels[0].Name)
+	}
+}
+
+func TestHelmReleaseAddedWithVersionRange(t *testing.T) {
+	myNsFoo := metav1.ObjectMeta{
+		Namespace: "myns",
+		Name:      "foo",
+	}
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: myNsFoo,
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.2.3",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	// Request a range instead of the exact version served by the fake repo
+	h.Spec.Chart.Repository.Version = "^1.2.0"
+	controller.informer.GetIndexer().Update(&h)
+
+	err := controller.updateRelease("myns/foo")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res.Status.ResolvedVersion != "1.2.3" {
+		t.Errorf("Expected resolved version 1.2.3 received %s", res.Status.ResolvedVersion)
+	}
+}
+
+func TestHelmReleaseDeployedStatus(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+
+	err := controller.updateRelease("myns/foo")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res.Status.Chart != "foo" || res.Status.ChartVersion != "1.0.0" {
+		t.Errorf("Expected chart foo 1.0.0 received %s %s", res.Status.Chart, res.Status.ChartVersion)
+	}
+	if res.Status.ReleaseName != "myns-foo" {
+		t.Errorf("Expected release name myns-foo received %s", res.Status.ReleaseName)
+	}
+	if res.Status.Revision != 1 {
+		t.Errorf("Expected revision 1 received %d", res.Status.Revision)
+	}
+	if res.Status.LastDeployed == nil || res.Status.LastDeployed.Unix() != 242085845 {
+		t.Errorf("Unexpected last deployed time %v", res.Status.LastDeployed)
+	}
+	expectedResources := []helmCRDApi.ResourceReference{{APIVersion: "v1", Kind: "Secret", Name: "fixture"}}
+	if !apiequality.Semantic.DeepEqual(res.Status.Resources, expectedResources) {
+		t.Errorf("Expected resources %+v received %+v", expectedResources, res.Status.Resources)
+	}
+}
+
+type fakeNotifier struct {
+	events []notify.Event
+}
+
+func (f *fakeNotifier) Notify(e notify.Event) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func TestHelmReleaseNotifications(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", Finalizers: []string{releaseFinalizer}},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	notifier := &fakeNotifier{}
+	controller.notifier = notifier
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []notify.Event{{Type: notify.Installed, Namespace: "myns", Name: "foo", Release: "myns-foo", Chart: "foo", Version: "1.0.0"}}
+	if !apiequality.Semantic.DeepEqual(notifier.events, expected) {
+		t.Errorf("Expected events %+v received %+v", expected, notifier.events)
+	}
+
+	notifier.events = nil
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+		tillerConfigMap("myns-foo", "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "foo"}))
+	deleted := h.DeepCopy()
+	deleted.DeletionTimestamp = &metav1.Time{}
+	controller.informer.GetIndexer().Update(deleted)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Type != notify.Deleted {
+		t.Errorf("Expected a deleted event received %+v", notifier.events)
+	}
+
+	// Retries removing the finalizer don't notify the deletion again
+	notifier.events = nil
+	deleted.Status.Phase = helmCRDApi.PhaseDeleted
+	controller.informer.GetIndexer().Update(deleted)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(notifier.events) != 0 {
+		t.Errorf("Expected no events received %+v", notifier.events)
+	}
+}
+
+func TestHelmReleaseTracing(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						Name string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, span := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+			names = append(names, span.Name)
+		}
+	}))
+	defer server.Close()
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	controller.tracer = tracing.NewTracer(server.URL, "helm-crd-controller", http.DefaultClient)
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := controller.tracer.Flush(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []string{"fetchRepoIndex", "fetchChart", "resolveDependencies", "tiller.history", "tiller.install", "reconcile"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected spans %v received %v", expected, names)
+	}
+}
+
+func TestHelmReleaseDryRun(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
 		},
 	}
 	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	controller.dryRun = true
 
 	err := controller.updateRelease("myns/foo")
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
-	rels, err := controller.helmClient.ListReleases()
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if hasFinalizer(res) {
+		t.Errorf("Expected no finalizer to be added in dry-run mode")
+	}
+	if res.Status.DryRun == nil || res.Status.DryRun.Action != "install" {
+		t.Fatalf("Expected a dry-run install received %+v", res.Status.DryRun)
+	}
+	if len(res.Status.DryRun.Added) != 1 || res.Status.DryRun.Added[0].Name != "fixture" {
+		t.Errorf("Unexpected added resources %+v", res.Status.DryRun.Added)
+	}
+	if res.Status.Revision != 0 {
+		t.Errorf("Expected no deployed revision received %d", res.Status.Revision)
+	}
+}
+
+func TestHelmReleaseRenderOnly(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			RenderOnly: true,
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+
+	err := controller.updateRelease("myns/foo")
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
-	if rels.Releases[0].Name != h.Spec.ReleaseName {
-		t.Errorf("Expected release named %s received %s", h.Spec.ReleaseName, rels.Releases[0].Name)
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	ref := res.Status.RenderedManifests
+	if ref == nil || ref.Kind != "ConfigMap" || ref.Name != "foo-manifests" {
+		t.Fatalf("Unexpected rendered manifests reference %+v", ref)
+	}
+	cm, err := controller.kubeClient.Core().ConfigMaps("myns").Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cm.Data[ref.Key] != helmclient.MockManifest {
+		t.Errorf("Expected rendered manifest %q received %q", helmclient.MockManifest, cm.Data[ref.Key])
+	}
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].Name != "foo" {
+		t.Errorf("Expected ConfigMap to be owned by the HelmRelease received %+v", cm.OwnerReferences)
+	}
+	if hasFinalizer(res) {
+		t.Errorf("Expected no finalizer on render-only releases")
+	}
+}
+
+func TestTruncateNotes(t *testing.T) {
+	if notes := truncateNotes("Visit http://foo"); notes != "Visit http://foo" {
+		t.Errorf("Expected short notes to be kept received %q", notes)
+	}
+	notes := truncateNotes(strings.Repeat("é", maxNotesLen))
+	if len(notes) > maxNotesLen || !strings.HasSuffix(notes, "[truncated]") {
+		t.Errorf("Expected notes to be truncated to %d bytes received %d", maxNotesLen, len(notes))
+	}
+	if !utf8.ValidString(notes) {
+		t.Errorf("Expected truncated notes to be valid UTF-8")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	retries := int32(20)
+	hrs := []helmCRDApi.HelmRelease{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}, Spec: helmCRDApi.HelmReleaseSpec{Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Name: "foo"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "bar"}, Spec: helmCRDApi.HelmReleaseSpec{Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Name: "bar"}}, Retries: &retries}},
+	}
+	controller := prepareTestController(hrs, []string{})
+	controller.maxRetries = 10
+	if r := controller.retries("myns/foo"); r != 10 {
+		t.Errorf("Expected 10 retries received %d", r)
+	}
+	if r := controller.retries("myns/bar"); r != 20 {
+		t.Errorf("Expected 20 retries received %d", r)
+	}
+}
+
+func TestReleaseNeedsResync(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected bool
+	}{
+		{"1.0.0", false},
+		{"", false},
+		{"^1.0.0", true},
+		{"1.x", true},
+	}
+	for _, tt := range tests {
+		h := &helmCRDApi.HelmRelease{Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Version: tt.version}},
+		}}
+		if res := releaseNeedsResync(h); res != tt.expected {
+			t.Errorf("Expecting releaseNeedsResync to be %v for version %q", tt.expected, tt.version)
+		}
 	}
 }
 
@@ -190,10 +506,14 @@ func TestHelmReleaseUpdated(t *testing.T) {
 		ObjectMeta: myNsFoo,
 		Spec: helmCRDApi.HelmReleaseSpec{
 			ReleaseName: releaseName,
-			RepoURL:     "http://charts.example.com/repo/",
-			ChartName:   "foo",
-			Version:     "v1.0.0",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "v1.0.0",
+			}},
 		},
+		// Deployed by a previous reconcile
+		Status: helmCRDApi.HelmReleaseStatus{Revision: 1},
 	}
 	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{releaseName})
 
@@ -201,14 +521,46 @@ func TestHelmReleaseUpdated(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
-	rels, err := controller.helmClient.ListReleases()
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	rels := fakeHelmClient(controller).Releases
+	if len(rels) != 2 || rels[1].Name != rels[0].Name || rels[1].Version != 2 {
+		t.Errorf("Unexpected releases %v, it should upgrade the existing one", rels)
+	}
+}
+
+func TestHelmReleaseAdoptExisting(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "v1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{"bar"})
+
+	// Releases deployed by something else are not upgraded
+	if err := controller.updateRelease("myns/foo"); err == nil {
+		t.Errorf("Expected an error upgrading a release not deployed for the HelmRelease")
 	}
-	// We cannot test that the release content changes because fake UpdateReleaseResponse
-	// does not modify the release
-	if len(rels.Releases) != 1 {
-		t.Errorf("Unexpected amount of releases %d, it should update the existing one", len(rels.Releases))
+
+	h.Spec.AdoptExisting = true
+	controller.informer.GetIndexer().Update(&h)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != "Adopted" {
+		t.Errorf("Expected an Adopted event received %+v", events.Items)
+	}
+
+	// Releases of other HelmReleases are never adopted
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+		tillerConfigMap("bar", "2", map[string]string{managedNamespaceLabel: "other", managedNameLabel: "foo"}))
+	if err := controller.updateRelease("myns/foo"); err == nil || !strings.Contains(err.Error(), "other/foo") {
+		t.Errorf("Expected an error adopting the release of another HelmRelease, received %v", err)
 	}
 }
 
@@ -224,22 +576,312 @@ func TestHelmReleaseDeleted(t *testing.T) {
 		ObjectMeta: myNsFoo,
 		Spec: helmCRDApi.HelmReleaseSpec{
 			ReleaseName: releaseName,
-			RepoURL:     "http://charts.example.com/repo/",
-			ChartName:   "foo",
-			Version:     "v1.0.0",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "v1.0.0",
+			}},
 		},
 	}
 	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{releaseName})
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+		tillerConfigMap(releaseName, "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "foo"}))
 
 	err := controller.updateRelease("myns/foo")
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
-	rels, err := controller.helmClient.ListReleases()
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	rels := fakeHelmClient(controller).Releases
+	if len(rels) != 0 {
+		t.Errorf("Unexpected amount of releases %d, it should be empty", len(rels))
+	}
+}
+
+func TestHelmReleaseDeletedForeignRelease(t *testing.T) {
+	for _, policy := range []helmCRDApi.DeletionPolicy{helmCRDApi.DeletionPolicyDelete, helmCRDApi.DeletionPolicyDeleteHistoryOnly} {
+		t.Run(string(policy), func(t *testing.T) {
+			h := helmCRDApi.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "myns",
+					Name:              "foo",
+					DeletionTimestamp: &metav1.Time{},
+					Finalizers:        []string{releaseFinalizer},
+				},
+				Spec: helmCRDApi.HelmReleaseSpec{
+					ReleaseName:    "bar",
+					Chart:          helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Name: "foo"}},
+					DeletionPolicy: policy,
+				},
+			}
+			controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{"bar"})
+			// bar was installed by hand, or by another HelmRelease
+			controller.kubeClient.Core().ConfigMaps("kube-system").Create(tillerConfigMap("bar", "1", nil))
+			controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+				tillerConfigMap("bar", "2", map[string]string{managedNamespaceLabel: "other", managedNameLabel: "foo"}))
+
+			if err := controller.updateRelease("myns/foo"); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if rels := fakeHelmClient(controller).Releases; len(rels) != 1 {
+				t.Errorf("Expected the foreign release to be kept, received %d releases", len(rels))
+			}
+			cms, _ := controller.kubeClient.Core().ConfigMaps("kube-system").List(metav1.ListOptions{})
+			if len(cms.Items) != 2 {
+				t.Errorf("Expected the foreign release history to be kept, received %d ConfigMaps", len(cms.Items))
+			}
+			res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+			if hasFinalizer(res) {
+				t.Errorf("Expected the finalizer to be removed")
+			}
+		})
+	}
+}
+
+func TestHelmReleaseFinalizerOwnership(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{"bar"})
+
+	// The existing release isn't adopted, so the HelmRelease gets no finalizer
+	if err := controller.updateRelease("myns/foo"); err == nil {
+		t.Errorf("Expected an error for a release not owned")
 	}
-	if len(rels.Releases) != 0 {
-		t.Errorf("Unexpected amount of releases %d, it should be empty", len(rels.Releases))
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if hasFinalizer(res) {
+		t.Errorf("Expected no finalizer for a release not owned")
 	}
 }
+
+func TestHelmReleaseDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		policy           helmCRDApi.DeletionPolicy
+		expectedReleases int
+		expectedStorage  int
+	}{
+		{helmCRDApi.DeletionPolicyDelete, 0, 1},
+		{helmCRDApi.DeletionPolicyRetain, 1, 1},
+		{helmCRDApi.DeletionPolicyDeleteHistoryOnly, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			h := helmCRDApi.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "myns",
+					Name:              "foo",
+					DeletionTimestamp: &metav1.Time{},
+					Finalizers:        []string{releaseFinalizer},
+				},
+				Spec: helmCRDApi.HelmReleaseSpec{
+					ReleaseName:    "bar",
+					Chart:          helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Name: "foo"}},
+					DeletionPolicy: tt.policy,
+				},
+			}
+			controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{"bar"})
+			controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+				tillerConfigMap("bar", "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "foo"}))
+
+			if err := controller.updateRelease("myns/foo"); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			rels := fakeHelmClient(controller).Releases
+			if len(rels) != tt.expectedReleases {
+				t.Errorf("Expected %d releases received %d", tt.expectedReleases, len(rels))
+			}
+			cms, _ := controller.kubeClient.Core().ConfigMaps("kube-system").List(metav1.ListOptions{})
+			if len(cms.Items) != tt.expectedStorage {
+				t.Fatalf("Expected %d Tiller ConfigMaps received %d", tt.expectedStorage, len(cms.Items))
+			}
+			if tt.policy == helmCRDApi.DeletionPolicyRetain && cms.Items[0].Labels[managedNameLabel] != "" {
+				t.Errorf("Expected the retained release to be unlabeled, received %v", cms.Items[0].Labels)
+			}
+			res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+			if hasFinalizer(res) {
+				t.Errorf("Expected the finalizer to be removed")
+			}
+		})
+	}
+}
+
+// stuckDeleteClient is a Helm client failing to delete releases
+type stuckDeleteClient struct {
+	*helmclient.FakeClient
+}
+
+func (c *stuckDeleteClient) Delete(rlsName string, opts helmclient.DeleteOptions) error {
+	return nil
+}
+
+func TestHelmReleaseDeleteVerified(t *testing.T) {
+	purge := false
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "myns",
+			Name:              "foo",
+			DeletionTimestamp: &metav1.Time{},
+			Finalizers:        []string{releaseFinalizer},
+		},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart:       helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{Name: "foo"}},
+			Uninstall:   &helmCRDApi.UninstallSpec{Purge: &purge, Timeout: 60, DisableHooks: true},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{"bar"})
+	controller.helmClient = &stuckDeleteClient{fakeHelmClient(controller)}
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+		tillerConfigMap("bar", "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "foo"}))
+
+	if err := controller.updateRelease("myns/foo"); err == nil {
+		t.Errorf("Expected an error for a release still deployed after deletion")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if !hasFinalizer(res) {
+		t.Errorf("Expected the finalizer to be kept until the release is deleted")
+	}
+}
+
+// hooksClient is a Helm client recording whether hooks were disabled
+type hooksClient struct {
+	*helmclient.FakeClient
+	disabled []string
+}
+
+func (c *hooksClient) Install(ch *chart.Chart, namespace string, opts helmclient.InstallOptions) (*release.Release, error) {
+	if opts.DisableHooks {
+		c.disabled = append(c.disabled, "Install")
+	}
+	return c.FakeClient.Install(ch, namespace, opts)
+}
+
+func (c *hooksClient) Upgrade(rlsName string, ch *chart.Chart, opts helmclient.UpgradeOptions) (*release.Release, error) {
+	if opts.DisableHooks {
+		c.disabled = append(c.disabled, "Upgrade")
+	}
+	return c.FakeClient.Upgrade(rlsName, ch, opts)
+}
+
+func (c *hooksClient) Delete(rlsName string, opts helmclient.DeleteOptions) error {
+	if opts.DisableHooks {
+		c.disabled = append(c.disabled, "Delete")
+	}
+	return c.FakeClient.Delete(rlsName, opts)
+}
+
+func TestHelmReleaseDisableHooks(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Repository: &helmCRDApi.RepositoryChartSource{
+				URL: "http://charts.example.com/repo/", Name: "foo", Version: "1.0.0",
+			}},
+			AdoptExisting: true,
+			DisableHooks:  true,
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	client := &hooksClient{FakeClient: fakeHelmClient(controller)}
+	controller.helmClient = client
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	deleted := h
+	deleted.DeletionTimestamp = &metav1.Time{}
+	deleted.Finalizers = []string{releaseFinalizer}
+	if err := controller.deleteRelease(&deleted); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := []string{"Install", "Upgrade", "Delete"}; !reflect.DeepEqual(client.disabled, expected) {
+		t.Errorf("Expecting hooks to be disabled on %v, received %v", expected, client.disabled)
+	}
+}
+
+func TestHelmReleaseTarball(t *testing.T) {
+	h := helmCRDApi.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCRDApi.HelmReleaseSpec{
+			Chart: helmCRDApi.ChartSource{Tarball: &helmCRDApi.TarballChartSource{
+				URL: "https://artifacts.example.com/foo-1.0.0.tgz",
+				// sha256 of the empty archive served by fakeHTTPClient
+				Digest: "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if res.Status.ResolvedVersion != "1.0.0" || len(fakeHelmClient(controller).Deployed()) != 1 {
+		t.Errorf("Expecting version 1.0.0 of the chart to be deployed, received %+v", res.Status)
+	}
+
+	h.Spec.Chart.Tarball.Digest = "sha256:" + strings.Repeat("0", 64)
+	controller = prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Expecting a digest mismatch, received %v", err)
+	}
+
+	// spec.chartURL and spec.chartDigest are shorthands of the tarball
+	h.Spec = helmCRDApi.HelmReleaseSpec{
+		ChartURL:    "https://artifacts.example.com/foo-1.0.0.tgz",
+		ChartDigest: "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	}
+	controller = prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Deployed()) != 1 {
+		t.Errorf("Expecting the chart of spec.chartURL to be deployed")
+	}
+	h.Spec.ChartDigest = "sha256:" + strings.Repeat("0", 64)
+	controller = prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Expecting a digest mismatch, received %v", err)
+	}
+
+	// The archive of spec.chartURL is the only request, authenticated with
+	// the matching RepositoryCredential
+	h.Spec.ChartDigest = ""
+	controller = prepareTestController([]helmCRDApi.HelmRelease{h}, []string{})
+	controller.credentialInformer.GetStore().Add(&helmCRDApi.RepositoryCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "artifacts"},
+		Spec:       helmCRDApi.RepositoryCredentialSpec{URLPrefixes: []string{"https://artifacts.example.com/"}, Auth: credentialAuthHeader("artifacts")},
+	})
+	controller.kubeClient.Core().Secrets(controllerNamespace()).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "artifacts"},
+		Data:       map[string][]byte{"token": []byte("Bearer s3cret")},
+	})
+	recorder := &recordingHTTPClient{client: *controller.netClient}
+	*controller.netClient = recorder
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(recorder.requests) != 1 || recorder.requests[0].URL.String() != h.Spec.ChartURL || recorder.requests[0].Header.Get("Authorization") != "Bearer s3cret" {
+		t.Errorf("Expecting a single authenticated request of the chart archive, received %v", recorder.requests)
+	}
+}
+
+// recordingHTTPClient records the requests sent through client
+type recordingHTTPClient struct {
+	client   chartUtils.HTTPClient
+	requests []*http.Request
+}
+
+func (r *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return r.client.Do(req)
+}
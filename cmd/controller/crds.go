@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/postrender"
+)
+
+const (
+	// defaultCRDTimeout is how long CRDs installed first may take to be
+	// established for HelmReleases not setting spec.timeout
+	defaultCRDTimeout = 2 * time.Minute
+	// defaultCRDPollInterval is the period of checking whether CRDs
+	// installed first are established
+	defaultCRDPollInterval = time.Second
+)
+
+// handlesCRDs returns whether the CRDs bundled in the chart of h are not
+// left to Tiller
+func handlesCRDs(h *helmCrdV2.HelmRelease) bool {
+	return h.Spec.SkipCRDs || h.Spec.InstallCRDsFirst
+}
+
+func isCRD(obj manifest.Object) bool {
+	return obj.Kind == "CustomResourceDefinition" && strings.HasPrefix(obj.APIVersion, "apiextensions.k8s.io/")
+}
+
+// separateCRDs returns a chart rendering the rendered manifest, hooks and
+// notes of rel. Unless h handles the CRDs of its chart, it is the chart of
+// postrender.Chart. Otherwise the CRDs of the manifest and hooks, such as
+// crd-install hooks, are left out and, with spec.installCRDsFirst,
+// returned after those of the crds/ directory of ch.
+func separateCRDs(h *helmCrdV2.HelmRelease, ch *chart.Chart, rel *release.Release, rendered string) (*chart.Chart, []manifest.Object, error) {
+	if !handlesCRDs(h) {
+		return postrender.Chart(rel, rendered), nil, nil
+	}
+	var crds []manifest.Object
+	for _, f := range ch.GetFiles() {
+		if !strings.HasPrefix(f.TypeUrl, "crds/") {
+			continue
+		}
+		switch path.Ext(f.TypeUrl) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		objs, err := manifest.Objects(string(f.Value))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", f.TypeUrl, err)
+		}
+		for _, obj := range objs {
+			if isCRD(obj) {
+				crds = append(crds, obj)
+			}
+		}
+	}
+
+	rendered, removed, err := manifest.Split(rendered, isCRD)
+	if err != nil {
+		return nil, nil, err
+	}
+	crds = append(crds, removed...)
+	withoutCRDs := *rel
+	withoutCRDs.Hooks = nil
+	for _, hook := range rel.GetHooks() {
+		hookManifest, removed, err := manifest.Split(hook.GetManifest(), isCRD)
+		if err != nil {
+			return nil, nil, err
+		}
+		crds = append(crds, removed...)
+		if len(removed) == 0 {
+			withoutCRDs.Hooks = append(withoutCRDs.Hooks, hook)
+		} else if strings.TrimSpace(hookManifest) != "" {
+			kept := *hook
+			kept.Manifest = hookManifest
+			withoutCRDs.Hooks = append(withoutCRDs.Hooks, &kept)
+		}
+	}
+
+	if h.Spec.SkipCRDs {
+		crds = nil
+	}
+	return postrender.Chart(&withoutCRDs, rendered), crds, nil
+}
+
+// installCRDs applies crds and waits for them to be established, so that
+// Tiller can create the custom resources of the release. With
+// spec.serviceAccountName, the service account must be allowed to create
+// or patch them.
+func (c *Controller) installCRDs(h *helmCrdV2.HelmRelease, crds []manifest.Object) error {
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		return err
+	}
+	for _, crd := range crds {
+		if h.Spec.ServiceAccountName != "" {
+			verb := "patch"
+			if _, err := objects.Get(crd, ""); k8sErrors.IsNotFound(err) {
+				verb = "create"
+			} else if err != nil {
+				return err
+			}
+			allowed, err := c.serviceAccountAllowed(h, objectChange{verb, crd}, "")
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return fmt.Errorf("service account %s is not allowed to %s CustomResourceDefinition %s", h.Spec.ServiceAccountName, verb, crd.Name)
+			}
+		}
+		if err := objects.Apply(crd, ""); err != nil {
+			return fmt.Errorf("unable to apply CustomResourceDefinition %s: %v", crd.Name, err)
+		}
+	}
+
+	timeout := c.crdTimeout
+	if h.Spec.Timeout > 0 {
+		timeout = time.Duration(h.Spec.Timeout) * time.Second
+	}
+	for _, crd := range crds {
+		err := wait.PollImmediate(c.crdPollInterval, timeout, func() (bool, error) {
+			live, err := objects.Get(crd, "")
+			if k8sErrors.IsNotFound(err) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			return crdEstablished(live), nil
+		})
+		if err == wait.ErrWaitTimeout {
+			return fmt.Errorf("timed out waiting for CustomResourceDefinition %s to be established", crd.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crdEstablished returns whether the Established condition of a live
+// CustomResourceDefinition is True
+func crdEstablished(live map[string]interface{}) bool {
+	status, _ := live["status"].(map[string]interface{})
+	conditions, _ := status["conditions"].([]interface{})
+	for _, c := range conditions {
+		cond, _ := c.(map[string]interface{})
+		if cond["type"] == "Established" {
+			return cond["status"] == "True"
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+const crdManifest = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: %s.example.com
+`
+
+func crd(plural string) string {
+	return strings.Replace(crdManifest, "%s", plural, 1)
+}
+
+func TestSeparateCRDs(t *testing.T) {
+	rel := &release.Release{
+		Manifest: "---\n" + crd("foos") + "---\napiVersion: example.com/v1\nkind: Foo\nmetadata:\n  name: foo\n",
+		Hooks: []*release.Hook{
+			{Path: "templates/crd.yaml", Manifest: crd("bars")},
+			{Path: "templates/job.yaml", Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\n"},
+		},
+		Info: &release.Info{Status: &release.Status{}},
+	}
+	ch := &chart.Chart{Files: []*any.Any{
+		{TypeUrl: "crds/bazs.yaml", Value: []byte(crd("bazs"))},
+		{TypeUrl: "crds/README.md", Value: []byte("# CRDs")},
+	}}
+
+	h := &helmCrdV2.HelmRelease{}
+	rendered, crds, err := separateCRDs(h, ch, rel, rel.Manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(crds) != 0 || len(rendered.Templates) != 3 || !strings.Contains(string(rendered.Templates[0].Data), "CustomResourceDefinition") {
+		t.Errorf("Expecting CRDs to be left to Tiller by default, received %v", rendered.Templates)
+	}
+
+	h.Spec.InstallCRDsFirst = true
+	rendered, crds, err = separateCRDs(h, ch, rel, rel.Manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var names []string
+	for _, crd := range crds {
+		names = append(names, crd.Name)
+	}
+	if expected := []string{"bazs.example.com", "foos.example.com", "bars.example.com"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expecting CRDs %v to be installed first, received %v", expected, names)
+	}
+	if len(rendered.Templates) != 2 || strings.Contains(string(rendered.Templates[0].Data), "CustomResourceDefinition") || !strings.Contains(string(rendered.Templates[1].Data), "kind: Job") {
+		t.Errorf("Expecting the chart without CRDs, received %v", rendered.Templates)
+	}
+	if len(rel.Hooks) != 2 {
+		t.Errorf("Expecting the release to be left unmodified, received %v", rel.Hooks)
+	}
+
+	h.Spec.InstallCRDsFirst = false
+	h.Spec.SkipCRDs = true
+	rendered, crds, err = separateCRDs(h, ch, rel, rel.Manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(crds) != 0 || len(rendered.Templates) != 2 {
+		t.Errorf("Expecting CRDs to be skipped, received %v and %v", crds, rendered.Templates)
+	}
+}
+
+func TestInstallCRDs(t *testing.T) {
+	established := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+				map[string]interface{}{"type": "Established", "status": "True"},
+			},
+		},
+	}
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{
+		"CustomResourceDefinition/foos.example.com": established,
+		"CustomResourceDefinition/bars.example.com": {},
+	}}
+	c := &Controller{objects: objects, crdTimeout: 10 * time.Millisecond, crdPollInterval: time.Millisecond}
+	crds, err := manifest.Objects(crd("foos"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	h := &helmCrdV2.HelmRelease{}
+	if err := c.installCRDs(h, crds); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if expected := []string{"/CustomResourceDefinition/foos.example.com"}; !reflect.DeepEqual(objects.applied, expected) {
+		t.Errorf("Expecting %v to be applied, received %v", expected, objects.applied)
+	}
+
+	crds, err = manifest.Objects(crd("bars"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := c.installCRDs(h, crds); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expecting a timeout waiting for a CRD not established, received %v", err)
+	}
+}
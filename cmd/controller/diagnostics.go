@@ -0,0 +1,57 @@
+package main
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/pprof"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+)
+
+// Counters served at /debug/vars with --enable-pprof, along with the
+// memstats and cmdline published by expvar
+var (
+	reconcileCount      = expvar.NewInt("reconciles")
+	reconcileErrorCount = expvar.NewInt("reconcileErrors")
+	chartLoadCount      = expvar.NewInt("chartsLoaded")
+	chartBytesCount     = expvar.NewInt("chartBytesLoaded")
+)
+
+// countChartLoads returns load, counting the charts loaded and the size
+// of their archives, known from the Size of readers such as bytes.Reader
+// and io.SectionReader
+func countChartLoads(load chartUtils.LoadChart) chartUtils.LoadChart {
+	return func(in io.Reader) (*chart.Chart, error) {
+		chartLoadCount.Add(1)
+		if r, ok := in.(interface {
+			Size() int64
+		}); ok {
+			chartBytesCount.Add(r.Size())
+		}
+		return load(in)
+	}
+}
+
+// publishQueueDepth publishes the number of HelmReleases waiting to be
+// reconciled by c at /debug/vars
+func publishQueueDepth(c *Controller) {
+	expvar.Publish("queueDepth", expvar.Func(func() interface{} {
+		return c.queue.Len()
+	}))
+}
+
+// diagnosticsHandler serves the net/http/pprof profiles under
+// /debug/pprof/ and the expvar variables at /debug/vars
+func diagnosticsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
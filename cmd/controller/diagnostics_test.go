@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	load := countChartLoads(func(in io.Reader) (*chart.Chart, error) {
+		return &chart.Chart{}, nil
+	})
+	before := chartBytesCount.Value()
+	if _, err := load(bytes.NewReader(make([]byte, 42))); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if loaded := chartBytesCount.Value() - before; loaded != 42 {
+		t.Errorf("Expecting 42 chart bytes to be counted, received %d", loaded)
+	}
+
+	handler := diagnosticsHandler()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/vars", nil))
+	vars := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, name := range []string{"reconciles", "chartBytesLoaded", "memstats"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("Expecting %s in /debug/vars, received %v", name, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/heap", nil))
+	if w.Code != 200 {
+		t.Errorf("Expecting the heap profile to be served, received status %d", w.Code)
+	}
+}
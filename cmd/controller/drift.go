@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// maxDriftMessages caps the drifted objects listed in the condition message
+const maxDriftMessages = 5
+
+// detectDrift compares the objects in the manifest of the deployed release
+// with their live state and the configuration last applied to them with
+// kubectl, and returns the resulting Drifted condition. In correct mode
+// drifted objects are patched back to the manifest, removing the fields
+// applied since.
+func (c *Controller) detectDrift(h *helmCrdV2.HelmRelease, rel *release.Release) (helmCrdV2.HelmReleaseCondition, error) {
+	cond := helmCrdV2.HelmReleaseCondition{Type: helmCrdV2.HelmReleaseDrifted}
+
+	objs, err := manifest.Objects(rel.GetManifest())
+	if err != nil {
+		return cond, err
+	}
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		return cond, err
+	}
+
+	var drifted []string
+	var corrected int
+	for _, obj := range objs {
+		var msg string
+		live, err := objects.Get(obj, rel.GetNamespace())
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return cond, err
+		}
+		lastApplied := manifest.LastApplied(live)
+		if err != nil {
+			msg = fmt.Sprintf("%s %s: deleted", obj.Kind, obj.Name)
+		} else if diffs := manifest.Diff3(obj.Content, lastApplied, live); len(diffs) > 0 {
+			msg = fmt.Sprintf("%s %s: %s", obj.Kind, obj.Name, strings.Join(diffs, ", "))
+		} else {
+			continue
+		}
+		logger.With("release", rel.GetName(), "drift", msg).Warnf("Release drifted")
+		drifted = append(drifted, msg)
+
+		if h.Spec.DriftDetection.Mode == helmCrdV2.DriftDetectionCorrect && !c.dryRun && !h.Spec.RenderOnly {
+			patch := obj
+			patch.Content = manifest.RemovalPatch(obj.Content, lastApplied, live)
+			if err := objects.Apply(patch, rel.GetNamespace()); err != nil {
+				return cond, fmt.Errorf("unable to correct %s %s: %v", obj.Kind, obj.Name, err)
+			}
+			corrected++
+		}
+	}
+
+	if len(drifted) > maxDriftMessages {
+		drifted = append(drifted[:maxDriftMessages], fmt.Sprintf("and %d more", len(drifted)-maxDriftMessages))
+	}
+	switch {
+	case len(drifted) == 0:
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = "InSync"
+	case corrected > 0:
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = "DriftCorrected"
+		cond.Message = strings.Join(drifted, "; ")
+	default:
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = "DriftDetected"
+		cond.Message = strings.Join(drifted, "; ")
+	}
+	return cond, nil
+}
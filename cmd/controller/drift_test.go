@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+type fakeObjectClient struct {
+	live    map[string]map[string]interface{}
+	applied []string
+}
+
+func (f *fakeObjectClient) Get(obj manifest.Object, namespace string) (map[string]interface{}, error) {
+	live, ok := f.live[obj.Kind+"/"+obj.Name]
+	if !ok {
+		return nil, k8sErrors.NewNotFound(schema.GroupResource{Resource: obj.Kind}, obj.Name)
+	}
+	return live, nil
+}
+
+func (f *fakeObjectClient) Resource(apiVersion, kind string) (*metav1.APIResource, error) {
+	return &metav1.APIResource{
+		Name:       strings.ToLower(kind) + "s",
+		Kind:       kind,
+		Namespaced: kind != "Namespace" && kind != "Node",
+	}, nil
+}
+
+func (f *fakeObjectClient) Apply(obj manifest.Object, namespace string) error {
+	f.applied = append(f.applied, fmt.Sprintf("%s/%s/%s", namespace, obj.Kind, obj.Name))
+	return nil
+}
+
+const driftManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  key: value
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+`
+
+func TestDetectDrift(t *testing.T) {
+	rel := &release.Release{Name: "myns-foo", Namespace: "myns", Manifest: driftManifest}
+	tests := []struct {
+		name           string
+		mode           helmCrdV2.DriftDetectionMode
+		live           map[string]map[string]interface{}
+		expectedStatus corev1.ConditionStatus
+		expectedReason string
+		expectedApply  []string
+	}{
+		{
+			"in sync",
+			helmCrdV2.DriftDetectionWarn,
+			map[string]map[string]interface{}{
+				"ConfigMap/foo": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "foo", "uid": "1234"}, "data": map[string]interface{}{"key": "value"}},
+				"Service/foo":   {"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "foo"}},
+			},
+			corev1.ConditionFalse, "InSync", nil,
+		},
+		{
+			"drifted",
+			helmCrdV2.DriftDetectionWarn,
+			map[string]map[string]interface{}{
+				"ConfigMap/foo": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "foo"}, "data": map[string]interface{}{"key": "changed"}},
+			},
+			corev1.ConditionTrue, "DriftDetected", nil,
+		},
+		{
+			"applied since",
+			helmCrdV2.DriftDetectionWarn,
+			map[string]map[string]interface{}{
+				"ConfigMap/foo": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "foo", "annotations": map[string]interface{}{
+					manifest.LastAppliedAnnotation: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"},"data":{"key":"value","debug":"true"}}`,
+				}}, "data": map[string]interface{}{"key": "value", "debug": "true"}},
+				"Service/foo": {"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "foo"}},
+			},
+			corev1.ConditionTrue, "DriftDetected", nil,
+		},
+		{
+			"corrected",
+			helmCrdV2.DriftDetectionCorrect,
+			map[string]map[string]interface{}{
+				"ConfigMap/foo": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "foo"}, "data": map[string]interface{}{"key": "changed"}},
+			},
+			corev1.ConditionFalse, "DriftCorrected", []string{"myns/ConfigMap/foo", "myns/Service/foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := &fakeObjectClient{live: tt.live}
+			c := &Controller{objects: objects}
+			h := &helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{
+				DriftDetection: &helmCrdV2.DriftDetectionSpec{Mode: tt.mode},
+			}}
+			cond, err := c.detectDrift(h, rel)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if cond.Status != tt.expectedStatus || cond.Reason != tt.expectedReason {
+				t.Errorf("Expecting %s/%s received %s/%s (%s)", tt.expectedStatus, tt.expectedReason, cond.Status, cond.Reason, cond.Message)
+			}
+			if fmt.Sprint(objects.applied) != fmt.Sprint(tt.expectedApply) {
+				t.Errorf("Expecting %v to be applied received %v", tt.expectedApply, objects.applied)
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	status := &helmCrdV2.HelmReleaseStatus{}
+	setCondition(status, helmCrdV2.HelmReleaseCondition{Type: helmCrdV2.HelmReleaseDrifted, Status: corev1.ConditionTrue})
+	first := getCondition(status, helmCrdV2.HelmReleaseDrifted)
+	if first == nil {
+		t.Fatalf("Expected condition to be set")
+	}
+	transition := first.LastTransitionTime
+
+	// Same status keeps the transition time
+	cached := status.Conditions
+	setCondition(status, helmCrdV2.HelmReleaseCondition{Type: helmCrdV2.HelmReleaseDrifted, Status: corev1.ConditionTrue, Message: "foo"})
+	if len(status.Conditions) != 1 || status.Conditions[0].Message != "foo" {
+		t.Errorf("Unexpected conditions %+v", status.Conditions)
+	}
+	if !status.Conditions[0].LastTransitionTime.Equal(&transition) {
+		t.Errorf("Expected transition time to be kept")
+	}
+	if cached[0].Message != "" {
+		t.Errorf("Expected previous conditions slice to be left unchanged")
+	}
+
+	removeCondition(status, helmCrdV2.HelmReleaseDrifted)
+	if getCondition(status, helmCrdV2.HelmReleaseDrifted) != nil {
+		t.Errorf("Expected condition to be removed")
+	}
+}
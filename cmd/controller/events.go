@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// eventSource identifies the controller in the Events it records
+const eventSource = "helm-crd-controller"
+
+// recordEvent creates an Event about h. client-go's record package is not
+// vendored, so Events are created directly and not aggregated.
+func (c *Controller) recordEvent(h *helmCrdV2.HelmRelease, eventType, reason, message string) {
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", h.Name, now.UnixNano()),
+			Namespace: h.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      helmCrdV2.SchemeGroupVersion.String(),
+			Kind:            "HelmRelease",
+			Namespace:       h.Namespace,
+			Name:            h.Name,
+			UID:             h.UID,
+			ResourceVersion: h.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Source:         corev1.EventSource{Component: eventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+	if _, err := c.kubeClient.Core().Events(h.Namespace).Create(event); err != nil {
+		logger.With("namespace", h.Namespace, "name", h.Name, "reason", reason, "error", err).Warnf("Unable to record event")
+	}
+}
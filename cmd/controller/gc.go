@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/audit"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// Tiller stores each release revision in a ConfigMap named
+// <release>.v<revision> in its namespace, the apply executor in a Secret.
+// The controller labels the revisions it deploys with the HelmRelease
+// owning them, so releases left behind by deleted HelmReleases can be found.
+const (
+	tillerOwnerLabel      = "OWNER"
+	tillerNameLabel       = "NAME"
+	managedNamespaceLabel = "helm.bitnami.com/namespace"
+	managedNameLabel      = "helm.bitnami.com/name"
+)
+
+// labelReleaseStorage labels the stored revision rel as managed by h
+func (c *Controller) labelReleaseStorage(h *helmCrdV2.HelmRelease, rel *release.Release) error {
+	storage, err := c.storageFor(h)
+	if err != nil {
+		return err
+	}
+	namespace := c.releaseTillerNamespace(h)
+	name := fmt.Sprintf("%s.v%d", rel.GetName(), rel.GetVersion())
+	meta, err := storage.Get(namespace, name)
+	if err != nil {
+		return err
+	}
+	if meta.Labels[managedNamespaceLabel] == h.Namespace && meta.Labels[managedNameLabel] == h.Name {
+		return nil
+	}
+	labels := map[string]string{}
+	for k, v := range meta.Labels {
+		labels[k] = v
+	}
+	labels[managedNamespaceLabel] = h.Namespace
+	labels[managedNameLabel] = h.Name
+	return storage.SetLabels(namespace, name, labels)
+}
+
+// releaseOwner returns the namespace/name of the HelmRelease the storage
+// labels of rel point to, or "" if unlabeled or not stored in ConfigMaps.
+// namespace is where the Tiller of rel stores releases.
+func releaseOwner(storage revisionStorage, namespace string, rel *release.Release) (string, error) {
+	name := fmt.Sprintf("%s.v%d", rel.GetName(), rel.GetVersion())
+	meta, err := storage.Get(namespace, name)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if meta.Labels[managedNameLabel] == "" {
+		return "", nil
+	}
+	return meta.Labels[managedNamespaceLabel] + "/" + meta.Labels[managedNameLabel], nil
+}
+
+// checkOwnership fails if the deployed release rel was not deployed for h,
+// unless h adopts existing releases. Releases deployed before they were
+// labeled are recognized by the revision recorded in the status.
+func (c *Controller) checkOwnership(h *helmCrdV2.HelmRelease, rel *release.Release) error {
+	key := h.Namespace + "/" + h.Name
+	storage, err := c.storageFor(h)
+	if err != nil {
+		return err
+	}
+	owner, err := releaseOwner(storage, c.releaseTillerNamespace(h), rel)
+	if err != nil {
+		return err
+	}
+	switch {
+	case owner == key:
+		return nil
+	case owner != "":
+		return fmt.Errorf("release %s is managed by HelmRelease %s", rel.GetName(), owner)
+	case h.Status.Revision != 0:
+		return nil
+	case !h.Spec.AdoptExisting:
+		return fmt.Errorf("release %s already exists and was not deployed for this HelmRelease, set spec.adoptExisting to adopt it", rel.GetName())
+	}
+	logger.With("helmrelease", key, "release", rel.GetName()).Infof("Adopting existing release")
+	c.recordEvent(h, corev1.EventTypeNormal, "Adopted", fmt.Sprintf("Adopted existing release %s", rel.GetName()))
+	return nil
+}
+
+// ownsRelease returns whether the stored revisions of the release of h
+// are labeled as managed by h. Releases deployed before they were labeled
+// are recognized by the revision recorded in the status, as long as no
+// revision is labeled as managed by another HelmRelease.
+func (c *Controller) ownsRelease(h *helmCrdV2.HelmRelease) (bool, error) {
+	_, metas, err := c.releaseStorage(h)
+	if err != nil {
+		return false, err
+	}
+	labeled := false
+	for _, meta := range metas {
+		if meta.Labels[managedNameLabel] == "" {
+			continue
+		}
+		if meta.Labels[managedNamespaceLabel] == h.Namespace && meta.Labels[managedNameLabel] == h.Name {
+			return true, nil
+		}
+		labeled = true
+	}
+	return !labeled && len(metas) > 0 && h.Status.Revision != 0, nil
+}
+
+// releaseStorage returns the stored revisions of the release of h and
+// the storage holding them
+func (c *Controller) releaseStorage(h *helmCrdV2.HelmRelease) (revisionStorage, []metav1.ObjectMeta, error) {
+	storage, err := c.storageFor(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	metas, err := storage.List(c.releaseTillerNamespace(h), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=TILLER,%s=%s", tillerOwnerLabel, tillerNameLabel, getReleaseName(h)),
+	})
+	return storage, metas, err
+}
+
+// deleteReleaseStorage deletes the records of all the revisions of the
+// release of h, so it is forgotten without deleting its objects. The
+// deletion is recorded in the audit log.
+func (c *Controller) deleteReleaseStorage(h *helmCrdV2.HelmRelease) error {
+	err := c.deleteRevisions(h)
+	c.recordAudit(h.Namespace, h.Name, audit.Delete, getReleaseName(h), nil, "", nil, err)
+	return err
+}
+
+func (c *Controller) deleteRevisions(h *helmCrdV2.HelmRelease) error {
+	storage, metas, err := c.releaseStorage(h)
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		err := storage.Delete(c.releaseTillerNamespace(h), meta.Name)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlabelReleaseStorage removes the HelmRelease labels from the stored
+// revisions of the release of h, so it is neither collected nor seen as owned
+func (c *Controller) unlabelReleaseStorage(h *helmCrdV2.HelmRelease) error {
+	storage, metas, err := c.releaseStorage(h)
+	if err != nil {
+		return err
+	}
+	for _, meta := range metas {
+		if meta.Labels[managedNamespaceLabel] != h.Namespace || meta.Labels[managedNameLabel] != h.Name {
+			continue
+		}
+		labels := map[string]string{}
+		for k, v := range meta.Labels {
+			if k != managedNamespaceLabel && k != managedNameLabel {
+				labels[k] = v
+			}
+		}
+		if err := storage.SetLabels(c.releaseTillerNamespace(h), meta.Name, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectGarbage deletes the Tiller releases deployed by the controller
+// for HelmReleases that no longer exist, e.g. because they were deleted
+// while the controller was down and their finalizer was removed by hand.
+// Only the releases of the default Tiller are collected.
+func (c *Controller) collectGarbage() {
+	metas, err := c.storage.List(c.tillerNamespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=TILLER,%s", tillerOwnerLabel, managedNameLabel),
+	})
+	if err != nil {
+		logger.With("error", err).Warnf("Unable to list Tiller releases")
+		return
+	}
+
+	orphaned := map[string]string{}
+	for _, meta := range metas {
+		rlsName := meta.Labels[tillerNameLabel]
+		key := meta.Labels[managedNamespaceLabel] + "/" + meta.Labels[managedNameLabel]
+		if rlsName == "" || meta.Labels[managedNameLabel] == "" {
+			continue
+		}
+		_, exists, err := c.informer.GetIndexer().GetByKey(key)
+		if err != nil || exists {
+			continue
+		}
+		orphaned[rlsName] = key
+	}
+
+	for rlsName, key := range orphaned {
+		rlog := logger.With("release", rlsName, "helmrelease", key)
+		if c.dryRun {
+			rlog.Infof("Dry-run: would delete orphaned release")
+			continue
+		}
+		rlog.Infof("Deleting release of deleted HelmRelease")
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		if err := c.audited(namespace, name, c.helmClient).Delete(rlsName, helmclient.DeleteOptions{Purge: true}); err != nil && !helmclient.IsNotFound(err) {
+			rlog.With("error", err).Warnf("Unable to delete orphaned release")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func tillerConfigMap(rlsName, version string, labels map[string]string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "kube-system",
+		Name:      rlsName + ".v" + version,
+		Labels:    map[string]string{"OWNER": "TILLER", "NAME": rlsName, "VERSION": version},
+	}}
+	for k, v := range labels {
+		cm.Labels[k] = v
+	}
+	return cm
+}
+
+func TestLabelReleaseStorage(t *testing.T) {
+	h := helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}}
+	controller := prepareTestController(nil, []string{})
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(tillerConfigMap("myns-foo", "2", nil))
+
+	if err := controller.labelReleaseStorage(&h, &release.Release{Name: "myns-foo", Version: 2}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cm, err := controller.kubeClient.Core().ConfigMaps("kube-system").Get("myns-foo.v2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cm.Labels[managedNamespaceLabel] != "myns" || cm.Labels[managedNameLabel] != "foo" || cm.Labels["OWNER"] != "TILLER" {
+		t.Errorf("Unexpected labels %v", cm.Labels)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "kept"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"myns-kept", "myns-orphan", "unmanaged"})
+	for _, cm := range []*corev1.ConfigMap{
+		tillerConfigMap("myns-kept", "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "kept"}),
+		tillerConfigMap("myns-orphan", "1", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "orphan"}),
+		tillerConfigMap("unmanaged", "1", nil),
+	} {
+		controller.kubeClient.Core().ConfigMaps("kube-system").Create(cm)
+	}
+
+	controller.dryRun = true
+	controller.collectGarbage()
+	rels := fakeHelmClient(controller).Releases
+	if len(rels) != 3 {
+		t.Errorf("Expected no release to be deleted in dry-run mode, received %v", rels)
+	}
+
+	controller.dryRun = false
+	controller.collectGarbage()
+	rels = fakeHelmClient(controller).Releases
+	var names []string
+	for _, r := range rels {
+		names = append(names, r.Name)
+	}
+	if len(names) != 2 || names[0] != "myns-kept" || names[1] != "unmanaged" {
+		t.Errorf("Expected only the orphaned release to be deleted, remaining %v", names)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// globalValuesFile is the key of the global values ConfigMap holding the
+// values merged into those of every release
+const globalValuesFile = "values.yaml"
+
+// globalValuesKey returns the key of the global values ConfigMap, "" if
+// the controller has none
+func (c *Controller) globalValuesKey() string {
+	if c.globalValuesConfigMap == "" {
+		return ""
+	}
+	return controllerNamespace() + "/" + c.globalValuesConfigMap
+}
+
+// globalValues returns the values of the global values ConfigMap, nil if
+// the controller has none or it doesn't exist
+func (c *Controller) globalValues() ([]byte, error) {
+	key := c.globalValuesKey()
+	if key == "" {
+		return nil, nil
+	}
+	obj, exists, err := c.configMapInformer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+	values := []byte(obj.(*corev1.ConfigMap).Data[globalValuesFile])
+	if _, err := valuesUtils.Merge(values); err != nil {
+		return nil, fmt.Errorf("invalid global values of ConfigMap %s: %v", key, err)
+	}
+	return values, nil
+}
+
+// globalValuesHandler queues every HelmRelease when the global values
+// ConfigMap is created, changed or deleted
+func (c *Controller) globalValuesHandler() cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil || key == "" || key != c.globalValuesKey() {
+			return
+		}
+		logger.With("configmap", key).Infof("Global values changed")
+		for _, obj := range c.informer.GetStore().List() {
+			if key, err := cache.MetaNamespaceKeyFunc(obj.(*helmCrdV2.HelmRelease)); err == nil {
+				c.queue.Add(key)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Periodic resyncs don't change the resource version
+			if oldObj.(metav1.Object).GetResourceVersion() != newObj.(metav1.Object).GetResourceVersion() {
+				enqueue(newObj)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
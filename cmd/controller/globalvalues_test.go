@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestGlobalValues(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Values: "image:\n  tag: 2.0.0\n",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.globalValuesConfigMap = "helm-crd-global-values"
+
+	// A missing ConfigMap sets no values
+	values, _, err := controller.releaseValues(&h)
+	if err != nil || string(values) != string(h.Spec.Values) {
+		t.Errorf("Expecting the inline values, received %q, %v", values, err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "helm-crd-global-values", ResourceVersion: "1"},
+		Data:       map[string]string{globalValuesFile: "image:\n  registry: mirror.example.com\n  tag: 1.0.0\n"},
+	}
+	controller.configMapInformer.GetStore().Add(cm)
+	controller.globalValuesHandler().OnAdd(cm)
+	if controller.queue.Len() != 1 {
+		t.Errorf("Expecting the HelmReleases to be queued, %d queued", controller.queue.Len())
+	}
+	values, _, err = controller.releaseValues(&h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := "image:\n  registry: mirror.example.com\n  tag: 2.0.0\n"; string(values) != expected {
+		t.Errorf("Expecting %q, received %q", expected, values)
+	}
+
+	cm = cm.DeepCopy()
+	cm.Data[globalValuesFile] = "- not a map\n"
+	controller.configMapInformer.GetStore().Update(cm)
+	if _, _, err := controller.releaseValues(&h); err == nil {
+		t.Errorf("Expecting an error for invalid global values")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// ready returns why c is not ready to reconcile HelmReleases: its caches
+// are not synced yet or the default Tiller is unreachable
+func (c *Controller) ready() error {
+	if !c.HasSynced() {
+		return fmt.Errorf("caches not synced")
+	}
+	if hc, ok := c.helmClient.(helmclient.HealthChecker); ok {
+		return hc.Healthy()
+	}
+	return nil
+}
+
+// healthHandler serves the liveness probe at /healthz, succeeding while
+// the controller runs, and the readiness probe at /readyz
+func (c *Controller) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := c.ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	})
+	return mux
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// emptyInformer returns an informer of an empty list of objects
+func emptyInformer() cache.SharedIndexInformer {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &metav1.List{}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	return cache.NewSharedIndexInformer(lw, &corev1.ConfigMap{}, 0, cache.Indexers{})
+}
+
+// unhealthyClient is a client of an unreachable Tiller
+type unhealthyClient struct {
+	*helmclient.FakeClient
+}
+
+func (c unhealthyClient) Healthy() error {
+	return &helmclient.UnavailableError{Host: "localhost:44134", Err: fmt.Errorf("connection refused")}
+}
+
+func TestHealthHandler(t *testing.T) {
+	controller := prepareTestController(nil, []string{})
+	handler := controller.healthHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != 200 {
+		t.Errorf("Expecting the controller to be live, received %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 503 || !strings.Contains(w.Body.String(), "caches not synced") {
+		t.Errorf("Expecting the controller not to be ready before syncing, received %d %s", w.Code, w.Body.String())
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	for _, informer := range []*cache.SharedIndexInformer{&controller.informer, &controller.policyInformer, &controller.credentialInformer, &controller.setInformer, &controller.namespaceInformer, &controller.secretInformer, &controller.configMapInformer} {
+		*informer = emptyInformer()
+		go (*informer).Run(stop)
+	}
+	if !cache.WaitForCacheSync(stop, controller.HasSynced) {
+		t.Fatalf("Timed out waiting for caches to sync")
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 200 {
+		t.Errorf("Expecting the controller to be ready, received %d %s", w.Code, w.Body.String())
+	}
+
+	controller.helmClient = unhealthyClient{fakeHelmClient(controller)}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 503 || !strings.Contains(w.Body.String(), "unavailable") {
+		t.Errorf("Expecting the controller not to be ready while Tiller is unreachable, received %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorReasonTillerUnavailable(t *testing.T) {
+	unavailable := &helmclient.UnavailableError{Host: "localhost:44134", Err: fmt.Errorf("connection refused")}
+	if reason := errorReason(failed(reasonUpgradeFailed, unavailable)); reason != reasonTillerUnavailable {
+		t.Errorf("Expecting %s, received %s", reasonTillerUnavailable, reason)
+	}
+	if reason := errorReason(unavailable); reason != reasonTillerUnavailable {
+		t.Errorf("Expecting %s, received %s", reasonTillerUnavailable, reason)
+	}
+	if reason := errorReason(failed(reasonUpgradeFailed, fmt.Errorf("upgrade failed"))); reason != reasonUpgradeFailed {
+		t.Errorf("Expecting %s, received %s", reasonUpgradeFailed, reason)
+	}
+}
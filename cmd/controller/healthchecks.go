@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// healthCheckTimeout bounds the requests of URL health checks
+const healthCheckTimeout = 10 * time.Second
+
+// healthCheckName returns the name of hc, or what it checks
+func healthCheckName(hc helmCrdV2.HealthCheck) string {
+	switch {
+	case hc.Name != "":
+		return hc.Name
+	case hc.Job != "":
+		return "Job " + hc.Job
+	case hc.URL != "":
+		return hc.URL
+	case hc.Resource != nil:
+		return fmt.Sprintf("%s %s %s", hc.Resource.Kind, hc.Resource.Name, hc.Resource.FieldPath)
+	}
+	return "health check"
+}
+
+// runHealthCheck returns whether hc passes, and if not why, failed being
+// true if it never will, i.e. its Job failed. Objects are looked up in the
+// release namespace.
+func (c *Controller) runHealthCheck(hc helmCrdV2.HealthCheck, objects objectClient, namespace string) (ok, failed bool, reason string) {
+	switch {
+	case hc.Job != "":
+		obj := manifest.Object{APIVersion: "batch/v1", Kind: "Job", Name: hc.Job}
+		live, err := objects.Get(obj, namespace)
+		if err != nil {
+			return false, false, objectError(err)
+		}
+		return workloadReadiness("Job", live)
+	case hc.URL != "":
+		req, err := http.NewRequest("GET", hc.URL, nil)
+		if err != nil {
+			return false, false, err.Error()
+		}
+		res, err := c.healthClient.Do(req)
+		if err != nil {
+			return false, false, err.Error()
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return false, false, res.Status
+		}
+		return true, false, ""
+	case hc.Resource != nil:
+		r := hc.Resource
+		obj := manifest.Object{APIVersion: r.APIVersion, Kind: r.Kind, Name: r.Name}
+		live, err := objects.Get(obj, namespace)
+		if err != nil {
+			return false, false, objectError(err)
+		}
+		value, found, err := valuesUtils.Lookup(live, r.FieldPath)
+		if err != nil {
+			return false, false, err.Error()
+		}
+		if !found {
+			return false, false, fmt.Sprintf("%s not set", r.FieldPath)
+		}
+		if s := fmt.Sprint(value); s != r.Value {
+			return false, false, fmt.Sprintf("%s is %q, expecting %q", r.FieldPath, s, r.Value)
+		}
+		return true, false, ""
+	}
+	return false, false, "nothing to check"
+}
+
+// objectError describes the error getting an object checked
+func objectError(err error) string {
+	if k8sErrors.IsNotFound(err) {
+		return "not found"
+	}
+	return err.Error()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestRunHealthCheck(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{
+		"Job/migrate": {
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}},
+		},
+		"Job/seed": {
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"}}},
+		},
+		"Database/foo": {
+			"status": map[string]interface{}{"phase": "Provisioning", "replicas": float64(2)},
+		},
+	}}
+	controller := prepareTestController(nil, []string{})
+
+	tests := []struct {
+		name   string
+		check  helmCrdV2.HealthCheck
+		ok     bool
+		failed bool
+		reason string
+	}{
+		{"complete job", helmCrdV2.HealthCheck{Job: "migrate"}, true, false, ""},
+		{"failed job", helmCrdV2.HealthCheck{Job: "seed"}, false, true, "failed: BackoffLimitExceeded"},
+		{"missing job", helmCrdV2.HealthCheck{Job: "other"}, false, false, "not found"},
+		{"healthy URL", helmCrdV2.HealthCheck{URL: server.URL}, true, false, ""},
+		{
+			"resource field",
+			helmCrdV2.HealthCheck{Resource: &helmCrdV2.ResourceHealthCheck{APIVersion: "example.com/v1", Kind: "Database", Name: "foo", FieldPath: "status.replicas", Value: "2"}},
+			true, false, "",
+		},
+		{
+			"resource field not matching",
+			helmCrdV2.HealthCheck{Resource: &helmCrdV2.ResourceHealthCheck{APIVersion: "example.com/v1", Kind: "Database", Name: "foo", FieldPath: "status.phase", Value: "Ready"}},
+			false, false, `status.phase is "Provisioning", expecting "Ready"`,
+		},
+		{
+			"resource field not set",
+			helmCrdV2.HealthCheck{Resource: &helmCrdV2.ResourceHealthCheck{APIVersion: "example.com/v1", Kind: "Database", Name: "foo", FieldPath: "status.endpoint", Value: "db"}},
+			false, false, "status.endpoint not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, failed, reason := controller.runHealthCheck(tt.check, objects, "myns")
+			if ok != tt.ok || failed != tt.failed || reason != tt.reason {
+				t.Errorf("Expecting %v %v %q, received %v %v %q", tt.ok, tt.failed, tt.reason, ok, failed, reason)
+			}
+		})
+	}
+
+	// Health checks gate the Ready condition along with workloads
+	healthy = false
+	deployed := metav1.NewTime(time.Now())
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{HealthChecks: []helmCrdV2.HealthCheck{
+			{Job: "migrate"},
+			{Name: "api", URL: server.URL},
+		}},
+	}
+	controller.objects = objects
+	status := helmCrdV2.HelmReleaseStatus{ReleaseName: "foo", Revision: 1, LastDeployed: &deployed}
+	if !healthCheckEnabled(h) {
+		t.Fatalf("Expecting health checks to enable checking the release health")
+	}
+	controller.assessHealth(h, &status)
+	cond := getCondition(&status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != reasonProgressing || cond.Message != "api: 503 Service Unavailable" {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+	healthy = true
+	controller.assessHealth(h, &status)
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseReady); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+}
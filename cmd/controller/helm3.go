@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helm3"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+const (
+	reasonMigratedToHelm3      = "MigratedToHelm3"
+	reasonHelm3MigrationFailed = "Helm3MigrationFailed"
+	// maxMigratedRevisions bounds the revisions copied to Helm 3 storage,
+	// as Helm 3 keeps 10 by default
+	maxMigratedRevisions = 256
+)
+
+// helm3MigrationRequested returns whether the revisions of the release
+// of h are copied to Helm 3 storage
+func helm3MigrationRequested(h *helmCrdV2.HelmRelease) bool {
+	return h.Annotations[helmCrdV2.MigrateToHelm3Annotation] == "true"
+}
+
+// migrateToHelm3 copies the revisions of the release of h, up to rel, to
+// the Secrets Helm 3 stores releases in, in the release namespace, so that
+// a Helm 3 client or controller takes the release over without
+// redeploying it. Revisions already copied are overwritten, as their
+// status changes with later revisions. Tiller records are kept.
+func (c *Controller) migrateToHelm3(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, rel *release.Release) error {
+	history, err := helmClient.History(rel.GetName(), maxMigratedRevisions)
+	if err != nil {
+		return err
+	}
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		return err
+	}
+	for _, r := range history {
+		secret, err := helm3.Secret(r)
+		if err != nil {
+			return fmt.Errorf("revision %d: %v", r.GetVersion(), err)
+		}
+		data, err := json.Marshal(secret)
+		if err != nil {
+			return err
+		}
+		obj := manifest.Object{APIVersion: "v1", Kind: "Secret", Namespace: secret.Namespace, Name: secret.Name}
+		if err := json.Unmarshal(data, &obj.Content); err != nil {
+			return err
+		}
+		if err := objects.Apply(obj, secret.Namespace); err != nil {
+			return fmt.Errorf("unable to store revision %d: %v", r.GetVersion(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestMigrateToHelm3(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "myns",
+			Name:        "foo",
+			Annotations: map[string]string{helmCrdV2.MigrateToHelm3Annotation: "true"},
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{}}
+	controller.objects = objects
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if expected := []string{fmt.Sprintf("myns/Secret/sh.helm.release.v1.myns-foo.v%d", res.Status.Revision)}; !reflect.DeepEqual(objects.applied, expected) {
+		t.Errorf("Expecting %v to be applied, received %v", expected, objects.applied)
+	}
+	if res.Status.Helm3MigratedRevision != res.Status.Revision {
+		t.Errorf("Expecting revision %d to be migrated, received %d", res.Status.Revision, res.Status.Helm3MigratedRevision)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != reasonMigratedToHelm3 {
+		t.Errorf("Expecting a MigratedToHelm3 event, received %+v", events.Items)
+	}
+	if releaseNeedsResync(res) {
+		t.Errorf("Expecting migrated releases not to be resynced")
+	}
+}
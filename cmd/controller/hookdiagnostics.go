@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// reasonHookFailed is the reason of the events describing the failed hook
+// containers of a failed install or upgrade
+const reasonHookFailed = "HookFailed"
+
+// reasonHooksNotSupported is the reason of the Ready condition of
+// HelmReleases whose chart has hooks, which aren't run with --executor=apply
+const reasonHooksNotSupported = "HooksNotSupported"
+
+const (
+	// hookLogTailLines is the number of log lines kept of failed hook containers
+	hookLogTailLines = 10
+	// maxHookLogLen caps the logs kept of each hook container
+	maxHookLogLen = 1024
+)
+
+// fetchPodLogs returns the last tailLines lines of the logs of a container
+func (c *Controller) fetchPodLogs(namespace, pod, container string, tailLines int64) (string, error) {
+	logs, err := c.kubeClient.Core().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &tailLines,
+	}).Do().Raw()
+	return string(logs), err
+}
+
+// withHookDiagnostics returns err, the failed install or upgrade of the
+// release of h, along with the termination reason and last log lines of
+// the failed containers of its Job and Pod hooks, each also recorded as
+// an event. Releases of remote clusters are not diagnosed.
+func (c *Controller) withHookDiagnostics(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, rlsName string, err error) error {
+	if h.Spec.KubeConfigSecretRef != nil || helmclient.IsUnavailable(err) {
+		return err
+	}
+	history, histErr := helmClient.History(rlsName, 1)
+	if histErr != nil || len(history) == 0 {
+		return err
+	}
+	diags := c.hookDiagnostics(history[0])
+	if len(diags) == 0 {
+		return err
+	}
+	for _, diag := range diags {
+		c.recordEvent(h, corev1.EventTypeWarning, reasonHookFailed, diag)
+	}
+	return fmt.Errorf("%v\n%s", err, strings.Join(diags, "\n"))
+}
+
+// hookDiagnostics describes the failed containers of the Job and Pod
+// hooks of rel that still exist
+func (c *Controller) hookDiagnostics(rel *release.Release) []string {
+	var diags []string
+	for _, hook := range rel.GetHooks() {
+		namespace := rel.GetNamespace()
+		if objs, err := manifest.Objects(hook.GetManifest()); err == nil && len(objs) == 1 && objs[0].Namespace != "" {
+			namespace = objs[0].Namespace
+		}
+		switch hook.GetKind() {
+		case "Job":
+			diags = append(diags, c.jobDiagnostics(namespace, hook.GetName())...)
+		case "Pod":
+			pod, err := c.kubeClient.Core().Pods(namespace).Get(hook.GetName(), metav1.GetOptions{})
+			if err != nil {
+				if !k8sErrors.IsNotFound(err) {
+					logger.With("namespace", namespace, "pod", hook.GetName(), "error", err).Warnf("Unable to get hook pod")
+				}
+				continue
+			}
+			diags = append(diags, c.podDiagnostics("hook Pod "+namespace+"/"+pod.Name, pod)...)
+		}
+	}
+	return diags
+}
+
+// jobDiagnostics describes the failed containers of the pods of a Job
+// hook, prefixed with the reason the Job failed if it did
+func (c *Controller) jobDiagnostics(namespace, name string) []string {
+	job, err := c.kubeClient.BatchV1().Jobs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			logger.With("namespace", namespace, "job", name, "error", err).Warnf("Unable to get hook job")
+		}
+		return nil
+	}
+	prefix := "hook Job " + namespace + "/" + name
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			prefix += " " + cond.Reason
+		}
+	}
+	pods, err := c.kubeClient.Core().Pods(namespace).List(metav1.ListOptions{LabelSelector: "job-name=" + name})
+	if err != nil {
+		logger.With("namespace", namespace, "job", name, "error", err).Warnf("Unable to list hook job pods")
+		return nil
+	}
+	var diags []string
+	for i := range pods.Items {
+		diags = append(diags, c.podDiagnostics(prefix+", pod "+pods.Items[i].Name, &pods.Items[i])...)
+	}
+	return diags
+}
+
+// podDiagnostics describes the containers of pod that terminated with a
+// non-zero exit code, with their last log lines, or are waiting on an
+// error such as an image pull failure
+func (c *Controller) podDiagnostics(prefix string, pod *corev1.Pod) []string {
+	var diags []string
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		term := cs.State.Terminated
+		if term == nil {
+			term = cs.LastTerminationState.Terminated
+		}
+		var diag string
+		switch {
+		case term != nil && term.ExitCode != 0:
+			diag = fmt.Sprintf("%s container %s: %s (exit code %d)", prefix, cs.Name, term.Reason, term.ExitCode)
+			if term.Message != "" {
+				diag += ": " + strings.TrimSpace(term.Message)
+			}
+			logs, err := c.podLogs(pod.Namespace, pod.Name, cs.Name, hookLogTailLines)
+			if err != nil {
+				logger.With("namespace", pod.Namespace, "pod", pod.Name, "error", err).Warnf("Unable to get hook pod logs")
+			} else if logs = strings.TrimSpace(logs); logs != "" {
+				if len(logs) > maxHookLogLen {
+					logs = "..." + logs[len(logs)-maxHookLogLen:]
+				}
+				diag += "\n" + logs
+			}
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "" &&
+			cs.State.Waiting.Reason != "ContainerCreating" && cs.State.Waiting.Reason != "PodInitializing":
+			diag = fmt.Sprintf("%s container %s: %s", prefix, cs.Name, cs.State.Waiting.Reason)
+			if cs.State.Waiting.Message != "" {
+				diag += ": " + cs.State.Waiting.Message
+			}
+		default:
+			continue
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// failingHookClient is a Helm client whose upgrades fail on a Job hook,
+// recording the failed revision the way Tiller does
+type failingHookClient struct {
+	*helmclient.FakeClient
+}
+
+func (c *failingHookClient) Upgrade(rlsName string, ch *chart.Chart, opts helmclient.UpgradeOptions) (*release.Release, error) {
+	rel := helmclient.MockRelease(rlsName, "myns", 2, ch, release.Status_FAILED)
+	rel.Hooks = []*release.Hook{
+		{Name: "migrate", Kind: "Job", Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n"},
+		{Name: "cleanup", Kind: "Job", Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: cleanup\n"},
+	}
+	c.Releases = append(c.Releases, rel)
+	return nil, fmt.Errorf("UPGRADE FAILED: job failed: BackoffLimitExceeded")
+}
+
+func TestHookDiagnostics(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+		Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+	controller.helmClient = &failingHookClient{fakeHelmClient(controller)}
+	controller.kubeClient.BatchV1().Jobs("myns").Create(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "migrate"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"},
+		}},
+	})
+	controller.kubeClient.Core().Pods("myns").Create(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "migrate-x7k2p", Labels: map[string]string{"job-name": "migrate"}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "migrate", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 2}}},
+			{Name: "sidecar", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+		}},
+	})
+	controller.kubeClient.Core().Pods("myns").Create(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "other", Labels: map[string]string{"job-name": "other"}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "other", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+		}},
+	})
+	controller.podLogs = func(namespace, pod, container string, tailLines int64) (string, error) {
+		if tailLines != hookLogTailLines {
+			t.Errorf("Unexpected tail lines %d", tailLines)
+		}
+		return fmt.Sprintf("logs of %s/%s %s\n", namespace, pod, container), nil
+	}
+
+	err := controller.updateRelease("myns/foo")
+	expected := "hook Job myns/migrate BackoffLimitExceeded, pod migrate-x7k2p container migrate: Error (exit code 2)\nlogs of myns/migrate-x7k2p migrate"
+	if errorReason(err) != reasonUpgradeFailed || err.Error() != "UPGRADE FAILED: job failed: BackoffLimitExceeded\n"+expected {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != reasonHookFailed || events.Items[0].Message != expected {
+		t.Errorf("Unexpected events %+v", events.Items)
+	}
+}
+
+func TestPodDiagnosticsWaiting(t *testing.T) {
+	controller := &Controller{}
+	pod := &corev1.Pod{Status: corev1.PodStatus{
+		InitContainerStatuses: []corev1.ContainerStatus{
+			{Name: "init", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image \"busybox:nope\""}}},
+		},
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "main", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}},
+		},
+	}}
+	diags := controller.podDiagnostics("hook Pod myns/foo", pod)
+	if len(diags) != 1 || !strings.HasPrefix(diags[0], "hook Pod myns/foo container init: ImagePullBackOff: ") {
+		t.Errorf("Unexpected diagnostics %q", diags)
+	}
+}
+
+// hooksNotSupportedClient is a Helm client rejecting charts with hooks,
+// as the apply executor does
+type hooksNotSupportedClient struct {
+	*helmclient.FakeClient
+}
+
+func (c *hooksNotSupportedClient) Install(ch *chart.Chart, namespace string, opts helmclient.InstallOptions) (*release.Release, error) {
+	if !opts.DryRun && !opts.DisableHooks {
+		return nil, &helmclient.HooksNotSupportedError{Hooks: []string{"Job/migrate"}}
+	}
+	return c.FakeClient.Install(ch, namespace, opts)
+}
+
+func TestHooksNotSupported(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.helmClient = &hooksNotSupportedClient{fakeHelmClient(controller)}
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Reason != reasonHooksNotSupported || !strings.Contains(cond.Message, "spec.disableHooks") {
+		t.Errorf("Expecting a HooksNotSupported Ready condition, received %+v", cond)
+	}
+	if res.Status.Phase != helmCrdV2.PhaseFailed || res.Status.Operation != nil {
+		t.Errorf("Expecting the release to be failed, received %+v", res.Status)
+	}
+
+	res.Spec.DisableHooks = true
+	controller = prepareTestController([]helmCrdV2.HelmRelease{*res}, []string{})
+	fake := fakeHelmClient(controller)
+	controller.helmClient = &hooksNotSupportedClient{fake}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fake.Releases) != 1 {
+		t.Errorf("Expecting the release to be installed without its hooks")
+	}
+}
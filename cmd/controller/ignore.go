@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+// ignored returns whether the release of h is left alone with the
+// IgnoreAnnotation
+func ignored(h *helmCrdV2.HelmRelease) bool {
+	return h.Annotations[helmCrdV2.IgnoreAnnotation] == "true"
+}
+
+// reconcileIgnored records the deployed release of an ignored HelmRelease
+// in its status without installing, upgrading or rolling it back. Drift
+// is reported but never corrected, so that objects patched by hand are
+// kept until the annotation is removed.
+func (c *Controller) reconcileIgnored(helmObj *helmCrdV2.HelmRelease, rlog *logging.Logger) error {
+	rlog.Debugf("Release is ignored")
+	helmClient, err := c.helmClientFor(helmObj)
+	if err != nil {
+		return err
+	}
+	rlsName := getReleaseName(helmObj)
+	history, err := helmClient.History(rlsName, 1)
+	if err != nil && !helmclient.IsNotFound(err) {
+		return err
+	}
+
+	status := helmObj.Status
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseIgnored,
+		Status:  corev1.ConditionTrue,
+		Reason:  "IgnoreAnnotation",
+		Message: fmt.Sprintf("Release %s is not managed while annotated with %s", rlsName, helmCrdV2.IgnoreAnnotation),
+	})
+	if err == nil && len(history) > 0 {
+		setDeployedStatus(&status, history[0])
+		if driftDetectionEnabled(helmObj) {
+			warnOnly := helmObj.DeepCopy()
+			warnOnly.Spec.DriftDetection.Mode = helmCrdV2.DriftDetectionWarn
+			if cond, err := c.detectDrift(warnOnly, history[0]); err != nil {
+				rlog.With("error", err).Warnf("Unable to detect drift")
+			} else {
+				setCondition(&status, cond)
+			}
+		}
+	}
+	_, err = c.updateStatus(helmObj, status)
+	return err
+}
+
+// clearIgnored removes the Ignored condition of h once it is managed
+// again, returning the updated object
+func (c *Controller) clearIgnored(h *helmCrdV2.HelmRelease) (*helmCrdV2.HelmRelease, error) {
+	if getCondition(&h.Status, helmCrdV2.HelmReleaseIgnored) == nil {
+		return h, nil
+	}
+	status := h.Status
+	removeCondition(&status, helmCrdV2.HelmReleaseIgnored)
+	return c.updateStatus(h, status)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestHelmReleaseIgnored(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "myns",
+			Name:        "foo",
+			Finalizers:  []string{releaseFinalizer},
+			Annotations: map[string]string{helmCrdV2.IgnoreAnnotation: "true"},
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			DriftDetection: &helmCrdV2.DriftDetectionSpec{Mode: helmCrdV2.DriftDetectionCorrect},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	objects := &fakeObjectClient{}
+	controller.objects = objects
+	patched := &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "0.9.0"}}
+	fakeHelmClient(controller).Releases = []*release.Release{
+		helmclient.MockRelease("myns-foo", "myns", 3, patched, release.Status_DEPLOYED),
+	}
+
+	if !releaseNeedsResync(&h) {
+		t.Errorf("Expecting ignored releases to be resynced")
+	}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rels := fakeHelmClient(controller).Releases; len(rels) != 1 {
+		t.Errorf("Expecting ignored releases not to be upgraded, received %d revisions", len(rels))
+	}
+	if len(objects.applied) != 0 {
+		t.Errorf("Expecting the drift of ignored releases not to be corrected, applied %v", objects.applied)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if res.Status.Revision != 3 || res.Status.ChartVersion != "0.9.0" {
+		t.Errorf("Expecting the status of the deployed release, received revision %d of %s", res.Status.Revision, res.Status.ChartVersion)
+	}
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseIgnored); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("Expecting the Ignored condition, received %+v", res.Status.Conditions)
+	}
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseDrifted); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("Expecting the drift to be reported, received %+v", res.Status.Conditions)
+	}
+
+	delete(res.Annotations, helmCrdV2.IgnoreAnnotation)
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rels := fakeHelmClient(controller).Releases; len(rels) != 2 {
+		t.Errorf("Expecting the release to be upgraded once managed again, received %d revisions", len(rels))
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if getCondition(&res.Status, helmCrdV2.HelmReleaseIgnored) != nil {
+		t.Errorf("Expecting the Ignored condition to be removed")
+	}
+
+	// Deleting an ignored HelmRelease leaves its release alone
+	controller.kubeClient.Core().ConfigMaps("kube-system").Create(
+		tillerConfigMap("myns-foo", "4", map[string]string{managedNamespaceLabel: "myns", managedNameLabel: "foo"}))
+	res.Annotations = map[string]string{helmCrdV2.IgnoreAnnotation: "true"}
+	res.DeletionTimestamp = &metav1.Time{}
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, call := range fakeHelmClient(controller).Calls {
+		if call == "Delete" {
+			t.Errorf("Expecting ignored releases not to be deleted")
+		}
+	}
+	if rels := fakeHelmClient(controller).Releases; len(rels) != 2 {
+		t.Errorf("Expecting the release to be kept, received %d revisions", len(rels))
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if hasFinalizer(res) {
+		t.Errorf("Expecting the finalizer to be removed, received %v", res.Finalizers)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"sort"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// inlineChart assembles the chart defined by src, as if loaded from a
+// directory with its Chart.yaml, values.yaml and templates/. Templates
+// are sorted by name, as chartutil loads them.
+func inlineChart(src *helmCrdV2.InlineChartSource) *chart.Chart {
+	names := make([]string, 0, len(src.Templates))
+	for name := range src.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	templates := make([]*chart.Template, 0, len(names))
+	for _, name := range names {
+		templates = append(templates, &chart.Template{Name: "templates/" + name, Data: []byte(src.Templates[name])})
+	}
+	return &chart.Chart{
+		Metadata: &chart.Metadata{
+			ApiVersion:  "v1",
+			Name:        src.Metadata.Name,
+			Version:     src.Metadata.Version,
+			AppVersion:  src.Metadata.AppVersion,
+			Description: src.Metadata.Description,
+		},
+		Templates: templates,
+		Values:    &chart.Config{Raw: string(src.Values)},
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/chartutil"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/render"
+)
+
+func inlineRelease() helmCrdV2.HelmRelease {
+	return helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{
+				Metadata: helmCrdV2.InlineChartMetadata{Name: "glue", Version: "0.1.0"},
+				Templates: map[string]string{
+					"configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ include "glue.name" . }}
+data:
+  greeting: {{ .Values.greeting }}
+`,
+					"_helpers.tpl": `{{- define "glue.name" -}}{{ .Release.Name }}-{{ .Chart.Name }}{{- end -}}`,
+				},
+				Values: "greeting: hello\n",
+			}},
+		},
+	}
+}
+
+func TestInlineChart(t *testing.T) {
+	h := inlineRelease()
+	ch := inlineChart(h.Spec.Chart.Inline)
+	if names := []string{ch.Templates[0].Name, ch.Templates[1].Name}; names[0] != "templates/_helpers.tpl" || names[1] != "templates/configmap.yaml" {
+		t.Errorf("Unexpected templates %v", names)
+	}
+	res, err := render.Render(ch, nil, chartutil.ReleaseOptions{Name: "rls"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, e := range []string{"name: rls-glue", "greeting: hello"} {
+		if !strings.Contains(res.Manifest, e) {
+			t.Errorf("Expecting %q in the manifest, received %s", e, res.Manifest)
+		}
+	}
+
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	releases := fakeHelmClient(controller).Releases
+	if len(releases) != 1 || releases[0].GetChart().GetMetadata().GetName() != "glue" {
+		t.Fatalf("Expecting the inline chart to be installed, received %v", releases)
+	}
+	hr, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if hr.Status.ResolvedVersion != "0.1.0" {
+		t.Errorf("Expecting the inline chart version to be resolved, received %q", hr.Status.ResolvedVersion)
+	}
+}
+
+func TestInlineChartRepoPolicy(t *testing.T) {
+	controller := prepareTestController([]helmCrdV2.HelmRelease{inlineRelease()}, []string{})
+	controller.repoPolicy = policy.RepoPolicy{Allowed: []string{"http://charts.example.com/*"}}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting rejected HelmReleases not to be retried, received %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting inline charts not to be installed with allowed repositories")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); cond == nil || cond.Reason != reasonRepositoryNotAllowed {
+		t.Errorf("Expecting a RepositoryNotAllowed Ready condition, received %+v", cond)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/lint"
+)
+
+// reasonLintFailed is the reason of the LintFailed and Ready conditions
+// of HelmReleases whose chart has lint errors, with the enforce mode
+const reasonLintFailed = "LintFailed"
+
+// maxLintWarnings caps the lint messages recorded in the status
+const maxLintWarnings = 20
+
+// lintChart lints ch with values, as released with rlsName into
+// namespace, returning the warnings and errors and an error if there
+// are errors that must block the release
+func lintChart(h *helmCrdV2.HelmRelease, ch *chart.Chart, rlsName, namespace string, values []byte, install bool, caps *chartutil.Capabilities) ([]string, error) {
+	msgs := lint.Chart(ch, values, chartutil.ReleaseOptions{Name: rlsName, Namespace: namespace, IsInstall: install, IsUpgrade: !install}, caps)
+	var warnings []string
+	errors := 0
+	for _, m := range msgs {
+		if m.Severity < lint.Warning {
+			continue
+		}
+		if m.Severity == lint.Error {
+			errors++
+		}
+		warnings = append(warnings, m.String())
+	}
+	if len(warnings) > maxLintWarnings {
+		warnings = append(warnings[:maxLintWarnings], fmt.Sprintf("and %d more", len(warnings)-maxLintWarnings))
+	}
+	if errors > 0 && h.Spec.Lint.Mode == helmCrdV2.LintEnforce {
+		return warnings, fmt.Errorf("chart has %d lint errors", errors)
+	}
+	return warnings, nil
+}
+
+// rejectLintErrors records in the LintFailed and Ready conditions of h
+// that its chart has lint errors, along with the lint warnings
+func (c *Controller) rejectLintErrors(h *helmCrdV2.HelmRelease, warnings []string, err error) error {
+	h = h.DeepCopy()
+	h.Status.LintWarnings = warnings
+	return c.rejectRelease(h, reasonLintFailed, err, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseLintFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonLintFailed,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestLintChart(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Lint: &helmCrdV2.LintSpec{Mode: helmCrdV2.LintEnforce},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	// The chart of fakeLoadChart has no apiVersion and no templates
+	expected := []string{
+		"[ERROR] Chart.yaml: apiVersion is required",
+		"[WARNING] templates/: directory not found",
+	}
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting lint errors not to be retried, received %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseLintFailed); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("Unexpected LintFailed condition %+v", cond)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonLintFailed {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	if !reflect.DeepEqual(res.Status.LintWarnings, expected) {
+		t.Errorf("Expecting lint warnings %q, received %q", expected, res.Status.LintWarnings)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+
+	// The warn mode installs the release, keeping the warnings
+	res.Spec.Lint.Mode = helmCrdV2.LintWarn
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseLintFailed); cond != nil {
+		t.Errorf("Expecting the LintFailed condition to be removed, received %+v", cond)
+	}
+	if !reflect.DeepEqual(res.Status.LintWarnings, expected) {
+		t.Errorf("Expecting lint warnings %q, received %q", expected, res.Status.LintWarnings)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be deployed, received %v", deployed)
+	}
+}
@@ -1,32 +1,183 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/pflag"
+	"golang.org/x/crypto/openpgp"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/helm/pkg/chartutil"
-	"k8s.io/helm/pkg/helm"
 	"k8s.io/helm/pkg/helm/environment"
 
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
 	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/audit"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cosign"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/notify"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/sops"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/tracing"
 )
 
 var (
-	settings environment.EnvSettings
+	settings      environment.EnvSettings
+	resyncPeriod  time.Duration
+	dryRun        bool
+	sopsKeyring   string
+	sopsAgeKeys   string
+	fulcioRoots   string
+	rekorKey      string
+	clusterDomain string
+	notifications string
+	auditLog      string
+	logLevel      string
+	logFormat     string
+	otlpEndpoint  string
+	maxRetries    int
+	workers       int
+	rlsTimeout    time.Duration
+	settingsCM    string
+	globalValues  string
+	retryBase     time.Duration
+	retryMax      time.Duration
+	httpAttempts  int
+	httpBase      time.Duration
+	httpMax       time.Duration
+	httpTimeout   time.Duration
+	httpOptions   chartUtils.TransportOptions
+	maxChartSize  int64
+	offlineMode   bool
+	offlineMirror []string
+	offlineCache  string
+	chartProxy    string
+	shardFlag     string
+	shardKey      string
+	recoveryFlag  string
+	maxDownloads  int
+	maxHostDls    int
+	maxTillerOps  int
+	maxHostOps    int
+	repoFailures  int
+	repoCooldown  time.Duration
+	gcInterval    time.Duration
+	executor      string
+	repoURL       string
+	repoAuth      string
+	authScope     string
+	denyAuth      bool
+	labelObjects  bool
+	allowedRepos  []string
+	tillerNSs     []string
+	tillerHosts   []string
+	deniedRepos   []string
+	regoCM        string
+	restrictNS    bool
+	enablePprof   bool
+	pprofAddress  string
+	gracePeriod   time.Duration
+	healthAddress string
+	apiAddress    string
+	apiTokenFile  string
+	webhookAddr   string
+	webhookSecret string
+	tillerOptions = defaultTillerOptions
+
+	logger = logging.New(os.Stderr, logging.Info, logging.TextFormat)
 )
 
 func init() {
 	settings.AddFlags(pflag.CommandLine)
+	pflag.DurationVar(&resyncPeriod, "resync-period", 5*time.Minute, "interval at which releases with a version range or drift detection are resynced")
+	pflag.StringVar(&sopsKeyring, "sops-keyring", "", "file with the armored PGP private keys decrypting SOPS values, usually mounted from a Secret")
+	pflag.StringVar(&sopsAgeKeys, "sops-age-keys", "", "age keys file with the AGE-SECRET-KEY-1 identities decrypting SOPS values, usually mounted from a Secret")
+	pflag.StringVar(&fulcioRoots, "sigstore-fulcio-roots", "", "PEM file with the Fulcio root and intermediate certificates issuing the certificates of keyless chart signatures")
+	pflag.StringVar(&rekorKey, "sigstore-rekor-public-key", "", "PEM file with the public key of the Rekor transparency log countersigning keyless chart signatures")
+	pflag.StringVar(&clusterDomain, "cluster-domain", "cluster.local", "cluster DNS domain, substituted for ${CLUSTER_DOMAIN} in values")
+	pflag.StringVar(&notifications, "notifications-config", "", "YAML file configuring the Slack, webhook and SMTP notifications of release lifecycle events, usually mounted from a ConfigMap or Secret")
+	pflag.StringVar(&auditLog, "audit-log", "", "Record every install, upgrade, rollback and deletion of releases as JSON: in this file, on stdout with \"-\", or POSTed to this http(s) URL")
+	pflag.StringVar(&logLevel, "log-level", "info", "minimum level of logged messages: debug, info, warn or error")
+	pflag.StringVar(&logFormat, "log-format", "text", "log format: text or json")
+	pflag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OpenTelemetry collector OTLP/HTTP endpoint receiving reconcile traces, e.g. http://otel-collector:4318. Tracing is disabled if empty.")
+	pflag.IntVar(&maxRetries, "max-retries", defaultMaxRetries, "number of times a failed reconcile is retried before giving up, unless overridden by spec.retries")
+	pflag.IntVar(&workers, "workers", defaultWorkers, "number of HelmReleases reconciled concurrently")
+	pflag.DurationVar(&rlsTimeout, "release-timeout", 0, "how long Tiller waits for the installs and upgrades of HelmReleases not setting spec.timeout, Tiller's default if zero")
+	pflag.StringVar(&settingsCM, "settings-configmap", "", "name of a ConfigMap of the controller namespace whose logLevel, defaultRepoURL, maxRetries, releaseTimeout and workers keys override the flags of the same settings, applied without a restart when it changes")
+	pflag.StringVar(&globalValues, "global-values-configmap", "", "name of a ConfigMap of the controller namespace whose values.yaml key is merged into the values of every release, with the lowest precedence")
+	pflag.DurationVar(&retryBase, "retry-base-delay", 5*time.Millisecond, "delay before the first retry of a failed reconcile, doubled on every further failure")
+	pflag.DurationVar(&retryMax, "retry-max-delay", 1000*time.Second, "maximum delay between retries of a failed reconcile")
+	pflag.IntVar(&httpAttempts, "http-attempts", 3, "maximum number of attempts of chart repository requests failing with a connection error or a 5xx response")
+	pflag.DurationVar(&httpBase, "http-retry-base-delay", 500*time.Millisecond, "delay before retrying a failed chart repository request, doubled on every further attempt and jittered")
+	pflag.DurationVar(&httpMax, "http-retry-max-delay", 10*time.Second, "maximum delay between attempts of a chart repository request")
+	pflag.DurationVar(&httpTimeout, "http-timeout", defaultTimeoutSeconds*time.Second, "maximum duration of a chart repository request, including downloading the response. Unlimited if zero.")
+	pflag.DurationVar(&httpOptions.DialTimeout, "http-dial-timeout", 10*time.Second, "maximum duration of establishing a connection to a chart repository")
+	pflag.DurationVar(&httpOptions.TLSHandshakeTimeout, "http-tls-handshake-timeout", 10*time.Second, "maximum duration of the TLS handshake with a chart repository")
+	pflag.DurationVar(&httpOptions.ResponseHeaderTimeout, "http-response-header-timeout", 30*time.Second, "maximum time waiting for the response headers of a chart repository request")
+	pflag.IntVar(&httpOptions.MaxConnsPerHost, "http-max-conns-per-host", 32, "maximum number of connections to each chart repository host, further requests waiting for one. Unlimited if zero.")
+	pflag.IntVar(&httpOptions.MaxIdleConnsPerHost, "http-max-idle-conns-per-host", 16, "number of idle connections kept alive to each chart repository host")
+	pflag.IntVar(&maxDownloads, "max-concurrent-downloads", 0, "maximum number of chart repository requests in flight, including reading the chart archives, further requests waiting for one to finish. Unlimited if zero.")
+	pflag.IntVar(&maxHostDls, "max-concurrent-downloads-per-host", 0, "maximum number of requests in flight to each chart repository host. Unlimited if zero.")
+	pflag.IntVar(&maxTillerOps, "max-concurrent-tiller-ops", 0, "maximum number of Tiller operations running concurrently, over all Tillers, further operations waiting for one to finish. Unlimited if zero.")
+	pflag.IntVar(&maxHostOps, "max-concurrent-tiller-ops-per-host", 0, "maximum number of operations running concurrently on each Tiller, or each cluster with --executor=apply. Unlimited if zero.")
+	pflag.IntVar(&repoFailures, "repo-failure-threshold", defaultRepoFailureThreshold, "number of consecutive failed requests to a chart repository host after which requests to it fail fast for --repo-cooldown, with a RepoUnavailable condition. Disabled if zero.")
+	pflag.DurationVar(&repoCooldown, "repo-cooldown", defaultRepoCooldown, "time requests to a failing chart repository host fail fast before one is sent to check whether it recovered")
+	pflag.StringVar(&shardFlag, "shard", "", "<index>/<count> partition of the HelmReleases reconciled by this instance, counted from 0, to run count instances each reconciling its shard. All HelmReleases are reconciled if empty. Only shard 0 collects garbage.")
+	pflag.StringVar(&recoveryFlag, "recovery-policy", string(helmCrdV2.RecoveryWait), "how releases left pending by an interrupted install or upgrade are recovered once it timed out, unless set by spec.recovery: wait, failing the reconcile, rollback, rolling back or deleting the release, or retry, then installing or upgrading it again")
+	pflag.StringVar(&shardKey, "shard-key", shardByNamespace, "what HelmReleases are partitioned by with --shard: namespace, keeping the HelmReleases of a namespace in the same shard, or name, the namespace and name")
+	pflag.StringVar(&chartProxy, "chart-proxy-url", "", "URL of the caching chart-proxy that chart, dependency and values requests are sent through, e.g. http://helm-crd-chart-proxy.kube-system:8080. Requests are sent directly if empty.")
+	pflag.BoolVar(&offlineMode, "offline-mode", false, "air-gapped mode: only send chart, dependency and values requests to --offline-mirrors, serving the others from --offline-cache-dir and failing them with a ChartNotMirrored reason if not cached")
+	pflag.StringSliceVar(&offlineMirror, "offline-mirrors", nil, "comma separated URL prefixes of the internal chart repositories and registries reachable in --offline-mode, e.g. http://chartmuseum.charts.svc:8080/")
+	pflag.StringVar(&offlineCache, "offline-cache-dir", "", "directory of the pre-populated cache of --offline-mode, holding the file of each URL at <host>/<path>, as downloaded by wget --force-directories")
+	pflag.Int64Var(&maxChartSize, "max-chart-size", defaultMaxChartSize, "size in bytes of the largest chart archive downloaded, including dependencies. Larger charts fail to reconcile. Unlimited if zero.")
+	pflag.DurationVar(&gcInterval, "gc-interval", 0, "interval at which Tiller releases deployed for HelmReleases that no longer exist are deleted, disabled if zero")
+	pflag.StringVar(&executor, "executor", "tiller", "how releases are deployed: tiller, or apply to render charts in the controller and apply their objects with server-side apply, storing revisions in Secrets of the Tiller namespace")
+	pflag.StringVar(&repoURL, "default-repo-url", envOrDefault("DEFAULT_REPO_URL", defaultRepoURL), "repository of charts not setting chart.repository.url, defaults to $DEFAULT_REPO_URL if set")
+	pflag.StringVar(&repoAuth, "default-repo-auth-secret", os.Getenv("DEFAULT_REPO_AUTH_SECRET"), "<name>:<key> of a Secret in the controller namespace holding the Authorization header sent to --default-repo-url, defaults to $DEFAULT_REPO_AUTH_SECRET")
+	pflag.StringVar(&authScope, "default-auth-scope", string(helmCrdV2.AuthScopeController), "where the auth secrets of chart sources not setting scope are read from: Controller, the controller namespace, or Namespace, the namespace of the HelmRelease")
+	pflag.BoolVar(&denyAuth, "deny-cross-namespace-auth", false, "only let HelmReleases read the auth secrets of their own namespace, rejecting Controller scoped auth outside of the controller namespace")
+	pflag.StringSliceVar(&allowedRepos, "allowed-repos", nil, "comma separated URL patterns of the repositories charts may be downloaded from, * matching any characters. All repositories are allowed if empty.")
+	pflag.StringSliceVar(&deniedRepos, "denied-repos", nil, "comma separated URL patterns of the repositories charts may not be downloaded from, even if allowed")
+	pflag.StringVar(&regoCM, "rego-configmap", "", "name of a ConfigMap of the controller namespace whose *.rego keys are Rego policies of package helmcrd, whose deny rule lists why a rendered release is denied. Releases are not checked if empty.")
+	pflag.StringSliceVar(&tillerNSs, "allowed-tiller-namespaces", nil, "comma separated namespaces of the Tillers HelmReleases may select with spec.tillerNamespace, which is rejected if empty")
+	pflag.StringSliceVar(&tillerHosts, "allowed-tiller-hosts", nil, "comma separated host:port addresses of the Tillers HelmReleases may select with spec.tillerHost, which is rejected if empty")
+	pflag.BoolVar(&restrictNS, "restrict-to-own-namespace", false, "confine HelmReleases to their own namespace for untrusted tenants: other target namespaces, cluster scoped objects and objects of other namespaces are rejected. Implies --deny-cross-namespace-auth.")
+	pflag.BoolVar(&enablePprof, "enable-pprof", false, "serve net/http/pprof profiles at /debug/pprof/ and expvar counters at /debug/vars on --pprof-address")
+	pflag.StringVar(&pprofAddress, "pprof-address", "localhost:6060", "address of the --enable-pprof diagnostics server, only reachable from the pod by default")
+	pflag.DurationVar(&tillerOptions.Keepalive, "tiller-keepalive", defaultTillerOptions.Keepalive, "interval of the keepalive pings of the connections to Tiller")
+	pflag.DurationVar(&tillerOptions.BackoffMaxDelay, "tiller-reconnect-max-delay", defaultTillerOptions.BackoffMaxDelay, "maximum delay between attempts to reconnect to Tiller, doubled on every failed attempt")
+	pflag.DurationVar(&tillerOptions.HealthTimeout, "tiller-health-timeout", defaultTillerOptions.HealthTimeout, "maximum duration of the Tiller health check of the readiness probe")
+	pflag.StringVar(&healthAddress, "health-address", ":8081", "address serving the /healthz liveness and /readyz readiness probes, the controller being ready once its caches are synced and while Tiller is reachable. Disabled if empty.")
+	pflag.StringVar(&apiAddress, "api-address", "", "address serving the read-only releases API at /api/v1/releases, for dashboards. Disabled if empty.")
+	pflag.StringVar(&apiTokenFile, "api-token-file", "", "file holding the bearer token required by the releases API, usually mounted from a Secret. Required with --api-address.")
+	pflag.StringVar(&webhookAddr, "webhook-address", "", "address serving the receivers of the Harbor, GitHub and generic webhooks at /webhooks/<kind>, queuing the HelmReleases using the updated charts and values files. Disabled if empty.")
+	pflag.StringVar(&webhookSecret, "webhook-secret-file", "", "file holding the secret signing the webhook payloads, or sent as their Authorization header, usually mounted from a Secret. Required with --webhook-address.")
+	pflag.DurationVar(&gracePeriod, "shutdown-grace-period", 25*time.Second, "time the reconciles in flight are given to finish on SIGTERM, shorter than the pod terminationGracePeriodSeconds")
+	pflag.BoolVar(&labelObjects, "label-resources", false, "label the objects of every release with helm.bitnami.com/release=<release name> and annotate them with helm.bitnami.com/helmrelease=<namespace>/<name> of their HelmRelease, rendering charts in the controller first")
+	pflag.BoolVar(&dryRun, "dry-run", false, "render releases and record the changes they would make in their status, without installing, upgrading or deleting anything")
 }
 
 func main2() error {
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	format, err := logging.ParseFormat(logFormat)
+	if err != nil {
+		return err
+	}
+	logger = logging.New(os.Stderr, level, format)
+
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return err
@@ -42,27 +193,289 @@ func main2() error {
 		return err
 	}
 
-	log.Printf("Using tiller host: %s", settings.TillerHost)
-	helmClient := helm.NewClient(helm.Host(settings.TillerHost))
+	var helmClient helmclient.Interface
+	switch executor {
+	case "tiller":
+		logger.With("tillerHost", settings.TillerHost).Infof("Connecting to tiller")
+		helmClient, err = helmclient.DialTiller(settings.TillerHost, tillerOptions)
+		if err != nil {
+			return err
+		}
+	case "apply":
+		logger.With("storageNamespace", settings.TillerNamespace).Infof("Applying releases without Tiller")
+		objects := &restObjectClient{discovery: kubeClient.Discovery()}
+		helmClient = helmclient.NewApplyClient(kubeClient, objects, settings.TillerNamespace)
+	default:
+		return fmt.Errorf("unknown executor %q, expecting tiller or apply", executor)
+	}
+
+	var httpClient chartUtils.HTTPClient = &http.Client{
+		Transport: chartUtils.NewTransport(httpOptions),
+		Timeout:   httpTimeout,
+	}
+	if chartProxy != "" {
+		logger.With("url", chartProxy).Infof("Sending chart requests through the chart proxy")
+		httpClient = &chartUtils.ProxyClient{Client: httpClient, URL: chartProxy}
+	}
+	var netClient chartUtils.HTTPClient = &chartUtils.CircuitBreaker{
+		Client: &chartUtils.RetryingClient{
+			Client: &chartUtils.ConcurrencyLimiter{
+				Client:     httpClient,
+				Max:        maxDownloads,
+				MaxPerHost: maxHostDls,
+			},
+			Attempts:  httpAttempts,
+			BaseDelay: httpBase,
+			MaxDelay:  httpMax,
+		},
+		Threshold: repoFailures,
+		Cooldown:  repoCooldown,
+	}
+	if offlineMode {
+		if len(offlineMirror) == 0 && offlineCache == "" {
+			return fmt.Errorf("--offline-mode requires --offline-mirrors or --offline-cache-dir")
+		}
+		logger.With("mirrors", strings.Join(offlineMirror, ","), "cacheDir", offlineCache).Infof("Running in offline mode, only fetching charts from mirrors and the cache")
+		netClient = &chartUtils.OfflineClient{Client: netClient, Mirrors: offlineMirror, CacheDir: offlineCache}
+	}
+
+	controller := NewController(clientset, kubeClient, helmClient, netClient, countChartLoads(chartutil.LoadArchive), resyncPeriod, newRateLimiter(retryBase, retryMax))
+	controller.tillerOptions = tillerOptions
+	controller.tillerNamespace = settings.TillerNamespace
+	controller.tillerLimit = helmclient.NewSemaphore(maxTillerOps)
+	controller.maxTillerOpsPerHost = maxHostOps
+	if executor == "apply" {
+		controller.tillerless = true
+		controller.storage = secretStorage{kubeClient: kubeClient}
+	}
+	controller.gcInterval = gcInterval
+	controller.maxChartSize = maxChartSize
+	controller.flagSettings = runtimeSettings{
+		logLevel:       level,
+		defaultRepoURL: repoURL,
+		maxRetries:     maxRetries,
+		releaseTimeout: rlsTimeout,
+		workers:        workers,
+	}
+	controller.applySettings(controller.flagSettings)
+	controller.settingsConfigMap = settingsCM
+	controller.globalValuesConfigMap = globalValues
+	controller.regoConfigMap = regoCM
+	controller.repoPolicy = policy.RepoPolicy{Allowed: allowedRepos, Denied: deniedRepos}
+	if repoAuth != "" {
+		controller.defaultRepoAuth, err = parseAuthSecret(repoAuth)
+		if err != nil {
+			return err
+		}
+	}
+	switch scope := helmCrdV2.AuthScope(authScope); scope {
+	case helmCrdV2.AuthScopeController, helmCrdV2.AuthScopeNamespace:
+		controller.defaultAuthScope = scope
+	default:
+		return fmt.Errorf("unknown auth scope %q, expecting Controller or Namespace", authScope)
+	}
+	controller.denyCrossNamespaceAuth = denyAuth || restrictNS
+	controller.restrictNamespace = restrictNS
+	controller.allowedTillerNamespaces = tillerNSs
+	controller.allowedTillerHosts = tillerHosts
+	if controller.shard, err = parseShard(shardFlag, shardKey); err != nil {
+		return err
+	}
+	if shardFlag != "" {
+		logger.With("shard", controller.shard.String(), "key", shardKey).Infof("Reconciling a shard of the HelmReleases")
+	}
+	switch policy := helmCrdV2.RecoveryPolicy(recoveryFlag); policy {
+	case helmCrdV2.RecoveryWait, helmCrdV2.RecoveryRollback, helmCrdV2.RecoveryRetry:
+		controller.defaultRecoveryPolicy = policy
+	default:
+		return fmt.Errorf("unknown recovery policy %q, expecting wait, rollback or retry", recoveryFlag)
+	}
+	controller.labelResources = labelObjects
+	controller.clusterDomain = clusterDomain
+	if dryRun {
+		logger.Infof("Running in dry-run mode, releases will not be changed")
+		controller.dryRun = true
+	}
+	if sopsKeyring != "" {
+		controller.sopsKeys.PGP, err = readKeyring(sopsKeyring)
+		if err != nil {
+			return err
+		}
+	}
+	if sopsAgeKeys != "" {
+		data, err := ioutil.ReadFile(sopsAgeKeys)
+		if err != nil {
+			return err
+		}
+		controller.sopsKeys.Age, err = sops.ParseAgeIdentities(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", sopsAgeKeys, err)
+		}
+	}
+	if fulcioRoots != "" {
+		data, err := ioutil.ReadFile(fulcioRoots)
+		if err != nil {
+			return err
+		}
+		if controller.fulcioRoots, err = cosign.ParseCertPool(data); err != nil {
+			return fmt.Errorf("--sigstore-fulcio-roots: %v", err)
+		}
+	}
+	if rekorKey != "" {
+		data, err := ioutil.ReadFile(rekorKey)
+		if err != nil {
+			return err
+		}
+		if controller.rekorKey, err = cosign.ParsePublicKey(data); err != nil {
+			return fmt.Errorf("--sigstore-rekor-public-key: %v", err)
+		}
+	}
 
-	netClient := &http.Client{
-		Timeout: time.Second * defaultTimeoutSeconds,
+	if notifications != "" {
+		config, err := notify.LoadConfig(notifications)
+		if err != nil {
+			return err
+		}
+		notifier, err := notify.New(config, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return err
+		}
+		controller.notifier = notify.Async(notifier, notificationQueueSize, func(e notify.Event, err error) {
+			logger.With("namespace", e.Namespace, "name", e.Name, "event", e.Type, "error", err).Warnf("Unable to send notification")
+		})
 	}
 
-	controller := NewController(clientset, kubeClient, helmClient, netClient, chartutil.LoadArchive)
+	if auditLog != "" {
+		controller.auditSink, err = audit.NewSink(auditLog, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return fmt.Errorf("--audit-log: %v", err)
+		}
+	}
 
 	stop := make(chan struct{})
-	defer close(stop)
 
-	go controller.Run(stop)
+	if otlpEndpoint != "" {
+		logger.With("endpoint", otlpEndpoint).Infof("Exporting traces")
+		controller.tracer = tracing.NewTracer(otlpEndpoint, "helm-crd-controller", &http.Client{Timeout: 10 * time.Second})
+		go controller.tracer.Run(5*time.Second, stop, func(err error) {
+			logger.With("error", err).Warnf("Unable to export traces")
+		})
+	}
+
+	if enablePprof {
+		publishQueueDepth(controller)
+		logger.With("address", pprofAddress).Infof("Serving diagnostics")
+		go func() {
+			if err := http.ListenAndServe(pprofAddress, diagnosticsHandler()); err != nil {
+				logger.With("error", err).Errorf("Unable to serve diagnostics")
+			}
+		}()
+	}
+
+	if healthAddress != "" {
+		go func() {
+			if err := http.ListenAndServe(healthAddress, controller.healthHandler()); err != nil {
+				logger.With("error", err).Errorf("Unable to serve health probes")
+			}
+		}()
+	}
+
+	if apiAddress != "" {
+		if apiTokenFile == "" {
+			return fmt.Errorf("--api-token-file is required with --api-address")
+		}
+		data, err := ioutil.ReadFile(apiTokenFile)
+		if err != nil {
+			return err
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return fmt.Errorf("empty API token in %s", apiTokenFile)
+		}
+		logger.With("address", apiAddress).Infof("Serving releases API")
+		go func() {
+			if err := http.ListenAndServe(apiAddress, controller.apiHandler(token)); err != nil {
+				logger.With("error", err).Errorf("Unable to serve releases API")
+			}
+		}()
+	}
+
+	if webhookAddr != "" {
+		if webhookSecret == "" {
+			return fmt.Errorf("--webhook-secret-file is required with --webhook-address")
+		}
+		data, err := ioutil.ReadFile(webhookSecret)
+		if err != nil {
+			return err
+		}
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return fmt.Errorf("empty webhook secret in %s", webhookSecret)
+		}
+		logger.With("address", webhookAddr).Infof("Serving webhooks")
+		go func() {
+			if err := http.ListenAndServe(webhookAddr, controller.webhookHandler(secret)); err != nil {
+				logger.With("error", err).Errorf("Unable to serve webhooks")
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		controller.Run(stop)
+		close(done)
+	}()
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGTERM)
 	<-sigterm
 
+	logger.With("gracePeriod", gracePeriod).Infof("Received SIGTERM, waiting for the reconciles in flight")
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(gracePeriod):
+		logger.Warnf("Shutdown grace period expired")
+		if err := controller.recordInterrupted(); err != nil {
+			logger.With("error", err).Errorf("Unable to record interrupted release")
+		}
+	}
 	return nil
 }
 
+// envOrDefault returns the value of the environment variable name, or
+// value if it is not set
+func envOrDefault(name, value string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return value
+}
+
+// parseAuthSecret parses a <name>:<key> Secret key reference into an
+// Authorization header auth
+func parseAuthSecret(ref string) (helmCrdV2.HelmReleaseAuth, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return helmCrdV2.HelmReleaseAuth{}, fmt.Errorf("invalid Secret key %q, expecting <name>:<key>", ref)
+	}
+	return helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+		SecretKeyRef: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: parts[0]},
+			Key:                  parts[1],
+		},
+	}}, nil
+}
+
+func readKeyring(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return openpgp.ReadArmoredKeyRing(f)
+}
+
 func main() {
 	pflag.Parse()
 
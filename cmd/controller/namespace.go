@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// namespaceOwnerAnnotation is set on the namespaces created for
+// HelmReleases setting spec.createNamespace to <namespace>/<name> of the
+// HelmRelease
+const namespaceOwnerAnnotation = "helm.bitnami.com/created-by"
+
+// ensureNamespace creates the target namespace of h if it does not exist,
+// with the labels and annotations of spec.namespaceMetadata, and keeps
+// them set on the namespaces it created. Namespaces created otherwise are
+// left untouched. Namespaces are not deleted with their HelmRelease.
+func (c *Controller) ensureNamespace(h *helmCrdV2.HelmRelease, namespace string) error {
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		return err
+	}
+	owner := h.Namespace + "/" + h.Name
+	labels := map[string]interface{}{}
+	annotations := map[string]interface{}{namespaceOwnerAnnotation: owner}
+	if md := h.Spec.NamespaceMetadata; md != nil {
+		for k, v := range md.Labels {
+			labels[k] = v
+		}
+		for k, v := range md.Annotations {
+			annotations[k] = v
+		}
+	}
+	ns := manifest.Object{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       namespace,
+		Content: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]interface{}{
+				"name":        namespace,
+				"labels":      labels,
+				"annotations": annotations,
+			},
+		},
+	}
+
+	verb := "create"
+	live, err := objects.Get(ns, "")
+	switch {
+	case err == nil:
+		if namespaceAnnotation(live, namespaceOwnerAnnotation) != owner || namespaceUpToDate(live, labels, annotations) {
+			return nil
+		}
+		verb = "patch"
+	case !k8sErrors.IsNotFound(err):
+		return err
+	}
+	if h.Spec.ServiceAccountName != "" {
+		allowed, err := c.serviceAccountAllowed(h, objectChange{verb, ns}, "")
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("service account %s is not allowed to %s namespace %s", h.Spec.ServiceAccountName, verb, namespace)
+		}
+	}
+	if err := objects.Apply(ns, ""); err != nil {
+		return fmt.Errorf("unable to %s namespace %s: %v", verb, namespace, err)
+	}
+	return nil
+}
+
+// namespaceAnnotation returns the annotation key of the live namespace
+func namespaceAnnotation(live map[string]interface{}, key string) string {
+	metadata, _ := live["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	value, _ := annotations[key].(string)
+	return value
+}
+
+// namespaceUpToDate returns whether the live namespace has labels and
+// annotations set
+func namespaceUpToDate(live map[string]interface{}, labels, annotations map[string]interface{}) bool {
+	metadata, _ := live["metadata"].(map[string]interface{})
+	desired := map[string]interface{}{"annotations": annotations}
+	if len(labels) > 0 {
+		desired["labels"] = labels
+	}
+	return len(manifest.Diff(desired, metadata)) == 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestCreateNamespace(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			TargetNamespace:   "apps",
+			CreateNamespace:   true,
+			NamespaceMetadata: &helmCrdV2.NamespaceMetadata{Labels: map[string]string{"team": "a"}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{}}
+	controller.objects = objects
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := []string{"/Namespace/apps"}; !reflect.DeepEqual(objects.applied, expected) {
+		t.Errorf("Expecting %v to be applied, received %v", expected, objects.applied)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be deployed, received %v", deployed)
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		applied  bool
+	}{
+		{
+			name: "created and up to date",
+			metadata: map[string]interface{}{
+				"labels":      map[string]interface{}{"team": "a"},
+				"annotations": map[string]interface{}{namespaceOwnerAnnotation: "myns/foo"},
+			},
+		},
+		{
+			name: "created with other labels",
+			metadata: map[string]interface{}{
+				"labels":      map[string]interface{}{"team": "b"},
+				"annotations": map[string]interface{}{namespaceOwnerAnnotation: "myns/foo"},
+			},
+			applied: true,
+		},
+		{
+			name:     "created otherwise",
+			metadata: map[string]interface{}{"labels": map[string]interface{}{"team": "b"}},
+		},
+	}
+	for _, tt := range tests {
+		objects := &fakeObjectClient{live: map[string]map[string]interface{}{
+			"Namespace/apps": {"metadata": tt.metadata},
+		}}
+		controller.objects = objects
+		if err := controller.ensureNamespace(&h, "apps"); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if applied := len(objects.applied) > 0; applied != tt.applied {
+			t.Errorf("%s: expecting the namespace to be applied: %v, received %v", tt.name, tt.applied, objects.applied)
+		}
+	}
+}
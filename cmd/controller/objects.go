@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// objectClient reads and writes the objects deployed by releases. The
+// namespace is used for namespaced objects not setting one in the manifest.
+type objectClient interface {
+	Get(obj manifest.Object, namespace string) (map[string]interface{}, error)
+	Apply(obj manifest.Object, namespace string) error
+	Resource(apiVersion, kind string) (*metav1.APIResource, error)
+}
+
+// restObjectClient is an objectClient for any kind served by the API
+// server, using discovery to find its resource
+type restObjectClient struct {
+	discovery discovery.DiscoveryInterface
+}
+
+// Resource returns the resource serving kind in apiVersion
+func (c *restObjectClient) Resource(apiVersion, kind string) (*metav1.APIResource, error) {
+	resources, err := c.discovery.ServerResourcesForGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resources.APIResources {
+		if r.Kind == kind && !strings.Contains(r.Name, "/") {
+			return &resources.APIResources[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no resource found for kind %s in %s", kind, apiVersion)
+}
+
+// collectionPath returns the API path of the collection holding obj
+func (c *restObjectClient) collectionPath(obj manifest.Object, namespace string) (string, error) {
+	r, err := c.Resource(obj.APIVersion, obj.Kind)
+	if err != nil {
+		return "", err
+	}
+	prefix := "/apis/" + obj.APIVersion
+	if !strings.Contains(obj.APIVersion, "/") {
+		// Legacy core group
+		prefix = "/api/" + obj.APIVersion
+	}
+	if !r.Namespaced {
+		return fmt.Sprintf("%s/%s", prefix, r.Name), nil
+	}
+	if obj.Namespace != "" {
+		namespace = obj.Namespace
+	}
+	return fmt.Sprintf("%s/namespaces/%s/%s", prefix, namespace, r.Name), nil
+}
+
+func (c *restObjectClient) Get(obj manifest.Object, namespace string) (map[string]interface{}, error) {
+	collection, err := c.collectionPath(obj, namespace)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.discovery.RESTClient().Get().AbsPath(collection, obj.Name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]interface{}{}
+	if err := json.Unmarshal(data, &live); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// Apply merges the manifest fields of obj into the live object, creating
+// it if missing
+func (c *restObjectClient) Apply(obj manifest.Object, namespace string) error {
+	collection, err := c.collectionPath(obj, namespace)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(obj.Content)
+	if err != nil {
+		return err
+	}
+	err = c.discovery.RESTClient().Patch(types.MergePatchType).AbsPath(collection, obj.Name).Body(data).Do().Error()
+	if k8sErrors.IsNotFound(err) {
+		err = c.discovery.RESTClient().Post().AbsPath(collection).Body(data).Do().Error()
+	}
+	return err
+}
+
+// applyPatchType is the content type of server-side apply patches
+const applyPatchType types.PatchType = "application/apply-patch+yaml"
+
+// ServerSideApply applies the manifest fields of obj as fieldManager with
+// server-side apply, forcing conflicts with other managers
+func (c *restObjectClient) ServerSideApply(obj manifest.Object, namespace, fieldManager string) error {
+	collection, err := c.collectionPath(obj, namespace)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(obj.Content)
+	if err != nil {
+		return err
+	}
+	return c.discovery.RESTClient().Patch(applyPatchType).AbsPath(collection, obj.Name).
+		Param("fieldManager", fieldManager).Param("force", "true").Body(data).Do().Error()
+}
+
+// Delete deletes obj and its dependents, ignoring missing objects
+func (c *restObjectClient) Delete(obj manifest.Object, namespace string) error {
+	collection, err := c.collectionPath(obj, namespace)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	data, err := json.Marshal(&metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		return err
+	}
+	err = c.discovery.RESTClient().Delete().AbsPath(collection, obj.Name).Body(data).Do().Error()
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
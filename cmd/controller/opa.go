@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/opa"
+)
+
+// reasonPolicyDenied is the reason of the PolicyDenied and Ready
+// conditions of HelmReleases whose objects are denied by Rego policies
+const reasonPolicyDenied = "PolicyDenied"
+
+// renderRelease renders release rlsName of ch with a dry-run install, or
+// upgrade if it is deployed, with caps if not nil
+func renderRelease(helmClient helmclient.Interface, ch *chart.Chart, rlsName, namespace string, values []byte, deployed bool, caps *chartutil.Capabilities) (*release.Release, error) {
+	if deployed {
+		return helmClient.Upgrade(rlsName, ch, helmclient.UpgradeOptions{Values: values, DryRun: true, Capabilities: caps})
+	}
+	return helmClient.Install(ch, namespace, helmclient.InstallOptions{ReleaseName: rlsName, Values: values, DryRun: true, Capabilities: caps})
+}
+
+// regoKey returns the key of the Rego policies ConfigMap, "" if the
+// controller has none
+func (c *Controller) regoKey() string {
+	if c.regoConfigMap == "" {
+		return ""
+	}
+	return controllerNamespace() + "/" + c.regoConfigMap
+}
+
+// regoPolicies returns the Rego policies of the Rego policies ConfigMap,
+// compiled again only when it changes, nil if the controller has none or
+// it doesn't exist
+func (c *Controller) regoPolicies() (opa.Evaluator, error) {
+	key := c.regoKey()
+	if key == "" {
+		return nil, nil
+	}
+	obj, exists, err := c.configMapInformer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, err
+	}
+	cm := obj.(*corev1.ConfigMap)
+	c.regoLock.Lock()
+	defer c.regoLock.Unlock()
+	if c.policyEvaluator != nil && c.regoVersion == cm.ResourceVersion {
+		return c.policyEvaluator, nil
+	}
+	modules := map[string]string{}
+	for name, module := range cm.Data {
+		if strings.HasSuffix(name, ".rego") {
+			modules[name] = module
+		}
+	}
+	evaluator, err := opa.New(modules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Rego policies of ConfigMap %s: %v", key, err)
+	}
+	c.policyEvaluator, c.regoVersion = evaluator, cm.ResourceVersion
+	return evaluator, nil
+}
+
+// regoHandler queues every HelmRelease when the Rego policies ConfigMap
+// is created, changed or deleted, so that denied releases are checked
+// again
+func (c *Controller) regoHandler() cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil || key == "" || key != c.regoKey() {
+			return
+		}
+		logger.With("configmap", key).Infof("Rego policies changed")
+		for _, obj := range c.informer.GetStore().List() {
+			if key, err := cache.MetaNamespaceKeyFunc(obj.(*helmCrdV2.HelmRelease)); err == nil {
+				c.queue.Add(key)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Periodic resyncs don't change the resource version
+			if oldObj.(metav1.Object).GetResourceVersion() != newObj.(metav1.Object).GetResourceVersion() {
+				enqueue(newObj)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
+// evaluatePolicies renders the release of h and returns the reasons the
+// Rego policies of evaluator deny its objects, including its hooks
+// unless they are disabled and the CRDs separated from the chart
+func (c *Controller) evaluatePolicies(h *helmCrdV2.HelmRelease, evaluator opa.Evaluator, helmClient helmclient.Interface, ch *chart.Chart, namespace string, values []byte, deployed bool, crds []manifest.Object, caps *chartutil.Capabilities) ([]string, error) {
+	rel, err := renderRelease(helmClient, ch, getReleaseName(h), namespace, values, deployed, caps)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := manifest.Objects(rel.GetManifest())
+	if err != nil {
+		return nil, err
+	}
+	if !h.Spec.DisableHooks {
+		for _, hook := range rel.GetHooks() {
+			hookObjs, err := manifest.Objects(hook.GetManifest())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", hook.GetPath(), err)
+			}
+			objs = append(objs, hookObjs...)
+		}
+	}
+	input := opa.Input{
+		Namespace:       h.Namespace,
+		Name:            h.Name,
+		Release:         getReleaseName(h),
+		TargetNamespace: namespace,
+		Chart:           ch.GetMetadata().GetName(),
+		Version:         ch.GetMetadata().GetVersion(),
+		Objects:         []map[string]interface{}{},
+	}
+	for _, obj := range append(crds, objs...) {
+		input.Objects = append(input.Objects, obj.Content)
+	}
+	return evaluator.Deny(input)
+}
+
+// rejectDenied records in the PolicyDenied and Ready conditions of h the
+// reasons its objects are denied
+func (c *Controller) rejectDenied(h *helmCrdV2.HelmRelease, denials []string) error {
+	if len(denials) > maxDeniedReported {
+		denials = append(denials[:maxDeniedReported], fmt.Sprintf("%d more", len(denials)-maxDeniedReported))
+	}
+	err := fmt.Errorf("denied by policy: %s", strings.Join(denials, "; "))
+	return c.rejectRelease(h, reasonPolicyDenied, err, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleasePolicyDenied,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonPolicyDenied,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+const denySecrets = `package helmcrd
+
+deny[msg] {
+	obj := input.objects[_]
+	obj.kind == "Secret"
+	msg := sprintf("Secret of release %s chart %s is not allowed", [input.release, input.chart])
+}
+`
+
+func TestPolicyDenied(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.regoConfigMap = "helm-crd-policies"
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "helm-crd-policies", ResourceVersion: "1"},
+		Data:       map[string]string{"secrets.rego": denySecrets, "README.md": "Not a policy"},
+	}
+	controller.configMapInformer.GetStore().Add(cm)
+	controller.regoHandler().OnAdd(cm)
+	if controller.queue.Len() != 1 {
+		t.Errorf("Expecting the HelmReleases to be queued, %d queued", controller.queue.Len())
+	}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting denied releases not to be retried, received %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleasePolicyDenied)
+	if cond == nil || !strings.Contains(cond.Message, "Secret of release myns-foo chart foo is not allowed") {
+		t.Errorf("Unexpected PolicyDenied condition %+v", cond)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonPolicyDenied {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+
+	// Invalid policies fail the reconcile
+	cm = cm.DeepCopy()
+	cm.ResourceVersion = "2"
+	cm.Data["secrets.rego"] = "package helmcrd\n\ndeny[msg] {\n"
+	controller.configMapInformer.GetStore().Update(cm)
+	res.Spec.Values = "foo: bar"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err == nil || !strings.Contains(err.Error(), "invalid Rego policies") {
+		t.Errorf("Expecting an error for invalid policies, received %v", err)
+	}
+
+	// Once policies allow the release, it is installed and the condition
+	// removed
+	cm = cm.DeepCopy()
+	cm.ResourceVersion = "3"
+	delete(cm.Data, "secrets.rego")
+	controller.configMapInformer.GetStore().Update(cm)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleasePolicyDenied); cond != nil {
+		t.Errorf("Expecting the PolicyDenied condition to be removed, received %+v", cond)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be deployed, received %v", deployed)
+	}
+}
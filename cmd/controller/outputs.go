@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// reasonOutputsFailed is the reason of the failures of reconciles that
+// deployed the release but couldn't export its outputs
+const reasonOutputsFailed = "OutputsFailed"
+
+// exportOutputs stores the spec.outputs of h, read from its values or the
+// Secrets of its target namespace, in its outputs Secret, returning their
+// names. Secrets of another namespace are only read when the service
+// accounts of the HelmRelease namespace may read them, and outputs Secrets
+// not controlled by h are left alone. The Secret is deleted once h has no
+// outputs.
+func (c *Controller) exportOutputs(h *helmCrdV2.HelmRelease, values []byte) ([]string, error) {
+	secrets := c.kubeClient.Core().Secrets(h.Namespace)
+	existing, err := secrets.Get(valuesUtils.OutputsSecretName(h.Name), metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		existing = nil
+	} else if err != nil {
+		return h.Status.Outputs, err
+	} else if !metav1.IsControlledBy(existing, h) {
+		return h.Status.Outputs, fmt.Errorf("Secret %s/%s exists and is not controlled by the HelmRelease", h.Namespace, existing.Name)
+	}
+	if len(h.Spec.Outputs) == 0 {
+		if existing != nil {
+			if err := secrets.Delete(existing.Name, &metav1.DeleteOptions{}); err != nil && !k8sErrors.IsNotFound(err) {
+				return h.Status.Outputs, err
+			}
+		}
+		return nil, nil
+	}
+
+	var vals map[string]interface{}
+	if err := yaml.Unmarshal(values, &vals); err != nil {
+		return nil, err
+	}
+	targetNamespace := h.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = h.Namespace
+	}
+	data := map[string][]byte{}
+	var names []string
+	for _, out := range h.Spec.Outputs {
+		switch {
+		case out.ValuePath != "":
+			value, found, err := valuesUtils.Lookup(vals, out.ValuePath)
+			if err != nil {
+				return nil, fmt.Errorf("output %s: %v", out.Name, err)
+			}
+			if !found {
+				return nil, fmt.Errorf("output %s: value %s not found", out.Name, out.ValuePath)
+			}
+			if s, ok := value.(string); ok {
+				data[out.Name] = []byte(s)
+			} else if data[out.Name], err = json.Marshal(value); err != nil {
+				return nil, fmt.Errorf("output %s: %v", out.Name, err)
+			}
+		case out.SecretKeyRef != nil:
+			ref := out.SecretKeyRef
+			if targetNamespace != h.Namespace {
+				if err := c.authorizeGet(h.Namespace, corev1.SchemeGroupVersion.WithResource("secrets"), ref.Name, targetNamespace); err != nil {
+					return nil, fmt.Errorf("output %s: %v", out.Name, err)
+				}
+			}
+			secret, err := c.kubeClient.Core().Secrets(targetNamespace).Get(ref.Name, metav1.GetOptions{})
+			if err != nil {
+				if isOptional(ref.Optional) && k8sErrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("output %s: %v", out.Name, err)
+			}
+			value, ok := secret.Data[ref.Key]
+			if !ok {
+				if isOptional(ref.Optional) {
+					continue
+				}
+				return nil, fmt.Errorf("output %s: key %q not found in Secret %s/%s", out.Name, ref.Key, targetNamespace, ref.Name)
+			}
+			data[out.Name] = value
+		}
+		names = append(names, out.Name)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       h.Namespace,
+			Name:            valuesUtils.OutputsSecretName(h.Name),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(h, helmCrdV2.SchemeGroupVersion.WithKind("HelmRelease"))},
+		},
+		Data: data,
+	}
+	switch {
+	case existing == nil:
+		_, err = secrets.Create(secret)
+	case !reflect.DeepEqual(existing.Data, data):
+		// Consumers are only requeued when the outputs change
+		_, err = secrets.Update(secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestOutputs(t *testing.T) {
+	db := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "db"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Values: "auth:\n  username: app\nport: 5432\n",
+			Outputs: []helmCrdV2.ReleaseOutput{
+				{Name: "username", ValuePath: "auth.username"},
+				{Name: "port", ValuePath: "port"},
+				{Name: "password", SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "db-mariadb"},
+					Key:                  "mariadb-password",
+				}},
+			},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{db}, []string{})
+
+	// The chart didn't generate the password yet
+	if err := controller.updateRelease("myns/db"); errorReason(err) != reasonOutputsFailed {
+		t.Fatalf("Expecting the outputs to fail, received %v", err)
+	}
+	controller.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "db-mariadb"},
+		Data:       map[string][]byte{"mariadb-password": []byte("s3kret")},
+	})
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("db", metav1.GetOptions{})
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/db"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	secret, err := controller.kubeClient.Core().Secrets("myns").Get("db-outputs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if string(secret.Data["username"]) != "app" || string(secret.Data["port"]) != "5432" || string(secret.Data["password"]) != "s3kret" {
+		t.Errorf("Unexpected outputs %q", secret.Data)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "db" {
+		t.Errorf("Expecting the outputs to be owned by the HelmRelease, received %+v", secret.OwnerReferences)
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("db", metav1.GetOptions{})
+	if len(res.Status.Outputs) != 3 {
+		t.Errorf("Unexpected status outputs %v", res.Status.Outputs)
+	}
+
+	app := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "app"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "db", Output: "password", TargetPath: "database.password"}},
+				{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "db"}},
+			},
+			Values: "replicas: 2\n",
+		},
+	}
+	values, _, err := controller.releaseValues(app)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "database:\n  password: s3kret\npassword: s3kret\nport: \"5432\"\nreplicas: 2\nusername: app\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q, received %q", expected, values)
+	}
+	if !controller.referencesObject(app, "Secret", "myns", "db-outputs") {
+		t.Errorf("Expecting the outputs Secret to be referenced")
+	}
+
+	app.Spec.ValuesFrom = []helmCrdV2.ValuesSource{{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "cache"}}}
+	if _, _, err := controller.releaseValues(app); err == nil {
+		t.Errorf("Expecting an error until the outputs are available")
+	}
+}
+
+func TestOutputsAuthorization(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "db", UID: "1234"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			TargetNamespace: "other",
+			Outputs: []helmCrdV2.ReleaseOutput{{Name: "password", SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "db-mariadb"},
+				Key:                  "mariadb-password",
+			}}},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "db-mariadb"},
+		Data:       map[string][]byte{"mariadb-password": []byte("s3kret")},
+	})
+	controller := &Controller{kubeClient: kubeClient}
+
+	// Secrets of the target namespace are only read when the service
+	// accounts of the HelmRelease namespace may
+	if _, err := controller.exportOutputs(h, nil); err == nil {
+		t.Errorf("Expecting an error reading a Secret of another namespace")
+	}
+	var review *authorizationv1.SubjectAccessReview
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review = action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+	if _, err := controller.exportOutputs(h, nil); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	attrs := review.Spec.ResourceAttributes
+	if review.Spec.Groups[0] != "system:serviceaccounts:myns" || attrs.Namespace != "other" || attrs.Resource != "secrets" || attrs.Name != "db-mariadb" {
+		t.Errorf("Unexpected access review %+v", review.Spec)
+	}
+
+	// Outputs Secrets of another owner are neither updated nor deleted
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "app-outputs"},
+		Data:       map[string][]byte{"token": []byte("foreign")},
+	}
+	kubeClient.Core().Secrets("myns").Create(foreign)
+	app := h.DeepCopy()
+	app.Name, app.UID = "app", "5678"
+	if _, err := controller.exportOutputs(app, nil); err == nil {
+		t.Errorf("Expecting an error for an outputs Secret not controlled by the HelmRelease")
+	}
+	app.Spec.Outputs = nil
+	app.Status.Outputs = []string{"token"}
+	if _, err := controller.exportOutputs(app, nil); err == nil {
+		t.Errorf("Expecting an error for an outputs Secret not controlled by the HelmRelease")
+	}
+	if secret, err := kubeClient.Core().Secrets("myns").Get("app-outputs", metav1.GetOptions{}); err != nil || string(secret.Data["token"]) != "foreign" {
+		t.Errorf("Expecting the Secret to be left alone, received %v, %v", secret, err)
+	}
+}
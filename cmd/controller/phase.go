@@ -0,0 +1,110 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// Reasons of failed reconciles, recorded in status.failureReason
+const (
+	reasonChartDownloadFailed = "ChartDownloadFailed"
+	reasonChartNotMirrored    = "ChartNotMirrored"
+	reasonInstallFailed       = "InstallFailed"
+	reasonUpgradeFailed       = "UpgradeFailed"
+	reasonDeleteFailed        = "DeleteFailed"
+)
+
+// reconcileError is an error failing a reconcile, along with its reason
+type reconcileError struct {
+	reason string
+	err    error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+// failed returns err, recorded with reason when the reconcile fails
+func failed(reason string, err error) error {
+	return &reconcileError{reason: reason, err: err}
+}
+
+// fetchFailed returns err, failing to download a chart, with its reason
+func fetchFailed(err error) error {
+	if _, ok := err.(*chartUtils.NotMirroredError); ok {
+		return failed(reasonChartNotMirrored, err)
+	}
+	return failed(reasonChartDownloadFailed, err)
+}
+
+// errorReason returns the reason err was returned with by failed, or
+// reasonReconcileFailed. Errors reaching Tiller are reported as
+// reasonTillerUnavailable whatever the operation.
+func errorReason(err error) string {
+	e, ok := err.(*reconcileError)
+	if ok {
+		err = e.err
+	}
+	switch {
+	case helmclient.IsUnavailable(err):
+		return reasonTillerUnavailable
+	case ok:
+		return e.reason
+	}
+	return reasonReconcileFailed
+}
+
+// setFailed records err, verbatim, as the failure of the last reconcile
+func setFailed(status *helmCrdV2.HelmReleaseStatus, reason string, err error) {
+	status.Phase = helmCrdV2.PhaseFailed
+	status.FailureReason = reason
+	status.FailureMessage = err.Error()
+	status.Operation = nil
+}
+
+// setDeployed records that the release of h is up to date, clearing the
+// failure of previous reconciles
+func setDeployed(status *helmCrdV2.HelmReleaseStatus, h *helmCrdV2.HelmRelease) {
+	status.LastForceSync = h.Annotations[helmCrdV2.ForceSyncAnnotation]
+	status.Phase = helmCrdV2.PhaseDeployed
+	status.FailureReason = ""
+	status.FailureMessage = ""
+	status.Operation = nil
+	status.Remediation = nil
+}
+
+// setPhase updates the phase of h before a Tiller operation, returning the
+// updated object. The operation is in flight until the reconcile ends. The
+// phase is left unchanged with --dry-run.
+func (c *Controller) setPhase(h *helmCrdV2.HelmRelease, phase helmCrdV2.HelmReleasePhase) (*helmCrdV2.HelmRelease, error) {
+	if c.dryRun {
+		return h, nil
+	}
+	if phase != helmCrdV2.PhasePending {
+		c.inFlight.set(h, phase)
+	}
+	status := h.Status
+	status.Phase = phase
+	return c.updateStatus(h, status)
+}
+
+// startOperation updates the phase of h before installing or upgrading
+// revision of its release, recording the operation so that it is
+// recognized as interrupted if the release is left pending
+func (c *Controller) startOperation(h *helmCrdV2.HelmRelease, phase helmCrdV2.HelmReleasePhase, revision int32) (*helmCrdV2.HelmRelease, error) {
+	if c.dryRun {
+		return h, nil
+	}
+	c.inFlight.set(h, phase)
+	status := h.Status
+	status.Phase = phase
+	status.Operation = &helmCrdV2.OperationStatus{
+		Phase:     phase,
+		Revision:  revision,
+		StartTime: metav1.NewTime(c.now()),
+	}
+	return c.updateStatus(h, status)
+}
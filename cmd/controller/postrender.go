@@ -0,0 +1,107 @@
+package main
+
+import (
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/postrender"
+)
+
+// releaseLabel is set with --label-resources on the objects of releases
+// to the release name, and helmReleaseAnnotation to <namespace>/<name> of
+// their HelmRelease
+const (
+	releaseLabel          = "helm.bitnami.com/release"
+	helmReleaseAnnotation = "helm.bitnami.com/helmrelease"
+)
+
+// postRendered returns whether the chart of h is rendered by the
+// controller before being installed
+func (c *Controller) postRendered(h *helmCrdV2.HelmRelease) bool {
+	return h.Spec.PostRender != nil || handlesCRDs(h) || c.labelResources || h.Spec.PropagateMetadata != nil
+}
+
+// propagatedMetadata returns the labels and annotations of h selected by
+// spec.propagateMetadata
+func propagatedMetadata(h *helmCrdV2.HelmRelease) (map[string]string, map[string]string) {
+	labels, annotations := map[string]string{}, map[string]string{}
+	pm := h.Spec.PropagateMetadata
+	if pm == nil {
+		return labels, annotations
+	}
+	for _, k := range pm.Labels {
+		if v, ok := h.Labels[k]; ok {
+			labels[k] = v
+		}
+	}
+	for _, k := range pm.Annotations {
+		if v, ok := h.Annotations[k]; ok {
+			annotations[k] = v
+		}
+	}
+	return labels, annotations
+}
+
+// postRenderChart renders the chart with a Tiller dry-run, applies the
+// post-render modifications of h and returns a chart rendering the result.
+// The labels and annotations of spec.propagateMetadata are set on the
+// objects, and with --label-resources the release name.
+// Hooks and notes are kept unmodified. With spec.skipCRDs or
+// spec.installCRDsFirst, the CustomResourceDefinitions of the manifest and
+// hooks are left out of the chart; the latter are returned, along with
+// those of the crds/ directory of ch, to be installed first.
+func (c *Controller) postRenderChart(h *helmCrdV2.HelmRelease, ch *chart.Chart, rlsName, namespace string, values []byte, deployed bool, caps *chartutil.Capabilities) (*chart.Chart, []manifest.Object, error) {
+	helmClient, err := c.helmClientFor(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	rel, err := renderRelease(helmClient, ch, rlsName, namespace, values, deployed, caps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rendered := rel.GetManifest()
+	if pr := h.Spec.PostRender; pr != nil && pr.Kustomize != nil {
+		var images []postrender.Image
+		for _, img := range pr.Kustomize.Images {
+			images = append(images, postrender.Image{Name: img.Name, NewName: img.NewName, NewTag: img.NewTag, Digest: img.Digest})
+		}
+		rendered, err = postrender.Kustomize(rendered, pr.Kustomize.PatchesStrategicMerge, images)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if pr := h.Spec.PostRender; pr != nil && len(pr.PatchesJSON6902) > 0 {
+		var patches []postrender.JSONPatch
+		for _, p := range pr.PatchesJSON6902 {
+			patches = append(patches, postrender.JSONPatch{
+				Target: postrender.Target{
+					Group:     p.Target.Group,
+					Version:   p.Target.Version,
+					Kind:      p.Target.Kind,
+					Namespace: p.Target.Namespace,
+					Name:      p.Target.Name,
+				},
+				Patch: p.Patch,
+			})
+		}
+		rendered, err = postrender.ApplyJSONPatches(rendered, patches)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	labels, annotations := propagatedMetadata(h)
+	if c.labelResources {
+		labels[releaseLabel] = rlsName
+		annotations[helmReleaseAnnotation] = h.Namespace + "/" + h.Name
+	}
+	if len(labels) > 0 || len(annotations) > 0 {
+		rendered, err = postrender.SetMetadata(rendered, labels, annotations)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return separateCRDs(h, ch, rel, rendered)
+}
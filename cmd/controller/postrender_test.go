@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestPostRenderChart(t *testing.T) {
+	c := &Controller{helmClient: helmclient.NewFakeClient("myns-foo")}
+	h := &helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{
+		PostRender: &helmCrdV2.PostRenderSpec{Kustomize: &helmCrdV2.KustomizeSpec{
+			PatchesStrategicMerge: []string{"kind: Secret\nmetadata:\n  name: fixture\n  labels:\n    patched: \"true\"\n"},
+		}},
+	}}
+	h.Spec.PostRender.PatchesJSON6902 = []helmCrdV2.JSON6902Patch{{
+		Target: helmCrdV2.PatchTarget{Kind: "Secret", Name: "fixture"},
+		Patch:  `[{"op": "add", "path": "/type", "value": "Opaque"}]`,
+	}}
+
+	ch, _, err := c.postRenderChart(h, &chart.Chart{Metadata: &chart.Metadata{Name: "foo"}}, "myns-foo", "myns", nil, true, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ch.Metadata.Name != "foo" {
+		t.Errorf("Expected the rendered chart metadata received %+v", ch.Metadata)
+	}
+	if len(ch.Templates) == 0 || !strings.Contains(string(ch.Templates[0].Data), "patched: \"true\"") {
+		t.Errorf("Expected the patched manifest received %+v", ch.Templates)
+	}
+	if !strings.Contains(string(ch.Templates[0].Data), "type: Opaque") {
+		t.Errorf("Expected the JSON patch to be applied received %s", ch.Templates[0].Data)
+	}
+}
+
+func TestPostRenderChartLabelResources(t *testing.T) {
+	c := &Controller{helmClient: helmclient.NewFakeClient("myns-foo"), labelResources: true}
+	h := &helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}}
+	if !c.postRendered(h) {
+		t.Fatalf("Expecting charts to be post-rendered with --label-resources")
+	}
+
+	ch, _, err := c.postRenderChart(h, &chart.Chart{Metadata: &chart.Metadata{Name: "foo"}}, "myns-foo", "myns", nil, true, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(ch.Templates) == 0 {
+		t.Fatalf("Expected the rendered manifest")
+	}
+	data := string(ch.Templates[0].Data)
+	if !strings.Contains(data, "helm.bitnami.com/release: myns-foo") || !strings.Contains(data, "helm.bitnami.com/helmrelease: myns/foo") {
+		t.Errorf("Expected the objects to be labeled received %s", data)
+	}
+}
+
+func TestPostRenderChartPropagateMetadata(t *testing.T) {
+	c := &Controller{helmClient: helmclient.NewFakeClient("myns-foo")}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "myns",
+			Name:        "foo",
+			Labels:      map[string]string{"cost-center": "1234", "unrelated": "true"},
+			Annotations: map[string]string{"owner": "team-a"},
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{PropagateMetadata: &helmCrdV2.PropagateMetadata{
+			Labels:      []string{"cost-center", "missing"},
+			Annotations: []string{"owner"},
+		}},
+	}
+	if !c.postRendered(h) {
+		t.Fatalf("Expecting charts propagating metadata to be post-rendered")
+	}
+
+	ch, _, err := c.postRenderChart(h, &chart.Chart{Metadata: &chart.Metadata{Name: "foo"}}, "myns-foo", "myns", nil, true, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	data := string(ch.Templates[0].Data)
+	if !strings.Contains(data, "cost-center: \"1234\"") || !strings.Contains(data, "owner: team-a") {
+		t.Errorf("Expected the propagated metadata received %s", data)
+	}
+	if strings.Contains(data, "unrelated") || strings.Contains(data, "missing") {
+		t.Errorf("Expected only the selected metadata received %s", data)
+	}
+
+	updated := h.DeepCopy()
+	updated.Labels["cost-center"] = "5678"
+	if !releaseObjChanged(h, updated) {
+		t.Errorf("Expecting changes of propagated labels to be reconciled")
+	}
+	updated = h.DeepCopy()
+	updated.Labels["unrelated"] = "false"
+	if releaseObjChanged(h, updated) {
+		t.Errorf("Expecting changes of other labels to be ignored")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// deletionPriority is the priority of the deletions of releases, ahead of
+// any spec.priority
+const deletionPriority = math.MaxInt32
+
+// priorityQueue is a workqueue.RateLimitingInterface handing out the item
+// with the highest priority first, in the order they were added among
+// items of the same priority. As with workqueue.Type, an item is queued
+// at most once and never handed to two workers at the same time.
+type priorityQueue struct {
+	cond *sync.Cond
+	// priority returns the priority of an item, computed when it's queued
+	priority    func(item interface{}) int32
+	rateLimiter workqueue.RateLimiter
+
+	queue        []prioritizedItem
+	dirty        map[interface{}]bool
+	processing   map[interface{}]bool
+	shuttingDown bool
+}
+
+type prioritizedItem struct {
+	item     interface{}
+	priority int32
+}
+
+func newPriorityQueue(rateLimiter workqueue.RateLimiter, priority func(item interface{}) int32) *priorityQueue {
+	return &priorityQueue{
+		cond:        sync.NewCond(&sync.Mutex{}),
+		priority:    priority,
+		rateLimiter: rateLimiter,
+		dirty:       map[interface{}]bool{},
+		processing:  map[interface{}]bool{},
+	}
+}
+
+// releasePriority returns the priority of the HelmRelease with a key:
+// deletions first, then by spec.priority, which is capped below
+// deletionPriority so that it can't tie with deletions
+func releasePriority(indexer cache.Indexer) func(item interface{}) int32 {
+	return func(item interface{}) int32 {
+		obj, exists, err := indexer.GetByKey(item.(string))
+		if err != nil {
+			return 0
+		}
+		if !exists {
+			return deletionPriority
+		}
+		h := obj.(*helmCrdV2.HelmRelease)
+		if h.DeletionTimestamp != nil {
+			return deletionPriority
+		}
+		if h.Spec.Priority >= deletionPriority {
+			return deletionPriority - 1
+		}
+		return h.Spec.Priority
+	}
+}
+
+// push queues item. q.cond.L must be held.
+func (q *priorityQueue) push(item interface{}) {
+	q.queue = append(q.queue, prioritizedItem{item: item, priority: q.priority(item)})
+	q.cond.Signal()
+}
+
+// Add queues item unless it's already queued
+func (q *priorityQueue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown || q.dirty[item] {
+		return
+	}
+	q.dirty[item] = true
+	// Queued again by Done
+	if q.processing[item] {
+		return
+	}
+	q.push(item)
+}
+
+// Len returns the number of queued items
+func (q *priorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.queue)
+}
+
+// Get blocks until it can return the item with the highest priority,
+// which must be marked Done once processed. shutdown is true once the
+// queue is shut down.
+func (q *priorityQueue) Get() (item interface{}, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return nil, true
+	}
+	next := 0
+	for i, it := range q.queue {
+		if it.priority > q.queue[next].priority {
+			next = i
+		}
+	}
+	item = q.queue[next].item
+	q.queue = append(q.queue[:next], q.queue[next+1:]...)
+	q.processing[item] = true
+	delete(q.dirty, item)
+	return item, false
+}
+
+// Done marks item as processed, queueing it again if it was added while
+// being processed
+func (q *priorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	if q.dirty[item] {
+		q.push(item)
+	}
+}
+
+// ShutDown makes Get return shutdown once the queue is drained, ignoring
+// further items
+func (q *priorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *priorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+// AddAfter queues item once duration elapsed
+func (q *priorityQueue) AddAfter(item interface{}, duration time.Duration) {
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	time.AfterFunc(duration, func() { q.Add(item) })
+}
+
+// AddRateLimited queues item once the rate limiter allows it
+func (q *priorityQueue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+// Forget stops tracking the retries of item
+func (q *priorityQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+// NumRequeues returns the number of times item was retried
+func (q *priorityQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	priorities := map[string]int32{"ingress": 100, "monitoring": 10, "deleted": deletionPriority}
+	q := newPriorityQueue(workqueue.DefaultControllerRateLimiter(), func(item interface{}) int32 {
+		return priorities[item.(string)]
+	})
+	for _, item := range []string{"app", "monitoring", "other", "ingress", "app", "deleted"} {
+		q.Add(item)
+	}
+	if q.Len() != 5 {
+		t.Errorf("Expecting items to be queued once, received %d", q.Len())
+	}
+	var order []string
+	for _, expected := range []string{"deleted", "ingress", "monitoring", "app", "other"} {
+		item, shutdown := q.Get()
+		if shutdown || item != expected {
+			t.Fatalf("Expecting %s, received %v after %v", expected, item, order)
+		}
+		order = append(order, expected)
+		if expected == "app" {
+			// Added again while processed: queued once done
+			q.Add("app")
+			if q.Len() != 1 {
+				t.Errorf("Expecting items being processed to wait, received %d", q.Len())
+			}
+		}
+		q.Done(item)
+	}
+	if item, _ := q.Get(); item != "app" {
+		t.Errorf("Expecting the item added while processed, received %v", item)
+	}
+	q.Done("app")
+
+	q.AddAfter("later", 10*time.Millisecond)
+	if item, _ := q.Get(); item != "later" {
+		t.Errorf("Expecting the delayed item, received %v", item)
+	}
+	q.Done("later")
+
+	q.ShutDown()
+	q.Add("ignored")
+	if _, shutdown := q.Get(); !shutdown || !q.ShuttingDown() {
+		t.Errorf("Expecting the queue to be shut down")
+	}
+}
+
+func TestReleasePriority(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	now := metav1.Now()
+	indexer.Add(&helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "ingress"},
+		Spec:       helmCrdV2.HelmReleaseSpec{Priority: 100},
+	})
+	indexer.Add(&helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "deleting", DeletionTimestamp: &now},
+		Spec:       helmCrdV2.HelmReleaseSpec{Priority: -10},
+	})
+	indexer.Add(&helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "greedy"},
+		Spec:       helmCrdV2.HelmReleaseSpec{Priority: math.MaxInt32},
+	})
+	priority := releasePriority(indexer)
+	for key, expected := range map[string]int32{"myns/ingress": 100, "myns/greedy": deletionPriority - 1, "myns/deleting": deletionPriority, "myns/deleted": deletionPriority} {
+		if p := priority(key); p != expected {
+			t.Errorf("Expecting priority %d for %s, received %d", expected, key, p)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// Reasons of the Ready condition
+const (
+	reasonDeployed        = "Deployed"
+	reasonRendered        = "Rendered"
+	reasonReconcileFailed = "ReconcileFailed"
+)
+
+// setReady sets the Ready condition to True after a successful reconcile
+func setReady(status *helmCrdV2.HelmReleaseStatus, reason, message string) {
+	setCondition(status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// markNotReady sets the Ready condition of the HelmRelease with key to
+// False, and its phase to Failed, after a failed reconcile that will be
+// retried
+func (c *Controller) markNotReady(key string, err error) {
+	obj, exists, getErr := c.informer.GetIndexer().GetByKey(key)
+	if getErr != nil || !exists {
+		return
+	}
+	helmObj := obj.(*helmCrdV2.HelmRelease)
+	status := helmObj.Status
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  errorReason(err),
+		Message: err.Error(),
+	})
+	setFailed(&status, errorReason(err), err)
+	if _, err := c.updateStatus(helmObj, status); err != nil {
+		logger.With("helmrelease", key, "error", err).Warnf("Unable to set Ready condition")
+	}
+}
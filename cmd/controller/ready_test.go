@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestReadyCondition(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	get := func() *helmCrdV2.HelmRelease {
+		res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return res
+	}
+
+	controller.markNotReady("myns/foo", fmt.Errorf("chart download request failed"))
+	cond := getCondition(&get().Status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != reasonReconcileFailed || cond.Message != "chart download request failed" {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+
+	controller.informer.GetIndexer().Update(get())
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cond = getCondition(&get().Status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != reasonDeployed || cond.Message != "Release myns-foo revision 1 deployed" {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+}
+
+// failingInstallClient is a Helm client failing to install releases,
+// recording the phase of the HelmRelease during the install
+type failingInstallClient struct {
+	*helmclient.FakeClient
+	controller *Controller
+	phase      helmCrdV2.HelmReleasePhase
+}
+
+func (c *failingInstallClient) Install(ch *chart.Chart, namespace string, opts helmclient.InstallOptions) (*release.Release, error) {
+	h, _ := c.controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	c.phase = h.Status.Phase
+	return nil, fmt.Errorf("release foo failed: timed out waiting for the condition")
+}
+
+func TestPhase(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	fakeClient := fakeHelmClient(controller)
+	failing := &failingInstallClient{FakeClient: fakeClient, controller: controller}
+	controller.helmClient = failing
+	get := func() *helmCrdV2.HelmRelease {
+		res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return res
+	}
+
+	err := controller.updateRelease("myns/foo")
+	if err == nil {
+		t.Fatalf("Expecting the install to fail")
+	}
+	if failing.phase != helmCrdV2.PhaseInstalling {
+		t.Errorf("Expecting phase Installing during the install, received %q", failing.phase)
+	}
+	controller.informer.GetIndexer().Update(get())
+	controller.markNotReady("myns/foo", err)
+	status := get().Status
+	if status.Phase != helmCrdV2.PhaseFailed || status.FailureReason != reasonInstallFailed || status.FailureMessage != "release foo failed: timed out waiting for the condition" {
+		t.Errorf("Unexpected failure in status %+v", status)
+	}
+
+	controller.helmClient = fakeClient
+	controller.informer.GetIndexer().Update(get())
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	status = get().Status
+	if status.Phase != helmCrdV2.PhaseDeployed || status.FailureReason != "" || status.FailureMessage != "" {
+		t.Errorf("Expecting phase Deployed without failure, received %+v", status)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+// Reasons of the Interrupted condition, or of the failures, of releases
+// left pending by an interrupted install or upgrade
+const (
+	reasonOperationPending   = "OperationPending"
+	reasonOperationStuck     = "OperationStuck"
+	reasonOperationRecovered = "OperationRecovered"
+)
+
+// recoveryGrace is added to the release timeout when the pending
+// operations of a HelmRelease without spec.recovery.timeout are considered
+// interrupted, for Tiller to give up on them first
+const recoveryGrace = 60
+
+// defaultTillerTimeout is the timeout in seconds Tiller applies to the
+// operations sent without one
+const defaultTillerTimeout = 300
+
+// pendingOperation returns whether rel is being installed, upgraded or
+// rolled back, Tiller refusing any other operation on it meanwhile
+func pendingOperation(rel *release.Release) bool {
+	switch rel.GetInfo().GetStatus().GetCode() {
+	case release.Status_PENDING_INSTALL, release.Status_PENDING_UPGRADE, release.Status_PENDING_ROLLBACK:
+		return true
+	}
+	return false
+}
+
+// recoveryPolicy returns how the pending operations of h are recovered
+func (c *Controller) recoveryPolicy(h *helmCrdV2.HelmRelease) helmCrdV2.RecoveryPolicy {
+	if r := h.Spec.Recovery; r != nil && r.Policy != "" {
+		return r.Policy
+	}
+	if c.defaultRecoveryPolicy != "" {
+		return c.defaultRecoveryPolicy
+	}
+	return helmCrdV2.RecoveryWait
+}
+
+// recoveryTimeout returns how long after they started the pending
+// operations of h are considered interrupted
+func (c *Controller) recoveryTimeout(h *helmCrdV2.HelmRelease) time.Duration {
+	if r := h.Spec.Recovery; r != nil && r.Timeout > 0 {
+		return time.Duration(r.Timeout) * time.Second
+	}
+	timeout := c.timeout(h)
+	if timeout <= 0 {
+		timeout = defaultTillerTimeout
+	}
+	return time.Duration(timeout+recoveryGrace) * time.Second
+}
+
+// recoverPending handles rel, the latest revision of the release of h,
+// left pending by an operation that may have been interrupted, e.g. by a
+// restart of the controller or Tiller. Until the recovery timeout the
+// operation is waited for, requeuing h. Past it, the operation is failed,
+// or, per the recovery policy, the release is rolled back, or deleted if
+// it was being installed, and h is installed or upgraded again if proceed
+// is returned.
+func (c *Controller) recoverPending(h *helmCrdV2.HelmRelease, key string, helmClient helmclient.Interface, rel *release.Release, rlog *logging.Logger) (bool, error) {
+	code := rel.GetInfo().GetStatus().GetCode()
+	operation := strings.ToLower(strings.TrimPrefix(code.String(), "PENDING_"))
+	var started time.Time
+	op := h.Status.Operation
+	ours := op != nil && op.Revision == rel.Version
+	if ours {
+		started = op.StartTime.Time
+	} else {
+		started, _ = ptypes.Timestamp(rel.GetInfo().GetLastDeployed())
+	}
+	rlog = rlog.With("operation", operation, "revision", rel.Version, "started", started)
+	timeout := c.recoveryTimeout(h)
+	if remaining := started.Add(timeout).Sub(c.now()); remaining > 0 {
+		rlog.Infof("Waiting for pending operation")
+		c.queue.AddAfter(key, remaining)
+		status := h.Status
+		setCondition(&status, helmCrdV2.HelmReleaseCondition{
+			Type:    helmCrdV2.HelmReleaseInterrupted,
+			Status:  corev1.ConditionTrue,
+			Reason:  reasonOperationPending,
+			Message: fmt.Sprintf("%s of revision %d pending, waiting up to %s", operation, rel.Version, remaining.Round(time.Second)),
+		})
+		_, err := c.updateStatus(h, status)
+		return false, err
+	}
+
+	err := fmt.Errorf("%s of revision %d pending for more than %s", operation, rel.Version, timeout)
+	policy := c.recoveryPolicy(h)
+	if policy == helmCrdV2.RecoveryWait {
+		rlog.Warnf("Pending operation timed out")
+		return false, failed(reasonOperationStuck, err)
+	}
+
+	// Only the releases of h are recovered, those whose operation it did
+	// not record must already be owned
+	if !ours {
+		if err := c.checkOwnership(h, rel); err != nil {
+			return false, err
+		}
+	}
+	rlog.With("policy", policy).Warnf("Recovering pending operation")
+	if code == release.Status_PENDING_INSTALL {
+		err = helmClient.Delete(rel.Name, helmclient.DeleteOptions{Purge: true})
+	} else {
+		_, err = helmClient.Rollback(rel.Name, helmclient.RollbackOptions{DisableHooks: h.Spec.DisableHooks})
+	}
+	if err != nil {
+		return false, failed(reasonOperationStuck, fmt.Errorf("unable to recover pending %s of revision %d: %v", operation, rel.Version, err))
+	}
+	action := "rolled back"
+	if code == release.Status_PENDING_INSTALL {
+		action = "deleted"
+	}
+	message := fmt.Sprintf("%s of revision %d pending for more than %s, %s", operation, rel.Version, timeout, action)
+	if policy == helmCrdV2.RecoveryRetry {
+		c.recordEvent(h, corev1.EventTypeWarning, reasonOperationRecovered, message+", retrying")
+		return true, nil
+	}
+	return false, c.rejectRelease(h, reasonOperationRecovered, fmt.Errorf("%s", message), helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseInterrupted,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonOperationRecovered,
+		Message: message,
+	})
+}
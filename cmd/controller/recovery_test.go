@@ -0,0 +1,201 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestRecoverPendingOperation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   helmCrdV2.RecoveryPolicy
+		code     release.Status_Code
+		started  time.Duration
+		err      string
+		phase    helmCrdV2.HelmReleasePhase
+		reason   string
+		deployed []int32
+		calls    []string
+		// foreign pending operations were not started by the HelmRelease
+		foreign bool
+	}{
+		{
+			name:     "timed out pending install of another release left alone",
+			policy:   helmCrdV2.RecoveryRollback,
+			code:     release.Status_PENDING_INSTALL,
+			started:  -time.Hour,
+			err:      "release foo already exists and was not deployed for this HelmRelease, set spec.adoptExisting to adopt it",
+			phase:    helmCrdV2.PhaseFailed,
+			deployed: []int32{1},
+			calls:    []string{"History"},
+			foreign:  true,
+		},
+		{
+			name:     "pending upgrade waited for",
+			policy:   helmCrdV2.RecoveryWait,
+			code:     release.Status_PENDING_UPGRADE,
+			started:  -time.Minute,
+			phase:    helmCrdV2.PhaseUpgrading,
+			reason:   reasonOperationPending,
+			deployed: []int32{1, 2},
+			calls:    []string{"History"},
+		},
+		{
+			name:     "timed out pending upgrade failed",
+			policy:   helmCrdV2.RecoveryWait,
+			code:     release.Status_PENDING_UPGRADE,
+			started:  -time.Hour,
+			err:      "upgrade of revision 2 pending for more than 6m0s",
+			phase:    helmCrdV2.PhaseFailed,
+			deployed: []int32{1, 2},
+			calls:    []string{"History"},
+		},
+		{
+			name:     "timed out pending upgrade rolled back",
+			policy:   helmCrdV2.RecoveryRollback,
+			code:     release.Status_PENDING_UPGRADE,
+			started:  -time.Hour,
+			phase:    helmCrdV2.PhaseFailed,
+			reason:   reasonOperationRecovered,
+			deployed: []int32{1, 2, 3},
+			calls:    []string{"History", "Rollback"},
+		},
+		{
+			name:     "timed out pending upgrade retried",
+			policy:   helmCrdV2.RecoveryRetry,
+			code:     release.Status_PENDING_UPGRADE,
+			started:  -time.Hour,
+			phase:    helmCrdV2.PhaseDeployed,
+			deployed: []int32{1, 2, 3, 4},
+			calls:    []string{"History", "Rollback", "History", "Upgrade", "Status"},
+		},
+		{
+			name:     "timed out pending install retried",
+			policy:   helmCrdV2.RecoveryRetry,
+			code:     release.Status_PENDING_INSTALL,
+			started:  -time.Hour,
+			phase:    helmCrdV2.PhaseDeployed,
+			deployed: []int32{1},
+			calls:    []string{"History", "Delete", "History", "Install", "Status"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			h := helmCrdV2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+				Spec: helmCrdV2.HelmReleaseSpec{
+					ReleaseName: "foo",
+					Timeout:     300,
+					Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+						URL:     "http://charts.example.com/repo/",
+						Name:    "foo",
+						Version: "1.0.0",
+					}},
+					Recovery: &helmCrdV2.RecoverySpec{Policy: tc.policy},
+				},
+			}
+			if tc.code == release.Status_PENDING_UPGRADE {
+				h.Status.Operation = &helmCrdV2.OperationStatus{
+					Phase:     helmCrdV2.PhaseUpgrading,
+					Revision:  2,
+					StartTime: metav1.NewTime(now.Add(tc.started)),
+				}
+				h.Status.Revision = 1
+			} else if !tc.foreign {
+				h.Status.Operation = &helmCrdV2.OperationStatus{
+					Phase:     helmCrdV2.PhaseInstalling,
+					Revision:  1,
+					StartTime: metav1.NewTime(now.Add(tc.started)),
+				}
+			}
+			if op := h.Status.Operation; op != nil {
+				h.Status.Phase = op.Phase
+			}
+			controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+			controller.now = func() time.Time { return now }
+			helmClient := fakeHelmClient(controller)
+			if tc.code == release.Status_PENDING_UPGRADE {
+				helmClient.Releases = append(helmClient.Releases, helmclient.MockRelease("foo", "myns", 1, &chart.Chart{}, release.Status_DEPLOYED))
+			}
+			// Foreign operations are pending since the 1977 of MockRelease
+			helmClient.Releases = append(helmClient.Releases, helmclient.MockRelease("foo", "myns", int32(len(helmClient.Releases)+1), &chart.Chart{}, tc.code))
+
+			err := controller.updateRelease("myns/foo")
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expecting error %q, received %v", tc.err, err)
+				}
+				if reason := errorReason(err); tc.policy == helmCrdV2.RecoveryWait && reason != reasonOperationStuck {
+					t.Errorf("Unexpected failure reason %q", reason)
+				}
+				controller.markNotReady("myns/foo", err)
+			} else if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if res.Status.Phase != tc.phase {
+				t.Errorf("Expecting phase %s, received %s", tc.phase, res.Status.Phase)
+			}
+			cond := getCondition(&res.Status, helmCrdV2.HelmReleaseInterrupted)
+			if tc.reason == "" && cond != nil {
+				t.Errorf("Unexpected Interrupted condition %+v", cond)
+			}
+			if tc.reason != "" && (cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != tc.reason) {
+				t.Errorf("Expecting an Interrupted condition with reason %s, received %+v", tc.reason, cond)
+			}
+			if tc.phase == helmCrdV2.PhaseDeployed && res.Status.Operation != nil {
+				t.Errorf("Expecting the operation to be cleared, received %+v", res.Status.Operation)
+			}
+			var versions []int32
+			for _, rel := range helmClient.Releases {
+				versions = append(versions, rel.Version)
+			}
+			if !reflect.DeepEqual(versions, tc.deployed) {
+				t.Errorf("Expecting revisions %v, received %v", tc.deployed, versions)
+			}
+			if !reflect.DeepEqual(helmClient.Calls, tc.calls) {
+				t.Errorf("Expecting calls %v, received %v", tc.calls, helmClient.Calls)
+			}
+		})
+	}
+}
+
+func TestStartOperation(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	now := time.Now()
+	controller.now = func() time.Time { return now }
+	res, err := controller.startOperation(&h, helmCrdV2.PhaseUpgrading, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := &helmCrdV2.OperationStatus{Phase: helmCrdV2.PhaseUpgrading, Revision: 3, StartTime: metav1.NewTime(now)}
+	if res.Status.Phase != helmCrdV2.PhaseUpgrading || !reflect.DeepEqual(res.Status.Operation, expected) {
+		t.Errorf("Unexpected status %+v", res.Status)
+	}
+	if ops := controller.inFlight.get(); ops["myns/foo"] != helmCrdV2.PhaseUpgrading {
+		t.Errorf("Expecting the upgrade to be in flight, received %v", ops)
+	}
+}
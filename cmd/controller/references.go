@@ -0,0 +1,97 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// referencesObject returns whether h reads the Secret or ConfigMap, as
+// given by kind, namespace/name: through valuesFrom, including the
+// outputs Secrets of other HelmReleases, the auth of its chart source,
+// including that of a RepositoryCredential, or spec.kubeConfigSecretRef
+func (c *Controller) referencesObject(h *helmCrdV2.HelmRelease, kind, namespace, name string) bool {
+	if kind == "ConfigMap" {
+		if h.Namespace != namespace {
+			return false
+		}
+		for _, src := range h.Spec.ValuesFrom {
+			if ref := src.ConfigMapKeyRef; ref != nil && ref.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Chart auth secrets are resolved as when fetching the chart
+	url, auth, ownAuth := c.chartSourceAuth(h)
+	if authNamespace, err := c.authSecretNamespace(h, ownAuth); err == nil {
+		authNamespace, auth = c.credentialAuth(url, authNamespace, auth)
+		refs, _ := authSecretRefs(auth)
+		for _, ref := range refs {
+			if ref.Name == name && authNamespace == namespace {
+				return true
+			}
+		}
+	}
+
+	if h.Namespace != namespace {
+		return false
+	}
+	if ref := h.Spec.KubeConfigSecretRef; ref != nil && ref.Name == name {
+		return true
+	}
+	if v := h.Spec.Verification; v != nil && v.SecretRef != nil && v.SecretRef.Name == name {
+		return true
+	}
+	for _, src := range h.Spec.ValuesFrom {
+		if ref := src.SecretKeyRef; ref != nil && ref.Name == name {
+			return true
+		}
+		if ref := src.AuthSecretKeyRef; ref != nil && ref.Name == name {
+			return true
+		}
+		if ref := src.HelmReleaseRef; ref != nil && valuesUtils.OutputsSecretName(ref.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceHandler queues the HelmReleases referencing the Secrets or
+// ConfigMaps, as given by kind, that are created, changed or deleted, so
+// that rotated credentials and updated values are deployed
+func (c *Controller) referenceHandler(kind string) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return
+		}
+		for _, obj := range c.informer.GetStore().List() {
+			h := obj.(*helmCrdV2.HelmRelease)
+			if !c.referencesObject(h, kind, namespace, name) {
+				continue
+			}
+			if key, err := cache.MetaNamespaceKeyFunc(h); err == nil {
+				logger.With("helmrelease", key).Debugf("Referenced %s %s/%s changed", kind, namespace, name)
+				c.queue.Add(key)
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Periodic resyncs don't change the resource version
+			if oldObj.(metav1.Object).GetResourceVersion() != newObj.(metav1.Object).GetResourceVersion() {
+				enqueue(newObj)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
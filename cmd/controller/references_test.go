@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestReferencesObject(t *testing.T) {
+	secretRef := func(name string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "key"}
+	}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{SecretKeyRef: *secretRef("repo-creds")}},
+			}},
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"}},
+				{SecretKeyRef: secretRef("secret-values")},
+				{URL: "https://example.com/values.yaml", AuthSecretKeyRef: secretRef("values-creds")},
+			},
+			KubeConfigSecretRef: secretRef("kubeconfig"),
+		},
+	}
+	c := &Controller{defaultAuthScope: helmCrdV2.AuthScopeController}
+	tests := []struct {
+		kind, namespace, name string
+		expected              bool
+	}{
+		{"ConfigMap", "myns", "values", true},
+		{"ConfigMap", "other", "values", false},
+		{"Secret", "myns", "values", false},
+		{"Secret", "myns", "secret-values", true},
+		{"Secret", "myns", "values-creds", true},
+		{"Secret", "myns", "kubeconfig", true},
+		{"Secret", defaultNamespace, "repo-creds", true},
+		{"Secret", "myns", "repo-creds", false},
+		{"Secret", "myns", "unrelated", false},
+	}
+	for _, tt := range tests {
+		if actual := c.referencesObject(h, tt.kind, tt.namespace, tt.name); actual != tt.expected {
+			t.Errorf("Expecting %s %s/%s to be referenced: %v, received %v", tt.kind, tt.namespace, tt.name, tt.expected, actual)
+		}
+	}
+
+	// Namespace scoped auth
+	h.Spec.Chart.Repository.Auth.Header.Scope = helmCrdV2.AuthScopeNamespace
+	if !c.referencesObject(h, "Secret", "myns", "repo-creds") || c.referencesObject(h, "Secret", defaultNamespace, "repo-creds") {
+		t.Errorf("Expecting the auth secret of the HelmRelease namespace to be referenced")
+	}
+}
+
+func TestReferenceHandler(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Version: "1.0.0"}},
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"}},
+			},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, nil)
+	handler := controller.referenceHandler("Secret")
+
+	old := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "values", ResourceVersion: "1"}}
+	handler.OnUpdate(old, old.DeepCopy())
+	if controller.queue.Len() != 0 {
+		t.Errorf("Expecting resyncs to be ignored")
+	}
+	updated := old.DeepCopy()
+	updated.ResourceVersion = "2"
+	handler.OnUpdate(old, updated)
+	if controller.queue.Len() != 1 {
+		t.Fatalf("Expecting the HelmRelease to be queued")
+	}
+	if key, _ := controller.queue.Get(); key != "myns/foo" {
+		t.Errorf("Unexpected key %v", key)
+	}
+	handler.OnDelete(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "other"}})
+	if controller.queue.Len() != 0 {
+		t.Errorf("Expecting unrelated Secrets to be ignored")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/validation"
+)
+
+// Reasons of the Ready condition of HelmReleases whose release name is
+// invalid or already used
+const (
+	reasonInvalidReleaseName  = "InvalidReleaseName"
+	reasonReleaseNameConflict = "ReleaseNameConflict"
+)
+
+// checkReleaseName returns an error unless the release name of h, also
+// when rendered from its template, is accepted by Tiller
+func checkReleaseName(h *helmCrdV2.HelmRelease) error {
+	rname, err := renderReleaseName(h)
+	if err != nil {
+		return fmt.Errorf("invalid release name template: %v", err)
+	}
+	if errs := validation.ValidateReleaseName(rname, field.NewPath("spec", "releaseName")); len(errs) > 0 {
+		return fmt.Errorf("invalid release name: %v", errs.ToAggregate())
+	}
+	return nil
+}
+
+// sameRelease returns whether a and b manage the same release of the
+// same Tiller of the same cluster
+func (c *Controller) sameRelease(a, b *helmCrdV2.HelmRelease) bool {
+	if getReleaseName(a) != getReleaseName(b) || c.releaseTillerNamespace(a) != c.releaseTillerNamespace(b) || remoteClusterKey(a) != remoteClusterKey(b) {
+		return false
+	}
+	return c.tillerless || a.Spec.TillerHost == b.Spec.TillerHost
+}
+
+// precedes returns whether a keeps its release when b uses the same
+// name: the HelmRelease that deployed it, or else the oldest one
+func precedes(a, b *helmCrdV2.HelmRelease) bool {
+	if (a.Status.Revision > 0) != (b.Status.Revision > 0) {
+		return a.Status.Revision > 0
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+}
+
+// conflictingRelease returns the HelmRelease using the release of h
+// before h, if any
+func (c *Controller) conflictingRelease(h *helmCrdV2.HelmRelease) *helmCrdV2.HelmRelease {
+	for _, obj := range c.informer.GetStore().List() {
+		other := obj.(*helmCrdV2.HelmRelease)
+		if other.Namespace == h.Namespace && other.Name == h.Name {
+			continue
+		}
+		if other.DeletionTimestamp == nil && c.sameRelease(h, other) && precedes(other, h) {
+			return other
+		}
+	}
+	return nil
+}
+
+// rejectConflict records in the Conflict and Ready conditions of h that
+// its release is managed by other, rather than upgrading it
+func (c *Controller) rejectConflict(h, other *helmCrdV2.HelmRelease) error {
+	err := fmt.Errorf("release %s is already managed by HelmRelease %s/%s", getReleaseName(h), other.Namespace, other.Name)
+	return c.rejectRelease(h, reasonReleaseNameConflict, err, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseConflict,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonReleaseNameConflict,
+		Message: err.Error(),
+	})
+}
+
+// enqueueConflicting queues the HelmReleases using the release of a
+// deleted HelmRelease, so that the next one takes it over
+func (c *Controller) enqueueConflicting(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	deleted, ok := obj.(*helmCrdV2.HelmRelease)
+	if !ok {
+		return
+	}
+	for _, obj := range c.informer.GetStore().List() {
+		h := obj.(*helmCrdV2.HelmRelease)
+		if c.sameRelease(h, deleted) {
+			if key, err := cache.MetaNamespaceKeyFunc(h); err == nil {
+				c.queue.Add(key)
+			}
+		}
+	}
+}
+
+// hasConflict returns whether h was refused its release because another
+// HelmRelease manages it, in which case deleting h leaves it alone
+func hasConflict(h *helmCrdV2.HelmRelease) bool {
+	cond := getCondition(&h.Status, helmCrdV2.HelmReleaseConflict)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/releasename"
+)
+
+func TestReleaseNameConflict(t *testing.T) {
+	chartSrc := helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+		URL:     "http://charts.example.com/repo/",
+		Name:    "foo",
+		Version: "1.0.0",
+	}}
+	first := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", CreationTimestamp: metav1.NewTime(time.Unix(1, 0))},
+		Spec:       helmCrdV2.HelmReleaseSpec{ReleaseName: "shared", Chart: chartSrc},
+	}
+	second := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "otherns", Name: "bar", CreationTimestamp: metav1.NewTime(time.Unix(2, 0))},
+		Spec:       helmCrdV2.HelmReleaseSpec{ReleaseName: "shared", Chart: chartSrc},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{first, second}, []string{})
+
+	if err := controller.updateRelease("otherns/bar"); err != nil {
+		t.Fatalf("Expecting conflicting HelmReleases not to be retried, received %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed for the second HelmRelease")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("otherns").Get("bar", metav1.GetOptions{})
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseConflict)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Message != "release shared is already managed by HelmRelease myns/foo" {
+		t.Errorf("Unexpected Conflict condition %+v", cond)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonReleaseNameConflict {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	if hasFinalizer(res) {
+		t.Errorf("Expecting no finalizer on the conflicting HelmRelease")
+	}
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expecting the release to be installed for the first HelmRelease")
+	}
+
+	// Deleting the conflicting HelmRelease leaves the release alone
+	res.DeletionTimestamp = &metav1.Time{}
+	res.Finalizers = []string{releaseFinalizer}
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("otherns/bar"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be kept, received %v", deployed)
+	}
+}
+
+func TestInvalidReleaseName(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseNameTemplate: "{{ .Name }}_{{ .ShortHash }}",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	key := "myns/" + h.Name
+	if err := controller.updateRelease(key); err != nil {
+		t.Fatalf("Expecting invalid release names not to be retried, received %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonInvalidReleaseName {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+}
+
+func TestReleaseNameTemplate(t *testing.T) {
+	long := strings.Repeat("a", 50)
+	tests := []struct {
+		name, template string
+		expected       string
+	}{
+		{"foo", "", "myns-foo"},
+		{"foo", "{{ .Name }}", "foo"},
+		{long, "", "myns-" + strings.Repeat("a", 39) + "-" + releasename.ShortHash("myns", long)},
+	}
+	for _, tt := range tests {
+		h := helmCrdV2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: tt.name},
+			Spec: helmCrdV2.HelmReleaseSpec{
+				ReleaseNameTemplate: tt.template,
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+					URL:     "http://charts.example.com/repo/",
+					Name:    "foo",
+					Version: "1.0.0",
+				}},
+			},
+		}
+		controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+		if err := controller.updateRelease("myns/" + tt.name); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 || deployed[0] != tt.expected {
+			t.Errorf("Expecting release %s to be deployed, received %v", tt.expected, deployed)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+)
+
+// reasonPolicyViolation is the reason of the Ready condition and Event of
+// HelmReleases a HelmReleasePolicy of their namespace rejects
+const reasonPolicyViolation = "PolicyViolation"
+
+// checkReleasePolicies returns an error unless every HelmReleasePolicy of
+// the namespace of h allows deploying version chartVersion of chartName
+func (c *Controller) checkReleasePolicies(h *helmCrdV2.HelmRelease, chartName, chartVersion string) error {
+	if c.policyInformer == nil {
+		return nil
+	}
+	policies, err := c.policyInformer.GetIndexer().ByIndex(cache.NamespaceIndex, h.Namespace)
+	if err != nil {
+		return err
+	}
+	targetNamespace := h.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = h.Namespace
+	}
+	rel := policy.Release{
+		RepoURLs:        c.chartURLs(h),
+		ChartName:       chartName,
+		ChartVersion:    chartVersion,
+		TargetNamespace: targetNamespace,
+		Inline:          h.Spec.Chart.Inline != nil,
+	}
+	for _, obj := range policies {
+		p := obj.(*helmCrdV2.HelmReleasePolicy)
+		if err := policy.CheckRelease(&p.Spec, rel); err != nil {
+			return fmt.Errorf("HelmReleasePolicy %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// enqueueNamespace queues the HelmReleases of the namespace of a changed
+// HelmReleasePolicy, so that they are checked against it
+func (c *Controller) enqueueNamespace(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	for _, obj := range c.informer.GetStore().List() {
+		h := obj.(*helmCrdV2.HelmRelease)
+		if h.Namespace == namespace {
+			if key, err := cache.MetaNamespaceKeyFunc(h); err == nil {
+				c.queue.Add(key)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestReleasePolicies(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "2.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	p := &helmCrdV2.HelmReleasePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "charts"},
+		Spec: helmCrdV2.HelmReleasePolicySpec{
+			Charts:       []helmCrdV2.ChartRule{{Name: "foo", Versions: "^1.0.0"}},
+			Repositories: []string{"http://charts.example.com/*"},
+		},
+	}
+	controller.policyInformer.GetIndexer().Add(p)
+	// Policies of other namespaces do not apply
+	controller.policyInformer.GetIndexer().Add(&helmCrdV2.HelmReleasePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "otherns", Name: "none"},
+		Spec:       helmCrdV2.HelmReleasePolicySpec{TargetNamespaces: []string{"otherns"}},
+	})
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting rejected HelmReleases not to be retried, received %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != reasonPolicyViolation {
+		t.Errorf("Expecting a PolicyViolation Ready condition, received %+v", cond)
+	}
+
+	p.Spec.Charts[0].Versions = "^2.0.0"
+	controller.policyInformer.GetIndexer().Update(p)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expecting the release to be installed")
+	}
+}
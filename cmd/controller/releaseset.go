@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// setLabel is set on the HelmReleases of a HelmReleaseSet to the name of
+// the set
+const setLabel = "helm.bitnami.com/set"
+
+// setHashAnnotation records on the HelmReleases of sets a hash of what
+// the set last applied, which can't be compared to their spec since the
+// admission webhook fills in defaults
+const setHashAnnotation = "helm.bitnami.com/set-hash"
+
+// setKind is the kind of the owner references of the HelmReleases of sets
+var setKind = helmCrdV2.SchemeGroupVersion.WithKind("HelmReleaseSet")
+
+// setInstances returns the instances of set: its items and the namespaces
+// matching its selector, sorted by name. Items override the instance of
+// the namespace of the same name.
+func setInstances(set *helmCrdV2.HelmReleaseSet, namespaces []*corev1.Namespace) ([]helmCrdV2.HelmReleaseSetItem, error) {
+	instances := map[string]helmCrdV2.HelmReleaseSetItem{}
+	if set.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(set.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector: %v", err)
+		}
+		for _, ns := range namespaces {
+			if selector.Matches(labels.Set(ns.Labels)) {
+				instances[ns.Name] = helmCrdV2.HelmReleaseSetItem{Name: ns.Name, TargetNamespace: ns.Name}
+			}
+		}
+	}
+	for _, item := range set.Spec.Items {
+		if selected, ok := instances[item.Name]; ok && item.TargetNamespace == "" {
+			item.TargetNamespace = selected.TargetNamespace
+		}
+		instances[item.Name] = item
+	}
+
+	items := make([]helmCrdV2.HelmReleaseSetItem, 0, len(instances))
+	for _, item := range instances {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// setRelease returns the HelmRelease of an instance of set, named
+// <set>-<instance>
+func setRelease(set *helmCrdV2.HelmReleaseSet, item helmCrdV2.HelmReleaseSetItem) (*helmCrdV2.HelmRelease, error) {
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       set.Namespace,
+			Name:            set.Name + "-" + item.Name,
+			Labels:          map[string]string{},
+			Annotations:     map[string]string{},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(set, setKind)},
+		},
+		Spec: *set.Spec.Template.Spec.DeepCopy(),
+	}
+	for k, v := range set.Spec.Template.Labels {
+		h.Labels[k] = v
+	}
+	h.Labels[setLabel] = set.Name
+	for k, v := range set.Spec.Template.Annotations {
+		h.Annotations[k] = v
+	}
+	if item.TargetNamespace != "" {
+		h.Spec.TargetNamespace = item.TargetNamespace
+	}
+	if item.Values != "" {
+		values, err := valuesUtils.Merge([]byte(h.Spec.Values), []byte(item.Values))
+		if err != nil {
+			return nil, fmt.Errorf("invalid values of instance %s: %v", item.Name, err)
+		}
+		h.Spec.Values = helmCrdV2.Values(values)
+	}
+
+	data, err := json.Marshal([]interface{}{h.Labels, h.Annotations, h.Spec})
+	if err != nil {
+		return nil, err
+	}
+	h.Annotations[setHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256(data))
+	return h, nil
+}
+
+// setReleases returns the HelmReleases controlled by set
+func (c *Controller) setReleases(set *helmCrdV2.HelmReleaseSet) []*helmCrdV2.HelmRelease {
+	var hrs []*helmCrdV2.HelmRelease
+	for _, obj := range c.informer.GetStore().List() {
+		h := obj.(*helmCrdV2.HelmRelease)
+		if h.Namespace == set.Namespace && h.Labels[setLabel] == set.Name && metav1.IsControlledBy(h, set) {
+			hrs = append(hrs, h)
+		}
+	}
+	return hrs
+}
+
+// reconcileSet creates, updates and deletes the HelmReleases of the
+// HelmReleaseSet with key to match its instances. HelmReleases are owned
+// by the set, so they are garbage collected when it is deleted.
+func (c *Controller) reconcileSet(key string) error {
+	obj, exists, err := c.setInformer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return fmt.Errorf("error fetching object with key %s from store: %v", key, err)
+	}
+	if !exists {
+		return nil
+	}
+	set := obj.(*helmCrdV2.HelmReleaseSet)
+	if set.DeletionTimestamp != nil {
+		return nil
+	}
+	slog := logger.With("helmreleaseset", key)
+
+	var namespaces []*corev1.Namespace
+	if set.Spec.NamespaceSelector != nil {
+		for _, obj := range c.namespaceInformer.GetStore().List() {
+			namespaces = append(namespaces, obj.(*corev1.Namespace))
+		}
+	}
+	items, err := setInstances(set, namespaces)
+	if err != nil {
+		return c.updateSetStatus(set, nil, err)
+	}
+
+	existing := map[string]*helmCrdV2.HelmRelease{}
+	for _, h := range c.setReleases(set) {
+		existing[h.Name] = h
+	}
+	var names, errs []string
+	for _, item := range items {
+		desired, err := setRelease(set, item)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		names = append(names, desired.Name)
+		current, ok := existing[desired.Name]
+		delete(existing, desired.Name)
+		switch {
+		case ok && setReleaseUpToDate(current, desired):
+			continue
+		case c.dryRun:
+			slog.With("helmrelease", desired.Name).Infof("Dry-run: would create or update HelmRelease")
+		case ok:
+			updated := current.DeepCopy()
+			updated.Labels, updated.Spec = desired.Labels, desired.Spec
+			// Keep the annotations set by users, such as force-sync requests
+			if updated.Annotations == nil {
+				updated.Annotations = map[string]string{}
+			}
+			for k, v := range desired.Annotations {
+				updated.Annotations[k] = v
+			}
+			slog.With("helmrelease", desired.Name).Infof("Updating HelmRelease")
+			if _, err := updateHelmRelease(c.helmReleaseClient, updated); err != nil {
+				errs = append(errs, fmt.Sprintf("unable to update HelmRelease %s: %v", desired.Name, err))
+			}
+		default:
+			slog.With("helmrelease", desired.Name).Infof("Creating HelmRelease")
+			_, err := c.helmReleaseClient.HelmV2().HelmReleases(set.Namespace).Create(desired)
+			if k8sErrors.IsAlreadyExists(err) {
+				err = fmt.Errorf("not owned by the set")
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("unable to create HelmRelease %s: %v", desired.Name, err))
+			}
+		}
+	}
+	for name := range existing {
+		if c.dryRun {
+			slog.With("helmrelease", name).Infof("Dry-run: would delete HelmRelease")
+			continue
+		}
+		slog.With("helmrelease", name).Infof("Deleting HelmRelease of removed instance")
+		err := c.helmReleaseClient.HelmV2().HelmReleases(set.Namespace).Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf("unable to delete HelmRelease %s: %v", name, err))
+		}
+	}
+
+	sort.Strings(errs)
+	err = nil
+	if len(errs) > 0 {
+		err = fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return c.updateSetStatus(set, names, err)
+}
+
+// setReleaseUpToDate returns whether current was last updated to desired
+func setReleaseUpToDate(current, desired *helmCrdV2.HelmRelease) bool {
+	return current.Annotations[setHashAnnotation] == desired.Annotations[setHashAnnotation]
+}
+
+// updateSetStatus records the HelmReleases of set and their readiness,
+// along with the reconcile error, which is returned
+func (c *Controller) updateSetStatus(set *helmCrdV2.HelmReleaseSet, names []string, err error) error {
+	status := helmCrdV2.HelmReleaseSetStatus{
+		ObservedGeneration: set.Generation,
+		Instances:          names,
+	}
+	for _, h := range c.setReleases(set) {
+		if ready := getCondition(&h.Status, helmCrdV2.HelmReleaseReady); ready != nil && ready.Status == corev1.ConditionTrue {
+			status.ReadyInstances++
+		}
+	}
+	if err != nil {
+		status.Message = err.Error()
+	}
+	if c.dryRun || apiequality.Semantic.DeepEqual(set.Status, status) {
+		return err
+	}
+	setCopy := set.DeepCopy()
+	setCopy.Status = status
+	if _, updateErr := c.helmReleaseClient.HelmV2().HelmReleaseSets(set.Namespace).Update(setCopy); updateErr != nil && err == nil {
+		return updateErr
+	}
+	return err
+}
+
+// enqueueSet queues the HelmReleaseSet controlling a changed HelmRelease,
+// so that its status counts the ready instances
+func (c *Controller) enqueueSet(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	h, ok := obj.(*helmCrdV2.HelmRelease)
+	if !ok {
+		return
+	}
+	if ref := metav1.GetControllerOf(h); ref != nil && ref.Kind == setKind.Kind && h.Labels[setLabel] == ref.Name {
+		c.setQueue.Add(h.Namespace + "/" + ref.Name)
+	}
+}
+
+// enqueueSelectingSets queues the HelmReleaseSets with a namespace
+// selector when a namespace changes
+func (c *Controller) enqueueSelectingSets(obj interface{}) {
+	for _, obj := range c.setInformer.GetStore().List() {
+		set := obj.(*helmCrdV2.HelmReleaseSet)
+		if set.Spec.NamespaceSelector == nil {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(set); err == nil {
+			c.setQueue.Add(key)
+		}
+	}
+}
+
+func (c *Controller) runSetWorker() {
+	for c.processNextSet() {
+		// continue looping
+	}
+}
+
+func (c *Controller) processNextSet() bool {
+	key, quit := c.setQueue.Get()
+	if quit {
+		return false
+	}
+	defer c.setQueue.Done(key)
+	if !c.shard.owns(key.(string)) {
+		c.setQueue.Forget(key)
+		return true
+	}
+
+	err := c.reconcileSet(key.(string))
+	if err == nil {
+		c.setQueue.Forget(key)
+	} else if c.setQueue.NumRequeues(key) < defaultMaxRetries {
+		logger.With("helmreleaseset", key, "error", err).Warnf("Error updating, will retry")
+		c.setQueue.AddRateLimited(key)
+	} else {
+		logger.With("helmreleaseset", key, "error", err).Errorf("Error updating, giving up")
+		c.setQueue.Forget(key)
+		utilruntime.HandleError(err)
+	}
+	return true
+}
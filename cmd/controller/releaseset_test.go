@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func testReleaseSet() *helmCrdV2.HelmReleaseSet {
+	return &helmCrdV2.HelmReleaseSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "wordpress", UID: "1234"},
+		Spec: helmCrdV2.HelmReleaseSetSpec{
+			Template: helmCrdV2.HelmReleaseTemplate{
+				Labels: map[string]string{"app": "wordpress"},
+				Spec: helmCrdV2.HelmReleaseSpec{
+					Chart:  helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "wordpress"}},
+					Values: "replicas: 1\nimage: wordpress\n",
+				},
+			},
+			Items: []helmCrdV2.HelmReleaseSetItem{
+				{Name: "tenant-a", Values: "replicas: 3\n"},
+				{Name: "team-b", TargetNamespace: "team-b-prod"},
+			},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"wordpress": "true"}},
+		},
+	}
+}
+
+func testNamespaces() []*corev1.Namespace {
+	return []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"wordpress": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"wordpress": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-c"}},
+	}
+}
+
+func TestSetInstances(t *testing.T) {
+	items, err := setInstances(testReleaseSet(), testNamespaces())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []helmCrdV2.HelmReleaseSetItem{
+		{Name: "team-a", TargetNamespace: "team-a"},
+		{Name: "team-b", TargetNamespace: "team-b-prod"},
+		{Name: "tenant-a", Values: "replicas: 3\n"},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expecting %v, received %v", expected, items)
+	}
+	for i := range expected {
+		if items[i] != expected[i] {
+			t.Errorf("Expecting %v, received %v", expected[i], items[i])
+		}
+	}
+}
+
+func TestReconcileSet(t *testing.T) {
+	controller := prepareTestController(nil, []string{})
+	set := testReleaseSet()
+	controller.helmReleaseClient.HelmV2().HelmReleaseSets("myns").Create(set)
+	controller.setInformer.GetIndexer().Add(set)
+	for _, ns := range testNamespaces() {
+		controller.namespaceInformer.GetIndexer().Add(ns)
+	}
+
+	if err := controller.reconcileSet("myns/wordpress"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	hrs, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").List(metav1.ListOptions{})
+	if len(hrs.Items) != 3 {
+		t.Fatalf("Expecting 3 HelmReleases, received %d", len(hrs.Items))
+	}
+	for i := range hrs.Items {
+		h := &hrs.Items[i]
+		if !metav1.IsControlledBy(h, set) || h.Labels[setLabel] != "wordpress" || h.Labels["app"] != "wordpress" {
+			t.Errorf("%s: unexpected metadata %+v", h.Name, h.ObjectMeta)
+		}
+		switch h.Name {
+		case "wordpress-tenant-a":
+			if !strings.Contains(string(h.Spec.Values), "replicas: 3") || !strings.Contains(string(h.Spec.Values), "image: wordpress") {
+				t.Errorf("Expecting the item values to be merged, received %q", h.Spec.Values)
+			}
+		case "wordpress-team-b":
+			if h.Spec.TargetNamespace != "team-b-prod" {
+				t.Errorf("Expecting the item to override the target namespace, received %q", h.Spec.TargetNamespace)
+			}
+		case "wordpress-team-a":
+			if h.Spec.TargetNamespace != "team-a" || h.Spec.Values != set.Spec.Template.Spec.Values {
+				t.Errorf("Unexpected spec %+v", h.Spec)
+			}
+		default:
+			t.Errorf("Unexpected HelmRelease %s", h.Name)
+		}
+		controller.informer.GetIndexer().Add(h)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleaseSets("myns").Get("wordpress", metav1.GetOptions{})
+	if len(res.Status.Instances) != 3 || res.Status.ReadyInstances != 0 {
+		t.Errorf("Unexpected status %+v", res.Status)
+	}
+
+	// Defaults filled in by the webhook don't make HelmReleases outdated
+	obj, _, _ := controller.informer.GetIndexer().GetByKey("myns/wordpress-team-a")
+	defaulted := obj.(*helmCrdV2.HelmRelease).DeepCopy()
+	defaulted.Spec.Timeout = 300
+	controller.informer.GetIndexer().Update(defaulted)
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(defaulted)
+
+	set = set.DeepCopy()
+	set.Spec.NamespaceSelector = nil
+	controller.setInformer.GetIndexer().Update(set)
+	if err := controller.reconcileSet("myns/wordpress"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	hrs, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").List(metav1.ListOptions{})
+	if len(hrs.Items) != 2 {
+		t.Fatalf("Expecting the HelmRelease of the unselected namespace to be deleted, received %d", len(hrs.Items))
+	}
+	h, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("wordpress-team-b", metav1.GetOptions{})
+	if h.Spec.TargetNamespace != "team-b-prod" || h.Spec.Timeout != 0 {
+		t.Errorf("Unexpected spec %+v", h.Spec)
+	}
+}
+
+func TestReconcileSetNotOwned(t *testing.T) {
+	existing := helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "wordpress-tenant-a"}}
+	controller := prepareTestController(nil, []string{})
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Create(&existing)
+	set := testReleaseSet()
+	set.Spec.NamespaceSelector = nil
+	controller.helmReleaseClient.HelmV2().HelmReleaseSets("myns").Create(set)
+	controller.setInformer.GetIndexer().Add(set)
+
+	err := controller.reconcileSet("myns/wordpress")
+	if err == nil || !strings.Contains(err.Error(), "not owned by the set") {
+		t.Errorf("Expecting an error, received %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleaseSets("myns").Get("wordpress", metav1.GetOptions{})
+	if !strings.Contains(res.Status.Message, "wordpress-tenant-a") {
+		t.Errorf("Unexpected status %+v", res.Status)
+	}
+	h, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("wordpress-tenant-a", metav1.GetOptions{})
+	if h.Spec.Chart.Repository != nil {
+		t.Errorf("Expecting the existing HelmRelease to be left alone")
+	}
+}
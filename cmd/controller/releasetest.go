@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/tracing"
+)
+
+// reasonTestFailed is the reason of the Ready condition of HelmReleases
+// rolled back because the tests of an upgrade failed
+const reasonTestFailed = "TestFailed"
+
+// testUpgrade runs the tests of the release of h after an upgrade and,
+// if they fail, rolls it back to the previous revision. The failure is
+// returned.
+func (c *Controller) testUpgrade(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, rlsName string, span *tracing.Span, rlog *logging.Logger) error {
+	t := h.Spec.Test
+	s := c.tracer.Start(span, "tiller.test")
+	err := helmClient.Test(rlsName, helmclient.TestOptions{Timeout: t.Timeout, Cleanup: t.Cleanup})
+	s.End(err)
+	if err == nil {
+		return nil
+	}
+
+	rlog.With("error", err).Warnf("Tests failed, rolling back")
+	opts := helmclient.RollbackOptions{DisableHooks: h.Spec.DisableHooks}
+	if rb := h.Spec.Rollback; rb != nil {
+		opts.Recreate, opts.Force = rb.Recreate, rb.Force
+	}
+	s = c.tracer.Start(span, "tiller.rollback")
+	_, rbErr := helmClient.Rollback(rlsName, opts)
+	s.End(rbErr)
+	if rbErr != nil {
+		return fmt.Errorf("tests failed: %v, and rolling back failed: %v", err, rbErr)
+	}
+	return fmt.Errorf("tests failed, rolled back to the previous revision: %v", err)
+}
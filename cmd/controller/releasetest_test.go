@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUpgradeTests(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure string
+		calls   []string
+		reason  string
+	}{
+		{"passing", "", []string{"History", "Upgrade", "Test", "Status"}, reasonDeployed},
+		{"failing", "test foo-test failed", []string{"History", "Upgrade", "Test", "Rollback"}, reasonTestFailed},
+	}
+	for _, tt := range tests {
+		h := helmCrdV2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			Spec: helmCrdV2.HelmReleaseSpec{
+				ReleaseName: "bar",
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+					URL:     "http://charts.example.com/repo/",
+					Name:    "foo",
+					Version: "1.0.0",
+				}},
+				Test: &helmCrdV2.TestSpec{Enable: true, Timeout: 60},
+			},
+			// Deployed by a previous reconcile
+			Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+		}
+		controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+		helmClient := fakeHelmClient(controller)
+		if tt.failure != "" {
+			helmClient.TestFailures = map[string]string{"bar": tt.failure}
+		}
+		if err := controller.updateRelease("myns/foo"); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(helmClient.Calls, tt.calls) {
+			t.Errorf("%s: expecting calls %v, received %v", tt.name, tt.calls, helmClient.Calls)
+		}
+		res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+		ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady)
+		if ready == nil || ready.Reason != tt.reason || !strings.Contains(ready.Message, tt.failure) {
+			t.Errorf("%s: unexpected Ready condition %+v", tt.name, ready)
+		}
+		if tt.failure != "" && res.Status.FailureReason != reasonTestFailed {
+			t.Errorf("%s: expecting failure reason %s, received %q", tt.name, reasonTestFailed, res.Status.FailureReason)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+// Reasons of the Events and failures of the remediations of failed
+// releases
+const (
+	reasonRemediated           = "Remediated"
+	reasonRemediationFailed    = "RemediationFailed"
+	reasonRemediationExhausted = "RemediationExhausted"
+)
+
+// Defaults of spec.remediation
+const (
+	defaultRemediationRetries = 3
+	defaultRemediationBackoff = 30 * time.Second
+	maxRemediationBackoff     = time.Hour
+)
+
+// failedRelease returns whether the install or upgrade of rel failed
+func failedRelease(rel *release.Release) bool {
+	return rel.GetInfo().GetStatus().GetCode() == release.Status_FAILED
+}
+
+// remediationBackoff returns how long after the failure of its release h
+// is remediated for the attempts+1th time
+func remediationBackoff(spec *helmCrdV2.RemediationSpec, attempts int32) time.Duration {
+	backoff := defaultRemediationBackoff
+	if spec.Backoff > 0 {
+		backoff = time.Duration(spec.Backoff) * time.Second
+	}
+	for i := int32(0); i < attempts && backoff < maxRemediationBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRemediationBackoff {
+		backoff = maxRemediationBackoff
+	}
+	return backoff
+}
+
+// remediate repairs rel, the failed latest revision of the release of h,
+// per spec.remediation: rolling it back, or uninstalling it if there is
+// no previous revision or with the uninstall-reinstall strategy. The
+// remediations of a generation of h are backed off and limited to its
+// retries, after which h is failed until its spec changes. If proceed is
+// returned the release is repaired and h is upgraded or installed again.
+func (c *Controller) remediate(h *helmCrdV2.HelmRelease, key string, helmClient helmclient.Interface, rel *release.Release, rlog *logging.Logger) (*helmCrdV2.HelmRelease, bool, error) {
+	spec := h.Spec.Remediation
+	retries := int32(defaultRemediationRetries)
+	if spec.Retries != nil {
+		retries = *spec.Retries
+	}
+	var attempts int32
+	if r := h.Status.Remediation; r != nil && r.Generation == h.Generation {
+		attempts = r.Attempts
+	}
+	rlog = rlog.With("revision", rel.Version, "attempts", attempts)
+	if attempts >= retries {
+		err := fmt.Errorf("release %s revision %d failed after %d remediations: %s", rel.Name, rel.Version, attempts, rel.GetInfo().GetDescription())
+		rlog.Warnf("Giving up remediating release")
+		return h, false, c.rejectRelease(h, reasonRemediationExhausted, err)
+	}
+
+	failedAt, _ := ptypes.Timestamp(rel.GetInfo().GetLastDeployed())
+	if r := h.Status.Remediation; attempts > 0 && r.LastAttemptTime.After(failedAt) {
+		failedAt = r.LastAttemptTime.Time
+	}
+	if remaining := failedAt.Add(remediationBackoff(spec, attempts)).Sub(c.now()); remaining > 0 {
+		rlog.With("after", remaining).Infof("Remediating failed release later")
+		c.queue.AddAfter(key, remaining)
+		return h, false, nil
+	}
+
+	// Failed revisions are not labeled, the releases whose last install
+	// or upgrade h did not fail must be owned already
+	switch h.Status.FailureReason {
+	case reasonInstallFailed, reasonUpgradeFailed, reasonRemediationFailed:
+	default:
+		if err := c.checkOwnership(h, rel); err != nil {
+			return h, false, err
+		}
+	}
+
+	strategy := spec.Strategy
+	if strategy == "" {
+		strategy = helmCrdV2.RemediationRollback
+	}
+	action := "rolled back"
+	var err error
+	if strategy == helmCrdV2.RemediationUninstallReinstall || rel.Version <= 1 {
+		action = "uninstalled"
+		err = helmClient.Delete(rel.Name, helmclient.DeleteOptions{Purge: true, DisableHooks: h.Spec.DisableHooks})
+	} else {
+		_, err = helmClient.Rollback(rel.Name, helmclient.RollbackOptions{DisableHooks: h.Spec.DisableHooks})
+	}
+
+	status := h.Status
+	status.Remediation = &helmCrdV2.RemediationStatus{
+		Attempts:        attempts + 1,
+		Generation:      h.Generation,
+		LastAttemptTime: metav1.NewTime(c.now()),
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to remediate release %s revision %d: %v", rel.Name, rel.Version, err)
+		setFailed(&status, reasonRemediationFailed, err)
+	}
+	h, updateErr := c.updateStatus(h, status)
+	if updateErr != nil {
+		return h, false, updateErr
+	}
+	if err != nil {
+		rlog.With("error", err).Warnf("Remediation failed")
+		c.queue.AddAfter(key, remediationBackoff(spec, attempts+1))
+		return h, false, nil
+	}
+	message := fmt.Sprintf("Failed release %s revision %d %s, attempt %d of %d", rel.Name, rel.Version, action, attempts+1, retries)
+	rlog.With("strategy", strategy).Infof("Remediated failed release")
+	c.recordEvent(h, corev1.EventTypeNormal, reasonRemediated, message)
+	return h, true, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestRemediateFailedRelease(t *testing.T) {
+	one := int32(1)
+	testCases := []struct {
+		name        string
+		remediation helmCrdV2.RemediationSpec
+		// failed are the revisions, the last one failed
+		failed   int32
+		attempts int32
+		phase    helmCrdV2.HelmReleasePhase
+		reason   string
+		deployed []int32
+		calls    []string
+	}{
+		{
+			name:     "failed upgrade rolled back and upgraded again",
+			failed:   2,
+			phase:    helmCrdV2.PhaseDeployed,
+			deployed: []int32{1, 2, 3, 4},
+			calls:    []string{"History", "Rollback", "History", "Upgrade", "Status"},
+		},
+		{
+			name:     "failed install uninstalled and installed again",
+			failed:   1,
+			phase:    helmCrdV2.PhaseDeployed,
+			deployed: []int32{1},
+			calls:    []string{"History", "Delete", "History", "Install", "Status"},
+		},
+		{
+			name:        "failed upgrade reinstalled",
+			remediation: helmCrdV2.RemediationSpec{Strategy: helmCrdV2.RemediationUninstallReinstall},
+			failed:      2,
+			phase:       helmCrdV2.PhaseDeployed,
+			deployed:    []int32{1},
+			calls:       []string{"History", "Delete", "History", "Install", "Status"},
+		},
+		{
+			name:        "retries exhausted",
+			remediation: helmCrdV2.RemediationSpec{Retries: &one},
+			failed:      2,
+			attempts:    1,
+			phase:       helmCrdV2.PhaseFailed,
+			reason:      reasonRemediationExhausted,
+			deployed:    []int32{1, 2},
+			calls:       []string{"History"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			remediation := tc.remediation
+			h := helmCrdV2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", Generation: 2},
+				Spec: helmCrdV2.HelmReleaseSpec{
+					ReleaseName: "foo",
+					Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+						URL:     "http://charts.example.com/repo/",
+						Name:    "foo",
+						Version: "1.0.0",
+					}},
+					Remediation: &remediation,
+				},
+				Status: helmCrdV2.HelmReleaseStatus{
+					Phase:         helmCrdV2.PhaseFailed,
+					FailureReason: reasonUpgradeFailed,
+				},
+			}
+			if tc.failed == 1 {
+				h.Status.FailureReason = reasonInstallFailed
+			} else {
+				h.Status.Revision = tc.failed - 1
+			}
+			if tc.attempts > 0 {
+				h.Status.Remediation = &helmCrdV2.RemediationStatus{Attempts: tc.attempts, Generation: 2}
+			}
+			controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+			helmClient := fakeHelmClient(controller)
+			for v := int32(1); v < tc.failed; v++ {
+				helmClient.Releases = append(helmClient.Releases, helmclient.MockRelease("foo", "myns", v, &chart.Chart{}, release.Status_SUPERSEDED))
+			}
+			helmClient.Releases = append(helmClient.Releases, helmclient.MockRelease("foo", "myns", tc.failed, &chart.Chart{}, release.Status_FAILED))
+
+			if err := controller.updateRelease("myns/foo"); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if res.Status.Phase != tc.phase || res.Status.FailureReason != tc.reason {
+				t.Errorf("Expecting phase %s with reason %q, received %s %q", tc.phase, tc.reason, res.Status.Phase, res.Status.FailureReason)
+			}
+			if tc.phase == helmCrdV2.PhaseDeployed && res.Status.Remediation != nil {
+				t.Errorf("Expecting the remediations to be cleared, received %+v", res.Status.Remediation)
+			}
+			var versions []int32
+			for _, rel := range helmClient.Releases {
+				versions = append(versions, rel.Version)
+			}
+			if !reflect.DeepEqual(versions, tc.deployed) {
+				t.Errorf("Expecting revisions %v, received %v", tc.deployed, versions)
+			}
+			if !reflect.DeepEqual(helmClient.Calls, tc.calls) {
+				t.Errorf("Expecting calls %v, received %v", tc.calls, helmClient.Calls)
+			}
+		})
+	}
+}
+
+func TestRemediationBackoff(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", Generation: 1},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "foo",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Remediation: &helmCrdV2.RemediationSpec{Backoff: 60},
+		},
+		Status: helmCrdV2.HelmReleaseStatus{
+			Phase:         helmCrdV2.PhaseFailed,
+			FailureReason: reasonUpgradeFailed,
+			Revision:      1,
+		},
+	}
+	now := time.Now()
+	// The second remediation is backed off twice as long after the first
+	h.Status.Remediation = &helmCrdV2.RemediationStatus{Attempts: 1, Generation: 1, LastAttemptTime: metav1.NewTime(now.Add(-90 * time.Second))}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.now = func() time.Time { return now }
+	helmClient := fakeHelmClient(controller)
+	helmClient.Releases = append(helmClient.Releases,
+		helmclient.MockRelease("foo", "myns", 1, &chart.Chart{}, release.Status_SUPERSEDED),
+		helmclient.MockRelease("foo", "myns", 2, &chart.Chart{}, release.Status_FAILED))
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(helmClient.Calls, []string{"History"}) {
+		t.Errorf("Expecting the remediation to be backed off, received calls %v", helmClient.Calls)
+	}
+
+	for attempts, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		if backoff := remediationBackoff(h.Spec.Remediation, int32(attempts)); backoff != expected {
+			t.Errorf("Expecting backoff %s after %d attempts, received %s", expected, attempts, backoff)
+		}
+	}
+	if backoff := remediationBackoff(h.Spec.Remediation, 20); backoff != maxRemediationBackoff {
+		t.Errorf("Expecting the backoff to be capped, received %s", backoff)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/kubeconfig"
+)
+
+// reasonRemoteClusterFailed is the failure reason of HelmReleases whose
+// spec.kubeConfigSecretRef can't be used
+const reasonRemoteClusterFailed = "RemoteClusterFailed"
+
+// remoteCluster holds the clients of a cluster that releases are deployed
+// to with spec.kubeConfigSecretRef
+type remoteCluster struct {
+	// helmClient applies releases to the cluster, nil with Tiller, whose
+	// address is given by spec.tillerHost
+	helmClient helmclient.Interface
+	objects    objectClient
+	storage    revisionStorage
+	// hash identifies the kubeconfig the clients were created from
+	hash [sha256.Size]byte
+}
+
+// newRemoteCluster connects to the cluster of config. Without Tiller,
+// revisions are stored in storageNamespace of that cluster.
+func newRemoteCluster(config *rest.Config, tillerless bool, storageNamespace string) (*remoteCluster, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	objects := &restObjectClient{discovery: kubeClient.Discovery()}
+	if !tillerless {
+		return &remoteCluster{objects: objects, storage: configMapStorage{kubeClient: kubeClient}}, nil
+	}
+	return &remoteCluster{
+		helmClient: helmclient.NewApplyClient(kubeClient, objects, storageNamespace),
+		objects:    objects,
+		storage:    secretStorage{kubeClient: kubeClient},
+	}, nil
+}
+
+// remoteClusterFor returns the clients of the cluster h is deployed to,
+// or nil for the controller's cluster. Clients are reused until the
+// kubeconfig Secret changes.
+func (c *Controller) remoteClusterFor(h *helmCrdV2.HelmRelease) (*remoteCluster, error) {
+	ref := h.Spec.KubeConfigSecretRef
+	if ref == nil {
+		return nil, nil
+	}
+	secret, err := c.kubeClient.Core().Secrets(h.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, failed(reasonRemoteClusterFailed, fmt.Errorf("unable to read kubeconfig: %v", err))
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, failed(reasonRemoteClusterFailed, fmt.Errorf("key %s not found in Secret %s", ref.Key, ref.Name))
+	}
+	hash := sha256.Sum256(data)
+	key := h.Namespace + "/" + ref.Name + "/" + ref.Key
+
+	c.remoteClustersLock.Lock()
+	defer c.remoteClustersLock.Unlock()
+	if cluster, ok := c.remoteClusters[key]; ok && cluster.hash == hash {
+		return cluster, nil
+	}
+	config, err := kubeconfig.RESTConfig(data)
+	if err != nil {
+		return nil, failed(reasonRemoteClusterFailed, fmt.Errorf("Secret %s: %v", ref.Name, err))
+	}
+	cluster, err := c.newRemoteCluster(config, c.tillerless, c.tillerNamespace)
+	if err != nil {
+		return nil, failed(reasonRemoteClusterFailed, err)
+	}
+	cluster.hash = hash
+	c.remoteClusters[key] = cluster
+	return cluster, nil
+}
+
+// objectsFor returns the client of the objects of the release of h
+func (c *Controller) objectsFor(h *helmCrdV2.HelmRelease) (objectClient, error) {
+	cluster, err := c.remoteClusterFor(h)
+	if err != nil || cluster == nil {
+		return c.objects, err
+	}
+	return cluster.objects, nil
+}
+
+// storageFor returns the storage of the revisions of the release of h
+func (c *Controller) storageFor(h *helmCrdV2.HelmRelease) (revisionStorage, error) {
+	cluster, err := c.remoteClusterFor(h)
+	if err != nil || cluster == nil {
+		return c.storage, err
+	}
+	return cluster.storage, nil
+}
+
+// remoteClusterKey identifies the cluster of the release of h, "" for
+// the controller's cluster
+func remoteClusterKey(h *helmCrdV2.HelmRelease) string {
+	if ref := h.Spec.KubeConfigSecretRef; ref != nil {
+		return h.Namespace + "/" + ref.Name
+	}
+	return ""
+}
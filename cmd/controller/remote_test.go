@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+const testKubeConfig = `
+apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: deployer
+users:
+- name: deployer
+  user:
+    token: abcd
+`
+
+func remoteHelmRelease() helmCrdV2.HelmRelease {
+	return helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			KubeConfigSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "prod"},
+				Key:                  "value",
+			},
+		},
+	}
+}
+
+func TestRemoteCluster(t *testing.T) {
+	h := remoteHelmRelease()
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.tillerless = true
+	controller.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "prod"},
+		Data:       map[string][]byte{"value": []byte(testKubeConfig)},
+	})
+	remote := helmclient.NewFakeClient()
+	var hosts []string
+	controller.newRemoteCluster = func(config *rest.Config, tillerless bool, storageNamespace string) (*remoteCluster, error) {
+		hosts = append(hosts, config.Host)
+		return &remoteCluster{
+			helmClient: remote,
+			objects:    controller.objects,
+			storage:    secretStorage{kubeClient: fake.NewSimpleClientset()},
+		}, nil
+	}
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(remote.Releases) != 1 || len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release to be deployed to the remote cluster only")
+	}
+	if c, err := controller.helmClientFor(&h); err != nil || c != remote {
+		t.Errorf("Expecting the client of the remote cluster, received %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "https://prod.example.com" {
+		t.Errorf("Expecting a cached client of the remote cluster, connected to %v", hosts)
+	}
+
+	local := h
+	local.Name = "bar"
+	local.Spec.ReleaseName = "myns-foo"
+	local.Spec.KubeConfigSecretRef = nil
+	if controller.sameRelease(&h, &local) {
+		t.Errorf("Expecting releases of different clusters not to conflict")
+	}
+}
+
+func TestRemoteClusterErrors(t *testing.T) {
+	h := remoteHelmRelease()
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err == nil || errorReason(err) != reasonRemoteClusterFailed {
+		t.Errorf("Expecting a missing kubeconfig Secret to fail, received %v", err)
+	}
+
+	controller.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "prod"},
+		Data:       map[string][]byte{"value": []byte(testKubeConfig)},
+	})
+	controller.newRemoteCluster = func(config *rest.Config, tillerless bool, storageNamespace string) (*remoteCluster, error) {
+		return &remoteCluster{objects: controller.objects, storage: controller.storage}, nil
+	}
+	if err := controller.updateRelease("myns/foo"); err == nil || errorReason(err) != reasonRemoteClusterFailed {
+		t.Errorf("Expecting spec.tillerHost to be required with Tiller, received %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed in the local cluster")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// renderedManifestsKey is the data key holding the manifests of render-only releases
+const renderedManifestsKey = "manifest.yaml"
+
+// usesSecretValues returns true when any values may be sensitive, i.e.
+// not literal nor read from a plain ConfigMap, in which case the rendered
+// manifests must not end up in a ConfigMap
+func usesSecretValues(h *helmCrdV2.HelmRelease) bool {
+	for _, src := range h.Spec.ValuesFrom {
+		if src.ConfigMapKeyRef == nil || src.Sops {
+			return true
+		}
+	}
+	return false
+}
+
+// storeRenderedManifests writes the manifest of a render-only release to a
+// ConfigMap or Secret owned by the HelmRelease
+func (c *Controller) storeRenderedManifests(h *helmCrdV2.HelmRelease, rel *release.Release) (*helmCrdV2.RenderedManifestsReference, error) {
+	return c.storeReleaseData(h, h.Name+"-manifests", renderedManifestsKey, rel.GetManifest())
+}
+
+// storeReleaseData writes data under key to the ConfigMap name owned by
+// the HelmRelease, or to a Secret when values may be sensitive as data
+// may include them. Objects not owned by the HelmRelease are left alone,
+// and the one of the other kind, written by previous reconciles, is
+// deleted.
+func (c *Controller) storeReleaseData(h *helmCrdV2.HelmRelease, name, key, data string) (*helmCrdV2.RenderedManifestsReference, error) {
+	ref := &helmCrdV2.RenderedManifestsReference{
+		Kind: "ConfigMap",
+		Name: name,
+		Key:  key,
+	}
+	objMeta := metav1.ObjectMeta{
+		Namespace:       h.Namespace,
+		Name:            ref.Name,
+		OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(h, helmCrdV2.SchemeGroupVersion.WithKind("HelmRelease"))},
+	}
+	secrets := c.kubeClient.Core().Secrets(h.Namespace)
+	configMaps := c.kubeClient.Core().ConfigMaps(h.Namespace)
+
+	if usesSecretValues(h) {
+		ref.Kind = "Secret"
+		secret, err := secrets.Get(name, metav1.GetOptions{})
+		switch {
+		case k8sErrors.IsNotFound(err):
+			_, err = secrets.Create(&corev1.Secret{ObjectMeta: objMeta, Data: map[string][]byte{ref.Key: []byte(data)}})
+		case err != nil:
+		case !metav1.IsControlledBy(secret, h):
+			err = fmt.Errorf("Secret %s is not owned by HelmRelease %s", name, h.Name)
+		default:
+			secret = secret.DeepCopy()
+			secret.Data = map[string][]byte{ref.Key: []byte(data)}
+			_, err = secrets.Update(secret)
+		}
+		if err != nil {
+			return ref, err
+		}
+		if cm, err := configMaps.Get(name, metav1.GetOptions{}); err == nil && metav1.IsControlledBy(cm, h) {
+			return ref, configMaps.Delete(name, &metav1.DeleteOptions{})
+		}
+		return ref, nil
+	}
+
+	cm, err := configMaps.Get(name, metav1.GetOptions{})
+	switch {
+	case k8sErrors.IsNotFound(err):
+		_, err = configMaps.Create(&corev1.ConfigMap{ObjectMeta: objMeta, Data: map[string]string{ref.Key: data}})
+	case err != nil:
+	case !metav1.IsControlledBy(cm, h):
+		err = fmt.Errorf("ConfigMap %s is not owned by HelmRelease %s", name, h.Name)
+	default:
+		cm = cm.DeepCopy()
+		cm.Data = map[string]string{ref.Key: data}
+		_, err = configMaps.Update(cm)
+	}
+	if err != nil {
+		return ref, err
+	}
+	if secret, err := secrets.Get(name, metav1.GetOptions{}); err == nil && metav1.IsControlledBy(secret, h) {
+		return ref, secrets.Delete(name, &metav1.DeleteOptions{})
+	}
+	return ref, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUsesSecretValues(t *testing.T) {
+	configMap := &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"}
+	tests := []struct {
+		name     string
+		sources  []helmCrdV2.ValuesSource
+		expected bool
+	}{
+		{"literal", nil, false},
+		{"configmap", []helmCrdV2.ValuesSource{{ConfigMapKeyRef: configMap}}, false},
+		{"sops configmap", []helmCrdV2.ValuesSource{{ConfigMapKeyRef: configMap, Sops: true}}, true},
+		{"secret", []helmCrdV2.ValuesSource{{ConfigMapKeyRef: configMap}, {SecretKeyRef: &corev1.SecretKeySelector{}}}, true},
+		{"field", []helmCrdV2.ValuesSource{{FieldRef: &helmCrdV2.ObjectFieldRef{}}}, true},
+		{"url", []helmCrdV2.ValuesSource{{URL: "https://example.com/values.yaml"}}, true},
+		{"outputs", []helmCrdV2.ValuesSource{{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "db"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{ValuesFrom: tt.sources}}
+			if secret := usesSecretValues(h); secret != tt.expected {
+				t.Errorf("Expecting %v, received %v", tt.expected, secret)
+			}
+		})
+	}
+}
+
+func TestStoreReleaseData(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", UID: "1234"},
+		Spec:       helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	configMaps := controller.kubeClient.Core().ConfigMaps("myns")
+	secrets := controller.kubeClient.Core().Secrets("myns")
+
+	if _, err := controller.storeReleaseData(&h, "foo-manifests", "manifest.yaml", "kind: ConfigMap"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	h.Spec.ValuesFrom = []helmCrdV2.ValuesSource{{URL: "https://example.com/values.yaml"}}
+	ref, err := controller.storeReleaseData(&h, "foo-manifests", "manifest.yaml", "kind: Secret")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ref.Kind != "Secret" {
+		t.Errorf("Expecting data to be stored in a Secret, received %s", ref.Kind)
+	}
+	if secret, err := secrets.Get("foo-manifests", metav1.GetOptions{}); err != nil || string(secret.Data["manifest.yaml"]) != "kind: Secret" {
+		t.Errorf("Expecting the data in the Secret, received %v %v", secret, err)
+	}
+	if _, err := configMaps.Get("foo-manifests", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expecting the ConfigMap of previous reconciles to be deleted")
+	}
+
+	configMaps.Create(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "bar-manifests"},
+		Data:       map[string]string{"app.conf": "foreign"},
+	})
+	h.Spec.ValuesFrom = nil
+	if _, err := controller.storeReleaseData(&h, "bar-manifests", "manifest.yaml", "kind: ConfigMap"); err == nil {
+		t.Errorf("Expecting ConfigMaps not owned by the HelmRelease not to be overwritten")
+	}
+	if cm, _ := configMaps.Get("bar-manifests", metav1.GetOptions{}); cm.Data["app.conf"] != "foreign" {
+		t.Errorf("Expecting the ConfigMap to be left alone, received %v", cm.Data)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+)
+
+// reasonRepoUnavailable is the reason of the RepoUnavailable and Ready
+// conditions of HelmReleases whose chart repository is down
+const reasonRepoUnavailable = "RepoUnavailable"
+
+// Defaults of the circuit breaker of chart repository hosts
+const (
+	defaultRepoFailureThreshold = 5
+	defaultRepoCooldown         = time.Minute
+)
+
+// markRepoUnavailable records in the RepoUnavailable and Ready conditions
+// of h that the host of its chart repository failed too many consecutive
+// requests, and requeues it for when a request is let through again. This
+// doesn't count as a failed reconcile, so the HelmReleases of a repository
+// that is down don't use up their retries.
+func (c *Controller) markRepoUnavailable(h *helmCrdV2.HelmRelease, key string, e *chartUtils.RepoUnavailableError) error {
+	c.queue.AddAfter(key, e.RetryAfter.Sub(c.now()))
+	status := h.Status
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseRepoUnavailable); cond == nil || cond.Status != corev1.ConditionTrue {
+		c.recordEvent(h, corev1.EventTypeWarning, reasonRepoUnavailable, e.Error())
+	}
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseRepoUnavailable,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonRepoUnavailable,
+		Message: e.Error(),
+	})
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reasonRepoUnavailable,
+		Message: e.Error(),
+	})
+	setFailed(&status, reasonRepoUnavailable, e)
+	_, err := c.updateStatus(h, status)
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+)
+
+func TestRepoUnavailable(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	fake := (*controller.netClient).(*fakeHTTPClient)
+	repoURLs := fake.repoURLs
+	// The repository is down
+	fake.repoURLs = nil
+	var breaker chartUtils.HTTPClient = &chartUtils.CircuitBreaker{Client: fake, Threshold: 1, Cooldown: time.Hour}
+	controller.netClient = &breaker
+
+	if err := controller.updateRelease("myns/foo"); errorReason(err) != reasonChartDownloadFailed {
+		t.Fatalf("Expecting the first request to fail, received %v", err)
+	}
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting the reconcile not to be retried, received %v", err)
+	}
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseRepoUnavailable)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != reasonRepoUnavailable {
+		t.Errorf("Unexpected RepoUnavailable condition %+v", cond)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Status != corev1.ConditionFalse {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	events, err := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != reasonRepoUnavailable {
+		t.Errorf("Unexpected events %+v", events.Items)
+	}
+
+	// The condition is removed once the repository is back
+	fake.repoURLs = repoURLs
+	var netClient chartUtils.HTTPClient = fake
+	controller.netClient = &netClient
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, err = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseRepoUnavailable); cond != nil {
+		t.Errorf("Expecting the RepoUnavailable condition to be removed, received %+v", cond)
+	}
+}
+
+func TestChartNotMirrored(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	var offline chartUtils.HTTPClient = &chartUtils.OfflineClient{
+		Client:  *controller.netClient,
+		Mirrors: []string{"http://mirror.charts.svc/"},
+	}
+	controller.netClient = &offline
+
+	err := controller.updateRelease("myns/foo")
+	if errorReason(err) != reasonChartNotMirrored {
+		t.Fatalf("Expecting the chart not to be mirrored, received %v", err)
+	}
+	if expected := "offline mode: http://charts.example.com/repo/index.yaml is not in the chart cache nor served by a mirror"; err.Error() != expected {
+		t.Errorf("Expecting error %q, received %q", expected, err)
+	}
+
+	// The chart is served by the mirror
+	offline.(*chartUtils.OfflineClient).Mirrors = append(offline.(*chartUtils.OfflineClient).Mirrors, "http://charts.example.com/repo/")
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// reasonRepositoryNotAllowed is the reason of the Ready condition and
+// Event of HelmReleases whose chart source the repository policy rejects
+const reasonRepositoryNotAllowed = "RepositoryNotAllowed"
+
+// chartURLs returns all the URLs the chart of h may be downloaded from
+func (c *Controller) chartURLs(h *helmCrdV2.HelmRelease) []string {
+	var urls []string
+	chart := h.Spec.ChartLocation()
+	if tarball := chart.Tarball; tarball != nil {
+		urls = append(urls, tarball.URL)
+	}
+	if oci := chart.OCI; oci != nil {
+		urls = append(urls, oci.URL)
+	}
+	if repo := chart.Repository; repo != nil {
+		repoURL, _ := c.repoURLAndAuth(repo)
+		urls = append(urls, repoURL)
+		urls = append(urls, repo.Mirrors...)
+	}
+	return urls
+}
+
+// checkRepoPolicy returns an error unless the controller repository
+// policy allows all the URLs the chart of h may be downloaded from.
+// Inline charts are only allowed when all repositories are.
+func (c *Controller) checkRepoPolicy(h *helmCrdV2.HelmRelease) error {
+	if h.Spec.Chart.Inline != nil && len(c.repoPolicy.Allowed) > 0 {
+		return fmt.Errorf("inline charts are not allowed, allowed repositories are %s", strings.Join(c.repoPolicy.Allowed, ", "))
+	}
+	for _, u := range c.chartURLs(h) {
+		if err := c.repoPolicy.Check(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectRelease records that h is not allowed, for reason, in its Ready
+// condition, along with conditions, and a Warning Event. The HelmRelease
+// is not retried until its spec changes.
+func (c *Controller) rejectRelease(h *helmCrdV2.HelmRelease, reason string, err error, conditions ...helmCrdV2.HelmReleaseCondition) error {
+	status := h.Status
+	for _, cond := range conditions {
+		setCondition(&status, cond)
+	}
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+	setFailed(&status, reason, err)
+	if _, updateErr := c.updateStatus(h, status); updateErr != nil {
+		return fmt.Errorf("unable to set Ready condition: %v", updateErr)
+	}
+	c.recordEvent(h, corev1.EventTypeWarning, reason, err.Error())
+	return nil
+}
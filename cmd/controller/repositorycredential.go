@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// repositoryCredential returns the RepositoryCredential of the controller
+// namespace with the longest URL prefix matching url, nil if none does
+func (c *Controller) repositoryCredential(rawURL string) *helmCrdV2.RepositoryCredential {
+	if c.credentialInformer == nil {
+		return nil
+	}
+	objs := c.credentialInformer.GetStore().List()
+	// Credentials matching with prefixes of the same length are picked by
+	// name, not by the order of the store
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].(*helmCrdV2.RepositoryCredential).Name < objs[j].(*helmCrdV2.RepositoryCredential).Name
+	})
+	var match *helmCrdV2.RepositoryCredential
+	longest := 0
+	for _, obj := range objs {
+		cred := obj.(*helmCrdV2.RepositoryCredential)
+		if cred.Namespace != controllerNamespace() {
+			continue
+		}
+		for _, prefix := range cred.Spec.URLPrefixes {
+			if len(prefix) > longest && matchesURLPrefix(rawURL, prefix) {
+				match, longest = cred, len(prefix)
+			}
+		}
+	}
+	return match
+}
+
+// matchesURLPrefix returns whether rawURL has the scheme and host of
+// prefix and its path is the path of prefix or under it, so that
+// https://charts.example.com/team does not match
+// https://charts.example.com/team-b or https://charts.example.com.evil.org
+func matchesURLPrefix(rawURL, prefix string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	p, err := url.Parse(prefix)
+	if err != nil || p.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(u.Scheme, p.Scheme) || !strings.EqualFold(u.Host, p.Host) || u.User != nil {
+		return false
+	}
+	prefixPath := strings.TrimSuffix(p.Path, "/")
+	return u.Path == prefixPath || strings.HasPrefix(u.Path, prefixPath+"/")
+}
+
+// credentialAuth returns the auth of the RepositoryCredential matching
+// url and the namespace of its secret, unless auth, read from namespace,
+// is set
+func (c *Controller) credentialAuth(url, namespace string, auth helmCrdV2.HelmReleaseAuth) (string, helmCrdV2.HelmReleaseAuth) {
+	if !authEmpty(auth) {
+		return namespace, auth
+	}
+	cred := c.repositoryCredential(url)
+	if cred == nil {
+		return namespace, auth
+	}
+	return cred.Namespace, cred.Spec.Auth
+}
+
+// chartSourceAuth returns the URL of the chart source of h, its auth
+// after defaulting and the auth set on the HelmRelease itself
+func (c *Controller) chartSourceAuth(h *helmCrdV2.HelmRelease) (string, helmCrdV2.HelmReleaseAuth, helmCrdV2.HelmReleaseAuth) {
+	switch src := h.Spec.ChartLocation(); {
+	case src.Repository != nil:
+		url, auth := c.repoURLAndAuth(src.Repository)
+		return url, auth, src.Repository.Auth
+	case src.Tarball != nil:
+		return src.Tarball.URL, src.Tarball.Auth, src.Tarball.Auth
+	case src.OCI != nil:
+		return src.OCI.URL, src.OCI.Auth, src.OCI.Auth
+	}
+	return "", helmCrdV2.HelmReleaseAuth{}, helmCrdV2.HelmReleaseAuth{}
+}
+
+// credentialHandler queues the HelmReleases whose chart source matches
+// the URL prefixes of the RepositoryCredentials created, changed or
+// deleted, so that they are fetched with the new credentials
+func (c *Controller) credentialHandler() cache.ResourceEventHandlerFuncs {
+	enqueue := func(objs ...interface{}) {
+		var prefixes []string
+		for _, obj := range objs {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if cred, ok := obj.(*helmCrdV2.RepositoryCredential); ok && cred.Namespace == controllerNamespace() {
+				prefixes = append(prefixes, cred.Spec.URLPrefixes...)
+			}
+		}
+		for _, obj := range c.informer.GetStore().List() {
+			h := obj.(*helmCrdV2.HelmRelease)
+			url, _, _ := c.chartSourceAuth(h)
+			for _, prefix := range prefixes {
+				if matchesURLPrefix(url, prefix) {
+					if key, err := cache.MetaNamespaceKeyFunc(h); err == nil {
+						c.queue.Add(key)
+					}
+					break
+				}
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { enqueue(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Periodic resyncs don't change the resource version
+			if oldObj.(metav1.Object).GetResourceVersion() != newObj.(metav1.Object).GetResourceVersion() {
+				enqueue(oldObj, newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { enqueue(obj) },
+	}
+}
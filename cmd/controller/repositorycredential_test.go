@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func credentialAuthHeader(secret string) helmCrdV2.HelmReleaseAuth {
+	return helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+		SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret}, Key: "token"},
+	}}
+}
+
+func TestCredentialAuth(t *testing.T) {
+	controller := prepareTestController(nil, []string{})
+	for _, cred := range []*helmCrdV2.RepositoryCredential{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "example"},
+			Spec:       helmCrdV2.RepositoryCredentialSpec{URLPrefixes: []string{"https://charts.example.com/"}, Auth: credentialAuthHeader("example")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "private"},
+			Spec: helmCrdV2.RepositoryCredentialSpec{
+				URLPrefixes: []string{"https://charts.example.com/private/", "oci://registry.example.com/myorg/"},
+				Auth:        credentialAuthHeader("private"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "other"},
+			Spec:       helmCrdV2.RepositoryCredentialSpec{URLPrefixes: []string{"https://charts.other.com/"}, Auth: credentialAuthHeader("other")},
+		},
+	} {
+		controller.credentialInformer.GetStore().Add(cred)
+	}
+
+	ownAuth := credentialAuthHeader("own")
+	tests := []struct {
+		name              string
+		url               string
+		auth              helmCrdV2.HelmReleaseAuth
+		expectedNamespace string
+		expectedSecret    string
+	}{
+		{"prefix", "https://charts.example.com/stable", helmCrdV2.HelmReleaseAuth{}, controllerNamespace(), "example"},
+		{"longest prefix", "https://charts.example.com/private/foo-1.0.0.tgz", helmCrdV2.HelmReleaseAuth{}, controllerNamespace(), "private"},
+		{"without trailing slash", "https://charts.example.com/private", helmCrdV2.HelmReleaseAuth{}, controllerNamespace(), "private"},
+		{"oci", "oci://registry.example.com/myorg/foo", helmCrdV2.HelmReleaseAuth{}, controllerNamespace(), "private"},
+		{"own auth", "https://charts.example.com/stable", ownAuth, "myns", "own"},
+		{"no match", "https://charts.example.org/stable", helmCrdV2.HelmReleaseAuth{}, "myns", ""},
+		{"path boundary", "https://charts.example.com/private-other/foo-1.0.0.tgz", helmCrdV2.HelmReleaseAuth{}, controllerNamespace(), "example"},
+		{"host suffix", "https://charts.example.com.evil.org/stable", helmCrdV2.HelmReleaseAuth{}, "myns", ""},
+		{"user info", "https://charts.example.com@evil.org/stable", helmCrdV2.HelmReleaseAuth{}, "myns", ""},
+		{"other scheme", "http://charts.example.com/stable", helmCrdV2.HelmReleaseAuth{}, "myns", ""},
+		{"other namespace", "https://charts.other.com/stable", helmCrdV2.HelmReleaseAuth{}, "myns", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, auth := controller.credentialAuth(tt.url, "myns", tt.auth)
+			var secret string
+			if auth.Header != nil {
+				secret = auth.Header.SecretKeyRef.Name
+			}
+			if namespace != tt.expectedNamespace || secret != tt.expectedSecret {
+				t.Errorf("Expecting secret %q of namespace %s, received %q of %s", tt.expectedSecret, tt.expectedNamespace, secret, namespace)
+			}
+		})
+	}
+}
+
+func TestCredentialHandler(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	cred := &helmCrdV2.RepositoryCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "example", ResourceVersion: "1"},
+		Spec:       helmCrdV2.RepositoryCredentialSpec{URLPrefixes: []string{"http://charts.other.com/"}, Auth: credentialAuthHeader("creds")},
+	}
+	controller.credentialInformer.GetStore().Add(cred)
+	controller.credentialHandler().OnAdd(cred)
+	if controller.queue.Len() != 0 {
+		t.Errorf("Expecting HelmReleases of other repositories not to be queued")
+	}
+	if controller.referencesObject(&h, "Secret", controllerNamespace(), "creds") {
+		t.Errorf("Expecting the HelmRelease not to reference the secret of another repository")
+	}
+
+	updated := cred.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Spec.URLPrefixes = []string{"http://charts.example.com/"}
+	controller.credentialInformer.GetStore().Update(updated)
+	controller.credentialHandler().OnUpdate(cred, updated)
+	if controller.queue.Len() != 1 {
+		t.Errorf("Expecting the HelmRelease of the repository to be queued, %d queued", controller.queue.Len())
+	}
+	if !controller.referencesObject(&h, "Secret", controllerNamespace(), "creds") {
+		t.Errorf("Expecting the HelmRelease to reference the secret of its RepositoryCredential")
+	}
+}
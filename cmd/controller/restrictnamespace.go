@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// reasonNamespaceRestricted is the reason of the Ready condition of
+// HelmReleases deploying outside of their namespace with
+// --restrict-to-own-namespace
+const reasonNamespaceRestricted = "NamespaceRestricted"
+
+// checkTargetNamespace returns an error if h targets another namespace
+// than its own with --restrict-to-own-namespace
+func (c *Controller) checkTargetNamespace(h *helmCrdV2.HelmRelease) error {
+	if c.restrictNamespace && h.Spec.TargetNamespace != "" && h.Spec.TargetNamespace != h.Namespace {
+		return fmt.Errorf("spec.targetNamespace %s is not allowed, releases are restricted to namespace %s", h.Spec.TargetNamespace, h.Namespace)
+	}
+	return nil
+}
+
+// namespaceViolations renders the release of h and returns its objects,
+// including its hooks unless they are disabled and the CRDs separated
+// from the chart, that are cluster scoped or in another namespace than
+// namespace
+func (c *Controller) namespaceViolations(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, ch *chart.Chart, namespace string, values []byte, deployed bool, crds []manifest.Object, caps *chartutil.Capabilities) ([]string, error) {
+	rel, err := renderRelease(helmClient, ch, getReleaseName(h), namespace, values, deployed, caps)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := manifest.Objects(rel.GetManifest())
+	if err != nil {
+		return nil, err
+	}
+	if !h.Spec.DisableHooks {
+		for _, hook := range rel.GetHooks() {
+			hookObjs, err := manifest.Objects(hook.GetManifest())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", hook.GetPath(), err)
+			}
+			objs = append(objs, hookObjs...)
+		}
+	}
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		return nil, err
+	}
+	var violations []string
+	for _, obj := range append(crds, objs...) {
+		resource, err := objects.Resource(obj.APIVersion, obj.Kind)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !resource.Namespaced:
+			violations = append(violations, fmt.Sprintf("cluster scoped %s %s", obj.Kind, obj.Name))
+		case obj.Namespace != "" && obj.Namespace != namespace:
+			violations = append(violations, fmt.Sprintf("%s %s in namespace %s", obj.Kind, obj.Name, obj.Namespace))
+		}
+	}
+	return violations, nil
+}
+
+// rejectNamespaceViolations records in the Ready condition of h the
+// objects it may not deploy with --restrict-to-own-namespace
+func (c *Controller) rejectNamespaceViolations(h *helmCrdV2.HelmRelease, violations []string) error {
+	if len(violations) > maxDeniedReported {
+		violations = append(violations[:maxDeniedReported], fmt.Sprintf("%d more", len(violations)-maxDeniedReported))
+	}
+	err := fmt.Errorf("releases are restricted to namespace %s, not allowed: %s", h.Namespace, strings.Join(violations, ", "))
+	return c.rejectRelease(h, reasonNamespaceRestricted, err)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+func TestRestrictNamespace(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			TargetNamespace: "kube-system",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.restrictNamespace = true
+	controller.objects = &fakeObjectClient{}
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting rejected HelmReleases not to be retried, received %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed in another namespace")
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonNamespaceRestricted {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+
+	res.Spec.TargetNamespace = "myns"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be deployed in its namespace, received %v", deployed)
+	}
+}
+
+func TestNamespaceViolations(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}}
+	controller := prepareTestController(nil, []string{})
+	controller.objects = &fakeObjectClient{}
+	crds := []manifest.Object{
+		{APIVersion: "v1", Kind: "Namespace", Name: "other"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "kube-system", Name: "settings"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "myns", Name: "settings"},
+	}
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: "foo"}}
+	violations, err := controller.namespaceViolations(h, fakeHelmClient(controller), ch, "myns", nil, false, crds, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []string{"cluster scoped Namespace other", "ConfigMap settings in namespace kube-system"}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expecting violations %v, received %v", expected, violations)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// maxDeniedReported is the number of denied changes listed in errors
+const maxDeniedReported = 5
+
+// objectChange is a change to an object of a release and the verb
+// authorizing it
+type objectChange struct {
+	verb string
+	obj  manifest.Object
+}
+
+// authorizeRelease renders the release of h and checks that its
+// spec.serviceAccountName is allowed to make the resulting changes to
+// the release objects. Tiller applies releases with its own credentials,
+// so this keeps tenants from deploying what their RBAC does not allow.
+// deployed is the deployed release, nil when installing. Service accounts
+// have no permissions in remote clusters.
+func (c *Controller) authorizeRelease(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, ch *chart.Chart, namespace string, values []byte, deployed *release.Release, caps *chartutil.Capabilities) error {
+	if h.Spec.KubeConfigSecretRef != nil {
+		return fmt.Errorf("spec.serviceAccountName may not be set with spec.kubeConfigSecretRef")
+	}
+	rel, err := renderRelease(helmClient, ch, getReleaseName(h), namespace, values, deployed != nil, caps)
+	if err != nil {
+		return err
+	}
+
+	changes, err := releaseChanges(deployed.GetManifest(), rel, !h.Spec.DisableHooks)
+	if err != nil {
+		return err
+	}
+	var denied []string
+	for _, change := range changes {
+		allowed, err := c.serviceAccountAllowed(h, change, namespace)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			denied = append(denied, fmt.Sprintf("%s %s %s", change.verb, change.obj.Kind, change.obj.Name))
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	if len(denied) > maxDeniedReported {
+		denied = append(denied[:maxDeniedReported], fmt.Sprintf("%d more", len(denied)-maxDeniedReported))
+	}
+	return fmt.Errorf("service account %s is not allowed to %s", h.Spec.ServiceAccountName, strings.Join(denied, ", "))
+}
+
+// releaseChanges returns the changes upgrading from deployedManifest to
+// rel makes, including the creation of its hooks unless they are disabled
+func releaseChanges(deployedManifest string, rel *release.Release, hooks bool) ([]objectChange, error) {
+	added, changed, removed, err := manifest.Changes(deployedManifest, rel.GetManifest())
+	if err != nil {
+		return nil, err
+	}
+	var changes []objectChange
+	for _, obj := range added {
+		changes = append(changes, objectChange{"create", obj})
+	}
+	for _, obj := range changed {
+		changes = append(changes, objectChange{"patch", obj})
+	}
+	for _, obj := range removed {
+		changes = append(changes, objectChange{"delete", obj})
+	}
+	if !hooks {
+		return changes, nil
+	}
+	for _, hook := range rel.GetHooks() {
+		objs, err := manifest.Objects(hook.GetManifest())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			changes = append(changes, objectChange{"create", obj})
+		}
+	}
+	return changes, nil
+}
+
+// serviceAccountAllowed returns whether the service account of h is allowed
+// to make change. Objects without namespace are in namespace.
+func (c *Controller) serviceAccountAllowed(h *helmCrdV2.HelmRelease, change objectChange, namespace string) (bool, error) {
+	gv, err := schema.ParseGroupVersion(change.obj.APIVersion)
+	if err != nil {
+		return false, err
+	}
+	resource, err := c.objects.Resource(change.obj.APIVersion, change.obj.Kind)
+	if err != nil {
+		return false, err
+	}
+	objNamespace := ""
+	if resource.Namespaced {
+		objNamespace = change.obj.Namespace
+		if objNamespace == "" {
+			objNamespace = namespace
+		}
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: objNamespace,
+				Verb:      change.verb,
+				Group:     gv.Group,
+				Version:   gv.Version,
+				Resource:  resource.Name,
+				Name:      change.obj.Name,
+			},
+			User: serviceAccountUserPrefix + h.Namespace + ":" + h.Spec.ServiceAccountName,
+			Groups: []string{
+				serviceAccountsGroup,
+				serviceAccountsGroupPrefix + h.Namespace,
+				authenticatedGroup,
+			},
+		},
+	}
+	review, err = c.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestHelmReleaseServiceAccount(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			ServiceAccountName: "deployer",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.objects = &fakeObjectClient{}
+	var reviews []*authorizationv1.SubjectAccessReview
+	allowed := false
+	controller.kubeClient.(*fake.Clientset).PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = allowed
+		reviews = append(reviews, review)
+		return true, review, nil
+	})
+
+	if err := controller.updateRelease("myns/foo"); err == nil {
+		t.Errorf("Expected an error for a service account not allowed to create the release objects")
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expected the release not to be installed")
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != "Forbidden" {
+		t.Errorf("Expected a Forbidden event received %+v", events.Items)
+	}
+	if len(reviews) != 1 {
+		t.Fatalf("Expected a review of the release Secret received %d", len(reviews))
+	}
+	spec := reviews[0].Spec
+	attrs := spec.ResourceAttributes
+	if spec.User != "system:serviceaccount:myns:deployer" || attrs.Verb != "create" || attrs.Namespace != "myns" || attrs.Resource != "secrets" || attrs.Name != "fixture" {
+		t.Errorf("Unexpected access review %+v %+v", spec, attrs)
+	}
+
+	allowed = true
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expected the release to be installed")
+	}
+}
+
+func TestReleaseChanges(t *testing.T) {
+	deployed := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: old\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  a: b\n"
+	rel := &release.Release{
+		Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  a: c\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: new\n",
+		Hooks:    []*release.Hook{{Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n"}},
+	}
+	changes, err := releaseChanges(deployed, rel, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.verb+" "+change.obj.Kind+"/"+change.obj.Name)
+	}
+	expected := []string{"create Service/new", "patch ConfigMap/config", "delete Secret/old", "create Job/migrate"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected changes %v received %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected changes %v received %v", expected, got)
+			break
+		}
+	}
+
+	changes, err = releaseChanges(deployed, rel, false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected disabled hooks not to be authorized, received %v", changes)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+// Keys of the settings ConfigMap
+const (
+	settingLogLevel       = "logLevel"
+	settingDefaultRepoURL = "defaultRepoURL"
+	settingMaxRetries     = "maxRetries"
+	settingReleaseTimeout = "releaseTimeout"
+	settingWorkers        = "workers"
+)
+
+// runtimeSettings are the settings of the controller that its settings
+// ConfigMap overrides, applied without a restart whenever it changes
+type runtimeSettings struct {
+	logLevel       logging.Level
+	defaultRepoURL string
+	maxRetries     int
+	// releaseTimeout is how long Tiller waits for the installs and
+	// upgrades of HelmReleases not setting spec.timeout, Tiller's
+	// default if zero
+	releaseTimeout time.Duration
+	workers        int
+}
+
+// parseSettings returns defaults overridden by the keys of data set
+func parseSettings(data map[string]string, defaults runtimeSettings) (runtimeSettings, error) {
+	s := defaults
+	var err error
+	if v, ok := data[settingLogLevel]; ok {
+		if s.logLevel, err = logging.ParseLevel(v); err != nil {
+			return s, fmt.Errorf("%s: %v", settingLogLevel, err)
+		}
+	}
+	if v, ok := data[settingDefaultRepoURL]; ok {
+		if v == "" {
+			return s, fmt.Errorf("%s: empty URL", settingDefaultRepoURL)
+		}
+		s.defaultRepoURL = v
+	}
+	if v, ok := data[settingMaxRetries]; ok {
+		if s.maxRetries, err = strconv.Atoi(v); err != nil || s.maxRetries < 0 {
+			return s, fmt.Errorf("%s: expecting a non-negative integer, received %q", settingMaxRetries, v)
+		}
+	}
+	if v, ok := data[settingReleaseTimeout]; ok {
+		if s.releaseTimeout, err = time.ParseDuration(v); err != nil || s.releaseTimeout < 0 {
+			return s, fmt.Errorf("%s: expecting a non-negative duration, received %q", settingReleaseTimeout, v)
+		}
+	}
+	if v, ok := data[settingWorkers]; ok {
+		if s.workers, err = strconv.Atoi(v); err != nil || s.workers < 1 {
+			return s, fmt.Errorf("%s: expecting a positive integer, received %q", settingWorkers, v)
+		}
+	}
+	return s, nil
+}
+
+// applySettings applies s to the running controller. Reconciles in
+// flight keep the settings they started with.
+func (c *Controller) applySettings(s runtimeSettings) {
+	logger.SetLevel(s.logLevel)
+	c.settingsLock.Lock()
+	c.defaultRepoURL = s.defaultRepoURL
+	c.maxRetries = s.maxRetries
+	c.releaseTimeout = s.releaseTimeout
+	c.settingsLock.Unlock()
+	c.workers.resize(s.workers)
+}
+
+// settingsKey returns the key of the settings ConfigMap, "" if the
+// controller has none
+func (c *Controller) settingsKey() string {
+	if c.settingsConfigMap == "" {
+		return ""
+	}
+	return controllerNamespace() + "/" + c.settingsConfigMap
+}
+
+// reloadSettings applies the settings of the settings ConfigMap, or the
+// command line flags if it doesn't exist. Invalid settings are ignored,
+// keeping the current ones.
+func (c *Controller) reloadSettings() {
+	key := c.settingsKey()
+	if key == "" {
+		return
+	}
+	s := c.flagSettings
+	obj, exists, err := c.configMapInformer.GetStore().GetByKey(key)
+	if err != nil {
+		logger.With("configmap", key, "error", err).Warnf("Unable to get settings")
+		return
+	}
+	if exists {
+		if s, err = parseSettings(obj.(*corev1.ConfigMap).Data, c.flagSettings); err != nil {
+			logger.With("configmap", key, "error", err).Errorf("Invalid settings, keeping the current ones")
+			return
+		}
+	}
+	c.applySettings(s)
+	logger.With("configmap", key, "logLevel", s.logLevel, "defaultRepoURL", s.defaultRepoURL, "maxRetries", s.maxRetries,
+		"releaseTimeout", s.releaseTimeout, "workers", s.workers).Infof("Applied settings")
+}
+
+// settingsHandler reloads the settings when the settings ConfigMap is
+// created, changed or deleted
+func (c *Controller) settingsHandler() cache.ResourceEventHandlerFuncs {
+	reload := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err == nil && key != "" && key == c.settingsKey() {
+			c.reloadSettings()
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    reload,
+		UpdateFunc: func(oldObj, newObj interface{}) { reload(newObj) },
+		DeleteFunc: reload,
+	}
+}
+
+// timeout returns the Tiller timeout in seconds of the installs and
+// upgrades of h
+func (c *Controller) timeout(h *helmCrdV2.HelmRelease) int64 {
+	if h.Spec.Timeout > 0 {
+		return h.Spec.Timeout
+	}
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	return int64(c.releaseTimeout / time.Second)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+func TestReloadSettings(t *testing.T) {
+	defer logger.SetLevel(logger.Level())
+	controller := prepareTestController([]helmCrdV2.HelmRelease{}, []string{})
+	controller.flagSettings = runtimeSettings{
+		logLevel:       logging.Info,
+		defaultRepoURL: defaultRepoURL,
+		maxRetries:     defaultMaxRetries,
+		workers:        defaultWorkers,
+	}
+	controller.settingsConfigMap = "helm-crd-settings"
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: controllerNamespace(), Name: "helm-crd-settings"},
+		Data: map[string]string{
+			settingLogLevel:       "debug",
+			settingDefaultRepoURL: "https://charts.example.com/stable",
+			settingMaxRetries:     "10",
+			settingReleaseTimeout: "10m",
+			settingWorkers:        "4",
+		},
+	}
+	store := controller.configMapInformer.GetStore()
+	store.Add(cm)
+	controller.reloadSettings()
+	h := &helmCrdV2.HelmRelease{}
+	if logger.Level() != logging.Debug || controller.retries("myns/foo") != 10 || controller.timeout(h) != 600 || controller.workers.size != 4 {
+		t.Errorf("Unexpected settings, level %v, retries %d, timeout %d, workers %d", logger.Level(), controller.retries("myns/foo"), controller.timeout(h), controller.workers.size)
+	}
+	if url, _ := controller.repoURLAndAuth(&helmCrdV2.RepositoryChartSource{}); url != "https://charts.example.com/stable" {
+		t.Errorf("Unexpected default repo URL %q", url)
+	}
+	h.Spec.Timeout = 60
+	if timeout := controller.timeout(h); timeout != 60 {
+		t.Errorf("Expecting spec.timeout to override the release timeout, received %d", timeout)
+	}
+
+	// Invalid settings are ignored
+	cm = cm.DeepCopy()
+	cm.Data[settingLogLevel] = "error"
+	cm.Data[settingWorkers] = "0"
+	store.Update(cm)
+	controller.reloadSettings()
+	if logger.Level() != logging.Debug || controller.workers.size != 4 {
+		t.Errorf("Expecting invalid settings to be ignored, received level %v, workers %d", logger.Level(), controller.workers.size)
+	}
+
+	// Deleting the ConfigMap reverts to the flags
+	store.Delete(cm)
+	controller.reloadSettings()
+	if logger.Level() != logging.Info || controller.retries("myns/foo") != defaultMaxRetries || controller.timeout(&helmCrdV2.HelmRelease{}) != 0 || controller.workers.size != defaultWorkers {
+		t.Errorf("Expecting the flag settings, received level %v, retries %d, workers %d", logger.Level(), controller.retries("myns/foo"), controller.workers.size)
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	defaults := runtimeSettings{logLevel: logging.Info, defaultRepoURL: defaultRepoURL, maxRetries: 5, workers: 1}
+	s, err := parseSettings(map[string]string{settingMaxRetries: "0", settingReleaseTimeout: "90s"}, defaults)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := defaults
+	expected.maxRetries = 0
+	expected.releaseTimeout = 90 * time.Second
+	if s != expected {
+		t.Errorf("Expecting %+v, received %+v", expected, s)
+	}
+	for _, data := range []map[string]string{
+		{settingLogLevel: "verbose"},
+		{settingDefaultRepoURL: ""},
+		{settingMaxRetries: "-1"},
+		{settingReleaseTimeout: "5"},
+		{settingWorkers: "many"},
+	} {
+		if _, err := parseSettings(data, defaults); err == nil {
+			t.Errorf("Expecting an error parsing %v", data)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// Keys HelmReleases are partitioned by
+const (
+	shardByNamespace = "namespace"
+	shardByName      = "name"
+)
+
+// shard is the partition of the HelmReleases, and HelmReleaseSets,
+// reconciled by a controller instance out of count instances. The zero
+// shard reconciles everything.
+type shard struct {
+	index int
+	count int
+	// byName partitions by namespace/name rather than by namespace, which
+	// spreads large namespaces over all instances
+	byName bool
+}
+
+// parseShard parses the --shard flag, i/N for the ith instance out of N,
+// counted from 0
+func parseShard(s, key string) (shard, error) {
+	var sh shard
+	switch key {
+	case shardByNamespace:
+	case shardByName:
+		sh.byName = true
+	default:
+		return sh, fmt.Errorf("unknown shard key %q, expecting %s or %s", key, shardByNamespace, shardByName)
+	}
+	if s == "" {
+		return sh, nil
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return sh, fmt.Errorf("invalid shard %q, expecting <index>/<count>", s)
+	}
+	var err error
+	if sh.index, err = strconv.Atoi(parts[0]); err != nil {
+		return sh, fmt.Errorf("invalid shard index %q", parts[0])
+	}
+	if sh.count, err = strconv.Atoi(parts[1]); err != nil || sh.count < 1 {
+		return sh, fmt.Errorf("invalid shard count %q", parts[1])
+	}
+	if sh.index < 0 || sh.index >= sh.count {
+		return sh, fmt.Errorf("shard index %d out of range, expecting 0 to %d", sh.index, sh.count-1)
+	}
+	return sh, nil
+}
+
+// owns returns whether the object with key is reconciled by s. Every
+// object is owned by exactly one of the shards of a count.
+func (s shard) owns(key string) bool {
+	if s.count <= 1 {
+		return true
+	}
+	if !s.byName {
+		key, _, _ = cache.SplitMetaNamespaceKey(key)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32()%uint32(s.count)) == s.index
+}
+
+// first returns whether s is the first shard, running the cluster wide
+// tasks such as garbage collection
+func (s shard) first() bool {
+	return s.index == 0
+}
+
+func (s shard) String() string {
+	if s.count <= 1 {
+		return "all"
+	}
+	return fmt.Sprintf("%d/%d", s.index, s.count)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		flag, key string
+		expected  shard
+		valid     bool
+	}{
+		{"", shardByNamespace, shard{}, true},
+		{"0/3", shardByNamespace, shard{index: 0, count: 3}, true},
+		{"2/3", shardByName, shard{index: 2, count: 3, byName: true}, true},
+		{"3/3", shardByNamespace, shard{}, false},
+		{"-1/3", shardByNamespace, shard{}, false},
+		{"1/0", shardByNamespace, shard{}, false},
+		{"1", shardByNamespace, shard{}, false},
+		{"a/3", shardByNamespace, shard{}, false},
+		{"0/3", "label", shard{}, false},
+	}
+	for _, tt := range tests {
+		s, err := parseShard(tt.flag, tt.key)
+		if (err == nil) != tt.valid {
+			t.Errorf("Unexpected error of %q %q: %v", tt.flag, tt.key, err)
+		}
+		if err == nil && s != tt.expected {
+			t.Errorf("Expecting %q to be %+v, received %+v", tt.flag, tt.expected, s)
+		}
+	}
+}
+
+func TestShardOwns(t *testing.T) {
+	for _, byName := range []bool{false, true} {
+		owners := map[string]int{}
+		namespaceShards := map[string]map[int]bool{}
+		for i := 0; i < 3; i++ {
+			s := shard{index: i, count: 3, byName: byName}
+			for n := 0; n < 100; n++ {
+				ns := fmt.Sprintf("ns%d", n%10)
+				key := fmt.Sprintf("%s/release%d", ns, n)
+				if s.owns(key) {
+					owners[key]++
+					if namespaceShards[ns] == nil {
+						namespaceShards[ns] = map[int]bool{}
+					}
+					namespaceShards[ns][i] = true
+				}
+			}
+		}
+		if len(owners) != 100 {
+			t.Errorf("Expecting every release to be owned, %d are", len(owners))
+		}
+		for key, n := range owners {
+			if n != 1 {
+				t.Errorf("Expecting %s to be owned by a single shard, owned by %d", key, n)
+			}
+		}
+		for ns, shards := range namespaceShards {
+			if !byName && len(shards) != 1 {
+				t.Errorf("Expecting the releases of %s to be in the same shard, in %v", ns, shards)
+			}
+		}
+	}
+}
+
+func TestShardedController(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+		controller.shard = shard{index: i, count: 2}
+		controller.queue.Add("myns/foo")
+		controller.processNextItem()
+		deployed := len(fakeHelmClient(controller).Deployed()) == 1
+		if owns := controller.shard.owns("myns/foo"); deployed != owns {
+			t.Errorf("Expecting shard %s to deploy the release: %v, deployed %v", controller.shard, owns, deployed)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// reasonShutdown is the reason of the Interrupted condition
+const reasonShutdown = "ControllerShutdown"
+
+// inFlightOperations are the installs, upgrades and deletions the workers
+// of the controller are running, by HelmRelease key, recorded as
+// interrupted if they do not finish on shutdown
+type inFlightOperations struct {
+	mu     sync.Mutex
+	phases map[string]helmCrdV2.HelmReleasePhase
+}
+
+func (o *inFlightOperations) set(h *helmCrdV2.HelmRelease, phase helmCrdV2.HelmReleasePhase) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.phases == nil {
+		o.phases = map[string]helmCrdV2.HelmReleasePhase{}
+	}
+	o.phases[h.Namespace+"/"+h.Name] = phase
+}
+
+func (o *inFlightOperations) clear(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.phases, key)
+}
+
+func (o *inFlightOperations) get() map[string]helmCrdV2.HelmReleasePhase {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	phases := make(map[string]helmCrdV2.HelmReleasePhase, len(o.phases))
+	for key, phase := range o.phases {
+		phases[key] = phase
+	}
+	return phases
+}
+
+// recordInterrupted sets the Interrupted condition of the HelmReleases
+// whose operation is still running when the shutdown grace period
+// expires, so that the next controller resumes them
+func (c *Controller) recordInterrupted() error {
+	var errs []string
+	for key, phase := range c.inFlight.get() {
+		if err := c.recordInterruptedOperation(key, phase); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *Controller) recordInterruptedOperation(key string, phase helmCrdV2.HelmReleasePhase) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	h, err := c.helmReleaseClient.HelmV2().HelmReleases(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	status := h.Status
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseInterrupted,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonShutdown,
+		Message: fmt.Sprintf("%s interrupted by controller shutdown", strings.ToLower(string(phase))),
+	})
+	_, err = c.updateStatus(h, status)
+	return err
+}
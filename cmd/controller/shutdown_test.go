@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestShutdown(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	get := func() *helmCrdV2.HelmRelease {
+		res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return res
+	}
+
+	// An upgrade still running when the grace period expires
+	controller.inFlight.set(&h, helmCrdV2.PhaseUpgrading)
+	if err := controller.recordInterrupted(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cond := getCondition(&get().Status, helmCrdV2.HelmReleaseInterrupted)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != reasonShutdown || cond.Message != "upgrading interrupted by controller shutdown" {
+		t.Errorf("Unexpected Interrupted condition %+v", cond)
+	}
+
+	// Queued items are left to the next controller
+	controller.queue.Add("myns/foo")
+	controller.queue.ShutDown()
+	if controller.processNextItem() {
+		t.Errorf("Expecting no item to be processed once shutting down")
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+
+	// The next controller resumes the release
+	controller.inFlight.clear("myns/foo")
+	controller.informer.GetIndexer().Update(get())
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cond := getCondition(&get().Status, helmCrdV2.HelmReleaseInterrupted); cond != nil {
+		t.Errorf("Expecting the Interrupted condition to be removed, received %+v", cond)
+	}
+	if ops := controller.inFlight.get(); len(ops) != 0 {
+		t.Errorf("Unexpected operations in flight %v", ops)
+	}
+}
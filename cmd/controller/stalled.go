@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// reasonRetriesExhausted is the reason of the Stalled condition and Event
+// set when giving up on a HelmRelease
+const reasonRetriesExhausted = "RetriesExhausted"
+
+// stalledSpecs remembers the spec of the HelmReleases the controller gave
+// up on, to clear their Stalled condition once their spec changes
+type stalledSpecs struct {
+	mu    sync.Mutex
+	specs map[string]helmCrdV2.HelmReleaseSpec
+}
+
+func (s *stalledSpecs) set(key string, spec helmCrdV2.HelmReleaseSpec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.specs == nil {
+		s.specs = map[string]helmCrdV2.HelmReleaseSpec{}
+	}
+	s.specs[key] = spec
+}
+
+// changed returns true if the spec differs from the one stalled, or if it
+// is unknown, e.g. because the controller restarted since
+func (s *stalledSpecs) changed(key string, spec helmCrdV2.HelmReleaseSpec) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stalled, ok := s.specs[key]
+	return !ok || !apiequality.Semantic.DeepEqual(stalled, spec)
+}
+
+func (s *stalledSpecs) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.specs, key)
+}
+
+// markStalled records that the controller gave up on the HelmRelease with
+// key after err, in the Stalled and Ready conditions and a Warning Event
+func (c *Controller) markStalled(key string, err error) {
+	obj, exists, getErr := c.informer.GetIndexer().GetByKey(key)
+	if getErr != nil || !exists {
+		return
+	}
+	helmObj := obj.(*helmCrdV2.HelmRelease)
+	c.stalled.set(key, *helmObj.Spec.DeepCopy())
+
+	status := helmObj.Status
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseStalled,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonRetriesExhausted,
+		Message: err.Error(),
+	})
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reasonRetriesExhausted,
+		Message: err.Error(),
+	})
+	setFailed(&status, errorReason(err), err)
+	if _, err := c.updateStatus(helmObj, status); err != nil {
+		logger.With("helmrelease", key, "error", err).Warnf("Unable to set Stalled condition")
+	}
+	c.recordEvent(helmObj, corev1.EventTypeWarning, reasonRetriesExhausted, "Giving up after too many retries: "+err.Error())
+}
+
+// clearStalled removes the Stalled condition of a HelmRelease whose spec
+// changed since the controller gave up on it, or that is forced to sync,
+// returning the updated object
+func (c *Controller) clearStalled(key string, helmObj *helmCrdV2.HelmRelease) (*helmCrdV2.HelmRelease, error) {
+	if getCondition(&helmObj.Status, helmCrdV2.HelmReleaseStalled) == nil {
+		return helmObj, nil
+	}
+	forceSync := helmObj.Annotations[helmCrdV2.ForceSyncAnnotation] != helmObj.Status.LastForceSync
+	if !forceSync && !c.stalled.changed(key, helmObj.Spec) {
+		return helmObj, nil
+	}
+	c.stalled.remove(key)
+	status := helmObj.Status
+	removeCondition(&status, helmCrdV2.HelmReleaseStalled)
+	return c.updateStatus(helmObj, status)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestMarkStalled(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+
+	controller.markStalled("myns/foo", fmt.Errorf("chart download request failed"))
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseStalled)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != reasonRetriesExhausted || cond.Message != "chart download request failed" {
+		t.Errorf("Unexpected Stalled condition %+v", cond)
+	}
+	events, err := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Type != corev1.EventTypeWarning || events.Items[0].InvolvedObject.Name != "foo" {
+		t.Errorf("Unexpected events %+v", events.Items)
+	}
+
+	// The condition is kept until the spec changes
+	controller.informer.GetIndexer().Update(res)
+	if res, err = controller.clearStalled("myns/foo", res); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if getCondition(&res.Status, helmCrdV2.HelmReleaseStalled) == nil {
+		t.Errorf("Expected the Stalled condition to be kept for an unchanged spec")
+	}
+	changed := res.DeepCopy()
+	changed.Spec.Chart.Repository.Version = "1.0.1"
+	if res, err = controller.clearStalled("myns/foo", changed); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if getCondition(&res.Status, helmCrdV2.HelmReleaseStalled) != nil {
+		t.Errorf("Expected the Stalled condition to be cleared for a changed spec")
+	}
+
+	// Or until it is forced to sync
+	controller.informer.GetIndexer().Update(res)
+	controller.markStalled("myns/foo", fmt.Errorf("chart download request failed"))
+	if res, err = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res, err = controller.clearStalled("myns/foo", res); err != nil || getCondition(&res.Status, helmCrdV2.HelmReleaseStalled) == nil {
+		t.Fatalf("Expected the Stalled condition to be kept for an unchanged spec, received %v", err)
+	}
+	res.Annotations = map[string]string{helmCrdV2.ForceSyncAnnotation: "2018-06-01T00:00:00Z"}
+	if res, err = controller.clearStalled("myns/foo", res); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if getCondition(&res.Status, helmCrdV2.HelmReleaseStalled) != nil {
+		t.Errorf("Expected the Stalled condition to be cleared when forced to sync")
+	}
+}
+
+func TestForceSync(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "myns",
+			Name:        "foo",
+			Annotations: map[string]string{helmCrdV2.ForceSyncAnnotation: "2018-06-01T00:00:00Z"},
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, err := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res.Status.LastForceSync != "2018-06-01T00:00:00Z" {
+		t.Errorf("Expecting the force-sync annotation to be recorded as handled, received %q", res.Status.LastForceSync)
+	}
+
+	// The chart is downloaded and upgraded again for an unchanged spec
+	synced := res.DeepCopy()
+	synced.Annotations[helmCrdV2.ForceSyncAnnotation] = "2018-06-02T00:00:00Z"
+	if !releaseObjChanged(res, synced) {
+		t.Errorf("Expecting a changed force-sync annotation to trigger a reconcile")
+	}
+	controller.informer.GetIndexer().Update(synced)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res, err = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res.Status.Revision != 2 || res.Status.LastForceSync != "2018-06-02T00:00:00Z" {
+		t.Errorf("Expecting revision 2 forced to sync, received %+v", res.Status)
+	}
+}
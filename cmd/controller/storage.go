@@ -0,0 +1,92 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// revisionStorage reads and relabels the objects release revisions are
+// stored in: ConfigMaps for Tiller, Secrets for the apply executor
+type revisionStorage interface {
+	Get(namespace, name string) (*metav1.ObjectMeta, error)
+	List(namespace string, opts metav1.ListOptions) ([]metav1.ObjectMeta, error)
+	// SetLabels replaces the labels of a stored revision
+	SetLabels(namespace, name string, labels map[string]string) error
+	Delete(namespace, name string) error
+}
+
+type configMapStorage struct {
+	kubeClient kubernetes.Interface
+}
+
+func (s configMapStorage) Get(namespace, name string) (*metav1.ObjectMeta, error) {
+	cm, err := s.kubeClient.Core().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &cm.ObjectMeta, nil
+}
+
+func (s configMapStorage) List(namespace string, opts metav1.ListOptions) ([]metav1.ObjectMeta, error) {
+	cms, err := s.kubeClient.Core().ConfigMaps(namespace).List(opts)
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]metav1.ObjectMeta, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		metas = append(metas, cm.ObjectMeta)
+	}
+	return metas, nil
+}
+
+func (s configMapStorage) SetLabels(namespace, name string, labels map[string]string) error {
+	cm, err := s.kubeClient.Core().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	cm.Labels = labels
+	_, err = s.kubeClient.Core().ConfigMaps(namespace).Update(cm)
+	return err
+}
+
+func (s configMapStorage) Delete(namespace, name string) error {
+	return s.kubeClient.Core().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+type secretStorage struct {
+	kubeClient kubernetes.Interface
+}
+
+func (s secretStorage) Get(namespace, name string) (*metav1.ObjectMeta, error) {
+	secret, err := s.kubeClient.Core().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &secret.ObjectMeta, nil
+}
+
+func (s secretStorage) List(namespace string, opts metav1.ListOptions) ([]metav1.ObjectMeta, error) {
+	secrets, err := s.kubeClient.Core().Secrets(namespace).List(opts)
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]metav1.ObjectMeta, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		metas = append(metas, secret.ObjectMeta)
+	}
+	return metas, nil
+}
+
+func (s secretStorage) SetLabels(namespace, name string, labels map[string]string) error {
+	secret, err := s.kubeClient.Core().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secret.Labels = labels
+	_, err = s.kubeClient.Core().Secrets(namespace).Update(secret)
+	return err
+}
+
+func (s secretStorage) Delete(namespace, name string) error {
+	return s.kubeClient.Core().Secrets(namespace).Delete(name, &metav1.DeleteOptions{})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+// reconcileSuspended leaves the release of a suspended HelmRelease alone,
+// except for rolling it back to the revision requested by its
+// RollbackRevisionAnnotation. The annotation is removed once done.
+func (c *Controller) reconcileSuspended(helmObj *helmCrdV2.HelmRelease, rlog *logging.Logger) error {
+	value, ok := helmObj.Annotations[helmCrdV2.RollbackRevisionAnnotation]
+	if !ok {
+		rlog.Debugf("Release is suspended")
+		return nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || revision < 0 {
+		return fmt.Errorf("invalid %s annotation %q", helmCrdV2.RollbackRevisionAnnotation, value)
+	}
+	rlsName := getReleaseName(helmObj)
+	if c.dryRun {
+		rlog.Infof("Dry-run: would roll back release to revision %d", revision)
+		return nil
+	}
+
+	rlog.Infof("Rolling back release to revision %d", revision)
+	opts := helmclient.RollbackOptions{Version: int32(revision), DisableHooks: helmObj.Spec.DisableHooks}
+	if rb := helmObj.Spec.Rollback; rb != nil {
+		opts.Recreate, opts.Force = rb.Recreate, rb.Force
+	}
+	helmClient, err := c.helmClientFor(helmObj)
+	if err != nil {
+		return err
+	}
+	rel, err := helmClient.Rollback(rlsName, opts)
+	if err != nil {
+		c.recordEvent(helmObj, corev1.EventTypeWarning, "RollbackFailed", err.Error())
+		return err
+	}
+	c.recordEvent(helmObj, corev1.EventTypeNormal, "RolledBack", fmt.Sprintf("Rolled back release %s to revision %d", rlsName, revision))
+	if rel != nil {
+		if err := c.labelReleaseStorage(helmObj, rel); err != nil {
+			rlog.With("error", err).Warnf("Unable to label Tiller release storage")
+		}
+		status := helmObj.Status
+		setDeployedStatus(&status, rel)
+		if helmObj, err = c.updateStatus(helmObj, status); err != nil {
+			return err
+		}
+	}
+
+	helmObjCopy := helmObj.DeepCopy()
+	delete(helmObjCopy.Annotations, helmCrdV2.RollbackRevisionAnnotation)
+	_, err = updateHelmRelease(c.helmReleaseClient, helmObjCopy)
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestHelmReleaseSuspended(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", Finalizers: []string{releaseFinalizer}},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			Suspend: true,
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	rels := fakeHelmClient(controller).Releases
+	if len(rels) != 0 {
+		t.Errorf("Expected suspended releases not to be installed")
+	}
+
+	fakeHelmClient(controller).Releases = []*release.Release{
+		helmclient.MockRelease("myns-foo", "myns", 1, &chart.Chart{}, release.Status_SUPERSEDED),
+		helmclient.MockRelease("myns-foo", "myns", 2, &chart.Chart{}, release.Status_DEPLOYED),
+	}
+	h.Annotations = map[string]string{helmCrdV2.RollbackRevisionAnnotation: "1"}
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(&h)
+	controller.informer.GetIndexer().Update(&h)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != "RolledBack" {
+		t.Errorf("Expected a RolledBack event received %+v", events.Items)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get("foo", metav1.GetOptions{})
+	if _, ok := res.Annotations[helmCrdV2.RollbackRevisionAnnotation]; ok {
+		t.Errorf("Expected the rollback annotation to be removed")
+	}
+	if res.Status.Revision != 3 {
+		t.Errorf("Expected the rollback to deploy revision 3 received %d", res.Status.Revision)
+	}
+
+	h.Annotations[helmCrdV2.RollbackRevisionAnnotation] = "previous"
+	controller.informer.GetIndexer().Update(&h)
+	if err := controller.updateRelease("myns/foo"); err == nil {
+		t.Errorf("Expected an error for an invalid revision")
+	}
+}
+
+func TestReleaseObjChangedAnnotations(t *testing.T) {
+	old := &helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}}
+	new := old.DeepCopy()
+	new.Annotations["foo"] = "baz"
+	if releaseObjChanged(old, new) {
+		t.Errorf("Expected other annotations to be ignored")
+	}
+	new.Annotations[helmCrdV2.ForceSyncAnnotation] = "2018-05-01T10:00:00Z"
+	if !releaseObjChanged(old, new) {
+		t.Errorf("Expected a force-sync annotation change to trigger a reconcile")
+	}
+}
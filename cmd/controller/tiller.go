@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/helm/pkg/helm"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+// tillerServiceHost is the address of the Tiller service installed by
+// helm init in a namespace
+const tillerServiceHost = "tiller-deploy.%s:44134"
+
+// reasonTillerUnavailable is the reason of the failures of reconciles
+// that couldn't reach Tiller, which are retried until it is back
+const reasonTillerUnavailable = "TillerUnavailable"
+
+// reasonTillerNotAllowed is the reason of the Ready condition of
+// HelmReleases selecting a Tiller they may not use
+const reasonTillerNotAllowed = "TillerNotAllowed"
+
+// defaultTillerOptions configure the connections to Tiller unless
+// overridden by flags
+var defaultTillerOptions = helmclient.TillerOptions{
+	Keepalive:       30 * time.Second,
+	BackoffMaxDelay: time.Minute,
+	HealthTimeout:   5 * time.Second,
+}
+
+// dialTiller returns a client of the Tiller at host, sharing a connection
+// between calls
+func (c *Controller) dialTiller(host string) helmclient.Interface {
+	client, err := helmclient.DialTiller(host, c.tillerOptions)
+	if err != nil {
+		// Dialing doesn't block, so this is unexpected: fall back to
+		// connecting for every call
+		logger.With("tillerHost", host, "error", err).Warnf("Unable to dial tiller")
+		return helmclient.NewTillerClient(helm.NewClient(helm.Host(host)))
+	}
+	return client
+}
+
+// helmClientFor returns the client of the Tiller managing the release of
+// h: spec.tillerHost, the Tiller service of spec.tillerNamespace, or the
+// default Tiller. Without Tiller, releases of remote clusters are applied
+// by a client of that cluster. The operations of the client wait for the
+// --max-concurrent-tiller-ops limits and are recorded in the audit log.
+func (c *Controller) helmClientFor(h *helmCrdV2.HelmRelease) (helmclient.Interface, error) {
+	client, err := c.tillerClientFor(h)
+	if err != nil {
+		return nil, err
+	}
+	return c.audited(h.Namespace, h.Name, client), nil
+}
+
+func (c *Controller) tillerClientFor(h *helmCrdV2.HelmRelease) (helmclient.Interface, error) {
+	cluster, err := c.remoteClusterFor(h)
+	if err != nil {
+		return nil, err
+	}
+	if c.tillerless {
+		if cluster != nil {
+			return c.limitTiller(fmt.Sprintf("%x", cluster.hash), cluster.helmClient), nil
+		}
+		return c.limitTiller("", c.helmClient), nil
+	}
+	host := h.Spec.TillerHost
+	if cluster != nil && host == "" {
+		return nil, failed(reasonRemoteClusterFailed, fmt.Errorf("spec.tillerHost must be the address of the Tiller of the remote cluster unless the controller runs with --executor=apply"))
+	}
+	if host == "" && h.Spec.TillerNamespace != "" {
+		host = fmt.Sprintf(tillerServiceHost, h.Spec.TillerNamespace)
+	}
+	if host == "" {
+		return c.limitTiller("", c.helmClient), nil
+	}
+
+	c.tillerClientsLock.Lock()
+	client, ok := c.tillerClients[host]
+	if !ok {
+		client = c.newTillerClient(host)
+		c.tillerClients[host] = client
+	}
+	c.tillerClientsLock.Unlock()
+	return c.limitTiller(host, client), nil
+}
+
+// limitTiller returns client, whose operations wait for the global
+// --max-concurrent-tiller-ops limit and the per host one of the Tiller at
+// host, "" being the default Tiller or cluster
+func (c *Controller) limitTiller(host string, client helmclient.Interface) helmclient.Interface {
+	if c.tillerLimit == nil && c.maxTillerOpsPerHost <= 0 {
+		return client
+	}
+	c.tillerClientsLock.Lock()
+	defer c.tillerClientsLock.Unlock()
+	hostLimit, ok := c.tillerHostLimits[host]
+	if !ok {
+		hostLimit = helmclient.NewSemaphore(c.maxTillerOpsPerHost)
+		c.tillerHostLimits[host] = hostLimit
+	}
+	return helmclient.Limit(client, c.tillerLimit, hostLimit)
+}
+
+// checkTiller returns an error if h selects a Tiller other than the
+// default one that isn't listed in --allowed-tiller-namespaces or
+// --allowed-tiller-hosts, or at all with --restrict-to-own-namespace, so
+// that HelmReleases can't deploy with the permissions of another Tiller.
+func (c *Controller) checkTiller(h *helmCrdV2.HelmRelease) error {
+	if c.tillerless || (h.Spec.TillerNamespace == "" && h.Spec.TillerHost == "") {
+		return nil
+	}
+	if c.restrictNamespace {
+		return fmt.Errorf("spec.tillerNamespace and spec.tillerHost are not allowed, releases are restricted to namespace %s", h.Namespace)
+	}
+	if ns := h.Spec.TillerNamespace; ns != "" && findIndex(ns, c.allowedTillerNamespaces) == -1 {
+		return fmt.Errorf("spec.tillerNamespace %s is not allowed, see --allowed-tiller-namespaces", ns)
+	}
+	if host := h.Spec.TillerHost; host != "" && findIndex(host, c.allowedTillerHosts) == -1 {
+		return fmt.Errorf("spec.tillerHost %s is not allowed, see --allowed-tiller-hosts", host)
+	}
+	return nil
+}
+
+// releaseTillerNamespace returns the namespace where the Tiller of h
+// stores its release
+func (c *Controller) releaseTillerNamespace(h *helmCrdV2.HelmRelease) string {
+	if h.Spec.TillerNamespace != "" && !c.tillerless {
+		return h.Spec.TillerNamespace
+	}
+	return c.tillerNamespace
+}
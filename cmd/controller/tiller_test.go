@@ -0,0 +1,170 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+)
+
+func TestHelmClientFor(t *testing.T) {
+	controller := prepareTestController(nil, []string{})
+	var hosts []string
+	controller.newTillerClient = func(host string) helmclient.Interface {
+		hosts = append(hosts, host)
+		return helmclient.NewFakeClient()
+	}
+
+	if c, _ := controller.helmClientFor(&helmCrdV2.HelmRelease{}); c != controller.helmClient {
+		t.Errorf("Expecting the default Tiller client")
+	}
+	teamA := &helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{TillerNamespace: "team-a"}}
+	first, _ := controller.helmClientFor(teamA)
+	if c, _ := controller.helmClientFor(teamA); c == controller.helmClient || c != first {
+		t.Errorf("Expecting a cached client of the team-a Tiller")
+	}
+	controller.helmClientFor(&helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{TillerHost: "tiller.example.com:44134", TillerNamespace: "team-b"}})
+	if len(hosts) != 2 || hosts[0] != "tiller-deploy.team-a:44134" || hosts[1] != "tiller.example.com:44134" {
+		t.Errorf("Unexpected Tiller hosts %v", hosts)
+	}
+}
+
+func TestTillerLimits(t *testing.T) {
+	controller := prepareTestController(nil, []string{"foo"})
+	controller.newTillerClient = func(host string) helmclient.Interface {
+		return helmclient.NewFakeClient("foo")
+	}
+	controller.tillerLimit = helmclient.NewSemaphore(2)
+	controller.maxTillerOpsPerHost = 1
+
+	local, _ := controller.helmClientFor(&helmCrdV2.HelmRelease{})
+	teamA, _ := controller.helmClientFor(&helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{TillerNamespace: "team-a"}})
+	// An operation of the default Tiller is running
+	controller.tillerHostLimits[""] <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		local.Status("foo")
+		close(done)
+	}()
+	if _, err := teamA.Status("foo"); err != nil {
+		t.Errorf("Expecting the operations of other Tillers to run, received %v", err)
+	}
+	select {
+	case <-done:
+		t.Fatalf("Expecting the operation to wait for the default Tiller")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-controller.tillerHostLimits[""]
+	<-done
+}
+
+func TestHelmReleaseTillerNamespace(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			TillerNamespace: "team-a",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.allowedTillerNamespaces = []string{"team-a"}
+	teamA := helmclient.NewFakeClient()
+	controller.newTillerClient = func(host string) helmclient.Interface { return teamA }
+	cm := tillerConfigMap("myns-foo", "1", nil)
+	cm.Namespace = "team-a"
+	controller.kubeClient.Core().ConfigMaps("team-a").Create(cm)
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(teamA.Releases) != 1 || len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release to be installed by the team-a Tiller only")
+	}
+	cm, err := controller.kubeClient.Core().ConfigMaps("team-a").Get("myns-foo.v1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cm.Labels[managedNameLabel] != "foo" {
+		t.Errorf("Expecting the team-a release storage to be labeled, received %v", cm.Labels)
+	}
+}
+
+func TestTillerless(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			TillerNamespace: "team-a",
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.tillerless = true
+	controller.storage = secretStorage{kubeClient: controller.kubeClient}
+	controller.newTillerClient = func(host string) helmclient.Interface {
+		t.Fatalf("Unexpected Tiller client of %s", host)
+		return nil
+	}
+	cm := tillerConfigMap("myns-foo", "1", nil)
+	controller.kubeClient.Core().Secrets("kube-system").Create(&corev1.Secret{ObjectMeta: cm.ObjectMeta})
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(fakeHelmClient(controller).Releases) != 1 {
+		t.Errorf("Expecting spec.tillerNamespace to be ignored")
+	}
+	secret, err := controller.kubeClient.Core().Secrets("kube-system").Get("myns-foo.v1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if secret.Labels[managedNameLabel] != "foo" {
+		t.Errorf("Expecting the release Secret to be labeled, received %v", secret.Labels)
+	}
+
+	if err := controller.deleteReleaseStorage(&h); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := controller.kubeClient.Core().Secrets("kube-system").Get("myns-foo.v1", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expecting the release Secret to be deleted")
+	}
+}
+
+func TestCheckTiller(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     helmCrdV2.HelmReleaseSpec
+		restrict bool
+		allowed  bool
+	}{
+		{"default Tiller", helmCrdV2.HelmReleaseSpec{}, true, true},
+		{"allowed namespace", helmCrdV2.HelmReleaseSpec{TillerNamespace: "team-a"}, false, true},
+		{"other namespace", helmCrdV2.HelmReleaseSpec{TillerNamespace: "kube-system"}, false, false},
+		{"allowed host", helmCrdV2.HelmReleaseSpec{TillerHost: "tiller.team-a.svc:44134"}, false, true},
+		{"other host", helmCrdV2.HelmReleaseSpec{TillerHost: "tiller-deploy.kube-system:44134"}, false, false},
+		{"allowed namespace, other host", helmCrdV2.HelmReleaseSpec{TillerNamespace: "team-a", TillerHost: "tiller-deploy.kube-system:44134"}, false, false},
+		{"restricted", helmCrdV2.HelmReleaseSpec{TillerNamespace: "team-a"}, true, false},
+	}
+	for _, tt := range tests {
+		controller := &Controller{
+			restrictNamespace:       tt.restrict,
+			allowedTillerNamespaces: []string{"team-a"},
+			allowedTillerHosts:      []string{"tiller.team-a.svc:44134"},
+		}
+		h := &helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}, Spec: tt.spec}
+		if err := controller.checkTiller(h); (err == nil) != tt.allowed {
+			t.Errorf("%s: expected allowed %v, received error %v", tt.name, tt.allowed, err)
+		}
+	}
+}
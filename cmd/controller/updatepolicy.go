@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// reasonChartUpdated is the reason of the Events of chart versions
+// updated by update policies
+const reasonChartUpdated = "ChartUpdated"
+
+// updatePolicyEnabled returns whether h is updated to newer chart versions
+func updatePolicyEnabled(h *helmCrdV2.HelmRelease) bool {
+	return h.Spec.Chart.Repository != nil && h.Spec.UpdatePolicy != "" && h.Spec.UpdatePolicy != helmCrdV2.UpdatePolicyNone
+}
+
+// recordChartUpdate records in status and an Event that the update
+// policy of h updated its chart from previous to version
+func (c *Controller) recordChartUpdate(h *helmCrdV2.HelmRelease, status *helmCrdV2.HelmReleaseStatus, previous, version string) {
+	status.LastChartUpdate = &helmCrdV2.ChartUpdate{
+		PreviousVersion: previous,
+		Version:         version,
+		Time:            metav1.NewTime(c.now()),
+	}
+	c.recordEvent(h, corev1.EventTypeNormal, reasonChartUpdated,
+		fmt.Sprintf("Updated chart %s from version %s to %s", h.Spec.Chart.Repository.Name, previous, version))
+}
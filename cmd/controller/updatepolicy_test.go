@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUpdatePolicy(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.2.3",
+			}},
+			UpdatePolicy: helmCrdV2.UpdatePolicyPatch,
+		},
+		// Deployed by a previous reconcile
+		Status: helmCrdV2.HelmReleaseStatus{Revision: 1, ChartVersion: "1.2.3"},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"myns-foo"})
+	if !releaseNeedsResync(&h) {
+		t.Errorf("Expecting HelmReleases with an update policy to be resynced")
+	}
+
+	// Publish newer versions
+	netClient := (*controller.netClient).(*fakeHTTPClient)
+	for _, v := range []string{"1.2.5", "1.3.0"} {
+		chartURL := "http://charts.example.com/repo/foo-" + v + ".tgz"
+		netClient.chartURLs = append(netClient.chartURLs, chartURL)
+		netClient.index.Entries["foo"] = append(netClient.index.Entries["foo"], &repo.ChartVersion{
+			Metadata: &chart.Metadata{Name: "foo", Version: v},
+			URLs:     []string{chartURL},
+		})
+	}
+	netClient.index.SortEntries()
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if res.Status.ResolvedVersion != "1.2.5" {
+		t.Errorf("Expecting the chart to be updated to 1.2.5, received %s", res.Status.ResolvedVersion)
+	}
+	if u := res.Status.LastChartUpdate; u == nil || u.PreviousVersion != "1.2.3" || u.Version != "1.2.5" {
+		t.Errorf("Unexpected chart update %+v", u)
+	}
+	events, _ := controller.kubeClient.Core().Events("myns").List(metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != reasonChartUpdated {
+		t.Errorf("Expecting a ChartUpdated event, received %+v", events.Items)
+	}
+}
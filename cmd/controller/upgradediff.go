@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+const (
+	// upgradeDiffKey is the data key holding the full diff of upgrades
+	upgradeDiffKey = "diff.txt"
+	// maxDiffSummaryObjects caps the objects listed in the diff summary
+	maxDiffSummaryObjects = 20
+	// maxFullDiffLen keeps full diffs well below the size limit of
+	// ConfigMaps and Secrets
+	maxFullDiffLen = 512 * 1024
+)
+
+// upgradeDiffEnabled returns true if the changes of upgrades of h are
+// recorded before running them
+func upgradeDiffEnabled(h *helmCrdV2.HelmRelease) bool {
+	return h.Spec.UpgradeDiff != nil && h.Spec.UpgradeDiff.Enable
+}
+
+// diffUpgrade renders the upgrade of the deployed release to ch with
+// values and summarizes its changes, nil if it changes nothing. The full
+// diff of the manifests is returned too.
+func diffUpgrade(helmClient helmclient.Interface, rlsName string, ch *chart.Chart, values []byte, deployed *release.Release, caps *chartutil.Capabilities) (*helmCrdV2.UpgradeDiffStatus, string, error) {
+	rel, err := renderRelease(helmClient, ch, rlsName, deployed.GetNamespace(), values, true, caps)
+	if err != nil {
+		return nil, "", err
+	}
+	from, to := deployed.GetManifest(), rel.GetManifest()
+	diff := &helmCrdV2.UpgradeDiffStatus{
+		FromVersion: deployed.GetChart().GetMetadata().GetVersion(),
+		ToVersion:   rel.GetChart().GetMetadata().GetVersion(),
+	}
+	if from == to && diff.FromVersion == diff.ToVersion && rel.GetConfig().GetRaw() == deployed.GetConfig().GetRaw() {
+		return nil, "", nil
+	}
+	added, changed, removed, err := manifest.Changes(from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	diff.Added, diff.Changed, diff.Removed = len(added), len(changed), len(removed)
+
+	var lines []string
+	for _, c := range []struct {
+		prefix string
+		objs   []manifest.Object
+	}{{"+", added}, {"~", changed}, {"-", removed}} {
+		for _, obj := range c.objs {
+			lines = append(lines, c.prefix+" "+obj.String())
+		}
+	}
+	if len(lines) > maxDiffSummaryObjects {
+		lines = append(lines[:maxDiffSummaryObjects], fmt.Sprintf("and %d more", len(lines)-maxDiffSummaryObjects))
+	}
+	diff.Summary = strings.Join(lines, "\n")
+
+	full, err := manifest.TextDiff(from, to)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(full) > maxFullDiffLen {
+		full = full[:maxFullDiffLen] + "\n[truncated]"
+	}
+	return diff, full, nil
+}
+
+// recordUpgradeDiff records the changes of the upgrade of the release of h
+// in its status, storing the full diff with spec.upgradeDiff.full, before
+// the upgrade runs. Upgrades changing nothing keep the previous diff.
+func (c *Controller) recordUpgradeDiff(h *helmCrdV2.HelmRelease, helmClient helmclient.Interface, rlsName string, ch *chart.Chart, values []byte, deployed *release.Release, caps *chartutil.Capabilities) (*helmCrdV2.HelmRelease, error) {
+	diff, full, err := diffUpgrade(helmClient, rlsName, ch, values, deployed, caps)
+	if err != nil || diff == nil {
+		return h, err
+	}
+	if h.Spec.UpgradeDiff.Full {
+		diff.FullDiff, err = c.storeReleaseData(h, h.Name+"-upgrade-diff", upgradeDiffKey, full)
+		if err != nil {
+			return h, err
+		}
+	}
+	diff.Time = metav1.NewTime(c.now())
+	status := h.Status
+	status.UpgradeDiff = diff
+	return c.updateStatus(h, status)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUpgradeDiff(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ReleaseName: "bar",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			UpgradeDiff: &helmCrdV2.UpgradeDiffSpec{Enable: true, Full: true},
+		},
+		Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+	controller.now = func() time.Time { return now }
+	// The upgrade renders the fixture Secret of the fake client
+	fakeHelmClient(controller).Releases[0].Manifest = `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: fixture
+type: Opaque
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+`
+
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	diff := res.Status.UpgradeDiff
+	if diff == nil {
+		t.Fatalf("Expecting the upgrade diff to be recorded")
+	}
+	if diff.ToVersion != "1.0.0" || diff.Added != 0 || diff.Changed != 1 || diff.Removed != 1 || !diff.Time.Time.Equal(now) {
+		t.Errorf("Unexpected upgrade diff %+v", diff)
+	}
+	if expected := "~ Secret fixture\n- ConfigMap old"; diff.Summary != expected {
+		t.Errorf("Expecting summary %q, received %q", expected, diff.Summary)
+	}
+	if ref := diff.FullDiff; ref == nil || ref.Kind != "ConfigMap" || ref.Name != "foo-upgrade-diff" {
+		t.Fatalf("Unexpected full diff reference %+v", ref)
+	}
+	cm, err := controller.kubeClient.Core().ConfigMaps("myns").Get("foo-upgrade-diff", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if full := cm.Data[upgradeDiffKey]; !strings.Contains(full, "-type: Opaque\n") || !strings.Contains(full, "--- ConfigMap old\n") {
+		t.Errorf("Unexpected full diff %q", full)
+	}
+	if len(fakeHelmClient(controller).Releases) != 2 {
+		t.Errorf("Expecting the release to be upgraded")
+	}
+
+	// Resyncs changing nothing keep the diff
+	now = now.Add(time.Hour)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if res.Status.UpgradeDiff == nil || !res.Status.UpgradeDiff.Time.Time.Equal(diff.Time.Time) {
+		t.Errorf("Expecting the upgrade diff to be kept, received %+v", res.Status.UpgradeDiff)
+	}
+}
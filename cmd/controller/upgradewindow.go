@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/helmclient"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/window"
+)
+
+// Reasons of the conditions of HelmReleases with an upgrade window
+const (
+	reasonOutsideUpgradeWindow = "OutsideUpgradeWindow"
+	reasonInvalidUpgradeWindow = "InvalidUpgradeWindow"
+)
+
+// upgradeDeferred returns whether upgrades of h wait for its upgrade
+// window, closed now, and when the window opens, which is zero if it
+// never does. Force-syncs are not deferred.
+func (c *Controller) upgradeDeferred(h *helmCrdV2.HelmRelease) (time.Time, bool, error) {
+	if h.Annotations[helmCrdV2.ForceSyncAnnotation] != h.Status.LastForceSync {
+		return time.Time{}, false, nil
+	}
+	w, err := window.Parse(h.Spec.UpgradeWindow)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid upgradeWindow: %v", err)
+	}
+	now := c.now()
+	if w.Open(now) {
+		return time.Time{}, false, nil
+	}
+	return w.Next(now), true, nil
+}
+
+// upgradePending returns whether upgrading the deployed revision of
+// release rlsName to ch with values changes its chart, values or manifest
+func upgradePending(helmClient helmclient.Interface, rlsName string, ch *chart.Chart, values []byte, deployed *release.Release, caps *chartutil.Capabilities) (bool, error) {
+	rel, err := renderRelease(helmClient, ch, rlsName, deployed.GetNamespace(), values, true, caps)
+	if err != nil {
+		return false, err
+	}
+	return rel.GetManifest() != deployed.GetManifest() ||
+		rel.GetChart().GetMetadata().GetVersion() != deployed.GetChart().GetMetadata().GetVersion() ||
+		rel.GetConfig().GetRaw() != deployed.GetConfig().GetRaw(), nil
+}
+
+// deferUpgrade records in the UpgradeDeferred condition of h that its
+// upgrade to chartVersion waits for the upgrade window, and requeues it
+// for when the window opens
+func (c *Controller) deferUpgrade(h *helmCrdV2.HelmRelease, key, chartVersion string, opens time.Time) error {
+	msg := fmt.Sprintf("Upgrade to chart version %s deferred until the upgrade window opens", chartVersion)
+	if !opens.IsZero() {
+		msg += " at " + opens.UTC().Format(time.RFC3339)
+		c.queue.AddAfter(key, opens.Sub(c.now()))
+	}
+	status := h.Status
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseUpgradeDeferred); cond == nil || cond.Message != msg {
+		c.recordEvent(h, corev1.EventTypeNormal, reasonOutsideUpgradeWindow, msg)
+	}
+	setCondition(&status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseUpgradeDeferred,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonOutsideUpgradeWindow,
+		Message: msg,
+	})
+	_, err := c.updateStatus(h, status)
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestUpgradeWindow(t *testing.T) {
+	// A Thursday
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		start     string
+		forceSync string
+		upgraded  bool
+	}{
+		{"open", "11:00", "", true},
+		{"closed", "22:00", "", false},
+		{"closed, force-synced", "22:00", "2026-10-15T12:00:00Z", true},
+	}
+	for _, tt := range tests {
+		h := helmCrdV2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			Spec: helmCrdV2.HelmReleaseSpec{
+				ReleaseName: "bar",
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+					URL:     "http://charts.example.com/repo/",
+					Name:    "foo",
+					Version: "1.0.0",
+				}},
+				UpgradeWindow: &helmCrdV2.UpgradeWindow{
+					Ranges: []helmCrdV2.TimeRange{{Start: tt.start, End: "23:00"}},
+				},
+			},
+			// Deployed by a previous reconcile
+			Status: helmCrdV2.HelmReleaseStatus{Revision: 1},
+		}
+		if tt.forceSync != "" {
+			h.Annotations = map[string]string{helmCrdV2.ForceSyncAnnotation: tt.forceSync}
+		}
+		controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{"bar"})
+		controller.now = func() time.Time { return now }
+		if err := controller.updateRelease("myns/foo"); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if upgraded := len(fakeHelmClient(controller).Releases) == 2; upgraded != tt.upgraded {
+			t.Errorf("%s: expecting upgraded %v, received releases %v", tt.name, tt.upgraded, fakeHelmClient(controller).Releases)
+		}
+		res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+		cond := getCondition(&res.Status, helmCrdV2.HelmReleaseUpgradeDeferred)
+		if tt.upgraded {
+			if cond != nil {
+				t.Errorf("%s: unexpected UpgradeDeferred condition %+v", tt.name, cond)
+			}
+			continue
+		}
+		if cond == nil || !strings.Contains(cond.Message, "2026-10-15T22:00:00Z") {
+			t.Errorf("%s: unexpected UpgradeDeferred condition %+v", tt.name, cond)
+		}
+		if controller.queue.Len() != 0 {
+			t.Errorf("%s: expecting the HelmRelease to be requeued later", tt.name)
+		}
+	}
+}
+
+func TestUpgradeWindowInstalls(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+			// Never opens
+			UpgradeWindow: &helmCrdV2.UpgradeWindow{Schedule: "0 0 30 2 *", Duration: 3600},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be installed outside the upgrade window, received %v", deployed)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/sops"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// Names of the users and groups service accounts authenticate as
+const (
+	// serviceAccountUserPrefix is prefixed to namespace:name to name the
+	// user of a service account
+	serviceAccountUserPrefix = "system:serviceaccount:"
+	// serviceAccountsGroupPrefix is prefixed to a namespace to name the
+	// group of all its service accounts
+	serviceAccountsGroupPrefix = "system:serviceaccounts:"
+	serviceAccountsGroup       = "system:serviceaccounts"
+	authenticatedGroup         = "system:authenticated"
+)
+
+// releaseValues returns the YAML values for a release: the global values
+// of the controller and the valuesFrom sources merged in order, followed
+// by the inline values with variables
+// substituted, following spec.valuesMergeStrategy. The values files
+// fetched from URLs are returned too.
+func (c *Controller) releaseValues(h *helmCrdV2.HelmRelease) ([]byte, []helmCrdV2.FetchedValues, error) {
+	inline := valuesUtils.Substitute(string(h.Spec.Values), c.valuesVariables(h))
+	global, err := c.globalValues()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(h.Spec.ValuesFrom) == 0 && len(global) == 0 {
+		return []byte(inline), nil, nil
+	}
+
+	docs := [][]byte{global}
+	var fetched []helmCrdV2.FetchedValues
+	for i, src := range h.Spec.ValuesFrom {
+		doc, err := c.valuesFromSource(h.Namespace, src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("valuesFrom[%d]: %v", i, err)
+		}
+		if src.URL != "" {
+			fetched = append(fetched, helmCrdV2.FetchedValues{URL: src.URL, Checksum: valuesChecksum(doc)})
+		}
+		if src.Sops && len(doc) > 0 {
+			if c.sopsKeys.Empty() {
+				return nil, nil, fmt.Errorf("valuesFrom[%d]: no SOPS keys configured, see --sops-keyring and --sops-age-keys", i)
+			}
+			doc, err = sops.Decrypt(doc, c.sopsKeys)
+			if err != nil {
+				return nil, nil, fmt.Errorf("valuesFrom[%d]: %v", i, err)
+			}
+		}
+		docs = append(docs, doc)
+	}
+	docs = append(docs, []byte(inline))
+	values, err := valuesUtils.MergeStrategy(valuesUtils.Strategy(h.Spec.ValuesMergeStrategy), docs...)
+	return values, fetched, err
+}
+
+// valuesVariables returns the variables substituted in the inline values
+func (c *Controller) valuesVariables(h *helmCrdV2.HelmRelease) map[string]string {
+	return valuesUtils.Variables(h, getReleaseName(h), c.clusterDomain)
+}
+
+func (c *Controller) valuesFromSource(namespace string, src helmCrdV2.ValuesSource) ([]byte, error) {
+	switch {
+	case src.ConfigMapKeyRef != nil:
+		return valuesUtils.FromConfigMap(c.kubeClient, namespace, src.ConfigMapKeyRef)
+	case src.SecretKeyRef != nil:
+		return valuesUtils.FromSecret(c.kubeClient, namespace, src.SecretKeyRef)
+	case src.FieldRef != nil:
+		return c.fieldRefValues(namespace, src.FieldRef)
+	case src.URL != "":
+		return c.fetchValuesURL(namespace, src)
+	case src.HelmReleaseRef != nil:
+		return valuesUtils.FromOutputs(c.kubeClient, namespace, src.HelmReleaseRef)
+	}
+	return nil, fmt.Errorf("no values source set")
+}
+
+// fieldRefValues returns a values document setting the target path of ref
+// to the referenced field. Data fields of Secrets are decoded.
+func (c *Controller) fieldRefValues(namespace string, ref *helmCrdV2.ObjectFieldRef) ([]byte, error) {
+	resource, err := c.objects.Resource(ref.APIVersion, ref.Kind)
+	if err != nil {
+		return nil, err
+	}
+	objNamespace := namespace
+	if !resource.Namespaced {
+		objNamespace = ""
+	} else if ref.Namespace != "" {
+		objNamespace = ref.Namespace
+	}
+	if objNamespace != namespace {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.authorizeGet(namespace, gv.WithResource(resource.Name), ref.Name, objNamespace); err != nil {
+			return nil, err
+		}
+	}
+
+	obj := manifest.Object{APIVersion: ref.APIVersion, Kind: ref.Kind, Namespace: objNamespace, Name: ref.Name}
+	live, err := c.objects.Get(obj, objNamespace)
+	if err != nil {
+		if isOptional(ref.Optional) && k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	value, found, err := valuesUtils.Lookup(live, ref.FieldPath)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if isOptional(ref.Optional) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("field %s not found in %s %s", ref.FieldPath, ref.Kind, ref.Name)
+	}
+	if ref.APIVersion == "v1" && ref.Kind == "Secret" && strings.HasPrefix(ref.FieldPath, "data") {
+		if encoded, ok := value.(string); ok {
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, err
+			}
+			value = string(data)
+		}
+	}
+	values, err := valuesUtils.Set(ref.TargetPath, value)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(values)
+}
+
+// authorizeGet checks the service accounts of namespace are allowed to
+// get the object name of resource in objNamespace, read for a HelmRelease
+// of namespace, so that HelmReleases can't use the controller permissions
+// to read any object in the cluster
+func (c *Controller) authorizeGet(namespace string, resource schema.GroupVersionResource, name, objNamespace string) error {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: objNamespace,
+				Verb:      "get",
+				Group:     resource.Group,
+				Version:   resource.Version,
+				Resource:  resource.Resource,
+				Name:      name,
+			},
+			Groups: []string{serviceAccountsGroupPrefix + namespace},
+		},
+	}
+	review, err := c.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(review)
+	if err != nil {
+		return err
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("service accounts of namespace %s are not allowed to get %s %s in %q", namespace, resource.Resource, name, objNamespace)
+	}
+	return nil
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestReleaseValues(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "common"},
+			Data:       map[string]string{"values.yaml": "image:\n  tag: 1.0.0\n  pullPolicy: Always\nreplicas: 1\n"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "passwords"},
+			Data:       map[string][]byte{"values.yaml": []byte("password: sekret\n")},
+		},
+	)
+	controller := &Controller{kubeClient: kubeClient}
+	optional := true
+
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "common"},
+					Key:                  "values.yaml",
+				}},
+				{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "passwords"},
+					Key:                  "values.yaml",
+				}},
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+					Key:                  "values.yaml",
+					Optional:             &optional,
+				}},
+			},
+			Values: "image:\n  tag: 2.0.0\n",
+		},
+	}
+	values, _, err := controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "image:\n  pullPolicy: Always\n  tag: 2.0.0\npassword: sekret\nreplicas: 1\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q received %q", expected, values)
+	}
+
+	// Inline values replace whole top-level keys with the overwrite strategy
+	h.Spec.ValuesMergeStrategy = helmCrdV2.ValuesMergeOverwrite
+	values, _, err = controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected = "image:\n  tag: 2.0.0\npassword: sekret\nreplicas: 1\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q received %q", expected, values)
+	}
+
+	// Missing sources fail unless optional
+	h.Spec.ValuesFrom[2].ConfigMapKeyRef.Optional = nil
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error for a missing ConfigMap")
+	}
+}
+
+func TestReleaseValuesSopsWithoutKeys(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "passwords"},
+		Data:       map[string][]byte{"values.yaml": []byte("password: ENC[AES256_GCM,data:foo,iv:bar,tag:baz,type:str]\nsops: {}\n")},
+	})
+	controller := &Controller{kubeClient: kubeClient}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "passwords"},
+					Key:                  "values.yaml",
+				},
+				Sops: true,
+			}},
+		},
+	}
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error decrypting SOPS values without keys")
+	}
+}
+
+func TestReleaseValuesVariables(t *testing.T) {
+	controller := &Controller{clusterDomain: "cluster.local"}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Values: "url: http://${RELEASE_NAME}.${NAMESPACE}.svc.${CLUSTER_DOMAIN}\n",
+		},
+	}
+	values, _, err := controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "url: http://myns-foo.myns.svc.cluster.local\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q received %q", expected, values)
+	}
+}
+
+func TestReleaseValuesFieldRef(t *testing.T) {
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{
+		"Service/db": {"spec": map[string]interface{}{"clusterIP": "10.0.0.1"}},
+		"Secret/db":  {"data": map[string]interface{}{"password": "c2VrcmV0"}},
+	}}
+	kubeClient := fake.NewSimpleClientset()
+	controller := &Controller{kubeClient: kubeClient, objects: objects}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{FieldRef: &helmCrdV2.ObjectFieldRef{
+					APIVersion: "v1", Kind: "Service", Name: "db",
+					FieldPath: "spec.clusterIP", TargetPath: "database.host",
+				}},
+				{FieldRef: &helmCrdV2.ObjectFieldRef{
+					APIVersion: "v1", Kind: "Secret", Name: "db",
+					FieldPath: "data.password", TargetPath: "database.password",
+				}},
+			},
+		},
+	}
+	values, _, err := controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "database:\n  host: 10.0.0.1\n  password: sekret\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q received %q", expected, values)
+	}
+
+	// Reading another namespace requires its service accounts to be allowed
+	h.Spec.ValuesFrom[0].FieldRef.Namespace = "other"
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error reading an object of another namespace")
+	}
+	var review *authorizationv1.SubjectAccessReview
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review = action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+	if _, _, err := controller.releaseValues(h); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	attrs := review.Spec.ResourceAttributes
+	if review.Spec.Groups[0] != "system:serviceaccounts:myns" || attrs.Namespace != "other" || attrs.Resource != "services" || attrs.Name != "db" {
+		t.Errorf("Unexpected access review %+v", review.Spec)
+	}
+
+	// Missing fields fail unless optional
+	h.Spec.ValuesFrom[0].FieldRef.FieldPath = "spec.loadBalancerIP"
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error for a missing field")
+	}
+	optional := true
+	h.Spec.ValuesFrom[0].FieldRef.Optional = &optional
+	values, _, err = controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected = "database:\n  password: sekret\n"
+	if string(values) != expected {
+		t.Errorf("Expecting values %q received %q", expected, values)
+	}
+}
+
+// valuesServer serves a values file with an ETag, recording the
+// Authorization header and whether the file was downloaded
+type valuesServer struct {
+	body       string
+	auth       string
+	downloads  int
+	statusCode int
+}
+
+func (s *valuesServer) Do(req *http.Request) (*http.Response, error) {
+	s.auth = req.Header.Get("Authorization")
+	if s.statusCode != 0 {
+		return &http.Response{StatusCode: s.statusCode, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	etag := `"` + valuesChecksum([]byte(s.body)) + `"`
+	if req.Header.Get("If-None-Match") == etag {
+		return &http.Response{StatusCode: http.StatusNotModified, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	s.downloads++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": []string{etag}},
+		Body:       ioutil.NopCloser(strings.NewReader(s.body)),
+	}, nil
+}
+
+func TestReleaseValuesFromURL(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "artifacts"},
+		Data:       map[string][]byte{"token": []byte("Bearer token")},
+	})
+	server := &valuesServer{body: "replicas: 2\n"}
+	var netClient chartUtils.HTTPClient = server
+	controller := &Controller{kubeClient: kubeClient, netClient: &netClient}
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{{
+				URL: "https://artifacts.example.com/values.yaml",
+				AuthSecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "artifacts"},
+					Key:                  "token",
+				},
+			}},
+		},
+	}
+	values, fetched, err := controller.releaseValues(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if string(values) != "replicas: 2\n" || server.auth != "Bearer token" {
+		t.Errorf("Unexpected values %q fetched with auth %q", values, server.auth)
+	}
+	expected := []helmCrdV2.FetchedValues{{URL: "https://artifacts.example.com/values.yaml", Checksum: valuesChecksum([]byte("replicas: 2\n"))}}
+	if !reflect.DeepEqual(fetched, expected) {
+		t.Errorf("Expecting %v received %v", expected, fetched)
+	}
+
+	// Unchanged files are served from the cache
+	if values, _, err = controller.releaseValues(h); err != nil || string(values) != "replicas: 2\n" || server.downloads != 1 {
+		t.Errorf("Expecting cached values, received %q, %v after %d downloads", values, err, server.downloads)
+	}
+	server.body = "replicas: 3\n"
+	if values, fetched, err = controller.releaseValues(h); err != nil || string(values) != "replicas: 3\n" || fetched[0].Checksum == expected[0].Checksum {
+		t.Errorf("Expecting changed values, received %q, %v, %v", values, fetched, err)
+	}
+
+	server.body = strings.Repeat("#", maxValuesSize+1)
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error for a values file too large")
+	}
+
+	server.statusCode = http.StatusNotFound
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error for a missing values file")
+	}
+
+	// Values URLs are subject to the repository policy
+	server.statusCode = 0
+	controller.repoPolicy = policy.RepoPolicy{Allowed: []string{"https://charts.example.com/*"}}
+	if _, _, err := controller.releaseValues(h); err == nil {
+		t.Errorf("Expected an error for a values URL not allowed")
+	}
+}
+
+func TestEvictValues(t *testing.T) {
+	controller := &Controller{valuesCache: map[string]cachedValues{}}
+	start := time.Now()
+	for i := 0; i <= maxCachedValues; i++ {
+		controller.valuesCache[fmt.Sprintf("https://artifacts.example.com/%d.yaml\n", i)] = cachedValues{
+			lastUsed: start.Add(time.Duration(i) * time.Second),
+		}
+	}
+	controller.evictValues()
+	if len(controller.valuesCache) != maxCachedValues {
+		t.Errorf("Expected %d cached values files, received %d", maxCachedValues, len(controller.valuesCache))
+	}
+	if _, ok := controller.valuesCache["https://artifacts.example.com/0.yaml\n"]; ok {
+		t.Errorf("Expected the least recently used values file to be evicted")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/jsonschema"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// reasonValuesInvalid is the reason of the ValuesInvalid and Ready
+// conditions of HelmReleases whose values violate the chart's schema
+const reasonValuesInvalid = "ValuesInvalid"
+
+// valuesSchemaFile is the chart file holding the JSON schema of its values
+const valuesSchemaFile = "values.schema.json"
+
+// maxSchemaErrors caps the violations reported in conditions
+const maxSchemaErrors = 10
+
+// validateValuesSchema returns an error listing the violations of the
+// values schema bundled with ch, if any, by its default values merged
+// with values
+func validateValuesSchema(ch *chart.Chart, values []byte) error {
+	var schemaData []byte
+	for _, f := range ch.GetFiles() {
+		if f.TypeUrl == valuesSchemaFile {
+			schemaData = f.Value
+			break
+		}
+	}
+	if schemaData == nil {
+		return nil
+	}
+	schema, err := jsonschema.Parse(schemaData)
+	if err != nil {
+		return fmt.Errorf("%s: %v", valuesSchemaFile, err)
+	}
+
+	merged, err := valuesUtils.Merge([]byte(ch.GetValues().GetRaw()), values)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(merged, &v); err != nil {
+		return fmt.Errorf("invalid values: %v", err)
+	}
+	errs := schema.Validate(v)
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) > maxSchemaErrors {
+		errs = append(errs[:maxSchemaErrors], fmt.Sprintf("and %d more", len(errs)-maxSchemaErrors))
+	}
+	return fmt.Errorf("values don't match the chart's schema: %s", strings.Join(errs, "; "))
+}
+
+// rejectInvalidValues records in the ValuesInvalid and Ready conditions
+// of h that its values violate the chart's schema
+func (c *Controller) rejectInvalidValues(h *helmCrdV2.HelmRelease, err error) error {
+	return c.rejectRelease(h, reasonValuesInvalid, err, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseValuesInvalid,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonValuesInvalid,
+		Message: err.Error(),
+	})
+}
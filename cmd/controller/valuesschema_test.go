@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+const valuesSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {
+      "type": "object",
+      "properties": {"tag": {"type": "string"}},
+      "additionalProperties": false
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`
+
+func schemaChart(in io.Reader) (*chart.Chart, error) {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"},
+		Values:   &chart.Config{Raw: "image:\n  tag: \"1.0\"\nreplicas: 1\n"},
+		Files:    []*any.Any{{TypeUrl: "values.schema.json", Value: []byte(valuesSchema)}},
+	}, nil
+}
+
+func TestValidateValuesSchema(t *testing.T) {
+	ch, _ := schemaChart(nil)
+	tests := []struct {
+		values   string
+		expected []string
+	}{
+		{"", nil},
+		{"replicas: 3", nil},
+		{"replicas: 0\nimage:\n  tga: latest", []string{"image: additional property tga is not allowed", "replicas: must be greater than or equal to 1"}},
+		{"image:\n  tag: 2", []string{"image.tag: expected string, got integer"}},
+	}
+	for _, tt := range tests {
+		err := validateValuesSchema(ch, []byte(tt.values))
+		if tt.expected == nil {
+			if err != nil {
+				t.Errorf("Unexpected error for %q: %v", tt.values, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), strings.Join(tt.expected, "; ")) {
+			t.Errorf("Expecting %v for %q, received %v", tt.expected, tt.values, err)
+		}
+	}
+
+	if err := validateValuesSchema(&chart.Chart{}, []byte("foo: bar")); err != nil {
+		t.Errorf("Expecting charts without schema to accept any values, received %v", err)
+	}
+}
+
+func TestInvalidValues(t *testing.T) {
+	h := helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Values: "replicas: zero",
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{
+				URL:     "http://charts.example.com/repo/",
+				Name:    "foo",
+				Version: "1.0.0",
+			}},
+		},
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+	controller.loadChart = schemaChart
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Expecting invalid values not to be retried, received %v", err)
+	}
+	res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	cond := getCondition(&res.Status, helmCrdV2.HelmReleaseValuesInvalid)
+	if cond == nil || !strings.Contains(cond.Message, "replicas: expected integer, got string") {
+		t.Errorf("Unexpected ValuesInvalid condition %+v", cond)
+	}
+	if ready := getCondition(&res.Status, helmCrdV2.HelmReleaseReady); ready == nil || ready.Reason != reasonValuesInvalid {
+		t.Errorf("Unexpected Ready condition %+v", ready)
+	}
+	if len(fakeHelmClient(controller).Releases) != 0 {
+		t.Errorf("Expecting the release not to be installed")
+	}
+
+	// Fixing the values installs the release and clears the condition
+	res.Spec.Values = "replicas: 2"
+	controller.helmReleaseClient.HelmV2().HelmReleases("myns").Update(res)
+	controller.informer.GetIndexer().Update(res)
+	if err := controller.updateRelease("myns/foo"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	res, _ = controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+	if cond := getCondition(&res.Status, helmCrdV2.HelmReleaseValuesInvalid); cond != nil {
+		t.Errorf("Expecting the ValuesInvalid condition to be removed, received %+v", cond)
+	}
+	if deployed := fakeHelmClient(controller).Deployed(); len(deployed) != 1 {
+		t.Errorf("Expecting the release to be deployed, received %v", deployed)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+const (
+	// maxValuesSize is the size in bytes of the largest values file
+	// fetched from a URL
+	maxValuesSize = 1 << 20
+	// maxCachedValues is the number of values files cached, the least
+	// recently used being evicted beyond it
+	maxCachedValues = 100
+)
+
+// cachedValues is a values file fetched from a URL, along with the
+// validators of the response used to revalidate it
+type cachedValues struct {
+	data         []byte
+	etag         string
+	lastModified string
+	lastUsed     time.Time
+}
+
+// fetchValuesURL downloads the values file of src, sending the
+// Authorization header of its auth secret in namespace. Files are cached
+// and revalidated with conditional requests, so unchanged files are not
+// downloaded again on every reconcile. URLs are subject to the repository
+// policy, like charts.
+func (c *Controller) fetchValuesURL(namespace string, src helmCrdV2.ValuesSource) ([]byte, error) {
+	if err := c.repoPolicy.Check(src.URL); err != nil {
+		return nil, err
+	}
+	authHeader := ""
+	if ref := src.AuthSecretKeyRef; ref != nil {
+		secret, err := c.kubeClient.Core().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in Secret %s/%s", ref.Key, namespace, ref.Name)
+		}
+		authHeader = string(data)
+	}
+
+	req, err := http.NewRequest("GET", src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	// Responses may depend on the credentials
+	key := src.URL + "\n" + authHeader
+	c.valuesCacheLock.Lock()
+	cached, ok := c.valuesCache[key]
+	if ok {
+		cached.lastUsed = time.Now()
+		c.valuesCache[key] = cached
+	}
+	c.valuesCacheLock.Unlock()
+	if ok {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	res, err := (*c.netClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified && ok {
+		return cached.data, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("values download request failed with status %d", res.StatusCode)
+	}
+	if res.ContentLength > maxValuesSize {
+		return nil, fmt.Errorf("values file of %d bytes is larger than %d bytes", res.ContentLength, maxValuesSize)
+	}
+	// Read one more byte to tell files of exactly maxValuesSize bytes
+	// from larger ones
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxValuesSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxValuesSize {
+		return nil, fmt.Errorf("values file is larger than %d bytes", maxValuesSize)
+	}
+
+	c.valuesCacheLock.Lock()
+	if c.valuesCache == nil {
+		c.valuesCache = map[string]cachedValues{}
+	}
+	c.valuesCache[key] = cachedValues{
+		data:         data,
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+		lastUsed:     time.Now(),
+	}
+	c.evictValues()
+	c.valuesCacheLock.Unlock()
+	return data, nil
+}
+
+// evictValues removes the least recently used values files from the
+// cache beyond maxCachedValues. The caller must hold valuesCacheLock.
+func (c *Controller) evictValues() {
+	for len(c.valuesCache) > maxCachedValues {
+		var oldest string
+		for key, cached := range c.valuesCache {
+			if oldest == "" || cached.lastUsed.Before(c.valuesCache[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		delete(c.valuesCache, oldest)
+	}
+}
+
+// valuesChecksum returns the hex encoded SHA-256 checksum of a values file
+func valuesChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// logFetchedValuesChanges logs the values files whose checksum differs
+// from the one recorded in status by the previous reconcile
+func logFetchedValuesChanges(rlog *logging.Logger, previous, fetched []helmCrdV2.FetchedValues) {
+	checksums := map[string]string{}
+	for _, f := range previous {
+		checksums[f.URL] = f.Checksum
+	}
+	for _, f := range fetched {
+		if checksum, ok := checksums[f.URL]; ok && checksum != f.Checksum {
+			rlog.With("url", f.URL, "checksum", f.Checksum).Infof("Values file changed")
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cosign"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/oci"
+)
+
+// reasonVerificationFailed is the reason of the VerificationFailed and
+// Ready conditions, or of the warning Events, of HelmReleases whose chart
+// has no valid signature
+const reasonVerificationFailed = "VerificationFailed"
+
+// maxSignatureSize is the size in bytes of the largest signature payload
+const maxSignatureSize = 1 << 20
+
+// chartVerifier returns the verifier of the signatures of the chart of h,
+// with the keys of its verification Secret and its keyless identities
+func (c *Controller) chartVerifier(h *helmCrdV2.HelmRelease) (*cosign.Verifier, error) {
+	v := h.Spec.Verification
+	verifier := &cosign.Verifier{Keys: map[string]crypto.PublicKey{}, FulcioRoots: c.fulcioRoots, RekorKey: c.rekorKey}
+	if ref := v.SecretRef; ref != nil {
+		secret, err := c.kubeClient.Core().Secrets(h.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for name, data := range secret.Data {
+			if !strings.HasSuffix(name, ".pub") {
+				continue
+			}
+			key, err := cosign.ParsePublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("key %q of Secret %s/%s: %v", name, h.Namespace, ref.Name, err)
+			}
+			verifier.Keys[name] = key
+		}
+		if len(verifier.Keys) == 0 {
+			return nil, fmt.Errorf("no .pub keys in Secret %s/%s", h.Namespace, ref.Name)
+		}
+	}
+	if len(v.Keyless) > 0 && (c.fulcioRoots == nil || c.rekorKey == nil) {
+		return nil, fmt.Errorf("keyless verification requires the controller --sigstore-fulcio-roots and --sigstore-rekor-public-key")
+	}
+	for _, id := range v.Keyless {
+		issuer, err := regexp.Compile(id.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		subject, err := regexp.Compile(id.Subject)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Identities = append(verifier.Identities, cosign.Identity{Issuer: issuer, Subject: subject})
+	}
+	return verifier, nil
+}
+
+// verifyChart verifies the cosign signatures of the chart manifest of ref
+// with digest, returning who signed it. A *cosign.VerificationError is
+// returned for charts without a valid signature.
+func (c *Controller) verifyChart(h *helmCrdV2.HelmRelease, client *oci.Client, ref oci.Reference, digest string) (string, error) {
+	verifier, err := c.chartVerifier(h)
+	if err != nil {
+		return "", err
+	}
+	manifest, _, err := client.Manifest(ref, cosign.SignatureTag(digest))
+	if oci.IsNotFound(err) {
+		return "", &cosign.VerificationError{Digest: digest}
+	}
+	if err != nil {
+		return "", err
+	}
+	var sigs []cosign.Signature
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosign.SignatureMediaType {
+			continue
+		}
+		payload, err := client.Blob(ref, layer, maxSignatureSize)
+		if err != nil {
+			return "", err
+		}
+		sigs = append(sigs, cosign.Signature{Payload: payload, Annotations: layer.Annotations})
+	}
+	return verifier.Verify(digest, sigs)
+}
+
+// rejectUnverified records in the VerificationFailed and Ready conditions
+// of h that its chart has no valid signature
+func (c *Controller) rejectUnverified(h *helmCrdV2.HelmRelease, err error) error {
+	return c.rejectRelease(h, reasonVerificationFailed, err, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseVerificationFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  reasonVerificationFailed,
+		Message: err.Error(),
+	})
+}
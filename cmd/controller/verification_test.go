@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	chartUtils "github.com/bitnami-labs/helm-crd/pkg/utils/chart"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/cosign"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/oci"
+)
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry serves the chart foo:1.0.0 in the charts repository, signed
+// with key unless key is nil
+func fakeRegistry(t *testing.T, key *ecdsa.PrivateKey) *httptest.Server {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	addManifest := func(tag string, layer []byte, mediaType string, annotations map[string]string) string {
+		blobs[sha256Digest(layer)] = layer
+		data, _ := json.Marshal(oci.Manifest{
+			SchemaVersion: 2,
+			MediaType:     oci.ManifestMediaType,
+			Config:        oci.Descriptor{MediaType: oci.ChartConfigMediaType, Digest: sha256Digest(nil)},
+			Layers: []oci.Descriptor{{
+				MediaType:   mediaType,
+				Digest:      sha256Digest(layer),
+				Size:        int64(len(layer)),
+				Annotations: annotations,
+			}},
+		})
+		manifests[tag] = data
+		return sha256Digest(data)
+	}
+	digest := addManifest("1.0.0", []byte("chart"), oci.ChartLayerMediaType, nil)
+	if key != nil {
+		payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry/charts/foo"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+		sum := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		addManifest(cosign.SignatureTag(digest), payload, cosign.SignatureMediaType, map[string]string{
+			"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig),
+		})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v2/charts/foo/"), "/", 2)
+		var data []byte
+		var ok bool
+		switch parts[0] {
+		case "manifests":
+			data, ok = manifests[parts[1]]
+		case "blobs":
+			data, ok = blobs[parts[1]]
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+}
+
+func TestHelmReleaseVerification(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	tests := []struct {
+		name     string
+		signer   *ecdsa.PrivateKey
+		mode     helmCrdV2.VerificationMode
+		signedBy string
+		deployed bool
+	}{
+		{"signed", key, helmCrdV2.VerificationEnforce, "key cosign.pub", true},
+		{"unsigned", nil, helmCrdV2.VerificationEnforce, "", false},
+		{"unsigned in warn mode", nil, helmCrdV2.VerificationWarn, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := fakeRegistry(t, tt.signer)
+			defer registry.Close()
+			h := helmCrdV2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+				Spec: helmCrdV2.HelmReleaseSpec{
+					Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{
+						URL:       "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/charts/foo",
+						Version:   "1.0.0",
+						PlainHTTP: true,
+					}},
+					Verification: &helmCrdV2.VerificationSpec{
+						Mode:      tt.mode,
+						SecretRef: &corev1.LocalObjectReference{Name: "chart-keys"},
+					},
+				},
+			}
+			controller := prepareTestController([]helmCrdV2.HelmRelease{h}, []string{})
+			var netClient chartUtils.HTTPClient = http.DefaultClient
+			controller.netClient = &netClient
+			controller.kubeClient.Core().Secrets("myns").Create(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "chart-keys"},
+				Data:       map[string][]byte{"cosign.pub": pub},
+			})
+
+			if err := controller.updateRelease("myns/foo"); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			res, _ := controller.helmReleaseClient.HelmV2().HelmReleases("myns").Get(h.Name, metav1.GetOptions{})
+			if res.Status.SignedBy != tt.signedBy {
+				t.Errorf("Expecting the chart to be signed by %q, received %q", tt.signedBy, res.Status.SignedBy)
+			}
+			if deployed := len(fakeHelmClient(controller).Deployed()) == 1; deployed != tt.deployed {
+				t.Errorf("Expecting the release to be deployed: %v, received %v", tt.deployed, deployed)
+			}
+			cond := getCondition(&res.Status, helmCrdV2.HelmReleaseVerificationFailed)
+			if tt.deployed && cond != nil {
+				t.Errorf("Unexpected VerificationFailed condition %+v", cond)
+			}
+			if !tt.deployed && (cond == nil || cond.Status != corev1.ConditionTrue) {
+				t.Errorf("Expecting a VerificationFailed condition, received %+v", cond)
+			}
+		})
+	}
+}
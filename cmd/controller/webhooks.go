@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/tools/cache"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+const (
+	// webhooksPath is the path prefix of the webhook receivers
+	webhooksPath = "/webhooks/"
+	// maxWebhookPayload is the size in bytes of the largest webhook payload
+	maxWebhookPayload = 1 << 20
+	// githubSignatureHeader holds the HMAC-SHA256 signature of the payload
+	githubSignatureHeader = "X-Hub-Signature-256"
+)
+
+// sourceEvent is an update of the charts or values files served under
+// some URLs, received from a webhook
+type sourceEvent struct {
+	// URLs are the URL prefixes of the updated chart repositories, chart
+	// archives and values files, without scheme
+	URLs []string
+	// Chart is the name of the updated chart of the repositories, any
+	// chart if empty
+	Chart string
+}
+
+// parseEvent parses the payload of a webhook of a kind, returning nil for
+// events that don't update any source
+type parseEvent func(r *http.Request, payload []byte) (*sourceEvent, error)
+
+// webhookParsers are the payload parsers of the webhook kinds, served at
+// /webhooks/<kind>
+var webhookParsers = map[string]parseEvent{
+	"harbor":  parseHarborEvent,
+	"github":  parseGitHubEvent,
+	"generic": parseGenericEvent,
+}
+
+// webhookHandler serves the webhook receivers queuing the HelmReleases
+// using the chart repositories, chart archives or values files updated by
+// the events. Requests must be signed with secret in a GitHub style
+// X-Hub-Signature-256 header, or carry it in the Authorization header.
+func (c *Controller) webhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parse, ok := webhookParsers[strings.TrimPrefix(r.URL.Path, webhooksPath)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayload+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(payload) > maxWebhookPayload {
+			http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if !authorizeWebhook(r, payload, secret) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		event, err := parse(r, payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		queued := []string{}
+		if event != nil {
+			queued = c.enqueueAffected(event)
+		}
+		logger.With("path", r.URL.Path, "helmreleases", queued).Infof("Received webhook")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(map[string][]string{"queued": queued}); err != nil {
+			logger.With("error", err).Warnf("Unable to write webhook response")
+		}
+	})
+}
+
+// authorizeWebhook returns whether payload is signed with secret, or the
+// Authorization header of r is secret, optionally as a bearer token
+func authorizeWebhook(r *http.Request, payload []byte, secret string) bool {
+	if signature := r.Header.Get(githubSignatureHeader); signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(auth), []byte(secret)) == 1
+}
+
+// enqueueAffected queues the HelmReleases of the shard of c whose sources
+// are updated by e, returning their keys
+func (c *Controller) enqueueAffected(e *sourceEvent) []string {
+	keys := []string{}
+	for _, obj := range c.informer.GetStore().List() {
+		h := obj.(*helmCrdV2.HelmRelease)
+		if !c.affectedBy(h, e) || !c.shard.owns(h.Namespace+"/"+h.Name) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(h); err == nil {
+			logger.With("helmrelease", key).Debugf("Source updated by webhook")
+			c.queue.Add(key)
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// affectedBy returns whether the chart or values files of h are updated
+// by e
+func (c *Controller) affectedBy(h *helmCrdV2.HelmRelease, e *sourceEvent) bool {
+	switch src := h.Spec.ChartLocation(); {
+	case src.Repository != nil:
+		if e.Chart == "" || e.Chart == src.Repository.Name {
+			repoURL, _ := c.repoURLAndAuth(src.Repository)
+			for _, u := range append([]string{repoURL}, src.Repository.Mirrors...) {
+				if e.matches(u) {
+					return true
+				}
+			}
+		}
+	case src.Tarball != nil:
+		if e.matches(src.Tarball.URL) {
+			return true
+		}
+	case src.OCI != nil:
+		if e.matches(src.OCI.URL) {
+			return true
+		}
+	}
+	for _, src := range h.Spec.ValuesFrom {
+		if src.URL != "" && e.matches(src.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether u is under one of the URLs of e
+func (e *sourceEvent) matches(u string) bool {
+	u = trimScheme(u) + "/"
+	for _, prefix := range e.URLs {
+		if strings.HasPrefix(u, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimScheme returns u lowercased, without its scheme
+func trimScheme(u string) string {
+	u = strings.ToLower(u)
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	return u
+}
+
+// urlPrefix returns u as a prefix matched by sourceEvent
+func urlPrefix(u string) string {
+	return strings.TrimSuffix(trimScheme(u), "/") + "/"
+}
+
+// harborEvent is the payload of the Harbor webhooks
+type harborEvent struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+// parseHarborEvent parses the chart upload and deletion events of Harbor,
+// which serves the charts of a project at https://<host>/chartrepo/<project>
+func parseHarborEvent(r *http.Request, payload []byte) (*sourceEvent, error) {
+	var event harborEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	switch event.Type {
+	case "UPLOAD_CHART", "DELETE_CHART":
+	default:
+		return nil, nil
+	}
+	e := &sourceEvent{Chart: event.EventData.Repository.Name}
+	for _, res := range event.EventData.Resources {
+		host := strings.SplitN(trimScheme(res.ResourceURL), "/", 2)[0]
+		if host != "" {
+			e.URLs = append(e.URLs, urlPrefix(host+"/chartrepo/"+event.EventData.Repository.Namespace))
+		}
+	}
+	if len(e.URLs) == 0 {
+		return nil, fmt.Errorf("no resource URL in %s event", event.Type)
+	}
+	return e, nil
+}
+
+// githubEvent is the payload of the GitHub push webhooks
+type githubEvent struct {
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// parseGitHubEvent parses the push events of GitHub, updating the files
+// of the repository served from github.com, raw.githubusercontent.com and
+// GitHub Pages
+func parseGitHubEvent(r *http.Request, payload []byte) (*sourceEvent, error) {
+	if r.Header.Get("X-GitHub-Event") != "push" {
+		return nil, nil
+	}
+	var event githubEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(event.Repository.FullName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repository %q", event.Repository.FullName)
+	}
+	e := &sourceEvent{URLs: []string{
+		urlPrefix("github.com/" + event.Repository.FullName),
+		urlPrefix("raw.githubusercontent.com/" + event.Repository.FullName),
+	}}
+	if pages := parts[0] + ".github.io"; strings.EqualFold(parts[1], pages) {
+		e.URLs = append(e.URLs, urlPrefix(pages))
+	} else {
+		e.URLs = append(e.URLs, urlPrefix(pages+"/"+parts[1]))
+	}
+	return e, nil
+}
+
+// genericEvent is the payload of the generic webhook, e.g. posted after
+// uploading a chart to ChartMuseum
+type genericEvent struct {
+	// URL is the URL of the chart repository, chart archive or values
+	// file, or a prefix of them
+	URL string `json:"url"`
+	// Chart is the name of the updated chart of the repository
+	Chart string `json:"chart,omitempty"`
+}
+
+func parseGenericEvent(r *http.Request, payload []byte) (*sourceEvent, error) {
+	var event genericEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	if event.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	return &sourceEvent{URLs: []string{urlPrefix(event.URL)}, Chart: event.Chart}, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	release := func(name string, chart helmCrdV2.ChartSource, valuesURL string) helmCrdV2.HelmRelease {
+		h := helmCrdV2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: name},
+			Spec:       helmCrdV2.HelmReleaseSpec{Chart: chart},
+		}
+		if valuesURL != "" {
+			h.Spec.ValuesFrom = []helmCrdV2.ValuesSource{{URL: valuesURL}}
+		}
+		return h
+	}
+	repo := func(url, name string) helmCrdV2.ChartSource {
+		return helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{URL: url, Name: name}}
+	}
+	controller := prepareTestController([]helmCrdV2.HelmRelease{
+		release("harbor", repo("https://Harbor.example.com/chartrepo/library", "mychart"), ""),
+		release("harbor-other-chart", repo("https://harbor.example.com/chartrepo/library", "other"), ""),
+		release("harbor-other-project", repo("https://harbor.example.com/chartrepo/libraryx", "mychart"), ""),
+		release("tarball", helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{URL: "https://raw.githubusercontent.com/org/charts/master/mychart-1.0.0.tgz"}}, ""),
+		release("pages", repo("https://charts.example.com", "mychart"), "https://org.github.io/charts/values.yaml"),
+		release("museum", repo("http://chartmuseum.example.com:8080/", "mychart"), ""),
+	}, []string{})
+	handler := controller.webhookHandler("s3cret")
+
+	sign := func(payload string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(payload))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	testCases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		payload string
+		code    int
+		queued  []string
+	}{
+		{
+			"harbor upload", "/webhooks/harbor", map[string]string{"Authorization": "s3cret"},
+			`{"type":"UPLOAD_CHART","event_data":{"resources":[{"tag":"1.0.0","resource_url":"harbor.example.com/chartrepo/library/charts/mychart-1.0.0.tgz"}],"repository":{"name":"mychart","namespace":"library"}}}`,
+			202, []string{"myns/harbor"},
+		},
+		{
+			"harbor other event", "/webhooks/harbor", map[string]string{"Authorization": "Bearer s3cret"},
+			`{"type":"PULL_ARTIFACT","event_data":{}}`,
+			202, []string{},
+		},
+		{
+			"github push", "/webhooks/github", map[string]string{"X-GitHub-Event": "push", githubSignatureHeader: sign(`{"repository":{"full_name":"Org/charts"}}`)},
+			`{"repository":{"full_name":"Org/charts"}}`,
+			202, []string{"myns/pages", "myns/tarball"},
+		},
+		{
+			"github ping", "/webhooks/github", map[string]string{"X-GitHub-Event": "ping", githubSignatureHeader: sign(`{}`)},
+			`{}`,
+			202, []string{},
+		},
+		{
+			"generic", "/webhooks/generic", map[string]string{"Authorization": "s3cret"},
+			`{"url":"http://chartmuseum.example.com:8080","chart":"mychart"}`,
+			202, []string{"myns/museum"},
+		},
+		{
+			"generic without url", "/webhooks/generic", map[string]string{"Authorization": "s3cret"},
+			`{"chart":"mychart"}`,
+			400, nil,
+		},
+		{
+			"wrong signature", "/webhooks/github", map[string]string{"X-GitHub-Event": "push", githubSignatureHeader: sign(`{}`), "Authorization": "s3cret"},
+			`{"repository":{"full_name":"org/charts"}}`,
+			401, nil,
+		},
+		{
+			"wrong token", "/webhooks/generic", map[string]string{"Authorization": "secret"},
+			`{"url":"http://chartmuseum.example.com:8080"}`,
+			401, nil,
+		},
+		{
+			"unknown kind", "/webhooks/gitlab", map[string]string{"Authorization": "s3cret"},
+			`{}`,
+			404, nil,
+		},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.payload))
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.code {
+			t.Errorf("%s: expecting %d, received %d %s", tc.name, tc.code, w.Code, w.Body.String())
+			continue
+		}
+		if tc.code != 202 {
+			continue
+		}
+		var res map[string][]string
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(res["queued"], tc.queued) {
+			t.Errorf("%s: expecting %v to be queued, received %v", tc.name, tc.queued, res["queued"])
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/webhooks/generic", nil))
+	if w.Code != 405 {
+		t.Errorf("Expecting webhooks to be posted, received %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+)
+
+// defaultWorkers is the number of HelmReleases reconciled concurrently
+const defaultWorkers = 1
+
+// workerPool runs the workers reconciling HelmReleases. Its size can
+// change while it runs: workers are started right away when it grows,
+// and workers beyond a smaller size stop once they finish a reconcile.
+type workerPool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	started bool
+	wg      sync.WaitGroup
+	// work processes the next item of the queue, returning false once
+	// the queue is shut down
+	work func() bool
+}
+
+// start runs size workers
+func (p *workerPool) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = true
+	p.scale()
+}
+
+// resize sets the number of workers, at least one
+func (p *workerPool) resize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = size
+	if p.started {
+		p.scale()
+	}
+}
+
+// scale starts the missing workers. p.mu must be held.
+func (p *workerPool) scale() {
+	for ; p.running < p.size; p.running++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+}
+
+// wait returns once all workers stopped, after the queue is shut down
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+func (p *workerPool) worker() {
+	defer p.wg.Done()
+	for !p.retire() {
+		if !p.work() {
+			p.mu.Lock()
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+	}
+}
+
+// retire returns true, stopping the calling worker, if more workers are
+// running than the size of the pool
+func (p *workerPool) retire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running > p.size {
+		p.running--
+		return true
+	}
+	return false
+}
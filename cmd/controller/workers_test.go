@@ -0,0 +1,47 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	var running, done int32
+	release := make(chan struct{})
+	pool := &workerPool{size: 3, work: func() bool {
+		atomic.AddInt32(&running, 1)
+		_, open := <-release
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+		return open
+	}}
+	waitFor := func(n int32) {
+		for i := 0; atomic.LoadInt32(&running) != n; i++ {
+			if i == 100 {
+				t.Fatalf("Expecting %d busy workers, received %d", n, atomic.LoadInt32(&running))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	pool.start()
+	waitFor(3)
+
+	pool.resize(5)
+	waitFor(5)
+
+	// Workers beyond the new size stop once their item is done
+	pool.resize(2)
+	for i := 0; i < 5; i++ {
+		release <- struct{}{}
+	}
+	waitFor(2)
+	pool.mu.Lock()
+	if pool.running != 2 {
+		t.Errorf("Expecting 2 running workers, received %d", pool.running)
+	}
+	pool.mu.Unlock()
+
+	close(release)
+	pool.wait()
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// Reasons of the Healthy condition, and of the Ready condition of
+// releases whose workloads are not ready
+const (
+	reasonHealthy     = "Healthy"
+	reasonProgressing = "Progressing"
+	reasonUnhealthy   = "Unhealthy"
+)
+
+const (
+	// defaultHealthTimeout is how long the workloads of a deployed
+	// release have to become ready without spec.healthCheck.timeout
+	defaultHealthTimeout = 5 * time.Minute
+	// progressingPollInterval and unhealthyPollInterval are how often the
+	// workloads are checked until they are ready
+	progressingPollInterval = 10 * time.Second
+	unhealthyPollInterval   = time.Minute
+	// maxHealthMessages caps the workloads listed in the condition message
+	maxHealthMessages = 5
+)
+
+// healthCheckEnabled returns true if h is only Ready once its workloads are,
+// or once its health checks pass
+func healthCheckEnabled(h *helmCrdV2.HelmRelease) bool {
+	return workloadHealthEnabled(h) || len(h.Spec.HealthChecks) > 0
+}
+
+// workloadHealthEnabled returns true if the readiness of the workloads of
+// h is checked
+func workloadHealthEnabled(h *helmCrdV2.HelmRelease) bool {
+	hc := h.Spec.HealthCheck
+	return hc != nil && hc.Enable
+}
+
+// workloadReadiness returns whether the live workload of kind is ready,
+// and if not why, failed being true for workloads that will never be, i.e.
+// failed Jobs. Other kinds are always ready.
+func workloadReadiness(kind string, live map[string]interface{}) (ready, failed bool, reason string) {
+	generation, _ := nestedInt(live, "metadata", "generation")
+	observed, ok := nestedInt(live, "status", "observedGeneration")
+	if kind != "Job" && ok && observed < generation {
+		return false, false, "update not observed yet"
+	}
+	replicas, ok := nestedInt(live, "spec", "replicas")
+	if !ok {
+		replicas = 1
+	}
+	switch kind {
+	case "Deployment":
+		updated, _ := nestedInt(live, "status", "updatedReplicas")
+		available, _ := nestedInt(live, "status", "availableReplicas")
+		if updated < replicas {
+			return false, false, fmt.Sprintf("%d of %d replicas updated", updated, replicas)
+		}
+		if available < replicas {
+			return false, false, fmt.Sprintf("%d of %d replicas available", available, replicas)
+		}
+	case "StatefulSet":
+		ready, _ := nestedInt(live, "status", "readyReplicas")
+		if ready < replicas {
+			return false, false, fmt.Sprintf("%d of %d replicas ready", ready, replicas)
+		}
+		current, _ := nestedString(live, "status", "currentRevision")
+		update, _ := nestedString(live, "status", "updateRevision")
+		if update != "" && current != update {
+			updated, _ := nestedInt(live, "status", "updatedReplicas")
+			return false, false, fmt.Sprintf("%d of %d replicas updated", updated, replicas)
+		}
+	case "DaemonSet":
+		desired, _ := nestedInt(live, "status", "desiredNumberScheduled")
+		ready, _ := nestedInt(live, "status", "numberReady")
+		if updated, ok := nestedInt(live, "status", "updatedNumberScheduled"); ok && updated < desired {
+			return false, false, fmt.Sprintf("%d of %d pods updated", updated, desired)
+		}
+		if ready < desired {
+			return false, false, fmt.Sprintf("%d of %d pods ready", ready, desired)
+		}
+	case "Job":
+		conditions, _ := nestedField(live, "status", "conditions").([]interface{})
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			if cond["status"] != string(corev1.ConditionTrue) {
+				continue
+			}
+			switch cond["type"] {
+			case "Complete":
+				return true, false, ""
+			case "Failed":
+				return false, true, fmt.Sprintf("failed: %v", cond["message"])
+			}
+		}
+		return false, false, "not complete"
+	}
+	return true, false, ""
+}
+
+// assessHealth checks the readiness of the workloads among the resources
+// of the deployed release of h, and runs its health checks, setting the
+// Healthy and Ready conditions of status. Until they are ready the release is Progressing, and past
+// the health check timeout since it was deployed, or as soon as a Job
+// fails, Unhealthy. It returns when to check again, zero once healthy.
+func (c *Controller) assessHealth(h *helmCrdV2.HelmRelease, status *helmCrdV2.HelmReleaseStatus) time.Duration {
+	namespace := h.Spec.TargetNamespace
+	if namespace == "" {
+		namespace = h.Namespace
+	}
+	var notReady []string
+	var failed bool
+	objects, err := c.objectsFor(h)
+	if err != nil {
+		notReady = append(notReady, err.Error())
+	}
+	for _, ref := range status.Resources {
+		if objects == nil || !workloadHealthEnabled(h) {
+			break
+		}
+		switch ref.Kind {
+		case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		default:
+			continue
+		}
+		obj := manifest.Object{APIVersion: ref.APIVersion, Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+		live, err := objects.Get(obj, namespace)
+		if err != nil {
+			notReady = append(notReady, fmt.Sprintf("%s %s: %s", ref.Kind, ref.Name, objectError(err)))
+			continue
+		}
+		if ready, jobFailed, reason := workloadReadiness(ref.Kind, live); !ready {
+			notReady = append(notReady, fmt.Sprintf("%s %s: %s", ref.Kind, ref.Name, reason))
+			failed = failed || jobFailed
+		}
+	}
+
+	if objects != nil {
+		for _, hc := range h.Spec.HealthChecks {
+			if ok, checkFailed, reason := c.runHealthCheck(hc, objects, namespace); !ok {
+				notReady = append(notReady, fmt.Sprintf("%s: %s", healthCheckName(hc), reason))
+				failed = failed || checkFailed
+			}
+		}
+	}
+
+	deployedMessage := fmt.Sprintf("Release %s revision %d deployed", status.ReleaseName, status.Revision)
+	if len(notReady) == 0 {
+		setCondition(status, helmCrdV2.HelmReleaseCondition{
+			Type:    helmCrdV2.HelmReleaseHealthy,
+			Status:  corev1.ConditionTrue,
+			Reason:  reasonHealthy,
+			Message: "All workloads are ready and health checks passed",
+		})
+		setReady(status, reasonDeployed, deployedMessage)
+		return 0
+	}
+
+	if len(notReady) > maxHealthMessages {
+		notReady = append(notReady[:maxHealthMessages], fmt.Sprintf("and %d more", len(notReady)-maxHealthMessages))
+	}
+	message := strings.Join(notReady, "; ")
+	timeout := defaultHealthTimeout
+	if hc := h.Spec.HealthCheck; hc != nil && hc.Timeout > 0 {
+		timeout = time.Duration(hc.Timeout) * time.Second
+	}
+	// Measured from the latest of the deployment and the release becoming
+	// unhealthy, e.g. when a workload crashes later on
+	var since time.Time
+	if status.LastDeployed != nil {
+		since = status.LastDeployed.Time
+	}
+	prev := getCondition(status, helmCrdV2.HelmReleaseHealthy)
+	switch {
+	case prev == nil:
+	case prev.Status == corev1.ConditionFalse:
+		if prev.LastTransitionTime.After(since) {
+			since = prev.LastTransitionTime.Time
+		}
+	default:
+		since = c.now()
+	}
+
+	reason, poll := reasonProgressing, progressingPollInterval
+	if failed || !c.now().Before(since.Add(timeout)) {
+		reason, poll = reasonUnhealthy, unhealthyPollInterval
+		if prev == nil || prev.Reason != reasonUnhealthy {
+			c.recordEvent(h, corev1.EventTypeWarning, reasonUnhealthy, message)
+		}
+	}
+	setCondition(status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseHealthy,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	setCondition(status, helmCrdV2.HelmReleaseCondition{
+		Type:    helmCrdV2.HelmReleaseReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	return poll
+}
+
+// runHealthWorker checks the health of the releases of the health queue,
+// queued by successful reconciles until their workloads are ready, without
+// reconciling the releases again
+func (c *Controller) runHealthWorker() {
+	for c.processNextHealthCheck() {
+		// continue looping
+	}
+}
+
+func (c *Controller) processNextHealthCheck() bool {
+	key, quit := c.healthQueue.Get()
+	if quit {
+		return false
+	}
+	defer c.healthQueue.Done(key)
+	c.healthQueue.Forget(key)
+	if err := c.checkHealth(key.(string)); err != nil {
+		logger.With("helmrelease", key, "error", err).Warnf("Unable to check release health")
+		utilruntime.HandleError(err)
+		c.healthQueue.AddAfter(key, progressingPollInterval)
+	}
+	return true
+}
+
+// checkHealth updates the Healthy and Ready conditions of the deployed
+// HelmRelease with key
+func (c *Controller) checkHealth(key string) error {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	h := obj.(*helmCrdV2.HelmRelease)
+	if !healthCheckEnabled(h) || h.Status.Phase != helmCrdV2.PhaseDeployed || h.DeletionTimestamp != nil {
+		return nil
+	}
+	status := h.Status
+	poll := c.assessHealth(h, &status)
+	if _, err := c.updateStatus(h, status); err != nil {
+		return err
+	}
+	if poll > 0 {
+		c.healthQueue.AddAfter(key, poll)
+	}
+	return nil
+}
+
+// nestedField returns the field of obj at path, or nil
+func nestedField(obj map[string]interface{}, path ...string) interface{} {
+	var v interface{} = obj
+	for _, name := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// nestedInt returns the number at path in obj, decoded from JSON
+func nestedInt(obj map[string]interface{}, path ...string) (int64, bool) {
+	switch n := nestedField(obj, path...).(type) {
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// nestedString returns the string at path in obj
+func nestedString(obj map[string]interface{}, path ...string) (string, bool) {
+	s, ok := nestedField(obj, path...).(string)
+	return s, ok
+}
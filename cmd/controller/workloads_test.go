@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestWorkloadReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		live   map[string]interface{}
+		ready  bool
+		failed bool
+		reason string
+	}{
+		{
+			name: "available deployment",
+			kind: "Deployment",
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": float64(2)},
+				"spec":     map[string]interface{}{"replicas": float64(3)},
+				"status":   map[string]interface{}{"observedGeneration": float64(2), "updatedReplicas": float64(3), "availableReplicas": float64(3)},
+			},
+			ready: true,
+		},
+		{
+			name: "deployment rolling out",
+			kind: "Deployment",
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": float64(2)},
+				"spec":     map[string]interface{}{"replicas": float64(3)},
+				"status":   map[string]interface{}{"observedGeneration": float64(2), "updatedReplicas": float64(1), "availableReplicas": float64(3)},
+			},
+			reason: "1 of 3 replicas updated",
+		},
+		{
+			name: "deployment update not observed",
+			kind: "Deployment",
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": float64(3)},
+				"status":   map[string]interface{}{"observedGeneration": float64(2), "updatedReplicas": float64(1), "availableReplicas": float64(1)},
+			},
+			reason: "update not observed yet",
+		},
+		{
+			name: "statefulset updating",
+			kind: "StatefulSet",
+			live: map[string]interface{}{
+				"spec":   map[string]interface{}{"replicas": float64(2)},
+				"status": map[string]interface{}{"readyReplicas": float64(2), "updatedReplicas": float64(1), "currentRevision": "foo-1", "updateRevision": "foo-2"},
+			},
+			reason: "1 of 2 replicas updated",
+		},
+		{
+			name: "daemonset not ready",
+			kind: "DaemonSet",
+			live: map[string]interface{}{
+				"status": map[string]interface{}{"desiredNumberScheduled": float64(4), "updatedNumberScheduled": float64(4), "numberReady": float64(3)},
+			},
+			reason: "3 of 4 pods ready",
+		},
+		{
+			name: "complete job",
+			kind: "Job",
+			live: map[string]interface{}{
+				"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}},
+			},
+			ready: true,
+		},
+		{
+			name: "failed job",
+			kind: "Job",
+			live: map[string]interface{}{
+				"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"}}},
+			},
+			failed: true,
+			reason: "failed: BackoffLimitExceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, failed, reason := workloadReadiness(tt.kind, tt.live)
+			if ready != tt.ready || failed != tt.failed || reason != tt.reason {
+				t.Errorf("Expecting %v %v %q, received %v %v %q", tt.ready, tt.failed, tt.reason, ready, failed, reason)
+			}
+		})
+	}
+}
+
+func TestAssessHealth(t *testing.T) {
+	now := time.Now()
+	deployed := metav1.NewTime(now.Add(-time.Minute))
+	h := &helmCrdV2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV2.HelmReleaseSpec{HealthCheck: &helmCrdV2.HealthCheckSpec{Enable: true, Timeout: 120}},
+	}
+	controller := prepareTestController(nil, []string{})
+	controller.now = func() time.Time { return now }
+	objects := &fakeObjectClient{live: map[string]map[string]interface{}{
+		"Deployment/foo": {
+			"status": map[string]interface{}{"updatedReplicas": float64(1), "availableReplicas": float64(0)},
+		},
+	}}
+	controller.objects = objects
+	status := helmCrdV2.HelmReleaseStatus{
+		ReleaseName:  "foo",
+		Revision:     2,
+		LastDeployed: &deployed,
+		Resources: []helmCrdV2.ResourceReference{
+			{APIVersion: "v1", Kind: "ConfigMap", Name: "foo"},
+			{APIVersion: "apps/v1beta2", Kind: "Deployment", Name: "foo"},
+		},
+	}
+
+	// Not ready within the timeout
+	if poll := controller.assessHealth(h, &status); poll != progressingPollInterval {
+		t.Errorf("Expecting to poll again in %s, received %s", progressingPollInterval, poll)
+	}
+	cond := getCondition(&status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != reasonProgressing || cond.Message != "Deployment foo: 0 of 1 replicas available" {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+
+	// Past the timeout
+	now = now.Add(3 * time.Minute)
+	if poll := controller.assessHealth(h, &status); poll != unhealthyPollInterval {
+		t.Errorf("Expecting to poll again in %s, received %s", unhealthyPollInterval, poll)
+	}
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseHealthy); cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != reasonUnhealthy {
+		t.Errorf("Unexpected Healthy condition %+v", cond)
+	}
+
+	// Once available
+	objects.live["Deployment/foo"]["status"] = map[string]interface{}{"updatedReplicas": float64(1), "availableReplicas": float64(1)}
+	if poll := controller.assessHealth(h, &status); poll != 0 {
+		t.Errorf("Expecting healthy releases not to be polled, received %s", poll)
+	}
+	cond = getCondition(&status, helmCrdV2.HelmReleaseReady)
+	if cond == nil || cond.Status != corev1.ConditionTrue || cond.Reason != reasonDeployed || cond.Message != "Release foo revision 2 deployed" {
+		t.Errorf("Unexpected Ready condition %+v", cond)
+	}
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseHealthy); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("Unexpected Healthy condition %+v", cond)
+	}
+
+	// Deleted workloads are unhealthy
+	delete(objects.live, "Deployment/foo")
+	controller.assessHealth(h, &status)
+	if cond := getCondition(&status, helmCrdV2.HelmReleaseHealthy); cond == nil || cond.Reason != reasonProgressing || cond.Message != "Deployment foo: not found" {
+		t.Errorf("Unexpected Healthy condition %+v", cond)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+// stableRepoAlias is the repository name of charts from the stable
+// repository, which the controller uses by default
+const stableRepoAlias = "stable"
+
+type options struct {
+	chart      string
+	repoURL    string
+	version    string
+	name       string
+	namespace  string
+	valueFiles []string
+	set        []string
+	setString  []string
+	timeout    int64
+}
+
+// generate returns the HelmRelease YAML for opts. As with helm install,
+// --set values take precedence over values files.
+func generate(opts options) ([]byte, error) {
+	chart := opts.chart
+	if i := strings.Index(chart, "/"); i >= 0 {
+		if opts.repoURL != "" || chart[:i] != stableRepoAlias {
+			return nil, fmt.Errorf("unsupported chart reference %q, use --repo with the chart name", chart)
+		}
+		chart = chart[i+1:]
+	}
+
+	values, err := mergeValues(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.name
+	if name == "" {
+		name = chart
+	}
+	h := &helmCrdV2.HelmRelease{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: helmCrdV2.SchemeGroupVersion.String(),
+			Kind:       "HelmRelease",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: opts.namespace,
+		},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{
+				Repository: &helmCrdV2.RepositoryChartSource{
+					URL:     opts.repoURL,
+					Name:    chart,
+					Version: opts.version,
+				},
+			},
+			ReleaseName: opts.name,
+			Values:      helmCrdV2.Values(values),
+			Timeout:     opts.timeout,
+		},
+	}
+
+	// Drop the empty status and creationTimestamp from the output
+	data, err := yaml.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	delete(obj["metadata"].(map[string]interface{}), "creationTimestamp")
+	return yaml.Marshal(obj)
+}
+
+// mergeValues returns the YAML of the values files and --set values of
+// opts merged in order, or an empty string without values
+func mergeValues(opts options) (string, error) {
+	var docs [][]byte
+	for _, file := range opts.valueFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, data)
+	}
+	for _, sets := range []struct {
+		args         []string
+		stringValues bool
+	}{{opts.set, false}, {opts.setString, true}} {
+		for _, arg := range sets.args {
+			values, err := valuesUtils.ParseSet(arg, sets.stringValues)
+			if err != nil {
+				return "", fmt.Errorf("invalid --set %q: %v", arg, err)
+			}
+			data, err := yaml.Marshal(values)
+			if err != nil {
+				return "", err
+			}
+			docs = append(docs, data)
+		}
+	}
+	if len(docs) == 0 {
+		return "", nil
+	}
+	values, err := valuesUtils.Merge(docs...)
+	if err != nil {
+		return "", err
+	}
+	return string(values), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helmrelease-gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valuesFile := filepath.Join(dir, "values.yaml")
+	if err := ioutil.WriteFile(valuesFile, []byte("auth:\n  enabled: false\n  user: admin\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := generate(options{
+		chart:      "mydb",
+		repoURL:    "https://charts.example.com",
+		version:    "1.2.3",
+		name:       "db",
+		namespace:  "prod",
+		valueFiles: []string{valuesFile},
+		set:        []string{"auth.enabled=true,replicas=3"},
+		setString:  []string{"tag=1.10"},
+		timeout:    600,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	h := helmCrdV2.HelmRelease{}
+	if err := yaml.Unmarshal(data, &h); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if h.APIVersion != "helm.bitnami.com/v2" || h.Kind != "HelmRelease" || h.Name != "db" || h.Namespace != "prod" {
+		t.Errorf("Unexpected type or metadata in %s", data)
+	}
+	repo := h.Spec.Chart.Repository
+	if repo == nil || repo.URL != "https://charts.example.com" || repo.Name != "mydb" || repo.Version != "1.2.3" {
+		t.Errorf("Unexpected chart in %s", data)
+	}
+	if h.Spec.ReleaseName != "db" || h.Spec.Timeout != 600 {
+		t.Errorf("Unexpected release name or timeout in %s", data)
+	}
+	if expected := "auth:\n  enabled: true\n  user: admin\nreplicas: 3\ntag: \"1.10\"\n"; string(h.Spec.Values) != expected {
+		t.Errorf("Expecting values %q, received %q", expected, h.Spec.Values)
+	}
+	obj := map[string]interface{}{}
+	yaml.Unmarshal(data, &obj)
+	if _, ok := obj["status"]; ok {
+		t.Errorf("Expecting no status in %s", data)
+	}
+}
+
+func TestGenerateChartReference(t *testing.T) {
+	data, err := generate(options{chart: "stable/mysql"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	h := helmCrdV2.HelmRelease{}
+	yaml.Unmarshal(data, &h)
+	if h.Name != "mysql" || h.Spec.Chart.Repository.Name != "mysql" || h.Spec.Chart.Repository.URL != "" || h.Spec.ReleaseName != "" {
+		t.Errorf("Expecting a stable chart release named after the chart, received %s", data)
+	}
+
+	if _, err := generate(options{chart: "myrepo/mysql"}); err == nil {
+		t.Errorf("Expecting an error for a repository alias")
+	}
+	if _, err := generate(options{chart: "mysql", set: []string{"a"}}); err == nil {
+		t.Errorf("Expecting an error for an invalid --set")
+	}
+}
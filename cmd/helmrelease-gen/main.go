@@ -0,0 +1,43 @@
+// helmrelease-gen prints the HelmRelease equivalent to a helm install
+// command line, e.g.
+//
+//	helmrelease-gen --repo https://charts.example.com --version 1.2.3 \
+//	  --name mydb --namespace db -f values.yaml --set auth.enabled=true mychart
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	opts := options{}
+	pflag.StringVar(&opts.repoURL, "repo", "", "chart repository URL, defaults to the stable repository")
+	pflag.StringVar(&opts.version, "version", "", "chart version or semver range, defaults to the latest version")
+	pflag.StringVar(&opts.name, "name", "", "release name, also used as the HelmRelease name. Defaults to the chart name.")
+	pflag.StringVar(&opts.namespace, "namespace", "", "namespace of the HelmRelease and its release")
+	pflag.StringArrayVarP(&opts.valueFiles, "values", "f", nil, "YAML values file, can be repeated")
+	pflag.StringArrayVar(&opts.set, "set", nil, "values on the command line, e.g. a.b=c,d=e. Can be repeated.")
+	pflag.StringArrayVar(&opts.setString, "set-string", nil, "string values on the command line, can be repeated")
+	pflag.Int64Var(&opts.timeout, "timeout", 0, "time in seconds to wait for Tiller operations, defaults to Tiller's default")
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] CHART\n\nPrints the HelmRelease equivalent to helm install.\n\nFlags:\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+	pflag.Parse()
+
+	if pflag.NArg() != 1 {
+		pflag.Usage()
+		os.Exit(2)
+	}
+	opts.chart = pflag.Arg(0)
+
+	data, err := generate(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/releasename"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+)
+
+type plugin struct {
+	helmReleaseClient helmClientset.Interface
+	kubeClient        kubernetes.Interface
+	namespace         string
+	clusterDomain     string
+	out               io.Writer
+	errOut            io.Writer
+}
+
+func (p *plugin) run(args []string, allNamespaces bool) error {
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "list":
+		if err := expectArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+		namespace := p.namespace
+		if allNamespaces {
+			namespace = metav1.NamespaceAll
+		}
+		return p.list(namespace)
+	case "values":
+		if err := expectArgs(cmd, args, 1, 1); err != nil {
+			return err
+		}
+		return p.values(args[0])
+	case "sync":
+		if err := expectArgs(cmd, args, 1, 1); err != nil {
+			return err
+		}
+		return p.sync(args[0], time.Now())
+	case "rollback":
+		if err := expectArgs(cmd, args, 1, 2); err != nil {
+			return err
+		}
+		revision := int32(0)
+		if len(args) == 2 {
+			rev, err := strconv.ParseInt(args[1], 10, 32)
+			if err != nil || rev < 1 {
+				return fmt.Errorf("invalid revision %q", args[1])
+			}
+			revision = int32(rev)
+		}
+		return p.rollback(args[0], revision)
+	case "suspend", "resume":
+		if err := expectArgs(cmd, args, 1, 1); err != nil {
+			return err
+		}
+		return p.setSuspend(args[0], cmd == "suspend")
+	}
+	return fmt.Errorf("unknown command %q, see --help", cmd)
+}
+
+func expectArgs(cmd string, args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("unexpected arguments for %s, see --help", cmd)
+	}
+	return nil
+}
+
+// list writes a table of the HelmReleases of namespace, of all namespaces
+// when empty
+func (p *plugin) list(namespace string) error {
+	list, err := p.helmReleaseClient.HelmV2().HelmReleases(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 8, 2, ' ', 0)
+	if namespace == metav1.NamespaceAll {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tRELEASE\tCHART\tVERSION\tREVISION\tSTATUS\tLAST DEPLOYED")
+	for i := range list.Items {
+		h := &list.Items[i]
+		if namespace == metav1.NamespaceAll {
+			fmt.Fprintf(w, "%s\t", h.Namespace)
+		}
+		lastDeployed := "<none>"
+		if h.Status.LastDeployed != nil {
+			lastDeployed = h.Status.LastDeployed.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", h.Name, releaseName(h), chartName(h),
+			orNone(h.Status.ChartVersion), h.Status.Revision, releaseStatus(h), lastDeployed)
+	}
+	return w.Flush()
+}
+
+// releaseStatus summarizes the state of a HelmRelease in a word
+func releaseStatus(h *helmCrdV2.HelmRelease) string {
+	switch {
+	case h.Annotations[helmCrdV2.IgnoreAnnotation] == "true":
+		return "Ignored"
+	case h.Spec.Suspend:
+		return "Suspended"
+	case hasCondition(h, helmCrdV2.HelmReleaseStalled):
+		return "Stalled"
+	case hasCondition(h, helmCrdV2.HelmReleaseDrifted):
+		return "Drifted"
+	case h.Status.Phase != "":
+		return string(h.Status.Phase)
+	case h.Status.Revision > 0:
+		return "Deployed"
+	}
+	return "Pending"
+}
+
+func hasCondition(h *helmCrdV2.HelmRelease, condType helmCrdV2.HelmReleaseConditionType) bool {
+	for _, cond := range h.Status.Conditions {
+		if cond.Type == condType && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// values writes the values of a HelmRelease as the controller merges them,
+// without the global values of the controller. Values set from object
+// fields, fetched from URLs or encrypted with SOPS are only resolved by the
+// controller and are skipped.
+func (p *plugin) values(name string) error {
+	h, err := p.helmReleaseClient.HelmV2().HelmReleases(p.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	var docs [][]byte
+	for i, src := range h.Spec.ValuesFrom {
+		var doc []byte
+		switch {
+		case src.Sops:
+			fmt.Fprintf(p.errOut, "Skipping valuesFrom[%d]: SOPS encrypted values are only decrypted by the controller\n", i)
+			continue
+		case src.ConfigMapKeyRef != nil:
+			doc, err = valuesUtils.FromConfigMap(p.kubeClient, h.Namespace, src.ConfigMapKeyRef)
+		case src.SecretKeyRef != nil:
+			doc, err = valuesUtils.FromSecret(p.kubeClient, h.Namespace, src.SecretKeyRef)
+		case src.HelmReleaseRef != nil:
+			doc, err = valuesUtils.FromOutputs(p.kubeClient, h.Namespace, src.HelmReleaseRef)
+		case src.FieldRef != nil:
+			fmt.Fprintf(p.errOut, "Skipping valuesFrom[%d]: object fields are only resolved by the controller\n", i)
+			continue
+		case src.URL != "":
+			fmt.Fprintf(p.errOut, "Skipping valuesFrom[%d]: URLs are only fetched by the controller\n", i)
+			continue
+		default:
+			err = fmt.Errorf("no values source set")
+		}
+		if err != nil {
+			return fmt.Errorf("valuesFrom[%d]: %v", i, err)
+		}
+		docs = append(docs, doc)
+	}
+	variables := valuesUtils.Variables(h, releaseName(h), p.clusterDomain)
+	docs = append(docs, []byte(valuesUtils.Substitute(string(h.Spec.Values), variables)))
+	values, err := valuesUtils.MergeStrategy(valuesUtils.Strategy(h.Spec.ValuesMergeStrategy), docs...)
+	if err != nil {
+		return err
+	}
+	_, err = p.out.Write(values)
+	return err
+}
+
+// sync sets the force-sync annotation, which makes the controller
+// reconcile a HelmRelease even when its spec did not change
+func (p *plugin) sync(name string, now time.Time) error {
+	return p.update(name, func(h *helmCrdV2.HelmRelease) {
+		setAnnotation(h, helmCrdV2.ForceSyncAnnotation, now.UTC().Format(time.RFC3339Nano))
+	}, "sync requested")
+}
+
+// rollback suspends a HelmRelease so that later reconciles do not upgrade
+// it again, and asks the controller to roll it back to revision, or to the
+// previous revision when 0
+func (p *plugin) rollback(name string, revision int32) error {
+	return p.update(name, func(h *helmCrdV2.HelmRelease) {
+		h.Spec.Suspend = true
+		setAnnotation(h, helmCrdV2.RollbackRevisionAnnotation, strconv.Itoa(int(revision)))
+	}, "rollback requested, run \"kubectl helmrelease resume "+name+"\" to upgrade it again")
+}
+
+func (p *plugin) setSuspend(name string, suspend bool) error {
+	msg := "resumed"
+	if suspend {
+		msg = "suspended"
+	}
+	return p.update(name, func(h *helmCrdV2.HelmRelease) {
+		h.Spec.Suspend = suspend
+	}, msg)
+}
+
+// update applies mutate to a HelmRelease, retrying on conflicts
+func (p *plugin) update(name string, mutate func(*helmCrdV2.HelmRelease), msg string) error {
+	client := p.helmReleaseClient.HelmV2().HelmReleases(p.namespace)
+	for {
+		h, err := client.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		mutate(h)
+		_, err = client.Update(h)
+		if k8sErrors.IsConflict(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.out, "helmrelease %q %s\n", name, msg)
+		return nil
+	}
+}
+
+func setAnnotation(h *helmCrdV2.HelmRelease, key, value string) {
+	if h.Annotations == nil {
+		h.Annotations = map[string]string{}
+	}
+	h.Annotations[key] = value
+}
+
+func releaseName(h *helmCrdV2.HelmRelease) string {
+	if h.Spec.ReleaseName != "" {
+		return h.Spec.ReleaseName
+	}
+	rname, _ := releasename.Render(h.Spec.ReleaseNameTemplate, h.Namespace, h.Name)
+	return rname
+}
+
+func chartName(h *helmCrdV2.HelmRelease) string {
+	chart := h.Spec.ChartLocation()
+	if repo := chart.Repository; repo != nil {
+		return repo.Name
+	}
+	if tarball := chart.Tarball; tarball != nil {
+		return path.Base(tarball.URL)
+	}
+	if oci := chart.OCI; oci != nil {
+		return path.Base(oci.URL)
+	}
+	if inline := chart.Inline; inline != nil {
+		return inline.Metadata.Name
+	}
+	return "<none>"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	helmCRDFake "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/fake"
+)
+
+func prepareTestPlugin(hrs []helmCrdV2.HelmRelease, kubeObjects ...runtime.Object) (*plugin, *bytes.Buffer) {
+	hrObjects := []runtime.Object{}
+	for i := range hrs {
+		hrObjects = append(hrObjects, &hrs[i])
+	}
+	out := &bytes.Buffer{}
+	return &plugin{
+		helmReleaseClient: helmCRDFake.NewSimpleClientset(hrObjects...),
+		kubeClient:        fake.NewSimpleClientset(kubeObjects...),
+		namespace:         "default",
+		clusterDomain:     "cluster.local",
+		out:               out,
+		errOut:            &bytes.Buffer{},
+	}, out
+}
+
+func repoChart(name string) helmCrdV2.ChartSource {
+	return helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: name}}
+}
+
+func TestList(t *testing.T) {
+	hrs := []helmCrdV2.HelmRelease{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo")},
+			Status:     helmCrdV2.HelmReleaseStatus{ChartVersion: "1.0.0", Revision: 2},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "other"},
+			Spec:       helmCrdV2.HelmReleaseSpec{Chart: repoChart("bar"), Suspend: true},
+		},
+	}
+	p, out := prepareTestPlugin(hrs)
+
+	if err := p.run([]string{"list"}, false); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expecting a header and a release, received %q", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "foo default-foo foo 1.0.0 2 Deployed <none>" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+
+	out.Reset()
+	if err := p.run([]string{"list"}, true); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "NAMESPACE") || !strings.Contains(out.String(), "Suspended") {
+		t.Errorf("Expecting releases of all namespaces, received %q", out.String())
+	}
+}
+
+func TestReleaseStatus(t *testing.T) {
+	tests := []struct {
+		h      helmCrdV2.HelmRelease
+		status string
+	}{
+		{helmCrdV2.HelmRelease{}, "Pending"},
+		{helmCrdV2.HelmRelease{Status: helmCrdV2.HelmReleaseStatus{Revision: 1}}, "Deployed"},
+		{helmCrdV2.HelmRelease{Status: helmCrdV2.HelmReleaseStatus{Revision: 1, Conditions: []helmCrdV2.HelmReleaseCondition{
+			{Type: helmCrdV2.HelmReleaseDrifted, Status: corev1.ConditionTrue},
+		}}}, "Drifted"},
+		{helmCrdV2.HelmRelease{Status: helmCrdV2.HelmReleaseStatus{Conditions: []helmCrdV2.HelmReleaseCondition{
+			{Type: helmCrdV2.HelmReleaseStalled, Status: corev1.ConditionFalse},
+		}}}, "Pending"},
+		{helmCrdV2.HelmRelease{Status: helmCrdV2.HelmReleaseStatus{Conditions: []helmCrdV2.HelmReleaseCondition{
+			{Type: helmCrdV2.HelmReleaseStalled, Status: corev1.ConditionTrue},
+		}}}, "Stalled"},
+		{helmCrdV2.HelmRelease{Spec: helmCrdV2.HelmReleaseSpec{Suspend: true}}, "Suspended"},
+		{helmCrdV2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{helmCrdV2.IgnoreAnnotation: "true"}},
+			Spec: helmCrdV2.HelmReleaseSpec{Suspend: true}}, "Ignored"},
+		{helmCrdV2.HelmRelease{Status: helmCrdV2.HelmReleaseStatus{Revision: 1, Phase: helmCrdV2.PhaseFailed}}, "Failed"},
+	}
+	for _, test := range tests {
+		if status := releaseStatus(&test.h); status != test.status {
+			t.Errorf("Expecting status %s, received %s", test.status, status)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	hrs := []helmCrdV2.HelmRelease{{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: helmCrdV2.HelmReleaseSpec{
+			ValuesFrom: []helmCrdV2.ValuesSource{
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "defaults"},
+					Key:                  "values.yaml",
+				}},
+				{Sops: true, SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "secrets"},
+					Key:                  "values.yaml",
+				}},
+				{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "db", Output: "password", TargetPath: "db.password"}},
+				{URL: "https://config.example.com/values.yaml"},
+			},
+			Values: "host: ${RELEASE_NAME}.${NAMESPACE}\n",
+		},
+	}}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "default"},
+		Data:       map[string]string{"values.yaml": "host: example.com\nport: 80\n"},
+	}
+	outputs := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-outputs", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("sekret")},
+	}
+	p, out := prepareTestPlugin(hrs, cm, outputs)
+
+	if err := p.run([]string{"values", "foo"}, false); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := "db:\n  password: sekret\nhost: default-foo.default\nport: 80\n"; out.String() != expected {
+		t.Errorf("Expecting values %q, received %q", expected, out.String())
+	}
+	errOut := p.errOut.(*bytes.Buffer).String()
+	if !strings.Contains(errOut, "valuesFrom[1]: SOPS") {
+		t.Errorf("Expecting the SOPS source to be reported as skipped, received %q", errOut)
+	}
+	if !strings.Contains(errOut, "valuesFrom[3]: URLs") {
+		t.Errorf("Expecting the URL source to be reported as skipped, received %q", errOut)
+	}
+}
+
+func TestSyncRollbackSuspend(t *testing.T) {
+	hrs := []helmCrdV2.HelmRelease{{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+	}}
+	p, _ := prepareTestPlugin(hrs)
+	get := func() *helmCrdV2.HelmRelease {
+		h, err := p.helmReleaseClient.HelmV2().HelmReleases("default").Get("foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return h
+	}
+
+	now := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := p.sync("foo", now); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if token := get().Annotations[helmCrdV2.ForceSyncAnnotation]; token != "2018-06-01T00:00:00Z" {
+		t.Errorf("Expecting the force-sync annotation to be set, received %q", token)
+	}
+
+	if err := p.run([]string{"rollback", "foo", "3"}, false); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	h := get()
+	if !h.Spec.Suspend || h.Annotations[helmCrdV2.RollbackRevisionAnnotation] != "3" {
+		t.Errorf("Expecting a suspended release rolled back to revision 3, received %+v", h)
+	}
+	if err := p.run([]string{"rollback", "foo", "latest"}, false); err == nil {
+		t.Errorf("Expecting an invalid revision to fail")
+	}
+
+	if err := p.run([]string{"resume", "foo"}, false); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if get().Spec.Suspend {
+		t.Errorf("Expecting the release to be resumed")
+	}
+	if err := p.run([]string{"suspend", "foo"}, false); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !get().Spec.Suspend {
+		t.Errorf("Expecting the release to be suspended")
+	}
+
+	if err := p.run([]string{"sync"}, false); err == nil {
+		t.Errorf("Expecting a missing name to fail")
+	}
+	if err := p.run([]string{"upgrade", "foo"}, false); err == nil {
+		t.Errorf("Expecting an unknown command to fail")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/client-go/rest"
+)
+
+// The kubeconfig loader of client-go is not vendored, so read the subset of
+// the kubeconfig format needed to reach a cluster.
+
+type kubeconfig struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string            `json:"name"`
+		Cluster kubeconfigCluster `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string         `json:"name"`
+		User kubeconfigUser `json:"user"`
+	} `json:"users"`
+	Contexts []struct {
+		Name    string            `json:"name"`
+		Context kubeconfigContext `json:"context"`
+	} `json:"contexts"`
+}
+
+type kubeconfigCluster struct {
+	Server                   string `json:"server"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+	CertificateAuthority     string `json:"certificate-authority"`
+	CertificateAuthorityData string `json:"certificate-authority-data"`
+}
+
+type kubeconfigUser struct {
+	ClientCertificate     string `json:"client-certificate"`
+	ClientCertificateData string `json:"client-certificate-data"`
+	ClientKey             string `json:"client-key"`
+	ClientKeyData         string `json:"client-key-data"`
+	Token                 string `json:"token"`
+	TokenFile             string `json:"tokenFile"`
+	Username              string `json:"username"`
+	Password              string `json:"password"`
+}
+
+type kubeconfigContext struct {
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+}
+
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// loadConfig returns the client configuration and namespace of a kubeconfig
+// context, the current one when empty. The kubeconfig is path, the first
+// file of $KUBECONFIG or ~/.kube/config. Without kubeconfig the in-cluster
+// configuration is used. namespace overrides the namespace of the context.
+func loadConfig(path, context, namespace string) (*rest.Config, string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultKubeconfigPath()
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return inClusterConfig(namespace)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	kc := kubeconfig{}
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, "", fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return kc.restConfig(filepath.Dir(path), context, namespace)
+}
+
+func defaultKubeconfigPath() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 {
+		return paths[0]
+	}
+	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+}
+
+func inClusterConfig(namespace string) (*rest.Config, string, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if namespace == "" {
+		namespace = "default"
+		if data, err := ioutil.ReadFile(serviceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+	return config, namespace, nil
+}
+
+// restConfig returns the client configuration of a context. Relative file
+// paths are resolved against dir.
+func (kc *kubeconfig) restConfig(dir, context, namespace string) (*rest.Config, string, error) {
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	var ctx *kubeconfigContext
+	for i := range kc.Contexts {
+		if kc.Contexts[i].Name == context {
+			ctx = &kc.Contexts[i].Context
+		}
+	}
+	if ctx == nil {
+		return nil, "", fmt.Errorf("context %q not found in kubeconfig", context)
+	}
+	var cluster *kubeconfigCluster
+	for i := range kc.Clusters {
+		if kc.Clusters[i].Name == ctx.Cluster {
+			cluster = &kc.Clusters[i].Cluster
+		}
+	}
+	if cluster == nil {
+		return nil, "", fmt.Errorf("cluster %q not found in kubeconfig", ctx.Cluster)
+	}
+	user := &kubeconfigUser{}
+	for i := range kc.Users {
+		if kc.Users[i].Name == ctx.User {
+			user = &kc.Users[i].User
+		}
+	}
+
+	config := &rest.Config{
+		Host:     cluster.Server,
+		Username: user.Username,
+		Password: user.Password,
+	}
+	config.Insecure = cluster.InsecureSkipTLSVerify
+	config.CAFile = resolvePath(dir, cluster.CertificateAuthority)
+	config.CertFile = resolvePath(dir, user.ClientCertificate)
+	config.KeyFile = resolvePath(dir, user.ClientKey)
+	var err error
+	if config.CAData, err = decodeData(cluster.CertificateAuthorityData); err != nil {
+		return nil, "", fmt.Errorf("certificate-authority-data: %v", err)
+	}
+	if config.CertData, err = decodeData(user.ClientCertificateData); err != nil {
+		return nil, "", fmt.Errorf("client-certificate-data: %v", err)
+	}
+	if config.KeyData, err = decodeData(user.ClientKeyData); err != nil {
+		return nil, "", fmt.Errorf("client-key-data: %v", err)
+	}
+	config.BearerToken = user.Token
+	if user.TokenFile != "" {
+		token, err := ioutil.ReadFile(resolvePath(dir, user.TokenFile))
+		if err != nil {
+			return nil, "", err
+		}
+		config.BearerToken = strings.TrimSpace(string(token))
+	}
+
+	if namespace == "" {
+		namespace = ctx.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return config, namespace, nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func decodeData(data string) ([]byte, error) {
+	if data == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+    certificate-authority: ca.crt
+- name: prod
+  cluster:
+    server: https://prod.example.com
+    certificate-authority-data: Y2E=
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+    namespace: apps
+- name: prod
+  context:
+    cluster: prod
+    user: prod
+users:
+- name: dev
+  user:
+    token: secret
+- name: prod
+  user:
+    client-certificate-data: Y2VydA==
+    client-key: /etc/prod/client.key
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, namespace, err := loadConfig(path, "", "")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if config.Host != "https://dev.example.com" || config.BearerToken != "secret" || config.CAFile != filepath.Join(dir, "ca.crt") {
+		t.Errorf("Unexpected config of the current context %+v", config)
+	}
+	if namespace != "apps" {
+		t.Errorf("Expecting the namespace of the context, received %s", namespace)
+	}
+
+	config, namespace, err = loadConfig(path, "prod", "")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if string(config.CAData) != "ca" || string(config.CertData) != "cert" || config.KeyFile != "/etc/prod/client.key" {
+		t.Errorf("Unexpected config of the prod context %+v", config)
+	}
+	if namespace != "default" {
+		t.Errorf("Expecting the default namespace, received %s", namespace)
+	}
+
+	if _, namespace, _ = loadConfig(path, "", "other"); namespace != "other" {
+		t.Errorf("Expecting the namespace to be overridden, received %s", namespace)
+	}
+	if _, _, err = loadConfig(path, "staging", ""); err == nil {
+		t.Errorf("Expecting an unknown context to fail")
+	}
+	if _, _, err = loadConfig(filepath.Join(dir, "missing"), "", ""); err == nil {
+		t.Errorf("Expecting a missing explicit kubeconfig to fail")
+	}
+}
@@ -0,0 +1,73 @@
+// kubectl-helmrelease is a kubectl plugin managing HelmReleases. Install it
+// on the PATH and run "kubectl helmrelease".
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+
+	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+)
+
+const usage = `Manage HelmReleases
+
+Usage:
+  kubectl helmrelease list [--all-namespaces]  list HelmReleases with their status
+  kubectl helmrelease values NAME             show the values of a HelmRelease
+  kubectl helmrelease sync NAME               force a reconcile of an unchanged HelmRelease
+  kubectl helmrelease rollback NAME [REV]     suspend a HelmRelease and roll it back to a revision, the previous one by default
+  kubectl helmrelease suspend NAME            stop installing and upgrading a HelmRelease
+  kubectl helmrelease resume NAME             resume reconciling a suspended HelmRelease
+
+Flags:
+`
+
+func main() {
+	flags := pflag.NewFlagSet("kubectl-helmrelease", pflag.ExitOnError)
+	kubeconfig := flags.String("kubeconfig", "", "path to the kubeconfig file, defaults to $KUBECONFIG or ~/.kube/config")
+	context := flags.String("context", "", "kubeconfig context to use")
+	namespace := flags.StringP("namespace", "n", "", "namespace of the HelmReleases")
+	allNamespaces := flags.BoolP("all-namespaces", "A", false, "list HelmReleases of all namespaces")
+	clusterDomain := flags.String("cluster-domain", "cluster.local", "cluster DNS domain substituted for ${CLUSTER_DOMAIN} in values")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if err := run(flags.Args(), *kubeconfig, *context, *namespace, *allNamespaces, *clusterDomain); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, kubeconfig, context, namespace string, allNamespaces bool, clusterDomain string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command, see --help")
+	}
+	config, namespace, err := loadConfig(kubeconfig, context, namespace)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clientset, err := helmClientset.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	p := &plugin{
+		helmReleaseClient: clientset,
+		kubeClient:        kubeClient,
+		namespace:         namespace,
+		clusterDomain:     clusterDomain,
+		out:               os.Stdout,
+		errOut:            os.Stderr,
+	}
+	return p.run(args, allNamespaces)
+}
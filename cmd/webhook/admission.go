@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The admission.k8s.io/v1beta1 types are not available in the vendored
+// k8s.io/api, so the subset of the wire format we need is declared here.
+
+type admissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *admissionRequest  `json:"request,omitempty"`
+	Response        *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       string          `json:"uid"`
+	Namespace string          `json:"namespace,omitempty"`
+	Operation string          `json:"operation"`
+	Object    json.RawMessage `json:"object,omitempty"`
+	OldObject json.RawMessage `json:"oldObject,omitempty"`
+}
+
+type admissionResponse struct {
+	UID       string         `json:"uid"`
+	Allowed   bool           `json:"allowed"`
+	Result    *metav1.Status `json:"status,omitempty"`
+	Patch     []byte         `json:"patch,omitempty"`
+	PatchType *string        `json:"patchType,omitempty"`
+}
+
+var jsonPatchType = "JSONPatch"
+
+// admitFunc decides on a single admission request
+type admitFunc func(req *admissionRequest) *admissionResponse
+
+func denied(err error) *admissionResponse {
+	return &admissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: err.Error(),
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
+
+func allowed() *admissionResponse {
+	return &admissionResponse{Allowed: true}
+}
+
+// serveAdmission returns an http.Handler decoding an AdmissionReview,
+// passing its request to admit and writing back the response
+func serveAdmission(admit admitFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			http.Error(w, fmt.Sprintf("unexpected content type %q", ct), http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := admissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("unable to decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		res := admit(review.Request)
+		res.UID = review.Request.UID
+		review.Request = nil
+		review.Response = res
+
+		data, err := json.Marshal(review)
+		if err != nil {
+			logger.With("error", err).Errorf("Unable to encode admission response")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
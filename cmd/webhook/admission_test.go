@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	helmCRDFake "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	v1TypeMeta = metav1.TypeMeta{APIVersion: "helm.bitnami.com/v1", Kind: "HelmRelease"}
+	v2TypeMeta = metav1.TypeMeta{APIVersion: "helm.bitnami.com/v2", Kind: "HelmRelease"}
+)
+
+func repoChart(name, version string) helmCrdV2.ChartSource {
+	return helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: name, Version: version}}
+}
+
+func reviewRequest(t *testing.T, h interface{}, operation string) *http.Request {
+	obj, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	review := admissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request:  &admissionRequest{UID: "1234", Namespace: "myns", Operation: operation, Object: obj},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	req := httptest.NewRequest("POST", "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func doReview(t *testing.T, handler http.Handler, req *http.Request) *admissionResponse {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code %d: %s", w.Code, w.Body.String())
+	}
+	review := admissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if review.Response == nil {
+		t.Fatalf("Missing admission response")
+	}
+	if review.Response.UID != "1234" {
+		t.Errorf("Expected response UID 1234 received %s", review.Response.UID)
+	}
+	return review.Response
+}
+
+func TestValidateHelmRelease(t *testing.T) {
+	objMeta := metav1.ObjectMeta{Namespace: "myns", Name: "foo"}
+	tests := []struct {
+		name      string
+		spec      helmCrdV2.HelmReleaseSpec
+		operation string
+		allowed   bool
+	}{
+		{"valid", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "1.0.0")}, "CREATE", true},
+		{"invalid values", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", ""), Values: "foo: [bar"}, "CREATE", false},
+		{"invalid version on update", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "latest")}, "UPDATE", false},
+		{"invalid object on delete", helmCrdV2.HelmReleaseSpec{}, "DELETE", true},
+	}
+	handler := serveAdmission((&validator{}).validateHelmRelease)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &helmCrdV2.HelmRelease{TypeMeta: v2TypeMeta, ObjectMeta: objMeta, Spec: tt.spec}
+			res := doReview(t, handler, reviewRequest(t, h, tt.operation))
+			if res.Allowed != tt.allowed {
+				t.Errorf("Expected allowed to be %v received %v (%v)", tt.allowed, res.Allowed, res.Result)
+			}
+		})
+	}
+}
+
+func TestValidateHelmReleasePolicies(t *testing.T) {
+	v := &validator{clientset: helmCRDFake.NewSimpleClientset(&helmCrdV2.HelmReleasePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "charts"},
+		Spec: helmCrdV2.HelmReleasePolicySpec{
+			Charts:           []helmCrdV2.ChartRule{{Name: "foo", Versions: "^1.0.0"}},
+			TargetNamespaces: []string{"myns"},
+		},
+	})}
+	tests := []struct {
+		name    string
+		spec    helmCrdV2.HelmReleaseSpec
+		allowed bool
+	}{
+		{"allowed", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "1.0.0")}, true},
+		{"version range", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", ">1.0.0")}, true},
+		{"version", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "2.0.0")}, false},
+		{"chart", helmCrdV2.HelmReleaseSpec{Chart: repoChart("bar", "1.0.0")}, false},
+		{"target namespace", helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "1.0.0"), TargetNamespace: "kube-system"}, false},
+	}
+	handler := serveAdmission(v.validateHelmRelease)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &helmCrdV2.HelmRelease{TypeMeta: v2TypeMeta, ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"}, Spec: tt.spec}
+			res := doReview(t, handler, reviewRequest(t, h, "CREATE"))
+			if res.Allowed != tt.allowed {
+				t.Errorf("Expected allowed to be %v received %v (%v)", tt.allowed, res.Allowed, res.Result)
+			}
+		})
+	}
+}
+
+func TestValidateHelmReleasePolicy(t *testing.T) {
+	handler := serveAdmission(validateHelmReleasePolicy)
+	p := &helmCrdV2.HelmReleasePolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "charts"},
+		Spec:       helmCrdV2.HelmReleasePolicySpec{Charts: []helmCrdV2.ChartRule{{Name: "foo", Versions: "latest"}}},
+	}
+	if res := doReview(t, handler, reviewRequest(t, p, "CREATE")); res.Allowed {
+		t.Errorf("Expected an invalid version range to be denied")
+	}
+	p.Spec.Charts[0].Versions = "^1.0.0"
+	if res := doReview(t, handler, reviewRequest(t, p, "CREATE")); !res.Allowed {
+		t.Errorf("Expected policy to be allowed, received %v", res.Result)
+	}
+}
+
+func TestValidateHelmReleaseSet(t *testing.T) {
+	handler := serveAdmission(validateHelmReleaseSet)
+	set := &helmCrdV2.HelmReleaseSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "wordpress"},
+		Spec: helmCrdV2.HelmReleaseSetSpec{
+			Template: helmCrdV2.HelmReleaseTemplate{Spec: helmCrdV2.HelmReleaseSpec{Chart: repoChart("wordpress", "")}},
+			Items:    []helmCrdV2.HelmReleaseSetItem{{Name: "Tenant_A"}},
+		},
+	}
+	if res := doReview(t, handler, reviewRequest(t, set, "CREATE")); res.Allowed {
+		t.Errorf("Expected an invalid item name to be denied")
+	}
+	set.Spec.Items[0].Name = "tenant-a"
+	if res := doReview(t, handler, reviewRequest(t, set, "CREATE")); !res.Allowed {
+		t.Errorf("Expected set to be allowed, received %v", res.Result)
+	}
+}
+
+func TestValidateRepositoryCredential(t *testing.T) {
+	handler := serveAdmission(validateRepositoryCredential)
+	cred := &helmCrdV2.RepositoryCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "private-charts"},
+		Spec:       helmCrdV2.RepositoryCredentialSpec{URLPrefixes: []string{"https://charts.example.com/private/"}},
+	}
+	if res := doReview(t, handler, reviewRequest(t, cred, "CREATE")); res.Allowed {
+		t.Errorf("Expected a credential without auth to be denied")
+	}
+	cred.Spec.Auth.Provider = helmCrdV2.AuthProviderGCP
+	if res := doReview(t, handler, reviewRequest(t, cred, "CREATE")); !res.Allowed {
+		t.Errorf("Expected credential to be allowed, received %v", res.Result)
+	}
+}
+
+func TestValidateHelmReleaseV1(t *testing.T) {
+	handler := serveAdmission((&validator{}).validateHelmRelease)
+	h := &helmCrdV1.HelmRelease{
+		TypeMeta:   v1TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV1.HelmReleaseSpec{ChartName: "foo", Version: "latest"},
+	}
+	res := doReview(t, handler, reviewRequest(t, h, "CREATE"))
+	if res.Allowed {
+		t.Fatalf("Expected v1 object to be denied")
+	}
+	if !strings.Contains(res.Result.Message, "spec.chart.repository.version") {
+		t.Errorf("Unexpected message %q", res.Result.Message)
+	}
+}
+
+func TestServeAdmissionBadRequest(t *testing.T) {
+	handler := serveAdmission((&validator{}).validateHelmRelease)
+	req := httptest.NewRequest("POST", "/validate", bytes.NewReader([]byte("{}")))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d received %d", http.StatusBadRequest, w.Code)
+	}
+}
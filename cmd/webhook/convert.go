@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// The apiextensions.k8s.io/v1beta1 ConversionReview types are not available
+// in the vendored k8s.io/api either, so declare the subset we need.
+
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               string            `json:"uid"`
+	DesiredAPIVersion string            `json:"desiredAPIVersion"`
+	Objects           []json.RawMessage `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              string            `json:"uid"`
+	ConvertedObjects []json.RawMessage `json:"convertedObjects"`
+	Result           metav1.Status     `json:"result"`
+}
+
+// decodeHelmRelease decodes a HelmRelease of any served version into the
+// v2 representation, returning the apiVersion it was received as
+func decodeHelmRelease(data []byte) (*helmCrdV2.HelmRelease, string, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, "", err
+	}
+
+	helmObj := &helmCrdV2.HelmRelease{}
+	switch typeMeta.APIVersion {
+	case helmCrdV1.SchemeGroupVersion.String():
+		v1Obj := &helmCrdV1.HelmRelease{}
+		if err := json.Unmarshal(data, v1Obj); err != nil {
+			return nil, "", err
+		}
+		if err := helmCrdV1.Convert_v1_HelmRelease_To_v2_HelmRelease(v1Obj, helmObj); err != nil {
+			return nil, "", err
+		}
+	case helmCrdV2.SchemeGroupVersion.String():
+		if err := json.Unmarshal(data, helmObj); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", fmt.Errorf("unsupported apiVersion %q", typeMeta.APIVersion)
+	}
+	return helmObj, typeMeta.APIVersion, nil
+}
+
+// convertHelmRelease converts a v2 HelmRelease to the given apiVersion
+func convertHelmRelease(helmObj *helmCrdV2.HelmRelease, apiVersion string) (interface{}, error) {
+	switch apiVersion {
+	case helmCrdV1.SchemeGroupVersion.String():
+		v1Obj := &helmCrdV1.HelmRelease{}
+		if err := helmCrdV1.Convert_v2_HelmRelease_To_v1_HelmRelease(helmObj, v1Obj); err != nil {
+			return nil, err
+		}
+		return v1Obj, nil
+	case helmCrdV2.SchemeGroupVersion.String():
+		return helmObj, nil
+	}
+	return nil, fmt.Errorf("unsupported apiVersion %q", apiVersion)
+}
+
+func convertObjects(req *conversionRequest) ([]json.RawMessage, error) {
+	converted := make([]json.RawMessage, 0, len(req.Objects))
+	for _, obj := range req.Objects {
+		helmObj, _, err := decodeHelmRelease(obj)
+		if err != nil {
+			return nil, err
+		}
+		out, err := convertHelmRelease(helmObj, req.DesiredAPIVersion)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, data)
+	}
+	return converted, nil
+}
+
+// serveConversion returns an http.Handler converting the HelmReleases of a
+// ConversionReview to the desired apiVersion
+func serveConversion() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			http.Error(w, fmt.Sprintf("unexpected content type %q", ct), http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := conversionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("unable to decode conversion review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		res := &conversionResponse{UID: review.Request.UID}
+		converted, err := convertObjects(review.Request)
+		if err != nil {
+			logger.With("apiVersion", review.Request.DesiredAPIVersion, "error", err).Errorf("Unable to convert HelmReleases")
+			res.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+		} else {
+			res.ConvertedObjects = converted
+			res.Result = metav1.Status{Status: metav1.StatusSuccess}
+		}
+		review.Request = nil
+		review.Response = res
+
+		data, err := json.Marshal(review)
+		if err != nil {
+			logger.With("error", err).Errorf("Unable to encode conversion response")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func doConversion(t *testing.T, desiredAPIVersion string, objs ...interface{}) *conversionResponse {
+	req := &conversionRequest{UID: "1234", DesiredAPIVersion: desiredAPIVersion}
+	for _, obj := range objs {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		req.Objects = append(req.Objects, data)
+	}
+	body, err := json.Marshal(conversionReview{Request: req})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	r := httptest.NewRequest("POST", "/convert", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	serveConversion().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code %d: %s", w.Code, w.Body.String())
+	}
+	review := conversionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if review.Response == nil || review.Response.UID != "1234" {
+		t.Fatalf("Unexpected conversion response %s", w.Body.String())
+	}
+	return review.Response
+}
+
+func TestConvertV1ToV2(t *testing.T) {
+	h := &helmCrdV1.HelmRelease{
+		TypeMeta:   v1TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV1.HelmReleaseSpec{ChartName: "foo", RepoURL: "https://charts.example.com", Version: "1.0.0"},
+	}
+	res := doConversion(t, "helm.bitnami.com/v2", h)
+	if res.Result.Status != metav1.StatusSuccess || len(res.ConvertedObjects) != 1 {
+		t.Fatalf("Unexpected conversion response %+v", res)
+	}
+	converted := &helmCrdV2.HelmRelease{}
+	if err := json.Unmarshal(res.ConvertedObjects[0], converted); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if converted.APIVersion != "helm.bitnami.com/v2" || converted.Name != "foo" {
+		t.Errorf("Unexpected object metadata %+v %+v", converted.TypeMeta, converted.ObjectMeta)
+	}
+	repo := converted.Spec.Chart.Repository
+	if repo == nil || repo.Name != "foo" || repo.URL != "https://charts.example.com" || repo.Version != "1.0.0" {
+		t.Errorf("Unexpected chart source %+v", repo)
+	}
+}
+
+func TestConvertV2ToV1(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{
+		TypeMeta:   v2TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "1.0.0"), TargetNamespace: "other"},
+	}
+	res := doConversion(t, "helm.bitnami.com/v1", h)
+	if res.Result.Status != metav1.StatusSuccess || len(res.ConvertedObjects) != 1 {
+		t.Fatalf("Unexpected conversion response %+v", res)
+	}
+	converted := &helmCrdV1.HelmRelease{}
+	if err := json.Unmarshal(res.ConvertedObjects[0], converted); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if converted.APIVersion != "helm.bitnami.com/v1" || converted.Spec.ChartName != "foo" || converted.Spec.Version != "1.0.0" {
+		t.Errorf("Unexpected object %+v", converted)
+	}
+	if _, ok := converted.Annotations[helmCrdV1.V2Annotation]; !ok {
+		t.Errorf("Expected v2 only fields to be kept in an annotation")
+	}
+}
+
+func TestConvertUnsupportedVersion(t *testing.T) {
+	h := &helmCrdV2.HelmRelease{TypeMeta: v2TypeMeta, ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	res := doConversion(t, "helm.bitnami.com/v3", h)
+	if res.Result.Status != metav1.StatusFailure {
+		t.Errorf("Expected conversion to fail received %+v", res.Result)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+
+	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/logging"
+)
+
+var (
+	listenAddr     string
+	tlsCertFile    string
+	tlsKeyFile     string
+	defaultRepoURL string
+	defaultTimeout int64
+	logLevel       string
+	logFormat      string
+
+	logger = logging.New(os.Stderr, logging.Info, logging.TextFormat)
+)
+
+func init() {
+	pflag.StringVar(&listenAddr, "listen", ":8443", "address to serve the admission and conversion webhooks on")
+	pflag.StringVar(&tlsCertFile, "tls-cert-file", "/etc/webhook/certs/tls.crt", "x509 certificate for HTTPS")
+	pflag.StringVar(&tlsKeyFile, "tls-private-key-file", "/etc/webhook/certs/tls.key", "x509 private key matching --tls-cert-file")
+	pflag.StringVar(&defaultRepoURL, "default-repo-url", envOrDefault("DEFAULT_REPO_URL", "https://kubernetes-charts.storage.googleapis.com"), "repository URL set on HelmReleases without one, defaults to $DEFAULT_REPO_URL if set")
+	pflag.Int64Var(&defaultTimeout, "default-timeout", 300, "Tiller operation timeout in seconds set on HelmReleases without one")
+	pflag.StringVar(&logLevel, "log-level", "info", "minimum level of logged messages: debug, info, warn or error")
+	pflag.StringVar(&logFormat, "log-format", "text", "log format: text or json")
+}
+
+func main() {
+	pflag.Parse()
+
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		panic(err.Error())
+	}
+	format, err := logging.ParseFormat(logFormat)
+	if err != nil {
+		panic(err.Error())
+	}
+	logger = logging.New(os.Stderr, level, format)
+
+	mux := http.NewServeMux()
+	v := &validator{}
+	if config, err := rest.InClusterConfig(); err != nil {
+		logger.With("error", err).Warnf("Not running in a cluster, HelmReleasePolicies are not checked")
+	} else {
+		v.clientset, err = helmClientset.NewForConfig(config)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+	mux.Handle("/validate", serveAdmission(v.validateHelmRelease))
+	mux.Handle("/validate-policy", serveAdmission(validateHelmReleasePolicy))
+	mux.Handle("/validate-set", serveAdmission(validateHelmReleaseSet))
+	mux.Handle("/validate-credential", serveAdmission(validateRepositoryCredential))
+	d := &defaulter{repoURL: defaultRepoURL, timeout: defaultTimeout}
+	mux.Handle("/mutate", serveAdmission(d.mutateHelmRelease))
+	mux.Handle("/convert", serveConversion())
+
+	logger.With("address", listenAddr).Infof("Serving webhooks")
+	server := &http.Server{
+		Addr:    listenAddr,
+		Handler: mux,
+	}
+	if err := server.ListenAndServeTLS(tlsCertFile, tlsKeyFile); err != nil {
+		panic(err.Error())
+	}
+}
+
+// envOrDefault returns the value of the environment variable name, or
+// value if it is not set
+func envOrDefault(name, value string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return value
+}
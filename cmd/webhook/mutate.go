@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/releasename"
+)
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// defaulter fills in unset HelmRelease fields so that the defaults
+// applied by the controller are visible on the stored object
+type defaulter struct {
+	repoURL string
+	timeout int64
+}
+
+func (d *defaulter) setDefaults(h *helmCrdV2.HelmRelease, namespace string) {
+	spec := &h.Spec
+
+	if repo := spec.Chart.Repository; repo != nil {
+		repo.URL = strings.TrimSpace(repo.URL)
+		if repo.URL == "" {
+			repo.URL = d.repoURL
+		}
+	}
+	if tarball := spec.Chart.Tarball; tarball != nil {
+		tarball.URL = strings.TrimSpace(tarball.URL)
+	}
+	if oci := spec.Chart.OCI; oci != nil {
+		oci.URL = strings.TrimSpace(oci.URL)
+	}
+	spec.ChartURL = strings.TrimSpace(spec.ChartURL)
+
+	spec.ReleaseName = strings.ToLower(strings.TrimSpace(spec.ReleaseName))
+	if h.Namespace != "" {
+		namespace = h.Namespace
+	}
+	// Objects created with generateName have no name yet, leave the
+	// release name to be computed by the controller
+	if spec.ReleaseName == "" && h.Name != "" {
+		// Must match getReleaseName in the controller. Invalid templates
+		// are left to be rejected by validation.
+		if rname, err := releasename.Render(spec.ReleaseNameTemplate, namespace, h.Name); err == nil {
+			spec.ReleaseName = rname
+		}
+	}
+
+	if spec.Timeout == 0 {
+		spec.Timeout = d.timeout
+	}
+}
+
+// mutateHelmRelease returns a JSON patch with the defaulted spec of
+// created HelmReleases. Updates are left alone: defaults changed since
+// must not be applied to existing releases, nor their release names be
+// rewritten.
+func (d *defaulter) mutateHelmRelease(req *admissionRequest) *admissionResponse {
+	if req.Operation != "CREATE" {
+		return allowed()
+	}
+
+	helmObj, apiVersion, err := decodeHelmRelease(req.Object)
+	if err != nil {
+		return denied(fmt.Errorf("unable to decode HelmRelease: %v", err))
+	}
+
+	defaulted := helmObj.DeepCopy()
+	d.setDefaults(defaulted, req.Namespace)
+	if apiequality.Semantic.DeepEqual(helmObj.Spec, defaulted.Spec) {
+		return allowed()
+	}
+
+	// Patch the spec in the version it was received as
+	out, err := convertHelmRelease(defaulted, apiVersion)
+	if err != nil {
+		return denied(err)
+	}
+	var spec interface{}
+	switch o := out.(type) {
+	case *helmCrdV1.HelmRelease:
+		spec = o.Spec
+	case *helmCrdV2.HelmRelease:
+		spec = o.Spec
+	}
+	patch, err := json.Marshal([]jsonPatchOperation{
+		{Op: "add", Path: "/spec", Value: spec},
+	})
+	if err != nil {
+		return denied(err)
+	}
+	res := allowed()
+	res.Patch = patch
+	res.PatchType = &jsonPatchType
+	return res
+}
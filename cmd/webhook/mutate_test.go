@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func repoChartURL(name, url string) helmCrdV2.ChartSource {
+	return helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: name, URL: url}}
+}
+
+func TestSetDefaults(t *testing.T) {
+	d := &defaulter{repoURL: "https://charts.example.com", timeout: 300}
+	tests := []struct {
+		name     string
+		objMeta  metav1.ObjectMeta
+		spec     helmCrdV2.HelmReleaseSpec
+		expected helmCrdV2.HelmReleaseSpec
+	}{
+		{
+			"empty spec",
+			metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "")},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "https://charts.example.com"), ReleaseName: "myns-foo", Timeout: 300},
+		},
+		{
+			"normalized fields",
+			metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", " http://other.example.com/ "), ReleaseName: "MyRelease", Timeout: 60},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "http://other.example.com/"), ReleaseName: "myrelease", Timeout: 60},
+		},
+		{
+			"release name template",
+			metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", ""), ReleaseNameTemplate: "{{ .Name }}-prod"},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "https://charts.example.com"), ReleaseNameTemplate: "{{ .Name }}-prod", ReleaseName: "foo-prod", Timeout: 300},
+		},
+		{
+			"generated name",
+			metav1.ObjectMeta{Namespace: "myns", GenerateName: "foo-"},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "")},
+			helmCrdV2.HelmReleaseSpec{Chart: repoChartURL("foo", "https://charts.example.com"), Timeout: 300},
+		},
+		{
+			"no chart source",
+			metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+			helmCrdV2.HelmReleaseSpec{},
+			helmCrdV2.HelmReleaseSpec{ReleaseName: "myns-foo", Timeout: 300},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &helmCrdV2.HelmRelease{ObjectMeta: tt.objMeta, Spec: tt.spec}
+			d.setDefaults(h, "")
+			if !apiequality.Semantic.DeepEqual(h.Spec, tt.expected) {
+				t.Errorf("Expecting %+v received %+v", tt.expected, h.Spec)
+			}
+		})
+	}
+}
+
+func TestMutateHelmRelease(t *testing.T) {
+	d := &defaulter{repoURL: "https://charts.example.com", timeout: 300}
+	handler := serveAdmission(d.mutateHelmRelease)
+
+	h := &helmCrdV2.HelmRelease{
+		TypeMeta:   v2TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV2.HelmReleaseSpec{Chart: repoChart("foo", "")},
+	}
+	res := doReview(t, handler, reviewRequest(t, h, "CREATE"))
+	if !res.Allowed {
+		t.Fatalf("Expected object to be allowed")
+	}
+	if res.PatchType == nil || *res.PatchType != "JSONPatch" {
+		t.Errorf("Expected JSONPatch patch type")
+	}
+	patch := []jsonPatchOperation{}
+	if err := json.Unmarshal(res.Patch, &patch); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(patch) != 1 || patch[0].Path != "/spec" {
+		t.Errorf("Unexpected patch %s", res.Patch)
+	}
+
+	// Updated objects are not defaulted
+	res = doReview(t, handler, reviewRequest(t, h, "UPDATE"))
+	if !res.Allowed || len(res.Patch) != 0 {
+		t.Errorf("Expected updates not to be patched received %s", res.Patch)
+	}
+
+	// Already defaulted objects are not patched
+	d.setDefaults(h, "")
+	res = doReview(t, handler, reviewRequest(t, h, "CREATE"))
+	if !res.Allowed || len(res.Patch) != 0 {
+		t.Errorf("Expected no patch received %s", res.Patch)
+	}
+}
+
+func TestMutateHelmReleaseV1(t *testing.T) {
+	d := &defaulter{repoURL: "https://charts.example.com", timeout: 300}
+	handler := serveAdmission(d.mutateHelmRelease)
+
+	h := &helmCrdV1.HelmRelease{
+		TypeMeta:   v1TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec:       helmCrdV1.HelmReleaseSpec{ChartName: "foo"},
+	}
+	res := doReview(t, handler, reviewRequest(t, h, "CREATE"))
+	if !res.Allowed {
+		t.Fatalf("Expected object to be allowed")
+	}
+	patch := []struct {
+		Path  string                    `json:"path"`
+		Value helmCrdV1.HelmReleaseSpec `json:"value"`
+	}{}
+	if err := json.Unmarshal(res.Patch, &patch); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := helmCrdV1.HelmReleaseSpec{ChartName: "foo", RepoURL: "https://charts.example.com", ReleaseName: "myns-foo", Timeout: 300}
+	if len(patch) != 1 || !apiequality.Semantic.DeepEqual(patch[0].Value, expected) {
+		t.Errorf("Expecting v1 spec %+v received %s", expected, res.Patch)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/Masterminds/semver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	helmClientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/policy"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/validation"
+)
+
+// validator checks HelmReleases on admission
+type validator struct {
+	// clientset lists the HelmReleasePolicies of namespaces, policies are
+	// not checked if nil
+	clientset helmClientset.Interface
+}
+
+// validateHelmRelease rejects HelmReleases that would fail on every
+// reconcile, or that a HelmReleasePolicy of their namespace does not allow
+func (v *validator) validateHelmRelease(req *admissionRequest) *admissionResponse {
+	if req.Operation == "DELETE" {
+		return allowed()
+	}
+
+	helmObj, _, err := decodeHelmRelease(req.Object)
+	if err != nil {
+		return denied(fmt.Errorf("unable to decode HelmRelease: %v", err))
+	}
+
+	if errs := validation.ValidateHelmRelease(helmObj); len(errs) > 0 {
+		logger.With("namespace", req.Namespace, "name", helmObj.Name, "error", errs.ToAggregate()).Infof("Rejecting HelmRelease")
+		return denied(errs.ToAggregate())
+	}
+	if err := v.checkPolicies(helmObj, req.Namespace); err != nil {
+		logger.With("namespace", req.Namespace, "name", helmObj.Name, "error", err).Infof("Rejecting HelmRelease")
+		return denied(err)
+	}
+	return allowed()
+}
+
+// checkPolicies returns an error unless every HelmReleasePolicy of
+// namespace allows h. Chart versions are only checked when exact, and the
+// charts of tarballs are not known until downloaded, so the controller
+// checks policies again on reconcile.
+func (v *validator) checkPolicies(h *helmCrdV2.HelmRelease, namespace string) error {
+	if v.clientset == nil {
+		return nil
+	}
+	policies, err := v.clientset.HelmV2().HelmReleasePolicies(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to list HelmReleasePolicies: %v", err)
+	}
+
+	rel := policy.Release{TargetNamespace: h.Spec.TargetNamespace}
+	if rel.TargetNamespace == "" {
+		rel.TargetNamespace = namespace
+	}
+	chart := h.Spec.ChartLocation()
+	if tarball := chart.Tarball; tarball != nil {
+		rel.RepoURLs = append(rel.RepoURLs, tarball.URL)
+	}
+	if inline := chart.Inline; inline != nil {
+		rel.Inline = true
+		rel.ChartName = inline.Metadata.Name
+		rel.ChartVersion = inline.Metadata.Version
+	}
+	if oci := chart.OCI; oci != nil {
+		rel.RepoURLs = append(rel.RepoURLs, oci.URL)
+		rel.ChartName = path.Base(oci.URL)
+		rel.ChartVersion = oci.Version
+	}
+	if repo := chart.Repository; repo != nil {
+		if repo.URL != "" {
+			rel.RepoURLs = append(rel.RepoURLs, repo.URL)
+		}
+		rel.RepoURLs = append(rel.RepoURLs, repo.Mirrors...)
+		rel.ChartName = repo.Name
+		if _, err := semver.NewVersion(repo.Version); err == nil {
+			rel.ChartVersion = repo.Version
+		}
+	}
+	for _, p := range policies.Items {
+		if err := policy.CheckRelease(&p.Spec, rel); err != nil {
+			return fmt.Errorf("HelmReleasePolicy %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateHelmReleasePolicy rejects HelmReleasePolicies that would reject
+// every HelmRelease
+func validateHelmReleasePolicy(req *admissionRequest) *admissionResponse {
+	if req.Operation == "DELETE" {
+		return allowed()
+	}
+
+	p := &helmCrdV2.HelmReleasePolicy{}
+	if err := json.Unmarshal(req.Object, p); err != nil {
+		return denied(fmt.Errorf("unable to decode HelmReleasePolicy: %v", err))
+	}
+	if errs := validation.ValidateHelmReleasePolicy(p); len(errs) > 0 {
+		logger.With("namespace", req.Namespace, "name", p.Name, "error", errs.ToAggregate()).Infof("Rejecting HelmReleasePolicy")
+		return denied(errs.ToAggregate())
+	}
+	return allowed()
+}
+
+// validateHelmReleaseSet rejects HelmReleaseSets whose HelmReleases would
+// be rejected
+func validateHelmReleaseSet(req *admissionRequest) *admissionResponse {
+	if req.Operation == "DELETE" {
+		return allowed()
+	}
+
+	set := &helmCrdV2.HelmReleaseSet{}
+	if err := json.Unmarshal(req.Object, set); err != nil {
+		return denied(fmt.Errorf("unable to decode HelmReleaseSet: %v", err))
+	}
+	if errs := validation.ValidateHelmReleaseSet(set); len(errs) > 0 {
+		logger.With("namespace", req.Namespace, "name", set.Name, "error", errs.ToAggregate()).Infof("Rejecting HelmReleaseSet")
+		return denied(errs.ToAggregate())
+	}
+	return allowed()
+}
+
+// validateRepositoryCredential rejects RepositoryCredentials that would
+// authenticate nothing
+func validateRepositoryCredential(req *admissionRequest) *admissionResponse {
+	if req.Operation == "DELETE" {
+		return allowed()
+	}
+
+	cred := &helmCrdV2.RepositoryCredential{}
+	if err := json.Unmarshal(req.Object, cred); err != nil {
+		return denied(fmt.Errorf("unable to decode RepositoryCredential: %v", err))
+	}
+	if errs := validation.ValidateRepositoryCredential(cred); len(errs) > 0 {
+		logger.With("namespace", req.Namespace, "name", cred.Name, "error", errs.ToAggregate()).Infof("Rejecting RepositoryCredential")
+		return denied(errs.ToAggregate())
+	}
+	return allowed()
+}
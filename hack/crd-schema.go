@@ -0,0 +1,230 @@
+//go:build ignore
+// +build ignore
+
+// crd-schema prints the OpenAPI v3 validation schema of a HelmRelease
+// CRD version, or of the HelmReleasePolicy, HelmReleaseSet or
+// RepositoryCredential CRD, derived from the Go types plus the constraints
+// below.
+//
+// Usage: go run hack/crd-schema.go v2 > deploy/helmrelease-v2-schema.json
+// or: go run hack/crd-schema.go policy > deploy/helmreleasepolicy-schema.json
+// or: go run hack/crd-schema.go set > deploy/helmreleaseset-schema.json
+// or: go run hack/crd-schema.go credential > deploy/repositorycredential-schema.json
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	helmCrdV1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/openapi"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/validation"
+)
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func float64Ptr(f float64) *float64 { return &f }
+
+const (
+	releaseNamePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	namespacePattern   = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// Exact versions or semver ranges, see github.com/Masterminds/semver
+	versionPattern = `^[0-9A-Za-z.*^~<>=!|, +-]+$`
+	repoURLPattern = `^https?://`
+	// RepositoryCredentials also match OCI chart URLs
+	urlPrefixPattern = `^(https?|oci)://[^/]+`
+)
+
+func v1Spec() *openapi.Schema {
+	spec := openapi.SchemaFor(reflect.TypeOf(helmCrdV1.HelmReleaseSpec{}))
+
+	spec.Require("chartName")
+	spec.Property("chartName").MinLength = int64Ptr(1)
+
+	repoURL := spec.Property("repoUrl")
+	repoURL.Format = "uri"
+	repoURL.Pattern = repoURLPattern
+
+	releaseName := spec.Property("releaseName")
+	releaseName.MaxLength = int64Ptr(validation.MaxReleaseNameLen)
+	releaseName.Pattern = releaseNamePattern
+
+	spec.Property("version").Pattern = versionPattern
+
+	spec.Property("timeout").Minimum = float64Ptr(0)
+
+	// Only one authentication type may be given
+	spec.Property("auth").MaxProperties = int64Ptr(1)
+	return spec
+}
+
+func v2Spec() *openapi.Schema {
+	spec := openapi.SchemaFor(reflect.TypeOf(helmCrdV2.HelmReleaseSpec{}))
+
+	// Only one chart source may be given, none with chartURL
+	spec.Property("chart").MaxProperties = int64Ptr(1)
+
+	chartURL := spec.Property("chartURL")
+	chartURL.Format = "uri"
+	chartURL.Pattern = repoURLPattern
+
+	repo := spec.Property("chart.repository")
+	repo.Property("name").MinLength = int64Ptr(1)
+	repo.Property("url").Format = "uri"
+	repo.Property("url").Pattern = repoURLPattern
+	repo.Property("version").Pattern = versionPattern
+
+	inline := spec.Property("chart.inline")
+	inline.Property("metadata.name").MinLength = int64Ptr(1)
+	inline.Property("metadata.version").Pattern = versionPattern
+	// An object, or a string of YAML as spec.values
+	inline.Properties["values"] = &openapi.Schema{}
+
+	releaseName := spec.Property("releaseName")
+	releaseName.MaxLength = int64Ptr(validation.MaxReleaseNameLen)
+	releaseName.Pattern = releaseNamePattern
+
+	targetNamespace := spec.Property("targetNamespace")
+	targetNamespace.MaxLength = int64Ptr(63)
+	targetNamespace.Pattern = namespacePattern
+
+	// A values source must be given, exactly one is checked by the webhook
+	spec.Property("valuesFrom").Items.MinProperties = int64Ptr(1)
+
+	spec.Property("timeout").Minimum = float64Ptr(0)
+	spec.Property("retries").Minimum = float64Ptr(0)
+	spec.Property("uninstall.timeout").Minimum = float64Ptr(0)
+
+	spec.Property("deletionPolicy").Enum = []string{
+		string(helmCrdV2.DeletionPolicyDelete),
+		string(helmCrdV2.DeletionPolicyRetain),
+		string(helmCrdV2.DeletionPolicyDeleteHistoryOnly),
+	}
+	spec.Property("updatePolicy").Enum = []string{
+		string(helmCrdV2.UpdatePolicyNone),
+		string(helmCrdV2.UpdatePolicyPatch),
+		string(helmCrdV2.UpdatePolicyMinor),
+		string(helmCrdV2.UpdatePolicyLatest),
+	}
+	spec.Property("valuesMergeStrategy").Enum = []string{
+		string(helmCrdV2.ValuesMergeReplaceLists),
+		string(helmCrdV2.ValuesMergeDeep),
+		string(helmCrdV2.ValuesMergeOverwrite),
+	}
+	for _, auth := range []string{"chart.repository.auth", "chart.tarball.auth", "chart.oci.auth"} {
+		for _, scope := range []string{".header.scope", ".bearer.scope"} {
+			spec.Property(auth + scope).Enum = []string{
+				string(helmCrdV2.AuthScopeController),
+				string(helmCrdV2.AuthScopeNamespace),
+			}
+		}
+		spec.Property(auth + ".bearer.tokenURL").Pattern = repoURLPattern
+		spec.Property(auth + ".provider").Enum = []string{
+			string(helmCrdV2.AuthProviderGCP),
+			string(helmCrdV2.AuthProviderAzure),
+			string(helmCrdV2.AuthProviderAWS),
+		}
+	}
+	spec.Property("recovery.policy").Enum = []string{
+		string(helmCrdV2.RecoveryWait),
+		string(helmCrdV2.RecoveryRollback),
+		string(helmCrdV2.RecoveryRetry),
+	}
+	spec.Property("remediation.strategy").Enum = []string{
+		string(helmCrdV2.RemediationRollback),
+		string(helmCrdV2.RemediationUninstallReinstall),
+	}
+	spec.Property("driftDetection.mode").Enum = []string{
+		string(helmCrdV2.DriftDetectionWarn),
+		string(helmCrdV2.DriftDetectionCorrect),
+	}
+	spec.Property("upgradeApproval").Enum = []string{
+		string(helmCrdV2.UpgradeApprovalAutomatic),
+		string(helmCrdV2.UpgradeApprovalManual),
+	}
+	spec.Property("lint.mode").Enum = []string{
+		string(helmCrdV2.LintWarn),
+		string(helmCrdV2.LintEnforce),
+	}
+	spec.Property("verification.mode").Enum = []string{
+		string(helmCrdV2.VerificationWarn),
+		string(helmCrdV2.VerificationEnforce),
+	}
+	// An object, or a string of YAML as in earlier HelmReleases
+	spec.Properties["values"] = &openapi.Schema{}
+	return spec
+}
+
+func policySpec() *openapi.Schema {
+	spec := openapi.SchemaFor(reflect.TypeOf(helmCrdV2.HelmReleasePolicySpec{}))
+
+	charts := spec.Property("charts").Items
+	charts.Require("name")
+	charts.Property("name").MinLength = int64Ptr(1)
+	charts.Property("versions").Pattern = versionPattern
+	return spec
+}
+
+func setSpec() *openapi.Schema {
+	spec := openapi.SchemaFor(reflect.TypeOf(helmCrdV2.HelmReleaseSetSpec{}))
+
+	spec.Require("template")
+	spec.Property("template").Properties["spec"] = v2Spec()
+
+	items := spec.Property("items").Items
+	items.Require("name")
+	items.Property("name").MinLength = int64Ptr(1)
+	items.Property("name").MaxLength = int64Ptr(63)
+	items.Property("name").Pattern = namespacePattern
+	items.Property("targetNamespace").MaxLength = int64Ptr(63)
+	items.Property("targetNamespace").Pattern = namespacePattern
+	items.Properties["values"] = &openapi.Schema{}
+	return spec
+}
+
+func credentialSpec() *openapi.Schema {
+	spec := openapi.SchemaFor(reflect.TypeOf(helmCrdV2.RepositoryCredentialSpec{}))
+
+	spec.Property("urlPrefixes").Items.Pattern = urlPrefixPattern
+	return spec
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s v1|v2|policy|set|credential\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	var spec *openapi.Schema
+	switch os.Args[1] {
+	case "v1":
+		spec = v1Spec()
+	case "v2":
+		spec = v2Spec()
+	case "policy":
+		spec = policySpec()
+	case "set":
+		spec = setSpec()
+	case "credential":
+		spec = credentialSpec()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown version %q\n", os.Args[1])
+		os.Exit(1)
+	}
+
+	schema := &openapi.Schema{
+		Type:       "object",
+		Required:   []string{"spec"},
+		Properties: map[string]*openapi.Schema{"spec": spec},
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(schema); err != nil {
+		panic(err.Error())
+	}
+}
@@ -15,4 +15,4 @@ ${CODEGEN_PKG}/generate-groups.sh \
     all \
     github.com/bitnami-labs/helm-crd/pkg/client \
     github.com/bitnami-labs/helm-crd/pkg/apis \
-    helm.bitnami.com:v1
+    helm.bitnami.com:v1,v2
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"encoding/json"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// V2Annotation holds the v2 spec and status of objects served as v1, so
+// that fields without a v1 equivalent survive a round trip through v1
+const V2Annotation = "helm.bitnami.com/v2"
+
+type v2Fields struct {
+	Spec   v2.HelmReleaseSpec   `json:"spec"`
+	Status v2.HelmReleaseStatus `json:"status,omitempty"`
+}
+
+// Convert_v1_HelmRelease_To_v2_HelmRelease converts a v1 HelmRelease to v2
+func Convert_v1_HelmRelease_To_v2_HelmRelease(in *HelmRelease, out *v2.HelmRelease) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.APIVersion = v2.SchemeGroupVersion.String()
+	out.Kind = in.Kind
+
+	saved := v2Fields{}
+	if data, ok := in.Annotations[V2Annotation]; ok {
+		if err := json.Unmarshal([]byte(data), &saved); err != nil {
+			return err
+		}
+		delete(out.Annotations, V2Annotation)
+		if len(out.Annotations) == 0 {
+			out.Annotations = nil
+		}
+	}
+	out.Spec = saved.Spec
+	out.Status = saved.Status
+
+	if in.Spec.ChartName != "" || saved.Spec.Chart.Repository != nil {
+		repo := &v2.RepositoryChartSource{
+			URL:     in.Spec.RepoURL,
+			Name:    in.Spec.ChartName,
+			Version: in.Spec.Version,
+		}
+		if in.Spec.Auth.Header != nil {
+			repo.Auth.Header = &v2.HelmReleaseAuthHeader{}
+			in.Spec.Auth.Header.SecretKeyRef.DeepCopyInto(&repo.Auth.Header.SecretKeyRef)
+		}
+		out.Spec.Chart = v2.ChartSource{Repository: repo}
+	}
+	out.Spec.ReleaseName = in.Spec.ReleaseName
+	out.Spec.Values = v2.Values(in.Spec.Values)
+	out.Spec.Timeout = in.Spec.Timeout
+	out.Status.ResolvedVersion = in.Status.ResolvedVersion
+	return nil
+}
+
+// Convert_v2_HelmRelease_To_v1_HelmRelease converts a v2 HelmRelease to v1
+func Convert_v2_HelmRelease_To_v1_HelmRelease(in *v2.HelmRelease, out *HelmRelease) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.APIVersion = SchemeGroupVersion.String()
+	out.Kind = in.Kind
+
+	out.Spec = HelmReleaseSpec{
+		ReleaseName: in.Spec.ReleaseName,
+		Values:      string(in.Spec.Values),
+		Timeout:     in.Spec.Timeout,
+	}
+	if repo := in.Spec.Chart.Repository; repo != nil {
+		out.Spec.RepoURL = repo.URL
+		out.Spec.ChartName = repo.Name
+		out.Spec.Version = repo.Version
+		if repo.Auth.Header != nil {
+			out.Spec.Auth.Header = &HelmReleaseAuthHeader{}
+			repo.Auth.Header.SecretKeyRef.DeepCopyInto(&out.Spec.Auth.Header.SecretKeyRef)
+		}
+	}
+	out.Status = HelmReleaseStatus{
+		ResolvedVersion: in.Status.ResolvedVersion,
+	}
+
+	// Only annotate objects using fields that v1 cannot represent
+	roundTrip := &v2.HelmRelease{}
+	if err := Convert_v1_HelmRelease_To_v2_HelmRelease(out, roundTrip); err != nil {
+		return err
+	}
+	if apiequality.Semantic.DeepEqual(in.Spec, roundTrip.Spec) && apiequality.Semantic.DeepEqual(in.Status, roundTrip.Status) {
+		return nil
+	}
+	data, err := json.Marshal(v2Fields{Spec: in.Spec, Status: in.Status})
+	if err != nil {
+		return err
+	}
+	if out.Annotations == nil {
+		out.Annotations = map[string]string{}
+	}
+	out.Annotations[V2Annotation] = string(data)
+	return nil
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"testing"
+
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvertV1ToV2(t *testing.T) {
+	in := &HelmRelease{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "helm.bitnami.com/v1", Kind: "HelmRelease"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo"},
+		Spec: HelmReleaseSpec{
+			RepoURL:     "http://charts.example.com/repo/",
+			ChartName:   "foo",
+			Version:     "1.0.0",
+			ReleaseName: "bar",
+			Values:      "foo: bar",
+			Auth: HelmReleaseAuth{Header: &HelmReleaseAuthHeader{SecretKeyRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "auth"},
+				Key:                  "header",
+			}}},
+		},
+		Status: HelmReleaseStatus{ResolvedVersion: "1.0.0"},
+	}
+	out := &v2.HelmRelease{}
+	if err := Convert_v1_HelmRelease_To_v2_HelmRelease(in, out); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if out.APIVersion != "helm.bitnami.com/v2" {
+		t.Errorf("Unexpected apiVersion %s", out.APIVersion)
+	}
+	repo := out.Spec.Chart.Repository
+	if repo == nil || repo.URL != in.Spec.RepoURL || repo.Name != in.Spec.ChartName || repo.Version != in.Spec.Version {
+		t.Fatalf("Unexpected chart source %+v", out.Spec.Chart)
+	}
+	if repo.Auth.Header == nil || repo.Auth.Header.SecretKeyRef.Name != "auth" {
+		t.Errorf("Unexpected auth %+v", repo.Auth)
+	}
+	if out.Spec.ReleaseName != "bar" || out.Spec.Values != "foo: bar" || out.Status.ResolvedVersion != "1.0.0" {
+		t.Errorf("Unexpected conversion %+v", out)
+	}
+
+	back := &HelmRelease{}
+	if err := Convert_v2_HelmRelease_To_v1_HelmRelease(out, back); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, ok := back.Annotations[V2Annotation]; ok {
+		t.Errorf("Unexpected annotation for an object without v2 fields")
+	}
+	if !apiequality.Semantic.DeepEqual(in.Spec, back.Spec) || !apiequality.Semantic.DeepEqual(in.Status, back.Status) {
+		t.Errorf("Expecting %+v received %+v", in, back)
+	}
+}
+
+func TestConvertV2RoundTrip(t *testing.T) {
+	in := &v2.HelmRelease{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "helm.bitnami.com/v2", Kind: "HelmRelease"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "foo", Annotations: map[string]string{"foo": "bar"}},
+		Spec: v2.HelmReleaseSpec{
+			Chart:           v2.ChartSource{Repository: &v2.RepositoryChartSource{Name: "foo"}},
+			TargetNamespace: "other",
+			ValuesFrom: []v2.ValuesSource{
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "values"}, Key: "values.yaml"}},
+			},
+			Rollback: &v2.RollbackSpec{Enable: true},
+		},
+	}
+	v1Obj := &HelmRelease{}
+	if err := Convert_v2_HelmRelease_To_v1_HelmRelease(in, v1Obj); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, ok := v1Obj.Annotations[V2Annotation]; !ok {
+		t.Errorf("Expecting v2 fields to be kept in an annotation")
+	}
+	if in.Annotations[V2Annotation] != "" {
+		t.Errorf("Conversion modified its input")
+	}
+
+	// v1 clients may modify the fields they know about
+	v1Obj.Spec.Version = "2.0.0"
+
+	out := &v2.HelmRelease{}
+	if err := Convert_v1_HelmRelease_To_v2_HelmRelease(v1Obj, out); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := in.Spec.DeepCopy()
+	expected.Chart.Repository.Version = "2.0.0"
+	if !apiequality.Semantic.DeepEqual(*expected, out.Spec) {
+		t.Errorf("Expecting %+v received %+v", *expected, out.Spec)
+	}
+	if !apiequality.Semantic.DeepEqual(in.Annotations, out.Annotations) {
+		t.Errorf("Expecting annotations %v received %v", in.Annotations, out.Annotations)
+	}
+}
@@ -14,7 +14,8 @@ type HelmRelease struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec HelmReleaseSpec `json:"spec"`
+	Spec   HelmReleaseSpec   `json:"spec"`
+	Status HelmReleaseStatus `json:"status,omitempty"`
 }
 
 // HelmReleaseSpec is the spec for a HelmRelease resource.
@@ -25,12 +26,20 @@ type HelmReleaseSpec struct {
 	ChartName string `json:"chartName,omitempty"`
 	// ReleaseName is the Name of the release given to Tiller. Defaults to namespace-name. Must not be changed after initial object creation.
 	ReleaseName string `json:"releaseName,omitempty"`
-	// Version is the chart version
+	// Version is the chart version, or a semver range (e.g. "^1.2.0") that is re-resolved on every resync
 	Version string `json:"version,omitempty"`
 	// Auth is the authentication
 	Auth HelmReleaseAuth `json:"auth,omitempty"`
 	// Values is a string containing (unparsed) YAML values
 	Values string `json:"values,omitempty"`
+	// Timeout is the time in seconds Tiller waits for install/upgrade operations. Defaults to Tiller's default.
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// HelmReleaseStatus is the observed state of a HelmRelease resource.
+type HelmReleaseStatus struct {
+	// ResolvedVersion is the chart version selected from the repository index
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
 }
 
 type HelmReleaseAuth struct {
@@ -56,6 +56,10 @@ func RegisterDeepCopies(scheme *runtime.Scheme) error {
 			in.(*HelmReleaseSpec).DeepCopyInto(out.(*HelmReleaseSpec))
 			return nil
 		}, InType: reflect.TypeOf(&HelmReleaseSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseStatus).DeepCopyInto(out.(*HelmReleaseStatus))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseStatus{})},
 	)
 }
 
@@ -65,6 +69,7 @@ func (in *HelmRelease) DeepCopyInto(out *HelmRelease) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
 	return
 }
 
@@ -179,3 +184,19 @@ func (in *HelmReleaseSpec) DeepCopy() *HelmReleaseSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseStatus) DeepCopyInto(out *HelmReleaseStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseStatus.
+func (in *HelmReleaseStatus) DeepCopy() *HelmReleaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,40 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:noStatus
+
+// RepositoryCredential authenticates the chart downloads of every
+// HelmRelease from the URLs starting with one of its prefixes, unless
+// their chart source sets its own auth. Only the RepositoryCredentials of
+// the controller namespace are used.
+type RepositoryCredential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              RepositoryCredentialSpec `json:"spec"`
+}
+
+// RepositoryCredentialSpec maps URL prefixes to the auth of the
+// repositories, chart tarballs and OCI registries they match
+type RepositoryCredentialSpec struct {
+	// URLPrefixes are the prefixes of the repository, tarball and OCI chart URLs, e.g. https://charts.example.com/private/
+	// or oci://registry.example.com/myorg/. The credential with the longest matching prefix is used.
+	URLPrefixes []string `json:"urlPrefixes"`
+	// Auth authenticates the requests. The secret of header is read from the namespace of the RepositoryCredential,
+	// whatever its scope.
+	Auth HelmReleaseAuth `json:"auth"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RepositoryCredentialList is a list of RepositoryCredential resources
+type RepositoryCredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []RepositoryCredential `json:"items"`
+}
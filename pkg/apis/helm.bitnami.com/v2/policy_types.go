@@ -0,0 +1,46 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:noStatus
+
+// HelmReleasePolicy constrains what the HelmReleases of its namespace may
+// deploy. HelmReleases must satisfy every policy of their namespace.
+type HelmReleasePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              HelmReleasePolicySpec `json:"spec"`
+}
+
+// HelmReleasePolicySpec lists what HelmReleases are allowed to deploy.
+// Patterns match any characters with *. Empty lists allow anything.
+type HelmReleasePolicySpec struct {
+	// Charts are the charts HelmReleases may deploy
+	Charts []ChartRule `json:"charts,omitempty"`
+	// Repositories are the URL patterns of the repositories, and chart tarballs, charts may be downloaded from
+	Repositories []string `json:"repositories,omitempty"`
+	// TargetNamespaces are the patterns of the namespaces releases may be installed into
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+}
+
+// ChartRule allows the versions of charts matching a name pattern
+type ChartRule struct {
+	// Name is the pattern of the chart names
+	Name string `json:"name"`
+	// Versions is a semver range the chart versions must satisfy, e.g. "^4.0.0". Defaults to any version.
+	Versions string `json:"versions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HelmReleasePolicyList is a list of HelmReleasePolicy resources
+type HelmReleasePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HelmReleasePolicy `json:"items"`
+}
@@ -0,0 +1,70 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:noStatus
+
+// HelmReleaseSet stamps out a HelmRelease per instance: per item of a
+// list and per namespace matching a selector
+type HelmReleaseSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HelmReleaseSetSpec   `json:"spec"`
+	Status HelmReleaseSetStatus `json:"status,omitempty"`
+}
+
+// HelmReleaseSetSpec is the spec of a HelmReleaseSet resource
+type HelmReleaseSetSpec struct {
+	// Template is the HelmRelease created for each instance, named <set>-<instance> in the namespace of the set
+	Template HelmReleaseTemplate `json:"template"`
+	// Items are the instances of the set. Items named after a namespace matching NamespaceSelector override its instance.
+	Items []HelmReleaseSetItem `json:"items,omitempty"`
+	// NamespaceSelector adds an instance per namespace matching it, named after the namespace and installed into it
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// HelmReleaseTemplate is the template of the HelmReleases of a set
+type HelmReleaseTemplate struct {
+	// Labels and Annotations are set on every HelmRelease of the set
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Spec is the spec of every HelmRelease of the set. The release name must not be set.
+	Spec HelmReleaseSpec `json:"spec"`
+}
+
+// HelmReleaseSetItem is an instance of a HelmReleaseSet
+type HelmReleaseSetItem struct {
+	// Name identifies the instance, it must be a DNS-1123 label
+	Name string `json:"name"`
+	// TargetNamespace overrides the target namespace of the template, or the namespace of a selected instance
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// Values are the YAML values merged over the values of the template
+	Values Values `json:"values,omitempty"`
+}
+
+// HelmReleaseSetStatus is the status of a HelmReleaseSet resource
+type HelmReleaseSetStatus struct {
+	// ObservedGeneration is the generation of the spec the HelmReleases were last updated from
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Instances are the names of the HelmReleases of the set
+	Instances []string `json:"instances,omitempty"`
+	// ReadyInstances is the number of HelmReleases of the set that are Ready
+	ReadyInstances int `json:"readyInstances"`
+	// Message describes why the HelmReleases could not be updated, if so
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HelmReleaseSetList is a list of HelmReleaseSet resources
+type HelmReleaseSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HelmReleaseSet `json:"items"`
+}
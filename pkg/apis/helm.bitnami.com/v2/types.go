@@ -0,0 +1,938 @@
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ForceSyncAnnotation forces a reconcile of an unchanged HelmRelease when its value changes
+	ForceSyncAnnotation = "helm.bitnami.com/force-sync"
+	// RollbackRevisionAnnotation requests rolling a suspended HelmRelease back to the given Tiller revision
+	RollbackRevisionAnnotation = "helm.bitnami.com/rollback-revision"
+	// MigrateToHelm3Annotation set to "true" copies the revisions of the release to Helm 3 storage after every deployment
+	MigrateToHelm3Annotation = "helm.bitnami.com/migrate-to-helm3"
+	// ApprovedAnnotation approves the pending upgrade of a HelmRelease with manual upgrade approval when
+	// its value is the status.upgradeDiff.checksum of the upgrade
+	ApprovedAnnotation = "helm.bitnami.com/approved"
+	// IgnoreAnnotation set to "true" stops the controller from changing the release, e.g. while it is patched by hand
+	// during an incident, the status still reflecting the deployed release
+	IgnoreAnnotation = "helm.bitnami.com/ignore"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:noStatus
+
+// HelmRelease describes a Helm chart release.
+type HelmRelease struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HelmReleaseSpec   `json:"spec"`
+	Status HelmReleaseStatus `json:"status,omitempty"`
+}
+
+// HelmReleaseSpec is the spec for a HelmRelease resource.
+type HelmReleaseSpec struct {
+	// Chart is the location of the chart to release. Must be unset when ChartURL is set.
+	Chart ChartSource `json:"chart,omitempty"`
+	// ChartURL is the http(s) URL of the chart archive to release, downloaded without resolving it in a repository
+	// index, with the auth of the RepositoryCredential matching it. Shorthand for chart.tarball.url.
+	ChartURL string `json:"chartURL,omitempty"`
+	// ChartDigest is the SHA-256 digest the chart archive must have, e.g. "sha256:<hex>", pinning the exact
+	// chart released from ChartURL or from the repository, tarball or OCI chart source
+	ChartDigest string `json:"chartDigest,omitempty"`
+	// ReleaseName is the Name of the release given to Tiller. Defaults to ReleaseNameTemplate rendered. Must not be changed after initial object creation.
+	ReleaseName string `json:"releaseName,omitempty"`
+	// ReleaseNameTemplate is a Go template of the release name used when ReleaseName is unset, with the variables
+	// .Namespace, .Name and .ShortHash. Defaults to "{{ .Namespace }}-{{ .Name }}". Names longer than 53 characters
+	// are truncated and suffixed with .ShortHash. Must not be changed after initial object creation.
+	ReleaseNameTemplate string `json:"releaseNameTemplate,omitempty"`
+	// Suspend stops installing and upgrading the release until unset. Deletion is still processed.
+	Suspend bool `json:"suspend,omitempty"`
+	// AdoptExisting upgrades a release of the same name that was not deployed for this HelmRelease, instead of failing
+	AdoptExisting bool `json:"adoptExisting,omitempty"`
+	// TillerHost is the host:port of the Tiller managing the release. Defaults to the Tiller service of
+	// TillerNamespace if set, otherwise to the controller's Tiller.
+	TillerHost string `json:"tillerHost,omitempty"`
+	// TillerNamespace is the namespace where the Tiller managing the release stores it. Defaults to the controller's Tiller namespace.
+	TillerNamespace string `json:"tillerNamespace,omitempty"`
+	// KubeConfigSecretRef selects a kubeconfig in a Secret of the HelmRelease namespace, deploying the release to the
+	// cluster of its current context. With Tiller, TillerHost must be the address of the Tiller of that cluster.
+	KubeConfigSecretRef *corev1.SecretKeySelector `json:"kubeConfigSecretRef,omitempty"`
+	// ServiceAccountName is a service account of the HelmRelease namespace that must be allowed to make
+	// the changes to the release objects. Defaults to the controller permissions.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// TargetNamespace is the namespace the release is installed into. Defaults to the HelmRelease namespace.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// CreateNamespace creates the target namespace if it does not exist, annotated with the HelmRelease that created it
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+	// NamespaceMetadata are set on the namespace created with CreateNamespace
+	NamespaceMetadata *NamespaceMetadata `json:"namespaceMetadata,omitempty"`
+	// KubeVersion is the Kubernetes version, e.g. "1.12.3", templates are rendered for as .Capabilities.KubeVersion,
+	// without Tiller and when linting. Tiller renders with the version of its cluster. Defaults to 1.9.
+	KubeVersion string `json:"kubeVersion,omitempty"`
+	// APIVersions are the API versions, e.g. "apps/v1", templates are rendered for as .Capabilities.APIVersions
+	// besides "v1", without Tiller and when linting. Tiller renders with the API versions of its cluster.
+	APIVersions []string `json:"apiVersions,omitempty"`
+	// ValuesFrom are sources of YAML values, merged in order before Values
+	ValuesFrom []ValuesSource `json:"valuesFrom,omitempty"`
+	// Values are the YAML values of the release, an object or a string of YAML
+	Values Values `json:"values,omitempty"`
+	// ValuesMergeStrategy is how ValuesFrom and Values are combined: replace-lists (the default, as Helm)
+	// merges maps and replaces lists, deep also appends the items of lists, and overwrite replaces
+	// top-level keys
+	ValuesMergeStrategy ValuesMergeStrategy `json:"valuesMergeStrategy,omitempty"`
+	// Timeout is the time in seconds Tiller waits for install/upgrade operations. Defaults to Tiller's default.
+	Timeout int64 `json:"timeout,omitempty"`
+	// DisableHooks skips the hooks of the chart on install, upgrade, rollback and delete
+	DisableHooks bool `json:"disableHooks,omitempty"`
+	// Retries is the number of times a failed reconcile is retried before giving up. Defaults to the controller --max-retries.
+	Retries *int32 `json:"retries,omitempty"`
+	// Rollback configures rolling back failed upgrades
+	Rollback *RollbackSpec `json:"rollback,omitempty"`
+	// Recovery configures recovering the release when an install or upgrade was interrupted, e.g. by a
+	// controller or Tiller restart, leaving it pending
+	Recovery *RecoverySpec `json:"recovery,omitempty"`
+	// Remediation configures repairing the release when its latest revision failed, instead of leaving it to
+	// an operator
+	Remediation *RemediationSpec `json:"remediation,omitempty"`
+	// HealthCheck configures only reporting the HelmRelease Ready once the workloads of the release are
+	HealthCheck *HealthCheckSpec `json:"healthCheck,omitempty"`
+	// HealthChecks are extra checks the deployed release must pass to be Ready, within the healthCheck timeout
+	HealthChecks []HealthCheck `json:"healthChecks,omitempty"`
+	// Test runs the chart's tests after upgrades, rolling back to the previous revision if they fail
+	Test *TestSpec `json:"test,omitempty"`
+	// UpgradeWindow restricts when deployed releases are upgraded. Installs, deletions and force-synced
+	// upgrades proceed at any time.
+	UpgradeWindow *UpgradeWindow `json:"upgradeWindow,omitempty"`
+	// PostRender modifies the rendered manifests before they are applied
+	PostRender *PostRenderSpec `json:"postRender,omitempty"`
+	// PropagateMetadata copies labels and annotations of the HelmRelease to the objects of the release
+	PropagateMetadata *PropagateMetadata `json:"propagateMetadata,omitempty"`
+	// SkipCRDs leaves out the CustomResourceDefinitions bundled in the chart, including crd-install hooks
+	SkipCRDs bool `json:"skipCRDs,omitempty"`
+	// InstallCRDsFirst applies the CustomResourceDefinitions bundled in the chart, including crd-install hooks
+	// and the crds/ directory, and waits for them to be established before installing or upgrading the release
+	InstallCRDsFirst bool `json:"installCRDsFirst,omitempty"`
+	// RenderOnly renders the chart into a ConfigMap (or Secret) named in status instead of installing it
+	RenderOnly bool `json:"renderOnly,omitempty"`
+	// DriftDetection configures comparing deployed objects with the release manifest on resync
+	DriftDetection *DriftDetectionSpec `json:"driftDetection,omitempty"`
+	// DeletionPolicy is what happens to the release when the HelmRelease is deleted. Defaults to Delete.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Uninstall configures deleting the release with the Delete deletion policy
+	Uninstall *UninstallSpec `json:"uninstall,omitempty"`
+	// UpdatePolicy updates the release to the newest chart version of the repository that is at least
+	// chart.repository.version and within the same minor (Patch) or major (Minor) version, or any newer
+	// version (Latest), checking on every resync. Defaults to None.
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+	// Devel includes prerelease chart versions, e.g. 1.2.0-rc.1, when resolving the chart version of the
+	// repository, like helm install --devel
+	Devel bool `json:"devel,omitempty"`
+	// Lint runs the checks of helm lint on the chart and values before installing or upgrading the release
+	Lint *LintSpec `json:"lint,omitempty"`
+	// Verification verifies the cosign signatures of OCI charts before installing or upgrading the release
+	Verification *VerificationSpec `json:"verification,omitempty"`
+	// UpgradeDiff records the changes of upgrades in the status before running them
+	UpgradeDiff *UpgradeDiffSpec `json:"upgradeDiff,omitempty"`
+	// UpgradeApproval is Manual to upgrade the release only once the helm.bitnami.com/approved annotation
+	// is set to the generation of the HelmRelease. Defaults to Automatic.
+	UpgradeApproval UpgradeApproval `json:"upgradeApproval,omitempty"`
+	// Priority orders the reconciles waiting in the controller queue, higher first, deletions of
+	// HelmReleases going first. Defaults to 0.
+	Priority int32 `json:"priority,omitempty"`
+	// Outputs are values of the release exported to the other HelmReleases of the namespace, stored in
+	// the <name>-outputs Secret once the release is deployed
+	Outputs []ReleaseOutput `json:"outputs,omitempty"`
+}
+
+// ValuesMergeStrategy is how the values sources of a release are combined
+type ValuesMergeStrategy string
+
+const (
+	// ValuesMergeReplaceLists merges maps recursively and replaces lists
+	ValuesMergeReplaceLists ValuesMergeStrategy = "replace-lists"
+	// ValuesMergeDeep merges maps recursively and appends the items of lists
+	ValuesMergeDeep ValuesMergeStrategy = "deep"
+	// ValuesMergeOverwrite replaces top-level keys
+	ValuesMergeOverwrite ValuesMergeStrategy = "overwrite"
+)
+
+// UpdatePolicy is which newer chart versions a release is updated to
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyNone keeps the chart version of the spec
+	UpdatePolicyNone UpdatePolicy = "None"
+	// UpdatePolicyPatch updates to newer patch versions
+	UpdatePolicyPatch UpdatePolicy = "Patch"
+	// UpdatePolicyMinor updates to newer minor and patch versions
+	UpdatePolicyMinor UpdatePolicy = "Minor"
+	// UpdatePolicyLatest updates to any newer version
+	UpdatePolicyLatest UpdatePolicy = "Latest"
+)
+
+// DeletionPolicy is what happens to the release when the HelmRelease is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the release and its objects
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the release, which can still be managed with the helm CLI
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDeleteHistoryOnly deletes the Tiller release records but keeps its objects
+	DeletionPolicyDeleteHistoryOnly DeletionPolicy = "DeleteHistoryOnly"
+)
+
+// ChartLocation returns the chart source of the spec: Chart, or a tarball
+// source for ChartURL, with ChartDigest as the digest of the source
+func (s *HelmReleaseSpec) ChartLocation() ChartSource {
+	if s.ChartURL != "" {
+		return ChartSource{Tarball: &TarballChartSource{URL: s.ChartURL, Digest: s.ChartDigest}}
+	}
+	src := *s.Chart.DeepCopy()
+	if s.ChartDigest != "" {
+		switch {
+		case src.Repository != nil:
+			src.Repository.Digest = s.ChartDigest
+		case src.Tarball != nil:
+			src.Tarball.Digest = s.ChartDigest
+		case src.OCI != nil:
+			src.OCI.Digest = s.ChartDigest
+		}
+	}
+	return src
+}
+
+// ChartSource is the location of a chart. Exactly one of its fields must be set.
+type ChartSource struct {
+	// Repository is a chart in a Helm chart repository
+	Repository *RepositoryChartSource `json:"repository,omitempty"`
+	// Tarball is a chart archive downloaded from its URL, without resolving it in a repository index
+	Tarball *TarballChartSource `json:"tarball,omitempty"`
+	// OCI is a chart pushed to an OCI registry
+	OCI *OCIChartSource `json:"oci,omitempty"`
+	// Inline is a chart defined in the HelmRelease itself
+	Inline *InlineChartSource `json:"inline,omitempty"`
+}
+
+// RepositoryChartSource is a chart in a Helm chart repository
+type RepositoryChartSource struct {
+	// URL is the URL of the repository. Defaults to the controller --default-repo-url, the stable repo unless set.
+	URL string `json:"url,omitempty"`
+	// Mirrors are repositories serving the same charts as URL, tried in
+	// order when fetching the index of the previous one fails to connect,
+	// times out or returns a 5xx response
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Name is the name of the chart within the repo
+	Name string `json:"name"`
+	// Version is the chart version, or a semver range (e.g. "^1.2.0") that is re-resolved on every resync
+	Version string `json:"version,omitempty"`
+	// Digest is the SHA-256 digest the chart archive must have, e.g. "sha256:<hex>"
+	Digest string `json:"digest,omitempty"`
+	// Auth is the authentication
+	Auth HelmReleaseAuth `json:"auth,omitempty"`
+}
+
+// TarballChartSource is a chart archive at a URL
+type TarballChartSource struct {
+	// URL is the http(s) URL of the chart archive
+	URL string `json:"url"`
+	// Digest is the SHA-256 digest the chart archive must have, e.g. "sha256:<hex>"
+	Digest string `json:"digest,omitempty"`
+	// Auth is the authentication
+	Auth HelmReleaseAuth `json:"auth,omitempty"`
+}
+
+// OCIChartSource is a chart in an OCI registry
+type OCIChartSource struct {
+	// URL is the oci://<registry>/<repository> of the chart, e.g. oci://registry.example.com/charts/mariadb
+	URL string `json:"url"`
+	// Version is the tag of the chart
+	Version string `json:"version"`
+	// Digest is the SHA-256 digest the chart archive must have, e.g. "sha256:<hex>"
+	Digest string `json:"digest,omitempty"`
+	// PlainHTTP pulls the chart over http rather than https
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+	// Auth is the authentication, sent to the registry or exchanged for a registry token
+	Auth HelmReleaseAuth `json:"auth,omitempty"`
+}
+
+// InlineChartSource is a chart defined in the HelmRelease, for small glue
+// charts not worth publishing to a repository
+type InlineChartSource struct {
+	// Metadata is the Chart.yaml of the chart
+	Metadata InlineChartMetadata `json:"metadata"`
+	// Templates are the templates of the chart by file name within templates/, e.g. "configmap.yaml" or "_helpers.tpl"
+	Templates map[string]string `json:"templates"`
+	// Values are the default values of the chart, a YAML map
+	Values Values `json:"values,omitempty"`
+}
+
+// InlineChartMetadata is the Chart.yaml of an inline chart
+type InlineChartMetadata struct {
+	// Name is the name of the chart
+	Name string `json:"name"`
+	// Version is the SemVer 2 version of the chart
+	Version string `json:"version"`
+	// AppVersion is the version of the app the chart deploys
+	AppVersion string `json:"appVersion,omitempty"`
+	// Description is a single-sentence description of the chart
+	Description string `json:"description,omitempty"`
+}
+
+// ValuesSource is a source of YAML values. Exactly one of ConfigMapKeyRef, SecretKeyRef, FieldRef and URL must be set.
+type ValuesSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the HelmRelease namespace
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret in the HelmRelease namespace
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// FieldRef sets a values path to a field of another object
+	FieldRef *ObjectFieldRef `json:"fieldRef,omitempty"`
+	// URL is the http(s) URL of a values file, fetched on every reconcile
+	URL string `json:"url,omitempty"`
+	// AuthSecretKeyRef selects a key of a Secret in the HelmRelease namespace holding the Authorization header sent to URL
+	AuthSecretKeyRef *corev1.SecretKeySelector `json:"authSecretKeyRef,omitempty"`
+	// Sops decrypts the values with the controller's SOPS keys
+	Sops bool `json:"sops,omitempty"`
+	// HelmReleaseRef sets values to the outputs of another HelmRelease of the namespace
+	HelmReleaseRef *HelmReleaseOutputRef `json:"helmReleaseRef,omitempty"`
+}
+
+// ReleaseOutput is a value of a release exported to other HelmReleases.
+// Outputs are strings, other values being exported as JSON.
+type ReleaseOutput struct {
+	// Name of the output, the key of the outputs Secret
+	Name string `json:"name"`
+	// ValuePath is the dotted path of a value of the release, e.g. "auth.username"
+	ValuePath string `json:"valuePath,omitempty"`
+	// SecretKeyRef selects a key of a Secret of the target namespace, such as a password generated
+	// by the chart
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// HelmReleaseOutputRef selects the outputs of a HelmRelease
+type HelmReleaseOutputRef struct {
+	// Name of the HelmRelease, in the same namespace
+	Name string `json:"name"`
+	// Output is the name of a single output. All outputs are set at their name if empty.
+	Output string `json:"output,omitempty"`
+	// TargetPath is the dotted values path Output is set at. Defaults to the name of the output.
+	TargetPath string `json:"targetPath,omitempty"`
+	// Optional skips the source while the outputs are not available
+	Optional *bool `json:"optional,omitempty"`
+}
+
+// ObjectFieldRef selects a field of any object and the values path it is set at.
+// Reading objects of other namespaces or cluster scoped objects requires the
+// service accounts of the HelmRelease namespace to be allowed to get them.
+type ObjectFieldRef struct {
+	// APIVersion of the object, e.g. "v1"
+	APIVersion string `json:"apiVersion"`
+	// Kind of the object, e.g. "Service"
+	Kind string `json:"kind"`
+	// Namespace of the object. Defaults to the HelmRelease namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the object
+	Name string `json:"name"`
+	// FieldPath is the dotted path of the field, e.g. "spec.clusterIP" or "data['app.conf']"
+	FieldPath string `json:"fieldPath"`
+	// TargetPath is the dotted values path the field is set at, e.g. "database.host"
+	TargetPath string `json:"targetPath"`
+	// Optional skips the source when the object or field does not exist
+	Optional *bool `json:"optional,omitempty"`
+}
+
+// NamespaceMetadata are the labels and annotations of a created namespace
+type NamespaceMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PropagateMetadata selects the labels and annotations of a HelmRelease set
+// on the objects of its release. Keys missing from the HelmRelease are skipped.
+type PropagateMetadata struct {
+	// Labels are the keys of the labels set on the objects
+	Labels []string `json:"labels,omitempty"`
+	// Annotations are the keys of the annotations set on the objects
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// UninstallSpec configures deleting a release
+type UninstallSpec struct {
+	// Purge removes the release from the Tiller history, freeing its name. Defaults to true.
+	Purge *bool `json:"purge,omitempty"`
+	// Timeout is the time in seconds Tiller waits for the deletion. Defaults to Tiller's default.
+	Timeout int64 `json:"timeout,omitempty"`
+	// DisableHooks skips the pre-delete and post-delete hooks of the chart
+	DisableHooks bool `json:"disableHooks,omitempty"`
+}
+
+// RollbackSpec configures rolling back failed upgrades
+type RollbackSpec struct {
+	// Enable rolls back to the previous revision when an upgrade fails
+	Enable bool `json:"enable,omitempty"`
+	// Recreate performs pods restart for the resources if applicable
+	Recreate bool `json:"recreate,omitempty"`
+	// Force resource updates through delete/recreate if needed
+	Force bool `json:"force,omitempty"`
+}
+
+// RecoveryPolicy is how a release left pending by an interrupted install
+// or upgrade is recovered
+type RecoveryPolicy string
+
+const (
+	// RecoveryWait waits for the pending operation to complete, failing
+	// once it timed out
+	RecoveryWait RecoveryPolicy = "wait"
+	// RecoveryRollback rolls back timed out pending upgrades, and deletes
+	// timed out pending installs, failing the reconcile
+	RecoveryRollback RecoveryPolicy = "rollback"
+	// RecoveryRetry rolls back or deletes timed out pending operations as
+	// RecoveryRollback, then installs or upgrades the release again
+	RecoveryRetry RecoveryPolicy = "retry"
+)
+
+// RecoverySpec configures recovering interrupted operations
+type RecoverySpec struct {
+	// Policy is wait, rollback or retry. Defaults to the controller --recovery-policy.
+	Policy RecoveryPolicy `json:"policy,omitempty"`
+	// Timeout is the time in seconds after which a pending operation is considered interrupted. Defaults to
+	// the release timeout plus a minute.
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// RemediationStrategy is how a failed release is repaired
+type RemediationStrategy string
+
+const (
+	// RemediationRollback rolls back to the previous revision, failed
+	// installs being uninstalled, before upgrading the release again
+	RemediationRollback RemediationStrategy = "rollback"
+	// RemediationUninstallReinstall purges the release before installing
+	// it again
+	RemediationUninstallReinstall RemediationStrategy = "uninstall-reinstall"
+)
+
+// RemediationSpec configures repairing failed releases
+type RemediationSpec struct {
+	// Retries is the number of remediations of the same spec before giving up. Defaults to 3.
+	Retries *int32 `json:"retries,omitempty"`
+	// Strategy is rollback or uninstall-reinstall. Defaults to rollback.
+	Strategy RemediationStrategy `json:"strategy,omitempty"`
+	// Backoff is the time in seconds after the failure before the first remediation, doubled for each
+	// further one, up to an hour. Defaults to 30.
+	Backoff int64 `json:"backoff,omitempty"`
+}
+
+// HealthCheckSpec configures assessing the health of the workloads of a
+// deployed release
+type HealthCheckSpec struct {
+	// Enable checks the readiness of the Deployments, StatefulSets, DaemonSets and Jobs of the release
+	Enable bool `json:"enable,omitempty"`
+	// Timeout is the time in seconds the workloads have to become ready after the release is deployed before
+	// it is reported Unhealthy. Defaults to 300.
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// HealthCheck is a check of a deployed release, setting one of job, url
+// or resource
+type HealthCheck struct {
+	// Name identifies the check in the Healthy condition. Defaults to what is checked.
+	Name string `json:"name,omitempty"`
+	// Job is the name of a Job of the release namespace that must complete
+	Job string `json:"job,omitempty"`
+	// URL must answer a GET request with 200
+	URL string `json:"url,omitempty"`
+	// Resource is an object a field of which must have a value
+	Resource *ResourceHealthCheck `json:"resource,omitempty"`
+}
+
+// ResourceHealthCheck checks a field of an object of the release
+// namespace, or cluster scoped, e.g. the phase of a custom resource
+type ResourceHealthCheck struct {
+	// APIVersion of the object, e.g. "example.com/v1"
+	APIVersion string `json:"apiVersion"`
+	// Kind of the object, e.g. "Database"
+	Kind string `json:"kind"`
+	// Name of the object
+	Name string `json:"name"`
+	// FieldPath is the dotted path of the field, e.g. "status.phase"
+	FieldPath string `json:"fieldPath"`
+	// Value is the expected value of the field, formatted as a string
+	Value string `json:"value"`
+}
+
+// TestSpec configures testing upgraded releases
+type TestSpec struct {
+	// Enable runs the test hooks of the chart after each upgrade changing the release, and rolls back to the
+	// previous revision if they fail
+	Enable bool `json:"enable,omitempty"`
+	// Timeout is the time in seconds to wait for each test. Defaults to Tiller's default.
+	Timeout int64 `json:"timeout,omitempty"`
+	// Cleanup deletes the test pods once they complete
+	Cleanup bool `json:"cleanup,omitempty"`
+}
+
+// UpgradeWindow is a recurring maintenance window, open during the periods of Schedule or Ranges
+type UpgradeWindow struct {
+	// Schedule is a cron expression of the times the window opens, e.g. "0 2 * * Sat"
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is the time in seconds the window stays open after each time of Schedule
+	Duration int64 `json:"duration,omitempty"`
+	// Ranges are daily or weekly time ranges the window is open in
+	Ranges []TimeRange `json:"ranges,omitempty"`
+	// TimeZone is the IANA time zone of Schedule and Ranges, e.g. Europe/Madrid. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// TimeRange is a daily or weekly time range
+type TimeRange struct {
+	// Days are the days of the week the range starts on, e.g. Sat. Defaults to every day.
+	Days []string `json:"days,omitempty"`
+	// Start is the time of day the range starts at, as HH:MM
+	Start string `json:"start"`
+	// End is the time of day the range ends at, as HH:MM, the next day if not after Start
+	End string `json:"end"`
+}
+
+// PostRenderSpec configures modifications of the rendered manifests
+type PostRenderSpec struct {
+	// Kustomize applies kustomize style patches and image overrides
+	Kustomize *KustomizeSpec `json:"kustomize,omitempty"`
+	// PatchesJSON6902 are RFC 6902 JSON patches applied after Kustomize
+	PatchesJSON6902 []JSON6902Patch `json:"patchesJson6902,omitempty"`
+}
+
+// JSON6902Patch is a JSON patch applied to a single rendered object
+type JSON6902Patch struct {
+	// Target selects the patched object, it must match exactly one
+	Target PatchTarget `json:"target"`
+	// Patch is the list of operations, in JSON or YAML
+	Patch string `json:"patch"`
+}
+
+// PatchTarget selects rendered objects. Empty fields match any value.
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// KustomizeSpec is the subset of a kustomization.yaml applied to rendered manifests
+type KustomizeSpec struct {
+	// PatchesStrategicMerge are YAML strategic merge patches, each selecting its target by kind and metadata.name
+	PatchesStrategicMerge []string `json:"patchesStrategicMerge,omitempty"`
+	// Images overrides the name, tag or digest of container images
+	Images []KustomizeImage `json:"images,omitempty"`
+}
+
+// KustomizeImage overrides a container image
+type KustomizeImage struct {
+	// Name is the image name to match, without tag or digest
+	Name string `json:"name"`
+	// NewName replaces the image name
+	NewName string `json:"newName,omitempty"`
+	// NewTag replaces the image tag
+	NewTag string `json:"newTag,omitempty"`
+	// Digest replaces the image tag with a digest
+	Digest string `json:"digest,omitempty"`
+}
+
+// DriftDetectionMode is the action taken when deployed objects drift from the release manifest
+type DriftDetectionMode string
+
+const (
+	// DriftDetectionWarn only sets the Drifted condition
+	DriftDetectionWarn DriftDetectionMode = "warn"
+	// DriftDetectionCorrect patches drifted objects back to the release manifest
+	DriftDetectionCorrect DriftDetectionMode = "correct"
+)
+
+// LintMode is the action taken when linting the chart finds errors
+type LintMode string
+
+const (
+	// LintWarn only records the lint messages in the status
+	LintWarn LintMode = "warn"
+	// LintEnforce doesn't install or upgrade releases with lint errors
+	LintEnforce LintMode = "enforce"
+)
+
+// LintSpec configures linting the chart
+type LintSpec struct {
+	// Mode is warn or enforce. Defaults to warn.
+	Mode LintMode `json:"mode,omitempty"`
+}
+
+// VerificationMode is the action taken when a chart has no valid signature
+type VerificationMode string
+
+const (
+	// VerificationWarn only records an Event
+	VerificationWarn VerificationMode = "warn"
+	// VerificationEnforce doesn't install or upgrade releases of charts
+	// without a valid signature
+	VerificationEnforce VerificationMode = "enforce"
+)
+
+// VerificationSpec configures verifying the cosign signatures of a chart.
+// A signature made with any of the keys or keyless identities is valid.
+type VerificationSpec struct {
+	// Mode is warn or enforce. Defaults to enforce.
+	Mode VerificationMode `json:"mode,omitempty"`
+	// SecretRef selects a Secret of the HelmRelease namespace whose keys ending in .pub hold PEM encoded cosign public keys
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+	// Keyless are the identities of keyless signatures, whose Fulcio certificates and Rekor entries are verified with the
+	// controller --sigstore-fulcio-roots and --sigstore-rekor-public-key
+	Keyless []KeylessIdentity `json:"keyless,omitempty"`
+}
+
+// KeylessIdentity matches the Fulcio certificates of keyless signatures
+type KeylessIdentity struct {
+	// Issuer is a regular expression matching the OIDC issuer of the certificate, e.g. ^https://token\.actions\.githubusercontent\.com$
+	Issuer string `json:"issuer"`
+	// Subject is a regular expression matching the email or URI of the certificate, e.g. the URI of a CI workflow
+	Subject string `json:"subject"`
+}
+
+// UpgradeApproval is whether upgrades wait for an approval
+type UpgradeApproval string
+
+const (
+	// UpgradeApprovalAutomatic upgrades releases as soon as they change
+	UpgradeApprovalAutomatic UpgradeApproval = "Automatic"
+	// UpgradeApprovalManual upgrades releases once approved with the helm.bitnami.com/approved annotation
+	UpgradeApprovalManual UpgradeApproval = "Manual"
+)
+
+// UpgradeDiffSpec configures the preview of the changes of upgrades
+type UpgradeDiffSpec struct {
+	// Enable compares the rendered upgrade with the deployed release before upgrading
+	Enable bool `json:"enable"`
+	// Full also stores the diff of the manifests in a ConfigMap, or a Secret when values are read from Secrets
+	Full bool `json:"full,omitempty"`
+}
+
+// DriftDetectionSpec configures drift detection
+type DriftDetectionSpec struct {
+	// Mode is warn or correct
+	Mode DriftDetectionMode `json:"mode"`
+}
+
+// HelmReleaseStatus is the observed state of a HelmRelease resource.
+type HelmReleaseStatus struct {
+	// Phase summarizes the state of the release
+	Phase HelmReleasePhase `json:"phase,omitempty"`
+	// FailureReason is a CamelCase reason of the last failed reconcile,
+	// cleared once the release is deployed
+	FailureReason string `json:"failureReason,omitempty"`
+	// FailureMessage is the error of the last failed reconcile, as
+	// returned by Tiller or the chart download
+	FailureMessage string `json:"failureMessage,omitempty"`
+	// ResolvedVersion is the chart version selected from the repository index
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+	// LastChartUpdate is the last update of the chart version by the update policy
+	LastChartUpdate *ChartUpdate `json:"lastChartUpdate,omitempty"`
+	// Helm3MigratedRevision is the latest revision copied to Helm 3 storage, with the migrate-to-helm3 annotation
+	Helm3MigratedRevision int32 `json:"helm3MigratedRevision,omitempty"`
+	// ReleaseName is the name of the deployed release
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Chart is the name of the deployed chart
+	Chart string `json:"chart,omitempty"`
+	// ChartVersion is the version of the deployed chart, as reported by Tiller
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// AppVersion is the app version of the deployed chart
+	AppVersion string `json:"appVersion,omitempty"`
+	// Revision is the Tiller revision of the deployed release
+	Revision int32 `json:"revision,omitempty"`
+	// ReleaseChecksum is the checksum of the chart, values and render capabilities Revision was deployed with.
+	// Upgrades are skipped while it is unchanged.
+	ReleaseChecksum string `json:"releaseChecksum,omitempty"`
+	// LastDeployed is the time the release was last installed or upgraded
+	LastDeployed *metav1.Time `json:"lastDeployed,omitempty"`
+	// Notes is the rendered NOTES.txt of the release, truncated to a few KiB
+	Notes string `json:"notes,omitempty"`
+	// Resources are the objects created by the release
+	Resources []ResourceReference `json:"resources,omitempty"`
+	// Conditions are the latest observations of the HelmRelease state
+	Conditions []HelmReleaseCondition `json:"conditions,omitempty"`
+	// RenderedManifests locates the manifests of a render-only release
+	RenderedManifests *RenderedManifestsReference `json:"renderedManifests,omitempty"`
+	// DryRun is what the last reconcile would have changed, set when the controller runs with --dry-run
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+	// UpgradeDiff are the changes of the last upgrade changing the release, recorded before running it
+	UpgradeDiff *UpgradeDiffStatus `json:"upgradeDiff,omitempty"`
+	// LintWarnings are the warnings and errors of linting the chart, with spec.lint
+	LintWarnings []string `json:"lintWarnings,omitempty"`
+	// SignedBy is the key, or the identity of the keyless signature, that signed the chart, with spec.verification
+	SignedBy string `json:"signedBy,omitempty"`
+	// FetchedValues are the values files last fetched from valuesFrom URLs
+	FetchedValues []FetchedValues `json:"fetchedValues,omitempty"`
+	// Outputs are the names of the outputs last exported to the <name>-outputs Secret
+	Outputs []string `json:"outputs,omitempty"`
+	// Operation is the install or upgrade in progress, recorded before it is started and cleared once it
+	// completes, so that interrupted operations are recognized after a restart
+	Operation *OperationStatus `json:"operation,omitempty"`
+	// Remediation are the remediations of the failed release, with spec.remediation, cleared once it is
+	// deployed
+	Remediation *RemediationStatus `json:"remediation,omitempty"`
+	// LastForceSync is the value of the helm.bitnami.com/force-sync
+	// annotation handled by the last successful reconcile
+	LastForceSync string `json:"lastForceSync,omitempty"`
+}
+
+// OperationStatus is an install or upgrade in progress
+type OperationStatus struct {
+	// Phase is Installing or Upgrading
+	Phase HelmReleasePhase `json:"phase"`
+	// Revision is the Tiller revision being deployed
+	Revision int32 `json:"revision"`
+	// StartTime is when the operation was started
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// RemediationStatus counts the remediations of a failed release
+type RemediationStatus struct {
+	// Attempts is the number of remediations of the spec of generation
+	Attempts int32 `json:"attempts"`
+	// Generation is the generation of the HelmRelease remediated, attempts being reset when it changes
+	Generation int64 `json:"generation"`
+	// LastAttemptTime is when the release was last remediated
+	LastAttemptTime metav1.Time `json:"lastAttemptTime"`
+}
+
+// ChartUpdate is an update of the chart version of a release
+type ChartUpdate struct {
+	// PreviousVersion is the chart version before the update
+	PreviousVersion string `json:"previousVersion"`
+	// Version is the chart version updated to
+	Version string `json:"version"`
+	// Time is when the updated release was deployed
+	Time metav1.Time `json:"time"`
+}
+
+// FetchedValues identifies the content of a values file fetched from a URL
+type FetchedValues struct {
+	URL string `json:"url"`
+	// Checksum is the hex encoded SHA-256 checksum of the values file
+	Checksum string `json:"checksum"`
+}
+
+// RenderedManifestsReference locates the rendered manifests of a release
+// in the HelmRelease namespace
+type RenderedManifestsReference struct {
+	// Kind is ConfigMap, or Secret when values are read from Secrets
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// DryRunStatus describes the changes a dry-run install or upgrade would make
+type DryRunStatus struct {
+	// Action is install or upgrade
+	Action string `json:"action"`
+	// ChartVersion is the version of the chart that would be deployed
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// Added are the objects that would be created
+	Added []ResourceReference `json:"added,omitempty"`
+	// Changed are the objects that would be updated
+	Changed []ResourceReference `json:"changed,omitempty"`
+	// Removed are the objects that would be deleted
+	Removed []ResourceReference `json:"removed,omitempty"`
+}
+
+// UpgradeDiffStatus summarizes the changes of an upgrade
+type UpgradeDiffStatus struct {
+	// FromVersion is the chart version of the deployed release
+	FromVersion string `json:"fromVersion,omitempty"`
+	// ToVersion is the chart version of the upgrade
+	ToVersion string `json:"toVersion,omitempty"`
+	// Added, Changed and Removed are the numbers of objects created, updated and deleted
+	Added   int `json:"added"`
+	Changed int `json:"changed"`
+	Removed int `json:"removed"`
+	// Summary lists the added (+), changed (~) and removed (-) objects, truncated
+	Summary string `json:"summary,omitempty"`
+	// FullDiff locates the diff of the manifests, with spec.upgradeDiff.full
+	FullDiff *RenderedManifestsReference `json:"fullDiff,omitempty"`
+	// Checksum is the checksum of the upgraded release, approving the upgrade as the value of the
+	// approved annotation with manual upgrade approval
+	Checksum string `json:"checksum,omitempty"`
+	// Time is when the upgrade was compared with the deployed release
+	Time metav1.Time `json:"time"`
+}
+
+// HelmReleasePhase summarizes the state of a HelmRelease
+type HelmReleasePhase string
+
+const (
+	// PhasePending is set until the release is first installed
+	PhasePending HelmReleasePhase = "Pending"
+	// PhaseInstalling is set while Tiller installs the release
+	PhaseInstalling HelmReleasePhase = "Installing"
+	// PhaseUpgrading is set while Tiller upgrades the release
+	PhaseUpgrading HelmReleasePhase = "Upgrading"
+	// PhaseDeployed is set once the release, or the manifests of a
+	// render-only release, are up to date
+	PhaseDeployed HelmReleasePhase = "Deployed"
+	// PhaseFailed is set when the last reconcile failed, see FailureReason
+	// and FailureMessage
+	PhaseFailed HelmReleasePhase = "Failed"
+	// PhaseDeleting is set while the release is uninstalled
+	PhaseDeleting HelmReleasePhase = "Deleting"
+	// PhaseDeleted is set once the release is uninstalled, until the
+	// finalizer of the HelmRelease is removed
+	PhaseDeleted HelmReleasePhase = "Deleted"
+)
+
+// HelmReleaseConditionType is the type of a HelmReleaseCondition
+type HelmReleaseConditionType string
+
+const (
+	// HelmReleaseDrifted is True when deployed objects differ from the release manifest
+	HelmReleaseDrifted HelmReleaseConditionType = "Drifted"
+	// HelmReleaseStalled is True when the controller gave up retrying a failed reconcile
+	HelmReleaseStalled HelmReleaseConditionType = "Stalled"
+	// HelmReleaseReady is True when the last reconcile deployed the release,
+	// False when it failed
+	HelmReleaseReady HelmReleaseConditionType = "Ready"
+	// HelmReleaseConflict is True when another HelmRelease manages the
+	// release of the same name
+	HelmReleaseConflict HelmReleaseConditionType = "Conflict"
+	// HelmReleaseInterrupted is True when the controller shut down during
+	// an install, upgrade or deletion, until it is resumed
+	HelmReleaseInterrupted HelmReleaseConditionType = "Interrupted"
+	// HelmReleaseValuesInvalid is True when the values don't match the
+	// schema bundled with the chart
+	HelmReleaseValuesInvalid HelmReleaseConditionType = "ValuesInvalid"
+	// HelmReleasePolicyDenied is True when the Rego policies of the
+	// controller deny objects of the rendered release
+	HelmReleasePolicyDenied HelmReleaseConditionType = "PolicyDenied"
+	// HelmReleaseUpgradeDeferred is True when an upgrade waits for the
+	// upgrade window to open
+	HelmReleaseUpgradeDeferred HelmReleaseConditionType = "UpgradeDeferred"
+	// HelmReleaseRepoUnavailable is True when the chart repository failed
+	// too many consecutive requests, which fail fast until it recovers
+	HelmReleaseRepoUnavailable HelmReleaseConditionType = "RepoUnavailable"
+	// HelmReleaseLintFailed is True when linting the chart found errors,
+	// with the enforce lint mode
+	HelmReleaseLintFailed HelmReleaseConditionType = "LintFailed"
+	// HelmReleasePendingApproval is True when an upgrade waits for the
+	// helm.bitnami.com/approved annotation, with manual upgrade approval
+	HelmReleasePendingApproval HelmReleaseConditionType = "PendingApproval"
+	// HelmReleaseVerificationFailed is True when the chart has no valid
+	// signature, with the enforce verification mode
+	HelmReleaseVerificationFailed HelmReleaseConditionType = "VerificationFailed"
+	// HelmReleaseHealthy is True when the workloads of the release are
+	// ready, with spec.healthCheck
+	HelmReleaseHealthy HelmReleaseConditionType = "Healthy"
+	// HelmReleaseIgnored is True when the release is left alone, with
+	// the helm.bitnami.com/ignore annotation
+	HelmReleaseIgnored HelmReleaseConditionType = "Ignored"
+)
+
+// HelmReleaseCondition is an observation of the HelmRelease state
+type HelmReleaseCondition struct {
+	Type   HelmReleaseConditionType `json:"type"`
+	Status corev1.ConditionStatus   `json:"status"`
+	// LastTransitionTime is the last time Status changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a CamelCase reason for the last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the last transition
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceReference identifies an object deployed by a release
+type ResourceReference struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	// Namespace is empty for cluster scoped objects and for objects
+	// created in the release namespace
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type HelmReleaseAuth struct {
+	// Header is header based Authorization
+	Header *HelmReleaseAuthHeader `json:"header,omitempty"`
+	// Headers are sent along the requests, each read from a secret, e.g.
+	// X-API-Key or X-JFrog-Art-Api. All of them must have the same scope.
+	// Mutually exclusive with Header, they may only set Authorization
+	// without Bearer and Provider. Credentials sent as query parameters,
+	// such as SAS tokens, are not supported.
+	Headers map[string]HelmReleaseAuthHeader `json:"headers,omitempty"`
+	// Bearer sends a bearer token, read from a secret or issued by an
+	// OAuth2 token endpoint. Mutually exclusive with Header.
+	Bearer *HelmReleaseAuthBearer `json:"bearer,omitempty"`
+	// Provider gets short-lived credentials of a repository hosted by a
+	// cloud provider with the identity of the controller pod: GCP for
+	// Google Cloud Storage buckets and Artifact Registry, Azure for Azure
+	// Container Registries or AWS for Elastic Container Registries.
+	// Mutually exclusive with Header and Bearer.
+	Provider AuthProvider `json:"provider,omitempty"`
+}
+
+// AuthProvider is a cloud provider issuing repository credentials
+type AuthProvider string
+
+const (
+	// AuthProviderGCP sends the Google access token of the controller pod
+	AuthProviderGCP AuthProvider = "GCP"
+	// AuthProviderAzure sends an Azure Container Registry refresh token
+	// exchanged for the Azure AD token of the controller pod
+	AuthProviderAzure AuthProvider = "Azure"
+	// AuthProviderAWS sends an Elastic Container Registry authorization
+	// token issued for the IAM role of the controller pod
+	AuthProviderAWS AuthProvider = "AWS"
+)
+
+type HelmReleaseAuthHeader struct {
+	// Selects a key of a secret in the namespace given by Scope
+	SecretKeyRef corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// Scope is where the secret is read from: the controller namespace (Controller) or the namespace
+	// of the HelmRelease (Namespace). Defaults to the controller --default-auth-scope.
+	Scope AuthScope `json:"scope,omitempty"`
+}
+
+// HelmReleaseAuthBearer authenticates with a bearer token, either read
+// from a secret or, with TokenURL, issued with the OAuth2 client
+// credentials grant and renewed by the controller before it expires
+type HelmReleaseAuthBearer struct {
+	// Selects a key of a secret in the namespace given by Scope holding the token, or the client secret with TokenURL
+	SecretKeyRef corev1.SecretKeySelector `json:"secretKeyRef"`
+	// Scope is where the secret is read from, as for header
+	Scope AuthScope `json:"scope,omitempty"`
+	// TokenURL is the OAuth2 token endpoint issuing access tokens for the client credentials
+	TokenURL string `json:"tokenURL,omitempty"`
+	// ClientID is the OAuth2 client ID, required with TokenURL
+	ClientID string `json:"clientID,omitempty"`
+	// Scopes are the OAuth2 scopes requested with TokenURL
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// AuthScope is the namespace auth secrets are read from
+type AuthScope string
+
+const (
+	// AuthScopeController reads auth secrets from the controller namespace
+	AuthScopeController AuthScope = "Controller"
+	// AuthScopeNamespace reads auth secrets from the namespace of the HelmRelease
+	AuthScopeNamespace AuthScope = "Namespace"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HelmReleaseList is a list of HelmRelease resources
+type HelmReleaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HelmRelease `json:"items"`
+}
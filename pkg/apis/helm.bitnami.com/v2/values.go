@@ -0,0 +1,42 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ghodss/yaml"
+)
+
+// Values are the YAML values of a release. They are serialized as a JSON
+// object, so that they're stored structured in the HelmRelease,
+// validated at admission and patched like any other field, and also
+// decoded from the string of YAML of earlier HelmReleases.
+type Values string
+
+// MarshalJSON encodes v as an object, or as a string if it isn't a map
+// of YAML, to be reported by validation
+func (v Values) MarshalJSON() ([]byte, error) {
+	if obj, err := yaml.YAMLToJSON([]byte(v)); err == nil && bytes.HasPrefix(obj, []byte("{")) {
+		return obj, nil
+	}
+	return json.Marshal(string(v))
+}
+
+// UnmarshalJSON decodes an object, or a string of YAML
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = Values(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*v = ""
+		return nil
+	}
+	y, err := yaml.JSONToYAML(data)
+	if err != nil {
+		return err
+	}
+	*v = Values(y)
+	return nil
+}
@@ -0,0 +1,54 @@
+package v2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValuesJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   Values
+		expected string
+	}{
+		{"map", "image:\n  tag: \"1.10\"\nreplicas: 3\n", `{"values":{"image":{"tag":"1.10"},"replicas":3}}`},
+		{"empty", "", `{}`},
+		{"not a map", "- foo\n", `{"values":"- foo\n"}`},
+		{"invalid", "foo: [bar", `{"values":"foo: [bar"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := struct {
+				Values Values `json:"values,omitempty"`
+			}{tt.values}
+			data, err := json.Marshal(spec)
+			if err != nil || string(data) != tt.expected {
+				t.Errorf("Expecting %s, received %s (%v)", tt.expected, data, err)
+			}
+			spec.Values = ""
+			if err := json.Unmarshal(data, &spec); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if tt.name != "map" && spec.Values != tt.values {
+				t.Errorf("Expecting %q, received %q", tt.values, spec.Values)
+			}
+		})
+	}
+}
+
+func TestValuesUnmarshal(t *testing.T) {
+	var spec HelmReleaseSpec
+	// Structured values, and the string of YAML of earlier HelmReleases
+	if err := json.Unmarshal([]byte(`{"values":{"replicas":3,"image":{"tag":"1.10"}}}`), &spec); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := "image:\n  tag: \"1.10\"\nreplicas: 3\n"; string(spec.Values) != expected {
+		t.Errorf("Expecting %q, received %q", expected, spec.Values)
+	}
+	if err := json.Unmarshal([]byte(`{"values":"replicas: 3\n"}`), &spec); err != nil || spec.Values != "replicas: 3\n" {
+		t.Errorf("Expecting the values string, received %q (%v)", spec.Values, err)
+	}
+	if err := json.Unmarshal([]byte(`{"values":null}`), &spec); err != nil || spec.Values != "" {
+		t.Errorf("Expecting no values, received %q (%v)", spec.Values, err)
+	}
+}
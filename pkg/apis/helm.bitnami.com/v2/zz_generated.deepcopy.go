@@ -0,0 +1,1990 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file was autogenerated by deepcopy-gen. Do not edit it manually!
+
+package v2
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	conversion "k8s.io/apimachinery/pkg/conversion"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	reflect "reflect"
+)
+
+func init() {
+	SchemeBuilder.Register(RegisterDeepCopies)
+}
+
+// RegisterDeepCopies adds deep-copy functions to the given scheme. Public
+// to allow building arbitrary schemes.
+//
+// Deprecated: deepcopy registration will go away when static deepcopy is fully implemented.
+func RegisterDeepCopies(scheme *runtime.Scheme) error {
+	return scheme.AddGeneratedDeepCopyFuncs(
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ChartRule).DeepCopyInto(out.(*ChartRule))
+			return nil
+		}, InType: reflect.TypeOf(&ChartRule{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ChartSource).DeepCopyInto(out.(*ChartSource))
+			return nil
+		}, InType: reflect.TypeOf(&ChartSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ChartUpdate).DeepCopyInto(out.(*ChartUpdate))
+			return nil
+		}, InType: reflect.TypeOf(&ChartUpdate{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*DriftDetectionSpec).DeepCopyInto(out.(*DriftDetectionSpec))
+			return nil
+		}, InType: reflect.TypeOf(&DriftDetectionSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*DryRunStatus).DeepCopyInto(out.(*DryRunStatus))
+			return nil
+		}, InType: reflect.TypeOf(&DryRunStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*FetchedValues).DeepCopyInto(out.(*FetchedValues))
+			return nil
+		}, InType: reflect.TypeOf(&FetchedValues{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HealthCheck).DeepCopyInto(out.(*HealthCheck))
+			return nil
+		}, InType: reflect.TypeOf(&HealthCheck{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HealthCheckSpec).DeepCopyInto(out.(*HealthCheckSpec))
+			return nil
+		}, InType: reflect.TypeOf(&HealthCheckSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmRelease).DeepCopyInto(out.(*HelmRelease))
+			return nil
+		}, InType: reflect.TypeOf(&HelmRelease{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseAuth).DeepCopyInto(out.(*HelmReleaseAuth))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseAuth{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseAuthBearer).DeepCopyInto(out.(*HelmReleaseAuthBearer))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseAuthBearer{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseAuthHeader).DeepCopyInto(out.(*HelmReleaseAuthHeader))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseAuthHeader{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseCondition).DeepCopyInto(out.(*HelmReleaseCondition))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseCondition{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseList).DeepCopyInto(out.(*HelmReleaseList))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseList{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseOutputRef).DeepCopyInto(out.(*HelmReleaseOutputRef))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseOutputRef{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleasePolicy).DeepCopyInto(out.(*HelmReleasePolicy))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleasePolicy{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleasePolicyList).DeepCopyInto(out.(*HelmReleasePolicyList))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleasePolicyList{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleasePolicySpec).DeepCopyInto(out.(*HelmReleasePolicySpec))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleasePolicySpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSet).DeepCopyInto(out.(*HelmReleaseSet))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSet{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSetItem).DeepCopyInto(out.(*HelmReleaseSetItem))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSetItem{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSetList).DeepCopyInto(out.(*HelmReleaseSetList))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSetList{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSetSpec).DeepCopyInto(out.(*HelmReleaseSetSpec))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSetSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSetStatus).DeepCopyInto(out.(*HelmReleaseSetStatus))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSetStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseSpec).DeepCopyInto(out.(*HelmReleaseSpec))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseStatus).DeepCopyInto(out.(*HelmReleaseStatus))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*HelmReleaseTemplate).DeepCopyInto(out.(*HelmReleaseTemplate))
+			return nil
+		}, InType: reflect.TypeOf(&HelmReleaseTemplate{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*InlineChartMetadata).DeepCopyInto(out.(*InlineChartMetadata))
+			return nil
+		}, InType: reflect.TypeOf(&InlineChartMetadata{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*InlineChartSource).DeepCopyInto(out.(*InlineChartSource))
+			return nil
+		}, InType: reflect.TypeOf(&InlineChartSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*JSON6902Patch).DeepCopyInto(out.(*JSON6902Patch))
+			return nil
+		}, InType: reflect.TypeOf(&JSON6902Patch{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*KeylessIdentity).DeepCopyInto(out.(*KeylessIdentity))
+			return nil
+		}, InType: reflect.TypeOf(&KeylessIdentity{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*KustomizeImage).DeepCopyInto(out.(*KustomizeImage))
+			return nil
+		}, InType: reflect.TypeOf(&KustomizeImage{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*KustomizeSpec).DeepCopyInto(out.(*KustomizeSpec))
+			return nil
+		}, InType: reflect.TypeOf(&KustomizeSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*LintSpec).DeepCopyInto(out.(*LintSpec))
+			return nil
+		}, InType: reflect.TypeOf(&LintSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*NamespaceMetadata).DeepCopyInto(out.(*NamespaceMetadata))
+			return nil
+		}, InType: reflect.TypeOf(&NamespaceMetadata{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*OCIChartSource).DeepCopyInto(out.(*OCIChartSource))
+			return nil
+		}, InType: reflect.TypeOf(&OCIChartSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ObjectFieldRef).DeepCopyInto(out.(*ObjectFieldRef))
+			return nil
+		}, InType: reflect.TypeOf(&ObjectFieldRef{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*OperationStatus).DeepCopyInto(out.(*OperationStatus))
+			return nil
+		}, InType: reflect.TypeOf(&OperationStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*PatchTarget).DeepCopyInto(out.(*PatchTarget))
+			return nil
+		}, InType: reflect.TypeOf(&PatchTarget{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*PostRenderSpec).DeepCopyInto(out.(*PostRenderSpec))
+			return nil
+		}, InType: reflect.TypeOf(&PostRenderSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*PropagateMetadata).DeepCopyInto(out.(*PropagateMetadata))
+			return nil
+		}, InType: reflect.TypeOf(&PropagateMetadata{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RecoverySpec).DeepCopyInto(out.(*RecoverySpec))
+			return nil
+		}, InType: reflect.TypeOf(&RecoverySpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ReleaseOutput).DeepCopyInto(out.(*ReleaseOutput))
+			return nil
+		}, InType: reflect.TypeOf(&ReleaseOutput{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RemediationSpec).DeepCopyInto(out.(*RemediationSpec))
+			return nil
+		}, InType: reflect.TypeOf(&RemediationSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RemediationStatus).DeepCopyInto(out.(*RemediationStatus))
+			return nil
+		}, InType: reflect.TypeOf(&RemediationStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RenderedManifestsReference).DeepCopyInto(out.(*RenderedManifestsReference))
+			return nil
+		}, InType: reflect.TypeOf(&RenderedManifestsReference{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RepositoryChartSource).DeepCopyInto(out.(*RepositoryChartSource))
+			return nil
+		}, InType: reflect.TypeOf(&RepositoryChartSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RepositoryCredential).DeepCopyInto(out.(*RepositoryCredential))
+			return nil
+		}, InType: reflect.TypeOf(&RepositoryCredential{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RepositoryCredentialList).DeepCopyInto(out.(*RepositoryCredentialList))
+			return nil
+		}, InType: reflect.TypeOf(&RepositoryCredentialList{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RepositoryCredentialSpec).DeepCopyInto(out.(*RepositoryCredentialSpec))
+			return nil
+		}, InType: reflect.TypeOf(&RepositoryCredentialSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ResourceHealthCheck).DeepCopyInto(out.(*ResourceHealthCheck))
+			return nil
+		}, InType: reflect.TypeOf(&ResourceHealthCheck{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ResourceReference).DeepCopyInto(out.(*ResourceReference))
+			return nil
+		}, InType: reflect.TypeOf(&ResourceReference{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*RollbackSpec).DeepCopyInto(out.(*RollbackSpec))
+			return nil
+		}, InType: reflect.TypeOf(&RollbackSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*TarballChartSource).DeepCopyInto(out.(*TarballChartSource))
+			return nil
+		}, InType: reflect.TypeOf(&TarballChartSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*TestSpec).DeepCopyInto(out.(*TestSpec))
+			return nil
+		}, InType: reflect.TypeOf(&TestSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*TimeRange).DeepCopyInto(out.(*TimeRange))
+			return nil
+		}, InType: reflect.TypeOf(&TimeRange{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*UninstallSpec).DeepCopyInto(out.(*UninstallSpec))
+			return nil
+		}, InType: reflect.TypeOf(&UninstallSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*UpgradeDiffSpec).DeepCopyInto(out.(*UpgradeDiffSpec))
+			return nil
+		}, InType: reflect.TypeOf(&UpgradeDiffSpec{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*UpgradeDiffStatus).DeepCopyInto(out.(*UpgradeDiffStatus))
+			return nil
+		}, InType: reflect.TypeOf(&UpgradeDiffStatus{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*UpgradeWindow).DeepCopyInto(out.(*UpgradeWindow))
+			return nil
+		}, InType: reflect.TypeOf(&UpgradeWindow{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*ValuesSource).DeepCopyInto(out.(*ValuesSource))
+			return nil
+		}, InType: reflect.TypeOf(&ValuesSource{})},
+		conversion.GeneratedDeepCopyFunc{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
+			in.(*VerificationSpec).DeepCopyInto(out.(*VerificationSpec))
+			return nil
+		}, InType: reflect.TypeOf(&VerificationSpec{})},
+	)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartRule) DeepCopyInto(out *ChartRule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartRule.
+func (in *ChartRule) DeepCopy() *ChartRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartSource) DeepCopyInto(out *ChartSource) {
+	*out = *in
+	if in.Repository != nil {
+		in, out := &in.Repository, &out.Repository
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RepositoryChartSource)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Tarball != nil {
+		in, out := &in.Tarball, &out.Tarball
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TarballChartSource)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(OCIChartSource)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(InlineChartSource)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartSource.
+func (in *ChartSource) DeepCopy() *ChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartUpdate) DeepCopyInto(out *ChartUpdate) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartUpdate.
+func (in *ChartUpdate) DeepCopy() *ChartUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftDetectionSpec) DeepCopyInto(out *DriftDetectionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftDetectionSpec.
+func (in *DriftDetectionSpec) DeepCopy() *DriftDetectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftDetectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
+	*out = *in
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]ResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changed != nil {
+		in, out := &in.Changed, &out.Changed
+		*out = make([]ResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]ResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FetchedValues) DeepCopyInto(out *FetchedValues) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FetchedValues.
+func (in *FetchedValues) DeepCopy() *FetchedValues {
+	if in == nil {
+		return nil
+	}
+	out := new(FetchedValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.Resource != nil {
+		in, out := &in.Resource, &out.Resource
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ResourceHealthCheck)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckSpec) DeepCopyInto(out *HealthCheckSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckSpec.
+func (in *HealthCheckSpec) DeepCopy() *HealthCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmRelease) DeepCopyInto(out *HelmRelease) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmRelease.
+func (in *HelmRelease) DeepCopy() *HelmRelease {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmRelease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmRelease) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseAuth) DeepCopyInto(out *HelmReleaseAuth) {
+	*out = *in
+	if in.Header != nil {
+		in, out := &in.Header, &out.Header
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(HelmReleaseAuthHeader)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]HelmReleaseAuthHeader, len(*in))
+		for key, val := range *in {
+			newVal := new(HelmReleaseAuthHeader)
+			val.DeepCopyInto(newVal)
+			(*out)[key] = *newVal
+		}
+	}
+	if in.Bearer != nil {
+		in, out := &in.Bearer, &out.Bearer
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(HelmReleaseAuthBearer)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseAuth.
+func (in *HelmReleaseAuth) DeepCopy() *HelmReleaseAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseAuthBearer) DeepCopyInto(out *HelmReleaseAuthBearer) {
+	*out = *in
+	in.SecretKeyRef.DeepCopyInto(&out.SecretKeyRef)
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseAuthBearer.
+func (in *HelmReleaseAuthBearer) DeepCopy() *HelmReleaseAuthBearer {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseAuthBearer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseAuthHeader) DeepCopyInto(out *HelmReleaseAuthHeader) {
+	*out = *in
+	in.SecretKeyRef.DeepCopyInto(&out.SecretKeyRef)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseAuthHeader.
+func (in *HelmReleaseAuthHeader) DeepCopy() *HelmReleaseAuthHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseAuthHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseCondition) DeepCopyInto(out *HelmReleaseCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseCondition.
+func (in *HelmReleaseCondition) DeepCopy() *HelmReleaseCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseList) DeepCopyInto(out *HelmReleaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmRelease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseList.
+func (in *HelmReleaseList) DeepCopy() *HelmReleaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseOutputRef) DeepCopyInto(out *HelmReleaseOutputRef) {
+	*out = *in
+	if in.Optional != nil {
+		in, out := &in.Optional, &out.Optional
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseOutputRef.
+func (in *HelmReleaseOutputRef) DeepCopy() *HelmReleaseOutputRef {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseOutputRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleasePolicy) DeepCopyInto(out *HelmReleasePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleasePolicy.
+func (in *HelmReleasePolicy) DeepCopy() *HelmReleasePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleasePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleasePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleasePolicyList) DeepCopyInto(out *HelmReleasePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmReleasePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleasePolicyList.
+func (in *HelmReleasePolicyList) DeepCopy() *HelmReleasePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleasePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleasePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleasePolicySpec) DeepCopyInto(out *HelmReleasePolicySpec) {
+	*out = *in
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]ChartRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleasePolicySpec.
+func (in *HelmReleasePolicySpec) DeepCopy() *HelmReleasePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleasePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSet) DeepCopyInto(out *HelmReleaseSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSet.
+func (in *HelmReleaseSet) DeepCopy() *HelmReleaseSet {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleaseSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSetItem) DeepCopyInto(out *HelmReleaseSetItem) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSetItem.
+func (in *HelmReleaseSetItem) DeepCopy() *HelmReleaseSetItem {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSetItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSetList) DeepCopyInto(out *HelmReleaseSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmReleaseSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSetList.
+func (in *HelmReleaseSetList) DeepCopy() *HelmReleaseSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HelmReleaseSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSetSpec) DeepCopyInto(out *HelmReleaseSetSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HelmReleaseSetItem, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.LabelSelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSetSpec.
+func (in *HelmReleaseSetSpec) DeepCopy() *HelmReleaseSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSetStatus) DeepCopyInto(out *HelmReleaseSetStatus) {
+	*out = *in
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSetStatus.
+func (in *HelmReleaseSetStatus) DeepCopy() *HelmReleaseSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseSpec) DeepCopyInto(out *HelmReleaseSpec) {
+	*out = *in
+	in.Chart.DeepCopyInto(&out.Chart)
+	if in.KubeConfigSecretRef != nil {
+		in, out := &in.KubeConfigSecretRef, &out.KubeConfigSecretRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.NamespaceMetadata != nil {
+		in, out := &in.NamespaceMetadata, &out.NamespaceMetadata
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(NamespaceMetadata)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.APIVersions != nil {
+		in, out := &in.APIVersions, &out.APIVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RollbackSpec)
+			**out = **in
+		}
+	}
+	if in.Recovery != nil {
+		in, out := &in.Recovery, &out.Recovery
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RecoverySpec)
+			**out = **in
+		}
+	}
+	if in.Remediation != nil {
+		in, out := &in.Remediation, &out.Remediation
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RemediationSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(HealthCheckSpec)
+			**out = **in
+		}
+	}
+	if in.HealthChecks != nil {
+		in, out := &in.HealthChecks, &out.HealthChecks
+		*out = make([]HealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Test != nil {
+		in, out := &in.Test, &out.Test
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(TestSpec)
+			**out = **in
+		}
+	}
+	if in.UpgradeWindow != nil {
+		in, out := &in.UpgradeWindow, &out.UpgradeWindow
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UpgradeWindow)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PostRender != nil {
+		in, out := &in.PostRender, &out.PostRender
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PostRenderSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PropagateMetadata != nil {
+		in, out := &in.PropagateMetadata, &out.PropagateMetadata
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PropagateMetadata)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.DriftDetection != nil {
+		in, out := &in.DriftDetection, &out.DriftDetection
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(DriftDetectionSpec)
+			**out = **in
+		}
+	}
+	if in.Uninstall != nil {
+		in, out := &in.Uninstall, &out.Uninstall
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UninstallSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Lint != nil {
+		in, out := &in.Lint, &out.Lint
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(LintSpec)
+			**out = **in
+		}
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(VerificationSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.UpgradeDiff != nil {
+		in, out := &in.UpgradeDiff, &out.UpgradeDiff
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UpgradeDiffSpec)
+			**out = **in
+		}
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]ReleaseOutput, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseSpec.
+func (in *HelmReleaseSpec) DeepCopy() *HelmReleaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseStatus) DeepCopyInto(out *HelmReleaseStatus) {
+	*out = *in
+	if in.LastChartUpdate != nil {
+		in, out := &in.LastChartUpdate, &out.LastChartUpdate
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ChartUpdate)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.LastDeployed != nil {
+		in, out := &in.LastDeployed, &out.LastDeployed
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(meta_v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]HelmReleaseCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RenderedManifests != nil {
+		in, out := &in.RenderedManifests, &out.RenderedManifests
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RenderedManifestsReference)
+			**out = **in
+		}
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(DryRunStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.UpgradeDiff != nil {
+		in, out := &in.UpgradeDiff, &out.UpgradeDiff
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(UpgradeDiffStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.LintWarnings != nil {
+		in, out := &in.LintWarnings, &out.LintWarnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FetchedValues != nil {
+		in, out := &in.FetchedValues, &out.FetchedValues
+		*out = make([]FetchedValues, len(*in))
+		copy(*out, *in)
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Operation != nil {
+		in, out := &in.Operation, &out.Operation
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(OperationStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Remediation != nil {
+		in, out := &in.Remediation, &out.Remediation
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RemediationStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseStatus.
+func (in *HelmReleaseStatus) DeepCopy() *HelmReleaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseTemplate) DeepCopyInto(out *HelmReleaseTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseTemplate.
+func (in *HelmReleaseTemplate) DeepCopy() *HelmReleaseTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InlineChartMetadata) DeepCopyInto(out *InlineChartMetadata) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InlineChartMetadata.
+func (in *InlineChartMetadata) DeepCopy() *InlineChartMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(InlineChartMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InlineChartSource) DeepCopyInto(out *InlineChartSource) {
+	*out = *in
+	out.Metadata = in.Metadata
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InlineChartSource.
+func (in *InlineChartSource) DeepCopy() *InlineChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(InlineChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JSON6902Patch) DeepCopyInto(out *JSON6902Patch) {
+	*out = *in
+	out.Target = in.Target
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JSON6902Patch.
+func (in *JSON6902Patch) DeepCopy() *JSON6902Patch {
+	if in == nil {
+		return nil
+	}
+	out := new(JSON6902Patch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeylessIdentity) DeepCopyInto(out *KeylessIdentity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeylessIdentity.
+func (in *KeylessIdentity) DeepCopy() *KeylessIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(KeylessIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeImage) DeepCopyInto(out *KustomizeImage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeImage.
+func (in *KustomizeImage) DeepCopy() *KustomizeImage {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeSpec) DeepCopyInto(out *KustomizeSpec) {
+	*out = *in
+	if in.PatchesStrategicMerge != nil {
+		in, out := &in.PatchesStrategicMerge, &out.PatchesStrategicMerge
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]KustomizeImage, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeSpec.
+func (in *KustomizeSpec) DeepCopy() *KustomizeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LintSpec) DeepCopyInto(out *LintSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LintSpec.
+func (in *LintSpec) DeepCopy() *LintSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LintSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceMetadata) DeepCopyInto(out *NamespaceMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceMetadata.
+func (in *NamespaceMetadata) DeepCopy() *NamespaceMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIChartSource) DeepCopyInto(out *OCIChartSource) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIChartSource.
+func (in *OCIChartSource) DeepCopy() *OCIChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectFieldRef) DeepCopyInto(out *ObjectFieldRef) {
+	*out = *in
+	if in.Optional != nil {
+		in, out := &in.Optional, &out.Optional
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectFieldRef.
+func (in *ObjectFieldRef) DeepCopy() *ObjectFieldRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectFieldRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationStatus) DeepCopyInto(out *OperationStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationStatus.
+func (in *OperationStatus) DeepCopy() *OperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderSpec) DeepCopyInto(out *PostRenderSpec) {
+	*out = *in
+	if in.Kustomize != nil {
+		in, out := &in.Kustomize, &out.Kustomize
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(KustomizeSpec)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PatchesJSON6902 != nil {
+		in, out := &in.PatchesJSON6902, &out.PatchesJSON6902
+		*out = make([]JSON6902Patch, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderSpec.
+func (in *PostRenderSpec) DeepCopy() *PostRenderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRenderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropagateMetadata) DeepCopyInto(out *PropagateMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropagateMetadata.
+func (in *PropagateMetadata) DeepCopy() *PropagateMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(PropagateMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoverySpec) DeepCopyInto(out *RecoverySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoverySpec.
+func (in *RecoverySpec) DeepCopy() *RecoverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseOutput) DeepCopyInto(out *ReleaseOutput) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseOutput.
+func (in *ReleaseOutput) DeepCopy() *ReleaseOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationSpec) DeepCopyInto(out *RemediationSpec) {
+	*out = *in
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationSpec.
+func (in *RemediationSpec) DeepCopy() *RemediationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationStatus) DeepCopyInto(out *RemediationStatus) {
+	*out = *in
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationStatus.
+func (in *RemediationStatus) DeepCopy() *RemediationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RenderedManifestsReference) DeepCopyInto(out *RenderedManifestsReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderedManifestsReference.
+func (in *RenderedManifestsReference) DeepCopy() *RenderedManifestsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RenderedManifestsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryChartSource) DeepCopyInto(out *RepositoryChartSource) {
+	*out = *in
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Auth.DeepCopyInto(&out.Auth)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryChartSource.
+func (in *RepositoryChartSource) DeepCopy() *RepositoryChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCredential) DeepCopyInto(out *RepositoryCredential) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCredential.
+func (in *RepositoryCredential) DeepCopy() *RepositoryCredential {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCredential)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCredential) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCredentialList) DeepCopyInto(out *RepositoryCredentialList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryCredential, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCredentialList.
+func (in *RepositoryCredentialList) DeepCopy() *RepositoryCredentialList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCredentialList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCredentialList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	} else {
+		return nil
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCredentialSpec) DeepCopyInto(out *RepositoryCredentialSpec) {
+	*out = *in
+	if in.URLPrefixes != nil {
+		in, out := &in.URLPrefixes, &out.URLPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Auth.DeepCopyInto(&out.Auth)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCredentialSpec.
+func (in *RepositoryCredentialSpec) DeepCopy() *RepositoryCredentialSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCredentialSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceHealthCheck) DeepCopyInto(out *ResourceHealthCheck) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceHealthCheck.
+func (in *ResourceHealthCheck) DeepCopy() *ResourceHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceReference) DeepCopyInto(out *ResourceReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceReference.
+func (in *ResourceReference) DeepCopy() *ResourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackSpec) DeepCopyInto(out *RollbackSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackSpec.
+func (in *RollbackSpec) DeepCopy() *RollbackSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TarballChartSource) DeepCopyInto(out *TarballChartSource) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TarballChartSource.
+func (in *TarballChartSource) DeepCopy() *TarballChartSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TarballChartSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestSpec) DeepCopyInto(out *TestSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestSpec.
+func (in *TestSpec) DeepCopy() *TestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeRange) DeepCopyInto(out *TimeRange) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeRange.
+func (in *TimeRange) DeepCopy() *TimeRange {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UninstallSpec) DeepCopyInto(out *UninstallSpec) {
+	*out = *in
+	if in.Purge != nil {
+		in, out := &in.Purge, &out.Purge
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UninstallSpec.
+func (in *UninstallSpec) DeepCopy() *UninstallSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UninstallSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeDiffSpec) DeepCopyInto(out *UpgradeDiffSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeDiffSpec.
+func (in *UpgradeDiffSpec) DeepCopy() *UpgradeDiffSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeDiffSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeDiffStatus) DeepCopyInto(out *UpgradeDiffStatus) {
+	*out = *in
+	if in.FullDiff != nil {
+		in, out := &in.FullDiff, &out.FullDiff
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RenderedManifestsReference)
+			**out = **in
+		}
+	}
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeDiffStatus.
+func (in *UpgradeDiffStatus) DeepCopy() *UpgradeDiffStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeDiffStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeWindow) DeepCopyInto(out *UpgradeWindow) {
+	*out = *in
+	if in.Ranges != nil {
+		in, out := &in.Ranges, &out.Ranges
+		*out = make([]TimeRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeWindow.
+func (in *UpgradeWindow) DeepCopy() *UpgradeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesSource) DeepCopyInto(out *ValuesSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.ConfigMapKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.FieldRef != nil {
+		in, out := &in.FieldRef, &out.FieldRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ObjectFieldRef)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.AuthSecretKeyRef != nil {
+		in, out := &in.AuthSecretKeyRef, &out.AuthSecretKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.HelmReleaseRef != nil {
+		in, out := &in.HelmReleaseRef, &out.HelmReleaseRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(HelmReleaseOutputRef)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesSource.
+func (in *ValuesSource) DeepCopy() *ValuesSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationSpec) DeepCopyInto(out *VerificationSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.LocalObjectReference)
+			**out = **in
+		}
+	}
+	if in.Keyless != nil {
+		in, out := &in.Keyless, &out.Keyless
+		*out = make([]KeylessIdentity, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationSpec.
+func (in *VerificationSpec) DeepCopy() *VerificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
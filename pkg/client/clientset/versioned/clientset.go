@@ -17,6 +17,7 @@ package versioned
 
 import (
 	helmv1 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v1"
+	helmv2 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v2"
 	glog "github.com/golang/glog"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
@@ -26,8 +27,9 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	HelmV1() helmv1.HelmV1Interface
+	HelmV2() helmv2.HelmV2Interface
 	// Deprecated: please explicitly pick a version if possible.
-	Helm() helmv1.HelmV1Interface
+	Helm() helmv2.HelmV2Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -35,6 +37,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	helmV1 *helmv1.HelmV1Client
+	helmV2 *helmv2.HelmV2Client
 }
 
 // HelmV1 retrieves the HelmV1Client
@@ -42,10 +45,15 @@ func (c *Clientset) HelmV1() helmv1.HelmV1Interface {
 	return c.helmV1
 }
 
+// HelmV2 retrieves the HelmV2Client
+func (c *Clientset) HelmV2() helmv2.HelmV2Interface {
+	return c.helmV2
+}
+
 // Deprecated: Helm retrieves the default version of HelmClient.
 // Please explicitly pick a version.
-func (c *Clientset) Helm() helmv1.HelmV1Interface {
-	return c.helmV1
+func (c *Clientset) Helm() helmv2.HelmV2Interface {
+	return c.helmV2
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -68,6 +76,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.helmV2, err = helmv2.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -82,6 +94,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.helmV1 = helmv1.NewForConfigOrDie(c)
+	cs.helmV2 = helmv2.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -91,6 +104,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.helmV1 = helmv1.New(c)
+	cs.helmV2 = helmv2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -19,6 +19,8 @@ import (
 	clientset "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned"
 	helmv1 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v1"
 	fakehelmv1 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v1/fake"
+	helmv2 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v2"
+	fakehelmv2 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v2/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -64,7 +66,12 @@ func (c *Clientset) HelmV1() helmv1.HelmV1Interface {
 	return &fakehelmv1.FakeHelmV1{Fake: &c.Fake}
 }
 
-// Helm retrieves the HelmV1Client
-func (c *Clientset) Helm() helmv1.HelmV1Interface {
-	return &fakehelmv1.FakeHelmV1{Fake: &c.Fake}
+// HelmV2 retrieves the HelmV2Client
+func (c *Clientset) HelmV2() helmv2.HelmV2Interface {
+	return &fakehelmv2.FakeHelmV2{Fake: &c.Fake}
+}
+
+// Helm retrieves the HelmV2Client
+func (c *Clientset) Helm() helmv2.HelmV2Interface {
+	return &fakehelmv2.FakeHelmV2{Fake: &c.Fake}
 }
@@ -17,6 +17,7 @@ package fake
 
 import (
 	helmv1 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v1"
+	helmv2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -48,5 +49,6 @@ func init() {
 // correctly.
 func AddToScheme(scheme *runtime.Scheme) {
 	helmv1.AddToScheme(scheme)
+	helmv2.AddToScheme(scheme)
 
 }
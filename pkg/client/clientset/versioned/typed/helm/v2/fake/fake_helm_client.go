@@ -0,0 +1,49 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	v2 "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/typed/helm/v2"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+type FakeHelmV2 struct {
+	*testing.Fake
+}
+
+func (c *FakeHelmV2) HelmReleases(namespace string) v2.HelmReleaseInterface {
+	return &FakeHelmReleases{c, namespace}
+}
+
+func (c *FakeHelmV2) HelmReleasePolicies(namespace string) v2.HelmReleasePolicyInterface {
+	return &FakeHelmReleasePolicies{c, namespace}
+}
+
+func (c *FakeHelmV2) HelmReleaseSets(namespace string) v2.HelmReleaseSetInterface {
+	return &FakeHelmReleaseSets{c, namespace}
+}
+
+func (c *FakeHelmV2) RepositoryCredentials(namespace string) v2.RepositoryCredentialInterface {
+	return &FakeRepositoryCredentials{c, namespace}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeHelmV2) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
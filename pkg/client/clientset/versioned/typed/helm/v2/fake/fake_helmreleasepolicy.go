@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	helm_bitnami_com_v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHelmReleasePolicies implements HelmReleasePolicyInterface
+type FakeHelmReleasePolicies struct {
+	Fake *FakeHelmV2
+	ns   string
+}
+
+var helmreleasepoliciesResource = schema.GroupVersionResource{Group: "helm.bitnami.com", Version: "v2", Resource: "helmreleasepolicies"}
+
+var helmreleasepoliciesKind = schema.GroupVersionKind{Group: "helm.bitnami.com", Version: "v2", Kind: "HelmReleasePolicy"}
+
+// Get takes name of the helmReleasePolicy, and returns the corresponding helmReleasePolicy object, and an error if there is any.
+func (c *FakeHelmReleasePolicies) Get(name string, options v1.GetOptions) (result *helm_bitnami_com_v2.HelmReleasePolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(helmreleasepoliciesResource, c.ns, name), &helm_bitnami_com_v2.HelmReleasePolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleasePolicy), err
+}
+
+// List takes label and field selectors, and returns the list of HelmReleasePolicies that match those selectors.
+func (c *FakeHelmReleasePolicies) List(opts v1.ListOptions) (result *helm_bitnami_com_v2.HelmReleasePolicyList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(helmreleasepoliciesResource, helmreleasepoliciesKind, c.ns, opts), &helm_bitnami_com_v2.HelmReleasePolicyList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &helm_bitnami_com_v2.HelmReleasePolicyList{}
+	for _, item := range obj.(*helm_bitnami_com_v2.HelmReleasePolicyList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested helmReleasePolicies.
+func (c *FakeHelmReleasePolicies) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(helmreleasepoliciesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a helmReleasePolicy and creates it.  Returns the server's representation of the helmReleasePolicy, and an error, if there is any.
+func (c *FakeHelmReleasePolicies) Create(helmReleasePolicy *helm_bitnami_com_v2.HelmReleasePolicy) (result *helm_bitnami_com_v2.HelmReleasePolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(helmreleasepoliciesResource, c.ns, helmReleasePolicy), &helm_bitnami_com_v2.HelmReleasePolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleasePolicy), err
+}
+
+// Update takes the representation of a helmReleasePolicy and updates it. Returns the server's representation of the helmReleasePolicy, and an error, if there is any.
+func (c *FakeHelmReleasePolicies) Update(helmReleasePolicy *helm_bitnami_com_v2.HelmReleasePolicy) (result *helm_bitnami_com_v2.HelmReleasePolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(helmreleasepoliciesResource, c.ns, helmReleasePolicy), &helm_bitnami_com_v2.HelmReleasePolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleasePolicy), err
+}
+
+// Delete takes name of the helmReleasePolicy and deletes it. Returns an error if one occurs.
+func (c *FakeHelmReleasePolicies) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(helmreleasepoliciesResource, c.ns, name), &helm_bitnami_com_v2.HelmReleasePolicy{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHelmReleasePolicies) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(helmreleasepoliciesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &helm_bitnami_com_v2.HelmReleasePolicyList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched helmReleasePolicy.
+func (c *FakeHelmReleasePolicies) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *helm_bitnami_com_v2.HelmReleasePolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(helmreleasepoliciesResource, c.ns, name, data, subresources...), &helm_bitnami_com_v2.HelmReleasePolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleasePolicy), err
+}
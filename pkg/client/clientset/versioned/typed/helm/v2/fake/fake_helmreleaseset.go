@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	helm_bitnami_com_v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHelmReleaseSets implements HelmReleaseSetInterface
+type FakeHelmReleaseSets struct {
+	Fake *FakeHelmV2
+	ns   string
+}
+
+var helmreleasesetsResource = schema.GroupVersionResource{Group: "helm.bitnami.com", Version: "v2", Resource: "helmreleasesets"}
+
+var helmreleasesetsKind = schema.GroupVersionKind{Group: "helm.bitnami.com", Version: "v2", Kind: "HelmReleaseSet"}
+
+// Get takes name of the helmReleaseSet, and returns the corresponding helmReleaseSet object, and an error if there is any.
+func (c *FakeHelmReleaseSets) Get(name string, options v1.GetOptions) (result *helm_bitnami_com_v2.HelmReleaseSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(helmreleasesetsResource, c.ns, name), &helm_bitnami_com_v2.HelmReleaseSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleaseSet), err
+}
+
+// List takes label and field selectors, and returns the list of HelmReleaseSets that match those selectors.
+func (c *FakeHelmReleaseSets) List(opts v1.ListOptions) (result *helm_bitnami_com_v2.HelmReleaseSetList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(helmreleasesetsResource, helmreleasesetsKind, c.ns, opts), &helm_bitnami_com_v2.HelmReleaseSetList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &helm_bitnami_com_v2.HelmReleaseSetList{}
+	for _, item := range obj.(*helm_bitnami_com_v2.HelmReleaseSetList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested helmReleaseSets.
+func (c *FakeHelmReleaseSets) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(helmreleasesetsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a helmReleaseSet and creates it.  Returns the server's representation of the helmReleaseSet, and an error, if there is any.
+func (c *FakeHelmReleaseSets) Create(helmReleaseSet *helm_bitnami_com_v2.HelmReleaseSet) (result *helm_bitnami_com_v2.HelmReleaseSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(helmreleasesetsResource, c.ns, helmReleaseSet), &helm_bitnami_com_v2.HelmReleaseSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleaseSet), err
+}
+
+// Update takes the representation of a helmReleaseSet and updates it. Returns the server's representation of the helmReleaseSet, and an error, if there is any.
+func (c *FakeHelmReleaseSets) Update(helmReleaseSet *helm_bitnami_com_v2.HelmReleaseSet) (result *helm_bitnami_com_v2.HelmReleaseSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(helmreleasesetsResource, c.ns, helmReleaseSet), &helm_bitnami_com_v2.HelmReleaseSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleaseSet), err
+}
+
+// Delete takes name of the helmReleaseSet and deletes it. Returns an error if one occurs.
+func (c *FakeHelmReleaseSets) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(helmreleasesetsResource, c.ns, name), &helm_bitnami_com_v2.HelmReleaseSet{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHelmReleaseSets) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(helmreleasesetsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &helm_bitnami_com_v2.HelmReleaseSetList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched helmReleaseSet.
+func (c *FakeHelmReleaseSets) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *helm_bitnami_com_v2.HelmReleaseSet, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(helmreleasesetsResource, c.ns, name, data, subresources...), &helm_bitnami_com_v2.HelmReleaseSet{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.HelmReleaseSet), err
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	helm_bitnami_com_v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeRepositoryCredentials implements RepositoryCredentialInterface
+type FakeRepositoryCredentials struct {
+	Fake *FakeHelmV2
+	ns   string
+}
+
+var repositorycredentialsResource = schema.GroupVersionResource{Group: "helm.bitnami.com", Version: "v2", Resource: "repositorycredentials"}
+
+var repositorycredentialsKind = schema.GroupVersionKind{Group: "helm.bitnami.com", Version: "v2", Kind: "RepositoryCredential"}
+
+// Get takes name of the repositoryCredential, and returns the corresponding repositoryCredential object, and an error if there is any.
+func (c *FakeRepositoryCredentials) Get(name string, options v1.GetOptions) (result *helm_bitnami_com_v2.RepositoryCredential, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(repositorycredentialsResource, c.ns, name), &helm_bitnami_com_v2.RepositoryCredential{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.RepositoryCredential), err
+}
+
+// List takes label and field selectors, and returns the list of RepositoryCredentials that match those selectors.
+func (c *FakeRepositoryCredentials) List(opts v1.ListOptions) (result *helm_bitnami_com_v2.RepositoryCredentialList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(repositorycredentialsResource, repositorycredentialsKind, c.ns, opts), &helm_bitnami_com_v2.RepositoryCredentialList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &helm_bitnami_com_v2.RepositoryCredentialList{}
+	for _, item := range obj.(*helm_bitnami_com_v2.RepositoryCredentialList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested repositoryCredentials.
+func (c *FakeRepositoryCredentials) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(repositorycredentialsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a repositoryCredential and creates it.  Returns the server's representation of the repositoryCredential, and an error, if there is any.
+func (c *FakeRepositoryCredentials) Create(repositoryCredential *helm_bitnami_com_v2.RepositoryCredential) (result *helm_bitnami_com_v2.RepositoryCredential, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(repositorycredentialsResource, c.ns, repositoryCredential), &helm_bitnami_com_v2.RepositoryCredential{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.RepositoryCredential), err
+}
+
+// Update takes the representation of a repositoryCredential and updates it. Returns the server's representation of the repositoryCredential, and an error, if there is any.
+func (c *FakeRepositoryCredentials) Update(repositoryCredential *helm_bitnami_com_v2.RepositoryCredential) (result *helm_bitnami_com_v2.RepositoryCredential, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(repositorycredentialsResource, c.ns, repositoryCredential), &helm_bitnami_com_v2.RepositoryCredential{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.RepositoryCredential), err
+}
+
+// Delete takes name of the repositoryCredential and deletes it. Returns an error if one occurs.
+func (c *FakeRepositoryCredentials) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(repositorycredentialsResource, c.ns, name), &helm_bitnami_com_v2.RepositoryCredential{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeRepositoryCredentials) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(repositorycredentialsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &helm_bitnami_com_v2.RepositoryCredentialList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched repositoryCredential.
+func (c *FakeRepositoryCredentials) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *helm_bitnami_com_v2.RepositoryCredential, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(repositorycredentialsResource, c.ns, name, data, subresources...), &helm_bitnami_com_v2.RepositoryCredential{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*helm_bitnami_com_v2.RepositoryCredential), err
+}
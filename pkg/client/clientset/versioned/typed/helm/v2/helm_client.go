@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v2
+
+import (
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/scheme"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	rest "k8s.io/client-go/rest"
+)
+
+type HelmV2Interface interface {
+	RESTClient() rest.Interface
+	HelmReleasesGetter
+	HelmReleasePoliciesGetter
+	HelmReleaseSetsGetter
+	RepositoryCredentialsGetter
+}
+
+// HelmV2Client is used to interact with features provided by the helm.bitnami.com group.
+type HelmV2Client struct {
+	restClient rest.Interface
+}
+
+func (c *HelmV2Client) HelmReleases(namespace string) HelmReleaseInterface {
+	return newHelmReleases(c, namespace)
+}
+
+func (c *HelmV2Client) HelmReleasePolicies(namespace string) HelmReleasePolicyInterface {
+	return newHelmReleasePolicies(c, namespace)
+}
+
+func (c *HelmV2Client) HelmReleaseSets(namespace string) HelmReleaseSetInterface {
+	return newHelmReleaseSets(c, namespace)
+}
+
+func (c *HelmV2Client) RepositoryCredentials(namespace string) RepositoryCredentialInterface {
+	return newRepositoryCredentials(c, namespace)
+}
+
+// NewForConfig creates a new HelmV2Client for the given config.
+func NewForConfig(c *rest.Config) (*HelmV2Client, error) {
+	config := *c
+	if err := setConfigDefaults(&config); err != nil {
+		return nil, err
+	}
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &HelmV2Client{client}, nil
+}
+
+// NewForConfigOrDie creates a new HelmV2Client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) *HelmV2Client {
+	client, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// New creates a new HelmV2Client for the given RESTClient.
+func New(c rest.Interface) *HelmV2Client {
+	return &HelmV2Client{c}
+}
+
+func setConfigDefaults(config *rest.Config) error {
+	gv := v2.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return nil
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *HelmV2Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
+	}
+	return c.restClient
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v2
+
+import (
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	scheme "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HelmReleasePoliciesGetter has a method to return a HelmReleasePolicyInterface.
+// A group's client should implement this interface.
+type HelmReleasePoliciesGetter interface {
+	HelmReleasePolicies(namespace string) HelmReleasePolicyInterface
+}
+
+// HelmReleasePolicyInterface has methods to work with HelmReleasePolicy resources.
+type HelmReleasePolicyInterface interface {
+	Create(*v2.HelmReleasePolicy) (*v2.HelmReleasePolicy, error)
+	Update(*v2.HelmReleasePolicy) (*v2.HelmReleasePolicy, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v2.HelmReleasePolicy, error)
+	List(opts meta_v1.ListOptions) (*v2.HelmReleasePolicyList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.HelmReleasePolicy, err error)
+	HelmReleasePolicyExpansion
+}
+
+// helmReleasePolicies implements HelmReleasePolicyInterface
+type helmReleasePolicies struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHelmReleasePolicies returns a HelmReleasePolicies
+func newHelmReleasePolicies(c *HelmV2Client, namespace string) *helmReleasePolicies {
+	return &helmReleasePolicies{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the helmReleasePolicy, and returns the corresponding helmReleasePolicy object, and an error if there is any.
+func (c *helmReleasePolicies) Get(name string, options meta_v1.GetOptions) (result *v2.HelmReleasePolicy, err error) {
+	result = &v2.HelmReleasePolicy{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HelmReleasePolicies that match those selectors.
+func (c *helmReleasePolicies) List(opts meta_v1.ListOptions) (result *v2.HelmReleasePolicyList, err error) {
+	result = &v2.HelmReleasePolicyList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested helmReleasePolicies.
+func (c *helmReleasePolicies) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a helmReleasePolicy and creates it.  Returns the server's representation of the helmReleasePolicy, and an error, if there is any.
+func (c *helmReleasePolicies) Create(helmReleasePolicy *v2.HelmReleasePolicy) (result *v2.HelmReleasePolicy, err error) {
+	result = &v2.HelmReleasePolicy{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		Body(helmReleasePolicy).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a helmReleasePolicy and updates it. Returns the server's representation of the helmReleasePolicy, and an error, if there is any.
+func (c *helmReleasePolicies) Update(helmReleasePolicy *v2.HelmReleasePolicy) (result *v2.HelmReleasePolicy, err error) {
+	result = &v2.HelmReleasePolicy{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		Name(helmReleasePolicy.Name).
+		Body(helmReleasePolicy).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the helmReleasePolicy and deletes it. Returns an error if one occurs.
+func (c *helmReleasePolicies) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *helmReleasePolicies) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched helmReleasePolicy.
+func (c *helmReleasePolicies) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.HelmReleasePolicy, err error) {
+	result = &v2.HelmReleasePolicy{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("helmreleasepolicies").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v2
+
+import (
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	scheme "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HelmReleaseSetsGetter has a method to return a HelmReleaseSetInterface.
+// A group's client should implement this interface.
+type HelmReleaseSetsGetter interface {
+	HelmReleaseSets(namespace string) HelmReleaseSetInterface
+}
+
+// HelmReleaseSetInterface has methods to work with HelmReleaseSet resources.
+type HelmReleaseSetInterface interface {
+	Create(*v2.HelmReleaseSet) (*v2.HelmReleaseSet, error)
+	Update(*v2.HelmReleaseSet) (*v2.HelmReleaseSet, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v2.HelmReleaseSet, error)
+	List(opts meta_v1.ListOptions) (*v2.HelmReleaseSetList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.HelmReleaseSet, err error)
+	HelmReleaseSetExpansion
+}
+
+// helmReleaseSets implements HelmReleaseSetInterface
+type helmReleaseSets struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHelmReleaseSets returns a HelmReleaseSets
+func newHelmReleaseSets(c *HelmV2Client, namespace string) *helmReleaseSets {
+	return &helmReleaseSets{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the helmReleaseSet, and returns the corresponding helmReleaseSet object, and an error if there is any.
+func (c *helmReleaseSets) Get(name string, options meta_v1.GetOptions) (result *v2.HelmReleaseSet, err error) {
+	result = &v2.HelmReleaseSet{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HelmReleaseSets that match those selectors.
+func (c *helmReleaseSets) List(opts meta_v1.ListOptions) (result *v2.HelmReleaseSetList, err error) {
+	result = &v2.HelmReleaseSetList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested helmReleaseSets.
+func (c *helmReleaseSets) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a helmReleaseSet and creates it.  Returns the server's representation of the helmReleaseSet, and an error, if there is any.
+func (c *helmReleaseSets) Create(helmReleaseSet *v2.HelmReleaseSet) (result *v2.HelmReleaseSet, err error) {
+	result = &v2.HelmReleaseSet{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		Body(helmReleaseSet).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a helmReleaseSet and updates it. Returns the server's representation of the helmReleaseSet, and an error, if there is any.
+func (c *helmReleaseSets) Update(helmReleaseSet *v2.HelmReleaseSet) (result *v2.HelmReleaseSet, err error) {
+	result = &v2.HelmReleaseSet{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		Name(helmReleaseSet.Name).
+		Body(helmReleaseSet).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the helmReleaseSet and deletes it. Returns an error if one occurs.
+func (c *helmReleaseSets) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *helmReleaseSets) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched helmReleaseSet.
+func (c *helmReleaseSets) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.HelmReleaseSet, err error) {
+	result = &v2.HelmReleaseSet{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("helmreleasesets").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
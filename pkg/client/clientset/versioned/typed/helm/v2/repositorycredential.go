@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The helm-crd-controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v2
+
+import (
+	v2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	scheme "github.com/bitnami-labs/helm-crd/pkg/client/clientset/versioned/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// RepositoryCredentialsGetter has a method to return a RepositoryCredentialInterface.
+// A group's client should implement this interface.
+type RepositoryCredentialsGetter interface {
+	RepositoryCredentials(namespace string) RepositoryCredentialInterface
+}
+
+// RepositoryCredentialInterface has methods to work with RepositoryCredential resources.
+type RepositoryCredentialInterface interface {
+	Create(*v2.RepositoryCredential) (*v2.RepositoryCredential, error)
+	Update(*v2.RepositoryCredential) (*v2.RepositoryCredential, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v2.RepositoryCredential, error)
+	List(opts meta_v1.ListOptions) (*v2.RepositoryCredentialList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.RepositoryCredential, err error)
+	RepositoryCredentialExpansion
+}
+
+// repositoryCredentials implements RepositoryCredentialInterface
+type repositoryCredentials struct {
+	client rest.Interface
+	ns     string
+}
+
+// newRepositoryCredentials returns a RepositoryCredentials
+func newRepositoryCredentials(c *HelmV2Client, namespace string) *repositoryCredentials {
+	return &repositoryCredentials{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the repositoryCredential, and returns the corresponding repositoryCredential object, and an error if there is any.
+func (c *repositoryCredentials) Get(name string, options meta_v1.GetOptions) (result *v2.RepositoryCredential, err error) {
+	result = &v2.RepositoryCredential{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of RepositoryCredentials that match those selectors.
+func (c *repositoryCredentials) List(opts meta_v1.ListOptions) (result *v2.RepositoryCredentialList, err error) {
+	result = &v2.RepositoryCredentialList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested repositoryCredentials.
+func (c *repositoryCredentials) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Create takes the representation of a repositoryCredential and creates it.  Returns the server's representation of the repositoryCredential, and an error, if there is any.
+func (c *repositoryCredentials) Create(repositoryCredential *v2.RepositoryCredential) (result *v2.RepositoryCredential, err error) {
+	result = &v2.RepositoryCredential{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		Body(repositoryCredential).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a repositoryCredential and updates it. Returns the server's representation of the repositoryCredential, and an error, if there is any.
+func (c *repositoryCredentials) Update(repositoryCredential *v2.RepositoryCredential) (result *v2.RepositoryCredential, err error) {
+	result = &v2.RepositoryCredential{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		Name(repositoryCredential.Name).
+		Body(repositoryCredential).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the repositoryCredential and deletes it. Returns an error if one occurs.
+func (c *repositoryCredentials) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *repositoryCredentials) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched repositoryCredential.
+func (c *repositoryCredentials) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v2.RepositoryCredential, err error) {
+	result = &v2.RepositoryCredential{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("repositorycredentials").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
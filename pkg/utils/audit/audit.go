@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operation is a Helm operation changing a release
+type Operation string
+
+// These are the audited operations
+const (
+	Install  Operation = "install"
+	Upgrade  Operation = "upgrade"
+	Rollback Operation = "rollback"
+	Delete   Operation = "delete"
+)
+
+// Result is the outcome of an operation
+type Result string
+
+// These are the results of operations
+const (
+	Succeeded Result = "succeeded"
+	Failed    Result = "failed"
+)
+
+// Record is the audit record of an operation on the release of a
+// HelmRelease
+type Record struct {
+	Time      time.Time `json:"time"`
+	Operation Operation `json:"operation"`
+	// Namespace and Name identify the HelmRelease the operation was run for
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Release   string `json:"release"`
+	Chart     string `json:"chart,omitempty"`
+	Version   string `json:"version,omitempty"`
+	// ValuesChecksum is the SHA-256 checksum of the values of installs and
+	// upgrades, which may hold secrets
+	ValuesChecksum string `json:"valuesChecksum,omitempty"`
+	// Revision is the release revision the operation deployed
+	Revision int32  `json:"revision,omitempty"`
+	Result   Result `json:"result"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Sink stores audit records
+type Sink interface {
+	Write(r Record) error
+}
+
+// NewSink returns the Sink of target: "-" writes JSON lines to stdout,
+// http(s) URLs receive each record as a JSON POST sent with client, any
+// other target is a file JSON lines are appended to
+func NewSink(target string, client *http.Client) (Sink, error) {
+	switch {
+	case target == "-":
+		return &writerSink{w: os.Stdout}, nil
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return &httpSink{client: client, url: target}, nil
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &writerSink{w: f}, nil
+}
+
+// writerSink writes records as JSON lines
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// httpSink posts the JSON encoded records
+type httpSink struct {
+	client *http.Client
+	url    string
+}
+
+func (s *httpSink) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", s.url, res.Status)
+	}
+	return nil
+}
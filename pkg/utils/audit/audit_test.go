@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	for i, r := range []Record{
+		{Operation: Install, Namespace: "myns", Name: "foo", Release: "myns-foo", Chart: "mariadb", Version: "2.0.1", Revision: 1, Result: Succeeded},
+		{Operation: Upgrade, Namespace: "myns", Name: "foo", Release: "myns-foo", Result: Failed, Error: "timed out"},
+	} {
+		// Records are appended to existing logs
+		s, err := NewSink(path, http.DefaultClient)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if err := s.Write(r); err != nil {
+			t.Fatalf("%d: unexpected error %v", i, err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Unexpected line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 || records[0].Operation != Install || records[0].Revision != 1 || records[1].Result != Failed || records[1].Error != "timed out" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received []Record
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record Record
+		json.NewDecoder(r.Body).Decode(&record)
+		received = append(received, record)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	s, err := NewSink(server.URL, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := s.Write(Record{Operation: Delete, Namespace: "myns", Name: "foo", Release: "myns-foo", Result: Succeeded}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(received) != 1 || received[0].Operation != Delete || received[0].Release != "myns-foo" {
+		t.Errorf("Unexpected records %+v", received)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := s.Write(Record{Operation: Delete}); err == nil {
+		t.Errorf("Expecting an error for a failed request")
+	}
+}
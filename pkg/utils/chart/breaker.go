@@ -0,0 +1,109 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RepoUnavailableError is returned by CircuitBreaker for requests to a
+// host whose circuit is open, without sending them
+type RepoUnavailableError struct {
+	Host string
+	// Failures is the number of consecutive failed requests to the host
+	Failures int
+	// RetryAfter is when the next request to the host is let through
+	RetryAfter time.Time
+}
+
+func (e *RepoUnavailableError) Error() string {
+	return fmt.Sprintf("repository host %s is unavailable after %d consecutive failed requests, retrying after %s", e.Host, e.Failures, e.RetryAfter.UTC().Format(time.RFC3339))
+}
+
+// hostCircuit is the state of the circuit of a host
+type hostCircuit struct {
+	failures int
+	// openUntil is when the open circuit lets a request through to
+	// probe the host
+	openUntil time.Time
+}
+
+// CircuitBreaker is an HTTPClient failing requests to a host right away,
+// with a *RepoUnavailableError, once Threshold consecutive requests to it
+// failed with a connection error or a 5xx or 429 response. After Cooldown
+// a single request is sent to probe the host: the circuit closes if it
+// succeeds and stays open for another Cooldown otherwise.
+type CircuitBreaker struct {
+	Client HTTPClient
+	// Threshold is the number of consecutive failures opening the
+	// circuit of a host, never opened if zero
+	Threshold int
+	// Cooldown is how long requests fail fast once the circuit opens
+	Cooldown time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+	now   func() time.Time
+}
+
+func (c *CircuitBreaker) time() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// Do sends req unless the circuit of its host is open
+func (c *CircuitBreaker) Do(req *http.Request) (*http.Response, error) {
+	if c.Threshold <= 0 {
+		return c.Client.Do(req)
+	}
+	host := req.URL.Host
+	if err := c.allow(host); err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(req)
+	c.record(host, !retryable(res, err))
+	return res, err
+}
+
+// allow returns an error if the circuit of host is open. Once the
+// cooldown expired, it lets the request through and keeps failing the
+// others for another cooldown while it probes the host.
+func (c *CircuitBreaker) allow(host string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hosts[host]
+	if !ok || h.failures < c.Threshold {
+		return nil
+	}
+	now := c.time()
+	if now.Before(h.openUntil) {
+		return &RepoUnavailableError{Host: host, Failures: h.failures, RetryAfter: h.openUntil}
+	}
+	h.openUntil = now.Add(c.Cooldown)
+	return nil
+}
+
+// record records the outcome of a request to host
+func (c *CircuitBreaker) record(host string, success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if success {
+		delete(c.hosts, host)
+		return
+	}
+	if c.hosts == nil {
+		c.hosts = map[string]*hostCircuit{}
+	}
+	h, ok := c.hosts[host]
+	if !ok {
+		h = &hostCircuit{}
+		c.hosts[host] = h
+	}
+	h.failures++
+	if h.failures >= c.Threshold {
+		h.openUntil = c.time().Add(c.Cooldown)
+	}
+}
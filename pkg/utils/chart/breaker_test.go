@@ -0,0 +1,64 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	fake := &fakeResponses{responses: []interface{}{
+		503, fmt.Errorf("connection refused"), 500,
+		// another host
+		404,
+		// probe after the cooldown
+		502,
+		// probe after another cooldown
+		200, 200,
+	}}
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	client := &CircuitBreaker{
+		Client:    fake,
+		Threshold: 3,
+		Cooldown:  time.Minute,
+		now:       func() time.Time { return now },
+	}
+	get := func(url string) error {
+		req, _ := http.NewRequest("GET", url, nil)
+		_, err := client.Do(req)
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		get("http://charts.example.com/index.yaml")
+	}
+	err := get("http://charts.example.com/foo-1.0.0.tgz")
+	e, ok := err.(*RepoUnavailableError)
+	if !ok || e.Host != "charts.example.com" || e.Failures != 3 || !e.RetryAfter.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expecting the circuit to open after 3 failures, received %v", err)
+	}
+	if fake.requests != 3 {
+		t.Errorf("Expecting requests to fail fast, sent %d", fake.requests)
+	}
+	if err := get("http://other.example.com/index.yaml"); err != nil || fake.requests != 4 {
+		t.Errorf("Expecting requests to other hosts to be sent")
+	}
+
+	now = now.Add(time.Minute)
+	get("http://charts.example.com/index.yaml")
+	if err := get("http://charts.example.com/index.yaml"); err == nil {
+		t.Errorf("Expecting the failed probe to keep the circuit open")
+	}
+	if fake.requests != 5 {
+		t.Errorf("Expecting a single probe, sent %d requests", fake.requests)
+	}
+
+	now = now.Add(time.Minute)
+	if err := get("http://charts.example.com/index.yaml"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := get("http://charts.example.com/index.yaml"); err != nil {
+		t.Errorf("Expecting the successful probe to close the circuit, received %v", err)
+	}
+}
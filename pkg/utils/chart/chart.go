@@ -1,14 +1,17 @@
 package chart
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
+	"github.com/Masterminds/semver"
 	"github.com/ghodss/yaml"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 	"k8s.io/helm/pkg/repo"
@@ -19,7 +22,7 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func getReq(rawURL, authHeader string) (*http.Request, error) {
+func getReq(rawURL string, header http.Header) (*http.Request, error) {
 	parsedURL, err := url.ParseRequestURI(rawURL)
 	if err != nil {
 		return nil, err
@@ -30,19 +33,30 @@ func getReq(rawURL, authHeader string) (*http.Request, error) {
 		return nil, err
 	}
 
-	if len(authHeader) > 0 {
-		req.Header.Set("Authorization", authHeader)
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
 	}
 	return req, nil
 }
 
+// ResponseError is returned for repository responses other than 200 OK
+type ResponseError struct {
+	StatusCode int
+}
+
+func (e *ResponseError) Error() string {
+	return "chart download request failed"
+}
+
 func readResponseBody(res *http.Response) ([]byte, error) {
 	if res != nil {
 		defer res.Body.Close()
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("chart download request failed")
+		return nil, &ResponseError{StatusCode: res.StatusCode}
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
@@ -62,9 +76,10 @@ func parseIndex(data []byte) (*repo.IndexFile, error) {
 	return index, nil
 }
 
-// FetchRepoIndex returns a Helm repository
-func FetchRepoIndex(netClient *HTTPClient, repoURL string, authHeader string) (*repo.IndexFile, error) {
-	req, err := getReq(repoURL, authHeader)
+// FetchRepoIndex returns a Helm repository, sending header along the
+// request, e.g. its Authorization
+func FetchRepoIndex(netClient *HTTPClient, repoURL string, header http.Header) (*repo.IndexFile, error) {
+	req, err := getReq(repoURL, header)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +96,37 @@ func FetchRepoIndex(netClient *HTTPClient, repoURL string, authHeader string) (*
 	return parseIndex(data)
 }
 
+// FetchRepoIndexFailover fetches the index of the first of repoURLs that
+// responds, moving to the next one when a request fails to connect, times
+// out, returns a 5xx response or is failed by a CircuitBreaker. It returns the URL the index was fetched
+// from. Other errors, such as 404 responses, are returned right away.
+func FetchRepoIndexFailover(netClient *HTTPClient, repoURLs []string, header http.Header) (*repo.IndexFile, string, error) {
+	var errs []string
+	for _, repoURL := range repoURLs {
+		index, err := FetchRepoIndex(netClient, repoURL, header)
+		if err == nil {
+			return index, repoURL, nil
+		}
+		if !unavailable(err) || len(repoURLs) == 1 {
+			return nil, repoURL, err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", repoURL, err))
+	}
+	return nil, "", fmt.Errorf("no repository available: %s", strings.Join(errs, "; "))
+}
+
+// unavailable returns whether err is due to a repository that can't be
+// reached or fails to serve requests
+func unavailable(err error) bool {
+	switch e := err.(type) {
+	case *ResponseError:
+		return e.StatusCode >= 500
+	case *url.Error, *RepoUnavailableError, *NotMirroredError:
+		return true
+	}
+	return false
+}
+
 func resolveChartURL(index, chart string) (string, error) {
 	indexURL, err := url.Parse(strings.TrimSpace(index))
 	if err != nil {
@@ -93,28 +139,118 @@ func resolveChartURL(index, chart string) (string, error) {
 	return chartURL.String(), nil
 }
 
-// FindChartInRepoIndex returns the URL of a chart given a Helm repository and its name and version
-func FindChartInRepoIndex(repoIndex *repo.IndexFile, repoURL, chartName, chartVersion string) (string, error) {
+// IsVersionRange returns true if the given chart version is a semver
+// constraint (e.g. "^1.2.0" or "1.2.x") rather than an exact version
+func IsVersionRange(chartVersion string) bool {
+	if chartVersion == "" {
+		return false
+	}
+	_, err := semver.NewVersion(chartVersion)
+	return err != nil
+}
+
+// UpdateConstraint returns the version range a release of the exact chart
+// version may be updated to with an update policy: the newer versions of
+// the same minor version with Patch, of the same major version with Minor,
+// or any with Latest. Other policies keep version.
+func UpdateConstraint(version, policy string) (string, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("update policies require an exact chart version: %v", err)
+	}
+	switch policy {
+	case "Patch":
+		return fmt.Sprintf(">= %s, < %d.%d.0", v, v.Major(), v.Minor()+1), nil
+	case "Minor":
+		return fmt.Sprintf(">= %s, < %d.0.0", v, v.Major()+1), nil
+	case "Latest":
+		return fmt.Sprintf(">= %s", v), nil
+	}
+	return version, nil
+}
+
+// FindChartInRepoIndex returns the URL and the resolved version of a chart
+// given a Helm repository and its name and version (or version range).
+// Prerelease versions are only considered with devel, like
+// helm install --devel.
+func FindChartInRepoIndex(repoIndex *repo.IndexFile, repoURL, chartName, chartVersion string, devel bool) (string, string, error) {
 	errMsg := fmt.Sprintf("chart %q", chartName)
 	if chartVersion != "" {
 		errMsg = fmt.Sprintf("%s version %q", errMsg, chartVersion)
 	}
-	cv, err := repoIndex.Get(chartName, chartVersion)
+	cv, err := getChartVersion(repoIndex, chartName, chartVersion, devel)
 	if err != nil {
-		return "", fmt.Errorf("%s not found in repository", errMsg)
+		return "", "", fmt.Errorf("%s not found in repository", errMsg)
 	}
 	if len(cv.URLs) == 0 {
-		return "", fmt.Errorf("%s has no downloadable URLs", errMsg)
+		return "", "", fmt.Errorf("%s has no downloadable URLs", errMsg)
+	}
+	chartURL, err := resolveChartURL(repoURL, cv.URLs[0])
+	if err != nil {
+		return "", "", err
+	}
+	return chartURL, cv.Version, nil
+}
+
+// getChartVersion returns the newest version of a chart matching the
+// version constraint, like repo.IndexFile.Get, which skips prereleases
+// unless the constraint has one. With devel, prereleases are considered
+// too, matching the constraints their release matches.
+func getChartVersion(repoIndex *repo.IndexFile, chartName, chartVersion string, devel bool) (*repo.ChartVersion, error) {
+	if !devel {
+		return repoIndex.Get(chartName, chartVersion)
 	}
-	return resolveChartURL(repoURL, cv.URLs[0])
+	if chartVersion == "" {
+		chartVersion = ">0.0.0-0"
+	}
+	constraint, err := semver.NewConstraint(chartVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, cv := range repoIndex.Entries[chartName] {
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		if constraint.Check(v) {
+			return cv, nil
+		}
+		if v.Prerelease() != "" {
+			if release, err := v.SetPrerelease(""); err == nil && constraint.Check(&release) {
+				return cv, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no chart version found for %s-%s", chartName, chartVersion)
 }
 
 // LoadChart should return a Chart struct from an IOReader
 type LoadChart func(in io.Reader) (*chart.Chart, error)
 
-// FetchChart returns the Chart content given an URL and the auth header if needed
-func FetchChart(netClient *HTTPClient, chartURL, authHeader string, load LoadChart) (*chart.Chart, error) {
-	req, err := getReq(chartURL, authHeader)
+// ArchiveTooLargeError is returned for chart archives larger than the
+// maximum size
+type ArchiveTooLargeError struct {
+	URL     string
+	MaxSize int64
+}
+
+func (e *ArchiveTooLargeError) Error() string {
+	return fmt.Sprintf("chart archive %s exceeds the maximum size of %d bytes", e.URL, e.MaxSize)
+}
+
+// FetchChart returns the Chart content given an URL and the headers, such
+// as its Authorization, to send if needed. Archives larger than maxSize
+// bytes are rejected, unless maxSize is zero.
+func FetchChart(netClient *HTTPClient, chartURL string, header http.Header, maxSize int64, load LoadChart) (*chart.Chart, error) {
+	return FetchChartDigest(netClient, chartURL, header, "", maxSize, load)
+}
+
+// FetchChartDigest returns the Chart content like FetchChart, failing
+// unless the SHA-256 digest of the archive is digest, hex encoded and
+// optionally prefixed with "sha256:". An empty digest is not checked.
+// The archive is streamed to a temporary file rather than held in memory.
+func FetchChartDigest(netClient *HTTPClient, chartURL string, header http.Header, digest string, maxSize int64, load LoadChart) (*chart.Chart, error) {
+	req, err := getReq(chartURL, header)
 	if err != nil {
 		return nil, err
 	}
@@ -123,9 +259,40 @@ func FetchChart(netClient *HTTPClient, chartURL, authHeader string, load LoadCha
 	if err != nil {
 		return nil, err
 	}
-	data, err := readResponseBody(res)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, &ResponseError{StatusCode: res.StatusCode}
+	}
+	if maxSize > 0 && res.ContentLength > maxSize {
+		return nil, &ArchiveTooLargeError{URL: chartURL, MaxSize: maxSize}
+	}
+
+	f, err := ioutil.TempFile("", "chart-")
 	if err != nil {
 		return nil, err
 	}
-	return load(bytes.NewReader(data))
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	body := io.Reader(res.Body)
+	if maxSize > 0 {
+		// Read one more byte to tell archives of exactly maxSize bytes
+		// from larger ones
+		body = io.LimitReader(res.Body, maxSize+1)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, hash), body)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && n > maxSize {
+		return nil, &ArchiveTooLargeError{URL: chartURL, MaxSize: maxSize}
+	}
+	if digest != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != strings.TrimPrefix(digest, "sha256:") {
+			return nil, fmt.Errorf("chart digest sha256:%s does not match %s", actual, digest)
+		}
+	}
+	// The section tells loaders the size of the archive
+	return load(io.NewSectionReader(f, 0, n))
 }
@@ -1,7 +1,13 @@
 package chart
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -66,11 +72,224 @@ func TestFindChartInRepoIndex(t *testing.T) {
 	entries[name] = chartVersions
 	index := &repo.IndexFile{APIVersion: "v1", Generated: time.Now(), Entries: entries}
 
-	res, err := FindChartInRepoIndex(index, repoURL, name, version)
+	res, resolvedVersion, err := FindChartInRepoIndex(index, repoURL, name, version, false)
 	if err != nil {
 		t.Errorf("Unexpected error %v", err)
 	}
 	if res != expectedURL {
 		t.Errorf("Expecting %s to be resolved as %s", res, expectedURL)
 	}
+	if resolvedVersion != version {
+		t.Errorf("Expecting version %s received %s", version, resolvedVersion)
+	}
+}
+
+func TestFindChartInRepoIndexWithRange(t *testing.T) {
+	name := "foo"
+	repoURL := "http://charts.example.com/repo/"
+	entries := map[string]repo.ChartVersions{}
+	for _, v := range []string{"1.1.0", "1.2.0", "1.2.3", "2.0.0"} {
+		chartMeta := chart.Metadata{Name: name, Version: v}
+		chartVersion := repo.ChartVersion{Metadata: &chartMeta, URLs: []string{fmt.Sprintf("%s-%s.tgz", name, v)}}
+		entries[name] = append(entries[name], &chartVersion)
+	}
+	index := &repo.IndexFile{APIVersion: "v1", Generated: time.Now(), Entries: entries}
+	index.SortEntries()
+
+	tests := []struct {
+		constraint      string
+		expectedVersion string
+	}{
+		{"^1.2.0", "1.2.3"},
+		{"1.1.x", "1.1.0"},
+		{"", "2.0.0"},
+	}
+	for _, tt := range tests {
+		res, resolvedVersion, err := FindChartInRepoIndex(index, repoURL, name, tt.constraint, false)
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+		if resolvedVersion != tt.expectedVersion {
+			t.Errorf("Expecting %q to resolve to %s received %s", tt.constraint, tt.expectedVersion, resolvedVersion)
+		}
+		expectedURL := fmt.Sprintf("%s%s-%s.tgz", repoURL, name, tt.expectedVersion)
+		if res != expectedURL {
+			t.Errorf("Expecting %s to be resolved as %s", res, expectedURL)
+		}
+	}
+}
+
+func TestFindChartInRepoIndexDevel(t *testing.T) {
+	entries := map[string]repo.ChartVersions{}
+	for _, v := range []string{"1.1.0", "1.2.0-rc.1", "1.2.0", "1.2.1-beta.2", "2.0.0-alpha.1"} {
+		chartMeta := chart.Metadata{Name: "foo", Version: v}
+		entries["foo"] = append(entries["foo"], &repo.ChartVersion{Metadata: &chartMeta, URLs: []string{"foo-" + v + ".tgz"}})
+	}
+	index := &repo.IndexFile{APIVersion: "v1", Generated: time.Now(), Entries: entries}
+	index.SortEntries()
+
+	tests := []struct {
+		constraint string
+		devel      bool
+		expected   string
+	}{
+		{"", false, "1.2.0"},
+		{"", true, "2.0.0-alpha.1"},
+		{"~1.2.0", false, "1.2.0"},
+		{"~1.2.0", true, "1.2.1-beta.2"},
+		{"1.2.0-rc.1", false, "1.2.0-rc.1"},
+		{"<1.2.0", true, "1.1.0"},
+	}
+	for _, tt := range tests {
+		_, version, err := FindChartInRepoIndex(index, "http://charts.example.com/repo/", "foo", tt.constraint, tt.devel)
+		if err != nil || version != tt.expected {
+			t.Errorf("Expecting %q with devel %v to resolve to %s, received %s %v", tt.constraint, tt.devel, tt.expected, version, err)
+		}
+	}
+}
+
+func TestIsVersionRange(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected bool
+	}{
+		{"", false},
+		{"1.2.3", false},
+		{"v1.0.0", false},
+		{"1.2.3-rc.1", false},
+		{"^1.2.0", true},
+		{"~1.2", true},
+		{"1.2.x", true},
+		{">= 1.0, < 2.0", true},
+	}
+	for _, tt := range tests {
+		if res := IsVersionRange(tt.version); res != tt.expected {
+			t.Errorf("Expecting IsVersionRange(%q) to be %v", tt.version, tt.expected)
+		}
+	}
+}
+
+func TestUpdateConstraint(t *testing.T) {
+	index := &repo.IndexFile{APIVersion: "v1", Entries: map[string]repo.ChartVersions{}}
+	for _, v := range []string{"1.2.3", "1.2.9", "1.3.0", "2.0.0", "2.1.0-rc.1"} {
+		index.Add(&chart.Metadata{Name: "foo", Version: v}, "foo-"+v+".tgz", "https://charts.example.com/", "")
+	}
+	index.SortEntries()
+	tests := []struct {
+		version, policy string
+		expected        string
+	}{
+		{"1.2.3", "", "1.2.3"},
+		{"1.2.3", "None", "1.2.3"},
+		{"1.2.3", "Patch", "1.2.9"},
+		{"1.2.3", "Minor", "1.3.0"},
+		{"1.2.3", "Latest", "2.0.0"},
+		{"v1.2.9", "Patch", "1.2.9"},
+	}
+	for _, tt := range tests {
+		constraint, err := UpdateConstraint(tt.version, tt.policy)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		_, version, err := FindChartInRepoIndex(index, "https://charts.example.com/", "foo", constraint, false)
+		if err != nil || version != tt.expected {
+			t.Errorf("Expecting %s %s to resolve to %s, received %s %v", tt.version, tt.policy, tt.expected, version, err)
+		}
+	}
+	if _, err := UpdateConstraint("^1.2.0", "Minor"); err == nil {
+		t.Errorf("Expecting an error for a version range")
+	}
+}
+
+// fakeRepos responds to requests with the status, or error, set for their host
+type fakeRepos map[string]interface{}
+
+func (f fakeRepos) Do(req *http.Request) (*http.Response, error) {
+	switch r := f[req.URL.Host].(type) {
+	case error:
+		return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: r}
+	case int:
+		body := "apiVersion: v1\nentries: {}\n"
+		return &http.Response{StatusCode: r, Body: ioutil.NopCloser(bytes.NewBufferString(body))}, nil
+	}
+	return nil, fmt.Errorf("unexpected request to %s", req.URL)
+}
+
+func TestFetchRepoIndexFailover(t *testing.T) {
+	repos := []string{"http://primary/index.yaml", "http://mirror-1/index.yaml", "http://mirror-2/index.yaml"}
+	tests := []struct {
+		name        string
+		responses   fakeRepos
+		expectedURL string
+		expectedErr bool
+	}{
+		{"primary", fakeRepos{"primary": 200}, repos[0], false},
+		{"timeout", fakeRepos{"primary": fmt.Errorf("timeout"), "mirror-1": 200}, repos[1], false},
+		{"server errors", fakeRepos{"primary": 503, "mirror-1": 500, "mirror-2": 200}, repos[2], false},
+		{"not found is not failed over", fakeRepos{"primary": 404, "mirror-1": 200}, repos[0], true},
+		{"all unavailable", fakeRepos{"primary": 503, "mirror-1": 503, "mirror-2": 502}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var client HTTPClient = tt.responses
+			index, repoURL, err := FetchRepoIndexFailover(&client, repos, nil)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expecting error %v, received %v", tt.expectedErr, err)
+			}
+			if repoURL != tt.expectedURL {
+				t.Errorf("Expecting the index of %q, received %q", tt.expectedURL, repoURL)
+			}
+			if err == nil && index == nil {
+				t.Errorf("Expecting an index")
+			}
+		})
+	}
+}
+
+func TestFetchChartDigest(t *testing.T) {
+	const chartURL = "https://charts.example.com/foo-1.0.0.tgz"
+	var client HTTPClient = &fakeServer{bodies: map[string]string{chartURL: "foo 1.0.0"}, auth: map[string]string{}}
+	// sha256 of "foo 1.0.0"
+	const digest = "sha256:7a31c1c4dbf480fbbb931641bdda1cdd48ba6cc719eb5b38368f014da323160d"
+
+	if _, err := FetchChartDigest(&client, chartURL, nil, "sha256:"+strings.Repeat("0", 64), 0, loadNamed); err == nil {
+		t.Errorf("Expecting an error for a different digest")
+	}
+	ch, err := FetchChartDigest(&client, chartURL, nil, digest, 0, loadNamed)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ch.Metadata.Name != "foo" {
+		t.Errorf("Unexpected chart %v", ch.Metadata)
+	}
+
+	// Loaders are told the size of the archive
+	var size int64
+	sized := func(in io.Reader) (*chart.Chart, error) {
+		if r, ok := in.(interface {
+			Size() int64
+		}); ok {
+			size = r.Size()
+		}
+		return loadNamed(in)
+	}
+	if _, err := FetchChartDigest(&client, chartURL, nil, digest, 0, sized); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if size != int64(len("foo 1.0.0")) {
+		t.Errorf("Expecting an archive of %d bytes, received %d", len("foo 1.0.0"), size)
+	}
+}
+
+func TestFetchChartMaxSize(t *testing.T) {
+	const chartURL = "https://charts.example.com/foo-1.0.0.tgz"
+	var client HTTPClient = &fakeServer{bodies: map[string]string{chartURL: "foo 1.0.0"}, auth: map[string]string{}}
+
+	if _, err := FetchChart(&client, chartURL, nil, int64(len("foo 1.0.0")), loadNamed); err != nil {
+		t.Errorf("Unexpected error %v for an archive of the maximum size", err)
+	}
+	_, err := FetchChart(&client, chartURL, nil, 4, loadNamed)
+	if _, ok := err.(*ArchiveTooLargeError); !ok {
+		t.Errorf("Expecting ArchiveTooLargeError, received %v", err)
+	}
 }
@@ -0,0 +1,94 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// ResolveDependencies downloads the dependencies declared in the
+// requirements.yaml of ch that are not bundled in its charts/ directory,
+// as `helm dependency build` does: versions locked in requirements.lock
+// are used, ranges are resolved otherwise. Dependencies must name the URL
+// of their repository, aliases of local repositories are not supported.
+// header is only sent to repoURL, the repository of ch. Archives
+// larger than maxSize bytes are rejected, unless maxSize is zero. Unless
+// nil, check returns an error for the repository and chart URLs that may
+// not be downloaded from.
+func ResolveDependencies(netClient *HTTPClient, ch *chart.Chart, repoURL string, header http.Header, maxSize int64, load LoadChart, check func(url string) error) error {
+	reqs, err := chartutil.LoadRequirements(ch)
+	if err != nil {
+		if err == chartutil.ErrRequirementsNotFound {
+			return nil
+		}
+		return err
+	}
+	locked := map[string]string{}
+	if lock, err := chartutil.LoadRequirementsLock(ch); err == nil {
+		for _, dep := range lock.Dependencies {
+			locked[dep.Name] = dep.Version
+		}
+	}
+
+	bundled := map[string]bool{}
+	for _, dep := range ch.Dependencies {
+		bundled[dep.GetMetadata().GetName()] = true
+	}
+
+	indexes := map[string]*repo.IndexFile{}
+	for _, dep := range reqs.Dependencies {
+		if bundled[dep.Name] {
+			continue
+		}
+		if !strings.HasPrefix(dep.Repository, "http://") && !strings.HasPrefix(dep.Repository, "https://") {
+			return fmt.Errorf("dependency %q: unsupported repository %q, only http(s) URLs can be resolved", dep.Name, dep.Repository)
+		}
+		if err := checkURL(check, strings.TrimSpace(dep.Repository)); err != nil {
+			return fmt.Errorf("dependency %q: %v", dep.Name, err)
+		}
+		depRepoURL := strings.TrimSuffix(strings.TrimSpace(dep.Repository), "/") + "/index.yaml"
+		var depHeader http.Header
+		if depRepoURL == repoURL {
+			depHeader = header
+		}
+
+		index, ok := indexes[depRepoURL]
+		if !ok {
+			index, err = FetchRepoIndex(netClient, depRepoURL, depHeader)
+			if err != nil {
+				return fmt.Errorf("dependency %q: %v", dep.Name, err)
+			}
+			indexes[depRepoURL] = index
+		}
+		version := dep.Version
+		if v, ok := locked[dep.Name]; ok {
+			version = v
+		}
+		chartURL, _, err := FindChartInRepoIndex(index, depRepoURL, dep.Name, version, false)
+		if err != nil {
+			return fmt.Errorf("dependency %q: %v", dep.Name, err)
+		}
+		if err := checkURL(check, chartURL); err != nil {
+			return fmt.Errorf("dependency %q: %v", dep.Name, err)
+		}
+		depChart, err := FetchChart(netClient, chartURL, depHeader, maxSize, load)
+		if err != nil {
+			return fmt.Errorf("dependency %q: %v", dep.Name, err)
+		}
+		ch.Dependencies = append(ch.Dependencies, depChart)
+		bundled[dep.Name] = true
+	}
+	return nil
+}
+
+// checkURL returns the error of check for url, nil if check is nil
+func checkURL(check func(url string) error, url string) error {
+	if check == nil {
+		return nil
+	}
+	return check(url)
+}
@@ -0,0 +1,121 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// fakeServer serves the bodies of known URLs, recording the
+// Authorization header of each request
+type fakeServer struct {
+	bodies map[string]string
+	auth   map[string]string
+}
+
+func (f *fakeServer) Do(req *http.Request) (*http.Response, error) {
+	body, ok := f.bodies[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+	}
+	f.auth[req.URL.String()] = req.Header.Get("Authorization")
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))}, nil
+}
+
+// loadNamed loads charts whose archive is their name and version
+func loadNamed(in io.Reader) (*chart.Chart, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var name, version string
+	fmt.Sscanf(string(data), "%s %s", &name, &version)
+	return &chart.Chart{Metadata: &chart.Metadata{Name: name, Version: version}}, nil
+}
+
+func indexOf(name string, versions ...string) string {
+	index := "apiVersion: v1\nentries:\n  " + name + ":\n"
+	for _, v := range versions {
+		index += fmt.Sprintf("  - name: %s\n    version: %s\n    urls: [%s-%s.tgz]\n", name, v, name, v)
+	}
+	return index
+}
+
+func TestResolveDependencies(t *testing.T) {
+	server := &fakeServer{
+		bodies: map[string]string{
+			"https://charts.example.com/index.yaml":            indexOf("mariadb", "4.0.0", "4.1.0", "5.0.0"),
+			"https://charts.example.com/mariadb-4.0.0.tgz":     "mariadb 4.0.0",
+			"https://charts.example.com/mariadb-4.1.0.tgz":     "mariadb 4.1.0",
+			"https://other.example.com/charts/index.yaml":      indexOf("redis", "1.0.0"),
+			"https://other.example.com/charts/redis-1.0.0.tgz": "redis 1.0.0",
+		},
+		auth: map[string]string{},
+	}
+	var client HTTPClient = server
+	requirements := `dependencies:
+- name: mariadb
+  version: ^4.0.0
+  repository: https://charts.example.com/
+- name: redis
+  version: 1.0.0
+  repository: https://other.example.com/charts
+- name: bundled
+  version: 0.1.0
+  repository: "@local"
+`
+	newChart := func(files ...*any.Any) *chart.Chart {
+		return &chart.Chart{
+			Metadata:     &chart.Metadata{Name: "foo"},
+			Files:        files,
+			Dependencies: []*chart.Chart{{Metadata: &chart.Metadata{Name: "bundled"}}},
+		}
+	}
+	reqsFile := &any.Any{TypeUrl: "requirements.yaml", Value: []byte(requirements)}
+
+	ch := newChart(reqsFile)
+	if err := ResolveDependencies(&client, ch, "https://charts.example.com/index.yaml", http.Header{"Authorization": {"Bearer token"}}, 0, loadNamed, nil); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(ch.Dependencies) != 3 || ch.Dependencies[1].Metadata.Version != "4.1.0" || ch.Dependencies[2].Metadata.Name != "redis" {
+		t.Errorf("Expecting mariadb 4.1.0 and redis to be added, received %v", ch.Dependencies)
+	}
+	if server.auth["https://charts.example.com/mariadb-4.1.0.tgz"] != "Bearer token" || server.auth["https://other.example.com/charts/index.yaml"] != "" {
+		t.Errorf("Expecting the auth header to be sent to the chart repository only, received %v", server.auth)
+	}
+
+	lock := &any.Any{TypeUrl: "requirements.lock", Value: []byte("dependencies:\n- name: mariadb\n  version: 4.0.0\n")}
+	ch = newChart(reqsFile, lock)
+	if err := ResolveDependencies(&client, ch, "", nil, 0, loadNamed, nil); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ch.Dependencies[1].Metadata.Version != "4.0.0" {
+		t.Errorf("Expecting the locked version, received %v", ch.Dependencies[1].Metadata)
+	}
+
+	// Dependencies of repositories not allowed are not fetched
+	denyOther := func(url string) error {
+		if strings.HasPrefix(url, "https://other.example.com/") {
+			return fmt.Errorf("repository %s is not allowed", url)
+		}
+		return nil
+	}
+	if err := ResolveDependencies(&client, newChart(reqsFile), "", nil, 0, loadNamed, denyOther); err == nil || !strings.Contains(err.Error(), "redis") {
+		t.Errorf("Expecting the redis repository to be rejected, received %v", err)
+	}
+
+	unbundled := &any.Any{TypeUrl: "requirements.yaml", Value: []byte("dependencies:\n- name: local\n  repository: \"@local\"\n")}
+	if err := ResolveDependencies(&client, newChart(unbundled), "", nil, 0, loadNamed, nil); err == nil {
+		t.Errorf("Expecting a repository alias to fail")
+	}
+	if err := ResolveDependencies(&client, newChart(), "", nil, 0, loadNamed, nil); err != nil {
+		t.Errorf("Expecting charts without requirements to be left as is, received %v", err)
+	}
+}
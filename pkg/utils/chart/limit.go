@@ -0,0 +1,87 @@
+package chart
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ConcurrencyLimiter is an HTTPClient bounding the number of requests in
+// flight, overall and to each host. A request holds its slots until its
+// response body is closed, so that reading large chart archives counts.
+// Requests wait for a slot until their context is done.
+type ConcurrencyLimiter struct {
+	Client HTTPClient
+	// Max is the number of concurrent requests, unlimited if zero
+	Max int
+	// MaxPerHost is the number of concurrent requests to a host,
+	// unlimited if zero
+	MaxPerHost int
+
+	mu     sync.Mutex
+	global chan struct{}
+	hosts  map[string]chan struct{}
+}
+
+// slots returns the semaphores req must acquire
+func (c *ConcurrencyLimiter) slots(req *http.Request) []chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var slots []chan struct{}
+	if c.Max > 0 {
+		if c.global == nil {
+			c.global = make(chan struct{}, c.Max)
+		}
+		slots = append(slots, c.global)
+	}
+	if c.MaxPerHost > 0 {
+		if c.hosts == nil {
+			c.hosts = map[string]chan struct{}{}
+		}
+		host, ok := c.hosts[req.URL.Host]
+		if !ok {
+			host = make(chan struct{}, c.MaxPerHost)
+			c.hosts[req.URL.Host] = host
+		}
+		slots = append(slots, host)
+	}
+	return slots
+}
+
+// Do sends req once it got a slot
+func (c *ConcurrencyLimiter) Do(req *http.Request) (*http.Response, error) {
+	slots := c.slots(req)
+	release := func(n int) {
+		for _, slot := range slots[:n] {
+			<-slot
+		}
+	}
+	for i, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+		case <-req.Context().Done():
+			release(i)
+			return nil, req.Context().Err()
+		}
+	}
+	res, err := c.Client.Do(req)
+	if err != nil || len(slots) == 0 {
+		release(len(slots))
+		return res, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: func() { release(len(slots)) }}
+	return res, nil
+}
+
+// releasingBody releases the slots of its request once closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,47 @@
+package chart
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	client := &ConcurrencyLimiter{
+		Client:     &fakeResponses{responses: []interface{}{200, 200, 200, 200}},
+		Max:        3,
+		MaxPerHost: 2,
+	}
+	get := func(ctx context.Context, url string) (*http.Response, error) {
+		req, _ := http.NewRequest("GET", url, nil)
+		return client.Do(req.WithContext(ctx))
+	}
+	// waits returns the error of a request given 50ms to get a slot
+	waits := func(url string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := get(ctx, url)
+		return err
+	}
+
+	first, _ := get(context.Background(), "http://charts.example.com/foo-1.0.0.tgz")
+	get(context.Background(), "http://charts.example.com/bar-1.0.0.tgz")
+	if err := waits("http://charts.example.com/index.yaml"); err != context.DeadlineExceeded {
+		t.Fatalf("Expecting a third request to the host to wait, received %v", err)
+	}
+	get(context.Background(), "http://other.example.com/index.yaml")
+	if err := waits("http://another.example.com/index.yaml"); err != context.DeadlineExceeded {
+		t.Fatalf("Expecting a fourth request to wait, received %v", err)
+	}
+
+	// Closing a response body, twice being harmless, releases its slots
+	first.Body.Close()
+	first.Body.Close()
+	if err := waits("http://charts.example.com/index.yaml"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := waits("http://charts.example.com/index.yaml"); err != context.DeadlineExceeded {
+		t.Errorf("Expecting the released slot to be taken, received %v", err)
+	}
+}
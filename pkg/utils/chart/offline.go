@@ -0,0 +1,80 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// NotMirroredError is returned by OfflineClient for requests neither sent
+// to a mirror nor found in the cache
+type NotMirroredError struct {
+	URL string
+}
+
+func (e *NotMirroredError) Error() string {
+	return fmt.Sprintf("offline mode: %s is not in the chart cache nor served by a mirror", e.URL)
+}
+
+// OfflineClient is an HTTPClient of air-gapped clusters, which only sends
+// the requests of URLs under one of Mirrors, and serves the other GET
+// requests from the files of CacheDir at <host>/<path>, the layout of
+// `wget --force-directories`. Any other request fails with a
+// *NotMirroredError without reaching the network.
+type OfflineClient struct {
+	Client HTTPClient
+	// Mirrors are the URL prefixes of the internal repositories, e.g.
+	// http://chartmuseum.charts.svc:8080/
+	Mirrors []string
+	// CacheDir is the directory of the pre-populated cache, not used if
+	// empty
+	CacheDir string
+}
+
+// Do sends req if it is for a mirror, and serves it from the cache
+// otherwise
+func (c *OfflineClient) Do(req *http.Request) (*http.Response, error) {
+	u := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	for _, mirror := range c.Mirrors {
+		if u == mirror || strings.HasPrefix(u, strings.TrimSuffix(mirror, "/")+"/") {
+			return c.Client.Do(req)
+		}
+	}
+	if c.CacheDir != "" && req.Method == http.MethodGet {
+		name := filepath.Join(c.CacheDir, req.URL.Host, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+		if res, err := c.cached(req, name); err == nil {
+			return res, nil
+		}
+	}
+	return nil, &NotMirroredError{URL: u}
+}
+
+// cached returns a response with the content of the file name
+func (c *OfflineClient) cached(req *http.Request, name string) (*http.Response, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = fmt.Errorf("%s is not a file", name)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          f,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
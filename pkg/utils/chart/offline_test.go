@@ -0,0 +1,58 @@
+package chart
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "charts.example.com", "stable"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "charts.example.com", "stable", "index.yaml"), []byte("apiVersion: v1\n"), 0644)
+
+	fake := &fakeResponses{responses: []interface{}{200}}
+	client := &OfflineClient{
+		Client:   fake,
+		Mirrors:  []string{"http://chartmuseum.charts.svc:8080/charts"},
+		CacheDir: dir,
+	}
+	get := func(url string) (*http.Response, error) {
+		req, _ := http.NewRequest("GET", url, nil)
+		return client.Do(req)
+	}
+
+	if res, err := get("http://chartmuseum.charts.svc:8080/charts/foo-1.0.0.tgz"); err != nil || res.StatusCode != 200 || fake.requests != 1 {
+		t.Errorf("Expecting requests to mirrors to be sent, received %v", err)
+	}
+	res, err := get("https://charts.example.com/stable/index.yaml?token=x")
+	if err != nil {
+		t.Fatalf("Expecting cached files to be served, received %v", err)
+	}
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(data) != "apiVersion: v1\n" {
+		t.Errorf("Unexpected cached content %q", data)
+	}
+
+	for _, url := range []string{
+		"https://charts.example.com/stable/foo-1.0.0.tgz",
+		"https://charts.example.com/stable/",
+		"https://charts.example.com/stable/../../../etc/passwd",
+		"http://chartmuseum.charts.svc:8080/charts-other/foo-1.0.0.tgz",
+	} {
+		_, err := get(url)
+		if _, ok := err.(*NotMirroredError); !ok {
+			t.Errorf("Expecting %s not to be mirrored, received %v", url, err)
+		}
+	}
+	if fake.requests != 1 {
+		t.Errorf("Expecting no other request to be sent, sent %d", fake.requests)
+	}
+}
@@ -0,0 +1,63 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyClient is an HTTPClient sending requests through the caching chart
+// proxy at URL, requesting <URL>/<scheme>/<host>/<path> for the URL
+// <scheme>://<host>/<path>
+type ProxyClient struct {
+	Client HTTPClient
+	URL    string
+}
+
+// Do sends req through the proxy. The response is returned as if it came
+// from the requested URL.
+func (c *ProxyClient) Do(req *http.Request) (*http.Response, error) {
+	u, err := ProxiedURL(c.URL, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	proxied := new(http.Request)
+	*proxied = *req
+	proxied.URL = u
+	proxied.Host = u.Host
+	res, err := c.Client.Do(proxied)
+	if res != nil {
+		res.Request = req
+	}
+	return res, err
+}
+
+// ProxiedURL returns the URL of the chart proxy at proxyURL serving u
+func ProxiedURL(proxyURL string, u *url.URL) (*url.URL, error) {
+	p, err := url.Parse(strings.TrimSuffix(proxyURL, "/") + "/" + u.Scheme + "/" + u.Host + u.EscapedPath())
+	if err != nil {
+		return nil, err
+	}
+	p.RawQuery = u.RawQuery
+	return p, nil
+}
+
+// UpstreamURL returns the URL served by the chart proxy for the escaped
+// path, relative to the proxy root, <scheme>/<host>/<path>
+func UpstreamURL(path, rawQuery string) (*url.URL, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || (parts[0] != "http" && parts[0] != "https") || parts[1] == "" {
+		return nil, fmt.Errorf("%q is not /<http|https>/<host>/<path>", path)
+	}
+	rest := ""
+	if len(parts) == 3 {
+		rest = parts[2]
+	}
+	u, err := url.Parse(parts[0] + "://" + parts[1] + "/" + rest)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = rawQuery
+	return u, nil
+}
@@ -0,0 +1,32 @@
+package chart
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestProxiedURL(t *testing.T) {
+	tests := []string{
+		"https://charts.example.com/stable/index.yaml",
+		"https://charts.example.com/stable/foo-1.0.0+build.1.tgz?token=a%2Fb",
+		"http://registry.example.com:5000/v2/charts/foo/manifests/sha256:abc",
+		"https://charts.example.com/a%2Fb/foo-1.0.0.tgz",
+	}
+	for _, u := range tests {
+		parsed, _ := url.Parse(u)
+		proxied, err := ProxiedURL("http://chart-proxy.kube-system:8080/", parsed)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if proxied.Host != "chart-proxy.kube-system:8080" {
+			t.Errorf("Expecting %s to be requested from the proxy, received %s", u, proxied)
+		}
+		upstream, err := UpstreamURL(proxied.EscapedPath(), proxied.RawQuery)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if upstream.String() != u {
+			t.Errorf("Expecting the proxy to request %s, received %s", u, upstream)
+		}
+	}
+}
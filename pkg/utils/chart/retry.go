@@ -0,0 +1,69 @@
+package chart
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryingClient is an HTTPClient retrying GET requests that fail with a
+// connection error or a 5xx or 429 response, waiting an exponentially
+// growing and jittered delay between attempts
+type RetryingClient struct {
+	Client HTTPClient
+	// Attempts is the maximum number of requests sent, including the first one
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubled on every further one
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration
+
+	sleep func(time.Duration)
+}
+
+// Do sends req, retrying it as configured. The response of the last
+// attempt is returned.
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	sleep := c.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := c.Client.Do(req)
+		// Requests with a body can't be resent
+		if attempt >= c.Attempts || req.Method != http.MethodGet || !retryable(res, err) {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		sleep(c.backoff(attempt))
+	}
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+}
+
+// backoff returns the delay after the given failed attempt: half of the
+// exponential delay plus a random part up to the other half
+func (c *RetryingClient) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay
+	for i := 1; i < attempt && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
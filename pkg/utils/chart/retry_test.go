@@ -0,0 +1,87 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeResponses struct {
+	responses []interface{}
+	requests  int
+}
+
+func (f *fakeResponses) Do(req *http.Request) (*http.Response, error) {
+	r := f.responses[f.requests]
+	f.requests++
+	if err, ok := r.(error); ok {
+		return nil, err
+	}
+	return &http.Response{StatusCode: r.(int), Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+}
+
+func TestRetryingClient(t *testing.T) {
+	tests := []struct {
+		name             string
+		responses        []interface{}
+		expectedRequests int
+		expectedStatus   int
+	}{
+		{"success", []interface{}{200}, 1, 200},
+		{"connection reset", []interface{}{fmt.Errorf("connection reset by peer"), 200}, 2, 200},
+		{"server errors", []interface{}{503, 502, 200}, 3, 200},
+		{"rate limited", []interface{}{429, 200}, 2, 200},
+		{"not found is not retried", []interface{}{404}, 1, 404},
+		{"gives up", []interface{}{500, 500, 500, 200}, 3, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeResponses{responses: tt.responses}
+			var delays []time.Duration
+			client := &RetryingClient{
+				Client:    fake,
+				Attempts:  3,
+				BaseDelay: time.Second,
+				MaxDelay:  time.Minute,
+				sleep:     func(d time.Duration) { delays = append(delays, d) },
+			}
+			req, _ := http.NewRequest("GET", "http://charts.example.com/index.yaml", nil)
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if fake.requests != tt.expectedRequests {
+				t.Errorf("Expecting %d requests received %d", tt.expectedRequests, fake.requests)
+			}
+			if res.StatusCode != tt.expectedStatus {
+				t.Errorf("Expecting status %d received %d", tt.expectedStatus, res.StatusCode)
+			}
+			if len(delays) != tt.expectedRequests-1 {
+				t.Errorf("Expecting %d delays received %v", tt.expectedRequests-1, delays)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := &RetryingClient{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{10, 2500 * time.Millisecond, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := client.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Errorf("Expecting a delay between %v and %v after attempt %d received %v", tt.min, tt.max, tt.attempt, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package chart
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/helm/pkg/repo"
+)
+
+// IndexGroup coalesces concurrent fetches of the same repository index:
+// callers arriving while a fetch is in flight wait for it and share its
+// result rather than downloading the index again. Results are not cached
+// beyond the fetch. The zero value is ready to use.
+type IndexGroup struct {
+	mu    sync.Mutex
+	calls map[string]*indexCall
+}
+
+type indexCall struct {
+	done    chan struct{}
+	index   *repo.IndexFile
+	repoURL string
+	err     error
+}
+
+// FetchFailover returns the index of the first available of repoURLs like
+// FetchRepoIndexFailover, sharing the fetch with concurrent callers of the
+// same URLs and credentials. The returned index is shared, callers must
+// not modify it.
+func (g *IndexGroup) FetchFailover(netClient *HTTPClient, repoURLs []string, header http.Header) (*repo.IndexFile, string, error) {
+	key := strings.Join(repoURLs, " ") + "\x00" + headerKey(header)
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*indexCall{}
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.index, call.repoURL, call.err
+	}
+	call := &indexCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.index, call.repoURL, call.err = FetchRepoIndexFailover(netClient, repoURLs, header)
+	close(call.done)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return call.index, call.repoURL, call.err
+}
+
+// headerKey returns header as a string, sorted by name
+func headerKey(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(header[name], ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package chart
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/helm/pkg/repo"
+)
+
+// blockingServer serves an index once released, counting requests
+type blockingServer struct {
+	requests int32
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingServer) Do(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&s.requests, 1) == 1 {
+		close(s.started)
+	}
+	<-s.release
+	body := "apiVersion: v1\nentries: {}\n"
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}, nil
+}
+
+func TestIndexGroup(t *testing.T) {
+	server := &blockingServer{started: make(chan struct{}), release: make(chan struct{})}
+	var client HTTPClient = server
+	var group IndexGroup
+	repoURLs := []string{"https://charts.example.com/index.yaml"}
+
+	const callers = 5
+	indexes := make([]*repo.IndexFile, callers)
+	var wg sync.WaitGroup
+	fetch := func(i int) {
+		defer wg.Done()
+		index, _, err := group.FetchFailover(&client, repoURLs, nil)
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+		indexes[i] = index
+	}
+	wg.Add(callers)
+	go fetch(0)
+	<-server.started
+	for i := 1; i < callers; i++ {
+		go fetch(i)
+	}
+	// Give the other callers time to join the fetch in flight
+	time.Sleep(50 * time.Millisecond)
+	close(server.release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&server.requests); n != 1 {
+		t.Errorf("Expecting concurrent fetches to be coalesced, received %d requests", n)
+	}
+	for i, index := range indexes {
+		if index == nil || index != indexes[0] {
+			t.Errorf("Expecting caller %d to share the index", i)
+		}
+	}
+
+	// Fetches after the one in flight completed download the index again
+	if _, _, err := group.FetchFailover(&client, repoURLs, nil); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if n := atomic.LoadInt32(&server.requests); n != 2 {
+		t.Errorf("Expecting a new request, received %d requests", n)
+	}
+}
@@ -0,0 +1,71 @@
+package chart
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// TransportOptions tune the connections to chart repositories
+type TransportOptions struct {
+	// DialTimeout bounds establishing TCP connections
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout bounds TLS handshakes
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds waiting for the response headers once
+	// a request is sent, leaving large downloads unbounded
+	ResponseHeaderTimeout time.Duration
+	// MaxConnsPerHost limits the connections to each repository host,
+	// unlimited if zero
+	MaxConnsPerHost int
+	// MaxIdleConnsPerHost is the number of connections kept alive to
+	// each repository host
+	MaxIdleConnsPerHost int
+	// DeniedNetworks are the networks no connection is made to, checked
+	// against the address a host resolved to when connecting
+	DeniedNetworks []*net.IPNet
+}
+
+// tlsSessionCacheSize is the number of TLS sessions resumed across
+// connections, one per repository host
+const tlsSessionCacheSize = 256
+
+// NewTransport returns a transport shared by the requests to chart
+// repositories, reusing connections and TLS sessions per host. Proxies are
+// configured from the environment like in http.DefaultTransport.
+func NewTransport(opts TransportOptions) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if len(opts.DeniedNetworks) > 0 {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			for _, n := range opts.DeniedNetworks {
+				if ip != nil && n.Contains(ip) {
+					return fmt.Errorf("connections to %s are denied", ip)
+				}
+			}
+			return nil
+		}
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSClientConfig:       &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(tlsSessionCacheSize)},
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
@@ -0,0 +1,54 @@
+package chart
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewTransportReusesConnections(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	var client HTTPClient = &http.Client{Transport: NewTransport(TransportOptions{
+		DialTimeout:           time.Second,
+		ResponseHeaderTimeout: time.Second,
+		MaxIdleConnsPerHost:   1,
+	})}
+	for i := 0; i < 3; i++ {
+		if _, err := FetchRepoIndex(&client, server.URL+"/index.yaml", nil); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("Expecting requests to share one connection, opened %d", n)
+	}
+}
+
+func TestNewTransportDeniedNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	defer server.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	var client HTTPClient = &http.Client{Transport: NewTransport(TransportOptions{
+		DeniedNetworks: []*net.IPNet{loopback},
+	})}
+	if _, err := FetchRepoIndex(&client, server.URL+"/index.yaml", nil); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expecting connections to a denied network to fail, received %v", err)
+	}
+}
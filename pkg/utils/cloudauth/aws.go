@@ -0,0 +1,203 @@
+package cloudauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// awsSessionName is the name of the sessions of the assumed IAM role
+	awsSessionName = "helm-crd"
+	// ecrGetAuthorizationToken is the target of the ECR API action
+	// returning registry credentials
+	ecrGetAuthorizationToken = "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"
+)
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// ecrToken gets an authorization token of the Elastic Container Registry
+// of u, <account>.dkr.ecr.<region>.amazonaws.com, sent as basic auth
+func (h *Helper) ecrToken(u *url.URL) (credential, error) {
+	region, suffix := h.awsRegion(u.Hostname())
+	if region == "" {
+		return credential{}, fmt.Errorf("no AWS region in %s", u.Host)
+	}
+	creds, err := h.awsCredentials(region, suffix)
+	if err != nil {
+		return credential{}, err
+	}
+
+	body := []byte("{}")
+	req, err := http.NewRequest("POST", h.awsEndpoint("api.ecr", region, suffix), bytes.NewReader(body))
+	if err != nil {
+		return credential{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", ecrGetAuthorizationToken)
+	signAWS(req, body, creds, region, "ecr", h.now())
+	data, err := h.send(req)
+	if err != nil {
+		return credential{}, err
+	}
+	var res struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return credential{}, err
+	}
+	if len(res.AuthorizationData) == 0 || res.AuthorizationData[0].AuthorizationToken == "" {
+		return credential{}, fmt.Errorf("%s %s: no token in response", req.Method, req.URL.Host+req.URL.Path)
+	}
+	// The token is the base64 encoded user and password
+	return credential{
+		header:  "Basic " + res.AuthorizationData[0].AuthorizationToken,
+		expires: time.Unix(int64(res.AuthorizationData[0].ExpiresAt), 0),
+	}, nil
+}
+
+// awsRegion returns the region of an ECR registry host and the suffix of
+// the domain of its partition, or AWS_REGION for other hosts
+func (h *Helper) awsRegion(host string) (string, string) {
+	labels := strings.Split(host, ".")
+	if len(labels) >= 6 && labels[1] == "dkr" && labels[2] == "ecr" && labels[4] == "amazonaws" && labels[5] == "com" {
+		suffix := ""
+		if len(labels) > 6 {
+			suffix = "." + strings.Join(labels[6:], ".")
+		}
+		return labels[3], suffix
+	}
+	return h.getenv("AWS_REGION"), ""
+}
+
+// awsEndpoint returns the URL of the regional endpoint of service
+func (h *Helper) awsEndpoint(service, region, suffix string) string {
+	if h.AWSEndpointURL != "" {
+		return strings.TrimSuffix(h.AWSEndpointURL, "/") + "/"
+	}
+	return "https://" + service + "." + region + ".amazonaws.com" + suffix + "/"
+}
+
+// awsCredentials returns the credentials of the IAM role of the service
+// account of the pod if configured, or those of the environment
+func (h *Helper) awsCredentials(region, suffix string) (awsCredentials, error) {
+	roleARN, tokenFile := h.getenv("AWS_ROLE_ARN"), h.getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		creds := awsCredentials{
+			accessKeyID:     h.getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: h.getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    h.getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+			return awsCredentials{}, fmt.Errorf("no AWS credentials: neither AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE nor AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are set")
+		}
+		return creds, nil
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {awsSessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequest("POST", h.awsEndpoint("sts", region, suffix), strings.NewReader(form.Encode()))
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data, err := h.send(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var res struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string
+			SessionToken    string
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(data, &res); err != nil {
+		return awsCredentials{}, err
+	}
+	if res.Credentials.AccessKeyID == "" {
+		return awsCredentials{}, fmt.Errorf("%s %s: no credentials in response", req.Method, req.URL.Host+req.URL.Path)
+	}
+	return awsCredentials{
+		accessKeyID:     res.Credentials.AccessKeyID,
+		secretAccessKey: res.Credentials.SecretAccessKey,
+		sessionToken:    res.Credentials.SessionToken,
+	}, nil
+}
+
+// signAWS signs req, whose body is body, for service in region with
+// Signature Version 4, signing its host, content type and X-Amz headers
+func signAWS(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payload := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payload[:])}, "\n")
+	scope := strings.Join([]string{amzDate[:8], region, service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.secretAccessKey)
+	for _, part := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,380 @@
+// Package cloudauth exchanges the cloud identity of the pod, its GKE
+// workload identity, its Azure managed or workload identity or its EKS
+// IAM role, for the short-lived credentials of the chart repositories
+// hosted by the cloud provider, so that no long-lived secrets need to be
+// maintained. OAuth2 client credentials are exchanged for access tokens
+// likewise.
+package cloudauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider is a cloud provider
+type Provider string
+
+const (
+	// GCP gets Google access tokens from the metadata server, e.g. for
+	// repositories in Google Cloud Storage buckets or Artifact Registry
+	GCP Provider = "GCP"
+	// Azure exchanges Azure AD tokens for Azure Container Registry
+	// refresh tokens
+	Azure Provider = "Azure"
+	// AWS exchanges the IAM credentials of the pod for Elastic Container
+	// Registry authorization tokens
+	AWS Provider = "AWS"
+)
+
+// DefaultHosts are the hosts the credentials of each provider are sent
+// to, each "*" label matching any single label
+var DefaultHosts = map[Provider][]string{
+	GCP:   {"storage.googleapis.com", "*.pkg.dev", "gcr.io", "*.gcr.io"},
+	Azure: {"*.azurecr.io"},
+	AWS:   {"*.dkr.ecr.*.amazonaws.com", "*.dkr.ecr.*.amazonaws.com.cn"},
+}
+
+const (
+	defaultGCPMetadataURL     = "http://metadata.google.internal"
+	defaultAzureIMDSURL       = "http://169.254.169.254"
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com/"
+	// azureResource is the audience of the Azure AD tokens exchanged for
+	// registry refresh tokens
+	azureResource = "https://management.azure.com/"
+	// acrUsername is the user name sent along ACR refresh tokens
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+	// acrRefreshTokenLifetime is how long ACR refresh tokens are valid
+	acrRefreshTokenLifetime = 3 * time.Hour
+	// refreshMargin is how long before they expire credentials are renewed
+	refreshMargin = 5 * time.Minute
+)
+
+// Helper returns the Authorization headers of cloud hosted repositories,
+// caching them until shortly before they expire. Azure workload identity
+// is configured with the AZURE_CLIENT_ID, AZURE_TENANT_ID,
+// AZURE_FEDERATED_TOKEN_FILE and AZURE_AUTHORITY_HOST environment
+// variables set by AKS, falling back to the managed identity of the node.
+// AWS credentials are those of the IAM role of the service account, from
+// the AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE environment variables
+// set by EKS, or the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables.
+type Helper struct {
+	Client *http.Client
+	// Hosts are the hosts credentials are sent to by provider, over https
+	Hosts map[Provider][]string
+	// GCPMetadataURL is the URL of the GCE metadata server
+	GCPMetadataURL string
+	// AzureIMDSURL is the URL of the Azure instance metadata service
+	AzureIMDSURL string
+	// AWSEndpointURL replaces the regional STS and ECR endpoints if set
+	AWSEndpointURL string
+	// Getenv reads the environment, os.Getenv if nil
+	Getenv func(string) string
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]credential
+}
+
+type credential struct {
+	header  string
+	expires time.Time
+}
+
+// NewHelper returns a Helper sending its requests with client
+func NewHelper(client *http.Client) *Helper {
+	return &Helper{
+		Client:         client,
+		Hosts:          DefaultHosts,
+		GCPMetadataURL: defaultGCPMetadataURL,
+		AzureIMDSURL:   defaultAzureIMDSURL,
+	}
+}
+
+// AuthHeader returns the Authorization header of the repository at
+// repoURL hosted by provider, which must be one of its Hosts
+func (h *Helper) AuthHeader(provider Provider, repoURL string) (string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	hosts, ok := h.Hosts[provider]
+	if !ok {
+		return "", fmt.Errorf("unsupported cloud provider %q", provider)
+	}
+	if u.Scheme != "https" || !matchesHost(hosts, u.Hostname()) {
+		return "", fmt.Errorf("%s credentials are only sent over https to %s, not to %s", provider, strings.Join(hosts, ", "), u.Host)
+	}
+	header, err := h.cached(string(provider)+"\n"+u.Host, func() (credential, error) {
+		switch provider {
+		case GCP:
+			return h.gcpToken()
+		case Azure:
+			return h.acrToken(u)
+		case AWS:
+			return h.ecrToken(u)
+		}
+		return credential{}, fmt.Errorf("unsupported cloud provider %q", provider)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s credentials: %v", provider, err)
+	}
+	return header, nil
+}
+
+// matchesHost returns whether host matches one of patterns, whose "*"
+// labels match any single label
+func matchesHost(patterns []string, host string) bool {
+	labels := strings.Split(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		patternLabels := strings.Split(pattern, ".")
+		if len(patternLabels) != len(labels) {
+			continue
+		}
+		matches := true
+		for i, label := range patternLabels {
+			if label != "*" && label != labels[i] || labels[i] == "" {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientCredentialsAuthHeader returns the Bearer Authorization header of
+// an access token issued for scopes by the OAuth2 token endpoint at
+// tokenURL with the client credentials grant
+func (h *Helper) ClientCredentialsAuthHeader(tokenURL, clientID, clientSecret string, scopes []string) (string, error) {
+	// Rotated client secrets get new tokens
+	sum := sha256.Sum256([]byte(clientSecret))
+	key := strings.Join([]string{"oauth2", tokenURL, clientID, strings.Join(scopes, " "), hex.EncodeToString(sum[:])}, "\n")
+	header, err := h.cached(key, func() (credential, error) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if len(scopes) > 0 {
+			form.Set("scope", strings.Join(scopes, " "))
+		}
+		req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return credential{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+		var token tokenResponse
+		if err := h.do(req, &token); err != nil {
+			return credential{}, err
+		}
+		// Tokens without expiry are requested again every time
+		return credential{
+			header:  "Bearer " + token.AccessToken,
+			expires: h.now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		}, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("OAuth2 token of client %s: %v", clientID, err)
+	}
+	return header, nil
+}
+
+// cached returns the Authorization header cached under key, unless it
+// expires soon, in which case a new one is got with fetch
+func (h *Helper) cached(key string, fetch func() (credential, error)) (string, error) {
+	h.mu.Lock()
+	cred, ok := h.cache[key]
+	h.mu.Unlock()
+	if ok && h.now().Add(refreshMargin).Before(cred.expires) {
+		return cred.header, nil
+	}
+
+	cred, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	if h.cache == nil {
+		h.cache = map[string]credential{}
+	}
+	h.cache[key] = cred
+	h.mu.Unlock()
+	return cred.header, nil
+}
+
+// RegistryAuthHeader returns the Authorization header of the OCI registry
+// at registryURL hosted by provider, which the registry exchanges for a
+// bearer token
+func (h *Helper) RegistryAuthHeader(provider Provider, registryURL string) (string, error) {
+	header, err := h.AuthHeader(provider, registryURL)
+	if err != nil || provider != GCP {
+		return header, err
+	}
+	// Google registries take access tokens as the password of the
+	// oauth2accesstoken user
+	token := strings.TrimPrefix(header, "Bearer ")
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte("oauth2accesstoken:"+token)), nil
+}
+
+// tokenResponse is an OAuth 2 token response. Azure IMDS returns
+// expires_in as a string.
+type tokenResponse struct {
+	AccessToken  string  `json:"access_token"`
+	RefreshToken string  `json:"refresh_token"`
+	ExpiresIn    seconds `json:"expires_in"`
+}
+
+type seconds int64
+
+func (s *seconds) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	*s = seconds(n)
+	return err
+}
+
+// gcpToken gets an access token of the service account of the pod
+func (h *Helper) gcpToken() (credential, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(h.GCPMetadataURL, "/")+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return credential{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var token tokenResponse
+	if err := h.do(req, &token); err != nil {
+		return credential{}, err
+	}
+	return credential{
+		header:  "Bearer " + token.AccessToken,
+		expires: h.now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// acrToken exchanges an Azure AD token for a refresh token of the
+// registry of u, sent as the password of basic auth
+func (h *Helper) acrToken(u *url.URL) (credential, error) {
+	aadToken, err := h.azureADToken()
+	if err != nil {
+		return credential{}, err
+	}
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {u.Host},
+		"access_token": {aadToken},
+	}
+	req, err := http.NewRequest("POST", u.Scheme+"://"+u.Host+"/oauth2/exchange", strings.NewReader(form.Encode()))
+	if err != nil {
+		return credential{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token tokenResponse
+	if err := h.do(req, &token); err != nil {
+		return credential{}, err
+	}
+	return credential{
+		header:  "Basic " + base64.StdEncoding.EncodeToString([]byte(acrUsername+":"+token.RefreshToken)),
+		expires: h.now().Add(acrRefreshTokenLifetime),
+	}, nil
+}
+
+// azureADToken gets an Azure AD access token with the workload identity
+// of the pod if configured, or the managed identity of the node
+func (h *Helper) azureADToken() (string, error) {
+	var req *http.Request
+	clientID := h.getenv("AZURE_CLIENT_ID")
+	if tokenFile := h.getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+		assertion, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		authority := h.getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = defaultAzureAuthorityHost
+		}
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {clientID},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+			"scope":                 {azureResource + ".default"},
+		}
+		req, err = http.NewRequest("POST", strings.TrimSuffix(authority, "/")+"/"+h.getenv("AZURE_TENANT_ID")+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureResource}}
+		if clientID != "" {
+			query.Set("client_id", clientID)
+		}
+		var err error
+		req, err = http.NewRequest("GET", strings.TrimSuffix(h.AzureIMDSURL, "/")+"/metadata/identity/oauth2/token?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+	var token tokenResponse
+	if err := h.do(req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// do sends req, decoding the JSON response into v
+func (h *Helper) do(req *http.Request, v *tokenResponse) error {
+	body, err := h.send(req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return err
+	}
+	if v.AccessToken == "" && v.RefreshToken == "" {
+		return fmt.Errorf("%s %s: no token in response", req.Method, req.URL.Host+req.URL.Path)
+	}
+	return nil
+}
+
+// send sends req, returning the body of its successful response
+func (h *Helper) send(req *http.Request) ([]byte, error) {
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Host+req.URL.Path, res.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func (h *Helper) getenv(key string) string {
+	if h.Getenv != nil {
+		return h.Getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+func (h *Helper) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,275 @@
+package cloudauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGCP(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":3599,"token_type":"Bearer"}`, requests)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	h := NewHelper(http.DefaultClient)
+	h.GCPMetadataURL = server.URL
+	h.Now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		header, err := h.AuthHeader(GCP, "https://storage.googleapis.com/mycharts")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if header != "Bearer token1" {
+			t.Errorf("Expecting the cached token, received %q", header)
+		}
+	}
+
+	header, err := h.RegistryAuthHeader(GCP, "https://europe-docker.pkg.dev")
+	if expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("oauth2accesstoken:token2")); err != nil || header != expected {
+		t.Errorf("Expecting %q for registries, received %q %v", expected, header, err)
+	}
+
+	// Tokens are renewed before they expire
+	now = now.Add(56 * time.Minute)
+	if header, err := h.AuthHeader(GCP, "https://storage.googleapis.com/mycharts"); err != nil || header != "Bearer token3" {
+		t.Errorf("Expecting a new token, received %q %v", header, err)
+	}
+}
+
+func TestAzure(t *testing.T) {
+	var exchanged string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != azureResource {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"imds","expires_in":"3599"}`)
+		case "/mytenant/oauth2/v2.0/token":
+			r.ParseForm()
+			if r.PostForm.Get("client_assertion") != "federated" || r.PostForm.Get("client_id") != "myclient" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"workload","expires_in":3599}`)
+		case "/oauth2/exchange":
+			r.ParseForm()
+			exchanged = r.PostForm.Get("access_token")
+			fmt.Fprintf(w, `{"refresh_token":"refresh-%s"}`, exchanged)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	hosts := map[Provider][]string{Azure: {"127.0.0.1"}}
+	h := NewHelper(server.Client())
+	h.Hosts = hosts
+	h.AzureIMDSURL = server.URL
+	h.Getenv = func(string) string { return "" }
+	header, err := h.AuthHeader(Azure, server.URL+"/helm/v1/repo")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(acrUsername+":refresh-imds")); header != expected {
+		t.Errorf("Expecting %q, received %q", expected, header)
+	}
+
+	dir, err := ioutil.TempDir("", "cloudauth")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("federated\n"), 0600); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	env := map[string]string{
+		"AZURE_CLIENT_ID":            "myclient",
+		"AZURE_TENANT_ID":            "mytenant",
+		"AZURE_FEDERATED_TOKEN_FILE": tokenFile,
+		"AZURE_AUTHORITY_HOST":       server.URL + "/",
+	}
+	h = NewHelper(server.Client())
+	h.Hosts = hosts
+	h.Getenv = func(key string) string { return env[key] }
+	if _, err := h.AuthHeader(Azure, server.URL+"/helm/v1/repo"); err != nil || exchanged != "workload" {
+		t.Errorf("Expecting the workload identity token to be exchanged, received %q %v", exchanged, err)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.Method != "POST" || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "charts.read" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if user != "controller" || (password != "s3cret" && password != "rotated") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		requests++
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":600,"token_type":"Bearer"}`, requests)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	h := NewHelper(http.DefaultClient)
+	h.Now = func() time.Time { return now }
+	scopes := []string{"charts.read"}
+	for i := 0; i < 2; i++ {
+		header, err := h.ClientCredentialsAuthHeader(server.URL+"/token", "controller", "s3cret", scopes)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if header != "Bearer token1" {
+			t.Errorf("Expecting the cached token, received %q", header)
+		}
+	}
+
+	// Rotated secrets and tokens about to expire get new tokens
+	if header, err := h.ClientCredentialsAuthHeader(server.URL+"/token", "controller", "rotated", scopes); err != nil || header != "Bearer token2" {
+		t.Errorf("Expecting a token for the rotated secret, received %q %v", header, err)
+	}
+	now = now.Add(6 * time.Minute)
+	if header, err := h.ClientCredentialsAuthHeader(server.URL+"/token", "controller", "s3cret", scopes); err != nil || header != "Bearer token3" {
+		t.Errorf("Expecting a new token, received %q %v", header, err)
+	}
+
+	if _, err := h.ClientCredentialsAuthHeader(server.URL+"/token", "controller", "wrong", scopes); err == nil {
+		t.Errorf("Expecting an error for invalid client credentials")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no identity", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	h := NewHelper(http.DefaultClient)
+	h.GCPMetadataURL = server.URL
+	if _, err := h.AuthHeader(GCP, "https://storage.googleapis.com/mycharts"); err == nil {
+		t.Errorf("Expecting an error without an identity")
+	}
+	if _, err := h.AuthHeader(Provider("IBM"), "https://storage.googleapis.com/mycharts"); err == nil {
+		t.Errorf("Expecting an error for an unsupported provider")
+	}
+}
+
+func TestAWS(t *testing.T) {
+	var signed http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") == ecrGetAuthorizationToken {
+			signed = r.Header
+			token := base64.StdEncoding.EncodeToString([]byte("AWS:password"))
+			fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":"%s","expiresAt":1.9e9}]}`, token)
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("Action") != "AssumeRoleWithWebIdentity" || r.PostForm.Get("WebIdentityToken") != "federated" || r.PostForm.Get("RoleArn") != "arn:aws:iam::123456789012:role/charts" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken>
+</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "cloudauth")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("federated\n"), 0600); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	env := map[string]string{
+		"AWS_REGION":                  "eu-west-1",
+		"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/charts",
+		"AWS_WEB_IDENTITY_TOKEN_FILE": tokenFile,
+	}
+	h := NewHelper(server.Client())
+	h.Hosts = map[Provider][]string{AWS: {"127.0.0.1"}}
+	h.AWSEndpointURL = server.URL
+	h.Getenv = func(key string) string { return env[key] }
+	header, err := h.RegistryAuthHeader(AWS, server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("AWS:password")); header != expected {
+		t.Errorf("Expecting %q, received %q", expected, header)
+	}
+	if auth := signed.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=ASIAEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/ecr/aws4_request") || signed.Get("X-Amz-Security-Token") != "session" {
+		t.Errorf("Expecting a request signed with the credentials of the role, received %v", signed)
+	}
+
+	if region, suffix := h.awsRegion("123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn"); region != "cn-north-1" || suffix != ".cn" {
+		t.Errorf("Unexpected region %q and suffix %q", region, suffix)
+	}
+}
+
+func TestSignAWS(t *testing.T) {
+	// The get-vanilla case of the Signature Version 4 test suite
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if auth := req.Header.Get("Authorization"); auth != expected {
+		t.Errorf("Expecting %q, received %q", expected, auth)
+	}
+}
+
+func TestHosts(t *testing.T) {
+	h := NewHelper(http.DefaultClient)
+	for _, repoURL := range []string{
+		"https://attacker.example.com/charts",
+		"http://storage.googleapis.com/mycharts",
+		"https://storage.googleapis.com.attacker.example.com/mycharts",
+		"https://pkg.dev",
+		"https://myregistry.azurecr.io.attacker.example.com/helm/v1/repo",
+		"https://123456789012.dkr.ecr.eu-west-1.amazonaws.com.attacker.example.com",
+	} {
+		for _, provider := range []Provider{GCP, Azure, AWS} {
+			if _, err := h.AuthHeader(provider, repoURL); err == nil {
+				t.Errorf("Expecting %s credentials not to be sent to %s", provider, repoURL)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		provider Provider
+		host     string
+	}{
+		{GCP, "storage.googleapis.com"},
+		{GCP, "europe-docker.pkg.dev"},
+		{GCP, "eu.gcr.io"},
+		{Azure, "myregistry.azurecr.io"},
+		{AWS, "123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+		{AWS, "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn"},
+	} {
+		if !matchesHost(DefaultHosts[tc.provider], tc.host) {
+			t.Errorf("Expecting %s credentials to be sent to %s", tc.provider, tc.host)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package cosign verifies the cosign signatures of OCI artifacts, signed
+// with keys or keyless with Fulcio certificates recorded in Rekor
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Media type and annotations of the layers of cosign signature manifests
+const (
+	SignatureMediaType    = "application/vnd.dev.cosign.simplesigning.v1+json"
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+	// payloadType is the critical.type of the signed payloads
+	payloadType = "cosign container image signature"
+)
+
+// OIDs of the extensions of Fulcio certificates holding the OIDC issuer
+var (
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// SignatureTag returns the tag of the signatures of the artifact with
+// digest, e.g. sha256-<hex>.sig
+func SignatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// Signature is a layer of a cosign signature manifest
+type Signature struct {
+	// Payload is the signed simple signing payload
+	Payload []byte
+	// Annotations hold the signature, and for keyless signatures the
+	// certificate, its chain and the Rekor bundle
+	Annotations map[string]string
+}
+
+// Identity matches the subject, e.g. an email or a CI workflow URI, and
+// OIDC issuer of the Fulcio certificates of keyless signatures
+type Identity struct {
+	Issuer  *regexp.Regexp
+	Subject *regexp.Regexp
+}
+
+// Verifier verifies signatures made with Keys, or keyless by one of
+// Identities with a certificate issued by FulcioRoots and recorded in the
+// Rekor log signing with RekorKey
+type Verifier struct {
+	Keys        map[string]crypto.PublicKey
+	Identities  []Identity
+	FulcioRoots *x509.CertPool
+	RekorKey    crypto.PublicKey
+}
+
+// VerificationError is returned for artifacts without a valid signature
+type VerificationError struct {
+	Digest  string
+	Reasons []string
+}
+
+func (e *VerificationError) Error() string {
+	if len(e.Reasons) == 0 {
+		return fmt.Sprintf("no cosign signature found for %s", e.Digest)
+	}
+	return fmt.Sprintf("no valid cosign signature for %s: %s", e.Digest, strings.Join(e.Reasons, "; "))
+}
+
+// Verify returns who signed the artifact with digest, among sigs: the
+// name of a key or the subject of a certificate. A *VerificationError is
+// returned unless one of sigs is valid.
+func (v *Verifier) Verify(digest string, sigs []Signature) (string, error) {
+	verr := &VerificationError{Digest: digest}
+	for i, sig := range sigs {
+		signer, err := v.verify(digest, sig)
+		if err == nil {
+			return signer, nil
+		}
+		verr.Reasons = append(verr.Reasons, fmt.Sprintf("signature %d: %v", i, err))
+	}
+	return "", verr
+}
+
+func (v *Verifier) verify(digest string, sig Signature) (string, error) {
+	if err := checkPayload(sig.Payload, digest); err != nil {
+		return "", err
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Annotations[signatureAnnotation])
+	if err != nil || len(signature) == 0 {
+		return "", fmt.Errorf("missing or invalid %s annotation", signatureAnnotation)
+	}
+
+	if certPEM := sig.Annotations[certificateAnnotation]; certPEM != "" {
+		return v.verifyKeyless(sig, signature, certPEM)
+	}
+	if len(v.Keys) == 0 {
+		return "", fmt.Errorf("signed with a key, no keys configured")
+	}
+	for name, key := range v.Keys {
+		if verifySignature(key, sig.Payload, signature) == nil {
+			return "key " + name, nil
+		}
+	}
+	return "", fmt.Errorf("not signed with any of the keys")
+}
+
+// checkPayload checks payload is a signature of the artifact with digest
+func checkPayload(payload []byte, digest string) error {
+	var p struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid payload: %v", err)
+	}
+	if p.Critical.Type != payloadType {
+		return fmt.Errorf("unexpected payload type %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signs %s", p.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+// verifyKeyless verifies a signature with a Fulcio certificate, valid
+// when the signature was recorded in Rekor
+func (v *Verifier) verifyKeyless(sig Signature, signature []byte, certPEM string) (string, error) {
+	if len(v.Identities) == 0 {
+		return "", fmt.Errorf("signed keyless, no identities configured")
+	}
+	if v.FulcioRoots == nil || v.RekorKey == nil {
+		return "", fmt.Errorf("signed keyless, no Fulcio roots or Rekor key configured")
+	}
+	certs, err := parseCertificates([]byte(certPEM))
+	if err != nil || len(certs) != 1 {
+		return "", fmt.Errorf("invalid %s annotation", certificateAnnotation)
+	}
+	cert := certs[0]
+	intermediates := x509.NewCertPool()
+	if chainPEM := sig.Annotations[chainAnnotation]; chainPEM != "" {
+		chain, err := parseCertificates([]byte(chainPEM))
+		if err != nil {
+			return "", fmt.Errorf("invalid %s annotation: %v", chainAnnotation, err)
+		}
+		for _, c := range chain {
+			intermediates.AddCert(c)
+		}
+	}
+
+	integratedTime, err := v.verifyBundle(sig, signature, certPEM)
+	if err != nil {
+		return "", err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.FulcioRoots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return "", fmt.Errorf("certificate not trusted: %v", err)
+	}
+	if err := verifySignature(cert.PublicKey, sig.Payload, signature); err != nil {
+		return "", err
+	}
+
+	issuer := certificateIssuer(cert)
+	for _, subject := range certificateSubjects(cert) {
+		for _, id := range v.Identities {
+			if id.Issuer.MatchString(issuer) && id.Subject.MatchString(subject) {
+				return subject, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("certificate of %v issued by %q matches none of the identities", certificateSubjects(cert), issuer)
+}
+
+// bundle is the Rekor bundle of a keyless signature: the log entry and
+// the timestamp signed by Rekor when it was recorded
+type bundle struct {
+	SignedEntryTimestamp string        `json:"SignedEntryTimestamp"`
+	Payload              bundlePayload `json:"Payload"`
+}
+
+// bundlePayload is signed by Rekor, with its keys sorted as in the
+// canonical JSON Rekor signs
+type bundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the Rekor entries of signatures
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle verifies the Rekor bundle of sig records signature of its
+// payload with the certificate, returning when it was recorded
+func (v *Verifier) verifyBundle(sig Signature, signature []byte, certPEM string) (time.Time, error) {
+	var b bundle
+	if err := json.Unmarshal([]byte(sig.Annotations[bundleAnnotation]), &b); err != nil {
+		return time.Time{}, fmt.Errorf("missing or invalid %s annotation", bundleAnnotation)
+	}
+	canonical, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	set, err := base64.StdEncoding.DecodeString(b.SignedEntryTimestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %v", err)
+	}
+	if err := verifySignature(v.RekorKey, canonical, set); err != nil {
+		return time.Time{}, fmt.Errorf("bundle not signed by Rekor: %v", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid bundle body: %v", err)
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil || entry.Kind != "hashedrekord" {
+		return time.Time{}, fmt.Errorf("unsupported Rekor entry")
+	}
+	sum := sha256.Sum256(sig.Payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(sum[:]) {
+		return time.Time{}, fmt.Errorf("Rekor entry records another payload")
+	}
+	if entry.Spec.Signature.Content != base64.StdEncoding.EncodeToString(signature) {
+		return time.Time{}, fmt.Errorf("Rekor entry records another signature")
+	}
+	entryCert, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || strings.TrimSpace(string(entryCert)) != strings.TrimSpace(certPEM) {
+		return time.Time{}, fmt.Errorf("Rekor entry records another certificate")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// verifySignature verifies signature of payload with key
+func verifySignature(key crypto.PublicKey, payload, signature []byte) error {
+	sum := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, sum[:], signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], signature) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, payload, signature) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+// certificateIssuer returns the OIDC issuer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuer):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// certificateSubjects returns the email and URI subject alternative
+// names of a Fulcio certificate
+func certificateSubjects(cert *x509.Certificate) []string {
+	subjects := append([]string{}, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+	return subjects
+}
+
+// ParsePublicKey parses a PEM encoded public key
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// ParseCertPool parses PEM encoded certificates
+func ParseCertPool(data []byte) (*x509.CertPool, error) {
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificates")
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
@@ -0,0 +1,203 @@
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const digest = "sha256:4c8a5e0e7e4c47d9e3e1a1d1c7c6b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7"
+
+func payload(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.example.com/charts/mariadb"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return sig
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return key
+}
+
+func TestVerifyKey(t *testing.T) {
+	key, other := newKey(t), newKey(t)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	v := &Verifier{Keys: map[string]crypto.PublicKey{"cosign.pub": pub}}
+
+	signed := Signature{Payload: payload(digest), Annotations: map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sign(t, key, payload(digest))),
+	}}
+	signer, err := v.Verify(digest, []Signature{signed})
+	if err != nil || signer != "key cosign.pub" {
+		t.Errorf("Expecting the signature to be verified, received %q %v", signer, err)
+	}
+
+	otherKey := Signature{Payload: payload(digest), Annotations: map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sign(t, other, payload(digest))),
+	}}
+	if _, err := v.Verify(digest, []Signature{otherKey, signed}); err != nil {
+		t.Errorf("Expecting any valid signature to verify the artifact, received %v", err)
+	}
+	otherDigest := "sha256:" + strings.Repeat("0", 64)
+	for _, sigs := range [][]Signature{nil, {otherKey}, {{Payload: payload(otherDigest), Annotations: signed.Annotations}}} {
+		if _, err := v.Verify(digest, sigs); err == nil {
+			t.Errorf("Expecting %v not to be valid", sigs)
+		} else if _, ok := err.(*VerificationError); !ok {
+			t.Errorf("Expecting a VerificationError, received %v", err)
+		}
+	}
+	if _, err := v.Verify(otherDigest, []Signature{signed}); err == nil || !strings.Contains(err.Error(), "signs "+digest) {
+		t.Errorf("Expecting the signature of another artifact to be rejected, received %v", err)
+	}
+}
+
+// fulcio is a fake Fulcio CA and Rekor log
+type fulcio struct {
+	t       *testing.T
+	root    *x509.Certificate
+	rootKey *ecdsa.PrivateKey
+	rekor   *ecdsa.PrivateKey
+}
+
+func newFulcio(t *testing.T) *fulcio {
+	rootKey := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	root, _ := x509.ParseCertificate(der)
+	return &fulcio{t: t, root: root, rootKey: rootKey, rekor: newKey(t)}
+}
+
+// sign signs payload keyless as email, with a certificate valid from
+// issued for 10 minutes, recorded in Rekor at integrated
+func (f *fulcio) sign(payload []byte, email string, issued, integrated time.Time) Signature {
+	key := newKey(f.t)
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       issued,
+		NotAfter:        issued.Add(10 * time.Minute),
+		EmailAddresses:  []string{email},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuer, Value: []byte("https://accounts.google.com")}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, f.root, &key.PublicKey, f.rootKey)
+	if err != nil {
+		f.t.Fatalf("Unexpected error %v", err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	signature := base64.StdEncoding.EncodeToString(sign(f.t, key, payload))
+
+	var entry hashedRekord
+	entry.Kind = "hashedrekord"
+	sum := sha256.Sum256(payload)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	entry.Spec.Signature.Content = signature
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString([]byte(certPEM))
+	body, _ := json.Marshal(entry)
+	b := bundle{Payload: bundlePayload{Body: base64.StdEncoding.EncodeToString(body), IntegratedTime: integrated.Unix(), LogID: "c0d23d6a", LogIndex: 42}}
+	canonical, _ := json.Marshal(b.Payload)
+	b.SignedEntryTimestamp = base64.StdEncoding.EncodeToString(sign(f.t, f.rekor, canonical))
+	bundleJSON, _ := json.Marshal(b)
+
+	return Signature{Payload: payload, Annotations: map[string]string{
+		signatureAnnotation:   signature,
+		certificateAnnotation: certPEM,
+		bundleAnnotation:      string(bundleJSON),
+	}}
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	f := newFulcio(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(f.root)
+	v := &Verifier{
+		Identities: []Identity{{
+			Issuer:  regexp.MustCompile(`^https://accounts\.google\.com$`),
+			Subject: regexp.MustCompile(`@example\.com$`),
+		}},
+		FulcioRoots: roots,
+		RekorKey:    &f.rekor.PublicKey,
+	}
+
+	// Fulcio certificates expire long before the signatures are verified
+	issued := time.Now().Add(-30 * time.Minute)
+	sig := f.sign(payload(digest), "release@example.com", issued, issued.Add(time.Minute))
+	if signer, err := v.Verify(digest, []Signature{sig}); err != nil || signer != "release@example.com" {
+		t.Errorf("Expecting the signature to be verified, received %q %v", signer, err)
+	}
+
+	for name, sig := range map[string]Signature{
+		"other identity":                  f.sign(payload(digest), "someone@example.org", issued, issued.Add(time.Minute)),
+		"recorded after the cert expired": f.sign(payload(digest), "release@example.com", issued, issued.Add(time.Hour)),
+	} {
+		if _, err := v.Verify(digest, []Signature{sig}); err == nil {
+			t.Errorf("%s: expecting the signature to be rejected", name)
+		}
+	}
+
+	tampered := f.sign(payload(digest), "release@example.com", issued, issued.Add(time.Minute))
+	var b bundle
+	json.Unmarshal([]byte(tampered.Annotations[bundleAnnotation]), &b)
+	b.Payload.IntegratedTime++
+	data, _ := json.Marshal(b)
+	tampered.Annotations[bundleAnnotation] = string(data)
+	if _, err := v.Verify(digest, []Signature{tampered}); err == nil || !strings.Contains(err.Error(), "not signed by Rekor") {
+		t.Errorf("Expecting a tampered bundle to be rejected, received %v", err)
+	}
+
+	untrusted := newFulcio(t).sign(payload(digest), "release@example.com", issued, issued.Add(time.Minute))
+	untrusted.Annotations[bundleAnnotation] = sig.Annotations[bundleAnnotation]
+	if _, err := v.Verify(digest, []Signature{untrusted}); err == nil {
+		t.Errorf("Expecting a certificate of another CA to be rejected")
+	}
+
+	if _, err := (&Verifier{Identities: v.Identities}).Verify(digest, []Signature{sig}); err == nil {
+		t.Errorf("Expecting keyless signatures not to be verified without Fulcio roots")
+	}
+}
+
+func TestSignatureTag(t *testing.T) {
+	if tag := SignatureTag(digest); tag != "sha256-"+strings.TrimPrefix(digest, "sha256:")+".sig" {
+		t.Errorf("Unexpected tag %s", tag)
+	}
+}
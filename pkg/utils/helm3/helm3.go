@@ -0,0 +1,305 @@
+// Package helm3 converts Helm 2 releases, as stored by Tiller, to the
+// Secrets Helm 3 stores releases in, like the helm-2to3 plugin does.
+package helm3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// SecretType is the type of the Secrets Helm 3 stores releases in
+const SecretType = "helm.sh/release.v1"
+
+// SecretName returns the name of the Secret storing a revision of a release
+func SecretName(rlsName string, version int32) string {
+	return fmt.Sprintf("sh.helm.release.v1.%s.v%d", rlsName, version)
+}
+
+// Secret returns the Helm 3 storage Secret of a Helm 2 release revision,
+// in the namespace of the release. Subcharts are not kept, as in Helm 3
+// release records.
+func Secret(rel *release.Release) (*corev1.Secret, error) {
+	r, err := convert(rel)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SecretName(r.Name, r.Version),
+			Namespace: r.Namespace,
+			Labels: map[string]string{
+				"name":    r.Name,
+				"owner":   "helm",
+				"status":  r.Info.Status,
+				"version": strconv.Itoa(int(r.Version)),
+			},
+		},
+		Type: SecretType,
+		// Helm 3 base64 encodes the data itself
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+	}, nil
+}
+
+// Decode returns the Helm 3 release stored in a Secret as JSON
+func Decode(secret *corev1.Secret) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(string(secret.Data["release"]))
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// helm3Release mirrors the JSON encoding of Helm 3 releases
+type helm3Release struct {
+	Name      string                 `json:"name,omitempty"`
+	Info      *helm3Info             `json:"info,omitempty"`
+	Chart     *helm3Chart            `json:"chart,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+	Manifest  string                 `json:"manifest,omitempty"`
+	Hooks     []*helm3Hook           `json:"hooks,omitempty"`
+	Version   int32                  `json:"version,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+type helm3Info struct {
+	FirstDeployed time.Time `json:"first_deployed"`
+	LastDeployed  time.Time `json:"last_deployed"`
+	Deleted       time.Time `json:"deleted"`
+	Description   string    `json:"description,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+}
+
+type helm3Chart struct {
+	Metadata  *helm3Metadata         `json:"metadata"`
+	Templates []*helm3File           `json:"templates"`
+	Values    map[string]interface{} `json:"values"`
+	Files     []*helm3File           `json:"files"`
+}
+
+type helm3Metadata struct {
+	Name        string             `json:"name,omitempty"`
+	Home        string             `json:"home,omitempty"`
+	Sources     []string           `json:"sources,omitempty"`
+	Version     string             `json:"version,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Keywords    []string           `json:"keywords,omitempty"`
+	Maintainers []*helm3Maintainer `json:"maintainers,omitempty"`
+	Icon        string             `json:"icon,omitempty"`
+	APIVersion  string             `json:"apiVersion,omitempty"`
+	Condition   string             `json:"condition,omitempty"`
+	Tags        string             `json:"tags,omitempty"`
+	AppVersion  string             `json:"appVersion,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	KubeVersion string             `json:"kubeVersion,omitempty"`
+}
+
+type helm3Maintainer struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+type helm3File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+type helm3Hook struct {
+	Name           string        `json:"name,omitempty"`
+	Kind           string        `json:"kind,omitempty"`
+	Path           string        `json:"path,omitempty"`
+	Manifest       string        `json:"manifest,omitempty"`
+	Events         []string      `json:"events,omitempty"`
+	LastRun        *helm3HookRun `json:"last_run"`
+	Weight         int32         `json:"weight,omitempty"`
+	DeletePolicies []string      `json:"delete_policies,omitempty"`
+}
+
+type helm3HookRun struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Phase       string    `json:"phase"`
+}
+
+// statuses maps Helm 2 release statuses to Helm 3 ones
+var statuses = map[release.Status_Code]string{
+	release.Status_UNKNOWN:          "unknown",
+	release.Status_DEPLOYED:         "deployed",
+	release.Status_DELETED:          "uninstalled",
+	release.Status_SUPERSEDED:       "superseded",
+	release.Status_FAILED:           "failed",
+	release.Status_DELETING:         "uninstalling",
+	release.Status_PENDING_INSTALL:  "pending-install",
+	release.Status_PENDING_UPGRADE:  "pending-upgrade",
+	release.Status_PENDING_ROLLBACK: "pending-rollback",
+}
+
+// deletePolicies maps Helm 2 hook delete policies to Helm 3 ones
+var deletePolicies = map[release.Hook_DeletePolicy]string{
+	release.Hook_SUCCEEDED:            "hook-succeeded",
+	release.Hook_FAILED:               "hook-failed",
+	release.Hook_BEFORE_HOOK_CREATION: "before-hook-creation",
+}
+
+func convert(rel *release.Release) (*helm3Release, error) {
+	status, ok := statuses[rel.GetInfo().GetStatus().GetCode()]
+	if !ok {
+		return nil, fmt.Errorf("unknown status %s", rel.GetInfo().GetStatus().GetCode())
+	}
+	config, err := parseValues(rel.GetConfig().GetRaw())
+	if err != nil {
+		return nil, fmt.Errorf("invalid release values: %v", err)
+	}
+	ch, err := convertChart(rel.GetChart())
+	if err != nil {
+		return nil, err
+	}
+	r := &helm3Release{
+		Name: rel.GetName(),
+		Info: &helm3Info{
+			FirstDeployed: toTime(rel.GetInfo().GetFirstDeployed()),
+			LastDeployed:  toTime(rel.GetInfo().GetLastDeployed()),
+			Deleted:       toTime(rel.GetInfo().GetDeleted()),
+			Description:   rel.GetInfo().GetDescription(),
+			Status:        status,
+			Notes:         rel.GetInfo().GetStatus().GetNotes(),
+		},
+		Chart:     ch,
+		Config:    config,
+		Manifest:  rel.GetManifest(),
+		Version:   rel.GetVersion(),
+		Namespace: rel.GetNamespace(),
+	}
+	for _, hook := range rel.GetHooks() {
+		h := &helm3Hook{
+			Name:     hook.GetName(),
+			Kind:     hook.GetKind(),
+			Path:     hook.GetPath(),
+			Manifest: hook.GetManifest(),
+			Weight:   hook.GetWeight(),
+		}
+		for _, e := range hook.GetEvents() {
+			h.Events = append(h.Events, hookEvent(e))
+		}
+		for _, p := range hook.GetDeletePolicies() {
+			if policy, ok := deletePolicies[p]; ok {
+				h.DeletePolicies = append(h.DeletePolicies, policy)
+			}
+		}
+		if run := hook.GetLastRun(); run != nil {
+			// Helm 2 only records when hooks started
+			h.LastRun = &helm3HookRun{StartedAt: toTime(run), CompletedAt: toTime(run), Phase: "Unknown"}
+		}
+		r.Hooks = append(r.Hooks, h)
+	}
+	return r, nil
+}
+
+// hookEvent returns the Helm 3 name of a hook event, e.g. pre-install.
+// Both test events become test, the only test event of Helm 3.
+func hookEvent(e release.Hook_Event) string {
+	switch e {
+	case release.Hook_RELEASE_TEST_SUCCESS, release.Hook_RELEASE_TEST_FAILURE:
+		return "test"
+	}
+	return strings.Replace(strings.ToLower(e.String()), "_", "-", -1)
+}
+
+func convertChart(ch *chart.Chart) (*helm3Chart, error) {
+	if ch == nil {
+		return nil, nil
+	}
+	values, err := parseValues(ch.GetValues().GetRaw())
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart values: %v", err)
+	}
+	md := ch.GetMetadata()
+	c := &helm3Chart{
+		Metadata: &helm3Metadata{
+			Name:        md.GetName(),
+			Home:        md.GetHome(),
+			Sources:     md.GetSources(),
+			Version:     md.GetVersion(),
+			Description: md.GetDescription(),
+			Keywords:    md.GetKeywords(),
+			Icon:        md.GetIcon(),
+			APIVersion:  md.GetApiVersion(),
+			Condition:   md.GetCondition(),
+			Tags:        md.GetTags(),
+			AppVersion:  md.GetAppVersion(),
+			Deprecated:  md.GetDeprecated(),
+			Annotations: md.GetAnnotations(),
+			KubeVersion: md.GetKubeVersion(),
+		},
+		Templates: []*helm3File{},
+		Values:    values,
+		Files:     []*helm3File{},
+	}
+	if c.Metadata.APIVersion == "" {
+		// Helm 2 charts are v1 charts
+		c.Metadata.APIVersion = "v1"
+	}
+	for _, m := range md.GetMaintainers() {
+		c.Metadata.Maintainers = append(c.Metadata.Maintainers, &helm3Maintainer{Name: m.GetName(), Email: m.GetEmail(), URL: m.GetUrl()})
+	}
+	for _, t := range ch.GetTemplates() {
+		c.Templates = append(c.Templates, &helm3File{Name: t.GetName(), Data: t.GetData()})
+	}
+	for _, f := range ch.GetFiles() {
+		c.Files = append(c.Files, &helm3File{Name: f.GetTypeUrl(), Data: f.GetValue()})
+	}
+	return c, nil
+}
+
+func parseValues(raw string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func toTime(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+}
@@ -0,0 +1,102 @@
+package helm3
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+func TestSecret(t *testing.T) {
+	rel := &release.Release{
+		Name:      "myns-foo",
+		Namespace: "myns",
+		Version:   3,
+		Info: &release.Info{
+			Status:        &release.Status{Code: release.Status_SUPERSEDED, Notes: "Thanks"},
+			FirstDeployed: &timestamp.Timestamp{Seconds: 1500000000},
+			LastDeployed:  &timestamp.Timestamp{Seconds: 1600000000},
+			Description:   "Upgrade complete",
+		},
+		Chart: &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "foo", Version: "1.2.3"},
+			Templates: []*chart.Template{{Name: "templates/cm.yaml", Data: []byte("kind: ConfigMap")}},
+			Values:    &chart.Config{Raw: "replicas: 1\n"},
+		},
+		Config:   &chart.Config{Raw: "replicas: 2\n"},
+		Manifest: "---\nkind: ConfigMap\n",
+		Hooks: []*release.Hook{{
+			Name:           "foo-test",
+			Kind:           "Pod",
+			Events:         []release.Hook_Event{release.Hook_PRE_UPGRADE, release.Hook_RELEASE_TEST_SUCCESS},
+			DeletePolicies: []release.Hook_DeletePolicy{release.Hook_BEFORE_HOOK_CREATION},
+		}},
+	}
+
+	secret, err := Secret(rel)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if secret.Name != "sh.helm.release.v1.myns-foo.v3" || secret.Namespace != "myns" || secret.Type != SecretType {
+		t.Errorf("Unexpected secret %s/%s of type %s", secret.Namespace, secret.Name, secret.Type)
+	}
+	expectedLabels := map[string]string{"name": "myns-foo", "owner": "helm", "status": "superseded", "version": "3"}
+	if !reflect.DeepEqual(secret.Labels, expectedLabels) {
+		t.Errorf("Expecting labels %v, received %v", expectedLabels, secret.Labels)
+	}
+
+	data, err := Decode(secret)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var r struct {
+		Name string `json:"name"`
+		Info struct {
+			LastDeployed string `json:"last_deployed"`
+			Status       string `json:"status"`
+			Notes        string `json:"notes"`
+		} `json:"info"`
+		Chart struct {
+			Metadata struct {
+				Name       string `json:"name"`
+				APIVersion string `json:"apiVersion"`
+			} `json:"metadata"`
+			Templates []struct {
+				Name string `json:"name"`
+				Data []byte `json:"data"`
+			} `json:"templates"`
+			Values map[string]interface{} `json:"values"`
+		} `json:"chart"`
+		Config map[string]interface{} `json:"config"`
+		Hooks  []struct {
+			Events         []string `json:"events"`
+			DeletePolicies []string `json:"delete_policies"`
+		} `json:"hooks"`
+		Version int32 `json:"version"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if r.Name != "myns-foo" || r.Version != 3 || r.Info.Status != "superseded" || r.Info.Notes != "Thanks" {
+		t.Errorf("Unexpected release %s", data)
+	}
+	if r.Info.LastDeployed != "2020-09-13T12:26:40Z" {
+		t.Errorf("Unexpected last deployed time %s", r.Info.LastDeployed)
+	}
+	if r.Chart.Metadata.Name != "foo" || r.Chart.Metadata.APIVersion != "v1" {
+		t.Errorf("Unexpected chart metadata %+v", r.Chart.Metadata)
+	}
+	if len(r.Chart.Templates) != 1 || string(r.Chart.Templates[0].Data) != "kind: ConfigMap" {
+		t.Errorf("Unexpected templates %+v", r.Chart.Templates)
+	}
+	if r.Chart.Values["replicas"] != 1.0 || r.Config["replicas"] != 2.0 {
+		t.Errorf("Unexpected values %v and config %v", r.Chart.Values, r.Config)
+	}
+	if len(r.Hooks) != 1 || !reflect.DeepEqual(r.Hooks[0].Events, []string{"pre-upgrade", "test"}) ||
+		!reflect.DeepEqual(r.Hooks[0].DeletePolicies, []string{"before-hook-creation"}) {
+		t.Errorf("Unexpected hooks %+v", r.Hooks)
+	}
+}
@@ -0,0 +1,492 @@
+package helmclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/render"
+)
+
+const (
+	// FieldManager is the field manager of the objects applied by the
+	// apply executor
+	FieldManager = "helm-crd"
+	// ReleaseLabel is set on the objects applied by the apply executor to
+	// the name of their release
+	ReleaseLabel = "helm.bitnami.com/release"
+
+	// The revisions are stored as Tiller's secret storage driver does
+	storageOwner = "TILLER"
+	releaseKey   = "release"
+)
+
+// ObjectApplier applies and deletes the objects of rendered manifests. The
+// namespace is used for namespaced objects not setting one in the manifest.
+type ObjectApplier interface {
+	// ServerSideApply applies obj as fieldManager, taking over the fields
+	// owned by other managers
+	ServerSideApply(obj manifest.Object, namespace, fieldManager string) error
+	// Delete deletes obj, if it exists
+	Delete(obj manifest.Object, namespace string) error
+}
+
+// kindOrder is the order objects are applied in, from Tiller. Other kinds
+// are applied last and objects are deleted in the reverse order.
+var kindOrder = []string{
+	"Namespace", "ResourceQuota", "LimitRange", "PodSecurityPolicy", "Secret",
+	"ConfigMap", "StorageClass", "PersistentVolume", "PersistentVolumeClaim",
+	"ServiceAccount", "CustomResourceDefinition", "ClusterRole", "ClusterRoleBinding",
+	"Role", "RoleBinding", "Service", "DaemonSet", "Pod", "ReplicationController",
+	"ReplicaSet", "Deployment", "StatefulSet", "Job", "CronJob", "Ingress", "APIService",
+}
+
+type applyClient struct {
+	kubeClient       kubernetes.Interface
+	objects          ObjectApplier
+	storageNamespace string
+	now              func() time.Time
+}
+
+// NewApplyClient returns a client managing releases without Tiller: charts
+// are rendered locally and their objects applied with server-side apply,
+// labeled with their release. Revisions are stored in Secrets of
+// storageNamespace, in the format of Tiller's secret storage driver.
+// Hooks are not run: installing, upgrading or rolling back to a chart with
+// hooks fails with a HooksNotSupportedError, unless hooks are disabled.
+func NewApplyClient(kubeClient kubernetes.Interface, objects ObjectApplier, storageNamespace string) Interface {
+	return &applyClient{
+		kubeClient:       kubeClient,
+		objects:          objects,
+		storageNamespace: storageNamespace,
+		now:              time.Now,
+	}
+}
+
+func (c *applyClient) Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error) {
+	history, err := c.history(opts.ReleaseName)
+	if err != nil {
+		return nil, err
+	}
+	version := int32(1)
+	if len(history) > 0 {
+		if history[0].GetInfo().GetStatus().GetCode() != release.Status_DELETED {
+			return nil, fmt.Errorf("cannot re-use a name that is still in use")
+		}
+		version = history[0].Version + 1
+	}
+	rel, err := c.render(opts.ReleaseName, namespace, version, ch, opts.Values, true, opts.Capabilities)
+	if err != nil || opts.DryRun {
+		return rel, err
+	}
+	if err := checkHooks(rel, opts.DisableHooks); err != nil {
+		return nil, err
+	}
+	rel.Info.Description = "Install complete"
+	return rel, c.deploy(rel, nil)
+}
+
+func (c *applyClient) Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error) {
+	history, err := c.history(rlsName)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 || history[0].GetInfo().GetStatus().GetCode() == release.Status_DELETED {
+		return nil, notFoundError(rlsName)
+	}
+	prev := history[0]
+	rel, err := c.render(rlsName, prev.Namespace, prev.Version+1, ch, opts.Values, false, opts.Capabilities)
+	if err != nil || opts.DryRun {
+		return rel, err
+	}
+	if err := checkHooks(rel, opts.DisableHooks); err != nil {
+		return nil, err
+	}
+	rel.Info.FirstDeployed = prev.GetInfo().GetFirstDeployed()
+	rel.Info.Description = "Upgrade complete"
+	return rel, c.deploy(rel, prev)
+}
+
+func (c *applyClient) Rollback(rlsName string, opts RollbackOptions) (*release.Release, error) {
+	history, err := c.history(rlsName)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, notFoundError(rlsName)
+	}
+	prev := history[0]
+	version := opts.Version
+	if version == 0 {
+		version = prev.Version - 1
+	}
+	var target *release.Release
+	for _, rel := range history {
+		if rel.Version == version {
+			target = rel
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("release: %q revision %d not found", rlsName, version)
+	}
+	if err := checkHooks(target, opts.DisableHooks); err != nil {
+		return nil, err
+	}
+
+	now, err := ptypes.TimestampProto(c.now())
+	if err != nil {
+		return nil, err
+	}
+	rel := &release.Release{
+		Name:      rlsName,
+		Namespace: target.Namespace,
+		Version:   prev.Version + 1,
+		Chart:     target.Chart,
+		Config:    target.Config,
+		Manifest:  target.Manifest,
+		Hooks:     target.Hooks,
+		Info: &release.Info{
+			FirstDeployed: prev.GetInfo().GetFirstDeployed(),
+			LastDeployed:  now,
+			Status:        &release.Status{Code: release.Status_DEPLOYED, Notes: target.GetInfo().GetStatus().GetNotes()},
+			Description:   fmt.Sprintf("Rollback to %d", version),
+		},
+	}
+	return rel, c.deploy(rel, prev)
+}
+
+func (c *applyClient) Delete(rlsName string, opts DeleteOptions) error {
+	history, err := c.history(rlsName)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return notFoundError(rlsName)
+	}
+	latest := history[0]
+	if latest.GetInfo().GetStatus().GetCode() != release.Status_DELETED {
+		objs, err := manifest.Objects(latest.Manifest)
+		if err != nil {
+			return err
+		}
+		sortByKind(objs)
+		for i := len(objs) - 1; i >= 0; i-- {
+			if err := c.objects.Delete(objs[i], latest.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.Purge {
+		for _, rel := range history {
+			err := c.kubeClient.Core().Secrets(c.storageNamespace).Delete(storageName(rel), &metav1.DeleteOptions{})
+			if err != nil && !k8sErrors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	now, err := ptypes.TimestampProto(c.now())
+	if err != nil {
+		return err
+	}
+	latest.Info.Status.Code = release.Status_DELETED
+	latest.Info.Deleted = now
+	latest.Info.Description = "Deletion complete"
+	return c.store(latest, false)
+}
+
+func (c *applyClient) History(rlsName string, max int32) ([]*release.Release, error) {
+	history, err := c.history(rlsName)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, notFoundError(rlsName)
+	}
+	if max > 0 && int32(len(history)) > max {
+		history = history[:max]
+	}
+	return history, nil
+}
+
+func (c *applyClient) Status(rlsName string) (*release.Status, error) {
+	history, err := c.history(rlsName)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, notFoundError(rlsName)
+	}
+	return history[0].GetInfo().GetStatus(), nil
+}
+
+// HooksNotSupportedError is returned by the apply client deploying a
+// release with hooks that aren't disabled, which it can't run
+type HooksNotSupportedError struct {
+	// Hooks are the kinds and names of the hooks
+	Hooks []string
+}
+
+func (e *HooksNotSupportedError) Error() string {
+	return fmt.Sprintf("hooks are not run without Tiller, the chart has hooks %s", strings.Join(e.Hooks, ", "))
+}
+
+// IsHooksNotSupported returns whether err is a HooksNotSupportedError
+func IsHooksNotSupported(err error) bool {
+	_, ok := err.(*HooksNotSupportedError)
+	return ok
+}
+
+// checkHooks returns a HooksNotSupportedError if rel has hooks run on
+// install, upgrade, rollback or deletion and they aren't disabled. Test
+// hooks are only run by Test.
+func checkHooks(rel *release.Release, disabled bool) error {
+	if disabled {
+		return nil
+	}
+	var hooks []string
+	for _, h := range rel.Hooks {
+		for _, e := range h.Events {
+			if e != release.Hook_RELEASE_TEST_SUCCESS && e != release.Hook_RELEASE_TEST_FAILURE {
+				hooks = append(hooks, fmt.Sprintf("%s/%s", h.Kind, h.Name))
+				break
+			}
+		}
+	}
+	if len(hooks) > 0 {
+		return &HooksNotSupportedError{Hooks: hooks}
+	}
+	return nil
+}
+
+func (c *applyClient) Test(rlsName string, opts TestOptions) error {
+	return fmt.Errorf("chart tests are only supported with Tiller")
+}
+
+// render renders a revision of a release for a cluster of caps, deployed
+// once applied
+func (c *applyClient) render(rlsName, namespace string, version int32, ch *chart.Chart, values []byte, isInstall bool, caps *chartutil.Capabilities) (*release.Release, error) {
+	now, err := ptypes.TimestampProto(c.now())
+	if err != nil {
+		return nil, err
+	}
+	config := &chart.Config{Raw: string(values)}
+	res, err := render.Render(ch, config, chartutil.ReleaseOptions{
+		Name:      rlsName,
+		Namespace: namespace,
+		Time:      now,
+		Revision:  int(version),
+		IsInstall: isInstall,
+		IsUpgrade: !isInstall,
+	}, caps)
+	if err != nil {
+		return nil, err
+	}
+	return &release.Release{
+		Name:      rlsName,
+		Namespace: namespace,
+		Version:   version,
+		Chart:     ch,
+		Config:    config,
+		Manifest:  res.Manifest,
+		Hooks:     res.Hooks,
+		Info: &release.Info{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Status:        &release.Status{Code: release.Status_DEPLOYED, Notes: res.Notes},
+		},
+	}, nil
+}
+
+// deploy applies the objects of rel and deletes those of prev it no
+// longer has, then stores rel as the deployed revision. Failed revisions
+// are stored too, so that the next one is numbered after them.
+func (c *applyClient) deploy(rel, prev *release.Release) error {
+	if err := c.apply(rel, prev); err != nil {
+		rel.Info.Status.Code = release.Status_FAILED
+		rel.Info.Description = err.Error()
+		if storeErr := c.store(rel, true); storeErr != nil {
+			return fmt.Errorf("%v, and storing the failed revision: %v", err, storeErr)
+		}
+		return err
+	}
+	if err := c.store(rel, true); err != nil {
+		return err
+	}
+	if prev != nil && prev.GetInfo().GetStatus().GetCode() == release.Status_DEPLOYED {
+		prev.Info.Status.Code = release.Status_SUPERSEDED
+		return c.store(prev, false)
+	}
+	return nil
+}
+
+func (c *applyClient) apply(rel, prev *release.Release) error {
+	objs, err := manifest.Objects(rel.Manifest)
+	if err != nil {
+		return err
+	}
+	sortByKind(objs)
+	for _, obj := range objs {
+		setReleaseLabel(obj, rel.Name)
+		if err := c.objects.ServerSideApply(obj, rel.Namespace, FieldManager); err != nil {
+			return fmt.Errorf("unable to apply %s %s: %v", obj.Kind, obj.Name, err)
+		}
+	}
+	if prev == nil {
+		return nil
+	}
+	_, _, removed, err := manifest.Changes(prev.Manifest, rel.Manifest)
+	if err != nil {
+		return err
+	}
+	sortByKind(removed)
+	for i := len(removed) - 1; i >= 0; i-- {
+		if err := c.objects.Delete(removed[i], prev.Namespace); err != nil {
+			return fmt.Errorf("unable to delete %s %s: %v", removed[i].Kind, removed[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func setReleaseLabel(obj manifest.Object, rlsName string) {
+	metadata, ok := obj.Content["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		obj.Content["metadata"] = metadata
+	}
+	labels, ok := metadata["labels"].(map[string]interface{})
+	if !ok {
+		labels = map[string]interface{}{}
+		metadata["labels"] = labels
+	}
+	labels[ReleaseLabel] = rlsName
+}
+
+func sortByKind(objs []manifest.Object) {
+	rank := func(kind string) int {
+		for i, k := range kindOrder {
+			if k == kind {
+				return i
+			}
+		}
+		return len(kindOrder)
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return rank(objs[i].Kind) < rank(objs[j].Kind)
+	})
+}
+
+func storageName(rel *release.Release) string {
+	return fmt.Sprintf("%s.v%d", rel.Name, rel.Version)
+}
+
+// store writes a revision to its Secret, creating it if create is set
+func (c *applyClient) store(rel *release.Release, create bool) error {
+	data, err := encodeRelease(rel)
+	if err != nil {
+		return err
+	}
+	secrets := c.kubeClient.Core().Secrets(c.storageNamespace)
+	labels := map[string]string{
+		"OWNER":   storageOwner,
+		"NAME":    rel.Name,
+		"VERSION": strconv.Itoa(int(rel.Version)),
+		"STATUS":  rel.GetInfo().GetStatus().GetCode().String(),
+	}
+	if create {
+		_, err = secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: storageName(rel), Labels: labels},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{releaseKey: data},
+		})
+		return err
+	}
+	secret, err := secrets.Get(storageName(rel), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	// Keep the labels set by others, such as the HelmRelease owning it
+	for k, v := range labels {
+		secret.Labels[k] = v
+	}
+	secret.Data = map[string][]byte{releaseKey: data}
+	_, err = secrets.Update(secret)
+	return err
+}
+
+// history returns the stored revisions of a release, latest first
+func (c *applyClient) history(rlsName string) ([]*release.Release, error) {
+	secrets, err := c.kubeClient.Core().Secrets(c.storageNamespace).List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("OWNER=%s,NAME=%s", storageOwner, rlsName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*release.Release, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		rel, err := decodeRelease(secret.Data[releaseKey])
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %v", secret.Name, err)
+		}
+		history = append(history, rel)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Version > history[j].Version
+	})
+	return history, nil
+}
+
+func encodeRelease(rel *release.Release) ([]byte, error) {
+	data, err := proto.Marshal(rel)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+func decodeRelease(data []byte) (*release.Release, error) {
+	gz, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rel := &release.Release{}
+	if err := proto.Unmarshal(raw, rel); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
@@ -0,0 +1,196 @@
+package helmclient
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+type fakeApplier struct {
+	applied []string
+	deleted []string
+	labels  map[string]interface{}
+}
+
+func (f *fakeApplier) ServerSideApply(obj manifest.Object, namespace, fieldManager string) error {
+	f.applied = append(f.applied, fmt.Sprintf("%s/%s/%s", namespace, obj.Kind, obj.Name))
+	f.labels = obj.Content["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+	return nil
+}
+
+func (f *fakeApplier) Delete(obj manifest.Object, namespace string) error {
+	f.deleted = append(f.deleted, fmt.Sprintf("%s/%s/%s", namespace, obj.Kind, obj.Name))
+	return nil
+}
+
+func applyChart(templates ...string) *chart.Chart {
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"}}
+	for i, tpl := range templates {
+		ch.Templates = append(ch.Templates, &chart.Template{Name: fmt.Sprintf("templates/%d.yaml", i), Data: []byte(tpl)})
+	}
+	return ch
+}
+
+const (
+	deploymentTemplate = "apiVersion: apps/v1beta2\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n"
+	configMapTemplate  = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  key: {{ .Values.key }}\n"
+)
+
+func TestApplyClient(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	objects := &fakeApplier{}
+	c := NewApplyClient(kubeClient, objects, "kube-system")
+
+	ch := applyChart(deploymentTemplate, configMapTemplate)
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo", DryRun: true}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(objects.applied) != 0 {
+		t.Errorf("Expecting dry-run installs not to apply objects, received %v", objects.applied)
+	}
+	rel, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo", Values: []byte("key: a")})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Version != 1 || rel.Info.Status.Code != release.Status_DEPLOYED {
+		t.Errorf("Unexpected release %+v", rel)
+	}
+	// ConfigMaps are applied before Deployments
+	if expected := []string{"myns/ConfigMap/foo", "myns/Deployment/foo"}; !reflect.DeepEqual(objects.applied, expected) {
+		t.Errorf("Expecting %v to be applied, received %v", expected, objects.applied)
+	}
+	if objects.labels[ReleaseLabel] != "foo" {
+		t.Errorf("Expecting applied objects to be labeled with their release, received %v", objects.labels)
+	}
+	secret, err := kubeClient.Core().Secrets("kube-system").Get("foo.v1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expecting the revision to be stored, received %v", err)
+	}
+	if secret.Labels["OWNER"] != "TILLER" || secret.Labels["STATUS"] != "DEPLOYED" {
+		t.Errorf("Unexpected storage labels %v", secret.Labels)
+	}
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo"}); err == nil {
+		t.Errorf("Expecting an error installing a deployed release")
+	}
+
+	// Removing the ConfigMap from the chart deletes it
+	objects.applied = nil
+	rel, err = c.Upgrade("foo", applyChart(deploymentTemplate), UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Version != 2 || !reflect.DeepEqual(objects.applied, []string{"myns/Deployment/foo"}) {
+		t.Errorf("Unexpected upgrade %+v applying %v", rel, objects.applied)
+	}
+	if !reflect.DeepEqual(objects.deleted, []string{"myns/ConfigMap/foo"}) {
+		t.Errorf("Expecting the removed ConfigMap to be deleted, received %v", objects.deleted)
+	}
+	if _, err := c.Upgrade("missing", ch, UpgradeOptions{}); !IsNotFound(err) {
+		t.Errorf("Expecting a not found error upgrading a missing release, received %v", err)
+	}
+
+	// Rolling back to revision 1 applies its manifest again
+	objects.applied = nil
+	rel, err = c.Rollback("foo", RollbackOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Version != 3 || rel.Config.Raw != "key: a" || len(objects.applied) != 2 {
+		t.Errorf("Expecting revision 3 with the values of revision 1, received %+v applying %v", rel, objects.applied)
+	}
+
+	history, err := c.History("foo", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Fatalf("Expecting the 2 latest revisions, received %v", history)
+	}
+	if history[1].Info.Status.Code != release.Status_SUPERSEDED {
+		t.Errorf("Expecting previous revisions to be superseded, received %s", history[1].Info.Status.Code)
+	}
+
+	objects.deleted = nil
+	if err := c.Delete("foo", DeleteOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expected := []string{"myns/Deployment/foo", "myns/ConfigMap/foo"}; !reflect.DeepEqual(objects.deleted, expected) {
+		t.Errorf("Expecting %v to be deleted, received %v", expected, objects.deleted)
+	}
+	status, err := c.Status("foo")
+	if err != nil || status.Code != release.Status_DELETED {
+		t.Errorf("Expecting a deleted release, received %v, %v", status, err)
+	}
+	if rel, err = c.Install(ch, "myns", InstallOptions{ReleaseName: "foo"}); err != nil || rel.Version != 4 {
+		t.Errorf("Expecting a deleted release to be installed again as revision 4, received %v, %v", rel, err)
+	}
+
+	if err := c.Delete("foo", DeleteOptions{Purge: true}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.History("foo", 0); !IsNotFound(err) {
+		t.Errorf("Expecting a purged release to be forgotten, received %v", err)
+	}
+}
+
+func TestApplyClientRenderError(t *testing.T) {
+	c := NewApplyClient(fake.NewSimpleClientset(), &fakeApplier{}, "kube-system")
+	ch := applyChart(`{{ required "key is required" .Values.key }}`)
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo"}); err == nil {
+		t.Errorf("Expecting a render error")
+	}
+	if _, err := c.Status("foo"); !IsNotFound(err) {
+		t.Errorf("Expecting releases failing to render not to be stored, received %v", err)
+	}
+}
+
+func TestApplyClientHooks(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	objects := &fakeApplier{}
+	c := NewApplyClient(kubeClient, objects, "kube-system")
+	hook := "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  annotations:\n    helm.sh/hook: pre-upgrade\n"
+	test := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\n  annotations:\n    helm.sh/hook: test-success\n"
+
+	// Test hooks are only run by Test
+	if _, err := c.Install(applyChart(configMapTemplate, test), "myns", InstallOptions{ReleaseName: "foo", Values: []byte("key: a")}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	ch := applyChart(configMapTemplate, hook)
+	if _, err := c.Upgrade("foo", ch, UpgradeOptions{Values: []byte("key: b"), DryRun: true}); err != nil {
+		t.Errorf("Expecting dry-run upgrades to render hooks, received %v", err)
+	}
+	objects.applied = nil
+	_, err := c.Upgrade("foo", ch, UpgradeOptions{Values: []byte("key: b")})
+	if !IsHooksNotSupported(err) || !strings.Contains(err.Error(), "Job/migrate") {
+		t.Errorf("Expecting a hooks not supported error, received %v", err)
+	}
+	if len(objects.applied) != 0 {
+		t.Errorf("Expecting no object to be applied, received %v", objects.applied)
+	}
+	if history, _ := c.History("foo", 0); len(history) != 1 {
+		t.Errorf("Expecting the rejected upgrade not to be stored, received %d revisions", len(history))
+	}
+
+	// Unless hooks are disabled
+	if _, err := c.Upgrade("foo", ch, UpgradeOptions{Values: []byte("key: b"), DisableHooks: true}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "bar", Values: []byte("key: a")}); !IsHooksNotSupported(err) {
+		t.Errorf("Expecting a hooks not supported error, received %v", err)
+	}
+	if _, err := c.Upgrade("foo", applyChart(configMapTemplate), UpgradeOptions{Values: []byte("key: c")}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.Rollback("foo", RollbackOptions{}); !IsHooksNotSupported(err) {
+		t.Errorf("Expecting rolling back to a revision with hooks to fail, received %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package helmclient
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	rls "k8s.io/helm/pkg/proto/hapi/services"
+)
+
+// maxMsgSize is the size of the largest message received from Tiller, as
+// allowed by the helm client
+const maxMsgSize = 20 << 20
+
+// TillerOptions configure the connection to Tiller
+type TillerOptions struct {
+	// Keepalive is the interval of the pings keeping the connection open
+	// through proxies and detecting dead connections
+	Keepalive time.Duration
+	// BackoffMaxDelay is the maximum delay between reconnection attempts
+	BackoffMaxDelay time.Duration
+	// HealthTimeout is the maximum duration of a health check
+	HealthTimeout time.Duration
+}
+
+// HealthChecker is implemented by the clients of backends that can be
+// unreachable
+type HealthChecker interface {
+	// Healthy returns a *UnavailableError if the backend can't be reached
+	Healthy() error
+}
+
+// UnavailableError is returned when Tiller can't be reached, which is
+// worth retrying regardless of the operation
+type UnavailableError struct {
+	Host string
+	Err  error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("tiller at %s is unavailable: %v", e.Host, e.Err)
+}
+
+// IsUnavailable returns whether err is due to Tiller being unreachable
+func IsUnavailable(err error) bool {
+	_, ok := err.(*UnavailableError)
+	return ok
+}
+
+type connClient struct {
+	host   string
+	opts   TillerOptions
+	conn   *grpc.ClientConn
+	client rls.ReleaseServiceClient
+}
+
+// DialTiller returns a client managing releases with the Tiller at
+// host:port over a single connection, reconnecting in the background with
+// an exponential backoff when it is lost. Unlike the helm client, which
+// dials Tiller for every call, the connection is kept open with keepalive
+// pings. The returned client is also a HealthChecker.
+func DialTiller(host string, opts TillerOptions) (Interface, error) {
+	conn, err := grpc.Dial(host,
+		grpc.WithInsecure(),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: opts.Keepalive}),
+		grpc.WithBackoffMaxDelay(opts.BackoffMaxDelay),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &connClient{
+		host:   host,
+		opts:   opts,
+		conn:   conn,
+		client: rls.NewReleaseServiceClient(conn),
+	}, nil
+}
+
+// context returns the context of calls, carrying the client version
+// Tiller checks for compatibility
+func (c *connClient) context() context.Context {
+	return helm.NewContext()
+}
+
+// wrap returns err as an *UnavailableError if Tiller couldn't be reached
+func (c *connClient) wrap(err error) error {
+	if err != nil && grpc.Code(err) == codes.Unavailable {
+		return &UnavailableError{Host: c.host, Err: err}
+	}
+	return err
+}
+
+func (c *connClient) Healthy() error {
+	if state := c.conn.GetState(); state == connectivity.TransientFailure || state == connectivity.Shutdown {
+		return &UnavailableError{Host: c.host, Err: fmt.Errorf("connection is in state %s", state)}
+	}
+	ctx, cancel := context.WithTimeout(c.context(), c.opts.HealthTimeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "Tiller"})
+	if err != nil {
+		return &UnavailableError{Host: c.host, Err: err}
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return &UnavailableError{Host: c.host, Err: fmt.Errorf("health check returned %s", resp.GetStatus())}
+	}
+	return nil
+}
+
+func (c *connClient) Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error) {
+	req := &rls.InstallReleaseRequest{
+		Chart:        ch,
+		Values:       &chart.Config{Raw: string(opts.Values)},
+		Namespace:    namespace,
+		Name:         opts.ReleaseName,
+		DryRun:       opts.DryRun,
+		DisableHooks: opts.DisableHooks,
+		Timeout:      opts.Timeout,
+	}
+	if err := processRequirements(req.Chart, req.Values); err != nil {
+		return nil, err
+	}
+	res, err := c.client.InstallRelease(c.context(), req)
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *connClient) Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error) {
+	req := &rls.UpdateReleaseRequest{
+		Name:         rlsName,
+		Chart:        ch,
+		Values:       &chart.Config{Raw: string(opts.Values)},
+		DryRun:       opts.DryRun,
+		DisableHooks: opts.DisableHooks,
+		Timeout:      opts.Timeout,
+	}
+	if err := processRequirements(req.Chart, req.Values); err != nil {
+		return nil, err
+	}
+	res, err := c.client.UpdateRelease(c.context(), req)
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *connClient) Rollback(rlsName string, opts RollbackOptions) (*release.Release, error) {
+	res, err := c.client.RollbackRelease(c.context(), &rls.RollbackReleaseRequest{
+		Name:         rlsName,
+		Version:      opts.Version,
+		Recreate:     opts.Recreate,
+		Force:        opts.Force,
+		DisableHooks: opts.DisableHooks,
+	})
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *connClient) Delete(rlsName string, opts DeleteOptions) error {
+	_, err := c.client.UninstallRelease(c.context(), &rls.UninstallReleaseRequest{
+		Name:         rlsName,
+		Purge:        opts.Purge,
+		DisableHooks: opts.DisableHooks,
+		Timeout:      opts.Timeout,
+	})
+	return c.wrap(err)
+}
+
+func (c *connClient) History(rlsName string, max int32) ([]*release.Release, error) {
+	res, err := c.client.GetHistory(c.context(), &rls.GetHistoryRequest{Name: rlsName, Max: max})
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+	return res.GetReleases(), nil
+}
+
+func (c *connClient) Status(rlsName string) (*release.Status, error) {
+	res, err := c.client.GetReleaseStatus(c.context(), &rls.GetReleaseStatusRequest{Name: rlsName})
+	if err != nil {
+		return nil, c.wrap(err)
+	}
+	return res.GetInfo().GetStatus(), nil
+}
+
+func (c *connClient) Test(rlsName string, opts TestOptions) error {
+	stream, err := c.client.RunReleaseTest(c.context(), &rls.TestReleaseRequest{
+		Name:    rlsName,
+		Timeout: opts.Timeout,
+		Cleanup: opts.Cleanup,
+	})
+	if err != nil {
+		return c.wrap(err)
+	}
+	var failures []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.wrap(err)
+		}
+		if res.GetStatus() == release.TestRun_FAILURE {
+			failures = append(failures, res.GetMsg())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// processRequirements disables the dependencies of ch disabled by values
+// and imports their values, as the helm client does before sending charts
+// to Tiller
+func processRequirements(ch *chart.Chart, values *chart.Config) error {
+	if err := chartutil.ProcessRequirementsEnabled(ch, values); err != nil {
+		return err
+	}
+	return chartutil.ProcessRequirementsImportValues(ch)
+}
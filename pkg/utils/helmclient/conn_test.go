@@ -0,0 +1,82 @@
+package helmclient
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakeHealthServer struct {
+	status healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (s *fakeHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return &healthpb.HealthCheckResponse{Status: s.status}, nil
+}
+
+// countingListener counts the accepted connections
+type countingListener struct {
+	net.Listener
+	mu    sync.Mutex
+	count int
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.count++
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *countingListener) accepted() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+func TestDialTiller(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := &countingListener{Listener: l}
+	health := &fakeHealthServer{status: healthpb.HealthCheckResponse_SERVING}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health)
+	go server.Serve(lis)
+
+	c, err := DialTiller(l.Addr().String(), TillerOptions{Keepalive: time.Minute, BackoffMaxDelay: time.Second, HealthTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	hc := c.(HealthChecker)
+	for i := 0; i < 3; i++ {
+		if err := hc.Healthy(); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	if n := lis.accepted(); n != 1 {
+		t.Errorf("Expecting calls to share a connection, received %d connections", n)
+	}
+
+	health.status = healthpb.HealthCheckResponse_NOT_SERVING
+	if err := hc.Healthy(); !IsUnavailable(err) {
+		t.Errorf("Expecting Tiller not serving to be unavailable, received %v", err)
+	}
+
+	server.Stop()
+	if _, err := c.History("foo", 1); !IsUnavailable(err) {
+		t.Errorf("Expecting an unavailable error once Tiller is stopped, received %v", err)
+	}
+	if err := hc.Healthy(); !IsUnavailable(err) {
+		t.Errorf("Expecting Tiller to be unhealthy once stopped, received %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package helmclient
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// MockManifest is the manifest of the releases deployed by FakeClient
+const MockManifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: fixture
+`
+
+// FakeClient is an in-memory Interface for tests. Charts are not rendered,
+// all releases have MockManifest as manifest.
+type FakeClient struct {
+	mu sync.Mutex
+	// Releases are the revisions of all releases, oldest first
+	Releases []*release.Release
+	// Calls records the name of each method called
+	Calls []string
+	// TestFailures are the failures of the tests of each release
+	TestFailures map[string]string
+}
+
+var _ Interface = &FakeClient{}
+
+// NewFakeClient returns a FakeClient with a deployed first revision of
+// each of the named releases
+func NewFakeClient(rlsNames ...string) *FakeClient {
+	c := &FakeClient{}
+	for _, name := range rlsNames {
+		c.Releases = append(c.Releases, MockRelease(name, "default", 1, &chart.Chart{}, release.Status_DEPLOYED))
+	}
+	return c
+}
+
+// MockRelease returns a release revision deploying MockManifest
+func MockRelease(rlsName, namespace string, version int32, ch *chart.Chart, code release.Status_Code) *release.Release {
+	date := timestamp.Timestamp{Seconds: 242085845}
+	return &release.Release{
+		Name:      rlsName,
+		Namespace: namespace,
+		Version:   version,
+		Chart:     ch,
+		Config:    &chart.Config{},
+		Manifest:  MockManifest,
+		Info: &release.Info{
+			FirstDeployed: &date,
+			LastDeployed:  &date,
+			Status:        &release.Status{Code: code},
+		},
+	}
+}
+
+// Deployed returns the names of the releases whose latest revision is not
+// deleted
+func (c *FakeClient) Deployed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	seen := map[string]bool{}
+	for i := len(c.Releases) - 1; i >= 0; i-- {
+		rel := c.Releases[i]
+		if seen[rel.Name] {
+			continue
+		}
+		seen[rel.Name] = true
+		if rel.GetInfo().GetStatus().GetCode() != release.Status_DELETED {
+			names = append([]string{rel.Name}, names...)
+		}
+	}
+	return names
+}
+
+// latest returns the latest revision of a release, or nil
+func (c *FakeClient) latest(rlsName string) *release.Release {
+	for i := len(c.Releases) - 1; i >= 0; i-- {
+		if c.Releases[i].Name == rlsName {
+			return c.Releases[i]
+		}
+	}
+	return nil
+}
+
+// deploy records rel as the deployed revision, superseding the previous one
+func (c *FakeClient) deploy(rel *release.Release) {
+	if prev := c.latest(rel.Name); prev != nil && prev.GetInfo().GetStatus().GetCode() == release.Status_DEPLOYED {
+		prev.Info.Status.Code = release.Status_SUPERSEDED
+	}
+	c.Releases = append(c.Releases, rel)
+}
+
+func (c *FakeClient) Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Install")
+	version := int32(1)
+	if prev := c.latest(opts.ReleaseName); prev != nil {
+		if prev.GetInfo().GetStatus().GetCode() != release.Status_DELETED {
+			return nil, fmt.Errorf("cannot re-use a name that is still in use")
+		}
+		version = prev.Version + 1
+	}
+	rel := MockRelease(opts.ReleaseName, namespace, version, ch, release.Status_DEPLOYED)
+	rel.Config.Raw = string(opts.Values)
+	if !opts.DryRun {
+		c.deploy(rel)
+	}
+	return rel, nil
+}
+
+func (c *FakeClient) Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Upgrade")
+	prev := c.latest(rlsName)
+	if prev == nil || prev.GetInfo().GetStatus().GetCode() == release.Status_DELETED {
+		return nil, notFoundError(rlsName)
+	}
+	rel := MockRelease(rlsName, prev.Namespace, prev.Version+1, ch, release.Status_DEPLOYED)
+	rel.Config.Raw = string(opts.Values)
+	if !opts.DryRun {
+		c.deploy(rel)
+	}
+	return rel, nil
+}
+
+func (c *FakeClient) Rollback(rlsName string, opts RollbackOptions) (*release.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Rollback")
+	prev := c.latest(rlsName)
+	if prev == nil {
+		return nil, notFoundError(rlsName)
+	}
+	version := opts.Version
+	if version == 0 {
+		version = prev.Version - 1
+	}
+	var target *release.Release
+	for _, rel := range c.Releases {
+		if rel.Name == rlsName && rel.Version == version {
+			target = rel
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("release: %q revision %d not found", rlsName, version)
+	}
+	rel := MockRelease(rlsName, target.Namespace, prev.Version+1, target.Chart, release.Status_DEPLOYED)
+	rel.Config = target.Config
+	rel.Manifest = target.Manifest
+	c.deploy(rel)
+	return rel, nil
+}
+
+func (c *FakeClient) Delete(rlsName string, opts DeleteOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Delete")
+	prev := c.latest(rlsName)
+	if prev == nil {
+		return notFoundError(rlsName)
+	}
+	if opts.Purge {
+		var releases []*release.Release
+		for _, rel := range c.Releases {
+			if rel.Name != rlsName {
+				releases = append(releases, rel)
+			}
+		}
+		c.Releases = releases
+		return nil
+	}
+	prev.Info.Status.Code = release.Status_DELETED
+	return nil
+}
+
+func (c *FakeClient) History(rlsName string, max int32) ([]*release.Release, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "History")
+	var history []*release.Release
+	for i := len(c.Releases) - 1; i >= 0 && (max <= 0 || int32(len(history)) < max); i-- {
+		if c.Releases[i].Name == rlsName {
+			history = append(history, c.Releases[i])
+		}
+	}
+	if len(history) == 0 {
+		return nil, notFoundError(rlsName)
+	}
+	return history, nil
+}
+
+func (c *FakeClient) Status(rlsName string) (*release.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Status")
+	rel := c.latest(rlsName)
+	if rel == nil {
+		return nil, notFoundError(rlsName)
+	}
+	return rel.GetInfo().GetStatus(), nil
+}
+
+func (c *FakeClient) Test(rlsName string, opts TestOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Calls = append(c.Calls, "Test")
+	if c.latest(rlsName) == nil {
+		return notFoundError(rlsName)
+	}
+	if msg, ok := c.TestFailures[rlsName]; ok {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package helmclient
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+func TestFakeClient(t *testing.T) {
+	c := NewFakeClient("existing")
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"}}
+
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo", DryRun: true}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.History("foo", 0); !IsNotFound(err) {
+		t.Errorf("Expecting dry-run installs not to be stored, received %v", err)
+	}
+	rel, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo", Values: []byte("a: b")})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Version != 1 || rel.Namespace != "myns" || rel.Chart != ch || rel.Config.Raw != "a: b" {
+		t.Errorf("Unexpected release %+v", rel)
+	}
+	if _, err := c.Install(ch, "myns", InstallOptions{ReleaseName: "foo"}); err == nil {
+		t.Errorf("Expecting an error installing a deployed release")
+	}
+
+	if _, err := c.Upgrade("foo", ch, UpgradeOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.Upgrade("missing", ch, UpgradeOptions{}); !IsNotFound(err) {
+		t.Errorf("Expecting a not found error upgrading a missing release, received %v", err)
+	}
+	rel, err = c.Rollback("foo", RollbackOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Version != 3 || rel.Config.Raw != "a: b" {
+		t.Errorf("Expecting revision 3 with the values of revision 1, received %+v", rel)
+	}
+
+	history, err := c.History("foo", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Errorf("Expecting the 2 latest revisions, received %v", history)
+	}
+	if history[1].Info.Status.Code != release.Status_SUPERSEDED {
+		t.Errorf("Expecting previous revisions to be superseded, received %s", history[1].Info.Status.Code)
+	}
+
+	if err := c.Delete("foo", DeleteOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	status, err := c.Status("foo")
+	if err != nil || status.Code != release.Status_DELETED {
+		t.Errorf("Expecting a deleted release, received %v %v", status, err)
+	}
+	if deployed := c.Deployed(); !reflect.DeepEqual(deployed, []string{"existing"}) {
+		t.Errorf("Expecting only the existing release to be deployed, received %v", deployed)
+	}
+	if rel, err = c.Install(ch, "myns", InstallOptions{ReleaseName: "foo"}); err != nil || rel.Version != 4 {
+		t.Errorf("Expecting deleted releases to be reinstalled, received %v %v", rel, err)
+	}
+	if err := c.Delete("foo", DeleteOptions{Purge: true}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := c.Status("foo"); !IsNotFound(err) {
+		t.Errorf("Expecting purged releases to be forgotten, received %v", err)
+	}
+
+	expectedCalls := []string{"Install", "History", "Install", "Install", "Upgrade", "Upgrade", "Rollback", "History", "Delete", "Status", "Install", "Delete", "Status"}
+	if !reflect.DeepEqual(c.Calls, expectedCalls) {
+		t.Errorf("Expecting calls %v, received %v", expectedCalls, c.Calls)
+	}
+}
@@ -0,0 +1,103 @@
+// Package helmclient abstracts the Helm operations used by the controller,
+// so that it can be tested without Tiller and other backends can be added.
+package helmclient
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// Interface manages Helm releases
+type Interface interface {
+	// Install installs a chart as a new release in namespace
+	Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error)
+	// Upgrade upgrades a release to a chart
+	Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error)
+	// Rollback rolls a release back to a previous revision
+	Rollback(rlsName string, opts RollbackOptions) (*release.Release, error)
+	// Delete deletes a release
+	Delete(rlsName string, opts DeleteOptions) error
+	// History returns up to max revisions of a release, latest first
+	History(rlsName string, max int32) ([]*release.Release, error)
+	// Status returns the status of the latest revision of a release
+	Status(rlsName string) (*release.Status, error)
+	// Test runs the test hooks of a release, returning an error if one fails
+	Test(rlsName string, opts TestOptions) error
+}
+
+// InstallOptions configure Install
+type InstallOptions struct {
+	ReleaseName string
+	// Values are the YAML values overriding the chart defaults
+	Values []byte
+	// Timeout is the time in seconds to wait for the operation, 0 for the default
+	Timeout int64
+	// DryRun renders the release without installing it
+	DryRun bool
+	// DisableHooks skips the install hooks of the chart
+	DisableHooks bool
+	// Capabilities are those of the cluster the chart is rendered for,
+	// the defaults of chartutil if nil. Tiller renders with its own.
+	Capabilities *chartutil.Capabilities
+}
+
+// UpgradeOptions configure Upgrade
+type UpgradeOptions struct {
+	// Values are the YAML values overriding the chart defaults
+	Values []byte
+	// Timeout is the time in seconds to wait for the operation, 0 for the default
+	Timeout int64
+	// DryRun renders the release without upgrading it
+	DryRun bool
+	// DisableHooks skips the upgrade hooks of the chart
+	DisableHooks bool
+	// Capabilities are those of the cluster the chart is rendered for,
+	// the defaults of chartutil if nil. Tiller renders with its own.
+	Capabilities *chartutil.Capabilities
+}
+
+// RollbackOptions configure Rollback
+type RollbackOptions struct {
+	// Version is the revision to roll back to, 0 for the previous one
+	Version int32
+	// Recreate restarts the pods of the release
+	Recreate bool
+	// Force replaces objects that can't be patched
+	Force bool
+	// DisableHooks skips the rollback hooks of the chart
+	DisableHooks bool
+}
+
+// DeleteOptions configure Delete
+type DeleteOptions struct {
+	// Purge removes the release history, allowing its name to be reused
+	Purge bool
+	// Timeout is the time in seconds to wait for the operation, 0 for the default
+	Timeout int64
+	// DisableHooks skips the delete hooks of the release
+	DisableHooks bool
+}
+
+// TestOptions configure Test
+type TestOptions struct {
+	// Timeout is the time in seconds to wait for each test, 0 for the default
+	Timeout int64
+	// Cleanup deletes the test pods once they complete
+	Cleanup bool
+}
+
+// IsNotFound returns whether err is due to a release that does not exist
+func IsNotFound(err error) bool {
+	// Ideally this would be `grpc.Code(err) == codes.NotFound`,
+	// but it seems helm doesn't return grpc codes
+	return err != nil && strings.Contains(grpc.ErrorDesc(err), "not found")
+}
+
+func notFoundError(rlsName string) error {
+	return fmt.Errorf("release: %q not found", rlsName)
+}
@@ -0,0 +1,87 @@
+package helmclient
+
+import (
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// Semaphore bounds the number of concurrent operations
+type Semaphore chan struct{}
+
+// NewSemaphore returns a Semaphore letting n operations run concurrently,
+// nil, which doesn't limit anything, if n isn't positive
+func NewSemaphore(n int) Semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(Semaphore, n)
+}
+
+func (s Semaphore) acquire() {
+	if s != nil {
+		s <- struct{}{}
+	}
+}
+
+func (s Semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+type limitedClient struct {
+	client Interface
+	sems   []Semaphore
+}
+
+// Limit returns a client running the operations of client once it
+// acquired each of sems, in order
+func Limit(client Interface, sems ...Semaphore) Interface {
+	return &limitedClient{client: client, sems: sems}
+}
+
+func (c *limitedClient) acquire() func() {
+	for _, s := range c.sems {
+		s.acquire()
+	}
+	return func() {
+		for _, s := range c.sems {
+			s.release()
+		}
+	}
+}
+
+func (c *limitedClient) Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error) {
+	defer c.acquire()()
+	return c.client.Install(ch, namespace, opts)
+}
+
+func (c *limitedClient) Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error) {
+	defer c.acquire()()
+	return c.client.Upgrade(rlsName, ch, opts)
+}
+
+func (c *limitedClient) Rollback(rlsName string, opts RollbackOptions) (*release.Release, error) {
+	defer c.acquire()()
+	return c.client.Rollback(rlsName, opts)
+}
+
+func (c *limitedClient) Delete(rlsName string, opts DeleteOptions) error {
+	defer c.acquire()()
+	return c.client.Delete(rlsName, opts)
+}
+
+func (c *limitedClient) History(rlsName string, max int32) ([]*release.Release, error) {
+	defer c.acquire()()
+	return c.client.History(rlsName, max)
+}
+
+func (c *limitedClient) Status(rlsName string) (*release.Status, error) {
+	defer c.acquire()()
+	return c.client.Status(rlsName)
+}
+
+func (c *limitedClient) Test(rlsName string, opts TestOptions) error {
+	defer c.acquire()()
+	return c.client.Test(rlsName, opts)
+}
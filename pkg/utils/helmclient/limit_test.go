@@ -0,0 +1,38 @@
+package helmclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimit(t *testing.T) {
+	if NewSemaphore(0) != nil {
+		t.Errorf("Expecting no semaphore without a limit")
+	}
+	global, host := NewSemaphore(2), NewSemaphore(1)
+	c := Limit(NewFakeClient("foo"), global, NewSemaphore(0), host)
+
+	// Another operation on the host is running
+	host.acquire()
+	done := make(chan error)
+	go func() {
+		_, err := c.Status("foo")
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Expecting the operation to wait for the host semaphore")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(global) != 1 {
+		t.Errorf("Expecting the waiting operation to hold the global semaphore")
+	}
+
+	host.release()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(global) != 0 || len(host) != 0 {
+		t.Errorf("Expecting the semaphores to be released")
+	}
+}
@@ -0,0 +1,117 @@
+package helmclient
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+type tillerClient struct {
+	client helm.Interface
+}
+
+// NewTillerClient returns a client managing releases with Tiller
+func NewTillerClient(client helm.Interface) Interface {
+	return &tillerClient{client: client}
+}
+
+func (c *tillerClient) Install(ch *chart.Chart, namespace string, opts InstallOptions) (*release.Release, error) {
+	helmOpts := []helm.InstallOption{
+		helm.ValueOverrides(opts.Values),
+		helm.ReleaseName(opts.ReleaseName),
+		helm.InstallDryRun(opts.DryRun),
+		helm.InstallDisableHooks(opts.DisableHooks),
+	}
+	if opts.Timeout > 0 {
+		helmOpts = append(helmOpts, helm.InstallTimeout(opts.Timeout))
+	}
+	res, err := c.client.InstallReleaseFromChart(ch, namespace, helmOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *tillerClient) Upgrade(rlsName string, ch *chart.Chart, opts UpgradeOptions) (*release.Release, error) {
+	helmOpts := []helm.UpdateOption{
+		helm.UpdateValueOverrides(opts.Values),
+		helm.UpgradeDryRun(opts.DryRun),
+		helm.UpgradeDisableHooks(opts.DisableHooks),
+	}
+	if opts.Timeout > 0 {
+		helmOpts = append(helmOpts, helm.UpgradeTimeout(opts.Timeout))
+	}
+	res, err := c.client.UpdateReleaseFromChart(rlsName, ch, helmOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *tillerClient) Rollback(rlsName string, opts RollbackOptions) (*release.Release, error) {
+	res, err := c.client.RollbackRelease(rlsName,
+		helm.RollbackVersion(opts.Version),
+		helm.RollbackRecreate(opts.Recreate),
+		helm.RollbackForce(opts.Force),
+		helm.RollbackDisableHooks(opts.DisableHooks),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return res.GetRelease(), nil
+}
+
+func (c *tillerClient) Delete(rlsName string, opts DeleteOptions) error {
+	helmOpts := []helm.DeleteOption{
+		helm.DeletePurge(opts.Purge),
+		helm.DeleteDisableHooks(opts.DisableHooks),
+	}
+	if opts.Timeout > 0 {
+		helmOpts = append(helmOpts, helm.DeleteTimeout(opts.Timeout))
+	}
+	_, err := c.client.DeleteRelease(rlsName, helmOpts...)
+	return err
+}
+
+func (c *tillerClient) History(rlsName string, max int32) ([]*release.Release, error) {
+	res, err := c.client.ReleaseHistory(rlsName, helm.WithMaxHistory(max))
+	if err != nil {
+		return nil, err
+	}
+	return res.GetReleases(), nil
+}
+
+func (c *tillerClient) Status(rlsName string) (*release.Status, error) {
+	res, err := c.client.ReleaseStatus(rlsName)
+	if err != nil {
+		return nil, err
+	}
+	return res.GetInfo().GetStatus(), nil
+}
+
+func (c *tillerClient) Test(rlsName string, opts TestOptions) error {
+	helmOpts := []helm.ReleaseTestOption{helm.ReleaseTestCleanup(opts.Cleanup)}
+	if opts.Timeout > 0 {
+		helmOpts = append(helmOpts, helm.ReleaseTestTimeout(opts.Timeout))
+	}
+	resc, errc := c.client.RunReleaseTest(rlsName, helmOpts...)
+	if resc == nil {
+		return <-errc
+	}
+	var failures []string
+	for res := range resc {
+		if res.GetStatus() == release.TestRun_FAILURE {
+			failures = append(failures, res.GetMsg())
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package helmclient
+
+import (
+	"testing"
+
+	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+func TestTillerClient(t *testing.T) {
+	tiller := &helm.FakeClient{}
+	c := NewTillerClient(tiller)
+
+	rel, err := c.Install(&chart.Chart{}, "myns", InstallOptions{ReleaseName: "foo", Timeout: 60})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if rel.Name != "foo" || rel.Namespace != "myns" {
+		t.Errorf("Unexpected release %+v", rel)
+	}
+	if _, err := c.Upgrade("foo", &chart.Chart{}, UpgradeOptions{DryRun: true}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if rel, err := c.Rollback("foo", RollbackOptions{Version: 1}); err != nil || rel != nil {
+		t.Errorf("Expecting the empty response of the fake Tiller, received %v %v", rel, err)
+	}
+	if err := c.Test("foo", TestOptions{Timeout: 60}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	tiller.Responses = map[string]release.TestRun_Status{"RUNNING: foo-test": release.TestRun_RUNNING, "FAILED: foo-test": release.TestRun_FAILURE}
+	if err := c.Test("foo", TestOptions{Cleanup: true}); err == nil || err.Error() != "FAILED: foo-test" {
+		t.Errorf("Expecting the failed test to be reported, received %v", err)
+	}
+	history, err := c.History("foo", 1)
+	if err != nil || len(history) != 1 {
+		t.Errorf("Unexpected history %v %v", history, err)
+	}
+	status, err := c.Status("foo")
+	if err != nil || status.GetCode().String() != "DEPLOYED" {
+		t.Errorf("Unexpected status %v %v", status, err)
+	}
+	if err := c.Delete("foo", DeleteOptions{Purge: true, Timeout: 60}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := c.Status("foo"); err == nil {
+		t.Errorf("Expecting an error for a deleted release")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !IsNotFound(notFoundError("foo")) {
+		t.Errorf("Expecting a not found error")
+	}
+	if IsNotFound(nil) {
+		t.Errorf("Expecting nil not to be a not found error")
+	}
+}
@@ -0,0 +1,411 @@
+// Package jsonschema validates values against the JSON schemas charts
+// bundle as values.schema.json. It supports the keywords commonly used in
+// those schemas; other keywords are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed JSON schema
+type Schema struct {
+	root interface{}
+}
+
+// Parse parses a JSON schema document
+func Parse(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	if _, ok := root.(map[string]interface{}); !ok {
+		if _, ok := root.(bool); !ok {
+			return nil, fmt.Errorf("invalid JSON schema: not an object")
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate returns the violations of the schema by value, which must be
+// decoded from JSON or YAML, sorted by path. Paths are dotted, e.g.
+// "image.tag" or "ports[0]".
+func (s *Schema) Validate(value interface{}) []string {
+	v := &validator{root: s.root}
+	v.validate(s.root, normalize(value), "")
+	sort.Strings(v.errs)
+	return v.errs
+}
+
+type validator struct {
+	root interface{}
+	errs []string
+	// depth guards against recursive $refs
+	depth int
+}
+
+const maxDepth = 64
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.errs = append(v.errs, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string) {
+	if b, ok := schema.(bool); ok {
+		if !b {
+			v.fail(path, "not allowed")
+		}
+		return
+	}
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if v.depth > maxDepth {
+		v.fail(path, "schema nested too deeply")
+		return
+	}
+	v.depth++
+	defer func() { v.depth-- }()
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(target, value, path)
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", typeNames(t), typeOf(value))
+		return
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(normalize(e), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", jsonString(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(normalize(c), value) {
+		v.fail(path, "must be %s", jsonString(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(s, val, path)
+	case []interface{}:
+		v.validateArray(s, val, path)
+	case string:
+		v.validateString(s, val, path)
+	case float64:
+		v.validateNumber(s, val, path)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && v.matching(anyOf, value) == 0 {
+		v.fail(path, "must match at least one schema of anyOf")
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		if n := v.matching(oneOf, value); n != 1 {
+			v.fail(path, "must match exactly one schema of oneOf, matches %d", n)
+		}
+	}
+	if not, ok := s["not"]; ok && v.matching([]interface{}{not}, value) == 1 {
+		v.fail(path, "must not match the schema of not")
+	}
+}
+
+// matching returns the number of schemas value is valid against
+func (v *validator) matching(schemas []interface{}, value interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		sv := &validator{root: v.root, depth: v.depth}
+		sv.validate(sub, value, "")
+		if len(sv.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (v *validator) validateObject(s map[string]interface{}, obj map[string]interface{}, path string) {
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, ok := obj[name]; !ok {
+					v.fail(path, "%s is required", name)
+				}
+			}
+		}
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	for name, val := range obj {
+		childPath := join(path, name)
+		matched := false
+		if sub, ok := props[name]; ok {
+			v.validate(sub, val, childPath)
+			matched = true
+		}
+		for pattern, sub := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				v.fail(path, "invalid patternProperties pattern %q", pattern)
+				continue
+			}
+			if re.MatchString(name) {
+				v.validate(sub, val, childPath)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		switch additional := s["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path, "additional property %s is not allowed", name)
+			}
+		case map[string]interface{}:
+			v.validate(additional, val, childPath)
+		}
+	}
+	if n, ok := number(s["minProperties"]); ok && float64(len(obj)) < n {
+		v.fail(path, "must have at least %v properties", n)
+	}
+	if n, ok := number(s["maxProperties"]); ok && float64(len(obj)) > n {
+		v.fail(path, "must have at most %v properties", n)
+	}
+}
+
+func (v *validator) validateArray(s map[string]interface{}, arr []interface{}, path string) {
+	switch items := s["items"].(type) {
+	case map[string]interface{}, bool:
+		for i, val := range arr {
+			v.validate(items, val, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case []interface{}:
+		for i, val := range arr {
+			if i < len(items) {
+				v.validate(items[i], val, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	if n, ok := number(s["minItems"]); ok && float64(len(arr)) < n {
+		v.fail(path, "must have at least %v items", n)
+	}
+	if n, ok := number(s["maxItems"]); ok && float64(len(arr)) > n {
+		v.fail(path, "must have at most %v items", n)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateString(s map[string]interface{}, str string, path string) {
+	length := float64(len([]rune(str)))
+	if n, ok := number(s["minLength"]); ok && length < n {
+		v.fail(path, "must be at least %v characters long", n)
+	}
+	if n, ok := number(s["maxLength"]); ok && length > n {
+		v.fail(path, "must be at most %v characters long", n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern %q", pattern)
+		} else if !re.MatchString(str) {
+			v.fail(path, "must match %q", pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(s map[string]interface{}, n float64, path string) {
+	if min, ok := number(s["minimum"]); ok && n < min {
+		v.fail(path, "must be greater than or equal to %v", min)
+	}
+	if max, ok := number(s["maximum"]); ok && n > max {
+		v.fail(path, "must be less than or equal to %v", max)
+	}
+	if min, ok := number(s["exclusiveMinimum"]); ok && n <= min {
+		v.fail(path, "must be greater than %v", min)
+	}
+	if max, ok := number(s["exclusiveMaximum"]); ok && n >= max {
+		v.fail(path, "must be less than %v", max)
+	}
+	if m, ok := number(s["multipleOf"]); ok && m > 0 {
+		if q := n / m; q != math.Trunc(q) {
+			v.fail(path, "must be a multiple of %v", m)
+		}
+	}
+}
+
+// resolve returns the schema of a local reference, e.g.
+// "#/definitions/image"
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q, only local references are supported", ref)
+	}
+	cur := v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+		if cur, ok = m[token]; !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return cur, nil
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := typeOf(value)
+	switch name {
+	case "number":
+		return actual == "number" || actual == "integer"
+	default:
+		return actual == name
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func typeNames(t interface{}) string {
+	if names, ok := t.([]interface{}); ok {
+		var s []string
+		for _, name := range names {
+			s = append(s, fmt.Sprint(name))
+		}
+		return strings.Join(s, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// normalize converts the numbers of value to float64, as decoded from
+// JSON, since YAML decodes integers as int64
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = normalize(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[fmt.Sprint(k)] = normalize(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = normalize(val)
+		}
+		return out
+	}
+	if n, ok := number(value); ok {
+		return n
+	}
+	return value
+}
+
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func jsonString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
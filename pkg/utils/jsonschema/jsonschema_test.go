@@ -0,0 +1,94 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {"$ref": "#/definitions/image"},
+    "replicas": {"type": "integer", "minimum": 1},
+    "service": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "type": {"enum": ["ClusterIP", "NodePort", "LoadBalancer"]},
+        "port": {"type": ["integer", "string"]}
+      }
+    },
+    "hosts": {"type": "array", "items": {"type": "string", "pattern": "^[a-z.]+$"}}
+  },
+  "definitions": {
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string", "minLength": 1},
+        "tag": {"type": "string"}
+      }
+    }
+  }
+}`
+
+func validateYAML(t *testing.T, s *Schema, values string) []string {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(values), &v); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return s.Validate(v)
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	tests := []struct {
+		name     string
+		values   string
+		expected []string
+	}{
+		{"valid", "image: {repository: nginx, tag: '1.0'}\nreplicas: 2\nservice: {type: NodePort, port: http}\nhosts: [example.com]", nil},
+		{"missing required", "replicas: 2", []string{"(root): image is required"}},
+		{"wrong type", "image: {repository: nginx}\nreplicas: two", []string{"replicas: expected integer, got string"}},
+		{"not an integer", "image: {repository: nginx}\nreplicas: 1.5", []string{"replicas: expected integer, got number"}},
+		{"minimum", "image: {repository: nginx}\nreplicas: 0", []string{"replicas: must be greater than or equal to 1"}},
+		{"typo", "image: {repository: nginx}\nservice: {tpye: NodePort}", []string{"service: additional property tpye is not allowed"}},
+		{"enum", "image: {repository: nginx}\nservice: {type: Nodeport}", []string{`service.type: must be one of ["ClusterIP","NodePort","LoadBalancer"]`}},
+		{"ref", "image: {repository: ''}", []string{"image.repository: must be at least 1 characters long"}},
+		{"items", "image: {repository: nginx}\nhosts: [example.com, Example.com]", []string{`hosts[1]: must match "^[a-z.]+$"`}},
+	}
+	for _, tt := range tests {
+		errs := validateYAML(t, s, tt.values)
+		if !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("%s: expected %q, received %q", tt.name, tt.expected, errs)
+		}
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	s, err := Parse([]byte(`{"properties": {"port": {"oneOf": [{"type": "integer"}, {"type": "string", "pattern": "^[0-9]+$"}]}}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if errs := validateYAML(t, s, "port: 80"); len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+	if errs := validateYAML(t, s, "port: true"); len(errs) != 1 {
+		t.Errorf("Expecting an error, received %v", errs)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, data := range []string{"{", "[]"} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
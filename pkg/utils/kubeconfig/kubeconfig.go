@@ -0,0 +1,109 @@
+// Package kubeconfig builds clients of remote clusters from kubeconfig
+// files stored in Secrets
+package kubeconfig
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/client-go/rest"
+)
+
+// config is the subset of the kubeconfig file format needed to connect
+// to a cluster. Credentials must be embedded, since files referenced by
+// path are not available to the controller.
+type config struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                   string `json:"server"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+			CertificateAuthority     string `json:"certificate-authority"`
+			CertificateAuthorityData []byte `json:"certificate-authority-data"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			ClientCertificate     string `json:"client-certificate"`
+			ClientCertificateData []byte `json:"client-certificate-data"`
+			ClientKey             string `json:"client-key"`
+			ClientKeyData         []byte `json:"client-key-data"`
+			Token                 string `json:"token"`
+			TokenFile             string `json:"tokenFile"`
+			Username              string `json:"username"`
+			Password              string `json:"password"`
+		} `json:"user"`
+	} `json:"users"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster string `json:"cluster"`
+			User    string `json:"user"`
+		} `json:"context"`
+	} `json:"contexts"`
+}
+
+// RESTConfig returns the client configuration of the current context of
+// the kubeconfig file data
+func RESTConfig(data []byte) (*rest.Config, error) {
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse kubeconfig: %v", err)
+	}
+	if cfg.CurrentContext == "" {
+		return nil, fmt.Errorf("kubeconfig has no current-context")
+	}
+
+	var clusterName, userName string
+	found := false
+	for _, ctx := range cfg.Contexts {
+		if ctx.Name == cfg.CurrentContext {
+			clusterName, userName = ctx.Context.Cluster, ctx.Context.User
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", cfg.CurrentContext)
+	}
+
+	restConfig := &rest.Config{}
+	found = false
+	for _, cluster := range cfg.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		if cluster.Cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %q: certificate-authority files are not supported, use certificate-authority-data", clusterName)
+		}
+		restConfig.Host = cluster.Cluster.Server
+		restConfig.TLSClientConfig.Insecure = cluster.Cluster.InsecureSkipTLSVerify
+		restConfig.TLSClientConfig.CAData = cluster.Cluster.CertificateAuthorityData
+		found = true
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", clusterName)
+	}
+	if restConfig.Host == "" {
+		return nil, fmt.Errorf("cluster %q has no server", clusterName)
+	}
+
+	for _, user := range cfg.Users {
+		if user.Name != userName {
+			continue
+		}
+		if user.User.ClientCertificate != "" || user.User.ClientKey != "" || user.User.TokenFile != "" {
+			return nil, fmt.Errorf("user %q: credential files are not supported, embed them in the kubeconfig", userName)
+		}
+		restConfig.TLSClientConfig.CertData = user.User.ClientCertificateData
+		restConfig.TLSClientConfig.KeyData = user.User.ClientKeyData
+		restConfig.BearerToken = user.User.Token
+		restConfig.Username = user.User.Username
+		restConfig.Password = user.User.Password
+		break
+	}
+	return restConfig, nil
+}
@@ -0,0 +1,72 @@
+package kubeconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: staging
+  cluster:
+    server: https://staging.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+    certificate-authority-data: Y2E=
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: deployer
+users:
+- name: admin
+  user:
+    username: admin
+    password: secret
+- name: deployer
+  user:
+    token: abcd
+`
+
+func TestRESTConfig(t *testing.T) {
+	config, err := RESTConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if config.Host != "https://prod.example.com" {
+		t.Errorf("Unexpected host %s", config.Host)
+	}
+	if string(config.TLSClientConfig.CAData) != "ca" {
+		t.Errorf("Unexpected CA data %q", config.TLSClientConfig.CAData)
+	}
+	if config.BearerToken != "abcd" || config.Username != "" {
+		t.Errorf("Unexpected credentials %+v", config)
+	}
+}
+
+func TestRESTConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"no current context", strings.Replace(testConfig, "current-context: prod", "", 1), "no current-context"},
+		{"unknown context", strings.Replace(testConfig, "current-context: prod", "current-context: dev", 1), `context "dev" not found`},
+		{"certificate file", strings.Replace(testConfig, "certificate-authority-data: Y2E=", "certificate-authority: /etc/ca.crt", 1), "not supported"},
+		{"token file", strings.Replace(testConfig, "token: abcd", "tokenFile: /var/run/token", 1), "not supported"},
+	}
+	for _, tt := range tests {
+		_, err := RESTConfig([]byte(tt.config))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error containing %q, received %v", tt.name, tt.err, err)
+		}
+	}
+}
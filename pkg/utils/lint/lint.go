@@ -0,0 +1,146 @@
+// Package lint runs the checks of helm lint on a chart and the values it
+// is released with, without the chart directory helm lint requires.
+package lint
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/render"
+)
+
+// Severity is how serious a lint message is
+type Severity int
+
+const (
+	// Info is a recommendation
+	Info Severity = iota + 1
+	// Warning is a likely mistake
+	Warning
+	// Error makes the chart fail to install or to work as intended
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "INFO"
+	case Warning:
+		return "WARNING"
+	case Error:
+		return "ERROR"
+	}
+	return "UNKNOWN"
+}
+
+// Message is a finding of the linter
+type Message struct {
+	Severity Severity
+	// Path is the chart file the message is about
+	Path string
+	Text string
+}
+
+// String formats m the way helm lint prints it
+func (m Message) String() string {
+	return fmt.Sprintf("[%s] %s: %s", m.Severity, m.Path, m.Text)
+}
+
+// HasErrors returns true if any of msgs is an Error
+func HasErrors(msgs []Message) bool {
+	for _, m := range msgs {
+		if m.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	chartfile    = "Chart.yaml"
+	valuesfile   = "values.yaml"
+	templatesDir = "templates/"
+)
+
+// Chart lints ch, rendering its templates with values for a release
+// described by opts, in a cluster of caps, the defaults of chartutil if nil
+func Chart(ch *chart.Chart, values []byte, opts chartutil.ReleaseOptions, caps *chartutil.Capabilities) []Message {
+	var msgs []Message
+	add := func(sev Severity, path, format string, args ...interface{}) {
+		msgs = append(msgs, Message{Severity: sev, Path: path, Text: fmt.Sprintf(format, args...)})
+	}
+
+	meta := ch.GetMetadata()
+	if meta.GetApiVersion() == "" {
+		add(Error, chartfile, "apiVersion is required")
+	}
+	if meta.GetName() == "" {
+		add(Error, chartfile, "name is required")
+	}
+	if meta.GetVersion() == "" {
+		add(Error, chartfile, "version is required")
+	} else if v, err := semver.NewVersion(meta.GetVersion()); err != nil {
+		add(Error, chartfile, "version %q is not a valid SemVer", meta.GetVersion())
+	} else if c, _ := semver.NewConstraint("> 0"); !c.Check(v) {
+		add(Error, chartfile, "version %s is less than or equal to 0", meta.GetVersion())
+	}
+	if engine := meta.GetEngine(); engine != "" && engine != "gotpl" {
+		add(Error, chartfile, "engine %q not valid, only gotpl is supported", engine)
+	}
+	for _, m := range meta.GetMaintainers() {
+		if m.GetName() == "" {
+			add(Error, chartfile, "each maintainer requires a name")
+		} else if m.GetEmail() != "" {
+			if _, err := mail.ParseAddress(m.GetEmail()); err != nil {
+				add(Error, chartfile, "invalid email %q for maintainer %q", m.GetEmail(), m.GetName())
+			}
+		}
+	}
+	for _, src := range meta.GetSources() {
+		if !validURL(src) {
+			add(Error, chartfile, "invalid source URL %q", src)
+		}
+	}
+	if home := meta.GetHome(); home != "" && !validURL(home) {
+		add(Error, chartfile, "invalid home URL %q", home)
+	}
+	if icon := meta.GetIcon(); icon == "" {
+		add(Info, chartfile, "icon is recommended")
+	} else if !validURL(icon) {
+		add(Error, chartfile, "invalid icon URL %q", icon)
+	}
+
+	var defaults map[string]interface{}
+	if err := yaml.Unmarshal([]byte(ch.GetValues().GetRaw()), &defaults); err != nil {
+		add(Error, valuesfile, "unable to parse YAML: %v", err)
+	}
+
+	if len(ch.GetTemplates()) == 0 {
+		add(Warning, templatesDir, "directory not found")
+		return msgs
+	}
+	for _, t := range ch.GetTemplates() {
+		switch ext := path.Ext(t.GetName()); ext {
+		case ".yaml", ".tpl", ".txt":
+		default:
+			add(Error, t.GetName(), "file extension %q not valid, valid extensions are .yaml, .tpl or .txt", ext)
+		}
+	}
+	if _, err := render.Render(ch, &chart.Config{Raw: string(values)}, opts, caps); err != nil {
+		add(Error, templatesDir, "%v", err)
+	}
+	return msgs
+}
+
+// validURL returns true if s is an absolute http(s) URL
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
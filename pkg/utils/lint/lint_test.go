@@ -0,0 +1,72 @@
+package lint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func TestChart(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{
+			ApiVersion: "v1",
+			Name:       "foo",
+			Version:    "1.0.0",
+			Icon:       "https://example.com/foo.png",
+		},
+		Values: &chart.Config{Raw: "image: nginx\n"},
+		Templates: []*chart.Template{
+			{Name: "templates/deployment.yaml", Data: []byte("apiVersion: apps/v1beta2\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\nspec:\n  image: {{ required \"image is required\" .Values.image }}\n")},
+		},
+	}
+	opts := chartutil.ReleaseOptions{Name: "foo", Namespace: "myns", IsInstall: true}
+	if msgs := Chart(ch, nil, opts, nil); len(msgs) != 0 {
+		t.Errorf("Unexpected messages %v", msgs)
+	}
+
+	ch.Metadata = &chart.Metadata{
+		ApiVersion:  "v1",
+		Name:        "foo",
+		Version:     "1.x",
+		Home:        "example.com",
+		Maintainers: []*chart.Maintainer{{Email: "foo@example.com"}},
+	}
+	ch.Templates = append(ch.Templates, &chart.Template{Name: "templates/service.yml", Data: []byte("kind: Service\n")})
+	msgs := Chart(ch, []byte("image: null\n"), opts, nil)
+	var got []string
+	for _, m := range msgs {
+		got = append(got, m.String())
+	}
+	expected := []string{
+		`[ERROR] Chart.yaml: version "1.x" is not a valid SemVer`,
+		`[ERROR] Chart.yaml: each maintainer requires a name`,
+		`[ERROR] Chart.yaml: invalid home URL "example.com"`,
+		`[INFO] Chart.yaml: icon is recommended`,
+		`[ERROR] templates/service.yml: file extension ".yml" not valid, valid extensions are .yaml, .tpl or .txt`,
+	}
+	if len(got) != len(expected)+1 || !reflect.DeepEqual(got[:len(expected)], expected) {
+		t.Errorf("Expecting messages\n%q\nreceived\n%q", expected, got)
+	}
+	if last := got[len(got)-1]; !strings.HasPrefix(last, `[ERROR] templates/: render error in "foo/templates/deployment.yaml"`) ||
+		!strings.HasSuffix(last, "image is required") {
+		t.Errorf("Expecting a render error, received %q", last)
+	}
+	if !HasErrors(msgs) {
+		t.Errorf("Expecting errors")
+	}
+}
+
+func TestChartWithoutTemplates(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{ApiVersion: "v1", Name: "foo", Version: "0.0.0", Icon: "https://example.com/foo.png"},
+		Values:   &chart.Config{Raw: "image: [nginx\n"},
+	}
+	msgs := Chart(ch, nil, chartutil.ReleaseOptions{Name: "foo"}, nil)
+	if len(msgs) != 3 || msgs[0].Text != "version 0.0.0 is less than or equal to 0" || msgs[1].Path != valuesfile ||
+		msgs[2].Severity != Warning || HasErrors(msgs[2:]) {
+		t.Errorf("Unexpected messages %v", msgs)
+	}
+}
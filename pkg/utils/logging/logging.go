@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a message
+type Level int
+
+// These are the supported levels, in increasing severity
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the Level named s
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q, expecting one of %s", s, strings.Join(levelNames, ", "))
+}
+
+// Format is the encoding of log lines
+type Format string
+
+// These are the supported formats
+const (
+	// TextFormat writes "<time> <level> <message> key=value..." lines
+	TextFormat Format = "text"
+	// JSONFormat writes a JSON object per line, with time, level, msg and
+	// the fields as keys
+	JSONFormat Format = "json"
+)
+
+// ParseFormat returns the Format named s
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case TextFormat, JSONFormat:
+		return f, nil
+	}
+	return TextFormat, fmt.Errorf("unknown log format %q, expecting text or json", s)
+}
+
+// Logger writes leveled messages annotated with key/value fields. It is
+// safe for concurrent use, including the Loggers returned by With.
+type Logger struct {
+	mu  *sync.Mutex
+	out io.Writer
+	// level is shared with the Loggers returned by With, so SetLevel
+	// applies to all of them
+	level  *int32
+	format Format
+	fields []interface{}
+	now    func() time.Time
+}
+
+// New returns a Logger writing messages of at least level to out
+func New(out io.Writer, level Level, format Format) *Logger {
+	l := int32(level)
+	return &Logger{mu: &sync.Mutex{}, out: out, level: &l, format: format, now: time.Now}
+}
+
+// SetLevel changes the minimum level of the messages written by l and the
+// Loggers sharing its output, created by With
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(l.level, int32(level))
+}
+
+// Level returns the minimum level of the messages written
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(l.level))
+}
+
+// With returns a Logger adding the given alternating keys and values to
+// every message
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	child := *l
+	child.fields = make([]interface{}, 0, len(l.fields)+len(keysAndValues))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, keysAndValues...)
+	return &child
+}
+
+// Enabled returns true if messages of level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// Debugf logs a debug message
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(Debug, format, args...) }
+
+// Infof logs an informational message
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(Info, format, args...) }
+
+// Warnf logs a warning
+func (l *Logger) Warnf(format string, args ...interface{}) { l.logf(Warn, format, args...) }
+
+// Errorf logs an error
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(Error, format, args...) }
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	ts := l.now().UTC().Format(time.RFC3339)
+	msg := fmt.Sprintf(format, args...)
+
+	var buf bytes.Buffer
+	if l.format == JSONFormat {
+		entry := map[string]interface{}{}
+		for i := 0; i < len(l.fields); i += 2 {
+			entry[fieldKey(l.fields, i)] = fieldValue(l.fields, i)
+		}
+		entry["time"] = ts
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"time": ts, "level": level.String(), "msg": msg, "logError": err.Error()})
+		}
+		buf.Write(data)
+	} else {
+		fmt.Fprintf(&buf, "%s %s %s", ts, level, msg)
+		for i := 0; i < len(l.fields); i += 2 {
+			value := fmt.Sprint(fieldValue(l.fields, i))
+			if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+				value = fmt.Sprintf("%q", value)
+			}
+			fmt.Fprintf(&buf, " %s=%s", fieldKey(l.fields, i), value)
+		}
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+func fieldKey(fields []interface{}, i int) string {
+	return fmt.Sprint(fields[i])
+}
+
+func fieldValue(fields []interface{}, i int) interface{} {
+	if i+1 >= len(fields) {
+		return "(missing)"
+	}
+	if err, ok := fields[i+1].(error); ok {
+		return err.Error()
+	}
+	return fields[i+1]
+}
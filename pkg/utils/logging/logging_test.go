@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testLogger(level Level, format Format) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := New(&buf, level, format)
+	l.now = func() time.Time { return time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC) }
+	return l, &buf
+}
+
+func TestTextFormat(t *testing.T) {
+	l, buf := testLogger(Info, TextFormat)
+	l.With("release", "myns-foo", "chart", "mariadb").Infof("Installing release into %s", "myns")
+	l.Debugf("not logged")
+	l.With("error", fmt.Errorf("timed out"), "namespace", "").Errorf("Upgrade failed")
+
+	expected := "2018-05-01T10:00:00Z info Installing release into myns release=myns-foo chart=mariadb\n" +
+		"2018-05-01T10:00:00Z error Upgrade failed error=\"timed out\" namespace=\"\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expecting %q received %q", expected, buf.String())
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	l, buf := testLogger(Debug, JSONFormat)
+	l.With("release", "myns-foo", "revision", 2).Debugf("Updated")
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := map[string]interface{}{
+		"time":     "2018-05-01T10:00:00Z",
+		"level":    "debug",
+		"msg":      "Updated",
+		"release":  "myns-foo",
+		"revision": float64(2),
+	}
+	if fmt.Sprint(entry) != fmt.Sprint(expected) {
+		t.Errorf("Expecting %v received %v", expected, entry)
+	}
+}
+
+func TestWithDoesNotShareFields(t *testing.T) {
+	l, buf := testLogger(Info, TextFormat)
+	base := l.With("a", 1)
+	base.With("b", 2).Infof("one")
+	base.With("c", 3).Infof("two")
+	expected := "2018-05-01T10:00:00Z info one a=1 b=2\n2018-05-01T10:00:00Z info two a=1 c=3\n"
+	if buf.String() != expected {
+		t.Errorf("Expecting %q received %q", expected, buf.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	l, buf := testLogger(Info, TextFormat)
+	child := l.With("a", 1)
+	child.Debugf("hidden")
+	l.SetLevel(Debug)
+	child.Debugf("shown")
+	expected := "2018-05-01T10:00:00Z debug shown a=1\n"
+	if buf.String() != expected {
+		t.Errorf("Expecting %q received %q", expected, buf.String())
+	}
+	if child.Level() != Debug {
+		t.Errorf("Expecting the level of child loggers to change, received %s", child.Level())
+	}
+}
+
+func TestParse(t *testing.T) {
+	if l, err := ParseLevel("WARN"); err != nil || l != Warn {
+		t.Errorf("Expecting warn received %v (%v)", l, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expecting an error for an unknown level")
+	}
+	if f, err := ParseFormat("json"); err != nil || f != JSONFormat {
+		t.Errorf("Expecting json received %v (%v)", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("Expecting an error for an unknown format")
+	}
+}
@@ -0,0 +1,262 @@
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Object identifies a Kubernetes object in a rendered manifest
+type Object struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	// Content is the full object as rendered
+	Content map[string]interface{}
+}
+
+type objectHeader struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+var separator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// Objects lists the objects of a multi-document YAML manifest, as
+// rendered by Tiller, in order. Empty documents are skipped.
+func Objects(manifest string) ([]Object, error) {
+	var objs []Object
+	for _, doc := range separator.Split(manifest, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		h := objectHeader{}
+		if err := yaml.Unmarshal([]byte(doc), &h); err != nil {
+			return nil, err
+		}
+		if h.Kind == "" {
+			// Only comments, e.g. templates rendering to nothing
+			continue
+		}
+		content := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &content); err != nil {
+			return nil, err
+		}
+		objs = append(objs, Object{
+			APIVersion: h.APIVersion,
+			Kind:       h.Kind,
+			Namespace:  h.Metadata.Namespace,
+			Name:       h.Metadata.Name,
+			Content:    content,
+		})
+	}
+	return objs, nil
+}
+
+// Split removes the objects matching remove from a multi-document YAML
+// manifest, returning the remaining manifest, with its documents
+// unmodified, and the removed objects in order
+func Split(manifest string, remove func(Object) bool) (string, []Object, error) {
+	var kept []string
+	var removed []Object
+	for _, doc := range separator.Split(manifest, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		objs, err := Objects(doc)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(objs) == 1 && remove(objs[0]) {
+			removed = append(removed, objs[0])
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	if len(kept) == 0 {
+		return "", removed, nil
+	}
+	return "---" + strings.Join(kept, "---"), removed, nil
+}
+
+// Diff returns the paths of the fields set in desired whose value differs
+// in live. Fields only present in live, such as defaults and status
+// filled in by the API server, are ignored.
+func Diff(desired, live map[string]interface{}) []string {
+	return diffValue("", desired, live)
+}
+
+func diffValue(path string, desired, live interface{}) []string {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return []string{path}
+		}
+		var diffs []string
+		for _, k := range sortedKeys(d) {
+			diffs = append(diffs, diffValue(path+"."+k, d[k], l[k])...)
+		}
+		return diffs
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return []string{path}
+		}
+		var diffs []string
+		for i := range d {
+			diffs = append(diffs, diffValue(fmt.Sprintf("%s[%d]", path, i), d[i], l[i])...)
+		}
+		return diffs
+	case nil:
+		// null in a manifest means unset
+		return nil
+	}
+	if !reflect.DeepEqual(desired, live) {
+		return []string{path}
+	}
+	return nil
+}
+
+// LastAppliedAnnotation holds the configuration last applied to an object
+// by kubectl apply
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// LastApplied returns the configuration last applied to live with kubectl
+// apply, nil if none or invalid
+func LastApplied(live map[string]interface{}) map[string]interface{} {
+	metadata, _ := live["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	value, _ := annotations[LastAppliedAnnotation].(string)
+	if value == "" {
+		return nil
+	}
+	var lastApplied map[string]interface{}
+	if err := yaml.Unmarshal([]byte(value), &lastApplied); err != nil {
+		return nil
+	}
+	return lastApplied
+}
+
+// Diff3 is a three-way Diff also returning the paths of the fields set in
+// lastApplied, the configuration last applied to live, that desired
+// doesn't set but are still set in live, e.g. fields added with kubectl
+// apply. Defaults filled in by the API server are still ignored.
+func Diff3(desired, lastApplied, live map[string]interface{}) []string {
+	return append(diffValue("", desired, live), removedFields("", desired, lastApplied, live)...)
+}
+
+func removedFields(path string, desired, lastApplied, live map[string]interface{}) []string {
+	var diffs []string
+	for _, k := range sortedKeys(lastApplied) {
+		if !removable(path+"."+k, live[k]) {
+			continue
+		}
+		d, l, c, nested := nestedMaps(desired[k], lastApplied[k], live[k])
+		switch {
+		case desired[k] == nil:
+			diffs = append(diffs, path+"."+k)
+		case nested:
+			diffs = append(diffs, removedFields(path+"."+k, d, l, c)...)
+		}
+	}
+	return diffs
+}
+
+// RemovalPatch returns a JSON merge patch setting the fields of desired
+// and removing those found by Diff3 that lastApplied set
+func RemovalPatch(desired, lastApplied, live map[string]interface{}) map[string]interface{} {
+	return removalPatch("", desired, lastApplied, live)
+}
+
+func removalPatch(path string, desired, lastApplied, live map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{}, len(desired))
+	for k, v := range desired {
+		patch[k] = v
+	}
+	for k := range lastApplied {
+		if !removable(path+"."+k, live[k]) {
+			continue
+		}
+		d, l, c, nested := nestedMaps(desired[k], lastApplied[k], live[k])
+		switch {
+		case desired[k] == nil:
+			patch[k] = nil
+		case nested:
+			patch[k] = removalPatch(path+"."+k, d, l, c)
+		}
+	}
+	return patch
+}
+
+// removable returns whether the field at path, of value live in the live
+// object, may be removed when no longer desired. The namespace is left
+// out of rendered manifests.
+func removable(path string, live interface{}) bool {
+	return live != nil && path != ".metadata.namespace"
+}
+
+func nestedMaps(desired, lastApplied, live interface{}) (map[string]interface{}, map[string]interface{}, map[string]interface{}, bool) {
+	d, ok1 := desired.(map[string]interface{})
+	l, ok2 := lastApplied.(map[string]interface{})
+	c, ok3 := live.(map[string]interface{})
+	return d, l, c, ok1 && ok2 && ok3
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Changes compares the objects of two manifests, returning the objects of
+// to not in from, those in both with different content, and the objects
+// of from not in to
+func Changes(from, to string) (added, changed, removed []Object, err error) {
+	fromObjs, err := Objects(from)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	toObjs, err := Objects(to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fromByKey := map[string]Object{}
+	for _, obj := range fromObjs {
+		fromByKey[obj.key()] = obj
+	}
+	for _, obj := range toObjs {
+		prev, ok := fromByKey[obj.key()]
+		if !ok {
+			added = append(added, obj)
+			continue
+		}
+		delete(fromByKey, obj.key())
+		if !reflect.DeepEqual(prev.Content, obj.Content) {
+			changed = append(changed, obj)
+		}
+	}
+	for _, obj := range fromObjs {
+		if _, ok := fromByKey[obj.key()]; ok {
+			removed = append(removed, obj)
+		}
+	}
+	return added, changed, removed, nil
+}
+
+func (o Object) key() string {
+	return strings.Join([]string{o.APIVersion, o.Kind, o.Namespace, o.Name}, "/")
+}
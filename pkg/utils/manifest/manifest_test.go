@@ -0,0 +1,237 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestObjects(t *testing.T) {
+	manifest := `
+---
+# Source: foo/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: foo
+---
+# Source: foo/templates/empty.yaml
+---
+# Source: foo/templates/deployment.yaml
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: foo
+  namespace: other
+spec:
+  template:
+    metadata:
+      name: ignored
+`
+	objs, err := Objects(manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []Object{
+		{APIVersion: "v1", Kind: "Secret", Name: "foo"},
+		{APIVersion: "apps/v1beta2", Kind: "Deployment", Namespace: "other", Name: "foo"},
+	}
+	if len(objs) != len(expected) {
+		t.Fatalf("Expecting %d objects received %d", len(expected), len(objs))
+	}
+	for i := range objs {
+		if objs[i].Content["kind"] != expected[i].Kind {
+			t.Errorf("Unexpected content %v", objs[i].Content)
+		}
+		objs[i].Content = nil
+	}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Errorf("Expecting %+v received %+v", expected, objs)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	manifest := `
+---
+# Source: foo/templates/crd.yaml
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+---
+# Source: foo/templates/foo.yaml
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+`
+	kept, removed, err := Split(manifest, func(obj Object) bool { return obj.Kind == "CustomResourceDefinition" })
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(removed) != 1 || removed[0].Name != "foos.example.com" {
+		t.Errorf("Expecting the CRD to be removed, received %+v", removed)
+	}
+	expected := `---
+# Source: foo/templates/foo.yaml
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+`
+	if kept != expected {
+		t.Errorf("Expecting %q received %q", expected, kept)
+	}
+}
+
+func TestObjectsInvalid(t *testing.T) {
+	if _, err := Objects("kind: [foo"); err == nil {
+		t.Errorf("Expected an error for invalid YAML")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired := map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{
+			"replicas": float64(1),
+			"template": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "foo", "image": "foo:1.0"},
+				},
+			},
+			"paused": nil,
+		},
+	}
+	live := map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{
+			"replicas":             float64(3),
+			"revisionHistoryLimit": float64(10),
+			"template": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "foo", "image": "foo:1.0", "imagePullPolicy": "IfNotPresent"},
+				},
+			},
+		},
+		"status": map[string]interface{}{"replicas": float64(3)},
+	}
+	diffs := Diff(desired, live)
+	expected := []string{".spec.replicas"}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expecting %v received %v", expected, diffs)
+	}
+
+	live["spec"].(map[string]interface{})["template"] = map[string]interface{}{"containers": []interface{}{}}
+	diffs = Diff(desired, live)
+	expected = []string{".spec.replicas", ".spec.template.containers"}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expecting %v received %v", expected, diffs)
+	}
+}
+
+func TestDiff3(t *testing.T) {
+	desired := map[string]interface{}{
+		"kind":     "Service",
+		"metadata": map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"type":     "ClusterIP",
+			"selector": map[string]interface{}{"app": "foo"},
+		},
+	}
+	lastApplied := map[string]interface{}{
+		"kind":     "Service",
+		"metadata": map[string]interface{}{"name": "foo", "namespace": "myns"},
+		"spec": map[string]interface{}{
+			"type":                  "ClusterIP",
+			"selector":              map[string]interface{}{"app": "foo", "debug": "true"},
+			"externalTrafficPolicy": "Local",
+			"sessionAffinity":       "ClientIP",
+		},
+	}
+	live := map[string]interface{}{
+		"kind": "Service",
+		"metadata": map[string]interface{}{
+			"name":        "foo",
+			"namespace":   "myns",
+			"annotations": map[string]interface{}{LastAppliedAnnotation: `{"kind":"Service"}`},
+		},
+		"spec": map[string]interface{}{
+			"type":            "ClusterIP",
+			"clusterIP":       "10.0.0.1",
+			"selector":        map[string]interface{}{"app": "foo", "debug": "true"},
+			"sessionAffinity": "ClientIP",
+		},
+	}
+	diffs := Diff3(desired, lastApplied, live)
+	expected := []string{".spec.selector.debug", ".spec.sessionAffinity"}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expecting %v received %v", expected, diffs)
+	}
+	if diffs := Diff3(desired, nil, live); len(diffs) != 0 {
+		t.Errorf("Expecting fields only in live to be ignored without a last applied configuration, received %v", diffs)
+	}
+
+	patch := RemovalPatch(desired, lastApplied, live)
+	expectedPatch := map[string]interface{}{
+		"kind":     "Service",
+		"metadata": map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"type":            "ClusterIP",
+			"selector":        map[string]interface{}{"app": "foo", "debug": nil},
+			"sessionAffinity": nil,
+		},
+	}
+	if !reflect.DeepEqual(patch, expectedPatch) {
+		t.Errorf("Expecting patch %v received %v", expectedPatch, patch)
+	}
+
+	if lastApplied := LastApplied(live); !reflect.DeepEqual(lastApplied, map[string]interface{}{"kind": "Service"}) {
+		t.Errorf("Unexpected last applied configuration %v", lastApplied)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	from := `
+kind: ConfigMap
+metadata:
+  name: kept
+---
+kind: ConfigMap
+metadata:
+  name: changed
+data:
+  foo: bar
+---
+kind: Secret
+metadata:
+  name: removed
+`
+	to := `
+kind: ConfigMap
+metadata:
+  name: kept
+---
+kind: ConfigMap
+metadata:
+  name: changed
+data:
+  foo: baz
+---
+kind: Service
+metadata:
+  name: added
+`
+	added, changed, removed, err := Changes(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(added) != 1 || added[0].Name != "added" {
+		t.Errorf("Unexpected added objects %+v", added)
+	}
+	if len(changed) != 1 || changed[0].Name != "changed" {
+		t.Errorf("Unexpected changed objects %+v", changed)
+	}
+	if len(removed) != 1 || removed[0].Name != "removed" {
+		t.Errorf("Unexpected removed objects %+v", removed)
+	}
+}
@@ -0,0 +1,122 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// TextDiff returns a line diff of the objects changed between two
+// manifests: the lines of each object only in from are prefixed with -,
+// those only in to with +, and the unchanged lines with a space. Objects
+// are compared as YAML with sorted keys, so formatting differences of
+// the templates don't show up.
+func TextDiff(from, to string) (string, error) {
+	added, changed, removed, err := Changes(from, to)
+	if err != nil {
+		return "", err
+	}
+	fromObjs, err := Objects(from)
+	if err != nil {
+		return "", err
+	}
+	fromByKey := map[string]Object{}
+	for _, obj := range fromObjs {
+		fromByKey[obj.key()] = obj
+	}
+
+	out := &bytes.Buffer{}
+	write := func(header string, fromObj, toObj *Object) error {
+		var a, b []string
+		if fromObj != nil {
+			if a, err = yamlLines(fromObj.Content); err != nil {
+				return err
+			}
+		}
+		if toObj != nil {
+			if b, err = yamlLines(toObj.Content); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(out, header)
+		for _, l := range diffLines(a, b) {
+			fmt.Fprintln(out, l)
+		}
+		return nil
+	}
+	for i := range added {
+		if err := write("+++ "+added[i].String(), nil, &added[i]); err != nil {
+			return "", err
+		}
+	}
+	for i := range changed {
+		prev := fromByKey[changed[i].key()]
+		if err := write("~~~ "+changed[i].String(), &prev, &changed[i]); err != nil {
+			return "", err
+		}
+	}
+	for i := range removed {
+		if err := write("--- "+removed[i].String(), &removed[i], nil); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// String identifies o as kind namespace/name, or kind name for cluster
+// or release namespace objects
+func (o Object) String() string {
+	if o.Namespace == "" {
+		return o.Kind + " " + o.Name
+	}
+	return o.Kind + " " + o.Namespace + "/" + o.Name
+}
+
+func yamlLines(content map[string]interface{}) ([]string, error) {
+	out, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"), nil
+}
+
+// diffLines returns the lines of a and b, prefixed with -, + or a space,
+// following their longest common subsequence
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	return lines
+}
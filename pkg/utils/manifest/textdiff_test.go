@@ -0,0 +1,74 @@
+package manifest
+
+import "testing"
+
+func TestTextDiff(t *testing.T) {
+	from := `
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  a: "1"
+  b: "2"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: old
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: same
+`
+	to := `
+---
+apiVersion: v1
+kind: Service
+metadata: {name: same}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  b: "3"
+  a: "1"
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: new
+  namespace: other
+`
+	diff, err := TextDiff(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `+++ ServiceAccount other/new
++apiVersion: v1
++kind: ServiceAccount
++metadata:
++  name: new
++  namespace: other
+~~~ ConfigMap foo
+ apiVersion: v1
+ data:
+   a: "1"
+-  b: "2"
++  b: "3"
+ kind: ConfigMap
+ metadata:
+   name: foo
+--- Secret old
+-apiVersion: v1
+-kind: Secret
+-metadata:
+-  name: old
+`
+	if diff != expected {
+		t.Errorf("Expecting diff\n%s\nreceived\n%s", expected, diff)
+	}
+}
@@ -0,0 +1,312 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// EventType is a HelmRelease lifecycle event
+type EventType string
+
+// These are the published lifecycle events
+const (
+	Installed EventType = "installed"
+	Upgraded  EventType = "upgraded"
+	Failed    EventType = "failed"
+	Deleted   EventType = "deleted"
+)
+
+// Event describes a change of the release of a HelmRelease
+type Event struct {
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Release   string    `json:"release"`
+	Chart     string    `json:"chart,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// String returns a human readable summary of the event
+func (e Event) String() string {
+	s := fmt.Sprintf("HelmRelease %s/%s: release %s %s", e.Namespace, e.Name, e.Release, e.Type)
+	if e.Chart != "" {
+		s += fmt.Sprintf(" (chart %s %s)", e.Chart, e.Version)
+	}
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
+
+// Notifier publishes events
+type Notifier interface {
+	Notify(e Event) error
+}
+
+// Config lists the providers events are published to
+type Config struct {
+	Providers []Provider `json:"providers"`
+}
+
+// Provider is a notification sink
+type Provider struct {
+	// Name identifies the provider in errors
+	Name string `json:"name"`
+	// Type is one of slack, webhook or smtp
+	Type string `json:"type"`
+	// Address is the webhook URL, or the host:port of the SMTP server
+	Address string `json:"address"`
+	// Channel overrides the channel of a Slack webhook
+	Channel string `json:"channel,omitempty"`
+	// From, To, Username and Password configure SMTP mails
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	// Events restricts the published event types. Defaults to all.
+	Events []EventType `json:"events,omitempty"`
+	// Namespaces restricts the HelmRelease namespaces. Defaults to all.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// LoadConfig reads a YAML Config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// defaultTimeout bounds SMTP sessions when client has no timeout
+const defaultTimeout = 10 * time.Second
+
+// New returns a Notifier publishing events to every provider of config
+// accepting them, using client for webhooks. SMTP sessions are bounded by
+// the timeout of client.
+func New(config *Config, client *http.Client) (Notifier, error) {
+	timeout := client.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	n := multiNotifier{}
+	for i, p := range config.Providers {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("providers[%d]", i)
+		}
+		var sink Notifier
+		switch p.Type {
+		case "slack":
+			sink = &slackNotifier{client: client, url: p.Address, channel: p.Channel}
+		case "webhook":
+			sink = &webhookNotifier{client: client, url: p.Address}
+		case "smtp":
+			if p.From == "" || len(p.To) == 0 {
+				return nil, fmt.Errorf("%s: from and to are required", p.Name)
+			}
+			sink = &smtpNotifier{provider: p, timeout: timeout}
+		default:
+			return nil, fmt.Errorf("%s: unknown provider type %q", p.Name, p.Type)
+		}
+		if p.Address == "" {
+			return nil, fmt.Errorf("%s: address is required", p.Name)
+		}
+		n = append(n, filteredNotifier{provider: p, sink: sink})
+	}
+	return n, nil
+}
+
+// Async returns a Notifier queueing up to size events, published by n in
+// the background so that slow sinks don't hold up the caller. Notify only
+// fails when the queue is full, errors publishing events are passed to
+// onError.
+func Async(n Notifier, size int, onError func(Event, error)) Notifier {
+	a := &asyncNotifier{events: make(chan Event, size)}
+	go func() {
+		for e := range a.events {
+			if err := n.Notify(e); err != nil {
+				onError(e, err)
+			}
+		}
+	}()
+	return a
+}
+
+type asyncNotifier struct {
+	events chan Event
+}
+
+func (a *asyncNotifier) Notify(e Event) error {
+	select {
+	case a.events <- e:
+		return nil
+	default:
+		return fmt.Errorf("notification queue is full, dropping the event")
+	}
+}
+
+type multiNotifier []filteredNotifier
+
+// Notify publishes e to every accepting provider, returning the errors of
+// all failed ones
+func (m multiNotifier) Notify(e Event) error {
+	var errs []string
+	for _, n := range m {
+		if !n.accepts(e) {
+			continue
+		}
+		if err := n.sink.Notify(e); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", n.provider.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+type filteredNotifier struct {
+	provider Provider
+	sink     Notifier
+}
+
+func (f filteredNotifier) accepts(e Event) bool {
+	if len(f.provider.Events) > 0 {
+		found := false
+		for _, t := range f.provider.Events {
+			found = found || t == e.Type
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.provider.Namespaces) > 0 {
+		for _, ns := range f.provider.Namespaces {
+			if ns == e.Namespace {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func postJSON(client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	res, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, res.Status)
+	}
+	return nil
+}
+
+// slackNotifier posts to a Slack incoming webhook
+type slackNotifier struct {
+	client  *http.Client
+	url     string
+	channel string
+}
+
+func (s *slackNotifier) Notify(e Event) error {
+	msg := map[string]string{"text": e.String()}
+	if s.channel != "" {
+		msg["channel"] = s.channel
+	}
+	return postJSON(s.client, s.url, msg)
+}
+
+// webhookNotifier posts the JSON encoded event
+type webhookNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func (w *webhookNotifier) Notify(e Event) error {
+	return postJSON(w.client, w.url, e)
+}
+
+// smtpNotifier mails the event
+type smtpNotifier struct {
+	provider Provider
+	timeout  time.Duration
+}
+
+func (s *smtpNotifier) Notify(e Event) error {
+	p := s.provider
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: HelmRelease %s/%s %s\r\n\r\n%s\r\n",
+		p.From, strings.Join(p.To, ", "), e.Namespace, e.Name, e.Type, e)
+	return s.sendMail([]byte(msg))
+}
+
+// sendMail is smtp.SendMail, with the whole session bounded by the timeout
+func (s *smtpNotifier) sendMail(msg []byte) error {
+	p := s.provider
+	host, _, err := net.SplitHostPort(p.Address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", p.Address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if p.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", p.Username, p.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(p.From); err != nil {
+		return err
+	}
+	for _, to := range p.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		received = append(received, body)
+	}))
+	defer server.Close()
+
+	n, err := New(&Config{Providers: []Provider{
+		{Type: "slack", Address: server.URL + "/slack", Channel: "#deploys", Events: []EventType{Failed}},
+		{Type: "webhook", Address: server.URL + "/hook", Namespaces: []string{"myns"}},
+	}}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	e := Event{Type: Installed, Namespace: "myns", Name: "foo", Release: "myns-foo", Chart: "mariadb", Version: "2.0.1"}
+	if err := n.Notify(e); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(received) != 1 || received[0]["path"] != "/hook" || received[0]["type"] != "installed" || received[0]["version"] != "2.0.1" {
+		t.Errorf("Unexpected notifications %v", received)
+	}
+
+	received = nil
+	e = Event{Type: Failed, Namespace: "other", Name: "foo", Release: "other-foo", Message: "timed out"}
+	if err := n.Notify(e); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "HelmRelease other/foo: release other-foo failed: timed out"
+	if len(received) != 1 || received[0]["path"] != "/slack" || received[0]["text"] != expected || received[0]["channel"] != "#deploys" {
+		t.Errorf("Unexpected notifications %v", received)
+	}
+}
+
+func TestNotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n, err := New(&Config{Providers: []Provider{{Name: "hook", Type: "webhook", Address: server.URL}}}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := n.Notify(Event{Type: Deleted}); err == nil {
+		t.Errorf("Expecting an error for a failed webhook")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, p := range []Provider{
+		{Type: "irc", Address: "irc.example.com"},
+		{Type: "webhook"},
+		{Type: "smtp", Address: "smtp.example.com:25"},
+	} {
+		if _, err := New(&Config{Providers: []Provider{p}}, http.DefaultClient); err == nil {
+			t.Errorf("Expecting an error for provider %+v", p)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("providers:\n- type: smtp\n  address: smtp.example.com:25\n  from: helm@example.com\n  to: [ops@example.com]\n  events: [failed]\n")
+	f.Close()
+
+	config, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(config.Providers) != 1 || config.Providers[0].To[0] != "ops@example.com" || config.Providers[0].Events[0] != Failed {
+		t.Errorf("Unexpected config %+v", config)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	errs := make(chan error, 1)
+	n := Async(notifierFunc(func(e Event) error {
+		return fmt.Errorf("unable to publish %s", e.Name)
+	}), 1, func(e Event, err error) {
+		errs <- err
+	})
+	if err := n.Notify(Event{Type: Installed, Name: "foo"}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	select {
+	case err := <-errs:
+		if err.Error() != "unable to publish foo" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expecting the event to be published")
+	}
+
+	// Events are dropped rather than blocking once the queue is full
+	block := make(chan struct{})
+	defer close(block)
+	n = Async(notifierFunc(func(e Event) error {
+		<-block
+		return nil
+	}), 1, func(Event, error) {})
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = n.Notify(Event{Type: Installed})
+	}
+	if err == nil {
+		t.Errorf("Expecting an error once the queue is full")
+	}
+}
+
+func TestSMTPTimeout(t *testing.T) {
+	// The server accepts connections but never greets the client
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	n, err := New(&Config{Providers: []Provider{
+		{Type: "smtp", Address: l.Addr().String(), From: "helm@example.com", To: []string{"ops@example.com"}},
+	}}, &http.Client{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- n.Notify(Event{Type: Failed, Namespace: "myns", Name: "foo"})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expecting a timeout error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expecting the SMTP session to time out")
+	}
+}
+
+type notifierFunc func(Event) error
+
+func (f notifierFunc) Notify(e Event) error {
+	return f(e)
+}
@@ -0,0 +1,291 @@
+// Package oci pulls Helm charts, and the cosign signatures of artifacts,
+// from OCI registries with the distribution API
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Media types of the manifests and layers of Helm charts
+const (
+	ManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	ChartConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	ChartLayerMediaType  = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// maxManifestSize is the size in bytes of the largest manifest read
+	maxManifestSize = 4 << 20
+)
+
+// HTTPClient sends the requests to registries
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Reference is a repository of a registry
+type Reference struct {
+	// Registry is the host, and port, of the registry
+	Registry string
+	// Repository is the name of the repository in the registry
+	Repository string
+}
+
+// ParseReference parses an oci://<registry>/<repository> URL
+func ParseReference(rawURL string) (Reference, error) {
+	if !strings.HasPrefix(rawURL, "oci://") {
+		return Reference{}, fmt.Errorf("%q is not an oci:// URL", rawURL)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(rawURL, "oci://"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.Trim(parts[1], "/") == "" {
+		return Reference{}, fmt.Errorf("%q must be oci://<registry>/<repository>", rawURL)
+	}
+	return Reference{Registry: parts[0], Repository: strings.Trim(parts[1], "/")}, nil
+}
+
+func (r Reference) String() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Descriptor is the content addressed reference of a blob
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// ResponseError is returned for registry responses other than 200 OK
+type ResponseError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// IsNotFound returns whether err is a 404 response of a registry
+func IsNotFound(err error) bool {
+	e, ok := err.(*ResponseError)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Client pulls from a registry, sending Header to it. Its Authorization
+// is exchanged for a bearer token when the registry requires one.
+type Client struct {
+	HTTP   HTTPClient
+	Header http.Header
+	// PlainHTTP sends requests over http rather than https
+	PlainHTTP bool
+
+	token string
+}
+
+// Manifest returns the manifest of the tag or digest of ref, along with
+// its digest
+func (c *Client) Manifest(ref Reference, tagOrDigest string) (*Manifest, string, error) {
+	data, err := c.get(ref, "manifests/"+tagOrDigest, ManifestMediaType, maxManifestSize)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(tagOrDigest, "sha256:") && tagOrDigest != digest {
+		return nil, "", fmt.Errorf("manifest %s@%s has digest %s", ref, tagOrDigest, digest)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest %s:%s: %v", ref, tagOrDigest, err)
+	}
+	return &manifest, digest, nil
+}
+
+// Blob returns the blob of desc in ref, failing for blobs larger than
+// maxSize bytes, unless maxSize is zero, or not matching their digest
+func (c *Client) Blob(ref Reference, desc Descriptor, maxSize int64) ([]byte, error) {
+	if !strings.HasPrefix(desc.Digest, "sha256:") {
+		return nil, fmt.Errorf("unsupported digest %q", desc.Digest)
+	}
+	if maxSize > 0 && desc.Size > maxSize {
+		return nil, fmt.Errorf("blob %s@%s exceeds the maximum size of %d bytes", ref, desc.Digest, maxSize)
+	}
+	data, err := c.get(ref, "blobs/"+desc.Digest, "", maxSize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != desc.Digest {
+		return nil, fmt.Errorf("blob %s@%s has digest %s", ref, desc.Digest, actual)
+	}
+	return data, nil
+}
+
+// ChartLayer returns the chart archive layer of manifest
+func ChartLayer(manifest *Manifest) (Descriptor, error) {
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == ChartLayerMediaType {
+			return layer, nil
+		}
+	}
+	return Descriptor{}, fmt.Errorf("no %s layer, not a Helm chart", ChartLayerMediaType)
+}
+
+func (c *Client) get(ref Reference, path, accept string, maxSize int64) ([]byte, error) {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.Registry, ref.Repository, path)
+	res, err := c.do(u, accept, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, &ResponseError{URL: u, StatusCode: res.StatusCode}
+	}
+	body := io.Reader(res.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(res.Body, maxSize+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("GET %s: response exceeds the maximum size of %d bytes", u, maxSize)
+	}
+	return data, nil
+}
+
+// do sends a GET request of u, answering the bearer token challenge of
+// the registry if any
+func (c *Client) do(u, accept string, ref Reference) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range c.Header {
+			req.Header[name] = values
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		return c.HTTP.Do(req)
+	}
+	res, err := send()
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	challenge := res.Header.Get("WWW-Authenticate")
+	res.Body.Close()
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "Bearer") || params["realm"] == "" {
+		return nil, &ResponseError{URL: u, StatusCode: http.StatusUnauthorized}
+	}
+	if params["scope"] == "" {
+		params["scope"] = "repository:" + ref.Repository + ":pull"
+	}
+	if c.token, err = c.fetchToken(params); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// fetchToken gets a bearer token from the realm of a challenge, sending
+// the Authorization of Header to it
+func (c *Client) fetchToken(params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth := c.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", &ResponseError{URL: realm.Scheme + "://" + realm.Host + realm.Path, StatusCode: res.StatusCode}
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxManifestSize)).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned by %s", realm.Host)
+}
+
+// parseChallenge parses a WWW-Authenticate header of the form
+// <scheme> key="value",key="value"
+func parseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return parts[0], params
+}
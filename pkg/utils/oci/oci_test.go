@@ -0,0 +1,115 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference("oci://registry.example.com:5000/charts/mariadb/")
+	if err != nil || ref.Registry != "registry.example.com:5000" || ref.Repository != "charts/mariadb" {
+		t.Errorf("Unexpected reference %+v %v", ref, err)
+	}
+	for _, u := range []string{"https://registry.example.com/charts/mariadb", "oci://registry.example.com", "oci:///mariadb"} {
+		if _, err := ParseReference(u); err == nil {
+			t.Errorf("Expecting %s to be rejected", u)
+		}
+	}
+}
+
+func TestPull(t *testing.T) {
+	archive := []byte("chart archive")
+	manifest, _ := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		Config:        Descriptor{MediaType: ChartConfigMediaType, Digest: digestOf([]byte("{}")), Size: 2},
+		Layers:        []Descriptor{{MediaType: ChartLayerMediaType, Digest: digestOf(archive), Size: int64(len(archive))}},
+	})
+	var server *httptest.Server
+	tokens := 0
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.Header.Get("Authorization") != "Basic dXNlcjpwYXNz" || r.URL.Query().Get("scope") != "repository:charts/mariadb:pull" || r.URL.Query().Get("service") != "registry" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens++
+			fmt.Fprint(w, `{"token":"t0k3n"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:charts/mariadb:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/charts/mariadb/manifests/2.0.1":
+			if r.Header.Get("Accept") != ManifestMediaType {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Write(manifest)
+		case "/v2/charts/mariadb/blobs/" + digestOf(archive):
+			w.Write(archive)
+		case "/v2/charts/mariadb/blobs/" + digestOf([]byte("other")):
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ref := Reference{Registry: strings.TrimPrefix(server.URL, "http://"), Repository: "charts/mariadb"}
+	c := &Client{HTTP: http.DefaultClient, Header: http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}, PlainHTTP: true}
+	m, digest, err := c.Manifest(ref, "2.0.1")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if digest != digestOf(manifest) {
+		t.Errorf("Expecting digest %s, received %s", digestOf(manifest), digest)
+	}
+	layer, err := ChartLayer(m)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	data, err := c.Blob(ref, layer, 1024)
+	if err != nil || string(data) != string(archive) {
+		t.Errorf("Expecting the chart archive, received %q %v", data, err)
+	}
+	if tokens != 1 {
+		t.Errorf("Expecting the token to be reused, %d fetched", tokens)
+	}
+
+	if _, err := c.Blob(ref, layer, 4); err == nil {
+		t.Errorf("Expecting blobs larger than the maximum size to be rejected")
+	}
+	if _, err := c.Blob(ref, Descriptor{Digest: digestOf([]byte("other"))}, 0); err == nil || !strings.Contains(err.Error(), "has digest") {
+		t.Errorf("Expecting a digest mismatch, received %v", err)
+	}
+	if _, _, err := c.Manifest(ref, "1.0.0"); !IsNotFound(err) {
+		t.Errorf("Expecting a missing tag not to be found, received %v", err)
+	}
+	if _, _, err := (&Client{HTTP: http.DefaultClient, PlainHTTP: true}).Manifest(ref, "2.0.1"); err == nil {
+		t.Errorf("Expecting the registry to require credentials")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:charts/a:pull,push"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.example.com/token" || params["service"] != "registry.example.com" || params["scope"] != "repository:charts/a:pull,push" {
+		t.Errorf("Unexpected challenge %s %v", scheme, params)
+	}
+	if scheme, params := parseChallenge(`Basic realm=registry`); scheme != "Basic" || params["realm"] != "registry" {
+		t.Errorf("Unexpected challenge %s %v", scheme, params)
+	}
+}
@@ -0,0 +1,90 @@
+// Package opa checks rendered releases against Rego policies, evaluated
+// with the Open Policy Agent library
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// Decision is the query of the messages denying a release, defined by
+// the deny rules of package helmcrd
+const Decision = "data.helmcrd.deny"
+
+// Input is the document policies are evaluated against, as input
+type Input struct {
+	// Namespace and Name identify the HelmRelease
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Release is the Tiller release name
+	Release         string `json:"release"`
+	TargetNamespace string `json:"targetNamespace"`
+	Chart           string `json:"chart"`
+	Version         string `json:"version"`
+	// Objects are the rendered objects of the release, including hooks
+	Objects []map[string]interface{} `json:"objects"`
+}
+
+// Evaluator returns the reasons policies deny a release, if any
+type Evaluator interface {
+	Deny(input Input) ([]string, error)
+}
+
+// New compiles the Rego modules by file name into an Evaluator of
+// Decision, which must be a set or array of messages. An undefined
+// decision denies nothing.
+func New(modules map[string]string) (Evaluator, error) {
+	compiler, err := ast.CompileModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	return &policies{compiler: compiler}, nil
+}
+
+type policies struct {
+	compiler *ast.Compiler
+}
+
+func (p *policies) Deny(input Input) ([]string, error) {
+	// Policies see input as the JSON document OPA servers are given
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	rs, err := rego.New(rego.Compiler(p.compiler), rego.Query(Decision), rego.Input(doc)).Eval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate policies: %v", err)
+	}
+
+	var denials []string
+	for _, r := range rs {
+		for _, expr := range r.Expressions {
+			result, ok := expr.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected decision %v, expecting a set of messages", expr.Value)
+			}
+			for _, d := range result {
+				if msg, ok := d.(string); ok {
+					denials = append(denials, msg)
+					continue
+				}
+				msg, err := json.Marshal(d)
+				if err != nil {
+					return nil, err
+				}
+				denials = append(denials, string(msg))
+			}
+		}
+	}
+	sort.Strings(denials)
+	return denials, nil
+}
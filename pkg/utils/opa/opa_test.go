@@ -0,0 +1,74 @@
+package opa
+
+import (
+	"reflect"
+	"testing"
+)
+
+const policy = `package helmcrd
+
+deny[msg] {
+	obj := input.objects[_]
+	obj.spec.volumes[_].hostPath
+	msg := sprintf("%s %s mounts a hostPath volume", [obj.kind, obj.metadata.name])
+}
+
+deny[{"msg": "missing label team", "release": input.release}] {
+	obj := input.objects[_]
+	not obj.metadata.labels.team
+}
+`
+
+func TestDeny(t *testing.T) {
+	e, err := New(map[string]string{"main.rego": policy})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	input := Input{
+		Namespace: "myns",
+		Name:      "foo",
+		Release:   "myns-foo",
+		Objects: []map[string]interface{}{{
+			"kind":     "Pod",
+			"metadata": map[string]interface{}{"name": "foo"},
+			"spec":     map[string]interface{}{"volumes": []interface{}{map[string]interface{}{"hostPath": map[string]interface{}{"path": "/"}}}},
+		}},
+	}
+	denials, err := e.Deny(input)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []string{"Pod foo mounts a hostPath volume", `{"msg":"missing label team","release":"myns-foo"}`}
+	if !reflect.DeepEqual(denials, expected) {
+		t.Errorf("Expecting %v, received %v", expected, denials)
+	}
+
+	input.Objects = []map[string]interface{}{{
+		"kind":     "ConfigMap",
+		"metadata": map[string]interface{}{"name": "foo", "labels": map[string]interface{}{"team": "db"}},
+	}}
+	if denials, err := e.Deny(input); err != nil || len(denials) != 0 {
+		t.Errorf("Expecting no denials, received %v, %v", denials, err)
+	}
+
+	// Undefined decisions deny nothing
+	e, err = New(map[string]string{"other.rego": "package other\n\nallow = true\n"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if denials, err := e.Deny(input); err != nil || len(denials) != 0 {
+		t.Errorf("Expecting no denials, received %v, %v", denials, err)
+	}
+
+	e, err = New(map[string]string{"main.rego": "package helmcrd\n\ndeny = true\n"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := e.Deny(input); err == nil {
+		t.Errorf("Expecting an error for a boolean decision")
+	}
+
+	if _, err := New(map[string]string{"main.rego": "package helmcrd\n\ndeny[msg] {\n"}); err == nil {
+		t.Errorf("Expecting an error for an invalid policy")
+	}
+}
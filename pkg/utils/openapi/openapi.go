@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Schema is the subset of the OpenAPI v3 schema supported by
+// CustomResourceDefinition validation
+type Schema struct {
+	Type          string             `json:"type,omitempty"`
+	Format        string             `json:"format,omitempty"`
+	Pattern       string             `json:"pattern,omitempty"`
+	Enum          []string           `json:"enum,omitempty"`
+	MinLength     *int64             `json:"minLength,omitempty"`
+	MaxLength     *int64             `json:"maxLength,omitempty"`
+	Minimum       *float64           `json:"minimum,omitempty"`
+	MinProperties *int64             `json:"minProperties,omitempty"`
+	MaxProperties *int64             `json:"maxProperties,omitempty"`
+	Required      []string           `json:"required,omitempty"`
+	Properties    map[string]*Schema `json:"properties,omitempty"`
+	Items         *Schema            `json:"items,omitempty"`
+}
+
+var timeType = reflect.TypeOf(metav1.Time{})
+
+// SchemaFor returns the structural schema of a Go type as serialized by
+// encoding/json. Fields without omitempty are marked as required.
+func SchemaFor(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return SchemaFor(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is serialized as a base64 string
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: SchemaFor(t.Elem())}
+	case reflect.Map, reflect.Interface:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(s, t)
+		return s
+	}
+	return &Schema{}
+}
+
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts := parseTag(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && (name == "" || opts["inline"]) {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			addFields(s, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = SchemaFor(f.Type)
+		if !opts["omitempty"] && f.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+// Property returns the schema at the given dot separated property path,
+// or nil if there is none
+func (s *Schema) Property(path string) *Schema {
+	cur := s
+	for _, p := range strings.Split(path, ".") {
+		if cur == nil {
+			return nil
+		}
+		cur = cur.Properties[p]
+	}
+	return cur
+}
+
+// Require marks the given properties as required
+func (s *Schema) Require(names ...string) {
+	for _, n := range names {
+		found := false
+		for _, r := range s.Required {
+			if r == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.Required = append(s.Required, n)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/arschles/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type embedded struct {
+	Inlined string `json:"inlined"`
+}
+
+type nested struct {
+	Flag bool `json:"flag,omitempty"`
+}
+
+type example struct {
+	embedded  `json:",inline"`
+	Name      string            `json:"name"`
+	Count     int32             `json:"count,omitempty"`
+	Size      int64             `json:"size,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Nested    *nested           `json:"nested,omitempty"`
+	Timestamp metav1.Time       `json:"timestamp,omitempty"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func TestSchemaFor(t *testing.T) {
+	s := SchemaFor(reflect.TypeOf(example{}))
+	assert.Equal(t, s.Type, "object", "type")
+	assert.Equal(t, s.Required, []string{"inlined", "name"}, "required")
+	assert.Equal(t, len(s.Properties), 8, "number of properties")
+	assert.Equal(t, s.Property("count").Format, "int32", "int32 format")
+	assert.Equal(t, s.Property("size").Format, "int64", "int64 format")
+	assert.Equal(t, s.Property("tags").Items.Type, "string", "array items")
+	assert.Equal(t, s.Property("labels").Type, "object", "map type")
+	assert.Equal(t, s.Property("nested.flag").Type, "boolean", "nested property")
+	assert.Equal(t, s.Property("timestamp").Format, "date-time", "time format")
+	if s.Property("Ignored") != nil || s.Property("internal") != nil {
+		t.Errorf("Unexpected schema for ignored fields")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	s := &Schema{Required: []string{"foo"}}
+	s.Require("foo", "bar")
+	assert.Equal(t, s.Required, []string{"foo", "bar"}, "required")
+}
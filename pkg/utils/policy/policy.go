@@ -0,0 +1,130 @@
+// Package policy restricts what HelmReleases may deploy
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// Match returns whether s matches the glob pattern, where * matches any
+// sequence of characters, including slashes
+func Match(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+	return re.MatchString(s)
+}
+
+// RepoPolicy restricts the URLs charts may be downloaded from
+type RepoPolicy struct {
+	// Allowed are the patterns of the allowed URLs, all URLs are allowed
+	// if empty
+	Allowed []string
+	// Denied are the patterns of the URLs denied even if allowed
+	Denied []string
+}
+
+// Check returns an error unless repoURL is allowed and not denied. Trailing
+// slashes of URLs and patterns are ignored.
+func (p RepoPolicy) Check(repoURL string) error {
+	u := strings.TrimSuffix(repoURL, "/")
+	for _, pattern := range p.Denied {
+		if Match(strings.TrimSuffix(pattern, "/"), u) {
+			return fmt.Errorf("repository %s is denied by pattern %q", repoURL, pattern)
+		}
+	}
+	if len(p.Allowed) == 0 {
+		return nil
+	}
+	for _, pattern := range p.Allowed {
+		if Match(strings.TrimSuffix(pattern, "/"), u) {
+			return nil
+		}
+	}
+	return fmt.Errorf("repository %s is not allowed, allowed repositories are %s", repoURL, strings.Join(p.Allowed, ", "))
+}
+
+// Release is what a HelmRelease deploys, as checked against the
+// HelmReleasePolicies of its namespace. Empty fields are not known yet,
+// e.g. at admission, and are not checked.
+type Release struct {
+	// RepoURLs are the URLs the chart may be downloaded from
+	RepoURLs        []string
+	ChartName       string
+	ChartVersion    string
+	TargetNamespace string
+	// Inline is true for charts defined in the HelmRelease, which come
+	// from no repository
+	Inline bool
+}
+
+// CheckRelease returns an error unless the HelmReleasePolicy spec allows
+// rel
+func CheckRelease(spec *helmCrdV2.HelmReleasePolicySpec, rel Release) error {
+	if len(spec.Repositories) > 0 {
+		if rel.Inline {
+			return fmt.Errorf("inline charts are not allowed, allowed repositories are %s", strings.Join(spec.Repositories, ", "))
+		}
+		repoPolicy := RepoPolicy{Allowed: spec.Repositories}
+		for _, u := range rel.RepoURLs {
+			if err := repoPolicy.Check(u); err != nil {
+				return err
+			}
+		}
+	}
+	if len(spec.TargetNamespaces) > 0 && rel.TargetNamespace != "" && !matchAny(spec.TargetNamespaces, rel.TargetNamespace) {
+		return fmt.Errorf("target namespace %s is not allowed, allowed namespaces are %s", rel.TargetNamespace, strings.Join(spec.TargetNamespaces, ", "))
+	}
+	if len(spec.Charts) == 0 || rel.ChartName == "" {
+		return nil
+	}
+	nameAllowed := false
+	for _, rule := range spec.Charts {
+		if !Match(rule.Name, rel.ChartName) {
+			continue
+		}
+		nameAllowed = true
+		if rule.Versions == "" || rel.ChartVersion == "" {
+			return nil
+		}
+		ok, err := versionAllowed(rule.Versions, rel.ChartVersion)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	if nameAllowed {
+		return fmt.Errorf("version %s of chart %s is not allowed", rel.ChartVersion, rel.ChartName)
+	}
+	return fmt.Errorf("chart %s is not allowed", rel.ChartName)
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func versionAllowed(versions, version string) (bool, error) {
+	c, err := semver.NewConstraint(versions)
+	if err != nil {
+		return false, fmt.Errorf("invalid version range %q: %v", versions, err)
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("invalid chart version %q: %v", version, err)
+	}
+	return c.Check(v), nil
+}
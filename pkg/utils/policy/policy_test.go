@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"testing"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"https://charts.internal", "https://charts.internal", true},
+		{"https://charts.internal", "https://charts.internal.example.com", false},
+		{"https://charts.internal/*", "https://charts.internal/stable/incubator", true},
+		{"https://*.example.com/charts", "https://a.b.example.com/charts", true},
+		{"https://charts.example.com/stable?", "https://charts.example.com/stable1", false},
+	}
+	for _, tt := range tests {
+		if Match(tt.pattern, tt.s) != tt.expected {
+			t.Errorf("Expecting Match(%q, %q) to be %v", tt.pattern, tt.s, tt.expected)
+		}
+	}
+}
+
+func TestRepoPolicy(t *testing.T) {
+	p := RepoPolicy{
+		Allowed: []string{"https://charts.internal/*", "https://charts.example.com/"},
+		Denied:  []string{"https://charts.internal/incubator*"},
+	}
+	tests := []struct {
+		repoURL string
+		allowed bool
+	}{
+		{"https://charts.internal/stable/", true},
+		{"https://charts.example.com", true},
+		{"https://charts.internal/incubator", false},
+		{"https://kubernetes-charts.storage.googleapis.com", false},
+	}
+	for _, tt := range tests {
+		if err := p.Check(tt.repoURL); (err == nil) != tt.allowed {
+			t.Errorf("Expecting %s to be allowed %v, received %v", tt.repoURL, tt.allowed, err)
+		}
+	}
+	if err := (RepoPolicy{}).Check("https://anything.example.com"); err != nil {
+		t.Errorf("Expecting all repositories to be allowed by default, received %v", err)
+	}
+}
+
+func TestCheckRelease(t *testing.T) {
+	spec := &helmCrdV2.HelmReleasePolicySpec{
+		Charts: []helmCrdV2.ChartRule{
+			{Name: "mysql", Versions: "^1.0.0"},
+			{Name: "redis*"},
+		},
+		Repositories:     []string{"https://charts.internal/*"},
+		TargetNamespaces: []string{"team-*"},
+	}
+	tests := []struct {
+		name    string
+		rel     Release
+		allowed bool
+	}{
+		{"allowed", Release{[]string{"https://charts.internal/stable"}, "mysql", "1.2.0", "team-a", false}, true},
+		{"any version", Release{nil, "redis-ha", "9.0.0", "team-a", false}, true},
+		{"unknown version", Release{nil, "mysql", "", "", false}, true},
+		{"repository", Release{[]string{"https://charts.example.com"}, "mysql", "1.2.0", "team-a", false}, false},
+		{"version", Release{nil, "mysql", "2.0.0", "team-a", false}, false},
+		{"chart", Release{nil, "wordpress", "1.0.0", "team-a", false}, false},
+		{"namespace", Release{nil, "mysql", "1.2.0", "kube-system", false}, false},
+		{"inline", Release{nil, "mysql", "1.2.0", "team-a", true}, false},
+	}
+	for _, tt := range tests {
+		if err := CheckRelease(spec, tt.rel); (err == nil) != tt.allowed {
+			t.Errorf("%s: expecting %v to be allowed %v, received %v", tt.name, tt.rel, tt.allowed, err)
+		}
+	}
+	if err := CheckRelease(&helmCrdV2.HelmReleasePolicySpec{}, Release{nil, "wordpress", "1.0.0", "kube-system", false}); err != nil {
+		t.Errorf("Expecting an empty policy to allow anything, received %v", err)
+	}
+}
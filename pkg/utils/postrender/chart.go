@@ -0,0 +1,43 @@
+package postrender
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// Chart returns a chart whose templates render exactly the given manifest,
+// hooks and notes, so that post-rendered output of rel can be installed by
+// Tiller. The chart metadata of rel is kept.
+func Chart(rel *release.Release, renderedManifest string) *chart.Chart {
+	c := &chart.Chart{}
+	if meta := rel.GetChart().GetMetadata(); meta != nil {
+		c.Metadata = proto.Clone(meta).(*chart.Metadata)
+	}
+	c.Templates = append(c.Templates, &chart.Template{
+		Name: "templates/manifest.yaml",
+		Data: []byte(escape(renderedManifest)),
+	})
+	for i, hook := range rel.GetHooks() {
+		c.Templates = append(c.Templates, &chart.Template{
+			Name: fmt.Sprintf("templates/hook-%d-%s", i, path.Base(hook.Path)),
+			Data: []byte(escape(hook.Manifest)),
+		})
+	}
+	if notes := rel.GetInfo().GetStatus().GetNotes(); notes != "" {
+		c.Templates = append(c.Templates, &chart.Template{
+			Name: "templates/NOTES.txt",
+			Data: []byte(escape(notes)),
+		})
+	}
+	return c
+}
+
+// escape quotes template delimiters so rendered text renders as itself
+func escape(s string) string {
+	return strings.Replace(s, "{{", `{{ "{{" }}`, -1)
+}
@@ -0,0 +1,254 @@
+package postrender
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// Target selects the object a JSON patch applies to. Empty fields match
+// any value.
+type Target struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// JSONPatch is a RFC 6902 patch, in JSON or YAML, applied to its target
+type JSONPatch struct {
+	Target Target
+	Patch  string
+}
+
+type operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from"`
+	Value interface{} `json:"value"`
+}
+
+// ApplyJSONPatches applies JSON patches to the objects of a manifest.
+// Each patch must match exactly one object.
+func ApplyJSONPatches(m string, patches []JSONPatch) (string, error) {
+	objs, err := manifest.Objects(m)
+	if err != nil {
+		return "", err
+	}
+	for i, p := range patches {
+		ops := []operation{}
+		if err := yaml.Unmarshal([]byte(p.Patch), &ops); err != nil {
+			return "", fmt.Errorf("patch %d: %v", i, err)
+		}
+		var matched []*manifest.Object
+		for j := range objs {
+			if p.Target.matches(objs[j]) {
+				matched = append(matched, &objs[j])
+			}
+		}
+		if len(matched) != 1 {
+			return "", fmt.Errorf("patch %d: target matches %d objects", i, len(matched))
+		}
+		for _, op := range ops {
+			content, err := applyOperation(matched[0].Content, op)
+			if err != nil {
+				return "", fmt.Errorf("patch %d: %s %s: %v", i, op.Op, op.Path, err)
+			}
+			obj, ok := content.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("patch %d: %s %s: the object must remain an object", i, op.Op, op.Path)
+			}
+			matched[0].Content = obj
+		}
+	}
+	return Manifest(objs)
+}
+
+func (t Target) matches(obj manifest.Object) bool {
+	group, version := "", obj.APIVersion
+	if i := strings.Index(obj.APIVersion, "/"); i >= 0 {
+		group, version = obj.APIVersion[:i], obj.APIVersion[i+1:]
+	}
+	return (t.Group == "" || t.Group == group) &&
+		(t.Version == "" || t.Version == version) &&
+		(t.Kind == "" || t.Kind == obj.Kind) &&
+		(t.Namespace == "" || t.Namespace == obj.Namespace) &&
+		(t.Name == "" || t.Name == obj.Name)
+}
+
+func applyOperation(doc interface{}, op operation) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return add(doc, op.Path, op.Value)
+	case "remove":
+		doc, _, err := remove(doc, op.Path)
+		return doc, err
+	case "replace":
+		doc, _, err := remove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, op.Value)
+	case "move":
+		doc, value, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, value)
+	case "copy":
+		value, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, deepCopy(value))
+	case "test":
+		value, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, fmt.Errorf("test failed, found %v", value)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation")
+}
+
+// splitPointer returns the unescaped tokens of a JSON pointer
+func splitPointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path string) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(d) {
+				return nil, fmt.Errorf("invalid index %q", t)
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("%q not found", t)
+		}
+	}
+	return doc, nil
+}
+
+// add sets the value at path, returning the updated document since
+// inserting into a list replaces it
+func add(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPath := path[:strings.LastIndex(path, "/")]
+	parent, err := get(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+		return doc, nil
+	case []interface{}:
+		i := len(p)
+		if last != "-" {
+			i, err = strconv.Atoi(last)
+			if err != nil || i < 0 || i > len(p) {
+				return nil, fmt.Errorf("invalid index %q", last)
+			}
+		}
+		list := append(p[:i:i], append([]interface{}{value}, p[i:]...)...)
+		return add(doc, parentPath, list)
+	}
+	return nil, fmt.Errorf("cannot add to %s", parentPath)
+}
+
+// remove deletes the value at path, returning the updated document and
+// the removed value
+func remove(doc interface{}, path string) (interface{}, interface{}, error) {
+	tokens, err := splitPointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	parentPath := path[:strings.LastIndex(path, "/")]
+	parent, err := get(doc, parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		value, ok := p[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("%q not found", last)
+		}
+		delete(p, last)
+		return doc, value, nil
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(p) {
+			return nil, nil, fmt.Errorf("invalid index %q", last)
+		}
+		value := p[i]
+		list := append(p[:i:i], p[i+1:]...)
+		doc, err := add(doc, parentPath, list)
+		if err != nil {
+			return nil, nil, err
+		}
+		return doc, value, nil
+	}
+	return nil, nil, fmt.Errorf("cannot remove from %s", parentPath)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, v := range t {
+			c[i] = deepCopy(v)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,66 @@
+package postrender
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyJSONPatches(t *testing.T) {
+	patches := []JSONPatch{
+		{
+			Target: Target{Group: "apps", Kind: "Deployment", Name: "foo"},
+			Patch: `
+- op: replace
+  path: /spec/template/spec/containers/0/image
+  value: foo:2.0
+- op: add
+  path: /spec/template/spec/containers/0/env/0
+  value: {name: FIRST, value: "1"}
+- op: remove
+  path: /spec/template/spec/containers/0/env/1
+- op: copy
+  from: /metadata/name
+  path: /metadata/labels
+- op: test
+  path: /metadata/labels
+  value: foo
+`,
+		},
+		{
+			Target: Target{Kind: "Service"},
+			Patch:  `[{"op": "add", "path": "/metadata/annotations", "value": {"a~b/c": "d"}}, {"op": "move", "from": "/metadata/annotations/a~0b~1c", "path": "/metadata/moved"}]`,
+		},
+	}
+	out, err := ApplyJSONPatches(testManifest, patches)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, expected := range []string{"image: foo:2.0", "- name: FIRST", "labels: foo", "moved: d"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "name: A") {
+		t.Errorf("Expected env A to be removed:\n%s", out)
+	}
+}
+
+func TestApplyJSONPatchesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch JSONPatch
+	}{
+		{"ambiguous target", JSONPatch{Target: Target{Name: "foo"}, Patch: "[]"}},
+		{"no target", JSONPatch{Target: Target{Kind: "Secret"}, Patch: "[]"}},
+		{"missing path", JSONPatch{Target: Target{Kind: "Service"}, Patch: `[{"op": "remove", "path": "/spec/foo"}]`}},
+		{"failed test", JSONPatch{Target: Target{Kind: "Service"}, Patch: `[{"op": "test", "path": "/metadata/name", "value": "bar"}]`}},
+		{"unknown op", JSONPatch{Target: Target{Kind: "Service"}, Patch: `[{"op": "merge", "path": "/metadata"}]`}},
+		{"root not an object", JSONPatch{Target: Target{Kind: "Service"}, Patch: `[{"op": "add", "path": "", "value": "foo"}]`}},
+		{"root copied from a string", JSONPatch{Target: Target{Kind: "Service"}, Patch: `[{"op": "copy", "from": "/metadata/name", "path": ""}]`}},
+	}
+	for _, tt := range tests {
+		if _, err := ApplyJSONPatches(testManifest, []JSONPatch{tt.patch}); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package postrender
+
+import (
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// SetMetadata sets labels and annotations on every object of a manifest,
+// overriding the rendered ones of the same keys. Pod templates are left
+// unchanged, so that setting metadata does not restart workloads.
+func SetMetadata(m string, labels, annotations map[string]string) (string, error) {
+	objs, err := manifest.Objects(m)
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range objs {
+		metadata, ok := obj.Content["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			obj.Content["metadata"] = metadata
+		}
+		setStrings(metadata, "labels", labels)
+		setStrings(metadata, "annotations", annotations)
+	}
+	return Manifest(objs)
+}
+
+// setStrings merges values into the string map at key of obj
+func setStrings(obj map[string]interface{}, key string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	m, ok := obj[key].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		obj[key] = m
+	}
+	for k, v := range values {
+		m[k] = v
+	}
+}
@@ -0,0 +1,54 @@
+package postrender
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+func TestSetMetadata(t *testing.T) {
+	m := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  labels:
+    app: foo
+    helm.bitnami.com/release: rendered
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    metadata:
+      labels:
+        app: foo
+`
+	result, err := SetMetadata(m, map[string]string{"helm.bitnami.com/release": "foo"}, map[string]string{"helm.bitnami.com/helmrelease": "myns/foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	objs, err := manifest.Objects(result)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("Expecting 2 objects, received %d", len(objs))
+	}
+	expectedLabels := map[string]interface{}{"app": "foo", "helm.bitnami.com/release": "foo"}
+	if labels := objs[0].Content["metadata"].(map[string]interface{})["labels"]; !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("Expecting labels %v, received %v", expectedLabels, labels)
+	}
+	metadata := objs[1].Content["metadata"].(map[string]interface{})
+	if !reflect.DeepEqual(metadata["labels"], map[string]interface{}{"helm.bitnami.com/release": "foo"}) ||
+		!reflect.DeepEqual(metadata["annotations"], map[string]interface{}{"helm.bitnami.com/helmrelease": "myns/foo"}) {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+	template := objs[1].Content["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"]
+	if !reflect.DeepEqual(template, map[string]interface{}{"labels": map[string]interface{}{"app": "foo"}}) {
+		t.Errorf("Expecting the pod template to be unchanged, received %v", template)
+	}
+}
@@ -0,0 +1,208 @@
+package postrender
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/bitnami-labs/helm-crd/pkg/utils/manifest"
+)
+
+// Image overrides the name, tag or digest of container images, as in a
+// kustomization.yaml images entry
+type Image struct {
+	Name    string
+	NewName string
+	NewTag  string
+	Digest  string
+}
+
+// Kustomize applies strategic merge patches and image overrides to the
+// objects of a manifest. Each patch selects its target by kind and
+// metadata.name (and apiVersion and metadata.namespace when set).
+func Kustomize(m string, patches []string, images []Image) (string, error) {
+	objs, err := manifest.Objects(m)
+	if err != nil {
+		return "", err
+	}
+
+	for i, p := range patches {
+		patch := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(p), &patch); err != nil {
+			return "", fmt.Errorf("patch %d: %v", i, err)
+		}
+		target, err := findTarget(objs, patch)
+		if err != nil {
+			return "", fmt.Errorf("patch %d: %v", i, err)
+		}
+		target.Content = StrategicMerge(target.Content, patch)
+	}
+
+	for _, obj := range objs {
+		setImages(obj.Content, images)
+	}
+	return Manifest(objs)
+}
+
+// findTarget returns the object matching the identifying fields of patch
+func findTarget(objs []manifest.Object, patch map[string]interface{}) (*manifest.Object, error) {
+	apiVersion, _ := patch["apiVersion"].(string)
+	kind, _ := patch["kind"].(string)
+	meta, _ := patch["metadata"].(map[string]interface{})
+	name, _ := meta["name"].(string)
+	namespace, _ := meta["namespace"].(string)
+	if kind == "" || name == "" {
+		return nil, fmt.Errorf("kind and metadata.name are required to select the patched object")
+	}
+	for i := range objs {
+		obj := &objs[i]
+		if obj.Kind != kind || obj.Name != name ||
+			(apiVersion != "" && obj.APIVersion != apiVersion) ||
+			(namespace != "" && obj.Namespace != namespace) {
+			continue
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("no %s %s in the rendered manifest", kind, name)
+}
+
+// StrategicMerge merges patch into obj, following the strategic merge
+// patch conventions most used in practice: maps are merged recursively,
+// null removes a field, lists of objects with a name (containers, env,
+// volumes, ...) are merged by name and any other list is replaced.
+func StrategicMerge(obj, patch map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		obj = map[string]interface{}{}
+	}
+	for k, pv := range patch {
+		if pv == nil {
+			delete(obj, k)
+			continue
+		}
+		switch p := pv.(type) {
+		case map[string]interface{}:
+			if o, ok := obj[k].(map[string]interface{}); ok {
+				obj[k] = StrategicMerge(o, p)
+				continue
+			}
+		case []interface{}:
+			if o, ok := obj[k].([]interface{}); ok && namedList(o) && namedList(p) {
+				obj[k] = mergeNamedLists(o, p)
+				continue
+			}
+		}
+		obj[k] = pv
+	}
+	return obj
+}
+
+func namedList(l []interface{}) bool {
+	for _, item := range l {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeNamedLists(obj, patch []interface{}) []interface{} {
+	merged := append([]interface{}{}, obj...)
+	for _, pi := range patch {
+		p := pi.(map[string]interface{})
+		found := false
+		for i, oi := range merged {
+			o := oi.(map[string]interface{})
+			if o["name"] == p["name"] {
+				merged[i] = StrategicMerge(o, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// setImages applies the image overrides to every container of obj
+func setImages(obj interface{}, images []Image) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if k == "containers" || k == "initContainers" {
+				if containers, ok := v.([]interface{}); ok {
+					for _, c := range containers {
+						if container, ok := c.(map[string]interface{}); ok {
+							if image, ok := container["image"].(string); ok {
+								container["image"] = overrideImage(image, images)
+							}
+						}
+					}
+				}
+			}
+			setImages(v, images)
+		}
+	case []interface{}:
+		for _, v := range o {
+			setImages(v, images)
+		}
+	}
+}
+
+func overrideImage(image string, images []Image) string {
+	name, tag, digest := splitImage(image)
+	for _, img := range images {
+		if img.Name != name {
+			continue
+		}
+		if img.NewName != "" {
+			name = img.NewName
+		}
+		if img.NewTag != "" {
+			tag, digest = img.NewTag, ""
+		}
+		if img.Digest != "" {
+			tag, digest = "", img.Digest
+		}
+		break
+	}
+	switch {
+	case digest != "":
+		return name + "@" + digest
+	case tag != "":
+		return name + ":" + tag
+	}
+	return name
+}
+
+// splitImage splits an image reference into name, tag and digest
+func splitImage(image string) (string, string, string) {
+	name, tag, digest := image, "", ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	// A colon after the last slash separates the tag, before it a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+// Manifest serializes objects back into a multi-document manifest
+func Manifest(objs []manifest.Object) (string, error) {
+	docs := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj.Content)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, "---\n"+string(data))
+	}
+	return strings.Join(docs, ""), nil
+}
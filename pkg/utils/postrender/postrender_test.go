@@ -0,0 +1,148 @@
+package postrender
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+const testManifest = `---
+# Source: foo/templates/deployment.yaml
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      containers:
+      - name: foo
+        image: registry.example.com:5000/foo:1.0
+        env:
+        - name: A
+          value: a
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+`
+
+func TestKustomize(t *testing.T) {
+	patches := []string{`
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+      containers:
+      - name: foo
+        env:
+        - name: B
+          value: b
+      - name: sidecar
+        image: proxy:2.0
+`}
+	images := []Image{
+		{Name: "registry.example.com:5000/foo", NewTag: "1.1"},
+		{Name: "proxy", NewName: "mirror.example.com/proxy"},
+	}
+	out, err := Kustomize(testManifest, patches, images)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := `---
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: A
+          value: a
+        - name: B
+          value: b
+        image: registry.example.com:5000/foo:1.1
+        name: foo
+      - image: mirror.example.com/proxy:2.0
+        name: sidecar
+      nodeSelector:
+        disk: ssd
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+`
+	if out != expected {
+		t.Errorf("Expecting:\n%s\nreceived:\n%s", expected, out)
+	}
+}
+
+func TestKustomizeMissingTarget(t *testing.T) {
+	patches := []string{"kind: Deployment\nmetadata:\n  name: bar\n"}
+	if _, err := Kustomize(testManifest, patches, nil); err == nil {
+		t.Errorf("Expected an error for a patch without target")
+	}
+}
+
+func TestStrategicMergeDelete(t *testing.T) {
+	obj := map[string]interface{}{}
+	yaml.Unmarshal([]byte("spec:\n  replicas: 2\n  paused: true\n"), &obj)
+	patch := map[string]interface{}{}
+	yaml.Unmarshal([]byte("spec:\n  paused: null\n"), &patch)
+	out, _ := yaml.Marshal(StrategicMerge(obj, patch))
+	if string(out) != "spec:\n  replicas: 2\n" {
+		t.Errorf("Unexpected merge result %s", out)
+	}
+}
+
+func TestOverrideImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		images   []Image
+		expected string
+	}{
+		{"foo", []Image{{Name: "foo", NewTag: "2"}}, "foo:2"},
+		{"foo:1@sha256:abc", []Image{{Name: "foo", NewTag: "2"}}, "foo:2"},
+		{"foo:1", []Image{{Name: "foo", Digest: "sha256:def"}}, "foo@sha256:def"},
+		{"bar:1", []Image{{Name: "foo", NewTag: "2"}}, "bar:1"},
+		{"localhost:5000/foo", []Image{{Name: "localhost:5000/foo", NewName: "foo"}}, "foo"},
+	}
+	for _, tt := range tests {
+		if res := overrideImage(tt.image, tt.images); res != tt.expected {
+			t.Errorf("Expecting %s for %s received %s", tt.expected, tt.image, res)
+		}
+	}
+}
+
+func TestChart(t *testing.T) {
+	rel := &release.Release{
+		Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"}},
+		Hooks: []*release.Hook{{Path: "foo/templates/job.yaml", Manifest: "kind: Job\n"}},
+		Info:  &release.Info{Status: &release.Status{Notes: "Run {{ .Release.Name }}"}},
+	}
+	c := Chart(rel, "kind: ConfigMap\n")
+	if c.Metadata.Version != "1.0.0" {
+		t.Errorf("Expected chart metadata to be kept received %+v", c.Metadata)
+	}
+	if len(c.Templates) != 3 {
+		t.Fatalf("Expected manifest, hook and notes templates received %d", len(c.Templates))
+	}
+	if c.Templates[1].Name != "templates/hook-0-job.yaml" {
+		t.Errorf("Unexpected hook template %s", c.Templates[1].Name)
+	}
+	if notes := string(c.Templates[2].Data); notes != `Run {{ "{{" }} .Release.Name }}` {
+		t.Errorf("Expected template delimiters to be escaped received %s", notes)
+	}
+}
@@ -0,0 +1,72 @@
+// Package releasename computes the names of the releases of HelmReleases
+// that do not set one
+package releasename
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"text/template"
+)
+
+// MaxLen is the maximum length of a release name accepted by Tiller
+const MaxLen = 53
+
+// DefaultTemplate is the template of the release name of HelmReleases
+// that set neither a release name nor a template
+const DefaultTemplate = "{{ .Namespace }}-{{ .Name }}"
+
+// shortHashLen is the number of hex digits of ShortHash
+const shortHashLen = 8
+
+// Vars are the variables available to release name templates
+type Vars struct {
+	// Namespace is the namespace of the HelmRelease
+	Namespace string
+	// Name is the name of the HelmRelease
+	Name string
+	// ShortHash is a hash of the namespace and name of the HelmRelease
+	ShortHash string
+}
+
+// ShortHash returns a short hash identifying the HelmRelease namespace/name
+func ShortHash(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	return hex.EncodeToString(sum[:])[:shortHashLen]
+}
+
+// Parse parses a release name template
+func Parse(tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	return template.New("releaseName").Option("missingkey=error").Parse(tmpl)
+}
+
+// Render returns the release name of the HelmRelease namespace/name
+// rendered from tmpl, or from DefaultTemplate if empty. Names longer
+// than MaxLen are truncated and suffixed with the ShortHash so that they
+// remain unique.
+func Render(tmpl, namespace, name string) (string, error) {
+	t, err := Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	vars := Vars{Namespace: namespace, Name: name, ShortHash: ShortHash(namespace, name)}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return Truncate(strings.TrimSpace(buf.String()), vars.ShortHash), nil
+}
+
+// Truncate shortens names longer than MaxLen, replacing their end with
+// hash
+func Truncate(name, hash string) string {
+	if len(name) <= MaxLen {
+		return name
+	}
+	prefix := strings.TrimRight(name[:MaxLen-len(hash)-1], "-.")
+	return prefix + "-" + hash
+}
@@ -0,0 +1,51 @@
+package releasename
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	tests := []struct {
+		tmpl, namespace, name string
+		expected              string
+	}{
+		{"", "default", "foo", "default-foo"},
+		{"{{ .Name }}", "default", "foo", "foo"},
+		{"{{ .Name }}-{{ .ShortHash }}", "default", "foo", "foo-" + ShortHash("default", "foo")},
+		{"", "default", long, "default-" + strings.Repeat("a", MaxLen-len("default-")-9) + "-" + ShortHash("default", long)},
+		// Separators are not left before the hash
+		{"{{ .Namespace }}--{{ .Name }}", strings.Repeat("b", 43), "foobarbaz", strings.Repeat("b", 43) + "-" + ShortHash(strings.Repeat("b", 43), "foobarbaz")},
+	}
+	for _, tt := range tests {
+		name, err := Render(tt.tmpl, tt.namespace, tt.name)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.tmpl, err)
+			continue
+		}
+		if name != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.tmpl, tt.expected, name)
+		}
+		if len(name) > MaxLen {
+			t.Errorf("%q: %q is longer than %d", tt.tmpl, name, MaxLen)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, tmpl := range []string{"{{ .Name", "{{ .Release }}"} {
+		if _, err := Render(tmpl, "default", "foo"); err == nil {
+			t.Errorf("%q: expected an error", tmpl)
+		}
+	}
+}
+
+func TestShortHash(t *testing.T) {
+	if ShortHash("a", "b-c") == ShortHash("a-b", "c") {
+		t.Errorf("expected different hashes")
+	}
+	if len(ShortHash("default", "foo")) != shortHashLen {
+		t.Errorf("unexpected hash length")
+	}
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// Capabilities returns the capabilities charts are rendered with for a
+// cluster of kubeVersion, e.g. "1.12" or "v1.12.3", serving apiVersions
+// besides "v1". Either defaults to the capabilities Tiller assumes
+// without a cluster.
+func Capabilities(kubeVersion string, apiVersions []string) (*chartutil.Capabilities, error) {
+	caps := &chartutil.Capabilities{
+		APIVersions: chartutil.DefaultVersionSet,
+		KubeVersion: chartutil.DefaultKubeVersion,
+	}
+	if kubeVersion != "" {
+		v, err := semver.NewVersion(kubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version %q: %v", kubeVersion, err)
+		}
+		caps.KubeVersion = &version.Info{
+			Major:      strconv.FormatInt(v.Major(), 10),
+			Minor:      strconv.FormatInt(v.Minor(), 10),
+			GitVersion: "v" + v.String(),
+			GoVersion:  runtime.Version(),
+			Compiler:   runtime.Compiler,
+			Platform:   fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+		}
+	}
+	if len(apiVersions) > 0 {
+		caps.APIVersions = chartutil.NewVersionSet(append([]string{"v1"}, apiVersions...)...)
+	}
+	return caps, nil
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func TestCapabilities(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "foo"},
+		Templates: []*chart.Template{{Name: "templates/cm.yaml", Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  minor: {{ .Capabilities.KubeVersion.Minor | quote }}
+  version: {{ .Capabilities.KubeVersion.GitVersion | quote }}
+  apps: {{ .Capabilities.APIVersions.Has "apps/v1" | quote }}
+`)}},
+	}
+	tests := []struct {
+		kubeVersion string
+		apiVersions []string
+		expected    []string
+	}{
+		{"", nil, []string{`minor: "9"`, `version: "v1.9.0"`, `apps: "false"`}},
+		{"1.12", nil, []string{`minor: "12"`, `version: "v1.12.0"`, `apps: "false"`}},
+		{"v1.12.3", []string{"apps/v1"}, []string{`minor: "12"`, `version: "v1.12.3"`, `apps: "true"`}},
+	}
+	for _, tt := range tests {
+		caps, err := Capabilities(tt.kubeVersion, tt.apiVersions)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		res, err := Render(ch, nil, chartutil.ReleaseOptions{Name: "rls"}, caps)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		for _, e := range tt.expected {
+			if !strings.Contains(res.Manifest, e) {
+				t.Errorf("Expecting %s for %q %v, received %s", e, tt.kubeVersion, tt.apiVersions, res.Manifest)
+			}
+		}
+	}
+
+	if _, err := Capabilities("one.twelve", nil); err == nil {
+		t.Errorf("Expecting an error for an invalid version")
+	}
+}
@@ -0,0 +1,368 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/helm/pkg/chartutil"
+)
+
+// funcMap returns the functions available to templates, but include and
+// tpl, which depend on the template being rendered
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		// chartutil
+		"toYaml":   chartutil.ToYaml,
+		"fromYaml": chartutil.FromYaml,
+		"toJson":   chartutil.ToJson,
+		"fromJson": chartutil.FromJson,
+		"toToml":   chartutil.ToToml,
+
+		// Flow control
+		"required": required,
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		"fail":     func(msg string) (string, error) { return "", errors.New(msg) },
+
+		// Strings
+		"quote":      quote,
+		"squote":     squote,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      strings.Title,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"trunc":      trunc,
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+		"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"cat":        cat,
+		"join":       join,
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"toString":   toString,
+		"toStrings":  toStrings,
+		"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":     b64dec,
+		"sha256sum":  func(s string) string { sum := sha256.Sum256([]byte(s)); return hex.EncodeToString(sum[:]) },
+
+		// Numbers
+		"int":     func(v interface{}) int { return int(toInt64(v)) },
+		"int64":   toInt64,
+		"float64": toFloat64,
+		"add":     func(a, b interface{}) int64 { return toInt64(a) + toInt64(b) },
+		"sub":     func(a, b interface{}) int64 { return toInt64(a) - toInt64(b) },
+		"mul":     func(a, b interface{}) int64 { return toInt64(a) * toInt64(b) },
+		"div":     func(a, b interface{}) int64 { return toInt64(a) / toInt64(b) },
+		"mod":     func(a, b interface{}) int64 { return toInt64(a) % toInt64(b) },
+		"max":     maxInt,
+		"min":     minInt,
+
+		// Lists and dictionaries
+		"list":   func(v ...interface{}) []interface{} { return v },
+		"first":  first,
+		"last":   last,
+		"has":    has,
+		"dict":   dict,
+		"set":    func(d map[string]interface{}, k string, v interface{}) map[string]interface{} { d[k] = v; return d },
+		"unset":  func(d map[string]interface{}, k string) map[string]interface{} { delete(d, k); return d },
+		"hasKey": func(d map[string]interface{}, k string) bool { _, ok := d[k]; return ok },
+		"keys":   keys,
+		"pluck":  pluck,
+
+		// Types and versions
+		"kindIs":        func(kind string, v interface{}) bool { return kindOf(v) == kind },
+		"kindOf":        kindOf,
+		"typeOf":        func(v interface{}) string { return fmt.Sprintf("%T", v) },
+		"semverCompare": semverCompare,
+	}
+}
+
+func required(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+// empty returns whether v is the zero value of its type, an empty
+// collection or nil
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// defaultValue returns d when the piped value is missing or empty
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, val := range v {
+		if !empty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+func ternary(vt, vf interface{}, cond bool) interface{} {
+	if cond {
+		return vt
+	}
+	return vf
+}
+
+func quote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, strconv.Quote(toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, "'"+toString(s)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func trunc(n int, s string) string {
+	if n >= 0 && len(s) > n {
+		return s[:n]
+	}
+	if n < 0 && len(s) > -n {
+		return s[len(s)+n:]
+	}
+	return s
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func cat(v ...interface{}) string {
+	var out []string
+	for _, s := range v {
+		if s != nil {
+			out = append(out, toString(s))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func join(sep string, v interface{}) string {
+	return strings.Join(toStrings(v), sep)
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func toStrings(v interface{}) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []string{toString(v)}
+	}
+	out := make([]string, rv.Len())
+	for i := range out {
+		out[i] = toString(rv.Index(i).Interface())
+	}
+	return out
+}
+
+func toInt64(v interface{}) int64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(rv.Float())
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1
+		}
+	case reflect.String:
+		i, _ := strconv.ParseInt(rv.String(), 0, 64)
+		return i
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		f, _ := strconv.ParseFloat(rv.String(), 64)
+		return f
+	}
+	return float64(toInt64(v))
+}
+
+func maxInt(a interface{}, i ...interface{}) int64 {
+	m := toInt64(a)
+	for _, b := range i {
+		if v := toInt64(b); v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func minInt(a interface{}, i ...interface{}) int64 {
+	m := toInt64(a)
+	for _, b := range i {
+		if v := toInt64(b); v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func first(list interface{}) interface{} {
+	rv := reflect.ValueOf(list)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Len() == 0 {
+		return nil
+	}
+	return rv.Index(0).Interface()
+}
+
+func last(list interface{}) interface{} {
+	rv := reflect.ValueOf(list)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Len() == 0 {
+		return nil
+	}
+	return rv.Index(rv.Len() - 1).Interface()
+}
+
+func has(needle, list interface{}) bool {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if reflect.DeepEqual(rv.Index(i).Interface(), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func dict(v ...interface{}) map[string]interface{} {
+	d := map[string]interface{}{}
+	for i := 0; i+1 < len(v); i += 2 {
+		d[toString(v[i])] = v[i+1]
+	}
+	return d
+}
+
+func keys(dicts ...map[string]interface{}) []string {
+	var out []string
+	for _, d := range dicts {
+		for k := range d {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func pluck(key string, dicts ...map[string]interface{}) []interface{} {
+	var out []interface{}
+	for _, d := range dicts {
+		if v, ok := d[key]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func kindOf(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "invalid"
+	}
+	return rv.Kind().String()
+}
+
+func semverCompare(constraint, version string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
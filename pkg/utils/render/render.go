@@ -0,0 +1,254 @@
+// Package render renders charts locally, the way Tiller renders them on
+// install and upgrade, so that releases can be managed without Tiller.
+//
+// The template functions are those of text/template and chartutil plus
+// the commonly used subset of the Sprig library, which isn't vendored.
+// Charts calling other functions fail to render.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+const (
+	notesFileSuffix = "NOTES.txt"
+	// hookAnnotation marks the objects Helm runs as hooks
+	hookAnnotation       = "helm.sh/hook"
+	hookWeightAnnotation = "helm.sh/hook-weight"
+)
+
+var separator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// Result is a rendered chart
+type Result struct {
+	// Manifest holds the objects of the release, in the format of
+	// release.Release manifests
+	Manifest string
+	// Hooks are the objects annotated as hooks, which are not part of
+	// the manifest
+	Hooks []*release.Hook
+	// Notes are the rendered NOTES.txt of the chart
+	Notes string
+}
+
+type renderable struct {
+	tpl      string
+	vals     chartutil.Values
+	basePath string
+}
+
+// Render renders a chart and its dependencies with the given values.
+// caps defaults to the capabilities Tiller assumes without a cluster.
+func Render(ch *chart.Chart, values *chart.Config, opts chartutil.ReleaseOptions, caps *chartutil.Capabilities) (*Result, error) {
+	if values == nil {
+		values = &chart.Config{}
+	}
+	if caps == nil {
+		caps = &chartutil.Capabilities{
+			APIVersions: chartutil.DefaultVersionSet,
+			KubeVersion: chartutil.DefaultKubeVersion,
+		}
+	}
+	if err := chartutil.ProcessRequirementsEnabled(ch, values); err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessRequirementsImportValues(ch); err != nil {
+		return nil, err
+	}
+	vals, err := chartutil.ToRenderValuesCaps(ch, values, opts, caps)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]renderable{}
+	allTemplates(ch, vals, true, "", templates)
+	files, err := renderTemplates(templates)
+	if err != nil {
+		return nil, err
+	}
+	return splitFiles(ch.GetMetadata().GetName(), files)
+}
+
+// allTemplates collects the templates of a chart and its dependencies,
+// with the values each of them is rendered with: dependencies see their
+// own section of the values and the globals
+func allTemplates(ch *chart.Chart, parentVals chartutil.Values, top bool, parentID string, templates map[string]renderable) {
+	vals := parentVals
+	if !top {
+		vals = chartutil.Values{
+			"Values":       chartutil.Values{},
+			"Release":      parentVals["Release"],
+			"Chart":        ch.Metadata,
+			"Files":        chartutil.NewFiles(ch.Files),
+			"Capabilities": parentVals["Capabilities"],
+		}
+		if parentValues, err := parentVals.Table("Values"); err == nil {
+			if chartValues, err := parentValues.Table(ch.GetMetadata().GetName()); err == nil {
+				vals["Values"] = chartValues
+			}
+		}
+	}
+
+	id := ch.GetMetadata().GetName()
+	if parentID != "" {
+		id = path.Join(parentID, "charts", id)
+	}
+	for _, dep := range ch.Dependencies {
+		allTemplates(dep, vals, false, id, templates)
+	}
+	for _, t := range ch.Templates {
+		templates[path.Join(id, t.Name)] = renderable{
+			tpl:      string(t.Data),
+			vals:     vals,
+			basePath: path.Join(id, "templates"),
+		}
+	}
+}
+
+// renderTemplates executes the templates, returning the output of all
+// but the partials, whose name starts with an underscore
+func renderTemplates(templates map[string]renderable) (map[string]string, error) {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := template.New("chart").Option("missingkey=zero")
+	funcs := funcMap()
+	// include and tpl need the template set being rendered
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		buf := &bytes.Buffer{}
+		if err := t.ExecuteTemplate(buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	funcs["tpl"] = func(tpl string, vals interface{}) (string, error) {
+		clone, err := t.Clone()
+		if err != nil {
+			return "", err
+		}
+		inline, err := clone.New("tpl").Parse(tpl)
+		if err != nil {
+			return "", fmt.Errorf("tpl: %v", err)
+		}
+		buf := &bytes.Buffer{}
+		if err := inline.Execute(buf, vals); err != nil {
+			return "", fmt.Errorf("tpl: %v", err)
+		}
+		return buf.String(), nil
+	}
+	t.Funcs(funcs)
+
+	for _, name := range names {
+		if _, err := t.New(name).Parse(templates[name].tpl); err != nil {
+			return nil, fmt.Errorf("parse error in %q: %v", name, err)
+		}
+	}
+
+	files := map[string]string{}
+	for _, name := range names {
+		if strings.HasPrefix(path.Base(name), "_") {
+			continue
+		}
+		r := templates[name]
+		vals := chartutil.Values{}
+		for k, v := range r.vals {
+			vals[k] = v
+		}
+		vals["Template"] = map[string]interface{}{"Name": name, "BasePath": r.basePath}
+		buf := &bytes.Buffer{}
+		if err := t.ExecuteTemplate(buf, name, vals); err != nil {
+			return nil, fmt.Errorf("render error in %q: %v", name, err)
+		}
+		files[name] = strings.Replace(buf.String(), "<no value>", "", -1)
+	}
+	return files, nil
+}
+
+// splitFiles separates the notes of the chart and the hooks from the
+// objects of the rendered files
+func splitFiles(chartName string, files map[string]string) (*Result, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := &Result{}
+	manifest := &bytes.Buffer{}
+	for _, name := range names {
+		if strings.HasSuffix(name, notesFileSuffix) {
+			// Only the notes of the chart itself are shown
+			if name == path.Join(chartName, "templates", notesFileSuffix) {
+				res.Notes = files[name]
+			}
+			continue
+		}
+		for _, doc := range separator.Split(files[name], -1) {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+			hook, err := parseHook(name, doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			if hook != nil {
+				res.Hooks = append(res.Hooks, hook)
+				continue
+			}
+			fmt.Fprintf(manifest, "---\n# Source: %s\n%s\n", name, strings.Trim(doc, "\n"))
+		}
+	}
+	res.Manifest = manifest.String()
+	return res, nil
+}
+
+type hookHeader struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// parseHook returns the hook declared by a document, nil if it is not one
+func parseHook(path, doc string) (*release.Hook, error) {
+	h := hookHeader{}
+	if err := yaml.Unmarshal([]byte(doc), &h); err != nil {
+		return nil, err
+	}
+	events, ok := h.Metadata.Annotations[hookAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	hook := &release.Hook{
+		Name:     h.Metadata.Name,
+		Kind:     h.Kind,
+		Path:     path,
+		Manifest: doc,
+	}
+	for _, event := range strings.Split(events, ",") {
+		event = strings.ToUpper(strings.Replace(strings.TrimSpace(event), "-", "_", -1))
+		if code, ok := release.Hook_Event_value[event]; ok {
+			hook.Events = append(hook.Events, release.Hook_Event(code))
+		}
+	}
+	if weight, err := strconv.Atoi(h.Metadata.Annotations[hookWeightAnnotation]); err == nil {
+		hook.Weight = int32(weight)
+	}
+	return hook, nil
+}
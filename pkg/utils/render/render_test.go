@@ -0,0 +1,197 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+func testChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "foo", Version: "1.0.0"},
+		Values:   &chart.Config{Raw: "replicas: 1\nimage: nginx\nbar:\n  port: 80\nglobal:\n  env: test\n"},
+		Templates: []*chart.Template{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{- define "foo.fullname" -}}{{ .Release.Name }}-{{ .Chart.Name | trunc 63 }}{{- end -}}`)},
+			{Name: "templates/deployment.yaml", Data: []byte(`apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: {{ include "foo.fullname" . }}
+  labels: {{- dict "app" .Chart.Name "env" .Values.global.env | toYaml | trim | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicas }}
+  template:
+    spec:
+      containers:
+      - name: foo
+        image: {{ required "image is required" .Values.image | quote }}
+        args: {{- list "--port" (add .Values.bar.port 1 | toString) | toJson | nindent 10 }}
+`)},
+			{Name: "templates/job.yaml", Data: []byte(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    "helm.sh/hook": pre-install,pre-upgrade
+    "helm.sh/hook-weight": "-5"
+`)},
+			{Name: "templates/empty.yaml", Data: []byte(`{{- if .Values.ingress }}
+apiVersion: extensions/v1beta1
+kind: Ingress
+{{- end }}`)},
+			{Name: "templates/NOTES.txt", Data: []byte(`Installed {{ .Release.Name }} in {{ .Release.Namespace }}`)},
+		},
+		Dependencies: []*chart.Chart{{
+			Metadata: &chart.Metadata{Name: "bar", Version: "0.1.0"},
+			Values:   &chart.Config{Raw: "port: 8080\n"},
+			Templates: []*chart.Template{
+				{Name: "templates/service.yaml", Data: []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+  labels:
+    env: {{ .Values.global.env }}
+spec:
+  ports:
+  - port: {{ .Values.port }}
+`)},
+				{Name: "templates/NOTES.txt", Data: []byte(`Not shown`)},
+			},
+		}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	opts := chartutil.ReleaseOptions{Name: "rls", Namespace: "myns", IsInstall: true, Revision: 1}
+	res, err := Render(testChart(), &chart.Config{Raw: "replicas: 3\n"}, opts, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectedManifest := `---
+# Source: foo/charts/bar/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: rls-bar
+  labels:
+    env: test
+spec:
+  ports:
+  - port: 80
+---
+# Source: foo/templates/deployment.yaml
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: rls-foo
+  labels:
+    app: foo
+    env: test
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: foo
+        image: "nginx"
+        args:
+          ["--port","81"]
+`
+	if res.Manifest != expectedManifest {
+		t.Errorf("Expecting manifest\n%s\nreceived\n%s", expectedManifest, res.Manifest)
+	}
+	if res.Notes != "Installed rls in myns" {
+		t.Errorf("Unexpected notes %q", res.Notes)
+	}
+	if len(res.Hooks) != 1 {
+		t.Fatalf("Expecting a hook, received %v", res.Hooks)
+	}
+	hook := res.Hooks[0]
+	if hook.Name != "migrate" || hook.Kind != "Job" || hook.Path != "foo/templates/job.yaml" || hook.Weight != -5 {
+		t.Errorf("Unexpected hook %+v", hook)
+	}
+	if len(hook.Events) != 2 || hook.Events[0] != release.Hook_PRE_INSTALL || hook.Events[1] != release.Hook_PRE_UPGRADE {
+		t.Errorf("Unexpected hook events %v", hook.Events)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		tpl string
+		err string
+	}{
+		{`{{ required "image is required" .Values.missing }}`, "image is required"},
+		{`{{ .Values.image | sprigOnly }}`, `function "sprigOnly" not defined`},
+		{`{{ include "missing" . }}`, `no template "missing"`},
+	}
+	for _, test := range tests {
+		ch := &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "foo"},
+			Templates: []*chart.Template{{Name: "templates/cm.yaml", Data: []byte(test.tpl)}},
+		}
+		_, err := Render(ch, nil, chartutil.ReleaseOptions{Name: "rls"}, nil)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Expecting error %q, received %v", test.err, err)
+		}
+	}
+}
+
+func TestTpl(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "foo"},
+		Values:   &chart.Config{Raw: "host: \"{{ .Release.Name }}.example.com\"\n"},
+		Templates: []*chart.Template{{Name: "templates/cm.yaml", Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  host: {{ tpl .Values.host . }}
+`)}},
+	}
+	res, err := Render(ch, nil, chartutil.ReleaseOptions{Name: "rls"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !strings.Contains(res.Manifest, "host: rls.example.com") {
+		t.Errorf("Expecting the value to be rendered, received %s", res.Manifest)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		tpl      string
+		expected string
+	}{
+		{`{{ "" | default "x" }}`, "x"},
+		{`{{ "y" | default "x" }}`, "y"},
+		{`{{ coalesce "" 0 "z" }}`, "z"},
+		{`{{ "abcdef" | trunc 3 }}`, "abc"},
+		{`{{ "abcdef" | trunc -2 }}`, "ef"},
+		{`{{ "foo-" | trimSuffix "-" | upper }}`, "FOO"},
+		{`{{ list "a" "b" | join "," }}`, "a,b"},
+		{`{{ "a.b" | splitList "." | last }}`, "b"},
+		{`{{ "hi" | b64enc | b64dec }}`, "hi"},
+		{`{{ semverCompare ">=1.9-0" "1.10.2" }}`, "true"},
+		{`{{ kindIs "map" (dict "a" 1) }}`, "true"},
+		{`{{ hasKey (dict "a" 1) "a" }}`, "true"},
+		{`{{ ternary "yes" "no" true }}`, "yes"},
+		{`{{ max 1 3 2 }} {{ min 4 2 }}`, "3 2"},
+	}
+	for _, test := range tests {
+		ch := &chart.Chart{
+			Metadata:  &chart.Metadata{Name: "foo"},
+			Templates: []*chart.Template{{Name: "templates/NOTES.txt", Data: []byte(test.tpl)}},
+		}
+		res, err := Render(ch, nil, chartutil.ReleaseOptions{Name: "rls"}, nil)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.tpl, err)
+			continue
+		}
+		if res.Notes != test.expected {
+			t.Errorf("%s: expecting %q, received %q", test.tpl, test.expected, res.Notes)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	ageVersionLine   = "age-encryption.org/v1"
+	ageArmorHeader   = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorFooter   = "-----END AGE ENCRYPTED FILE-----"
+	ageIdentityHRP   = "age-secret-key-"
+	ageRecipientHRP  = "age"
+	ageX25519Label   = "age-encryption.org/v1/X25519"
+	ageChunkSize     = 64 * 1024
+	ageStanzaColumns = 64
+)
+
+// AgeIdentity is an age X25519 private key decrypting SOPS data keys
+type AgeIdentity struct {
+	key *ecdh.PrivateKey
+}
+
+// ParseAgeIdentities parses the AGE-SECRET-KEY-1 identities of an age
+// keys file, one per line. Blank lines and # comments are skipped.
+func ParseAgeIdentities(data []byte) ([]*AgeIdentity, error) {
+	var identities []*AgeIdentity
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hrp, scalar, err := bech32Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid age identity: %v", n+1, err)
+		}
+		if hrp != ageIdentityHRP {
+			return nil, fmt.Errorf("line %d: not an age X25519 identity", n+1)
+		}
+		key, err := ecdh.X25519().NewPrivateKey(scalar)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid age identity: %v", n+1, err)
+		}
+		identities = append(identities, &AgeIdentity{key: key})
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identity found")
+	}
+	return identities, nil
+}
+
+// Recipient returns the age1 public key of the identity
+func (i *AgeIdentity) Recipient() string {
+	return bech32Encode(ageRecipientHRP, i.key.PublicKey().Bytes())
+}
+
+// unwrap decrypts the file key of an X25519 recipient stanza
+func (i *AgeIdentity) unwrap(share, body []byte) ([]byte, error) {
+	pub, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := i.key.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(append([]byte{}, share...), i.key.PublicKey().Bytes()...)
+	aead, err := newChaCha20Poly1305(hkdfSHA256(shared, salt, ageX25519Label, 32))
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), body, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileKey) != 16 {
+		return nil, fmt.Errorf("invalid file key length %d", len(fileKey))
+	}
+	return fileKey, nil
+}
+
+// ageStanza is a recipient stanza of an age header
+type ageStanza struct {
+	args []string
+	body []byte
+}
+
+// decryptAge decrypts an age file, ASCII armored or not, with the first
+// identity matching one of its X25519 recipients
+func decryptAge(file string, identities []*AgeIdentity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identity configured")
+	}
+	data := []byte(file)
+	if trimmed := strings.TrimSpace(file); strings.HasPrefix(trimmed, ageArmorHeader) {
+		if !strings.HasSuffix(trimmed, ageArmorFooter) {
+			return nil, fmt.Errorf("invalid age armor")
+		}
+		encoded := strings.Join(strings.Fields(trimmed[len(ageArmorHeader):len(trimmed)-len(ageArmorFooter)]), "")
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("invalid age armor: %v", err)
+		}
+	}
+	header, stanzas, mac, payload, err := parseAgeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKey []byte
+	for _, s := range stanzas {
+		if len(s.args) != 2 || s.args[0] != "X25519" {
+			continue
+		}
+		share, err := base64.RawStdEncoding.DecodeString(s.args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid X25519 stanza: %v", err)
+		}
+		for _, identity := range identities {
+			if fileKey, err = identity.unwrap(share, s.body); err == nil {
+				break
+			}
+		}
+		if fileKey != nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, fmt.Errorf("no age identity matches the recipients")
+	}
+
+	h := hmac.New(sha256.New, hkdfSHA256(fileKey, nil, "header", 32))
+	h.Write(header)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, fmt.Errorf("age header MAC mismatch")
+	}
+	return decryptAgePayload(fileKey, payload)
+}
+
+// parseAgeHeader splits an age file into its header up to the MAC, which
+// the MAC covers, its recipient stanzas, MAC and payload
+func parseAgeHeader(data []byte) ([]byte, []ageStanza, []byte, []byte, error) {
+	line, rest, ok := cutLine(data)
+	if !ok || line != ageVersionLine {
+		return nil, nil, nil, nil, fmt.Errorf("not an age v1 file")
+	}
+	var stanzas []ageStanza
+	for {
+		if line, rest, ok = cutLine(rest); !ok {
+			return nil, nil, nil, nil, fmt.Errorf("truncated age header")
+		}
+		if strings.HasPrefix(line, "--- ") {
+			break
+		}
+		if !strings.HasPrefix(line, "-> ") {
+			return nil, nil, nil, nil, fmt.Errorf("invalid age header line %q", line)
+		}
+		s := ageStanza{args: strings.Split(line[len("-> "):], " ")}
+		// The body is wrapped at 64 columns and ends with a shorter line
+		for {
+			if line, rest, ok = cutLine(rest); !ok {
+				return nil, nil, nil, nil, fmt.Errorf("truncated age stanza")
+			}
+			b, err := base64.RawStdEncoding.DecodeString(line)
+			if err != nil || len(line) > ageStanzaColumns {
+				return nil, nil, nil, nil, fmt.Errorf("invalid age stanza body")
+			}
+			s.body = append(s.body, b...)
+			if len(line) < ageStanzaColumns {
+				break
+			}
+		}
+		stanzas = append(stanzas, s)
+	}
+	mac, err := base64.RawStdEncoding.DecodeString(line[len("--- "):])
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid age header MAC: %v", err)
+	}
+	header := data[:len(data)-len(rest)-len(line)-1+len("---")]
+	return header, stanzas, mac, rest, nil
+}
+
+// decryptAgePayload decrypts the STREAM chunks of an age payload, keyed
+// with its nonce and the file key
+func decryptAgePayload(fileKey, payload []byte) ([]byte, error) {
+	if len(payload) < 16 {
+		return nil, fmt.Errorf("truncated age payload")
+	}
+	aead, err := newChaCha20Poly1305(hkdfSHA256(fileKey, payload[:16], "payload", 32))
+	if err != nil {
+		return nil, err
+	}
+	payload = payload[16:]
+	var plaintext []byte
+	nonce := make([]byte, aead.NonceSize())
+	for counter := uint64(0); ; counter++ {
+		chunk, last := payload, true
+		if len(payload) > ageChunkSize+aead.Overhead() {
+			chunk, last = payload[:ageChunkSize+aead.Overhead()], false
+		}
+		payload = payload[len(chunk):]
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		if last {
+			nonce[11] = 1
+		}
+		plain, err := aead.Open(nil, nonce, chunk, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt age payload: %v", err)
+		}
+		plaintext = append(plaintext, plain...)
+		if last {
+			return plaintext, nil
+		}
+	}
+}
+
+func cutLine(data []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(data[:i]), data[i+1:], true
+}
+
+// hkdfSHA256 derives a key of length bytes with HKDF-SHA256 (RFC 5869)
+func hkdfSHA256(secret, salt []byte, info string, length int) []byte {
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+	var okm, t []byte
+	for i := byte(1); len(okm) < length; i++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(t)
+		expand.Write([]byte(info))
+		expand.Write([]byte{i})
+		t = expand.Sum(nil)
+		okm = append(okm, t...)
+	}
+	return okm[:length]
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode returns the lower case human readable part and the data of
+// a Bech32 string (BIP 173), age keys exceeding its length limit excepted
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, fmt.Errorf("invalid separator position")
+	}
+	hrp := s[:sep]
+	var values []byte
+	for _, c := range s[sep+1:] {
+		v := strings.IndexRune(bech32Charset, c)
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
+
+// bech32Encode returns the Bech32 string of hrp and data
+func bech32Encode(hrp string, data []byte) string {
+	values, _ := convertBits(data, 8, 5, true)
+	polymod := bech32Polymod(append(append(bech32ExpandHRP(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>uint(5*(5-i)))&31)
+	}
+	out := []byte(hrp + "1")
+	for _, v := range values {
+		out = append(out, bech32Charset[v])
+	}
+	return string(out)
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	var values []byte
+	for _, c := range []byte(hrp) {
+		values = append(values, c>>5)
+	}
+	values = append(values, 0)
+	for _, c := range []byte(hrp) {
+		values = append(values, c&31)
+	}
+	return values
+}
+
+// convertBits regroups data from groups of from bits to groups of to bits
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	var out []byte
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits)&(1<<to-1))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits))&(1<<to-1))
+		}
+	} else if bits >= from || byte(acc<<(to-bits))&(1<<to-1) != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return out, nil
+}
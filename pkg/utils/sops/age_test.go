@@ -0,0 +1,180 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// encryptAge returns the armored age file of plaintext encrypted to recipient
+func encryptAge(t *testing.T, recipient *ecdh.PublicKey, plaintext []byte) string {
+	fileKey := make([]byte, 16)
+	rand.Read(fileKey)
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	share := ephemeral.PublicKey().Bytes()
+	salt := append(append([]byte{}, share...), recipient.Bytes()...)
+	wrap, _ := newChaCha20Poly1305(hkdfSHA256(shared, salt, ageX25519Label, 32))
+	body := wrap.Seal(nil, make([]byte, 12), fileKey, nil)
+	header := fmt.Sprintf("%s\n-> X25519 %s\n%s\n---", ageVersionLine,
+		base64.RawStdEncoding.EncodeToString(share), base64.RawStdEncoding.EncodeToString(body))
+	mac := hmac.New(sha256.New, hkdfSHA256(fileKey, nil, "header", 32))
+	mac.Write([]byte(header))
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	stream, _ := newChaCha20Poly1305(hkdfSHA256(fileKey, nonce, "payload", 32))
+	last := make([]byte, 12)
+	last[11] = 1
+	file := []byte(header + " " + base64.RawStdEncoding.EncodeToString(mac.Sum(nil)) + "\n")
+	file = append(file, nonce...)
+	file = append(file, stream.Seal(nil, last, plaintext, nil)...)
+
+	encoded := base64.StdEncoding.EncodeToString(file)
+	var lines []string
+	for len(encoded) > 64 {
+		lines = append(lines, encoded[:64])
+		encoded = encoded[64:]
+	}
+	lines = append(lines, encoded)
+	return ageArmorHeader + "\n" + strings.Join(lines, "\n") + "\n" + ageArmorFooter + "\n"
+}
+
+func TestChaCha20Poly1305(t *testing.T) {
+	// RFC 8439 2.8.2
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("070000004041424344454647")
+	aad, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	expected, _ := hex.DecodeString("d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b6116" +
+		"1ae10b594f09e26a7e902ecbd0600691")
+
+	aead, err := newChaCha20Poly1305(key)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+	if !bytes.Equal(sealed, expected) {
+		t.Errorf("Expecting %x, received %x", expected, sealed)
+	}
+	opened, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Expecting the plaintext, received %q, %v", opened, err)
+	}
+	sealed[0] ^= 1
+	if _, err := aead.Open(nil, nonce, sealed, aad); err == nil {
+		t.Errorf("Expecting an authentication error")
+	}
+}
+
+func TestBech32(t *testing.T) {
+	// BIP 173 test vectors
+	for _, s := range []string{"A12UEL5L", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"} {
+		if _, _, err := bech32Decode(s); err != nil {
+			t.Errorf("%s: unexpected error %v", s, err)
+		}
+	}
+	for _, s := range []string{"A12UEL5l", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx", "1qzzfhee"} {
+		if _, _, err := bech32Decode(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+
+	data := []byte{0, 1, 2, 0xfe, 0xff}
+	hrp, decoded, err := bech32Decode(bech32Encode("age", data))
+	if err != nil || hrp != "age" || !bytes.Equal(decoded, data) {
+		t.Errorf("Expecting a round trip, received %q %x %v", hrp, decoded, err)
+	}
+}
+
+func TestParseAgeIdentities(t *testing.T) {
+	key, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	identity := strings.ToUpper(bech32Encode(ageIdentityHRP, key.Bytes()))
+	recipient := bech32Encode(ageRecipientHRP, key.PublicKey().Bytes())
+
+	identities, err := ParseAgeIdentities([]byte("# created: 2019-03-01T10:00:00Z\n# public key: " + recipient + "\n" + identity + "\n\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(identities) != 1 || identities[0].Recipient() != recipient {
+		t.Errorf("Expecting the identity of %s, received %v", recipient, identities)
+	}
+
+	for _, keys := range []string{"", "# no key\n", recipient, identity[:len(identity)-1] + "X"} {
+		if _, err := ParseAgeIdentities([]byte(keys)); err == nil {
+			t.Errorf("%q: expected an error", keys)
+		}
+	}
+}
+
+func TestDecryptAge(t *testing.T) {
+	key, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	identities := []*AgeIdentity{{key: key}}
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+
+	enc := encryptAge(t, key.PublicKey(), dataKey)
+	decrypted, err := decryptAge(enc, identities)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !bytes.Equal(decrypted, dataKey) {
+		t.Errorf("Expecting %x, received %x", dataKey, decrypted)
+	}
+
+	other, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	if _, err := decryptAge(enc, []*AgeIdentity{{key: other}}); err == nil {
+		t.Errorf("Expected an error without the identity")
+	}
+
+	// The header is authenticated with the file key
+	armored := strings.Split(strings.TrimSpace(enc), "\n")
+	file, _ := base64.StdEncoding.DecodeString(strings.Join(armored[1:len(armored)-1], ""))
+	tampered := bytes.Replace(file, []byte("\n---"), []byte("\n-> extra\n\n---"), 1)
+	if _, err := decryptAge(string(tampered), identities); err == nil || !strings.Contains(err.Error(), "MAC") {
+		t.Errorf("Expected a header MAC error, got %v", err)
+	}
+
+	// SOPS documents whose data key is encrypted with age
+	block, _ := aes.NewCipher(dataKey)
+	aead, _ := cipher.NewGCMWithNonceSize(block, 32)
+	doc := fmt.Sprintf(`password: %s
+sops:
+  age:
+  - recipient: %s
+    enc: |
+%s
+  lastmodified: "2019-03-01T10:00:00Z"
+  mac: %s
+`,
+		encryptValue(t, aead, "sekret", "str", "password:"),
+		identities[0].Recipient(),
+		"      "+strings.Replace(strings.TrimSpace(enc), "\n", "\n      ", -1),
+		encryptMAC(t, aead, "2019-03-01T10:00:00Z", "sekret"),
+	)
+	out, err := Decrypt([]byte(doc), Keys{Age: identities})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if string(out) != "password: sekret\n" {
+		t.Errorf("Expecting the decrypted password, received %q", out)
+	}
+	if _, err := Decrypt([]byte(doc), Keys{}); err == nil || !strings.Contains(err.Error(), "no age identity") {
+		t.Errorf("Expected an error without age identities, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package sops
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// chacha20poly1305 is the ChaCha20-Poly1305 AEAD of RFC 8439, which age
+// wraps file keys and encrypts payloads with. Only small messages are
+// sealed with it, so Poly1305 is computed with math/big.
+type chacha20poly1305 struct {
+	key [32]byte
+}
+
+func newChaCha20Poly1305(key []byte) (*chacha20poly1305, error) {
+	if len(key) != 32 {
+		return nil, errors.New("chacha20poly1305: bad key length")
+	}
+	c := &chacha20poly1305{}
+	copy(c.key[:], key)
+	return c, nil
+}
+
+func (c *chacha20poly1305) NonceSize() int { return 12 }
+
+func (c *chacha20poly1305) Overhead() int { return 16 }
+
+func (c *chacha20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 12 {
+		panic("chacha20poly1305: bad nonce length")
+	}
+	out := make([]byte, len(plaintext), len(plaintext)+16)
+	c.xorKeyStream(out, plaintext, nonce, 1)
+	out = append(out, c.tag(nonce, out, additionalData)...)
+	return append(dst, out...)
+}
+
+func (c *chacha20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != 12 {
+		panic("chacha20poly1305: bad nonce length")
+	}
+	if len(ciphertext) < 16 {
+		return nil, errors.New("chacha20poly1305: message authentication failed")
+	}
+	data, tag := ciphertext[:len(ciphertext)-16], ciphertext[len(ciphertext)-16:]
+	if subtle.ConstantTimeCompare(tag, c.tag(nonce, data, additionalData)) != 1 {
+		return nil, errors.New("chacha20poly1305: message authentication failed")
+	}
+	out := make([]byte, len(data))
+	c.xorKeyStream(out, data, nonce, 1)
+	return append(dst, out...), nil
+}
+
+// tag returns the Poly1305 tag of the additional data and ciphertext,
+// keyed with the first block of the key stream
+func (c *chacha20poly1305) tag(nonce, ciphertext, additionalData []byte) []byte {
+	var block [64]byte
+	c.block(&block, nonce, 0)
+	msg := append([]byte{}, additionalData...)
+	msg = append(msg, make([]byte, padding16(len(additionalData)))...)
+	msg = append(msg, ciphertext...)
+	msg = append(msg, make([]byte, padding16(len(ciphertext)))...)
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	return poly1305(block[:32], append(msg, lengths[:]...))
+}
+
+func (c *chacha20poly1305) xorKeyStream(dst, src, nonce []byte, counter uint32) {
+	var block [64]byte
+	for i := 0; i < len(src); i += 64 {
+		c.block(&block, nonce, counter)
+		counter++
+		for j := i; j < len(src) && j < i+64; j++ {
+			dst[j] = src[j] ^ block[j-i]
+		}
+	}
+}
+
+// block computes the ChaCha20 block of the key, nonce and counter
+func (c *chacha20poly1305) block(out *[64]byte, nonce []byte, counter uint32) {
+	var s, x [16]uint32
+	s[0], s[1], s[2], s[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		s[4+i] = binary.LittleEndian.Uint32(c.key[4*i:])
+	}
+	s[12] = counter
+	for i := 0; i < 3; i++ {
+		s[13+i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+	x = s
+	for i := 0; i < 10; i++ {
+		quarterRound(&x, 0, 4, 8, 12)
+		quarterRound(&x, 1, 5, 9, 13)
+		quarterRound(&x, 2, 6, 10, 14)
+		quarterRound(&x, 3, 7, 11, 15)
+		quarterRound(&x, 0, 5, 10, 15)
+		quarterRound(&x, 1, 6, 11, 12)
+		quarterRound(&x, 2, 7, 8, 13)
+		quarterRound(&x, 3, 4, 9, 14)
+	}
+	for i := range x {
+		binary.LittleEndian.PutUint32(out[4*i:], x[i]+s[i])
+	}
+}
+
+func quarterRound(x *[16]uint32, a, b, c, d int) {
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+	x[a] += x[b]
+	x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+	x[c] += x[d]
+	x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+}
+
+func padding16(n int) int {
+	return (16 - n%16) % 16
+}
+
+var (
+	poly1305Prime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 130), big.NewInt(5))
+	poly1305Clamp = littleEndianInt([]byte{0xff, 0xff, 0xff, 0x0f, 0xfc, 0xff, 0xff, 0x0f, 0xfc, 0xff, 0xff, 0x0f, 0xfc, 0xff, 0xff, 0x0f})
+)
+
+// poly1305 returns the one-time authenticator of msg with key
+func poly1305(key, msg []byte) []byte {
+	r := new(big.Int).And(littleEndianInt(key[:16]), poly1305Clamp)
+	s := littleEndianInt(key[16:32])
+	acc := new(big.Int)
+	for i := 0; i < len(msg); i += 16 {
+		end := i + 16
+		if end > len(msg) {
+			end = len(msg)
+		}
+		n := littleEndianInt(append(append([]byte{}, msg[i:end]...), 1))
+		acc.Add(acc, n)
+		acc.Mul(acc, r)
+		acc.Mod(acc, poly1305Prime)
+	}
+	acc.Add(acc, s)
+	be := acc.Bytes()
+	tag := make([]byte, 16)
+	for i := 0; i < 16 && i < len(be); i++ {
+		tag[i] = be[len(be)-1-i]
+	}
+	return tag
+}
+
+func littleEndianInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
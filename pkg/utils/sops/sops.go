@@ -0,0 +1,308 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// metadataKey is the top-level key holding the SOPS metadata
+const metadataKey = "sops"
+
+// nonceSize is the size of the IVs SOPS encrypts values with
+const nonceSize = 32
+
+var encrypted = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+type metadata struct {
+	PGP []struct {
+		Fingerprint string `json:"fp"`
+		Enc         string `json:"enc"`
+	} `json:"pgp"`
+	Age []struct {
+		Recipient string `json:"recipient"`
+		Enc       string `json:"enc"`
+	} `json:"age"`
+	KMS               []interface{} `json:"kms"`
+	GCPKMS            []interface{} `json:"gcp_kms"`
+	AzureKV           []interface{} `json:"azure_kv"`
+	UnencryptedSuffix string        `json:"unencrypted_suffix"`
+	MAC               string        `json:"mac"`
+	MACOnlyEncrypted  bool          `json:"mac_only_encrypted"`
+	LastModified      string        `json:"lastmodified"`
+}
+
+// Keys are the private keys decrypting SOPS data keys
+type Keys struct {
+	// PGP holds the PGP private keys
+	PGP openpgp.EntityList
+	// Age holds the age X25519 identities
+	Age []*AgeIdentity
+}
+
+// Empty returns whether there is no key at all
+func (k Keys) Empty() bool {
+	return len(k.PGP) == 0 && len(k.Age) == 0
+}
+
+// Decrypt decrypts a YAML or JSON document encrypted with SOPS, using keys
+// to decrypt the data key. Only PGP and age encrypted data keys are
+// supported. The document MAC is verified, so values can't be added,
+// removed or reordered.
+func Decrypt(doc []byte, keys Keys) ([]byte, error) {
+	// The MAC covers the values in document order, so keep it
+	tree := yamlv2.MapSlice{}
+	if err := yamlv2.Unmarshal(doc, &tree); err != nil {
+		return nil, err
+	}
+	var rawMeta interface{}
+	found := false
+	for i, item := range tree {
+		if fmt.Sprint(item.Key) == metadataKey {
+			rawMeta, found = item.Value, true
+			tree = append(tree[:i], tree[i+1:]...)
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("document is not encrypted with SOPS")
+	}
+
+	// Round trip the metadata through JSON to decode it into the struct
+	metaYAML, err := yamlv2.Marshal(rawMeta)
+	if err != nil {
+		return nil, err
+	}
+	meta := metadata{}
+	if err := yaml.Unmarshal(metaYAML, &meta); err != nil {
+		return nil, err
+	}
+	if meta.UnencryptedSuffix == "" {
+		meta.UnencryptedSuffix = "_unencrypted"
+	}
+	if meta.MAC == "" {
+		return nil, fmt.Errorf("no MAC in SOPS metadata")
+	}
+
+	key, err := dataKey(&meta, keys)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	d := &decrypter{
+		unencryptedSuffix: meta.UnencryptedSuffix,
+		macOnlyEncrypted:  meta.MACOnlyEncrypted,
+		hash:              sha512.New(),
+	}
+	d.aead, err = cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := d.walk(tree, "", true)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.verifyMAC(&meta); err != nil {
+		return nil, err
+	}
+	return yamlv2.Marshal(decrypted)
+}
+
+// dataKey decrypts the data key with the first matching PGP or age key
+func dataKey(meta *metadata, keys Keys) ([]byte, error) {
+	if len(meta.PGP) == 0 && len(meta.Age) == 0 {
+		var kinds []string
+		for kind, keys := range map[string][]interface{}{
+			"kms":      meta.KMS,
+			"gcp_kms":  meta.GCPKMS,
+			"azure_kv": meta.AzureKV,
+		} {
+			if len(keys) > 0 {
+				kinds = append(kinds, kind)
+			}
+		}
+		if len(kinds) > 0 {
+			sort.Strings(kinds)
+			return nil, fmt.Errorf("SOPS data key is only encrypted with unsupported %s keys, add a PGP or age key to the document", strings.Join(kinds, ", "))
+		}
+		return nil, fmt.Errorf("no PGP or age key in SOPS metadata")
+	}
+	var errs []string
+	for _, k := range meta.PGP {
+		key, err := pgpDataKey(k.Enc, keys.PGP)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", k.Fingerprint, err))
+			continue
+		}
+		return key, nil
+	}
+	for _, k := range meta.Age {
+		key, err := decryptAge(k.Enc, keys.Age)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", k.Recipient, err))
+			continue
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unable to decrypt SOPS data key: %s", strings.Join(errs, "; "))
+}
+
+// pgpDataKey decrypts an armored PGP message holding the data key
+func pgpDataKey(enc string, keyring openpgp.EntityList) ([]byte, error) {
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("no PGP key configured")
+	}
+	block, err := armor.Decode(strings.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+	md, err := openpgp.ReadMessage(block.Body, keyring, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+type decrypter struct {
+	aead              cipher.AEAD
+	unencryptedSuffix string
+	macOnlyEncrypted  bool
+	// hash accumulates the plaintext of the values covered by the MAC
+	hash hash.Hash
+}
+
+// walk decrypts the leaves of v, the ones under a key with the unencrypted
+// suffix excepted. path is the additional data SOPS authenticates each
+// value with: the keys leading to it, each followed by a colon.
+func (d *decrypter) walk(v interface{}, path string, encrypt bool) (interface{}, error) {
+	switch t := v.(type) {
+	case yamlv2.MapSlice:
+		for i, item := range t {
+			k := fmt.Sprint(item.Key)
+			decrypted, err := d.walk(item.Value, path+k+":", encrypt && !strings.HasSuffix(k, d.unencryptedSuffix))
+			if err != nil {
+				return nil, err
+			}
+			t[i].Value = decrypted
+		}
+		return t, nil
+	case []interface{}:
+		for i, item := range t {
+			decrypted, err := d.walk(item, path, encrypt)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = decrypted
+		}
+		return t, nil
+	}
+	if encrypt {
+		if s, ok := v.(string); ok && encrypted.MatchString(s) {
+			plain, valueType, err := d.decrypt(s, path)
+			if err != nil {
+				return nil, err
+			}
+			d.hash.Write(plain)
+			return parseValue(plain, valueType)
+		}
+	}
+	if !d.macOnlyEncrypted {
+		d.hash.Write(valueBytes(v))
+	}
+	return v, nil
+}
+
+// decrypt returns the plaintext and type of an encrypted value
+func (d *decrypter) decrypt(value, path string) ([]byte, string, error) {
+	m := encrypted.FindStringSubmatch(value)
+	if m == nil {
+		return nil, "", fmt.Errorf("invalid SOPS encrypted value")
+	}
+	data, err := base64.StdEncoding.DecodeString(m[1])
+	if err != nil {
+		return nil, "", err
+	}
+	iv, err := base64.StdEncoding.DecodeString(m[2])
+	if err != nil {
+		return nil, "", err
+	}
+	if len(iv) != nonceSize {
+		return nil, "", fmt.Errorf("unable to decrypt %s: invalid IV length %d", strings.TrimSuffix(path, ":"), len(iv))
+	}
+	tag, err := base64.StdEncoding.DecodeString(m[3])
+	if err != nil {
+		return nil, "", err
+	}
+	plain, err := d.aead.Open(nil, iv, append(data, tag...), []byte(path))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to decrypt %s: %v", strings.TrimSuffix(path, ":"), err)
+	}
+	return plain, m[4], nil
+}
+
+// verifyMAC checks the MAC of the metadata, encrypted with the date of the
+// last modification as additional data, against the values walked.
+func (d *decrypter) verifyMAC(meta *metadata) error {
+	lastModified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid SOPS lastmodified date: %v", err)
+	}
+	mac, _, err := d.decrypt(meta.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("unable to decrypt SOPS MAC: %v", err)
+	}
+	computed := fmt.Sprintf("%X", d.hash.Sum(nil))
+	if subtle.ConstantTimeCompare(mac, []byte(computed)) != 1 {
+		return fmt.Errorf("SOPS MAC mismatch, the document was modified")
+	}
+	return nil
+}
+
+// valueBytes returns the bytes SOPS hashes for an unencrypted value
+func valueBytes(v interface{}) []byte {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(t)
+	case float64:
+		return []byte(strconv.FormatFloat(t, 'f', -1, 64))
+	case bool:
+		return []byte(strings.Title(strconv.FormatBool(t)))
+	}
+	return []byte(fmt.Sprint(v))
+}
+
+func parseValue(plain []byte, valueType string) (interface{}, error) {
+	switch valueType {
+	case "str", "bytes":
+		return string(plain), nil
+	case "int":
+		return strconv.Atoi(string(plain))
+	case "float":
+		return strconv.ParseFloat(string(plain), 64)
+	case "bool":
+		return strconv.ParseBool(string(bytes.ToLower(plain)))
+	}
+	return nil, fmt.Errorf("unknown SOPS value type %q", valueType)
+}
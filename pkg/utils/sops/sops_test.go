@@ -0,0 +1,163 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func encryptValue(t *testing.T, aead cipher.AEAD, value, valueType, path string) string {
+	iv := make([]byte, 32)
+	rand.Read(iv)
+	sealed := aead.Seal(nil, iv, []byte(value), []byte(path))
+	data, tag := sealed[:len(sealed)-16], sealed[len(sealed)-16:]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType)
+}
+
+func encryptDataKey(t *testing.T, key []byte, to *openpgp.Entity) string {
+	// Entities created by NewEntity don't state hash preferences, which
+	// defaults to RIPEMD160. Prefer SHA256 (id 8, RFC 4880 9.4) instead.
+	for _, id := range to.Identities {
+		id.SelfSignature.PreferredHash = []uint8{8}
+	}
+	buf := &bytes.Buffer{}
+	armored, err := armor.Encode(buf, "PGP MESSAGE", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	w, err := openpgp.Encrypt(armored, []*openpgp.Entity{to}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	w.Write(key)
+	w.Close()
+	armored.Close()
+	return buf.String()
+}
+
+// encryptMAC returns the SOPS MAC of the plaintext values
+func encryptMAC(t *testing.T, aead cipher.AEAD, lastModified string, values ...string) string {
+	h := sha512.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+	}
+	return encryptValue(t, aead, fmt.Sprintf("%X", h.Sum(nil)), "str", lastModified)
+}
+
+func TestDecrypt(t *testing.T) {
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCMWithNonceSize(block, 32)
+
+	enc := encryptDataKey(t, key, entity)
+	doc := fmt.Sprintf(`db:
+  password: %s
+  port: %s
+  tls: %s
+hosts:
+- %s
+name_unencrypted: plain
+sops:
+  pgp:
+  - fp: %X
+    enc: |
+%s
+  lastmodified: "2019-03-01T10:00:00Z"
+  mac: %s
+`,
+		encryptValue(t, aead, "sekret", "str", "db:password:"),
+		encryptValue(t, aead, "3306", "int", "db:port:"),
+		encryptValue(t, aead, "True", "bool", "db:tls:"),
+		encryptValue(t, aead, "db.example.com", "str", "hosts:"),
+		entity.PrimaryKey.Fingerprint,
+		"      "+strings.Replace(strings.TrimSpace(enc), "\n", "\n      ", -1),
+		encryptMAC(t, aead, "2019-03-01T10:00:00Z", "sekret", "3306", "True", "db.example.com", "plain"),
+	)
+
+	out, err := Decrypt([]byte(doc), Keys{PGP: openpgp.EntityList{entity}})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `db:
+  password: sekret
+  port: 3306
+  tls: true
+hosts:
+- db.example.com
+name_unencrypted: plain
+`
+	if string(out) != expected {
+		t.Errorf("Expecting:\n%s\nreceived:\n%s", expected, out)
+	}
+
+	// Values are authenticated with their path
+	tampered := strings.Replace(doc, "password:", "passwd:", 1)
+	if _, err := Decrypt([]byte(tampered), Keys{PGP: openpgp.EntityList{entity}}); err == nil {
+		t.Errorf("Expected an error for a moved value")
+	}
+
+	// Unencrypted values are covered by the MAC
+	tampered = strings.Replace(doc, "name_unencrypted: plain", "name_unencrypted: other", 1)
+	if _, err := Decrypt([]byte(tampered), Keys{PGP: openpgp.EntityList{entity}}); err == nil {
+		t.Errorf("Expected an error for a modified unencrypted value")
+	}
+
+	// So is the number of values
+	tampered = strings.Replace(doc, "name_unencrypted: plain\n", "", 1)
+	if _, err := Decrypt([]byte(tampered), Keys{PGP: openpgp.EntityList{entity}}); err == nil {
+		t.Errorf("Expected an error for a removed value")
+	}
+
+	// Invalid IV lengths are errors, not panics
+	badIV := fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString([]byte("x")),
+		base64.StdEncoding.EncodeToString(make([]byte, 12)),
+		base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	tampered = strings.Replace(doc, "name_unencrypted: plain", "name: "+badIV, 1)
+	if _, err := Decrypt([]byte(tampered), Keys{PGP: openpgp.EntityList{entity}}); err == nil || !strings.Contains(err.Error(), "invalid IV length") {
+		t.Errorf("Expected an invalid IV error, got %v", err)
+	}
+
+	other, _ := openpgp.NewEntity("other", "", "other@example.com", nil)
+	if _, err := Decrypt([]byte(doc), Keys{PGP: openpgp.EntityList{other}}); err == nil {
+		t.Errorf("Expected an error without the private key")
+	}
+}
+
+func TestDecryptUnsupported(t *testing.T) {
+	tests := map[string]string{
+		"not encrypted": "foo: bar\n",
+		"age":           "foo: bar\nsops:\n  age:\n  - recipient: age1foo\n  mac: foo\n",
+		"gcp_kms":       "foo: bar\nsops:\n  gcp_kms:\n  - resource_id: foo\n  mac: foo\n",
+		"kms":           "foo: bar\nsops:\n  kms:\n  - arn: foo\n  mac: foo\n",
+		"no MAC":        "foo: bar\nsops:\n  pgp:\n  - fp: foo\n",
+	}
+	for name, doc := range tests {
+		if _, err := Decrypt([]byte(doc), Keys{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := Decrypt([]byte(tests["kms"]), Keys{})
+	if err == nil || !strings.Contains(err.Error(), "unsupported kms keys") {
+		t.Errorf("Expected the unsupported key types in the error, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxQueuedSpans caps the spans kept between exports. Spans ended
+	// while the queue is full are dropped.
+	maxQueuedSpans = 2048
+	// OTLP span kind and status codes
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// Tracer records spans and exports them to an OpenTelemetry collector
+// using OTLP/HTTP with JSON encoding. A nil *Tracer is valid and records
+// nothing, so callers don't need to check whether tracing is enabled.
+type Tracer struct {
+	client   *http.Client
+	endpoint string
+	service  string
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+}
+
+// NewTracer returns a Tracer exporting the spans of service to the OTLP
+// endpoint, e.g. "http://otel-collector:4318"
+func NewTracer(endpoint, service string, client *http.Client) *Tracer {
+	return &Tracer{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:  service,
+	}
+}
+
+// Span is a timed operation, child of another span of the same trace or
+// the root of a new trace. A nil *Span is valid and records nothing.
+type Span struct {
+	tracer   *Tracer
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []interface{}
+	err      error
+}
+
+// Start begins a span named name, child of parent if not nil. attrs are
+// alternating keys and values.
+func (t *Tracer) Start(parent *Span, name string, attrs ...interface{}) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, spanID: randomID(8), name: name, start: time.Now(), attrs: attrs}
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		s.traceID = randomID(16)
+	}
+	return s
+}
+
+// SetAttributes adds alternating keys and values to the span
+func (s *Span) SetAttributes(attrs ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End completes the span, recording err if not nil, and queues it for export
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, s)
+}
+
+// Run exports the queued spans every interval until stopCh is closed,
+// exporting the remaining ones before returning. Export errors are passed
+// to onError.
+func (t *Tracer) Run(interval time.Duration, stopCh <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				onError(err)
+			}
+		case <-stopCh:
+			if err := t.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		}
+	}
+}
+
+// Flush exports the queued spans
+func (t *Tracer) Flush() error {
+	t.mu.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	res, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", t.endpoint, res.Status)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("dropped %d spans, the export queue was full", dropped)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON request, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/collector/trace/v1/trace_service.proto
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (t *Tracer) request(spans []*Span) exportRequest {
+	var out []otlpSpan
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attrs),
+			Status:            status{Code: statusCodeOk},
+		}
+		if s.err != nil {
+			span.Status = status{Code: statusCodeError, Message: s.err.Error()}
+		}
+		out = append(out, span)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: attributes([]interface{}{"service.name", t.service})},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "github.com/bitnami-labs/helm-crd"}, Spans: out}},
+	}}}
+}
+
+func attributes(kvs []interface{}) []keyValue {
+	var attrs []keyValue
+	for i := 0; i+1 < len(kvs); i += 2 {
+		var value map[string]interface{}
+		switch v := kvs[i+1].(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int32:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(int64(v), 10)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		attrs = append(attrs, keyValue{Key: fmt.Sprint(kvs[i]), Value: value})
+	}
+	return attrs
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
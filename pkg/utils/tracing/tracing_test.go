@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start(nil, "reconcile")
+	span.SetAttributes("foo", "bar")
+	span.End(nil)
+	if span != nil {
+		t.Errorf("Expecting a nil span")
+	}
+}
+
+func TestExport(t *testing.T) {
+	var req exportRequest
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&req)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(server.URL+"/", "helm-crd-controller", http.DefaultClient)
+	root := tracer.Start(nil, "reconcile", "helmrelease", "myns/foo")
+	child := tracer.Start(root, "fetchChart", "attempt", 1)
+	child.End(fmt.Errorf("timed out"))
+	root.SetAttributes("dryRun", true)
+	root.End(nil)
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if path != "/v1/traces" {
+		t.Errorf("Expecting spans exported to /v1/traces received %s", path)
+	}
+	rs := req.ResourceSpans[0]
+	if rs.Resource.Attributes[0].Value["stringValue"] != "helm-crd-controller" {
+		t.Errorf("Unexpected resource %+v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expecting 2 spans received %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || r.ParentSpanID != "" || len(r.TraceID) != 32 || len(r.SpanID) != 16 {
+		t.Errorf("Unexpected span ids %+v %+v", c, r)
+	}
+	if c.Status.Code != statusCodeError || c.Status.Message != "timed out" || r.Status.Code != statusCodeOk {
+		t.Errorf("Unexpected span status %+v %+v", c.Status, r.Status)
+	}
+	if c.Attributes[0].Value["intValue"] != "1" || r.Attributes[1].Key != "dryRun" || r.Attributes[1].Value["boolValue"] != true {
+		t.Errorf("Unexpected attributes %+v %+v", c.Attributes, r.Attributes)
+	}
+
+	// Nothing is sent without spans
+	path = ""
+	if err := tracer.Flush(); err != nil || path != "" {
+		t.Errorf("Expecting no export, received %s (%v)", path, err)
+	}
+}
+
+func TestExportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(server.URL, "helm-crd-controller", http.DefaultClient)
+	tracer.Start(nil, "reconcile").End(nil)
+	if err := tracer.Flush(); err == nil {
+		t.Errorf("Expecting an error for a failed export")
+	}
+}
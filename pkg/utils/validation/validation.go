@@ -0,0 +1,800 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"golang.org/x/net/http/httpguts"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/oci"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/releasename"
+	valuesUtils "github.com/bitnami-labs/helm-crd/pkg/utils/values"
+	"github.com/bitnami-labs/helm-crd/pkg/utils/window"
+)
+
+// MaxReleaseNameLen is the maximum length of a release name accepted by Tiller
+const MaxReleaseNameLen = releasename.MaxLen
+
+// ValidateHelmRelease returns the errors in a HelmRelease that would make
+// every reconcile of it fail
+func ValidateHelmRelease(h *helmCrdV2.HelmRelease) field.ErrorList {
+	return ValidateHelmReleaseSpec(&h.Spec, field.NewPath("spec"))
+}
+
+// ValidateHelmReleaseSpec returns the errors in the spec of a HelmRelease
+// at specPath
+func ValidateHelmReleaseSpec(spec *helmCrdV2.HelmReleaseSpec, specPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, ValidateChartURL(spec, specPath)...)
+	allErrs = append(allErrs, ValidateReleaseName(spec.ReleaseName, specPath.Child("releaseName"))...)
+	if spec.ReleaseNameTemplate != "" {
+		allErrs = append(allErrs, ValidateReleaseNameTemplate(spec.ReleaseNameTemplate, specPath.Child("releaseNameTemplate"))...)
+	}
+	if spec.TillerHost != "" {
+		if _, port, err := net.SplitHostPort(spec.TillerHost); err != nil || port == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("tillerHost"), spec.TillerHost, "must be a host:port address"))
+		}
+	}
+	if spec.TillerNamespace != "" {
+		for _, msg := range utilvalidation.IsDNS1123Label(spec.TillerNamespace) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("tillerNamespace"), spec.TillerNamespace, msg))
+		}
+	}
+	if ref := spec.KubeConfigSecretRef; ref != nil {
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("kubeConfigSecretRef", "name"), ""))
+		}
+		if ref.Key == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("kubeConfigSecretRef", "key"), ""))
+		}
+		if spec.ServiceAccountName != "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("serviceAccountName"), spec.ServiceAccountName, "may not be set with kubeConfigSecretRef"))
+		}
+	}
+	if spec.ServiceAccountName != "" {
+		for _, msg := range utilvalidation.IsDNS1123Subdomain(spec.ServiceAccountName) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("serviceAccountName"), spec.ServiceAccountName, msg))
+		}
+	}
+	if spec.TargetNamespace != "" {
+		for _, msg := range utilvalidation.IsDNS1123Label(spec.TargetNamespace) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("targetNamespace"), spec.TargetNamespace, msg))
+		}
+	}
+	if md := spec.NamespaceMetadata; md != nil {
+		mdPath := specPath.Child("namespaceMetadata")
+		if !spec.CreateNamespace {
+			allErrs = append(allErrs, field.Forbidden(mdPath, "requires createNamespace"))
+		}
+		for k, v := range md.Labels {
+			for _, msg := range utilvalidation.IsQualifiedName(k) {
+				allErrs = append(allErrs, field.Invalid(mdPath.Child("labels"), k, msg))
+			}
+			for _, msg := range utilvalidation.IsValidLabelValue(v) {
+				allErrs = append(allErrs, field.Invalid(mdPath.Child("labels").Key(k), v, msg))
+			}
+		}
+		for k := range md.Annotations {
+			for _, msg := range utilvalidation.IsQualifiedName(k) {
+				allErrs = append(allErrs, field.Invalid(mdPath.Child("annotations"), k, msg))
+			}
+		}
+	}
+	if pm := spec.PropagateMetadata; pm != nil {
+		pmPath := specPath.Child("propagateMetadata")
+		for i, k := range pm.Labels {
+			for _, msg := range utilvalidation.IsQualifiedName(k) {
+				allErrs = append(allErrs, field.Invalid(pmPath.Child("labels").Index(i), k, msg))
+			}
+		}
+		for i, k := range pm.Annotations {
+			for _, msg := range utilvalidation.IsQualifiedName(k) {
+				allErrs = append(allErrs, field.Invalid(pmPath.Child("annotations").Index(i), k, msg))
+			}
+		}
+	}
+	for i, src := range spec.ValuesFrom {
+		allErrs = append(allErrs, ValidateValuesSource(&src, specPath.Child("valuesFrom").Index(i))...)
+	}
+	allErrs = append(allErrs, ValidateValues(string(spec.Values), specPath.Child("values"))...)
+	if r := spec.Retries; r != nil && *r < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("retries"), *r, "must be greater than or equal to 0"))
+	}
+	if pr := spec.PostRender; pr != nil {
+		allErrs = append(allErrs, ValidatePostRender(pr, specPath.Child("postRender"))...)
+	}
+	if spec.SkipCRDs && spec.InstallCRDsFirst {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("installCRDsFirst"), spec.InstallCRDsFirst, "may not be set with skipCRDs"))
+	}
+	if r := spec.Recovery; r != nil {
+		switch r.Policy {
+		case "", helmCrdV2.RecoveryWait, helmCrdV2.RecoveryRollback, helmCrdV2.RecoveryRetry:
+		default:
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("recovery", "policy"), r.Policy,
+				[]string{string(helmCrdV2.RecoveryWait), string(helmCrdV2.RecoveryRollback), string(helmCrdV2.RecoveryRetry)}))
+		}
+		if r.Timeout < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("recovery", "timeout"), r.Timeout, "must be greater than or equal to 0"))
+		}
+	}
+	if r := spec.Remediation; r != nil {
+		switch r.Strategy {
+		case "", helmCrdV2.RemediationRollback, helmCrdV2.RemediationUninstallReinstall:
+		default:
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("remediation", "strategy"), r.Strategy,
+				[]string{string(helmCrdV2.RemediationRollback), string(helmCrdV2.RemediationUninstallReinstall)}))
+		}
+		if r.Retries != nil && *r.Retries < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("remediation", "retries"), *r.Retries, "must be greater than or equal to 0"))
+		}
+		if r.Backoff < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("remediation", "backoff"), r.Backoff, "must be greater than or equal to 0"))
+		}
+	}
+	if hc := spec.HealthCheck; hc != nil && hc.Timeout < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("healthCheck", "timeout"), hc.Timeout, "must be greater than or equal to 0"))
+	}
+	for i, hc := range spec.HealthChecks {
+		allErrs = append(allErrs, ValidateHealthCheck(hc, specPath.Child("healthChecks").Index(i))...)
+	}
+	if spec.KubeVersion != "" {
+		if _, err := semver.NewVersion(spec.KubeVersion); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("kubeVersion"), spec.KubeVersion, err.Error()))
+		}
+	}
+	for i, v := range spec.APIVersions {
+		if v == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("apiVersions").Index(i), ""))
+		}
+	}
+	if t := spec.Test; t != nil && t.Timeout < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("test", "timeout"), t.Timeout, "must be greater than or equal to 0"))
+	}
+	if w := spec.UpgradeWindow; w != nil {
+		if _, err := window.Parse(w); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("upgradeWindow"), w, err.Error()))
+		}
+	}
+	if u := spec.Uninstall; u != nil && u.Timeout < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("uninstall", "timeout"), u.Timeout, "must be greater than or equal to 0"))
+	}
+	switch spec.DeletionPolicy {
+	case "", helmCrdV2.DeletionPolicyDelete, helmCrdV2.DeletionPolicyRetain, helmCrdV2.DeletionPolicyDeleteHistoryOnly:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("deletionPolicy"), spec.DeletionPolicy,
+			[]string{string(helmCrdV2.DeletionPolicyDelete), string(helmCrdV2.DeletionPolicyRetain), string(helmCrdV2.DeletionPolicyDeleteHistoryOnly)}))
+	}
+	switch spec.ValuesMergeStrategy {
+	case "", helmCrdV2.ValuesMergeReplaceLists, helmCrdV2.ValuesMergeDeep, helmCrdV2.ValuesMergeOverwrite:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("valuesMergeStrategy"), spec.ValuesMergeStrategy,
+			[]string{string(helmCrdV2.ValuesMergeReplaceLists), string(helmCrdV2.ValuesMergeDeep), string(helmCrdV2.ValuesMergeOverwrite)}))
+	}
+	switch spec.UpdatePolicy {
+	case "", helmCrdV2.UpdatePolicyNone:
+	case helmCrdV2.UpdatePolicyPatch, helmCrdV2.UpdatePolicyMinor, helmCrdV2.UpdatePolicyLatest:
+		policyPath := specPath.Child("updatePolicy")
+		if repo := spec.Chart.Repository; repo == nil {
+			allErrs = append(allErrs, field.Invalid(policyPath, spec.UpdatePolicy, "requires chart.repository"))
+		} else if _, err := semver.NewVersion(repo.Version); err != nil {
+			allErrs = append(allErrs, field.Invalid(policyPath, spec.UpdatePolicy, "requires an exact chart.repository.version"))
+		} else if repo.Digest != "" {
+			allErrs = append(allErrs, field.Invalid(policyPath, spec.UpdatePolicy, "may not be set with chart.repository.digest"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("updatePolicy"), spec.UpdatePolicy,
+			[]string{string(helmCrdV2.UpdatePolicyNone), string(helmCrdV2.UpdatePolicyPatch), string(helmCrdV2.UpdatePolicyMinor), string(helmCrdV2.UpdatePolicyLatest)}))
+	}
+	if dd := spec.DriftDetection; dd != nil {
+		switch dd.Mode {
+		case helmCrdV2.DriftDetectionWarn, helmCrdV2.DriftDetectionCorrect:
+		default:
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("driftDetection", "mode"), dd.Mode,
+				[]string{string(helmCrdV2.DriftDetectionWarn), string(helmCrdV2.DriftDetectionCorrect)}))
+		}
+	}
+	switch spec.UpgradeApproval {
+	case "", helmCrdV2.UpgradeApprovalAutomatic, helmCrdV2.UpgradeApprovalManual:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("upgradeApproval"), spec.UpgradeApproval,
+			[]string{string(helmCrdV2.UpgradeApprovalAutomatic), string(helmCrdV2.UpgradeApprovalManual)}))
+	}
+	if l := spec.Lint; l != nil {
+		switch l.Mode {
+		case "", helmCrdV2.LintWarn, helmCrdV2.LintEnforce:
+		default:
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("lint", "mode"), l.Mode,
+				[]string{string(helmCrdV2.LintWarn), string(helmCrdV2.LintEnforce)}))
+		}
+	}
+	if v := spec.Verification; v != nil {
+		allErrs = append(allErrs, ValidateVerification(v, spec.Chart.OCI != nil, specPath.Child("verification"))...)
+	}
+	allErrs = append(allErrs, ValidateOutputs(spec.Outputs, specPath.Child("outputs"))...)
+	return allErrs
+}
+
+// ValidateVerification checks that the signatures of an OCI chart are
+// verified with keys or keyless identities
+func ValidateVerification(v *helmCrdV2.VerificationSpec, oci bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !oci {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "only the signatures of OCI charts can be verified"))
+	}
+	switch v.Mode {
+	case "", helmCrdV2.VerificationWarn, helmCrdV2.VerificationEnforce:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("mode"), v.Mode,
+			[]string{string(helmCrdV2.VerificationWarn), string(helmCrdV2.VerificationEnforce)}))
+	}
+	if v.SecretRef == nil && len(v.Keyless) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath, "secretRef or keyless must be given"))
+	}
+	if v.SecretRef != nil && v.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("secretRef", "name"), ""))
+	}
+	for i, id := range v.Keyless {
+		idPath := fldPath.Child("keyless").Index(i)
+		for _, f := range []struct{ name, expr string }{{"issuer", id.Issuer}, {"subject", id.Subject}} {
+			if f.expr == "" {
+				allErrs = append(allErrs, field.Required(idPath.Child(f.name), ""))
+			} else if _, err := regexp.Compile(f.expr); err != nil {
+				allErrs = append(allErrs, field.Invalid(idPath.Child(f.name), f.expr, err.Error()))
+			}
+		}
+	}
+	return allErrs
+}
+
+// ValidateOutputs checks that outputs have unique names usable as Secret
+// keys and a single source
+func ValidateOutputs(outputs []helmCrdV2.ReleaseOutput, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, out := range outputs {
+		outPath := fldPath.Index(i)
+		for _, msg := range utilvalidation.IsConfigMapKey(out.Name) {
+			allErrs = append(allErrs, field.Invalid(outPath.Child("name"), out.Name, msg))
+		}
+		if names[out.Name] {
+			allErrs = append(allErrs, field.Duplicate(outPath.Child("name"), out.Name))
+		}
+		names[out.Name] = true
+		if (out.ValuePath == "") == (out.SecretKeyRef == nil) {
+			allErrs = append(allErrs, field.Invalid(outPath, out.Name, "exactly one of valuePath and secretKeyRef must be given"))
+		} else if out.ValuePath != "" {
+			if _, err := valuesUtils.ParsePath(out.ValuePath); err != nil {
+				allErrs = append(allErrs, field.Invalid(outPath.Child("valuePath"), out.ValuePath, err.Error()))
+			}
+		}
+	}
+	return allErrs
+}
+
+// ValidateHelmReleasePolicy returns the errors in a HelmReleasePolicy
+// that would make it reject every HelmRelease
+func ValidateHelmReleasePolicy(p *helmCrdV2.HelmReleasePolicy) field.ErrorList {
+	allErrs := field.ErrorList{}
+	chartsPath := field.NewPath("spec", "charts")
+	for i, rule := range p.Spec.Charts {
+		if rule.Name == "" {
+			allErrs = append(allErrs, field.Required(chartsPath.Index(i).Child("name"), ""))
+		}
+		allErrs = append(allErrs, ValidateVersion(rule.Versions, chartsPath.Index(i).Child("versions"))...)
+	}
+	return allErrs
+}
+
+// ValidateHelmReleaseSet returns the errors in a HelmReleaseSet that
+// would make every reconcile of it, or of its HelmReleases, fail
+func ValidateHelmReleaseSet(set *helmCrdV2.HelmReleaseSet) field.ErrorList {
+	specPath := field.NewPath("spec")
+	templatePath := specPath.Child("template", "spec")
+	allErrs := ValidateHelmReleaseSpec(&set.Spec.Template.Spec, templatePath)
+	if set.Spec.Template.Spec.ReleaseName != "" {
+		allErrs = append(allErrs, field.Forbidden(templatePath.Child("releaseName"), "use releaseNameTemplate to name the releases of a set"))
+	}
+	if sel := set.Spec.NamespaceSelector; sel != nil {
+		if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("namespaceSelector"), sel, err.Error()))
+		}
+	}
+	names := map[string]bool{}
+	for i, item := range set.Spec.Items {
+		itemPath := specPath.Child("items").Index(i)
+		for _, msg := range utilvalidation.IsDNS1123Label(item.Name) {
+			allErrs = append(allErrs, field.Invalid(itemPath.Child("name"), item.Name, msg))
+		}
+		if names[item.Name] {
+			allErrs = append(allErrs, field.Duplicate(itemPath.Child("name"), item.Name))
+		}
+		names[item.Name] = true
+		if item.TargetNamespace != "" {
+			for _, msg := range utilvalidation.IsDNS1123Label(item.TargetNamespace) {
+				allErrs = append(allErrs, field.Invalid(itemPath.Child("targetNamespace"), item.TargetNamespace, msg))
+			}
+		}
+		allErrs = append(allErrs, ValidateValues(string(item.Values), itemPath.Child("values"))...)
+	}
+	return allErrs
+}
+
+// ValidateRepositoryCredential returns the errors in a
+// RepositoryCredential that would make it match no chart source or
+// authenticate nothing
+func ValidateRepositoryCredential(cred *helmCrdV2.RepositoryCredential) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+	prefixesPath := specPath.Child("urlPrefixes")
+	if len(cred.Spec.URLPrefixes) == 0 {
+		allErrs = append(allErrs, field.Required(prefixesPath, ""))
+	}
+	for i, prefix := range cred.Spec.URLPrefixes {
+		u, err := url.Parse(prefix)
+		switch {
+		case err != nil:
+			allErrs = append(allErrs, field.Invalid(prefixesPath.Index(i), prefix, err.Error()))
+		case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "oci":
+			allErrs = append(allErrs, field.Invalid(prefixesPath.Index(i), prefix, "must be an http, https or oci URL"))
+		case u.Host == "":
+			allErrs = append(allErrs, field.Invalid(prefixesPath.Index(i), prefix, "must include a host"))
+		case u.User != nil || u.RawQuery != "" || u.Fragment != "":
+			allErrs = append(allErrs, field.Invalid(prefixesPath.Index(i), prefix, "must not include user info, a query or a fragment"))
+		}
+	}
+	authPath := specPath.Child("auth")
+	auth := &cred.Spec.Auth
+	if auth.Header == nil && len(auth.Headers) == 0 && auth.Bearer == nil && auth.Provider == "" {
+		allErrs = append(allErrs, field.Required(authPath, "header, headers, bearer or provider must be set"))
+	}
+	if auth.Header != nil && auth.Header.SecretKeyRef.Name == "" {
+		allErrs = append(allErrs, field.Required(authPath.Child("header", "secretKeyRef", "name"), ""))
+	}
+	if auth.Bearer != nil && auth.Bearer.SecretKeyRef.Name == "" {
+		allErrs = append(allErrs, field.Required(authPath.Child("bearer", "secretKeyRef", "name"), ""))
+	}
+	for name, header := range auth.Headers {
+		if header.SecretKeyRef.Name == "" {
+			allErrs = append(allErrs, field.Required(authPath.Child("headers").Key(name).Child("secretKeyRef", "name"), ""))
+		}
+	}
+	return append(allErrs, ValidateAuth(auth, authPath)...)
+}
+
+// ValidateChartURL checks that the chart of spec is given either by its
+// chart source or its chartURL, and that chartDigest only pins a chart
+// archive whose digest isn't set otherwise
+func ValidateChartURL(spec *helmCrdV2.HelmReleaseSpec, specPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	digestPath := specPath.Child("chartDigest")
+	allErrs = append(allErrs, ValidateDigest(spec.ChartDigest, digestPath)...)
+	if spec.ChartURL != "" {
+		if !apiequality.Semantic.DeepEqual(spec.Chart, helmCrdV2.ChartSource{}) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("chart"), "", "must not be set with chartURL"))
+		}
+		return append(allErrs, ValidateRepoURL(spec.ChartURL, specPath.Child("chartURL"))...)
+	}
+	allErrs = append(allErrs, ValidateChartSource(&spec.Chart, specPath.Child("chart"))...)
+	if spec.ChartDigest == "" {
+		return allErrs
+	}
+	switch src := spec.Chart; {
+	case src.Inline != nil:
+		allErrs = append(allErrs, field.Forbidden(digestPath, "inline charts are not pinned by digest"))
+	case src.Repository != nil && src.Repository.Digest != "",
+		src.Tarball != nil && src.Tarball.Digest != "",
+		src.OCI != nil && src.OCI.Digest != "":
+		allErrs = append(allErrs, field.Forbidden(digestPath, "the chart source digest is already set"))
+	}
+	return allErrs
+}
+
+// ValidateChartSource checks that exactly one chart location is given and is valid
+func ValidateChartSource(src *helmCrdV2.ChartSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	set := 0
+	for _, given := range []bool{src.Repository != nil, src.Tarball != nil, src.OCI != nil, src.Inline != nil} {
+		if given {
+			set++
+		}
+	}
+	if set > 1 {
+		return append(allErrs, field.Invalid(fldPath, "", "exactly one chart source must be given"))
+	}
+	if o := src.OCI; o != nil {
+		ociPath := fldPath.Child("oci")
+		if o.URL == "" {
+			allErrs = append(allErrs, field.Required(ociPath.Child("url"), ""))
+		} else if _, err := oci.ParseReference(o.URL); err != nil {
+			allErrs = append(allErrs, field.Invalid(ociPath.Child("url"), o.URL, err.Error()))
+		}
+		if o.Version == "" {
+			allErrs = append(allErrs, field.Required(ociPath.Child("version"), ""))
+		}
+		allErrs = append(allErrs, ValidateDigest(o.Digest, ociPath.Child("digest"))...)
+		allErrs = append(allErrs, ValidateAuth(&o.Auth, ociPath.Child("auth"))...)
+		return allErrs
+	}
+	if src.Inline != nil {
+		return append(allErrs, ValidateInlineChart(src.Inline, fldPath.Child("inline"))...)
+	}
+	if t := src.Tarball; t != nil {
+		tarballPath := fldPath.Child("tarball")
+		if t.URL == "" {
+			allErrs = append(allErrs, field.Required(tarballPath.Child("url"), ""))
+		}
+		allErrs = append(allErrs, ValidateRepoURL(t.URL, tarballPath.Child("url"))...)
+		allErrs = append(allErrs, ValidateDigest(t.Digest, tarballPath.Child("digest"))...)
+		allErrs = append(allErrs, ValidateAuth(&t.Auth, tarballPath.Child("auth"))...)
+		return allErrs
+	}
+	if src.Repository == nil {
+		return append(allErrs, field.Required(fldPath, "a chart source must be given"))
+	}
+	repoPath := fldPath.Child("repository")
+	if src.Repository.Name == "" {
+		allErrs = append(allErrs, field.Required(repoPath.Child("name"), ""))
+	}
+	allErrs = append(allErrs, ValidateRepoURL(src.Repository.URL, repoPath.Child("url"))...)
+	for i, mirror := range src.Repository.Mirrors {
+		mirrorPath := repoPath.Child("mirrors").Index(i)
+		if mirror == "" {
+			allErrs = append(allErrs, field.Required(mirrorPath, ""))
+			continue
+		}
+		allErrs = append(allErrs, ValidateRepoURL(mirror, mirrorPath)...)
+	}
+	allErrs = append(allErrs, ValidateVersion(src.Repository.Version, repoPath.Child("version"))...)
+	allErrs = append(allErrs, ValidateDigest(src.Repository.Digest, repoPath.Child("digest"))...)
+	allErrs = append(allErrs, ValidateAuth(&src.Repository.Auth, repoPath.Child("auth"))...)
+	return allErrs
+}
+
+// ValidateInlineChart checks that the inline chart has a name, a SemVer 2
+// version and templates within templates/
+func ValidateInlineChart(src *helmCrdV2.InlineChartSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	metaPath := fldPath.Child("metadata")
+	if src.Metadata.Name == "" {
+		allErrs = append(allErrs, field.Required(metaPath.Child("name"), ""))
+	}
+	if src.Metadata.Version == "" {
+		allErrs = append(allErrs, field.Required(metaPath.Child("version"), ""))
+	} else if _, err := semver.NewVersion(src.Metadata.Version); err != nil {
+		allErrs = append(allErrs, field.Invalid(metaPath.Child("version"), src.Metadata.Version, err.Error()))
+	}
+	if len(src.Templates) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("templates"), ""))
+	}
+	for name := range src.Templates {
+		if clean := path.Clean(name); clean != name || path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("templates").Key(name), name, "must be a relative path within templates/"))
+		}
+	}
+	allErrs = append(allErrs, ValidateValues(string(src.Values), fldPath.Child("values"))...)
+	return allErrs
+}
+
+// ValidateAuth checks the scope of the auth secret of a chart source, its
+// OAuth2 token endpoint and its cloud provider
+func ValidateAuth(auth *helmCrdV2.HelmReleaseAuth, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch auth.Provider {
+	case "", helmCrdV2.AuthProviderGCP, helmCrdV2.AuthProviderAzure, helmCrdV2.AuthProviderAWS:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("provider"), auth.Provider, []string{string(helmCrdV2.AuthProviderGCP), string(helmCrdV2.AuthProviderAzure), string(helmCrdV2.AuthProviderAWS)}))
+	}
+	if auth.Bearer != nil {
+		bearerPath := fldPath.Child("bearer")
+		allErrs = append(allErrs, validateAuthScope(auth.Bearer.Scope, bearerPath.Child("scope"))...)
+		if auth.Bearer.TokenURL != "" {
+			allErrs = append(allErrs, ValidateRepoURL(auth.Bearer.TokenURL, bearerPath.Child("tokenURL"))...)
+			if auth.Bearer.ClientID == "" {
+				allErrs = append(allErrs, field.Required(bearerPath.Child("clientID"), "required with tokenURL"))
+			}
+		} else if auth.Bearer.ClientID != "" || len(auth.Bearer.Scopes) > 0 {
+			allErrs = append(allErrs, field.Invalid(bearerPath.Child("tokenURL"), "", "clientID and scopes may only be set with tokenURL"))
+		}
+		if auth.Header != nil {
+			allErrs = append(allErrs, field.Invalid(bearerPath, "", "header and bearer are mutually exclusive"))
+		}
+		if auth.Provider != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("provider"), auth.Provider, "bearer and provider are mutually exclusive"))
+		}
+	}
+	if len(auth.Headers) > 0 {
+		allErrs = append(allErrs, validateAuthHeaders(auth, fldPath)...)
+	}
+	if auth.Header == nil {
+		return allErrs
+	}
+	allErrs = append(allErrs, validateAuthScope(auth.Header.Scope, fldPath.Child("header", "scope"))...)
+	if auth.Provider != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("provider"), auth.Provider, "header and provider are mutually exclusive"))
+	}
+	return allErrs
+}
+
+// validateAuthHeaders checks the names and scopes of the headers of auth,
+// all of their secrets being read from the same namespace
+func validateAuthHeaders(auth *helmCrdV2.HelmReleaseAuth, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	headersPath := fldPath.Child("headers")
+	if auth.Header != nil {
+		allErrs = append(allErrs, field.Invalid(headersPath, "", "header and headers are mutually exclusive"))
+	}
+	scope, scopeSet := helmCrdV2.AuthScope(""), false
+	if auth.Bearer != nil {
+		scope, scopeSet = auth.Bearer.Scope, true
+	}
+	names := make([]string, 0, len(auth.Headers))
+	for name := range auth.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := auth.Headers[name]
+		headerPath := headersPath.Key(name)
+		if !httpguts.ValidHeaderFieldName(name) {
+			allErrs = append(allErrs, field.Invalid(headerPath, name, "must be a valid HTTP header name"))
+		}
+		if http.CanonicalHeaderKey(name) == "Authorization" && (auth.Bearer != nil || auth.Provider != "") {
+			allErrs = append(allErrs, field.Invalid(headerPath, name, "may not be set with bearer or provider"))
+		}
+		allErrs = append(allErrs, validateAuthScope(header.Scope, headerPath.Child("scope"))...)
+		if scopeSet && header.Scope != scope {
+			allErrs = append(allErrs, field.Invalid(headerPath.Child("scope"), header.Scope, "all secrets of auth must have the same scope"))
+		}
+		scope, scopeSet = header.Scope, true
+	}
+	return allErrs
+}
+
+func validateAuthScope(scope helmCrdV2.AuthScope, fldPath *field.Path) field.ErrorList {
+	switch scope {
+	case "", helmCrdV2.AuthScopeController, helmCrdV2.AuthScopeNamespace:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath, scope, []string{string(helmCrdV2.AuthScopeController), string(helmCrdV2.AuthScopeNamespace)})}
+}
+
+// ValidateValuesSource checks that exactly one values source is given and is valid
+func ValidateValuesSource(src *helmCrdV2.ValuesSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	set := 0
+	if src.ConfigMapKeyRef != nil {
+		set++
+	}
+	if src.SecretKeyRef != nil {
+		set++
+	}
+	if src.FieldRef != nil {
+		set++
+		allErrs = append(allErrs, ValidateFieldRef(src.FieldRef, fldPath.Child("fieldRef"))...)
+		if src.Sops {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sops"), src.Sops, "fields can't be SOPS encrypted"))
+		}
+	}
+	if ref := src.HelmReleaseRef; ref != nil {
+		set++
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("helmReleaseRef", "name"), ""))
+		}
+		if ref.TargetPath != "" {
+			if ref.Output == "" {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("helmReleaseRef", "targetPath"), ref.TargetPath, "may only be set with output"))
+			} else if _, err := valuesUtils.Set(ref.TargetPath, nil); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("helmReleaseRef", "targetPath"), ref.TargetPath, err.Error()))
+			}
+		}
+		if src.Sops {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sops"), src.Sops, "outputs can't be SOPS encrypted"))
+		}
+	}
+	if src.URL != "" {
+		set++
+		allErrs = append(allErrs, ValidateRepoURL(src.URL, fldPath.Child("url"))...)
+	} else if src.AuthSecretKeyRef != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("authSecretKeyRef"), src.AuthSecretKeyRef.Name, "may only be set with url"))
+	}
+	if set != 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "exactly one values source must be given"))
+	}
+	return allErrs
+}
+
+// ValidateFieldRef checks that an object and valid field and values paths are given
+func ValidateFieldRef(ref *helmCrdV2.ObjectFieldRef, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if ref.APIVersion == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("apiVersion"), ""))
+	}
+	if ref.Kind == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("kind"), ""))
+	}
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+	if _, err := valuesUtils.ParsePath(ref.FieldPath); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("fieldPath"), ref.FieldPath, err.Error()))
+	}
+	if _, err := valuesUtils.Set(ref.TargetPath, nil); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("targetPath"), ref.TargetPath, err.Error()))
+	}
+	return allErrs
+}
+
+// ValidateHealthCheck checks that exactly one of a Job, a URL or a
+// resource field is checked
+func ValidateHealthCheck(hc helmCrdV2.HealthCheck, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	set := 0
+	if hc.Job != "" {
+		set++
+	}
+	if hc.URL != "" {
+		set++
+		allErrs = append(allErrs, ValidateRepoURL(hc.URL, fldPath.Child("url"))...)
+	}
+	if r := hc.Resource; r != nil {
+		set++
+		rPath := fldPath.Child("resource")
+		if r.APIVersion == "" {
+			allErrs = append(allErrs, field.Required(rPath.Child("apiVersion"), ""))
+		}
+		if r.Kind == "" {
+			allErrs = append(allErrs, field.Required(rPath.Child("kind"), ""))
+		}
+		if r.Name == "" {
+			allErrs = append(allErrs, field.Required(rPath.Child("name"), ""))
+		}
+		if _, err := valuesUtils.ParsePath(r.FieldPath); err != nil {
+			allErrs = append(allErrs, field.Invalid(rPath.Child("fieldPath"), r.FieldPath, err.Error()))
+		}
+	}
+	if set != 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "exactly one of job, url or resource must be given"))
+	}
+	return allErrs
+}
+
+// ValidatePostRender checks that patches are YAML objects selecting their target
+func ValidatePostRender(pr *helmCrdV2.PostRenderSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if k := pr.Kustomize; k != nil {
+		kPath := fldPath.Child("kustomize")
+		for i, p := range k.PatchesStrategicMerge {
+			patch := struct {
+				Kind     string `json:"kind"`
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			}{}
+			if err := yaml.Unmarshal([]byte(p), &patch); err != nil {
+				allErrs = append(allErrs, field.Invalid(kPath.Child("patchesStrategicMerge").Index(i), "", fmt.Sprintf("invalid YAML: %v", err)))
+			} else if patch.Kind == "" || patch.Metadata.Name == "" {
+				allErrs = append(allErrs, field.Invalid(kPath.Child("patchesStrategicMerge").Index(i), "", "kind and metadata.name are required"))
+			}
+		}
+		for i, img := range k.Images {
+			if img.Name == "" {
+				allErrs = append(allErrs, field.Required(kPath.Child("images").Index(i).Child("name"), ""))
+			}
+		}
+	}
+	for i, p := range pr.PatchesJSON6902 {
+		ops := []struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{}
+		pPath := fldPath.Child("patchesJson6902").Index(i).Child("patch")
+		if err := yaml.Unmarshal([]byte(p.Patch), &ops); err != nil {
+			allErrs = append(allErrs, field.Invalid(pPath, "", fmt.Sprintf("invalid JSON patch: %v", err)))
+			continue
+		}
+		for _, op := range ops {
+			switch op.Op {
+			case "add", "remove", "replace", "move", "copy", "test":
+			default:
+				allErrs = append(allErrs, field.Invalid(pPath, op.Op, "unknown JSON patch operation"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// ValidateRepoURL checks that the repository URL, if set, is an absolute http(s) URL
+func ValidateRepoURL(repoURL string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if repoURL == "" {
+		return allErrs
+	}
+	u, err := url.ParseRequestURI(repoURL)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, repoURL, err.Error()))
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		allErrs = append(allErrs, field.Invalid(fldPath, repoURL, "must be an http or https URL"))
+	} else if u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath, repoURL, "must include a host"))
+	}
+	return allErrs
+}
+
+// ValidateReleaseName checks that the release name, if set, is accepted by Tiller
+func ValidateReleaseName(name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if name == "" {
+		return allErrs
+	}
+	if len(name) > MaxReleaseNameLen {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, utilvalidation.MaxLenError(MaxReleaseNameLen)))
+	}
+	for _, msg := range utilvalidation.IsDNS1123Subdomain(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, msg))
+	}
+	return allErrs
+}
+
+// ValidateReleaseNameTemplate checks that the release name template
+// parses and renders
+func ValidateReleaseNameTemplate(tmpl string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if _, err := releasename.Render(tmpl, "namespace", "name"); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, tmpl, err.Error()))
+	}
+	return allErrs
+}
+
+// ValidateVersion checks that the chart version, if set, is a valid
+// semver version or range
+func ValidateVersion(version string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if version == "" {
+		return allErrs
+	}
+	if _, err := semver.NewConstraint(version); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, version, err.Error()))
+	}
+	return allErrs
+}
+
+// digestPattern matches hex encoded SHA-256 digests, optionally prefixed
+// with their algorithm
+var digestPattern = regexp.MustCompile(`^(sha256:)?[0-9a-f]{64}$`)
+
+// ValidateDigest checks that the chart digest, if set, is a SHA-256 digest
+func ValidateDigest(digest string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if digest != "" && !digestPattern.MatchString(digest) {
+		allErrs = append(allErrs, field.Invalid(fldPath, digest, "must be a hex encoded SHA-256 digest, optionally prefixed with \"sha256:\""))
+	}
+	return allErrs
+}
+
+// ValidateValues checks that the values, if set, are a YAML map
+func ValidateValues(values string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if values == "" {
+		return allErrs
+	}
+	parsed := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", fmt.Sprintf("invalid YAML: %v", err)))
+	}
+	return allErrs
+}
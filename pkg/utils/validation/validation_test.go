@@ -0,0 +1,623 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateHelmRelease(t *testing.T) {
+	negative := int32(-1)
+	tests := []struct {
+		name          string
+		spec          helmCrdV2.HelmReleaseSpec
+		expectedField string
+	}{
+		{
+			"valid",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", URL: "https://charts.example.com/", Version: "^1.0.0"}}, Values: "foo: bar\n"},
+			"",
+		},
+		{
+			"valid tarball",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{
+				URL:    "https://charts.example.com/foo-1.0.0.tgz",
+				Digest: "sha256:" + strings.Repeat("0a", 32),
+			}}},
+			"",
+		},
+		{
+			"valid chart url",
+			helmCrdV2.HelmReleaseSpec{ChartURL: "https://charts.example.com/foo-1.0.0.tgz", ChartDigest: "sha256:" + strings.Repeat("0a", 32)},
+			"",
+		},
+		{
+			"valid chart digest of a repository chart",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Version: "1.0.0"}}, ChartDigest: strings.Repeat("0a", 32)},
+			"",
+		},
+		{
+			"valid oci with verification",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo", Version: "1.0.0"}}, Verification: &helmCrdV2.VerificationSpec{
+				SecretRef: &corev1.LocalObjectReference{Name: "cosign"},
+				Keyless:   []helmCrdV2.KeylessIdentity{{Issuer: "^https://token\\.actions\\.githubusercontent\\.com$", Subject: "^https://github\\.com/org/"}},
+			}},
+			"",
+		},
+		{
+			"valid inline chart",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{
+				Metadata:  helmCrdV2.InlineChartMetadata{Name: "glue", Version: "0.1.0"},
+				Templates: map[string]string{"configmap.yaml": "kind: ConfigMap", "_helpers.tpl": "", "crds/foo.yaml": ""},
+				Values:    "foo: bar\n",
+			}}},
+			"",
+		},
+		{
+			"invalid oci url",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "https://registry.example.com/charts/foo", Version: "1.0.0"}}},
+			"spec.chart.oci.url",
+		},
+		{
+			"missing oci version",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo"}}},
+			"spec.chart.oci.version",
+		},
+		{
+			"oci and tarball",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo", Version: "1.0.0"},
+				Tarball: &helmCrdV2.TarballChartSource{URL: "https://charts.example.com/foo-1.0.0.tgz"}}},
+			"spec.chart",
+		},
+		{
+			"verification of a repository chart",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Verification: &helmCrdV2.VerificationSpec{SecretRef: &corev1.LocalObjectReference{Name: "cosign"}}},
+			"spec.verification",
+		},
+		{
+			"verification without keys",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo", Version: "1.0.0"}}, Verification: &helmCrdV2.VerificationSpec{Mode: helmCrdV2.VerificationWarn}},
+			"spec.verification",
+		},
+		{
+			"unknown verification mode",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo", Version: "1.0.0"}}, Verification: &helmCrdV2.VerificationSpec{Mode: "strict", SecretRef: &corev1.LocalObjectReference{Name: "cosign"}}},
+			"spec.verification.mode",
+		},
+		{
+			"invalid keyless subject",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/charts/foo", Version: "1.0.0"}}, Verification: &helmCrdV2.VerificationSpec{
+				Keyless: []helmCrdV2.KeylessIdentity{{Issuer: "^https://accounts\\.google\\.com$", Subject: "(unclosed"}},
+			}},
+			"spec.verification.keyless[0].subject",
+		},
+		{
+			"missing chart source",
+			helmCrdV2.HelmReleaseSpec{},
+			"spec.chart",
+		},
+		{
+			"missing chart name",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{}}},
+			"spec.chart.repository.name",
+		},
+		{
+			"relative repo url",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", URL: "charts.example.com"}}},
+			"spec.chart.repository.url",
+		},
+		{
+			"non http repo url",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", URL: "ftp://charts.example.com"}}},
+			"spec.chart.repository.url",
+		},
+		{
+			"invalid mirror",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Mirrors: []string{"https://mirror.example.com/", "mirror.example.com"}}}},
+			"spec.chart.repository.mirrors[1]",
+		},
+		{
+			"skipped CRDs installed first",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, SkipCRDs: true, InstallCRDsFirst: true},
+			"spec.installCRDsFirst",
+		},
+		{
+			"tarball and repository",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{
+				Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"},
+				Tarball:    &helmCrdV2.TarballChartSource{URL: "https://charts.example.com/foo-1.0.0.tgz"},
+			}},
+			"spec.chart",
+		},
+		{
+			"tarball without url",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{}}},
+			"spec.chart.tarball.url",
+		},
+		{
+			"invalid tarball digest",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{URL: "https://charts.example.com/foo-1.0.0.tgz", Digest: "md5:1234"}}},
+			"spec.chart.tarball.digest",
+		},
+		{
+			"chart url and chart source",
+			helmCrdV2.HelmReleaseSpec{ChartURL: "https://charts.example.com/foo-1.0.0.tgz", Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}},
+			"spec.chart",
+		},
+		{
+			"invalid chart url",
+			helmCrdV2.HelmReleaseSpec{ChartURL: "oci://registry.example.com/charts/foo"},
+			"spec.chartURL",
+		},
+		{
+			"invalid chart digest",
+			helmCrdV2.HelmReleaseSpec{ChartURL: "https://charts.example.com/foo-1.0.0.tgz", ChartDigest: "md5:1234"},
+			"spec.chartDigest",
+		},
+		{
+			"chart digest with a chart source digest",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{URL: "https://charts.example.com/foo-1.0.0.tgz", Digest: strings.Repeat("0a", 32)}},
+				ChartDigest: strings.Repeat("0b", 32)},
+			"spec.chartDigest",
+		},
+		{
+			"chart digest of an inline chart",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{Metadata: helmCrdV2.InlineChartMetadata{Name: "glue", Version: "0.1.0"},
+				Templates: map[string]string{"configmap.yaml": "kind: ConfigMap"}}},
+				ChartDigest: strings.Repeat("0a", 32)},
+			"spec.chartDigest",
+		},
+		{
+			"invalid repository digest",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Digest: "1234"}}},
+			"spec.chart.repository.digest",
+		},
+		{
+			"uppercase release name",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ReleaseName: "Foo"},
+			"spec.releaseName",
+		},
+		{
+			"too long release name",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ReleaseName: strings.Repeat("a", MaxReleaseNameLen+1)},
+			"spec.releaseName",
+		},
+		{
+			"unknown release name template variable",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ReleaseNameTemplate: "{{ .Release }}"},
+			"spec.releaseNameTemplate",
+		},
+		{
+			"invalid version",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Version: "not-a-version"}}},
+			"spec.chart.repository.version",
+		},
+		{
+			"invalid tiller host",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, TillerHost: "tiller-deploy.team-a"},
+			"spec.tillerHost",
+		},
+		{
+			"invalid tiller namespace",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, TillerNamespace: "Team_A"},
+			"spec.tillerNamespace",
+		},
+		{
+			"invalid service account name",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ServiceAccountName: "Deployer"},
+			"spec.serviceAccountName",
+		},
+		{
+			"service account of remote release",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ServiceAccountName: "deployer",
+				KubeConfigSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "prod"}, Key: "value"}},
+			"spec.serviceAccountName",
+		},
+		{
+			"kubeconfig without key",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				KubeConfigSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "prod"}}},
+			"spec.kubeConfigSecretRef.key",
+		},
+		{
+			"invalid target namespace",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, TargetNamespace: "my.ns"},
+			"spec.targetNamespace",
+		},
+		{
+			"namespace metadata without createNamespace",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				NamespaceMetadata: &helmCrdV2.NamespaceMetadata{Labels: map[string]string{"team": "a"}}},
+			"spec.namespaceMetadata",
+		},
+		{
+			"negative test timeout",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Test: &helmCrdV2.TestSpec{Enable: true, Timeout: -1}},
+			"spec.test.timeout",
+		},
+		{
+			"unknown recovery policy",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Recovery: &helmCrdV2.RecoverySpec{Policy: "ignore"}},
+			"spec.recovery.policy",
+		},
+		{
+			"negative recovery timeout",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Recovery: &helmCrdV2.RecoverySpec{Policy: helmCrdV2.RecoveryRetry, Timeout: -1}},
+			"spec.recovery.timeout",
+		},
+		{
+			"unknown remediation strategy",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Remediation: &helmCrdV2.RemediationSpec{Strategy: "reinstall"}},
+			"spec.remediation.strategy",
+		},
+		{
+			"negative remediation backoff",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Remediation: &helmCrdV2.RemediationSpec{Backoff: -1}},
+			"spec.remediation.backoff",
+		},
+		{
+			"negative health check timeout",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				HealthCheck: &helmCrdV2.HealthCheckSpec{Enable: true, Timeout: -1}},
+			"spec.healthCheck.timeout",
+		},
+		{
+			"health check of both a job and a URL",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				HealthChecks: []helmCrdV2.HealthCheck{{Job: "migrate"}, {Job: "seed", URL: "http://foo.example.com/healthz"}}},
+			"spec.healthChecks[1]",
+		},
+		{
+			"health check of a resource without field",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				HealthChecks: []helmCrdV2.HealthCheck{{Resource: &helmCrdV2.ResourceHealthCheck{APIVersion: "example.com/v1", Kind: "Database", Name: "foo"}}}},
+			"spec.healthChecks[0].resource.fieldPath",
+		},
+		{
+			"invalid kube version",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, KubeVersion: "one.twelve"},
+			"spec.kubeVersion",
+		},
+		{
+			"empty API version",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, APIVersions: []string{"apps/v1", ""}},
+			"spec.apiVersions[1]",
+		},
+		{
+			"inline chart without version",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{
+				Metadata:  helmCrdV2.InlineChartMetadata{Name: "glue"},
+				Templates: map[string]string{"configmap.yaml": "kind: ConfigMap"},
+			}}},
+			"spec.chart.inline.metadata.version",
+		},
+		{
+			"inline chart template outside templates/",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Inline: &helmCrdV2.InlineChartSource{
+				Metadata:  helmCrdV2.InlineChartMetadata{Name: "glue", Version: "0.1.0"},
+				Templates: map[string]string{"../Chart.yaml": "name: other"},
+			}}},
+			"spec.chart.inline.templates[../Chart.yaml]",
+		},
+		{
+			"inline and repository charts",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{
+				Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"},
+				Inline: &helmCrdV2.InlineChartSource{
+					Metadata:  helmCrdV2.InlineChartMetadata{Name: "glue", Version: "0.1.0"},
+					Templates: map[string]string{"configmap.yaml": "kind: ConfigMap"},
+				},
+			}},
+			"spec.chart",
+		},
+		{
+			"invalid upgrade window",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				UpgradeWindow: &helmCrdV2.UpgradeWindow{Schedule: "0 2 * * Sat"}},
+			"spec.upgradeWindow",
+		},
+		{
+			"update policy of a version range",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Version: "^1.0.0"}},
+				UpdatePolicy: helmCrdV2.UpdatePolicyMinor},
+			"spec.updatePolicy",
+		},
+		{
+			"unknown update policy",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo", Version: "1.0.0"}},
+				UpdatePolicy: "Major"},
+			"spec.updatePolicy",
+		},
+		{
+			"unknown values merge strategy",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesMergeStrategy: "shallow"},
+			"spec.valuesMergeStrategy",
+		},
+		{
+			"unknown upgrade approval",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				UpgradeApproval: "Auto"},
+			"spec.upgradeApproval",
+		},
+		{
+			"unknown lint mode",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Lint: &helmCrdV2.LintSpec{Mode: "strict"}},
+			"spec.lint.mode",
+		},
+		{
+			"invalid propagated label",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				PropagateMetadata: &helmCrdV2.PropagateMetadata{Labels: []string{"team", "cost center"}}},
+			"spec.propagateMetadata.labels[1]",
+		},
+		{
+			"unknown auth scope",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{Scope: "Cluster"}}}}},
+			"spec.chart.repository.auth.header.scope",
+		},
+		{
+			"unknown auth provider",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{URL: "https://example.com/foo-1.0.0.tgz",
+				Auth: helmCrdV2.HelmReleaseAuth{Provider: "IBM"}}}},
+			"spec.chart.tarball.auth.provider",
+		},
+		{
+			"auth header and provider",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{}, Provider: helmCrdV2.AuthProviderGCP}}}},
+			"spec.chart.repository.auth.provider",
+		},
+		{
+			"bearer token url",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Bearer: &helmCrdV2.HelmReleaseAuthBearer{TokenURL: "ftp://idp.example.com/token", ClientID: "controller"}}}}},
+			"spec.chart.repository.auth.bearer.tokenURL",
+		},
+		{
+			"bearer token url without client id",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{OCI: &helmCrdV2.OCIChartSource{URL: "oci://registry.example.com/foo", Version: "1.0.0",
+				Auth: helmCrdV2.HelmReleaseAuth{Bearer: &helmCrdV2.HelmReleaseAuthBearer{TokenURL: "https://idp.example.com/token"}}}}},
+			"spec.chart.oci.auth.bearer.clientID",
+		},
+		{
+			"auth header and bearer",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{}, Bearer: &helmCrdV2.HelmReleaseAuthBearer{}}}}},
+			"spec.chart.repository.auth.bearer",
+		},
+		{
+			"invalid auth header name",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Headers: map[string]helmCrdV2.HelmReleaseAuthHeader{"X API Key": {}}}}}},
+			"spec.chart.repository.auth.headers[X API Key]",
+		},
+		{
+			"auth headers of different scopes",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo",
+				Auth: helmCrdV2.HelmReleaseAuth{Bearer: &helmCrdV2.HelmReleaseAuthBearer{Scope: helmCrdV2.AuthScopeNamespace},
+					Headers: map[string]helmCrdV2.HelmReleaseAuthHeader{"X-API-Key": {}}}}}},
+			"spec.chart.repository.auth.headers[X-API-Key].scope",
+		},
+		{
+			"auth headers authorization and provider",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Tarball: &helmCrdV2.TarballChartSource{URL: "https://example.com/foo-1.0.0.tgz",
+				Auth: helmCrdV2.HelmReleaseAuth{Provider: helmCrdV2.AuthProviderGCP, Headers: map[string]helmCrdV2.HelmReleaseAuthHeader{"authorization": {}}}}}},
+			"spec.chart.tarball.auth.headers[authorization]",
+		},
+		{
+			"invalid namespace label",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, CreateNamespace: true,
+				NamespaceMetadata: &helmCrdV2.NamespaceMetadata{Labels: map[string]string{"team": "a b"}}},
+			"spec.namespaceMetadata.labels[team]",
+		},
+		{
+			"empty values source",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, ValuesFrom: []helmCrdV2.ValuesSource{{}}},
+			"spec.valuesFrom[0]",
+		},
+		{
+			"ambiguous values source",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesFrom: []helmCrdV2.ValuesSource{{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "values.yaml"},
+					SecretKeyRef:    &corev1.SecretKeySelector{Key: "values.yaml"},
+				}},
+			},
+			"spec.valuesFrom[0]",
+		},
+		{
+			"field reference without target path",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesFrom: []helmCrdV2.ValuesSource{{FieldRef: &helmCrdV2.ObjectFieldRef{
+					APIVersion: "v1", Kind: "Service", Name: "db", FieldPath: "spec.clusterIP",
+				}}},
+			},
+			"spec.valuesFrom[0].fieldRef.targetPath",
+		},
+		{
+			"relative values url",
+			helmCrdV2.HelmReleaseSpec{
+				Chart:      helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesFrom: []helmCrdV2.ValuesSource{{URL: "values.example.com/values.yaml"}},
+			},
+			"spec.valuesFrom[0].url",
+		},
+		{
+			"values auth without url",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesFrom: []helmCrdV2.ValuesSource{{
+					ConfigMapKeyRef:  &corev1.ConfigMapKeySelector{Key: "values.yaml"},
+					AuthSecretKeyRef: &corev1.SecretKeySelector{Key: "token"},
+				}},
+			},
+			"spec.valuesFrom[0].authSecretKeyRef",
+		},
+		{
+			"helm release output target path without output",
+			helmCrdV2.HelmReleaseSpec{
+				Chart:      helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				ValuesFrom: []helmCrdV2.ValuesSource{{HelmReleaseRef: &helmCrdV2.HelmReleaseOutputRef{Name: "db", TargetPath: "database"}}},
+			},
+			"spec.valuesFrom[0].helmReleaseRef.targetPath",
+		},
+		{
+			"duplicate output",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Outputs: []helmCrdV2.ReleaseOutput{
+					{Name: "username", ValuePath: "auth.username"},
+					{Name: "username", ValuePath: "auth.user"},
+				},
+			},
+			"spec.outputs[1].name",
+		},
+		{
+			"output without source",
+			helmCrdV2.HelmReleaseSpec{
+				Chart:   helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				Outputs: []helmCrdV2.ReleaseOutput{{Name: "password"}},
+			},
+			"spec.outputs[0]",
+		},
+		{
+			"negative retries",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, Retries: &negative},
+			"spec.retries",
+		},
+		{
+			"unknown deletion policy",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, DeletionPolicy: "Orphan"},
+			"spec.deletionPolicy",
+		},
+		{
+			"unknown drift detection mode",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, DriftDetection: &helmCrdV2.DriftDetectionSpec{Mode: "fix"}},
+			"spec.driftDetection.mode",
+		},
+		{
+			"post-render patch without target",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				PostRender: &helmCrdV2.PostRenderSpec{Kustomize: &helmCrdV2.KustomizeSpec{
+					PatchesStrategicMerge: []string{"spec:\n  replicas: 2\n"},
+				}},
+			},
+			"spec.postRender.kustomize.patchesStrategicMerge[0]",
+		},
+		{
+			"unknown JSON patch operation",
+			helmCrdV2.HelmReleaseSpec{
+				Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}},
+				PostRender: &helmCrdV2.PostRenderSpec{PatchesJSON6902: []helmCrdV2.JSON6902Patch{
+					{Target: helmCrdV2.PatchTarget{Kind: "Deployment"}, Patch: `[{"op": "merge", "path": "/spec"}]`},
+				}},
+			},
+			"spec.postRender.patchesJson6902[0].patch",
+		},
+		{
+			"invalid values",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, Values: "foo: [bar"},
+			"spec.values",
+		},
+		{
+			"values not a map",
+			helmCrdV2.HelmReleaseSpec{Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "foo"}}, Values: "- foo"},
+			"spec.values",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateHelmRelease(&helmCrdV2.HelmRelease{Spec: tt.spec})
+			if tt.expectedField == "" {
+				if len(errs) != 0 {
+					t.Errorf("Unexpected errors %v", errs)
+				}
+				return
+			}
+			if len(errs) == 0 {
+				t.Fatalf("Expecting an error for %s", tt.expectedField)
+			}
+			if errs[0].Field != tt.expectedField {
+				t.Errorf("Expecting error for %s received %v", tt.expectedField, errs)
+			}
+		})
+	}
+}
+
+func TestValidateHelmReleasePolicy(t *testing.T) {
+	p := &helmCrdV2.HelmReleasePolicy{Spec: helmCrdV2.HelmReleasePolicySpec{
+		Charts: []helmCrdV2.ChartRule{{Name: "mysql", Versions: "^1.0.0"}, {Name: "redis"}},
+	}}
+	if errs := ValidateHelmReleasePolicy(p); len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	p.Spec.Charts[1] = helmCrdV2.ChartRule{Versions: "not a range"}
+	errs := ValidateHelmReleasePolicy(p)
+	if len(errs) != 2 || errs[0].Field != "spec.charts[1].name" || errs[1].Field != "spec.charts[1].versions" {
+		t.Errorf("Expecting errors for spec.charts[1].name and versions, received %v", errs)
+	}
+}
+
+func TestValidateHelmReleaseSet(t *testing.T) {
+	set := &helmCrdV2.HelmReleaseSet{Spec: helmCrdV2.HelmReleaseSetSpec{
+		Template: helmCrdV2.HelmReleaseTemplate{Spec: helmCrdV2.HelmReleaseSpec{
+			Chart: helmCrdV2.ChartSource{Repository: &helmCrdV2.RepositoryChartSource{Name: "wordpress"}},
+		}},
+		Items: []helmCrdV2.HelmReleaseSetItem{{Name: "tenant-a", Values: "replicas: 3"}, {Name: "tenant-b"}},
+	}}
+	if errs := ValidateHelmReleaseSet(set); len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	set.Spec.Template.Spec.ReleaseName = "wordpress"
+	set.Spec.Items[1] = helmCrdV2.HelmReleaseSetItem{Name: "tenant-a", Values: "- not a map"}
+	errs := ValidateHelmReleaseSet(set)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	expected := []string{"spec.template.spec.releaseName", "spec.items[1].name", "spec.items[1].values"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expecting errors for %v, received %v", expected, errs)
+	}
+}
+
+func TestValidateRepositoryCredential(t *testing.T) {
+	cred := &helmCrdV2.RepositoryCredential{Spec: helmCrdV2.RepositoryCredentialSpec{
+		URLPrefixes: []string{"https://charts.example.com/private/", "oci://registry.example.com/myorg/"},
+		Auth: helmCrdV2.HelmReleaseAuth{Header: &helmCrdV2.HelmReleaseAuthHeader{
+			SecretKeyRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: "authorization"},
+		}},
+	}}
+	if errs := ValidateRepositoryCredential(cred); len(errs) != 0 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	cred.Spec.URLPrefixes = []string{"ftp://charts.example.com/", "/charts/", "https://user@charts.example.com/?ref=main"}
+	cred.Spec.Auth = helmCrdV2.HelmReleaseAuth{}
+	errs := ValidateRepositoryCredential(cred)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	expected := []string{"spec.urlPrefixes[0]", "spec.urlPrefixes[1]", "spec.urlPrefixes[2]", "spec.auth"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expecting errors for %v, received %v", expected, errs)
+	}
+}
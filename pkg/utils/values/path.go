@@ -0,0 +1,104 @@
+package values
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePath splits a dotted field path into its segments. Keys containing
+// dots are quoted in brackets (data['app.conf']) and list items are
+// selected by index (spec.ports[0]). Indices are returned as ints.
+func ParsePath(path string) ([]interface{}, error) {
+	var segments []interface{}
+	rest := path
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			quote := rest[1:2]
+			end := strings.Index(rest[2:], quote+"]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key in path %q", path)
+			}
+			segments = append(segments, rest[2:2+end])
+			rest = rest[2+end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in path %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q in path %q", rest[1:end], path)
+			}
+			segments = append(segments, index)
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path %q", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("empty key in path %q", path)
+			}
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// Lookup returns the field of obj at path, and whether it exists
+func Lookup(obj map[string]interface{}, path string) (interface{}, bool, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var current interface{} = obj
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			if current, ok = m[s]; !ok {
+				return nil, false, nil
+			}
+		case int:
+			l, ok := current.([]interface{})
+			if !ok || s >= len(l) {
+				return nil, false, nil
+			}
+			current = l[s]
+		}
+	}
+	return current, true, nil
+}
+
+// Set returns a values map with value nested at path. Only map keys are
+// supported, as list items can't be merged with other values.
+func Set(path string, value interface{}) (map[string]interface{}, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var current interface{} = value
+	for i := len(segments) - 1; i >= 0; i-- {
+		key, ok := segments[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("list indices are not supported in values path %q", path)
+		}
+		current = map[string]interface{}{key: current}
+	}
+	return current.(map[string]interface{}), nil
+}
@@ -0,0 +1,81 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []interface{}
+		err      bool
+	}{
+		{"spec.clusterIP", []interface{}{"spec", "clusterIP"}, false},
+		{"spec.ports[0].port", []interface{}{"spec", "ports", 0, "port"}, false},
+		{"data['app.conf']", []interface{}{"data", "app.conf"}, false},
+		{`data["app.conf"].x`, []interface{}{"data", "app.conf", "x"}, false},
+		{"", nil, true},
+		{"spec..foo", nil, true},
+		{"spec.", nil, true},
+		{"spec.ports[a]", nil, true},
+		{"data['foo", nil, true},
+	}
+	for _, tt := range tests {
+		segments, err := ParsePath(tt.path)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Expecting an error for %q", tt.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.path, err)
+		} else if !reflect.DeepEqual(segments, tt.expected) {
+			t.Errorf("Expecting %v received %v", tt.expected, segments)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	obj := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"clusterIP": "10.0.0.1",
+			"ports":     []interface{}{map[string]interface{}{"port": float64(80)}},
+		},
+	}
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{"spec.clusterIP", "10.0.0.1", true},
+		{"spec.ports[0].port", float64(80), true},
+		{"spec.ports[1].port", nil, false},
+		{"spec.clusterIP.foo", nil, false},
+		{"status", nil, false},
+	}
+	for _, tt := range tests {
+		value, found, err := Lookup(obj, tt.path)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if found != tt.found || value != tt.expected {
+			t.Errorf("Expecting %v (%v) for %q received %v (%v)", tt.expected, tt.found, tt.path, value, found)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	values, err := Set("database.host", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := map[string]interface{}{"database": map[string]interface{}{"host": "10.0.0.1"}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expecting %v received %v", expected, values)
+	}
+	if _, err := Set("hosts[0]", "foo"); err == nil {
+		t.Errorf("Expecting an error for list indices")
+	}
+}
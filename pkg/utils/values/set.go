@@ -0,0 +1,95 @@
+package values
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSet returns the values map of a helm --set argument, a comma
+// separated list of path=value pairs. Values in braces are lists
+// (a={b,c}), and commas are escaped with a backslash. Unless stringValues
+// is set, as for helm --set-string, integers, booleans and null are typed.
+func ParseSet(s string, stringValues bool) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, pair := range splitUnescaped(s, ',') {
+		if pair == "" {
+			continue
+		}
+		eq := strings.Index(pair, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("key %q has no value", pair)
+		}
+		path, raw := pair[:eq], pair[eq+1:]
+
+		var value interface{}
+		if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+			items := []interface{}{}
+			for _, item := range splitUnescaped(raw[1:len(raw)-1], ',') {
+				items = append(items, setValue(unescape(item), stringValues))
+			}
+			value = items
+		} else {
+			value = setValue(unescape(raw), stringValues)
+		}
+
+		v, err := Set(path, value)
+		if err != nil {
+			return nil, err
+		}
+		values = mergeMaps(values, v, false)
+	}
+	return values, nil
+}
+
+// splitUnescaped splits s at the separators that are neither escaped nor
+// within braces
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	return strings.Replace(s, `\,`, ",", -1)
+}
+
+func setValue(s string, stringValues bool) interface{} {
+	if stringValues {
+		return s
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	case "0":
+		return int64(0)
+	}
+	// Leading zeros are kept as strings, e.g. "0755"
+	if !strings.HasPrefix(s, "0") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package values
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		set          string
+		stringValues bool
+		expected     string
+	}{
+		{"a=b", false, "a: b"},
+		{"a.b=1,a.c=true,d=null", false, "{a: {b: 1, c: true}, d: null}"},
+		{"a.b=1,a.c=true", true, "a: {b: '1', c: 'true'}"},
+		{"mode=0755", false, "mode: '0755'"},
+		{`a=b\,c`, false, "a: b,c"},
+		{"a={b,c},d=e", false, "{a: [b, c], d: e}"},
+		{"data['app.conf']=x", false, "data: {app.conf: x}"},
+		{"a=", false, "a: ''"},
+	}
+	for _, test := range tests {
+		res, err := ParseSet(test.set, test.stringValues)
+		if err != nil {
+			t.Errorf("Unexpected error %v for %s", err, test.set)
+			continue
+		}
+		var expected map[string]interface{}
+		yaml.Unmarshal([]byte(test.expected), &expected)
+		data, _ := yaml.Marshal(res)
+		var got map[string]interface{}
+		yaml.Unmarshal(data, &got)
+		if !apiequality.Semantic.DeepEqual(got, expected) {
+			t.Errorf("Expecting %s for %s, received %s", test.expected, test.set, data)
+		}
+	}
+
+	for _, set := range []string{"a", "a[0]=b", "a..b=c"} {
+		if _, err := ParseSet(set, false); err == nil {
+			t.Errorf("Expecting an error for %s", set)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package values
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// OutputsSecretName returns the name of the Secret holding the outputs of
+// the HelmRelease name
+func OutputsSecretName(name string) string {
+	return name + "-outputs"
+}
+
+// Variables returns the variables substituted in the inline values of h,
+// released as releaseName in a cluster whose domain is clusterDomain
+func Variables(h *helmCrdV2.HelmRelease, releaseName, clusterDomain string) map[string]string {
+	targetNamespace := h.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = h.Namespace
+	}
+	return map[string]string{
+		"NAME":             h.Name,
+		"NAMESPACE":        h.Namespace,
+		"TARGET_NAMESPACE": targetNamespace,
+		"RELEASE_NAME":     releaseName,
+		"CLUSTER_DOMAIN":   clusterDomain,
+	}
+}
+
+// FromConfigMap returns the values document of a ConfigMap key in namespace
+func FromConfigMap(client kubernetes.Interface, namespace string, ref *corev1.ConfigMapKeySelector) ([]byte, error) {
+	cm, err := client.Core().ConfigMaps(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if isOptional(ref.Optional) && k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := cm.Data[ref.Key]
+	if !ok && !isOptional(ref.Optional) {
+		return nil, fmt.Errorf("key %q not found in ConfigMap %s/%s", ref.Key, namespace, ref.Name)
+	}
+	return []byte(data), nil
+}
+
+// FromSecret returns the values document of a Secret key in namespace
+func FromSecret(client kubernetes.Interface, namespace string, ref *corev1.SecretKeySelector) ([]byte, error) {
+	secret, err := client.Core().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if isOptional(ref.Optional) && k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok && !isOptional(ref.Optional) {
+		return nil, fmt.Errorf("key %q not found in Secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+	return data, nil
+}
+
+// FromOutputs returns a values document holding all the outputs of the
+// referenced HelmRelease of namespace, or setting the target path of ref
+// to one of them
+func FromOutputs(client kubernetes.Interface, namespace string, ref *helmCrdV2.HelmReleaseOutputRef) ([]byte, error) {
+	secret, err := client.Core().Secrets(namespace).Get(OutputsSecretName(ref.Name), metav1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			if isOptional(ref.Optional) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("outputs of HelmRelease %s/%s not available yet", namespace, ref.Name)
+		}
+		return nil, err
+	}
+	if ref.Output == "" {
+		values := map[string]interface{}{}
+		for name, value := range secret.Data {
+			values[name] = string(value)
+		}
+		return yaml.Marshal(values)
+	}
+	value, ok := secret.Data[ref.Output]
+	if !ok {
+		if isOptional(ref.Optional) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("output %q of HelmRelease %s/%s not found", ref.Output, namespace, ref.Name)
+	}
+	targetPath := ref.TargetPath
+	if targetPath == "" {
+		targetPath = ref.Output
+	}
+	values, err := Set(targetPath, string(value))
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(values)
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
@@ -0,0 +1,53 @@
+package values
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func TestFromSources(t *testing.T) {
+	optional := true
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "defaults"},
+			Data:       map[string]string{"values.yaml": "port: 80\n"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "myns", Name: "db-outputs"},
+			Data:       map[string][]byte{"password": []byte("sekret"), "host": []byte("db")},
+		},
+	)
+
+	doc, err := FromConfigMap(client, "myns", &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "defaults"},
+		Key:                  "values.yaml",
+	})
+	if err != nil || string(doc) != "port: 80\n" {
+		t.Errorf("Unexpected ConfigMap values %q, error %v", doc, err)
+	}
+	missing := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "values.yaml"}
+	if _, err := FromSecret(client, "myns", missing); err == nil {
+		t.Errorf("Expecting an error for a missing Secret")
+	}
+	missing.Optional = &optional
+	if doc, err := FromSecret(client, "myns", missing); err != nil || doc != nil {
+		t.Errorf("Expecting no values for an optional missing Secret, received %q, error %v", doc, err)
+	}
+
+	doc, err = FromOutputs(client, "myns", &helmCrdV2.HelmReleaseOutputRef{Name: "db"})
+	if err != nil || string(doc) != "host: db\npassword: sekret\n" {
+		t.Errorf("Unexpected outputs values %q, error %v", doc, err)
+	}
+	doc, err = FromOutputs(client, "myns", &helmCrdV2.HelmReleaseOutputRef{Name: "db", Output: "password", TargetPath: "db.password"})
+	if err != nil || string(doc) != "db:\n  password: sekret\n" {
+		t.Errorf("Unexpected output values %q, error %v", doc, err)
+	}
+	if _, err := FromOutputs(client, "myns", &helmCrdV2.HelmReleaseOutputRef{Name: "other"}); err == nil {
+		t.Errorf("Expecting an error for unavailable outputs")
+	}
+}
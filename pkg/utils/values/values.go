@@ -0,0 +1,91 @@
+package values
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Strategy is how MergeStrategy combines values
+type Strategy string
+
+const (
+	// ReplaceLists merges maps recursively and replaces any other value,
+	// lists included, as Helm does
+	ReplaceLists Strategy = "replace-lists"
+	// Deep merges maps recursively and appends the items of lists
+	Deep Strategy = "deep"
+	// Overwrite replaces top-level keys, without merging their values
+	Overwrite Strategy = "overwrite"
+)
+
+// Merge combines YAML values documents in order, later documents taking
+// precedence. Maps are merged recursively, any other value is replaced.
+func Merge(docs ...[]byte) ([]byte, error) {
+	return MergeStrategy(ReplaceLists, docs...)
+}
+
+// MergeStrategy combines YAML values documents in order like Merge,
+// following strategy, ReplaceLists if empty
+func MergeStrategy(strategy Strategy, docs ...[]byte) ([]byte, error) {
+	merged := map[string]interface{}{}
+	for _, doc := range docs {
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &values); err != nil {
+			return nil, err
+		}
+		switch strategy {
+		case "", ReplaceLists:
+			merged = mergeMaps(merged, values, false)
+		case Deep:
+			merged = mergeMaps(merged, values, true)
+		case Overwrite:
+			for k, v := range values {
+				merged[k] = v
+			}
+		default:
+			return nil, fmt.Errorf("unknown merge strategy %q", strategy)
+		}
+	}
+	return yaml.Marshal(merged)
+}
+
+// mergeMaps merges src into dest recursively, appending the items of
+// lists with appendLists and replacing them otherwise
+func mergeMaps(dest, src map[string]interface{}, appendLists bool) map[string]interface{} {
+	for k, v := range src {
+		switch srcValue := v.(type) {
+		case map[string]interface{}:
+			if destMap, ok := dest[k].(map[string]interface{}); ok {
+				dest[k] = mergeMaps(destMap, srcValue, appendLists)
+				continue
+			}
+		case []interface{}:
+			if destList, ok := dest[k].([]interface{}); ok && appendLists {
+				dest[k] = append(destList, srcValue...)
+				continue
+			}
+		}
+		dest[k] = v
+	}
+	return dest
+}
+
+var variable = regexp.MustCompile(`\$?\$\{([A-Z_][A-Z0-9_]*)\}`)
+
+// Substitute replaces ${VAR} placeholders with the value of VAR in vars.
+// Unknown variables are left as-is, and $${VAR} escapes a literal ${VAR}.
+func Substitute(s string, vars map[string]string) string {
+	return variable.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		name := match[2 : len(match)-1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
@@ -0,0 +1,87 @@
+package values
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		docs     []string
+		expected string
+	}{
+		{"no documents", []string{}, "{}"},
+		{"empty document", []string{"", "foo: bar"}, "foo: bar"},
+		{"override", []string{"foo: bar", "foo: baz"}, "foo: baz"},
+		{"nested maps", []string{"a: {b: 1, c: 2}", "a: {c: 3, d: 4}"}, "a: {b: 1, c: 3, d: 4}"},
+		{"lists are replaced", []string{"a: [1, 2]", "a: [3]"}, "a: [3]"},
+		{"map replaced by scalar", []string{"a: {b: 1}", "a: null"}, "a: null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs [][]byte
+			for _, d := range tt.docs {
+				docs = append(docs, []byte(d))
+			}
+			res, err := Merge(docs...)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			var got, expected interface{}
+			yaml.Unmarshal(res, &got)
+			yaml.Unmarshal([]byte(tt.expected), &expected)
+			if !apiequality.Semantic.DeepEqual(got, expected) {
+				t.Errorf("Expecting %s received %s", tt.expected, res)
+			}
+		})
+	}
+}
+
+func TestMergeStrategy(t *testing.T) {
+	docs := [][]byte{
+		[]byte("a: {b: 1, c: 2}\nlist: [1, 2]\nkeep: true"),
+		[]byte("a: {c: 3}\nlist: [3]"),
+	}
+	tests := []struct {
+		strategy Strategy
+		expected string
+	}{
+		{"", "a: {b: 1, c: 3}\nlist: [3]\nkeep: true"},
+		{ReplaceLists, "a: {b: 1, c: 3}\nlist: [3]\nkeep: true"},
+		{Deep, "a: {b: 1, c: 3}\nlist: [1, 2, 3]\nkeep: true"},
+		{Overwrite, "a: {c: 3}\nlist: [3]\nkeep: true"},
+	}
+	for _, tt := range tests {
+		res, err := MergeStrategy(tt.strategy, docs...)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		var got, expected interface{}
+		yaml.Unmarshal(res, &got)
+		yaml.Unmarshal([]byte(tt.expected), &expected)
+		if !apiequality.Semantic.DeepEqual(got, expected) {
+			t.Errorf("Expecting %q to merge to %s received %s", tt.strategy, tt.expected, res)
+		}
+	}
+	if _, err := MergeStrategy("shallow", docs...); err == nil {
+		t.Errorf("Expecting an error for an unknown strategy")
+	}
+}
+
+func TestMergeInvalid(t *testing.T) {
+	if _, err := Merge([]byte("foo: [bar")); err == nil {
+		t.Errorf("Expecting an error for invalid YAML")
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{"NAMESPACE": "myns", "CLUSTER_DOMAIN": "cluster.local"}
+	in := "host: db.${NAMESPACE}.svc.${CLUSTER_DOMAIN}\nscript: echo $${NAMESPACE} ${HOME}\n"
+	expected := "host: db.myns.svc.cluster.local\nscript: echo ${NAMESPACE} ${HOME}\n"
+	if out := Substitute(in, vars); out != expected {
+		t.Errorf("Expecting %q received %q", expected, out)
+	}
+}
@@ -0,0 +1,313 @@
+// Package window computes when the recurring maintenance windows of
+// HelmReleases are open
+package window
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+// maxSearch bounds the search of the next opening of a window
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// maxDuration is the maximum duration in seconds of scheduled windows
+const maxDuration = 31 * 24 * 3600
+
+// Window is a parsed UpgradeWindow
+type Window struct {
+	location *time.Location
+	schedule *schedule
+	duration time.Duration
+	ranges   []timeRange
+}
+
+type timeRange struct {
+	// days are the weekdays the range starts on, all if empty
+	days map[time.Weekday]bool
+	// start and end are minutes since midnight
+	start, end int
+}
+
+// Parse returns the window of spec
+func Parse(spec *helmCrdV2.UpgradeWindow) (*Window, error) {
+	w := &Window{location: time.UTC}
+	if spec.TimeZone != "" {
+		loc, err := time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeZone %q: %v", spec.TimeZone, err)
+		}
+		w.location = loc
+	}
+	if spec.Schedule == "" && len(spec.Ranges) == 0 {
+		return nil, fmt.Errorf("a schedule or ranges are required")
+	}
+	if spec.Schedule != "" {
+		s, err := parseSchedule(spec.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec.Schedule, err)
+		}
+		if spec.Duration <= 0 || spec.Duration > maxDuration {
+			return nil, fmt.Errorf("duration must be between 1 and %d seconds", maxDuration)
+		}
+		w.schedule = s
+		w.duration = time.Duration(spec.Duration) * time.Second
+	}
+	for i, r := range spec.Ranges {
+		tr, err := parseRange(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %d: %v", i, err)
+		}
+		w.ranges = append(w.ranges, tr)
+	}
+	return w, nil
+}
+
+func parseRange(r helmCrdV2.TimeRange) (timeRange, error) {
+	var tr timeRange
+	var err error
+	if tr.start, err = parseTimeOfDay(r.Start); err != nil {
+		return tr, fmt.Errorf("invalid start: %v", err)
+	}
+	if tr.end, err = parseTimeOfDay(r.End); err != nil {
+		return tr, fmt.Errorf("invalid end: %v", err)
+	}
+	if len(r.Days) > 0 {
+		tr.days = map[time.Weekday]bool{}
+		for _, d := range r.Days {
+			day, ok := parseWeekday(d)
+			if !ok {
+				return tr, fmt.Errorf("invalid day %q", d)
+			}
+			tr.days[day] = true
+		}
+	}
+	return tr, nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseWeekday parses a day name, abbreviated or not
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for i, d := range weekdays {
+		if len(s) >= 3 && strings.HasPrefix(strings.ToLower(time.Weekday(i).String()), s) || s == d {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// Open returns whether the window is open at t
+func (w *Window) Open(t time.Time) bool {
+	t = t.In(w.location)
+	for _, r := range w.ranges {
+		if r.open(t) {
+			return true
+		}
+	}
+	if w.schedule != nil {
+		// Look for a scheduled time within the duration before t
+		start := t.Truncate(time.Minute)
+		for s := start; t.Sub(s) < w.duration; s = s.Add(-time.Minute) {
+			if w.schedule.matches(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Next returns the first time the window is open from t, which is t if
+// it is open, or the zero time if it never opens
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Open(t) {
+		return t
+	}
+	t = t.In(w.location)
+	var next time.Time
+	for _, r := range w.ranges {
+		if n := r.next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	if w.schedule != nil {
+		if n := w.schedule.next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// open returns whether t, in the window location, is within r
+func (r timeRange) open(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if r.start < r.end {
+		return r.startsOn(t.Weekday()) && minute >= r.start && minute < r.end
+	}
+	// The range spans midnight
+	if minute >= r.start && r.startsOn(t.Weekday()) {
+		return true
+	}
+	return minute < r.end && r.startsOn((t.Weekday()+6)%7)
+}
+
+func (r timeRange) startsOn(day time.Weekday) bool {
+	return r.days == nil || r.days[day]
+}
+
+// next returns the first start of r after t
+func (r timeRange) next(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		start := time.Date(day.Year(), day.Month(), day.Day(), r.start/60, r.start%60, 0, 0, t.Location())
+		if start.After(t) && r.startsOn(day.Weekday()) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// schedule is a parsed cron expression: minute, hour, day of month,
+// month and day of week, each a set of allowed values
+type schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny and dowAny are set for the * fields, since a day matches
+	// either restricted day field
+	domAny, dowAny bool
+}
+
+var months = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+func parseSchedule(expr string) (*schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expecting 5 fields, found %d", len(fields))
+	}
+	s := &schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, months); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, weekdays); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+	if s.dow[7] {
+		// 7 is Sunday too
+		s.dow[0] = true
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// parseField parses a comma separated list of *, values, ranges and
+// steps, e.g. "1-5", "*/15" or "mon,wed"
+func parseField(field string, min, max int, names []string) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" && part != "?" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = parseValue(bounds[0], min, max, names); err != nil {
+				return nil, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = parseValue(bounds[1], min, max, names); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				hi = max
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func parseValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("%q is not a value between %d and %d", s, min, max)
+	}
+	return v, nil
+}
+
+func (s *schedule) matches(t time.Time) bool {
+	return s.minute[t.Minute()] && s.hour[t.Hour()] && s.month[int(t.Month())] && s.dayMatches(t)
+}
+
+func (s *schedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first scheduled time after t
+func (s *schedule) next(t time.Time) time.Time {
+	end := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(end) {
+		switch {
+		case !s.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,105 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	helmCrdV2 "github.com/bitnami-labs/helm-crd/pkg/apis/helm.bitnami.com/v2"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		spec helmCrdV2.UpgradeWindow
+		at   string
+		open bool
+		next string
+	}{
+		{
+			name: "weekly schedule, open",
+			// Saturdays from 02:00 for 4 hours
+			spec: helmCrdV2.UpgradeWindow{Schedule: "0 2 * * Sat", Duration: 4 * 3600},
+			at:   "2026-10-17 05:59", // a Saturday
+			open: true,
+			next: "2026-10-17 05:59",
+		},
+		{
+			name: "weekly schedule, closed",
+			spec: helmCrdV2.UpgradeWindow{Schedule: "0 2 * * 6", Duration: 4 * 3600},
+			at:   "2026-10-17 06:00",
+			next: "2026-10-24 02:00",
+		},
+		{
+			name: "schedule with steps and lists",
+			spec: helmCrdV2.UpgradeWindow{Schedule: "*/30 9-17 1,15 * *", Duration: 600},
+			at:   "2026-10-14 12:00",
+			next: "2026-10-15 09:00",
+		},
+		{
+			name: "schedule in a time zone",
+			spec: helmCrdV2.UpgradeWindow{Schedule: "0 2 * * *", Duration: 3600, TimeZone: "America/New_York"},
+			at:   "2026-10-15 03:00",
+			next: "2026-10-15 06:00",
+		},
+		{
+			name: "weekday range",
+			spec: helmCrdV2.UpgradeWindow{Ranges: []helmCrdV2.TimeRange{{Days: []string{"Mon", "Tuesday"}, Start: "09:00", End: "17:00"}}},
+			at:   "2026-10-15 10:00", // a Thursday
+			next: "2026-10-19 09:00",
+		},
+		{
+			name: "range spanning midnight",
+			spec: helmCrdV2.UpgradeWindow{Ranges: []helmCrdV2.TimeRange{{Days: []string{"Fri"}, Start: "22:00", End: "04:00"}}},
+			at:   "2026-10-17 03:00", // a Saturday
+			open: true,
+			next: "2026-10-17 03:00",
+		},
+		{
+			name: "earliest of ranges and schedule",
+			spec: helmCrdV2.UpgradeWindow{
+				Schedule: "0 0 1 * *",
+				Duration: 3600,
+				Ranges:   []helmCrdV2.TimeRange{{Start: "23:00", End: "23:30"}},
+			},
+			at:   "2026-10-31 23:45",
+			next: "2026-11-01 00:00",
+		},
+	}
+	for _, tt := range tests {
+		w, err := Parse(&tt.spec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		at := date(tt.at)
+		if open := w.Open(at); open != tt.open {
+			t.Errorf("%s: expecting open %v, received %v", tt.name, tt.open, open)
+		}
+		if next := w.Next(at); !next.Equal(date(tt.next)) {
+			t.Errorf("%s: expecting the window to open at %s, received %s", tt.name, tt.next, next.UTC())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []helmCrdV2.UpgradeWindow{
+		{},
+		{Schedule: "0 2 * *", Duration: 60},
+		{Schedule: "0 25 * * *", Duration: 60},
+		{Schedule: "0 2 * * *"},
+		{Schedule: "0 2 * * Sat", Duration: 60, TimeZone: "Mars/Olympus"},
+		{Ranges: []helmCrdV2.TimeRange{{Start: "09:00", End: "24:00"}}},
+		{Ranges: []helmCrdV2.TimeRange{{Days: []string{"Someday"}, Start: "09:00", End: "17:00"}}},
+	} {
+		if _, err := Parse(&spec); err == nil {
+			t.Errorf("Expecting an error for %+v", spec)
+		}
+	}
+}